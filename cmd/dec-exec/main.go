@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -16,15 +17,15 @@ var (
 
 func main() {
 	var projectRoot, bundle, plane string
+	var mcpURL, mcpTransport, mcpBearerEnv string
+	var mcpHeaders, mcpEnvHeaders, extraEnv []string
 	root := &cobra.Command{
 		Use:          "dec-exec --bundle NAME -- <command> [args...]",
 		Short:        "注入已落地的 Dec secrets 环境变量后执行命令",
+		Long:         "注入已落地的 Dec secrets 环境变量后执行命令。\n\n指定 --mcp-url 时不执行命令，而是作为 stdio→HTTP 桥连接远端 MCP server，凭据请求头从 bundle .env 解析。",
 		SilenceUsage: true,
 		Args:         cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
-				return fmt.Errorf("缺少要执行的命令")
-			}
 			if projectRoot == "" {
 				var err error
 				projectRoot, err = os.Getwd()
@@ -32,11 +33,30 @@ func main() {
 					return err
 				}
 			}
+			if mcpURL != "" {
+				if len(args) > 0 {
+					return fmt.Errorf("--mcp-url 与要执行的命令不能同时指定")
+				}
+				return app.RunMCPBridgeWithSecrets(context.Background(), app.MCPBridgeInput{
+					ProjectRoot: projectRoot,
+					Bundle:      bundle,
+					Plane:       parsePlane(plane),
+					URL:         mcpURL,
+					Transport:   mcpTransport,
+					Headers:     mcpHeaders,
+					EnvHeaders:  mcpEnvHeaders,
+					BearerEnv:   mcpBearerEnv,
+				})
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("缺少要执行的命令")
+			}
 			code, err := app.RunExecWithSecrets(app.ExecWithSecretsInput{
 				ProjectRoot: projectRoot,
 				Bundle:      bundle,
 				Plane:       parsePlane(plane),
 				Command:     args,
+				Env:         extraEnv,
 			})
 			if err != nil {
 				return err
@@ -50,6 +70,12 @@ func main() {
 	root.Flags().StringVar(&projectRoot, "project-root", "", "项目根目录（默认当前目录）")
 	root.Flags().StringVar(&bundle, "bundle", "", "只注入该 bundle + project 的 env")
 	root.Flags().StringVar(&plane, "plane", "project", "secrets 平面：project 或 user")
+	root.Flags().StringArrayVar(&extraEnv, "env", nil, "额外 env，格式 KEY=VALUE，VALUE 中的 ${VAR} / ${VAR:-default} 在注入 secrets 后展开，可重复")
+	root.Flags().StringVar(&mcpURL, "mcp-url", "", "作为 stdio→HTTP 桥连接的远端 MCP URL")
+	root.Flags().StringVar(&mcpTransport, "mcp-transport", "http", "远端 MCP 传输方式：http 或 sse")
+	root.Flags().StringArrayVar(&mcpHeaders, "mcp-header", nil, "明文请求头，格式 \"Name: value\"，可重复")
+	root.Flags().StringArrayVar(&mcpEnvHeaders, "mcp-env-header", nil, "凭据请求头，格式 Name=VAR，值取自 bundle .env，可重复")
+	root.Flags().StringVar(&mcpBearerEnv, "mcp-bearer-env", "", "以 Authorization: Bearer <VAR 的值> 发送的变量名")
 	if err := root.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/shichao402/Dec/internal/config"
 	"github.com/shichao402/Dec/internal/freshness"
 	"github.com/spf13/cobra"
 )
@@ -25,6 +26,8 @@ var freshnessCheckCmd = &cobra.Command{
 			// Fallback：父进程理论上总会传 --project-root，这里兜底。
 			root, _ = os.Getwd()
 		}
+		// 后台 worker 直接 fetch，不经过 dec-server，需要自己套用 git_backend 等仓库设置。
+		config.ApplyRepoSettings()
 		return freshness.RunBackgroundCheck(root)
 	},
 }
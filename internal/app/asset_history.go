@@ -0,0 +1,393 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shichao402/Dec/internal/bundle"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// defaultAssetHistoryLimit 是未指定 Limit 时最多列出的提交数。
+const defaultAssetHistoryLimit = 30
+
+// AssetHistoryQuery 指定要查看历史的资产或整个 bundle，以及查看方式。
+type AssetHistoryQuery struct {
+	Bundle string `json:"bundle"`
+	// Type 与 Name 都为空时查看整个 bundle。
+	Type string `json:"type,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Limit 是最多列出的提交数，<=0 时取默认值。
+	Limit int `json:"limit,omitempty"`
+	// Revision 非空时返回该提交对目标的改动（相对第一个父提交）；恢复时是要恢复的版本。
+	Revision string `json:"revision,omitempty"`
+	// From / To 任一非空时返回两个版本之间目标的差异；To 为空表示 vault 分支最新提交。
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// VaultCommit 是 vault 历史中的一次提交。
+type VaultCommit struct {
+	Hash    string
+	Parents []string
+	Author  string
+	Email   string
+	Time    time.Time
+	Subject string
+	Message string
+}
+
+// AssetHistoryResult 是资产历史查询的结果：列表模式填 Commits，提交详情填 Commit，版本比较填 From / To；
+// 后两者的 Changes 是目标范围内的逐文件差异。
+type AssetHistoryResult struct {
+	Bundle string
+	Type   string
+	Name   string
+	// Branch 是查询的 vault 分支，空串表示远端默认分支。
+	Branch string
+	// Paths 是查询覆盖的 vault 相对路径。
+	Paths   []string
+	Commits []VaultCommit
+	Commit  *VaultCommit
+	From    string
+	To      string
+	Changes []VaultFileChange
+}
+
+// AssetRestoreResult 是把历史版本恢复到本地缓存的结果，路径相对缓存目录。
+type AssetRestoreResult struct {
+	Bundle   string
+	Type     string
+	Name     string
+	Revision string
+	Restored []string
+	Removed  []string
+}
+
+// assetHistoryTarget 是解析后的查询目标。
+type assetHistoryTarget struct {
+	bundle string
+	kind   bundle.VaultAssetKind
+	name   string
+	// paths 用于历史与比较；restorePaths 只含 Dec 管理的资产目录与 bundle 声明，恢复时不碰其他文件。
+	paths        []string
+	restorePaths []string
+}
+
+func resolveAssetHistoryTarget(query AssetHistoryQuery) (assetHistoryTarget, error) {
+	var target assetHistoryTarget
+	target.bundle = strings.TrimSpace(query.Bundle)
+	if target.bundle == "" || strings.ContainsAny(target.bundle, `/\`) || target.bundle == "." || target.bundle == ".." {
+		return target, fmt.Errorf("bundle 名称无效: %q", query.Bundle)
+	}
+	bundleDir := path.Join(types.VaultBundlesDir, target.bundle)
+	itemType, name := strings.TrimSpace(query.Type), strings.TrimSpace(query.Name)
+	if itemType == "" && name == "" {
+		target.paths = []string{bundleDir}
+		target.restorePaths = []string{types.VaultBundleManifestPath(target.bundle)}
+		for _, dir := range bundle.VaultAssetDirs() {
+			target.restorePaths = append(target.restorePaths, path.Join(bundleDir, dir))
+		}
+		return target, nil
+	}
+	kind, ok := bundle.KindByType(itemType)
+	if !ok {
+		return target, fmt.Errorf("不支持的资产类型: %q", query.Type)
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return target, fmt.Errorf("资产名称无效: %q", query.Name)
+	}
+	target.kind = kind
+	target.name = name
+	target.paths = []string{path.Join(bundleDir, kind.Dir, bundle.AssetFileName(kind, name))}
+	target.restorePaths = target.paths
+	return target, nil
+}
+
+// LoadAssetHistory 查看资产或 bundle 在 vault 分支上的历史、某次提交的改动或两个版本之间的差异。
+// 先尝试同步远端，失败时退回本地 bare repo 中已有的历史。
+func LoadAssetHistory(ctx context.Context, workspace Workspace, query AssetHistoryQuery, reporter Reporter) (*AssetHistoryResult, error) {
+	reporter = defaultReporter(reporter)
+	target, err := resolveAssetHistoryTarget(query)
+	if err != nil {
+		return nil, err
+	}
+	projectConfig, err := loadWorkspaceBundleConfig(workspace)
+	if err != nil {
+		return nil, err
+	}
+	branch, err := resolveVaultBranch(projectConfig, reporter, "history")
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.FetchBare(); err != nil {
+		emit(reporter, EventWarn, "history", fmt.Sprintf("⚠️  同步远端失败，显示本地已有的历史: %v", err), nil)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &AssetHistoryResult{
+		Bundle: target.bundle,
+		Type:   target.kind.Type,
+		Name:   target.name,
+		Branch: branch,
+		Paths:  target.paths,
+	}
+	switch {
+	case strings.TrimSpace(query.Revision) != "":
+		info, _, err := repo.CommitDetails(strings.TrimSpace(query.Revision))
+		if err != nil {
+			return nil, err
+		}
+		commit := vaultCommitFromRepo(*info)
+		result.Commit = &commit
+		result.To = info.Hash
+		if len(info.Parents) > 0 {
+			result.From = info.Parents[0]
+		}
+		result.Changes, err = diffVaultRevisions(result.From, result.To, target.paths)
+		if err != nil {
+			return nil, err
+		}
+	case strings.TrimSpace(query.From) != "" || strings.TrimSpace(query.To) != "":
+		if result.From, err = resolveHistoryRevision(query.From, branch); err != nil {
+			return nil, err
+		}
+		if result.To, err = resolveHistoryRevision(query.To, branch); err != nil {
+			return nil, err
+		}
+		result.Changes, err = diffVaultRevisions(result.From, result.To, target.paths)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		limit := query.Limit
+		if limit <= 0 {
+			limit = defaultAssetHistoryLimit
+		}
+		ref := ""
+		if branch != "" {
+			ref = "refs/heads/" + branch
+		}
+		commits, err := repo.History(ref, target.paths, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range commits {
+			result.Commits = append(result.Commits, vaultCommitFromRepo(commit))
+		}
+		emit(reporter, EventInfo, "history", fmt.Sprintf("%s 共 %d 次相关提交", strings.Join(target.paths, ", "), len(result.Commits)), nil)
+	}
+	return result, nil
+}
+
+// resolveHistoryRevision 解析比较用的版本；空串表示 vault 分支最新提交。
+func resolveHistoryRevision(ref, branch string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		if branch == "" {
+			defaultBranch, err := repo.GetDefaultBranch()
+			if err != nil {
+				return "", err
+			}
+			branch = defaultBranch
+		}
+		ref = "refs/heads/" + branch
+	}
+	return repo.ResolveCommit(ref)
+}
+
+func vaultCommitFromRepo(info repo.CommitInfo) VaultCommit {
+	return VaultCommit{
+		Hash:    info.Hash,
+		Parents: info.Parents,
+		Author:  info.Author,
+		Email:   info.Email,
+		Time:    info.Time,
+		Subject: info.Subject,
+		Message: info.Message,
+	}
+}
+
+// openVaultRevision 以只读事务物化 revision 中落在 paths 下的文件，返回事务与这些文件的相对路径。
+// revision 为空表示空树（根提交的父版本），返回 nil 事务。
+func openVaultRevision(revision string, paths []string) (*repo.Transaction, []string, error) {
+	if revision == "" {
+		return nil, nil, nil
+	}
+	var matched []string
+	tx, err := repo.NewReadTransactionWith(repo.ReadOptions{
+		Ref:   revision,
+		Local: true,
+		Select: func(files []string) []string {
+			for _, file := range files {
+				if repo.PathWithin(file, paths) {
+					matched = append(matched, file)
+				}
+			}
+			return matched
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取 vault 版本 %s 失败: %w", shortCommit(revision), err)
+	}
+	return tx, matched, nil
+}
+
+// diffVaultRevisions 比较两个版本中 paths 下的文件，from 为空视为空树。
+func diffVaultRevisions(from, to string, paths []string) ([]VaultFileChange, error) {
+	before, beforeFiles, err := openVaultRevision(from, paths)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil {
+		defer before.Close()
+	}
+	after, afterFiles, err := openVaultRevision(to, paths)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil {
+		defer after.Close()
+	}
+
+	names := make(map[string]struct{}, len(beforeFiles)+len(afterFiles))
+	for _, file := range beforeFiles {
+		names[file] = struct{}{}
+	}
+	for _, file := range afterFiles {
+		names[file] = struct{}{}
+	}
+	var changes []VaultFileChange
+	for rel := range names {
+		old, oldOK, err := readRevisionFile(before, rel)
+		if err != nil {
+			return nil, err
+		}
+		cur, curOK, err := readRevisionFile(after, rel)
+		if err != nil {
+			return nil, err
+		}
+		if oldOK == curOK && string(old) == string(cur) {
+			continue
+		}
+		bundleName, _, _ := splitVaultBundlePath(rel)
+		changes = append(changes, newVaultFileChange(bundleName, rel, old, cur, oldOK, curOK))
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func readRevisionFile(tx *repo.Transaction, rel string) ([]byte, bool, error) {
+	if tx == nil {
+		return nil, false, nil
+	}
+	return readOptionalFile(filepath.Join(tx.WorkDir(), filepath.FromSlash(rel)))
+}
+
+// RestoreAssetRevision 把资产或 bundle 在 query.Revision 时的内容写回本地缓存，
+// 该版本中不存在的缓存文件一并删除；之后照常 push 即可把旧版本送回 vault。
+func RestoreAssetRevision(ctx context.Context, workspace Workspace, query AssetHistoryQuery, reporter Reporter) (*AssetRestoreResult, error) {
+	reporter = defaultReporter(reporter)
+	target, err := resolveAssetHistoryTarget(query)
+	if err != nil {
+		return nil, err
+	}
+	revision := strings.TrimSpace(query.Revision)
+	if revision == "" {
+		return nil, fmt.Errorf("请指定要恢复的版本")
+	}
+	hash, err := repo.ResolveCommit(revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tx, files, err := openVaultRevision(hash, target.restorePaths)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("版本 %s 中没有 %s", shortCommit(hash), strings.Join(target.paths, ", "))
+	}
+
+	cacheDir := workspaceCacheDir(workspace)
+	result := &AssetRestoreResult{Bundle: target.bundle, Type: target.kind.Type, Name: target.name, Revision: hash}
+	keep := make(map[string]bool, len(files))
+	for _, rel := range files {
+		_, rest, ok := splitVaultBundlePath(rel)
+		if !ok {
+			continue
+		}
+		cacheRel := path.Join(target.bundle, rest)
+		keep[cacheRel] = true
+		source := filepath.Join(tx.WorkDir(), filepath.FromSlash(rel))
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("读取版本 %s 中的 %s 失败: %w", shortCommit(hash), rel, err)
+		}
+		dest := filepath.Join(cacheDir, filepath.FromSlash(cacheRel))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(dest, data, assetFileMode(source)); err != nil {
+			return nil, fmt.Errorf("写入 %s 失败: %w", cacheRel, err)
+		}
+		result.Restored = append(result.Restored, cacheRel)
+	}
+
+	for _, rel := range target.restorePaths {
+		_, rest, ok := splitVaultBundlePath(rel)
+		if !ok {
+			continue
+		}
+		root := filepath.Join(cacheDir, target.bundle, filepath.FromSlash(rest))
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				if os.IsNotExist(walkErr) {
+					return nil
+				}
+				return walkErr
+			}
+			if d.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(cacheDir, p)
+			if err != nil {
+				return err
+			}
+			cacheRel := filepath.ToSlash(relPath)
+			if keep[cacheRel] {
+				return nil
+			}
+			if err := os.Remove(p); err != nil {
+				return fmt.Errorf("删除 %s 失败: %w", cacheRel, err)
+			}
+			result.Removed = append(result.Removed, cacheRel)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(result.Restored)
+	sort.Strings(result.Removed)
+
+	if projectConfig, err := loadWorkspaceBundleConfig(workspace); err == nil && !containsString(projectConfig.EnabledBundles, target.bundle) {
+		emit(reporter, EventWarn, "history", fmt.Sprintf("⚠️  bundle %s 未启用，push 不会带上恢复的内容", target.bundle), nil)
+	}
+	emit(reporter, EventInfo, "history", fmt.Sprintf("已把 %s 恢复到 %s 版本（%d 个文件写入 %s），push 后生效",
+		strings.Join(target.paths, ", "), shortCommit(hash), len(result.Restored), displayCacheDir(workspace)), nil)
+	return result, nil
+}
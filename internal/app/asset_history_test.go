@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestAssetHistoryLogDiffAndRestore(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/alpha.mdc": "---\ndescription: alpha\n---\nalpha v1\n",
+		"bundles/combo/rules/beta.mdc":  "---\ndescription: beta\n---\nbeta v1\n",
+		"bundles/combo/bundle.yaml":     "name: combo\nmembers:\n  - rule/alpha\n  - rule/beta\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"combo"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	if _, err := PullWorkspaceAssets(context.Background(), workspace, "", nil); err != nil {
+		t.Fatalf("PullWorkspaceAssets() 失败: %v", err)
+	}
+	alphaCache := getWorkspaceCachePath(workspace, "combo", "rule", "alpha")
+	for _, version := range []string{"v2", "v3"} {
+		writeFileProjectTest(t, alphaCache, "---\ndescription: alpha\n---\nalpha "+version+"\n")
+		if _, err := PushWorkspaceWith(context.Background(), workspace, PushOptions{Message: "alpha " + version}, nil); err != nil {
+			t.Fatalf("PushWorkspaceWith(%s) 失败: %v", version, err)
+		}
+		// push 不推进合并基准，下一轮编辑前先拉取。
+		if _, err := PullWorkspaceAssets(context.Background(), workspace, "", nil); err != nil {
+			t.Fatalf("PullWorkspaceAssets() 失败: %v", err)
+		}
+	}
+	writeFileProjectTest(t, getWorkspaceCachePath(workspace, "combo", "rule", "beta"), "---\ndescription: beta\n---\nbeta v2\n")
+	if _, err := PushWorkspaceAssets(context.Background(), workspace, nil); err != nil {
+		t.Fatalf("PushWorkspaceAssets() 失败: %v", err)
+	}
+	if _, err := PullWorkspaceAssets(context.Background(), workspace, "", nil); err != nil {
+		t.Fatalf("PullWorkspaceAssets() 失败: %v", err)
+	}
+
+	query := AssetHistoryQuery{Bundle: "combo", Type: "rule", Name: "alpha"}
+	history, err := LoadAssetHistory(context.Background(), workspace, query, nil)
+	if err != nil {
+		t.Fatalf("LoadAssetHistory() 失败: %v", err)
+	}
+	if len(history.Commits) != 3 || history.Paths[0] != "bundles/combo/rules/alpha.mdc" {
+		t.Fatalf("alpha 历史 = %+v", history)
+	}
+	if !strings.Contains(history.Commits[0].Subject, "alpha v3") || !strings.Contains(history.Commits[1].Subject, "alpha v2") {
+		t.Fatalf("历史顺序或说明不对: %q / %q", history.Commits[0].Subject, history.Commits[1].Subject)
+	}
+	bundleHistory, err := LoadAssetHistory(context.Background(), workspace, AssetHistoryQuery{Bundle: "combo"}, nil)
+	if err != nil || len(bundleHistory.Commits) != 4 {
+		t.Fatalf("bundle 历史 = %+v, %v", bundleHistory, err)
+	}
+
+	query.Revision = history.Commits[1].Hash
+	detail, err := LoadAssetHistory(context.Background(), workspace, query, nil)
+	if err != nil {
+		t.Fatalf("LoadAssetHistory(revision) 失败: %v", err)
+	}
+	if detail.Commit == nil || detail.From != history.Commits[2].Hash || len(detail.Changes) != 1 ||
+		!strings.Contains(detail.Changes[0].Diff, "-alpha v1") || !strings.Contains(detail.Changes[0].Diff, "+alpha v2") {
+		t.Fatalf("提交详情 = %+v", detail)
+	}
+
+	compare, err := LoadAssetHistory(context.Background(), workspace, AssetHistoryQuery{Bundle: "combo", Type: "rule", Name: "alpha", From: history.Commits[2].Hash[:10]}, nil)
+	if err != nil {
+		t.Fatalf("LoadAssetHistory(from) 失败: %v", err)
+	}
+	if len(compare.Changes) != 1 || !strings.Contains(compare.Changes[0].Diff, "+alpha v3") {
+		t.Fatalf("版本比较 = %+v", compare.Changes)
+	}
+
+	restored, err := RestoreAssetRevision(context.Background(), workspace, AssetHistoryQuery{Bundle: "combo", Type: "rule", Name: "alpha", Revision: history.Commits[2].Hash}, nil)
+	if err != nil {
+		t.Fatalf("RestoreAssetRevision() 失败: %v", err)
+	}
+	if len(restored.Restored) != 1 || restored.Restored[0] != "combo/rules/alpha.mdc" {
+		t.Fatalf("恢复结果 = %+v", restored)
+	}
+	if data, err := os.ReadFile(alphaCache); err != nil || !strings.Contains(string(data), "alpha v1") {
+		t.Fatalf("缓存应恢复为 v1: %q, %v", data, err)
+	}
+	if _, err := PushWorkspaceAssets(context.Background(), workspace, nil); err != nil {
+		t.Fatalf("恢复后 push 失败: %v", err)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/rules/alpha.mdc"); !strings.Contains(got, "alpha v1") {
+		t.Fatalf("恢复并推送后远端应为 v1: %q", got)
+	}
+
+	if _, err := RestoreAssetRevision(context.Background(), workspace, AssetHistoryQuery{Bundle: "combo", Type: "rule", Name: "gamma", Revision: history.Commits[0].Hash}, nil); err == nil {
+		t.Fatalf("版本中不存在的资产不应允许恢复")
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/vars"
+)
+
+// assetTemplate 是 text/template 渲染在 vars 之外需要的上下文。
+type assetTemplate struct {
+	// OptIn 来自 bundle.yaml 的 templates：资产内全部文件都按模板渲染；
+	// 否则只有 frontmatter 声明了 dec_template: true 的文件才按模板渲染
+	OptIn   bool
+	Bundles []string
+	Plane   string
+	// Source 是资产在 cache 中的展示路径（.dec/cache/... 或 ~/.dec/cache/...），模板错误与缺失变量据此指出文件与行号
+	Source string
+}
+
+// newAssetTemplate 组装某个资产的模板上下文。
+func newAssetTemplate(workspace Workspace, shared *vaultSharedVars, enabledBundles []string, bundleName, assetType, assetName string) assetTemplate {
+	source := getWorkspaceCachePath(workspace, bundleName, assetType, assetName)
+	if rel, err := filepath.Rel(workspaceCacheDir(workspace), source); err == nil {
+		source = filepath.Join(displayCacheDir(workspace), rel)
+	}
+	return assetTemplate{
+		OptIn:   shared.usesTemplate(bundleName, assetType, assetName),
+		Bundles: enabledBundles,
+		Plane:   string(workspace.EffectivePlane()),
+		Source:  filepath.ToSlash(source),
+	}
+}
+
+// data 生成某份输出（IDE）的模板数据；.Vars 包含该资产各层可见的全部变量。
+func (t assetTemplate) data(layers vars.Layers, assetType, assetName, bundleName, ideName string) vars.TemplateData {
+	return vars.TemplateData{
+		Vars:    vars.ResolveLayeredVars(layers, assetType, assetName, vars.LayerNames(layers, assetType, assetName)),
+		IDE:     ideName,
+		Bundle:  bundleName,
+		Bundles: t.Bundles,
+		Plane:   t.Plane,
+	}
+}
+
+// substituteAssetFiles 渲染暂存区中的一份副本（文件或目录）：选择模板的文件走 text/template，
+// 其余文件照常做 {{VAR}} 替换。返回缺失的占位符与模板错误（含未知过滤器）；后者不中断其它文件。
+func substituteAssetFiles(localPath string, resolved map[string]string, tmpl assetTemplate, data vars.TemplateData) ([]string, []error, error) {
+	var (
+		missing      []string
+		templateErrs []error
+	)
+	err := filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		header, body := splitRenderedHeader(string(raw))
+		name := tmpl.Source
+		if rel, err := filepath.Rel(localPath, path); err == nil && rel != "." {
+			name = filepath.ToSlash(filepath.Join(tmpl.Source, rel))
+		}
+		if !tmpl.OptIn && !vars.IsTemplateOptIn(body) {
+			if err := vars.CheckFilters(body); err != nil {
+				templateErrs = append(templateErrs, fmt.Errorf("%s: %w", name, err))
+				return nil
+			}
+			_, fileMissing, err := vars.SubstituteFile(path, resolved)
+			if err != nil {
+				return err
+			}
+			missing = append(missing, fileMissing...)
+			return nil
+		}
+
+		rendered, err := vars.RenderTemplate(name, body, data)
+		if err != nil {
+			templateErrs = append(templateErrs, err)
+			return nil
+		}
+		if rendered == body {
+			return nil
+		}
+		if err := os.WriteFile(path, []byte(header+rendered), info.Mode().Perm()); err != nil {
+			return fmt.Errorf("写入模板渲染结果失败: %w", err)
+		}
+		return nil
+	})
+	return missing, templateErrs, err
+}
+
+// splitRenderedHeader 把安装时注入的「勿编辑」注释与模板正文分开，
+// 使模板只看到 cache 中的原文，错误行号与 .dec/cache 中的文件一致。
+func splitRenderedHeader(content string) (string, string) {
+	if !strings.HasPrefix(content, "<!--") {
+		return "", content
+	}
+	end := strings.Index(content, "-->\n\n")
+	if end < 0 || !strings.Contains(content[:end], renderedHeaderMarker) {
+		return "", content
+	}
+	end += len("-->\n\n")
+	return content[:end], content[end:]
+}
+
+// assetUsesTemplate 判断 cache 中的资产是否有文件选择了模板渲染。
+func assetUsesTemplate(cachePath string, tmpl assetTemplate) bool {
+	if tmpl.OptIn {
+		return true
+	}
+	found := false
+	_ = filepath.Walk(cachePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || found {
+			return err
+		}
+		if data, err := os.ReadFile(path); err == nil && vars.IsTemplateOptIn(string(data)) {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
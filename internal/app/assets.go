@@ -206,7 +206,7 @@ func SaveWorkspaceEnabledBundles(workspace Workspace, bundles []string, reporter
 	// 否则每次 pull 只会得到一句「引用的 bundle 找不到声明，已忽略」。
 	requested := normalizeEnabledBundles(bundles)
 	emit(reporter, EventInfo, "assets.save", "校验仓库 bundle 声明", nil)
-	rejected, err := validateProjectEnabledBundles(requested, reporter)
+	rejected, err := validateProjectEnabledBundles(projectConfig, requested, reporter)
 	if err != nil {
 		return nil, err
 	}
@@ -245,7 +245,8 @@ func loadBundleSelection(projectConfig *types.ProjectConfig, reporter Reporter)
 }
 
 func loadBundleSelectionForPlane(projectConfig *types.ProjectConfig, plane WorkspacePlane, reporter Reporter) []AssetBundleOption {
-	tx, err := repo.NewLocalReadTransaction()
+	// 与 pull 读同一条频道：只在跟随分支上存在的 bundle 也要能看到、能勾选。
+	tx, err := newManifestReadTransaction(emitLocalReadVaultBranch(projectConfig, reporter, "assets.bundle"))
 	if err != nil {
 		emit(reporter, EventWarn, "assets.bundle",
 			fmt.Sprintf("打开仓库只读事务失败，Bundles 页将不展示 bundle: %v", err), nil)
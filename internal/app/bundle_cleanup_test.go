@@ -112,7 +112,7 @@ bundles:
 	}
 
 	// vault projects 不再引用 pkv
-	if err := withAppReadRepo(func(tx *repo.Transaction) error {
+	if err := withAppReadRepo("", func(tx *repo.Transaction) error {
 		for _, name := range []string{"Dec", "Other"} {
 			proj, ok, loadErr := LoadVaultProject(tx.WorkDir(), name)
 			if loadErr != nil || !ok {
@@ -182,7 +182,7 @@ bundles:
 	if _, err := os.Stat(filepath.Join(projectRoot, ".secrets", "bundles", "pkv")); !os.IsNotExist(err) {
 		t.Fatalf("本地 secrets 应已清")
 	}
-	if err := withAppReadRepo(func(tx *repo.Transaction) error {
+	if err := withAppReadRepo("", func(tx *repo.Transaction) error {
 		proj, ok, loadErr := LoadVaultProject(tx.WorkDir(), "Dec")
 		if loadErr != nil || !ok {
 			t.Fatalf("LoadVaultProject: ok=%v err=%v", ok, loadErr)
@@ -263,7 +263,7 @@ bundles:
 	_ = result
 
 	// Dec：vault 已无 pkv 声明 → resolve 忽略 enabled 中的 pkv，不应把 cache 写回 vault。
-	if err := withAppReadRepo(func(tx *repo.Transaction) error {
+	if err := withAppReadRepo("", func(tx *repo.Transaction) error {
 		if _, err := os.Stat(filepath.Join(tx.WorkDir(), "bundles", "pkv")); !os.IsNotExist(err) {
 			t.Fatalf("push 不应复活 bundles/pkv")
 		}
@@ -1,8 +1,8 @@
 // bundle_resolver.go 负责把 ProjectConfig.enabled_bundles 解析为本轮 pull 的目标资产集合，
 // 并记录每个资产的来源（bundle/<name>）。
 //
-// 本文件只做「想装哪些资产」的解析；真正的装卸仍由 operations.go 内的 installAssetToIDEs
-// 与 cleanupRemovedAssets 负责。同一资产被多个 bundle 引用时来源会叠加，
+// 本文件只做「想装哪些资产」的解析；真正的装卸仍由 render_stage.go 的渲染暂存区
+// 与 operations.go 的 cleanupRemovedAssets 负责。同一资产被多个 bundle 引用时来源会叠加，
 // 只要任何 bundle 仍引用它，它就会出现在目标集里，不会被清理掉。
 package app
 
@@ -13,6 +13,7 @@ import (
 	"sort"
 
 	"github.com/shichao402/Dec/internal/bundle"
+	"github.com/shichao402/Dec/internal/repo"
 	"github.com/shichao402/Dec/internal/types"
 )
 
@@ -241,8 +242,8 @@ func assetFileExists(repoDir, vault, itemType, name string) bool {
 	if path == "" {
 		return false
 	}
-	_, err := os.Stat(path)
-	return err == nil
+	// 稀疏只读事务里未物化的成员按提交索引判断
+	return repo.PathExists(path)
 }
 
 func assetKey(asset types.TypedAssetRef) string {
@@ -79,6 +79,16 @@ func (BundleWriter) MigrateProjectSecrets(ctx context.Context, input MigrateProj
 	return MigrateProjectSecretsToBundle(ctx, input, reporter)
 }
 
+// ImportUnmanagedAssets 把 IDE 里手写的非托管资产收编进 bundle（vault + .dec/cache）。
+func (BundleWriter) ImportUnmanagedAssets(ctx context.Context, input ImportUnmanagedInput, reporter Reporter) (*ImportUnmanagedResult, error) {
+	return ImportUnmanagedAssets(ctx, input, reporter)
+}
+
+// ImportGlobalMCPServers 把用户级 MCP 配置里的 server 规范化后写入 bundle 的 mcp/。
+func (BundleWriter) ImportGlobalMCPServers(ctx context.Context, input ImportGlobalMCPInput, reporter Reporter) (*ImportGlobalMCPResult, error) {
+	return ImportGlobalMCPServers(ctx, input, reporter)
+}
+
 // CleanupUnmanaged 删除非托管裸 folder 内容（只删 BW，不创建 Bundle）。文案钉死「非模型内写入」。
 func (BundleWriter) CleanupUnmanaged(ctx context.Context, input DeleteProjectInput, reporter Reporter) (*DeleteProjectResult, error) {
 	input.Mode = "remote"
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/shichao402/Dec/internal/bundle"
+	"github.com/shichao402/Dec/internal/config"
+)
+
+// DefaultCommitTemplate 是 push 提交说明的默认模板（text/template），全局配置 commit_template 可覆盖。
+// 首行是摘要，正文列出涉及的 bundle 与资产，末尾的 Dec-* 尾注便于按项目 / 机器 / 版本检索 vault 历史。
+const DefaultCommitTemplate = `push({{.Project}}): {{.Title}}
+{{- if .Body}}
+
+{{.Body}}
+{{- end}}
+{{- if .Bundles}}
+{{range .Bundles}}
+- {{.Name}}: {{join .Assets ", "}}
+{{- end}}
+{{- end}}
+
+Dec-Project: {{.Project}}
+Dec-Plane: {{.Plane}}
+Dec-Host: {{.Host}}
+Dec-Version: {{.Version}}
+`
+
+// commitSummaryAssetLimit 是自动摘要里单个 bundle 最多点名的资产数。
+const commitSummaryAssetLimit = 3
+
+// decVersion 是写进提交说明的 Dec 版本，由 dec-server 启动时注入。
+var decVersion = "dev"
+
+// SetDecVersion 设置提交说明里记录的 Dec 版本；空串保持 dev。
+func SetDecVersion(version string) {
+	if version = strings.TrimSpace(version); version != "" {
+		decVersion = version
+	}
+}
+
+// commitMessageData 是提交说明模板可用的字段。
+type commitMessageData struct {
+	// Title 是用户说明的首行，未填写时为 Summary。
+	Title string
+	// Body 是用户说明首行之后的部分。
+	Body string
+	// Message 是用户填写的完整说明，可能为空。
+	Message string
+	// Summary 是按改动自动生成的一句话摘要。
+	Summary string
+	Project string
+	Plane   string
+	Host    string
+	Version string
+	// Branch 是提交推往的分支（branch 策略下为评审分支）。
+	Branch   string
+	Bundles  []commitBundle
+	Files    int
+	Added    int
+	Modified int
+	Deleted  int
+}
+
+// commitBundle 是一个 bundle 内被改动的资产，Assets 形如 rule/alpha；非资产文件记文件名。
+type commitBundle struct {
+	Name   string
+	Assets []string
+	Files  int
+}
+
+// newCommitMessageData 按改动列表汇总模板字段。
+func newCommitMessageData(workspace Workspace, project, branch, message string, changes []VaultFileChange) commitMessageData {
+	message = strings.TrimSpace(message)
+	data := commitMessageData{
+		Message: message,
+		Project: project,
+		Plane:   string(workspace.EffectivePlane()),
+		Host:    commitHostname(),
+		Version: decVersion,
+		Branch:  branch,
+		Files:   len(changes),
+	}
+	byBundle := make(map[string]*commitBundle)
+	seen := make(map[string]bool)
+	for _, change := range changes {
+		switch change.Status {
+		case RenderedFileAdded:
+			data.Added++
+		case RenderedFileDeleted:
+			data.Deleted++
+		default:
+			data.Modified++
+		}
+		entry := byBundle[change.Bundle]
+		if entry == nil {
+			entry = &commitBundle{Name: change.Bundle}
+			byBundle[change.Bundle] = entry
+		}
+		entry.Files++
+		asset := vaultChangeAsset(change.Path)
+		if key := change.Bundle + "\x00" + asset; !seen[key] {
+			seen[key] = true
+			entry.Assets = append(entry.Assets, asset)
+		}
+	}
+	for _, entry := range byBundle {
+		sort.Strings(entry.Assets)
+		data.Bundles = append(data.Bundles, *entry)
+	}
+	sort.Slice(data.Bundles, func(i, j int) bool { return data.Bundles[i].Name < data.Bundles[j].Name })
+
+	data.Summary = commitSummary(data)
+	data.Title, data.Body, _ = strings.Cut(message, "\n")
+	data.Title = strings.TrimSpace(data.Title)
+	data.Body = strings.TrimSpace(data.Body)
+	if data.Title == "" {
+		data.Title = data.Summary
+	}
+	return data
+}
+
+// vaultChangeAsset 把 bundles/<name>/<dir>/<entry>... 映射为 type/name；bundle.yaml 等非资产文件返回相对 bundle 的路径。
+func vaultChangeAsset(rel string) string {
+	_, rest, ok := splitVaultBundlePath(rel)
+	if !ok {
+		return rel
+	}
+	dir, entry, found := strings.Cut(rest, "/")
+	kind, known := bundle.KindByDir(dir)
+	if !found || !known {
+		return rest
+	}
+	if kind.DirEntries {
+		entry, _, _ = strings.Cut(entry, "/")
+	}
+	return kind.Type + "/" + bundle.AssetEntryName(kind, entry)
+}
+
+// commitSummary 生成一句话摘要：单个 bundle 点名资产，多个 bundle 只列 bundle。
+func commitSummary(data commitMessageData) string {
+	switch len(data.Bundles) {
+	case 0:
+		return "更新 vault"
+	case 1:
+		only := data.Bundles[0]
+		assets := only.Assets
+		if len(assets) > commitSummaryAssetLimit {
+			return fmt.Sprintf("更新 %s：%s 等 %d 项", only.Name, strings.Join(assets[:commitSummaryAssetLimit], ", "), len(assets))
+		}
+		return fmt.Sprintf("更新 %s：%s", only.Name, strings.Join(assets, ", "))
+	default:
+		names := make([]string, len(data.Bundles))
+		for i, entry := range data.Bundles {
+			names[i] = entry.Name
+		}
+		return fmt.Sprintf("更新 %s（%d 个文件）", strings.Join(names, "、"), data.Files)
+	}
+}
+
+func commitHostname() string {
+	if host, err := os.Hostname(); err == nil && strings.TrimSpace(host) != "" {
+		return strings.TrimSpace(host)
+	}
+	return "unknown"
+}
+
+var commitTemplateFuncs = template.FuncMap{"join": strings.Join}
+
+// renderCommitMessage 用全局配置的 commit_template 渲染提交说明；模板无效或渲染为空时告警并回落默认模板。
+func renderCommitMessage(data commitMessageData, reporter Reporter, scope string) string {
+	text := DefaultCommitTemplate
+	if globalConfig, err := config.LoadGlobalConfig(); err == nil && strings.TrimSpace(globalConfig.CommitTemplate) != "" {
+		text = globalConfig.CommitTemplate
+	}
+	message, err := executeCommitTemplate(text, data)
+	if err == nil && message == "" {
+		err = fmt.Errorf("渲染结果为空")
+	}
+	if err != nil && text != DefaultCommitTemplate {
+		emit(reporter, EventWarn, scope, fmt.Sprintf("⚠️  全局配置 commit_template 无效（%v），已使用默认模板", err), nil)
+		message, err = executeCommitTemplate(DefaultCommitTemplate, data)
+	}
+	if err != nil || message == "" {
+		return "push: " + data.Title
+	}
+	return message
+}
+
+func executeCommitTemplate(text string, data commitMessageData) (string, error) {
+	tmpl, err := template.New("commit").Funcs(commitTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	message := strings.TrimSpace(b.String())
+	if message == "" {
+		return "", nil
+	}
+	return message + "\n", nil
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestNewCommitMessageDataSummarizesBundlesAndAssets(t *testing.T) {
+	changes := []VaultFileChange{
+		{Bundle: "combo", Path: "bundles/combo/rules/alpha.mdc", Status: RenderedFileModified},
+		{Bundle: "combo", Path: "bundles/combo/skills/writer/SKILL.md", Status: RenderedFileAdded},
+		{Bundle: "combo", Path: "bundles/combo/skills/writer/ref.md", Status: RenderedFileAdded},
+		{Bundle: "combo", Path: "bundles/combo/bundle.yaml", Status: RenderedFileModified},
+		{Bundle: "tools", Path: "bundles/tools/mcp/search.json", Status: RenderedFileDeleted},
+	}
+	data := newCommitMessageData(NewWorkspace(WorkspaceProject, "/tmp/demo"), "demo", "main", "", changes)
+	if data.Files != 5 || data.Added != 2 || data.Modified != 2 || data.Deleted != 1 {
+		t.Fatalf("统计 = %+v", data)
+	}
+	if len(data.Bundles) != 2 || strings.Join(data.Bundles[0].Assets, ",") != "bundle.yaml,rule/alpha,skill/writer" || data.Bundles[1].Assets[0] != "mcp/search" {
+		t.Fatalf("bundle 汇总 = %+v", data.Bundles)
+	}
+	if data.Title != data.Summary || data.Summary != "更新 combo、tools（5 个文件）" {
+		t.Fatalf("摘要 = %q / %q", data.Title, data.Summary)
+	}
+
+	message, err := executeCommitTemplate(DefaultCommitTemplate, data)
+	if err != nil {
+		t.Fatalf("默认模板渲染失败: %v", err)
+	}
+	for _, want := range []string{
+		"push(demo): 更新 combo、tools（5 个文件）\n",
+		"- combo: bundle.yaml, rule/alpha, skill/writer\n",
+		"- tools: mcp/search\n",
+		"Dec-Project: demo\n",
+		"Dec-Plane: project\n",
+		"Dec-Version: " + decVersion + "\n",
+	} {
+		if !strings.Contains(message, want) {
+			t.Fatalf("提交说明缺少 %q:\n%s", want, message)
+		}
+	}
+	if strings.Contains(message, "\n\n\n") {
+		t.Fatalf("提交说明不应有连续空行:\n%s", message)
+	}
+
+	data = newCommitMessageData(NewWorkspace(WorkspaceUser, ""), "user", "main", "收紧 alpha 规则\n\n原因：误报太多", changes[:1])
+	message, err = executeCommitTemplate(DefaultCommitTemplate, data)
+	if err != nil {
+		t.Fatalf("默认模板渲染失败: %v", err)
+	}
+	if !strings.HasPrefix(message, "push(user): 收紧 alpha 规则\n\n原因：误报太多\n\n- combo: rule/alpha\n") {
+		t.Fatalf("用户说明应进入标题与正文:\n%s", message)
+	}
+}
+
+func TestRenderCommitMessageUsesGlobalTemplateAndFallsBack(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	data := newCommitMessageData(NewWorkspace(WorkspaceProject, "/tmp/demo"), "demo", "main", "tweak", []VaultFileChange{
+		{Bundle: "combo", Path: "bundles/combo/rules/alpha.mdc", Status: RenderedFileModified},
+	})
+
+	if err := config.SaveGlobalConfig(&types.GlobalConfig{CommitTemplate: "[{{.Project}}] {{.Title}} ({{.Files}} files @ {{.Branch}})"}); err != nil {
+		t.Fatalf("SaveGlobalConfig() 失败: %v", err)
+	}
+	if got := renderCommitMessage(data, nil, "push.dec"); got != "[demo] tweak (1 files @ main)\n" {
+		t.Fatalf("自定义模板渲染 = %q", got)
+	}
+
+	if err := config.SaveGlobalConfig(&types.GlobalConfig{CommitTemplate: "{{.NoSuchField}}"}); err != nil {
+		t.Fatalf("SaveGlobalConfig() 失败: %v", err)
+	}
+	var warnings []string
+	got := renderCommitMessage(data, ReporterFunc(func(event OperationEvent) {
+		if event.Level == EventWarn {
+			warnings = append(warnings, event.Message)
+		}
+	}), "push.dec")
+	if !strings.HasPrefix(got, "push(demo): tweak\n") || len(warnings) != 1 || !strings.Contains(warnings[0], "commit_template") {
+		t.Fatalf("无效模板应回落默认模板并告警: %q / %v", got, warnings)
+	}
+}
@@ -693,8 +693,9 @@ func deleteSSHKeyItem(ctx context.Context, decBundleName, secretsBundleName, key
 	return nil
 }
 
-func withAppReadRepo(fn func(*repo.Transaction) error) error {
-	tx, err := repo.NewLocalReadTransaction()
+// withAppReadRepo 在本地 vault 镜像的 branch（空串为远端默认分支）上开只读事务，不 fetch。
+func withAppReadRepo(branch string, fn func(*repo.Transaction) error) error {
+	tx, err := repo.NewLocalReadTransactionOn(branch)
 	if err != nil {
 		return err
 	}
@@ -717,6 +718,7 @@ func deleteLocalDecAssetOnly(workspace Workspace, itemType, name, vault string,
 			emit(reporter, EventWarn, "delete.dec", fmt.Sprintf("IDE %s 清理失败: %v", ideImpl.Name(), err), nil)
 		}
 	}
+	removeStagedAsset(workspace, itemType, name)
 	cachePath := getWorkspaceCachePath(workspace, vault, itemType, name)
 	if cachePath == "" {
 		return nil
@@ -743,6 +745,7 @@ func deleteLocalBundleOnly(workspace Workspace, bundleName string, members []Ass
 				emit(reporter, EventWarn, "delete.bundle", fmt.Sprintf("IDE %s 清理 %s 失败: %v", ideImpl.Name(), member.Name, err), nil)
 			}
 		}
+		removeStagedAsset(workspace, member.Type, member.Name)
 	}
 	cacheBundleDir := filepath.Join(workspaceCacheDir(workspace), bundleName)
 	if _, err := os.Stat(cacheBundleDir); err == nil {
@@ -752,7 +755,7 @@ func deleteLocalBundleOnly(workspace Workspace, bundleName string, members []Ass
 		emit(reporter, EventInfo, "delete.bundle", "已删本地 bundle 缓存", nil)
 	}
 	// vault 目录已不在时，仍尽量摘掉 projects/*.yaml 残留声明，阻断 ApplyVaultProject 再启用。
-	if err := withAppWriteRepo(func(tx *repo.Transaction) error {
+	if err := withWorkspaceWriteRepo(workspace, reporter, "delete.bundle", func(tx *repo.Transaction, commit vaultCommitFunc) error {
 		pruned, pruneErr := pruneBundleFromVaultProjects(tx.WorkDir(), bundleName)
 		if pruneErr != nil {
 			return pruneErr
@@ -760,7 +763,7 @@ func deleteLocalBundleOnly(workspace Workspace, bundleName string, members []Ass
 		if len(pruned) == 0 {
 			return nil
 		}
-		if _, commitErr := tx.CommitAndPush(fmt.Sprintf("chore(projects): drop removed bundle %s", bundleName)); commitErr != nil {
+		if _, commitErr := commit(fmt.Sprintf("chore(projects): drop removed bundle %s", bundleName)); commitErr != nil {
 			return commitErr
 		}
 		emit(reporter, EventInfo, "delete.bundle",
@@ -837,7 +840,7 @@ func deleteRemoteBundleOnly(projectRoot string, plane WorkspacePlane, bundleName
 	if bundleName == "" {
 		return nil, fmt.Errorf("bundle 名称不能为空")
 	}
-	if err := withAppWriteRepo(func(tx *repo.Transaction) error {
+	if err := withWorkspaceWriteRepo(NewWorkspace(plane, projectRoot), reporter, "delete.bundle", func(tx *repo.Transaction, commit vaultCommitFunc) error {
 		repoDir := tx.WorkDir()
 		bundlePath := filepath.Join(repoDir, types.VaultBundlesDir, bundleName)
 		if _, err := os.Stat(bundlePath); err != nil {
@@ -858,7 +861,7 @@ func deleteRemoteBundleOnly(projectRoot string, plane WorkspacePlane, bundleName
 			return fmt.Errorf("从 projects 声明摘除 bundle 失败: %w", pruneErr)
 		}
 		result.PrunedProjects = pruned
-		if _, err := tx.CommitAndPush(fmt.Sprintf("remove bundle: %s", bundleName)); err != nil {
+		if _, err := commit(fmt.Sprintf("remove bundle: %s", bundleName)); err != nil {
 			return fmt.Errorf("提交失败: %w", err)
 		}
 		result.VersionCommit = tx.CommitHash()
@@ -867,7 +870,6 @@ func deleteRemoteBundleOnly(projectRoot string, plane WorkspacePlane, bundleName
 	}); err != nil {
 		return nil, err
 	}
-	_ = plane
 	return result, nil
 }
 
@@ -880,7 +882,7 @@ func deleteRemoteDecAssetOnly(projectRoot string, plane WorkspacePlane, itemType
 		Name:        name,
 		Vault:       vault,
 	}
-	if err := withAppWriteRepo(func(tx *repo.Transaction) error {
+	if err := withWorkspaceWriteRepo(NewWorkspace(plane, projectRoot), reporter, "delete.dec", func(tx *repo.Transaction, commit vaultCommitFunc) error {
 		repoDir := tx.WorkDir()
 		foundVault, fullPath, err := locateAssetInRepo(repoDir, itemType, name, vault)
 		if err != nil {
@@ -897,7 +899,7 @@ func deleteRemoteDecAssetOnly(projectRoot string, plane WorkspacePlane, itemType
 				return fmt.Errorf("从 projects 声明摘除空 bundle 失败: %w", pruneErr)
 			}
 		}
-		if _, err := tx.CommitAndPush(fmt.Sprintf("remove: %s/%s", foundVault, name)); err != nil {
+		if _, err := commit(fmt.Sprintf("remove: %s/%s", foundVault, name)); err != nil {
 			return fmt.Errorf("提交失败: %w", err)
 		}
 		result.VersionCommit = tx.CommitHash()
@@ -906,7 +908,6 @@ func deleteRemoteDecAssetOnly(projectRoot string, plane WorkspacePlane, itemType
 	}); err != nil {
 		return nil, err
 	}
-	_ = plane
 	return result, nil
 }
 
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 
+	"github.com/shichao402/Dec/internal/ide"
 	"github.com/shichao402/Dec/internal/secrets"
 	"github.com/shichao402/Dec/internal/sysproc"
 )
@@ -17,6 +19,8 @@ type ExecWithSecretsInput struct {
 	Plane       secrets.SyncPlane // 空则默认 project
 	Command     []string          // 目标命令 argv；不可为空
 	Environ     []string          // 可选基环境；nil 则用 os.Environ()
+	// Env 是 KEY=VALUE 形式的额外 env，VALUE 里的规范引用（${VAR}、${VAR:-default}）在注入 secrets 后展开
+	Env []string
 }
 
 // BuildExecEnviron 构造注入后的环境变量列表（不打印值）。
@@ -55,6 +59,43 @@ func BuildExecEnviron(projectRoot, bundle string, plane secrets.SyncPlane, base
 	return out, nil
 }
 
+// applyExecEnv 按顺序把 KEY=VALUE 追加到 environ，VALUE 中的引用对已注入 secrets 的环境展开；同名键被覆盖。
+func applyExecEnv(environ, extra []string) ([]string, error) {
+	if len(extra) == 0 {
+		return environ, nil
+	}
+	values := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			values[k] = v
+		}
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+	for _, kv := range extra {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("--env 需要 KEY=VALUE 格式: %q", kv)
+		}
+		values[k] = ide.ExpandEnvRefs(v, lookup)
+	}
+	out := make([]string, 0, len(values))
+	for _, kv := range environ {
+		if k, _, ok := strings.Cut(kv, "="); ok {
+			if _, seen := values[k]; seen {
+				continue
+			}
+		}
+		out = append(out, kv)
+	}
+	for _, k := range sortedKeys(values) {
+		out = append(out, k+"="+values[k])
+	}
+	return out, nil
+}
+
 // RunExecWithSecrets 注入 env 后执行命令，返回进程退出码。
 func RunExecWithSecrets(input ExecWithSecretsInput) (int, error) {
 	if strings.TrimSpace(input.ProjectRoot) == "" {
@@ -67,6 +108,10 @@ func RunExecWithSecrets(input ExecWithSecretsInput) (int, error) {
 	if err != nil {
 		return 1, err
 	}
+	env, err = applyExecEnv(env, input.Env)
+	if err != nil {
+		return 1, err
+	}
 	cmd := sysproc.Command(input.Command[0], input.Command[1:]...)
 	cmd.Dir = input.ProjectRoot
 	cmd.Env = env
@@ -109,6 +154,24 @@ func WrapMCPServerWithExecForPlane(projectRoot, bundle string, plane secrets.Syn
 		return strings.ReplaceAll(value, workspaceFolder, rootRef)
 	}
 
+	// 保留非密钥类显式 env 和 ${workspaceFolder}；同名透传（K = ${K}）去掉，由 dec-exec 继承或注入；
+	// ${input:id} 只有 VS Code 能展开，留在 IDE 配置里；其它引用（默认值、改名、拼接）改由 dec-exec 的 --env 展开。
+	cleanEnv := make(map[string]string)
+	var execEnv []string
+	for _, k := range sortedKeys(env) {
+		v := env[k]
+		withoutWorkspace := strings.ReplaceAll(v, workspaceFolder, "")
+		switch {
+		case !strings.Contains(withoutWorkspace, "${"):
+			cleanEnv[k] = resolveRoot(v)
+		case ide.IsInheritedEnvRef(k, v):
+		case ide.HasInputRefs(v):
+			cleanEnv[k] = resolveRoot(v)
+		default:
+			execEnv = append(execEnv, "--env", k+"="+resolveRoot(v))
+		}
+	}
+
 	wrappedArgs := []string{
 		"--project-root", rootRef,
 	}
@@ -118,18 +181,19 @@ func WrapMCPServerWithExecForPlane(projectRoot, bundle string, plane secrets.Syn
 	if strings.TrimSpace(bundle) != "" {
 		wrappedArgs = append(wrappedArgs, "--bundle", bundle)
 	}
+	wrappedArgs = append(wrappedArgs, execEnv...)
 	wrappedArgs = append(wrappedArgs, "--", resolveRoot(command))
 	for _, arg := range args {
 		wrappedArgs = append(wrappedArgs, resolveRoot(arg))
 	}
-	// 保留非密钥类显式 env 和 ${workspaceFolder}；其它 ${VAR} 占位去掉（由 dec-exec 注入）。
-	cleanEnv := make(map[string]string)
-	for k, v := range env {
-		withoutWorkspace := strings.ReplaceAll(v, workspaceFolder, "")
-		if strings.Contains(withoutWorkspace, "${") {
-			continue
-		}
-		cleanEnv[k] = resolveRoot(v)
-	}
 	return decBin, wrappedArgs, cleanEnv
 }
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -52,6 +52,44 @@ func TestWrapMCPServerWithExec_WrapsCommandAndStripsPlaceholders(t *testing.T) {
 	if _, ok := env["MIXED_PLACEHOLDERS"]; ok {
 		t.Fatalf("含其它变量占位符的 env 仍应去掉: %#v", env)
 	}
+	if !strings.Contains(joined, "--env MIXED_PLACEHOLDERS=${workspaceFolder}/${TOKEN} -- npx") {
+		t.Fatalf("含其它变量占位符的 env 应改由 dec-exec --env 展开: %#v", args)
+	}
+	if strings.Contains(joined, "VIKUNJA_URL") {
+		t.Fatalf("同名透传的 env 不应再传给 dec-exec: %#v", args)
+	}
+}
+
+func TestApplyExecEnvExpandsRefsAfterSecrets(t *testing.T) {
+	env, err := applyExecEnv(
+		[]string{"PATH=/bin", "TOKEN=secret", "EMPTY="},
+		[]string{"API_KEY=${TOKEN}", "HOST=${DB_HOST:-localhost}", "MODE=${EMPTY:-prod}", "AUTH=Bearer ${env:TOKEN}", "ASK=${input:pick}"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		got[k] = v
+	}
+	want := map[string]string{
+		"PATH":    "/bin",
+		"TOKEN":   "secret",
+		"API_KEY": "secret",
+		"HOST":    "localhost",
+		"MODE":    "prod",
+		"AUTH":    "Bearer secret",
+		"ASK":     "${input:pick}",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s = %q, want %q (env=%v)", k, got[k], v, env)
+		}
+	}
+	if _, err := applyExecEnv(nil, []string{"NOEQUALS"}); err == nil {
+		t.Fatal("缺少 = 的 --env 应报错")
+	}
 }
 
 func TestWrapMCPServerWithExecForPlane_UserPlaneResolvesWorkspacePlaceholder(t *testing.T) {
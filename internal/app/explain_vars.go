@@ -0,0 +1,201 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/bundle"
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/types"
+	"github.com/shichao402/Dec/internal/vars"
+)
+
+// ExplainVarsInput 指定要解释的资产；Bundle 为空时在 .dec/cache 中按名查找。
+type ExplainVarsInput struct {
+	ProjectRoot string
+	AssetType   string
+	AssetName   string
+	Bundle      string
+}
+
+// VarsExplanation 是 explain_vars 的结果：资产内每个占位符的生效值、来源层与文件，
+// 以及被它遮蔽的其余定义。
+type VarsExplanation struct {
+	ProjectRoot string
+	AssetType   string
+	AssetName   string
+	Bundle      string
+	// CachePath 是被扫描的模板（.dec/cache 下），占位符与 default 都取自这里
+	CachePath string
+	Vars      []vars.Explanation
+	Warnings  []string
+}
+
+// ExplainVars 解释某个已 pull 资产的变量解析过程。只读本地文件与本地 vault 镜像，不联网。
+// 层的顺序与 pull 时 substituteAssetVars 使用的 vars.ResolveLayers 一致，只是把
+// 项目层拆成 vars.yaml 与各 vars.d 片段，以便指出具体文件。
+func ExplainVars(input ExplainVarsInput) (*VarsExplanation, error) {
+	projectRoot := strings.TrimSpace(input.ProjectRoot)
+	if projectRoot == "" {
+		return nil, fmt.Errorf("变量溯源需要项目根目录：用户平面不做 vars 替换")
+	}
+	assetType := strings.TrimSpace(input.AssetType)
+	assetName := strings.TrimSpace(input.AssetName)
+	if _, ok := bundle.KindByType(assetType); !ok {
+		return nil, fmt.Errorf("不支持的资产类型 %q，仅允许 skill / command / rule / mcp", input.AssetType)
+	}
+	if assetName == "" {
+		return nil, fmt.Errorf("资产名不能为空")
+	}
+
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	result := &VarsExplanation{ProjectRoot: projectRoot, AssetType: assetType, AssetName: assetName}
+
+	bundleName := strings.TrimSpace(input.Bundle)
+	if bundleName == "" {
+		var candidates []string
+		for _, asset := range listCachedAssets(workspaceCacheDir(workspace)) {
+			if asset.Type == assetType && asset.Name == assetName {
+				candidates = append(candidates, asset.Vault)
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			return nil, fmt.Errorf("资产 %s/%s 不在 .dec/cache 中，请先 pull", assetType, assetName)
+		case 1:
+		default:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("多个 bundle 都有 %s/%s（%s），按 %s 解释；可指定 bundle", assetType, assetName, strings.Join(candidates, ", "), candidates[0]))
+		}
+		bundleName = candidates[0]
+	}
+	result.Bundle = bundleName
+
+	cachePath := getWorkspaceCachePath(workspace, bundleName, assetType, assetName)
+	info, err := os.Stat(cachePath)
+	if err != nil {
+		return nil, fmt.Errorf("资产 %s/%s (bundle: %s) 不在 .dec/cache 中，请先 pull", assetType, assetName, bundleName)
+	}
+	result.CachePath = cachePath
+
+	var (
+		placeholders []string
+		defaults     map[string]string
+	)
+	if info.IsDir() {
+		placeholders = vars.ExtractPlaceholdersFromDir(cachePath)
+		defaults = vars.ExtractPlaceholderDefaultsFromDir(cachePath)
+	} else {
+		placeholders = vars.ExtractPlaceholdersFromFile(cachePath)
+		if data, err := os.ReadFile(cachePath); err == nil {
+			defaults = vars.ExtractPlaceholderDefaults(string(data))
+		}
+	}
+	sort.Strings(placeholders)
+
+	layers, warnings := explainVarsLayers(projectRoot, assetType, assetName, bundleName)
+	result.Warnings = append(result.Warnings, warnings...)
+	layers = append(layers, vars.Layer{Source: vars.SourceDefault, File: cachePath, Vars: defaults})
+	result.Vars = vars.Explain(layers, placeholders)
+	return result, nil
+}
+
+// explainVarsLayers 按优先级列出某资产可见的全部变量层（不含模板里的 default）。
+func explainVarsLayers(projectRoot, assetType, assetName, bundleName string) ([]vars.Layer, []string) {
+	var (
+		layers   []vars.Layer
+		warnings []string
+	)
+	mgr := config.NewProjectConfigManager(projectRoot)
+
+	varsPath := mgr.GetVarsPath()
+	mainVars, err := vars.LoadVarsFile(varsPath)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("解析 %s 失败: %v", varsPath, err))
+		mainVars = &types.VarsConfig{}
+	}
+	projectConfig, err := mgr.LoadProjectConfig()
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("加载项目配置失败: %v", err))
+	}
+	var profileVars map[string]string
+	if projectConfig != nil {
+		profileVars, _ = mainVars.ProfileVars(projectConfig.ActiveProfile)
+	}
+	layers = append(layers,
+		vars.Layer{Source: vars.SourceAsset, File: varsPath, Vars: vars.AssetSpecificVars(mainVars, assetType, assetName)},
+		vars.Layer{Source: vars.SourceProfile, File: varsPath, Vars: profileVars},
+		vars.Layer{Source: vars.SourceProject, File: varsPath, Vars: mainVars.Vars},
+	)
+
+	// vars.d 按字典序合并、后者覆盖前者，所以倒序排列才是优先级顺序
+	fragments, err := mgr.LoadVarsFragments()
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	for i := len(fragments) - 1; i >= 0; i-- {
+		layers = append(layers, vars.Layer{Source: vars.SourceProject, File: fragments[i].Path, Vars: fragments[i].Vars})
+	}
+
+	globalPath, _ := config.GetGlobalVarsPath()
+	if globalVars, err := config.LoadGlobalVars(); err != nil {
+		warnings = append(warnings, fmt.Sprintf("读取全局变量失败: %v", err))
+	} else if globalVars != nil {
+		layers = append(layers, vars.Layer{Source: vars.SourceGlobal, File: globalPath, Vars: globalVars.Vars})
+	}
+
+	if projectConfig != nil {
+		shared, sharedWarnings := loadLocalVaultSharedVars(projectRoot, projectConfig)
+		warnings = append(warnings, sharedWarnings...)
+		if shared != nil {
+			projectName, _ := ResolveProjectName(projectRoot, projectConfig)
+			layers = append(layers,
+				vars.Layer{Source: vars.SourceVaultProject, File: types.VaultProjectPath(projectName), Vars: shared.Project},
+				vars.Layer{Source: vars.SourceVaultBundle, File: types.VaultBundleVarsPath(bundleName), Vars: shared.Bundles[bundleName]},
+			)
+		}
+	}
+
+	// DEC_IDE 每个 IDE 一份，这里与 Project 页一样列出全部取值
+	ideValue := ""
+	if projectConfig != nil {
+		if selection, err := config.ResolveEffectiveIDEs(projectConfig); err == nil {
+			ideValue = strings.Join(selection.IDEs, " | ")
+		}
+	}
+	layers = append(layers, vars.Layer{Source: vars.SourceBuiltin, Vars: vars.WithBuiltinScope(projectBuiltinVars(projectRoot), bundleName, ideValue)})
+	return layers, warnings
+}
+
+// listCachedAssets 枚举 .dec/cache/<bundle>/<kind>/ 下的资产，按 bundle、类型、名称排序。
+func listCachedAssets(cacheDir string) []types.TypedAssetRef {
+	bundleEntries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return nil
+	}
+	var assets []types.TypedAssetRef
+	for _, bundleEntry := range bundleEntries {
+		if !bundleEntry.IsDir() || strings.HasPrefix(bundleEntry.Name(), ".") {
+			continue
+		}
+		for _, kind := range bundle.VaultAssetKinds {
+			entries, err := os.ReadDir(filepath.Join(cacheDir, bundleEntry.Name(), kind.Dir))
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.IsDir() != kind.DirEntries || strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				name := entry.Name()
+				if !kind.DirEntries {
+					name = bundle.AssetEntryName(kind, name)
+				}
+				assets = append(assets, types.TypedAssetRef{Type: kind.Type, AssetRef: types.AssetRef{Name: name, Vault: bundleEntry.Name()}})
+			}
+		}
+	}
+	return assets
+}
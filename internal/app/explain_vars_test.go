@@ -0,0 +1,72 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/vars"
+)
+
+func TestExplainVarsReportsWinnerFileAndShadowed(t *testing.T) {
+	decHome := t.TempDir()
+	setEnvForProjectTest(t, "DEC_HOME", decHome)
+	projectRoot := t.TempDir()
+	decDir := filepath.Join(projectRoot, ".dec")
+	mainPath := filepath.Join(decDir, "vars.yaml")
+	fragA := filepath.Join(decDir, "vars.d", "10-a.yaml")
+	fragB := filepath.Join(decDir, "vars.d", "20-b.yaml")
+	globalPath := filepath.Join(decHome, "local", "vars.yaml")
+	cachePath := filepath.Join(decDir, "cache", "team", "rules", "style.mdc")
+
+	writeFile(t, mainPath, "vars:\n  A: main-a\n  B: main-b\n  G: \"\"\nassets:\n  rule:\n    style:\n      vars:\n        A: asset-a\n")
+	writeFile(t, fragA, "vars:\n  B: frag-a-b\n  C: frag-a-c\n")
+	writeFile(t, fragB, "vars:\n  C: frag-b-c\n")
+	writeFile(t, globalPath, "vars:\n  A: global-a\n  D: global-d\n")
+	writeFile(t, cachePath, `{{A}} {{B}} {{C}} {{D}} {{E|default:"e"}} {{F}} {{G|default:"g"}} {{DEC_BUNDLE}}`)
+
+	result, err := ExplainVars(ExplainVarsInput{ProjectRoot: projectRoot, AssetType: "rule", AssetName: "style"})
+	if err != nil {
+		t.Fatalf("ExplainVars() 失败: %v", err)
+	}
+	if result.Bundle != "team" || result.CachePath != cachePath {
+		t.Fatalf("应在 cache 中按名找到 bundle: %+v", result)
+	}
+
+	got := make(map[string]vars.Explanation, len(result.Vars))
+	for _, explanation := range result.Vars {
+		got[explanation.Name] = explanation
+	}
+	wantWinners := map[string]vars.Candidate{
+		"A":          {Source: vars.SourceAsset, File: mainPath, Value: "asset-a"},
+		"B":          {Source: vars.SourceProject, File: mainPath, Value: "main-b"},
+		"C":          {Source: vars.SourceProject, File: fragB, Value: "frag-b-c"},
+		"D":          {Source: vars.SourceGlobal, File: globalPath, Value: "global-d"},
+		"E":          {Source: vars.SourceDefault, File: cachePath, Value: "e"},
+		"G":          {Source: vars.SourceDefault, File: cachePath, Value: "g"},
+		"DEC_BUNDLE": {Source: vars.SourceBuiltin, Value: "team"},
+	}
+	for name, want := range wantWinners {
+		if got[name].Winner == nil || *got[name].Winner != want {
+			t.Fatalf("%s 胜出者 = %+v, 期望 %+v", name, got[name].Winner, want)
+		}
+	}
+	if got["F"].Winner != nil {
+		t.Fatalf("F 未定义应为缺失: %+v", got["F"])
+	}
+
+	shadowedA := got["A"].Shadowed
+	if len(shadowedA) != 2 || shadowedA[0].Value != "main-a" || shadowedA[1].File != globalPath {
+		t.Fatalf("A 应遮蔽 vars.yaml 与机器级定义: %+v", shadowedA)
+	}
+	// 与 Substitute 一致：空值按未设置处理，由模板 default 生效
+	if shadowedG := got["G"].Shadowed; len(shadowedG) != 1 || shadowedG[0].File != mainPath || shadowedG[0].Value != "" {
+		t.Fatalf("G 的空值应被 default 遮蔽: %+v", shadowedG)
+	}
+	if shadowedC := got["C"].Shadowed; len(shadowedC) != 1 || shadowedC[0].File != fragA {
+		t.Fatalf("vars.d 后者应覆盖前者: %+v", shadowedC)
+	}
+
+	if _, err := ExplainVars(ExplainVarsInput{ProjectRoot: projectRoot, AssetType: "skill", AssetName: "style"}); err == nil {
+		t.Fatal("cache 中不存在的资产应报错")
+	}
+}
@@ -0,0 +1,388 @@
+package app
+
+// import_mcp.go 把用户级（全局）MCP 配置里的 server 导入 vault bundle。
+// 来源是各客户端自己的配置文件（Cursor ~/.cursor/mcp.json、Claude Desktop、VS Code、Codex config.toml 等），
+// 读取一律走 ide 包的加载器；每个 server 规范化成 vault mcp/<name>.json，字面量凭据不进 Git，
+// 改写成 ${VAR} 引用或交给 dec-exec 从 bundle 的 .env 注入。
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/ide"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// GlobalMCPServer 是在用户级 MCP 配置中发现的一个可导入 server；多个来源中的同名 server 合并为一项。
+type GlobalMCPServer struct {
+	Name string
+	// Sources 是出现该 server 的来源名（如 cursor、claude-desktop）。
+	Sources []string
+	// Transport 为 stdio | http | sse。
+	Transport string
+	Command   string
+	URL       string
+	// SecretEnvKeys / SecretHeaders 是检测到的字面量凭据；只列键名，不回传值。
+	SecretEnvKeys []string
+	SecretHeaders []string
+	// Conflicting 表示同名 server 在不同来源中配置不同；导入时取第一个来源的版本。
+	Conflicting bool
+}
+
+// GlobalMCPScan 是一次全局 MCP 配置扫描的结果。
+type GlobalMCPScan struct {
+	Servers []GlobalMCPServer
+	// SourcePaths 是实际读到的配置文件。
+	SourcePaths []string
+	Warnings    []string
+}
+
+// ImportGlobalMCPInput 描述一次全局 MCP 导入。Plane 为空视为项目平面。
+type ImportGlobalMCPInput struct {
+	ProjectRoot string
+	Plane       WorkspacePlane
+	Bundle      string
+	Names       []string
+	// ExtractSecrets 为 true 时把字面量凭据移入 bundle 的 .env；为 false 且存在凭据时拒绝导入。
+	ExtractSecrets bool
+}
+
+// ImportGlobalMCPResult 汇总一次全局 MCP 导入的结果。
+type ImportGlobalMCPResult struct {
+	Bundle         string
+	Imported       []string
+	BundleCreated  bool
+	BundleEnabled  bool
+	SecretEnvFiles []string
+	SecretKeys     []string
+	VersionCommit  string
+	Warnings       []string
+}
+
+// globalMCPCandidate 是规范化后的 server 及其剥离出的凭据（只在服务端内存中流转）。
+type globalMCPCandidate struct {
+	view    GlobalMCPServer
+	snippet types.MCPServer
+	secrets map[string]string
+	digest  string
+}
+
+var headerSecretKeyRe = regexp.MustCompile(`(?i)^(authorization|proxy-authorization|cookie)$`)
+
+// looksLikeSecretHeader 判断请求头是否携带字面量凭据。
+func looksLikeSecretHeader(key, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.Contains(value, "${") {
+		return false
+	}
+	return headerSecretKeyRe.MatchString(key) || secretEnvKeyRe.MatchString(key)
+}
+
+var nonEnvNameRe = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// headerSecretVar 为请求头凭据生成 .env 变量名：Authorization → <SERVER>_TOKEN，其余 → <SERVER>_<HEADER>。
+func headerSecretVar(server, header string) string {
+	suffix := header
+	if strings.EqualFold(header, "authorization") {
+		suffix = "token"
+	}
+	name := nonEnvNameRe.ReplaceAllString(server+"_"+suffix, "_")
+	return strings.ToUpper(strings.Trim(name, "_"))
+}
+
+// normalizeGlobalMCPServer 把来源配置转成 vault mcp/<name>.json 形态，并剥离字面量凭据。
+//
+//   - 去掉 dec-exec / mise exec 等外层启动器，安装时由 Dec 重新包装
+//   - headers 统一为 http_headers；stdio 的 type 字段省略
+//   - env 凭据从 env 删除（dec-exec 运行时注入）；请求头凭据改写为 ${VAR} 引用
+func normalizeGlobalMCPServer(name string, server types.MCPServer) (types.MCPServer, map[string]string, []string, []string) {
+	out := server
+	out.Command, out.Args = stripExternalEnvLauncher(server.Command, server.Args)
+	if len(out.Args) == 0 {
+		out.Args = nil
+	}
+	if strings.EqualFold(out.Type, "stdio") || out.URL == "" {
+		out.Type = ""
+	}
+
+	secretVars := make(map[string]string)
+	var envKeys, headerKeys []string
+	if len(server.Env) > 0 {
+		out.Env = make(map[string]string, len(server.Env))
+		for key, value := range server.Env {
+			if looksLikeSecretEnv(key, value) {
+				secretVars[key] = value
+				envKeys = append(envKeys, key)
+				continue
+			}
+			out.Env[key] = value
+		}
+		if len(out.Env) == 0 {
+			out.Env = nil
+		}
+	}
+
+	out.Headers = nil
+	out.HTTPHeaders, headerKeys = extractMCPHeaderSecrets(name, server, secretVars)
+	sort.Strings(envKeys)
+	return out, secretVars, envKeys, headerKeys
+}
+
+// extractMCPHeaderSecrets 把 headers 与 http_headers 合并为 http_headers 形态，字面量凭据换成 ${VAR} 引用
+// （Bearer 前缀保留），凭据值写入 secretVars；返回改写后的请求头（没有时为 nil）与被剥离的头名（已排序）。
+func extractMCPHeaderSecrets(name string, server types.MCPServer, secretVars map[string]string) (map[string]string, []string) {
+	headers := make(map[string]string, len(server.HTTPHeaders)+len(server.Headers))
+	for key, value := range server.HTTPHeaders {
+		headers[key] = value
+	}
+	for key, value := range server.Headers {
+		headers[key] = value
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(headers))
+	var headerKeys []string
+	for key, value := range headers {
+		if !looksLikeSecretHeader(key, value) {
+			out[key] = value
+			continue
+		}
+		varName := headerSecretVar(name, key)
+		trimmed := strings.TrimSpace(value)
+		if len(trimmed) > len("Bearer ") && strings.EqualFold(trimmed[:len("Bearer ")], "Bearer ") {
+			secretVars[varName] = strings.TrimSpace(trimmed[len("Bearer "):])
+			out[key] = "Bearer ${" + varName + "}"
+		} else {
+			secretVars[varName] = trimmed
+			out[key] = "${" + varName + "}"
+		}
+		headerKeys = append(headerKeys, key)
+	}
+	sort.Strings(headerKeys)
+	return out, headerKeys
+}
+
+// mcpSecretHeaderKeys 列出请求头中疑似字面量凭据的头名（已排序），只用于扫描展示。
+func mcpSecretHeaderKeys(server types.MCPServer) []string {
+	var keys []string
+	for _, headers := range []map[string]string{server.HTTPHeaders, server.Headers} {
+		for key, value := range headers {
+			if looksLikeSecretHeader(key, value) && !containsString(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func mcpTransport(server types.MCPServer) string {
+	if server.URL == "" {
+		return "stdio"
+	}
+	if strings.EqualFold(server.Type, "sse") {
+		return "sse"
+	}
+	return "http"
+}
+
+// ScanGlobalMCPServers 读取已知的用户级 MCP 配置，列出可导入的 server（跳过 Dec 自己的 dec / dec-* 条目）。
+func ScanGlobalMCPServers(reporter Reporter) (*GlobalMCPScan, error) {
+	reporter = defaultReporter(reporter)
+	candidates, scan, err := collectGlobalMCPServers()
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range scan.Warnings {
+		emit(reporter, EventWarn, "import.mcp", warning, nil)
+	}
+	for _, candidate := range candidates {
+		scan.Servers = append(scan.Servers, candidate.view)
+	}
+	return scan, nil
+}
+
+func collectGlobalMCPServers() ([]*globalMCPCandidate, *GlobalMCPScan, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取用户主目录失败: %w", err)
+	}
+	scan := &GlobalMCPScan{}
+	byName := make(map[string]*globalMCPCandidate)
+	var ordered []*globalMCPCandidate
+
+	for _, source := range ide.ExternalMCPSources(home) {
+		if _, err := os.Stat(source.Path); err != nil {
+			continue
+		}
+		config, err := ide.LoadExternalMCPSource(source, home)
+		if err != nil {
+			scan.Warnings = append(scan.Warnings, fmt.Sprintf("读取 %s (%s) 失败: %v", source.Name, source.Path, err))
+			continue
+		}
+		scan.SourcePaths = append(scan.SourcePaths, source.Path)
+
+		names := make([]string, 0, len(config.MCPServers))
+		for name := range config.MCPServers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if isDecOwnedEntryName(name) {
+				continue
+			}
+			if err := validateRemoteOwnerName("MCP", name); err != nil {
+				scan.Warnings = append(scan.Warnings, fmt.Sprintf("跳过 %s 中的 %q: %v", source.Name, name, err))
+				continue
+			}
+			snippet, secretVars, envKeys, headerKeys := normalizeGlobalMCPServer(name, config.MCPServers[name])
+			snippetJSON, _ := json.Marshal(snippet)
+			secretJSON, _ := json.Marshal(secretVars)
+			digest := string(snippetJSON) + "\x00" + string(secretJSON)
+
+			if existing, ok := byName[name]; ok {
+				existing.view.Sources = append(existing.view.Sources, source.Name)
+				if existing.digest != digest {
+					existing.view.Conflicting = true
+				}
+				continue
+			}
+			candidate := &globalMCPCandidate{
+				view: GlobalMCPServer{
+					Name:          name,
+					Sources:       []string{source.Name},
+					Transport:     mcpTransport(snippet),
+					Command:       snippet.Command,
+					URL:           snippet.URL,
+					SecretEnvKeys: envKeys,
+					SecretHeaders: headerKeys,
+				},
+				snippet: snippet,
+				secrets: secretVars,
+				digest:  digest,
+			}
+			byName[name] = candidate
+			ordered = append(ordered, candidate)
+		}
+	}
+
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].view.Name < ordered[j].view.Name })
+	return ordered, scan, nil
+}
+
+// ImportGlobalMCPServers 把选中的全局 MCP server 写入目标 bundle 的 mcp/<name>.json，凭据按需移入 .env。
+func ImportGlobalMCPServers(ctx context.Context, input ImportGlobalMCPInput, reporter Reporter) (*ImportGlobalMCPResult, error) {
+	reporter = defaultReporter(reporter)
+	workspace := NewWorkspace(input.Plane, input.ProjectRoot)
+	bundleName := strings.TrimSpace(input.Bundle)
+	if err := validateRemoteOwnerName("bundle", bundleName); err != nil {
+		return nil, err
+	}
+	if len(input.Names) == 0 {
+		return nil, fmt.Errorf("未选择要导入的 MCP server")
+	}
+
+	candidates, _, err := collectGlobalMCPServers()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*globalMCPCandidate, len(candidates))
+	for _, candidate := range candidates {
+		byName[candidate.view.Name] = candidate
+	}
+
+	result := &ImportGlobalMCPResult{Bundle: bundleName}
+	var selected []*globalMCPCandidate
+	seen := make(map[string]struct{}, len(input.Names))
+	var withSecrets []string
+	for _, raw := range input.Names {
+		name := strings.TrimSpace(raw)
+		if _, dup := seen[name]; dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		candidate, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("未在用户级 MCP 配置中找到 %q", name)
+		}
+		if candidate.view.Conflicting {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s 在 %s 中配置不一致，已取 %s 的版本",
+				name, strings.Join(candidate.view.Sources, " / "), candidate.view.Sources[0]))
+		}
+		if len(candidate.secrets) > 0 {
+			withSecrets = append(withSecrets, name)
+		}
+		selected = append(selected, candidate)
+	}
+	if len(withSecrets) > 0 && !input.ExtractSecrets {
+		return nil, fmt.Errorf("%s 含字面量凭据，不能写进 Git；请允许移入 bundle .env（extract_secrets），或先改成 ${VAR} 引用", strings.Join(withSecrets, ", "))
+	}
+	for _, warning := range result.Warnings {
+		emit(reporter, EventWarn, "import.mcp", warning, nil)
+	}
+
+	stageDir, err := os.MkdirTemp("", "dec-import-mcp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stageDir)
+
+	refs := make([]ImportUnmanagedRef, 0, len(selected))
+	secretVars := make(map[string]map[string]string)
+	for _, candidate := range selected {
+		name := candidate.view.Name
+		data, err := json.MarshalIndent(candidate.snippet, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		stagedPath := importStagePath(stageDir, "mcp", name)
+		if err := os.MkdirAll(filepath.Dir(stagedPath), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(stagedPath, append(data, '\n'), 0644); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ImportUnmanagedRef{Type: "mcp", Name: name})
+		if len(candidate.secrets) > 0 {
+			secretVars[name] = candidate.secrets
+		}
+	}
+
+	imported, err := commitImportedAssets(ctx, workspace, bundleName, refs, stageDir,
+		fmt.Sprintf("import(mcp): %s ← %d 个用户级 MCP server", bundleName, len(refs)), reporter)
+	if err != nil {
+		return nil, err
+	}
+	result.BundleCreated = imported.bundleCreated
+	result.VersionCommit = imported.versionCommit
+	result.Imported = imported.imported
+	result.Warnings = append(result.Warnings, imported.warnings...)
+
+	if len(secretVars) > 0 {
+		files, keys, err := writeImportedSecretEnv(workspace, bundleName, secretVars)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("凭据未写入 .env: %v（请手动登记后再 pull）", err))
+			emit(reporter, EventWarn, "import.secrets", err.Error(), nil)
+		} else {
+			result.SecretEnvFiles, result.SecretKeys = files, keys
+			emit(reporter, EventInfo, "import.secrets",
+				fmt.Sprintf("🔐 %d 个凭据已移入 %s，下次 push 登记到 Bitwarden", len(keys), strings.Join(files, ", ")), nil)
+		}
+	}
+
+	enabled, err := enableImportedBundle(workspace, bundleName, reporter)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("启用 bundle %s 失败: %v", bundleName, err))
+	}
+	result.BundleEnabled = enabled
+
+	emit(reporter, EventInfo, "import.finish", fmt.Sprintf("✅ 已导入 %d 个 MCP server 到 bundle %s", len(result.Imported), bundleName), nil)
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/ide"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestNormalizeGlobalMCPServerExtractsSecrets(t *testing.T) {
+	snippet, secretVars, envKeys, headerKeys := normalizeGlobalMCPServer("linear-app", types.MCPServer{
+		Type:    "http",
+		URL:     "https://mcp.linear.app/mcp",
+		Headers: map[string]string{"Authorization": "Bearer lin_123", "X-Client": "dec"},
+		Env:     map[string]string{"LINEAR_API_KEY": "lin_api", "REGION": "eu"},
+	})
+	if snippet.Headers != nil || snippet.HTTPHeaders["Authorization"] != "Bearer ${LINEAR_APP_TOKEN}" || snippet.HTTPHeaders["X-Client"] != "dec" {
+		t.Fatalf("请求头应统一为 http_headers 并改写凭据: %#v", snippet)
+	}
+	if secretVars["LINEAR_APP_TOKEN"] != "lin_123" || secretVars["LINEAR_API_KEY"] != "lin_api" {
+		t.Fatalf("secretVars = %#v", secretVars)
+	}
+	if _, ok := snippet.Env["LINEAR_API_KEY"]; ok || snippet.Env["REGION"] != "eu" {
+		t.Fatalf("env 凭据应被剥离、其余保留: %#v", snippet.Env)
+	}
+	if strings.Join(envKeys, ",") != "LINEAR_API_KEY" || strings.Join(headerKeys, ",") != "Authorization" {
+		t.Fatalf("envKeys=%v headerKeys=%v", envKeys, headerKeys)
+	}
+
+	stdio, _, _, _ := normalizeGlobalMCPServer("fs", types.MCPServer{
+		Type:    "stdio",
+		Command: "dec-exec",
+		Args:    []string{"--project-root", "/x", "--", "npx", "fs-mcp"},
+	})
+	if stdio.Type != "" || stdio.Command != "npx" || strings.Join(stdio.Args, " ") != "fs-mcp" {
+		t.Fatalf("stdio server 应去掉 type 与外层启动器: %#v", stdio)
+	}
+}
+
+func TestImportGlobalMCPServersDedupesAcrossConfigs(t *testing.T) {
+	home := t.TempDir()
+	setEnvForProjectTest(t, "HOME", home)
+	setEnvForProjectTest(t, "XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/default/rules/keep.mdc": "keep\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+
+	github := `"github": {"command": "npx", "args": ["gh-mcp"], "env": {"GITHUB_TOKEN": "ghp_literal"}}`
+	writeFile(t, filepath.Join(home, ".cursor", "mcp.json"), `{"mcpServers": {`+github+`, "dec-x": {"command": "x"}}}`)
+	for _, source := range ide.ExternalMCPSources(home) {
+		switch source.Name {
+		case "claude-desktop":
+			writeFile(t, source.Path, `{"mcpServers": {`+github+`, "time": {"command": "uvx", "args": ["mcp-time"]}}}`)
+		case "vscode":
+			writeFile(t, source.Path, `{"servers": {"time": {"type": "stdio", "command": "uvx", "args": ["mcp-time", "--tz"]}}}`)
+		}
+	}
+
+	scan, err := ScanGlobalMCPServers(nil)
+	if err != nil {
+		t.Fatalf("ScanGlobalMCPServers() 失败: %v", err)
+	}
+	byName := make(map[string]GlobalMCPServer)
+	for _, server := range scan.Servers {
+		byName[server.Name] = server
+	}
+	if len(byName) != 2 {
+		t.Fatalf("应合并为 github 与 time 两项: %#v", scan.Servers)
+	}
+	if gh := byName["github"]; len(gh.Sources) != 2 || gh.Conflicting || strings.Join(gh.SecretEnvKeys, ",") != "GITHUB_TOKEN" {
+		t.Fatalf("github 应跨两处合并且无冲突: %#v", gh)
+	}
+	if tm := byName["time"]; !tm.Conflicting {
+		t.Fatalf("time 两处参数不同应标为冲突: %#v", tm)
+	}
+
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{IDEs: []string{"cursor"}}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	input := ImportGlobalMCPInput{ProjectRoot: projectRoot, Bundle: "tools", Names: []string{"github", "time"}}
+	if _, err := ImportGlobalMCPServers(context.Background(), input, nil); err == nil || !strings.Contains(err.Error(), "github") {
+		t.Fatalf("含凭据且未允许提取时应拒绝, got %v", err)
+	}
+
+	input.ExtractSecrets = true
+	result, err := ImportGlobalMCPServers(context.Background(), input, nil)
+	if err != nil {
+		t.Fatalf("ImportGlobalMCPServers() 失败: %v", err)
+	}
+	if len(result.Imported) != 2 || !result.BundleCreated || len(result.Warnings) != 1 {
+		t.Fatalf("导入结果不符合预期: %#v", result)
+	}
+
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	data, err := os.ReadFile(getWorkspaceCachePath(workspace, "tools", "mcp", "github"))
+	if err != nil {
+		t.Fatalf("cache 中应有 mcp/github.json: %v", err)
+	}
+	var server types.MCPServer
+	if err := json.Unmarshal(data, &server); err != nil || server.Command != "npx" || len(server.Env) != 0 {
+		t.Fatalf("snippet 不符合预期: %s err=%v", data, err)
+	}
+	envData, err := os.ReadFile(filepath.Join(projectRoot, ".secrets", "bundles", "tools", ".env", "github.env"))
+	if err != nil || !strings.Contains(string(envData), "GITHUB_TOKEN=ghp_literal") {
+		t.Fatalf("凭据应写入 bundle .env: data=%q err=%v", envData, err)
+	}
+}
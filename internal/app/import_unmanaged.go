@@ -0,0 +1,741 @@
+package app
+
+// import_unmanaged.go 把项目里已有的手写 IDE 资产（非 dec-* 的 rule / skill / command / MCP 条目）
+// 收编进 vault bundle。写入顺序：先在一次写事务里把资产与 bundle.yaml members 提交到 vault，
+// 成功后再镜像到 .dec/cache/<bundle>/，保证 cache 与 vault 一致；MCP env 里的凭据不进 Git，
+// 改写进该 bundle 的 .env SyncTarget，由 dec-exec 在启动时注入。
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/bundle"
+	"github.com/shichao402/Dec/internal/ide"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/secrets"
+	"github.com/shichao402/Dec/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// UnmanagedAsset 是在 IDE 目录或 MCP 配置里发现的一个非 Dec 托管资产。
+type UnmanagedAsset struct {
+	Type string
+	Name string
+	// IDEs 与 Paths 一一对应：在哪些 IDE 里发现、各自的落地路径（MCP 为配置文件路径）。
+	IDEs  []string
+	Paths []string
+	// SecretEnvKeys 是 MCP env 中疑似凭据的键；导入时移入 bundle 的 .env，不写进 vault。
+	SecretEnvKeys []string
+	// SecretHeaders 是 MCP 请求头（headers / http_headers）中的字面量凭据；导入时同样移入 .env，vault 中改为 ${VAR} 引用。
+	SecretHeaders []string
+	// Divergent 表示同名资产在不同 IDE 中内容不一致；导入时取第一个 IDE 的版本。
+	Divergent bool
+}
+
+// UnmanagedAssetScan 是一次非托管资产扫描的结果。
+type UnmanagedAssetScan struct {
+	Items    []UnmanagedAsset
+	Warnings []string
+}
+
+// ImportUnmanagedRef 指向扫描结果中的一项。
+type ImportUnmanagedRef struct {
+	Type string
+	Name string
+}
+
+// ImportUnmanagedInput 描述一次导入。Plane 为空视为项目平面。
+type ImportUnmanagedInput struct {
+	ProjectRoot string
+	Plane       WorkspacePlane
+	Bundle      string
+	Items       []ImportUnmanagedRef
+	// ReplaceOriginals 为 true 时登记替换：下一次 pull 装好 dec-<name> 后删除原始条目。
+	ReplaceOriginals bool
+}
+
+// ImportUnmanagedResult 汇总一次导入的结果。
+type ImportUnmanagedResult struct {
+	Bundle        string
+	Imported      []string
+	BundleCreated bool
+	// BundleEnabled 表示本次顺带把目标 bundle 加进了当前平面的 enabled_bundles。
+	BundleEnabled bool
+	// SecretEnvFiles 是写入的 .env 文件（同步根相对路径），下次 push 登记到 Bitwarden。
+	SecretEnvFiles []string
+	SecretKeys     []string
+	PendingReplace []string
+	VersionCommit  string
+	Warnings       []string
+}
+
+// secretEnvKeyRe 匹配常见凭据类变量名；只看键名，值是否为字面量另行判断。
+var secretEnvKeyRe = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|API[_-]?KEY|ACCESS[_-]?KEY|PRIVATE[_-]?KEY|CREDENTIAL|AUTH)`)
+
+// looksLikeSecretEnv 判断一个 env 条目是否应视为凭据：键名像凭据，且值是非空字面量（不是 ${VAR} 引用）。
+func looksLikeSecretEnv(key, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.Contains(value, "${") {
+		return false
+	}
+	return secretEnvKeyRe.MatchString(key)
+}
+
+// ScanUnmanagedAssets 扫描当前平面各 IDE 的 skills / commands / rules 目录与 MCP 配置，列出非 dec-* 条目。
+func ScanUnmanagedAssets(workspace Workspace, reporter Reporter) (*UnmanagedAssetScan, error) {
+	reporter = defaultReporter(reporter)
+	if workspace.EffectivePlane() == WorkspaceProject && strings.TrimSpace(workspace.Root) == "" {
+		return nil, fmt.Errorf("项目根目录不能为空")
+	}
+	projectIDEs := resolveWorkspaceIDEs(workspace, reporter)
+	home, _ := os.UserHomeDir()
+	plane := workspace.IDEPlane()
+
+	scan := &UnmanagedAssetScan{}
+	index := make(map[string]int)
+	digests := make(map[string]string)
+	add := func(itemType, name, ideName, path, digest string, secretKeys, secretHeaders []string) {
+		key := itemType + ":" + name
+		if idx, ok := index[key]; ok {
+			item := &scan.Items[idx]
+			for _, p := range item.Paths {
+				if filepath.Clean(p) == filepath.Clean(path) {
+					return
+				}
+			}
+			item.IDEs = append(item.IDEs, ideName)
+			item.Paths = append(item.Paths, path)
+			if digests[key] != digest {
+				item.Divergent = true
+			}
+			return
+		}
+		index[key] = len(scan.Items)
+		digests[key] = digest
+		scan.Items = append(scan.Items, UnmanagedAsset{
+			Type:          itemType,
+			Name:          name,
+			IDEs:          []string{ideName},
+			Paths:         []string{path},
+			SecretEnvKeys: secretKeys,
+			SecretHeaders: secretHeaders,
+		})
+	}
+
+	for _, ideImpl := range projectIDEs {
+		for _, itemType := range []string{"skill", "command", "rule"} {
+			if !ide.SupportsAssetType(ideImpl, itemType) {
+				continue
+			}
+			var dir string
+			switch itemType {
+			case "skill":
+				dir = ideImpl.SkillsDirForPlane(plane, workspace.Root, home)
+			case "command":
+				dir = ideImpl.CommandsDirForPlane(plane, workspace.Root, home)
+			case "rule":
+				dir = ideImpl.RulesDirForPlane(plane, workspace.Root, home)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				name, ok := unmanagedEntryName(itemType, filepath.Join(dir, entry.Name()))
+				if !ok {
+					continue
+				}
+				path := filepath.Join(dir, entry.Name())
+				digest, err := renderedDigest(path)
+				if err != nil {
+					scan.Warnings = append(scan.Warnings, fmt.Sprintf("读取 %s 失败: %v", path, err))
+					continue
+				}
+				add(itemType, name, ideImpl.Name(), path, digest, nil, nil)
+			}
+		}
+
+		mcpConfig, err := ideImpl.LoadMCPConfigForPlane(plane, workspace.Root, home)
+		if err != nil {
+			scan.Warnings = append(scan.Warnings, fmt.Sprintf("加载 %s MCP 配置失败: %v", ideImpl.Name(), err))
+			continue
+		}
+		configPath := ideImpl.MCPConfigPathForPlane(plane, workspace.Root, home)
+		for name, server := range mcpConfig.MCPServers {
+			if isDecOwnedEntryName(name) || validateRemoteOwnerName("资产", name) != nil {
+				continue
+			}
+			data, _ := json.Marshal(server)
+			var secretKeys []string
+			for key, value := range server.Env {
+				if looksLikeSecretEnv(key, value) {
+					secretKeys = append(secretKeys, key)
+				}
+			}
+			sort.Strings(secretKeys)
+			add("mcp", name, ideImpl.Name(), configPath, string(data), secretKeys, mcpSecretHeaderKeys(server))
+		}
+	}
+
+	sort.Slice(scan.Items, func(i, j int) bool {
+		if scan.Items[i].Type != scan.Items[j].Type {
+			return scan.Items[i].Type < scan.Items[j].Type
+		}
+		return scan.Items[i].Name < scan.Items[j].Name
+	})
+	return scan, nil
+}
+
+// unmanagedEntryName 从 IDE 目录条目推出资产短名；dec-* / 隐藏条目 / 类型不符的条目返回 false。
+func unmanagedEntryName(itemType, path string) (string, bool) {
+	base := filepath.Base(path)
+	if strings.HasPrefix(base, ".") {
+		return "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	var name string
+	switch itemType {
+	case "skill":
+		if !info.IsDir() {
+			return "", false
+		}
+		name = base
+	case "command":
+		// IDE 里手写的 command 常是单个 .md 文件；导入时包成目录型资产。
+		if info.IsDir() {
+			name = base
+		} else if strings.EqualFold(filepath.Ext(base), ".md") {
+			name = strings.TrimSuffix(base, filepath.Ext(base))
+		} else {
+			return "", false
+		}
+	case "rule":
+		ext := strings.ToLower(filepath.Ext(base))
+		if info.IsDir() || (ext != ".mdc" && ext != ".md") {
+			return "", false
+		}
+		name = strings.TrimSuffix(base, filepath.Ext(base))
+	default:
+		return "", false
+	}
+	if isDecOwnedEntryName(name) || validateRemoteOwnerName("资产", name) != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// isDecOwnedEntryName 报告 IDE 中的条目名是否归 Dec 所有：dec-* 托管资产，或 Dec 内置的 dec skill / MCP。
+func isDecOwnedEntryName(name string) bool {
+	return name == builtinDecMCPServerName || strings.HasPrefix(name, "dec-")
+}
+
+// ImportUnmanagedAssets 把选中的非托管资产写入目标 bundle（vault + .dec/cache），并按需登记原件替换。
+func ImportUnmanagedAssets(ctx context.Context, input ImportUnmanagedInput, reporter Reporter) (*ImportUnmanagedResult, error) {
+	reporter = defaultReporter(reporter)
+	workspace := NewWorkspace(input.Plane, input.ProjectRoot)
+	bundleName := strings.TrimSpace(input.Bundle)
+	if err := validateRemoteOwnerName("bundle", bundleName); err != nil {
+		return nil, err
+	}
+	if len(input.Items) == 0 {
+		return nil, fmt.Errorf("未选择要导入的资产")
+	}
+
+	scan, err := ScanUnmanagedAssets(workspace, reporter)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]UnmanagedAsset, len(scan.Items))
+	for _, item := range scan.Items {
+		found[item.Type+":"+item.Name] = item
+	}
+	selected := make([]UnmanagedAsset, 0, len(input.Items))
+	seen := make(map[string]struct{}, len(input.Items))
+	for _, ref := range input.Items {
+		key := strings.TrimSpace(ref.Type) + ":" + strings.TrimSpace(ref.Name)
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		item, ok := found[key]
+		if !ok {
+			return nil, fmt.Errorf("未在 IDE 中找到非托管资产 [%s] %s", ref.Type, ref.Name)
+		}
+		selected = append(selected, item)
+	}
+
+	stageDir, err := os.MkdirTemp("", "dec-import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stageDir)
+
+	result := &ImportUnmanagedResult{Bundle: bundleName}
+	secretVars := make(map[string]map[string]string)
+	for _, item := range selected {
+		if item.Divergent {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("[%s] %s 在各 IDE 中内容不一致，已取 %s 的版本", item.Type, item.Name, item.IDEs[0]))
+		}
+		stagedPath := getStagedImportPath(stageDir, item)
+		extracted, err := stageUnmanagedAsset(workspace, item, stagedPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取 [%s] %s 失败: %w", item.Type, item.Name, err)
+		}
+		if len(extracted) > 0 {
+			secretVars[item.Name] = extracted
+		}
+	}
+	for _, warning := range result.Warnings {
+		emit(reporter, EventWarn, "import.scan", warning, nil)
+	}
+
+	refs := make([]ImportUnmanagedRef, 0, len(selected))
+	for _, item := range selected {
+		refs = append(refs, ImportUnmanagedRef{Type: item.Type, Name: item.Name})
+	}
+	imported, err := commitImportedAssets(ctx, workspace, bundleName, refs, stageDir,
+		fmt.Sprintf("import: %s ← %d 项非托管资产", bundleName, len(refs)), reporter)
+	if err != nil {
+		return nil, err
+	}
+	result.BundleCreated = imported.bundleCreated
+	result.VersionCommit = imported.versionCommit
+	result.Imported = imported.imported
+	result.Warnings = append(result.Warnings, imported.warnings...)
+
+	if len(secretVars) > 0 {
+		files, keys, err := writeImportedSecretEnv(workspace, bundleName, secretVars)
+		if err != nil {
+			// vault 已提交、原件还在：凭据没落地只影响新装的 dec-* MCP，提示用户补登即可。
+			result.Warnings = append(result.Warnings, fmt.Sprintf("凭据未写入 .env: %v（原 MCP 条目保留不动）", err))
+			emit(reporter, EventWarn, "import.secrets", err.Error(), nil)
+		} else {
+			result.SecretEnvFiles, result.SecretKeys = files, keys
+			emit(reporter, EventInfo, "import.secrets",
+				fmt.Sprintf("🔐 %d 个凭据已移入 %s，下次 push 登记到 Bitwarden", len(keys), strings.Join(files, ", ")), nil)
+		}
+	}
+
+	enabled, err := enableImportedBundle(workspace, bundleName, reporter)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("启用 bundle %s 失败: %v", bundleName, err))
+	}
+	result.BundleEnabled = enabled
+
+	if input.ReplaceOriginals {
+		if err := addPendingImportReplacements(workspace, bundleName, selected); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("登记原件替换失败: %v", err))
+		} else {
+			for _, item := range selected {
+				result.PendingReplace = append(result.PendingReplace, fmt.Sprintf("[%s] %s", item.Type, item.Name))
+			}
+			emit(reporter, EventInfo, "import.replace", "下次 pull 装好 dec-* 版本后会删除原始条目", nil)
+		}
+	}
+
+	emit(reporter, EventInfo, "import.finish", fmt.Sprintf("✅ 已导入 %d 项到 bundle %s", len(result.Imported), bundleName), nil)
+	return result, nil
+}
+
+func getStagedImportPath(stageDir string, item UnmanagedAsset) string {
+	return importStagePath(stageDir, item.Type, item.Name)
+}
+
+// importStagePath 返回导入暂存目录里资产的 vault 形态路径（与 bundles/<name>/ 下布局一致）。
+func importStagePath(stageDir, itemType, name string) string {
+	kind, _ := bundle.KindByType(itemType)
+	return filepath.Join(stageDir, kind.Dir, bundle.AssetFileName(kind, name))
+}
+
+type importedAssets struct {
+	bundleCreated bool
+	versionCommit string
+	imported      []string
+	warnings      []string
+}
+
+// commitImportedAssets 把暂存目录里的资产写进 vault bundle 并追加 members，一次提交推送；
+// 成功后镜像到本平面 cache。bundle 不存在时按平面 scope 新建，已存在同名资产时整体失败。
+func commitImportedAssets(ctx context.Context, workspace Workspace, bundleName string, refs []ImportUnmanagedRef, stageDir, message string, reporter Reporter) (*importedAssets, error) {
+	out := &importedAssets{}
+	emit(reporter, EventInfo, "import.repo", fmt.Sprintf("写入 vault bundle %s（%d 项）", bundleName, len(refs)), nil)
+	if err := withWorkspaceWriteRepo(workspace, reporter, "import.repo", func(tx *repo.Transaction, commit vaultCommitFunc) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		repoDir := tx.WorkDir()
+		manifestAbs := filepath.Join(repoDir, filepath.FromSlash(types.VaultBundleManifestPath(bundleName)))
+		manifest := types.Bundle{Name: bundleName, Scope: bundleScopeForPlane(workspace.EffectivePlane()), Members: []string{}}
+		if data, readErr := os.ReadFile(manifestAbs); readErr == nil {
+			existing, _, parseErr := yamlBundleNameScope(data)
+			if parseErr != nil {
+				return fmt.Errorf("解析 vault bundle %q 失败: %w", bundleName, parseErr)
+			}
+			if existing.Scope != manifest.Scope {
+				return fmt.Errorf("bundle %q 的 scope 为 %s，不能从 %s 平面导入（ADR 0009）", bundleName, existing.Scope, workspace.EffectivePlane())
+			}
+			manifest = existing
+		} else if os.IsNotExist(readErr) {
+			out.bundleCreated = true
+			if err := os.MkdirAll(filepath.Dir(manifestAbs), 0755); err != nil {
+				return err
+			}
+		} else {
+			return fmt.Errorf("检查 vault bundle %q 失败: %w", bundleName, readErr)
+		}
+
+		for _, ref := range refs {
+			dest := resolveAssetFile(repoDir, bundleName, ref.Type, ref.Name)
+			if _, err := os.Stat(dest); err == nil {
+				return fmt.Errorf("bundle %s 中已存在 [%s] %s，请换名或先删除", bundleName, ref.Type, ref.Name)
+			}
+			if err := copyImportedAsset(ref.Type, importStagePath(stageDir, ref.Type, ref.Name), dest); err != nil {
+				return fmt.Errorf("写入 [%s] %s 失败: %w", ref.Type, ref.Name, err)
+			}
+			if !bundleHasMember(manifest, ref.Type, ref.Name) {
+				kind, _ := bundle.KindByType(ref.Type)
+				manifest.Members = append(manifest.Members, kind.Dir+"/"+ref.Name)
+			}
+		}
+		if err := writeBundleManifest(manifestAbs, manifest); err != nil {
+			return err
+		}
+		if _, err := commit(message); err != nil {
+			return fmt.Errorf("推送导入结果失败: %w", err)
+		}
+		out.versionCommit = tx.CommitHash()
+		return nil
+	}); err != nil {
+		emit(reporter, EventError, "import.repo", err.Error(), nil)
+		return nil, err
+	}
+
+	for _, ref := range refs {
+		cachePath := getWorkspaceCachePath(workspace, bundleName, ref.Type, ref.Name)
+		if err := copyImportedAsset(ref.Type, importStagePath(stageDir, ref.Type, ref.Name), cachePath); err != nil {
+			out.warnings = append(out.warnings, fmt.Sprintf("写入 %s 失败（下次 pull 会补齐）: %v", displayCacheDir(workspace), err))
+		}
+		out.imported = append(out.imported, fmt.Sprintf("[%s] %s", ref.Type, ref.Name))
+		emit(reporter, EventInfo, "import.asset", fmt.Sprintf("✅ [%-5s] %s → %s", ref.Type, ref.Name, bundleName), nil)
+	}
+	return out, nil
+}
+
+// enableImportedBundle 把导入目标 bundle 追加进本平面 enabled_bundles；已启用时返回 false。
+func enableImportedBundle(workspace Workspace, bundleName string, reporter Reporter) (bool, error) {
+	projectConfig, err := loadWorkspaceBundleConfig(workspace)
+	if err != nil {
+		return false, err
+	}
+	if containsString(projectConfig.EnabledBundles, bundleName) {
+		return false, nil
+	}
+	enabled := append(append([]string(nil), projectConfig.EnabledBundles...), bundleName)
+	if _, err := SaveWorkspaceEnabledBundles(workspace, enabled, reporter); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// stageUnmanagedAsset 把第一个 IDE 中的原件转成 vault 形态写到 stagedPath；MCP 返回被剥离的凭据。
+func stageUnmanagedAsset(workspace Workspace, item UnmanagedAsset, stagedPath string) (map[string]string, error) {
+	source := item.Paths[0]
+	switch item.Type {
+	case "skill":
+		return nil, copyDir(source, stagedPath)
+	case "command":
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, copyDir(source, stagedPath)
+		}
+		return nil, copyFile(source, filepath.Join(stagedPath, filepath.Base(source)))
+	case "rule":
+		return nil, copyFile(source, stagedPath)
+	case "mcp":
+		ideImpl := ide.Get(item.IDEs[0])
+		home, _ := os.UserHomeDir()
+		mcpConfig, err := ideImpl.LoadMCPConfigForPlane(workspace.IDEPlane(), workspace.Root, home)
+		if err != nil {
+			return nil, err
+		}
+		server, ok := mcpConfig.MCPServers[item.Name]
+		if !ok {
+			return nil, fmt.Errorf("MCP 条目已不存在")
+		}
+		extracted := make(map[string]string)
+		if len(server.Env) > 0 {
+			env := make(map[string]string, len(server.Env))
+			for key, value := range server.Env {
+				if looksLikeSecretEnv(key, value) {
+					extracted[key] = value
+					continue
+				}
+				env[key] = value
+			}
+			server.Env = env
+			if len(server.Env) == 0 {
+				server.Env = nil
+			}
+		}
+		server.HTTPHeaders, _ = extractMCPHeaderSecrets(item.Name, server, extracted)
+		server.Headers = nil
+		data, err := json.MarshalIndent(server, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(stagedPath), 0755); err != nil {
+			return nil, err
+		}
+		return extracted, os.WriteFile(stagedPath, append(data, '\n'), 0644)
+	default:
+		return nil, fmt.Errorf("不支持的资产类型 %q", item.Type)
+	}
+}
+
+func copyImportedAsset(itemType, src, dst string) error {
+	kind, ok := bundle.KindByType(itemType)
+	if !ok {
+		return fmt.Errorf("不支持的资产类型 %q", itemType)
+	}
+	if kind.DirEntries {
+		return copyDir(src, dst)
+	}
+	return copyFile(src, dst)
+}
+
+func bundleHasMember(b types.Bundle, itemType, name string) bool {
+	for _, raw := range b.Members {
+		member, err := bundle.ParseMember(raw)
+		if err == nil && member.Type == itemType && member.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, want string) bool {
+	for _, item := range list {
+		if item == want {
+			return true
+		}
+	}
+	return false
+}
+
+// writeImportedSecretEnv 把 MCP 凭据写进 bundle 的 .env SyncTarget（每个 MCP 一个 <name>.env），
+// 返回写入的文件（同步根相对路径）与键名。
+func writeImportedSecretEnv(workspace Workspace, bundleName string, secretVars map[string]map[string]string) ([]string, []string, error) {
+	var target secrets.SyncTarget
+	var err error
+	if workspace.EffectivePlane() == WorkspaceUser {
+		target, err = secrets.NewMachineBundleSyncTarget(bundleName, "")
+	} else {
+		target, err = secrets.NewBundleSyncTarget(bundleName, "")
+		if err == nil {
+			err = secrets.EnsureSecretsGitignore(workspace.Root)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(secretVars))
+	for name := range secretVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var files, keys []string
+	for _, name := range names {
+		noteRel, err := secrets.MergeEnvIntoTarget(workspace.Root, target, name, secretVars[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		display, _ := secrets.RootRelPath(target, noteRel)
+		files = append(files, display)
+		for key := range secretVars[name] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return files, keys, secrets.RememberSecretBundles([]string{bundleName})
+}
+
+// importPendingFileName 记录「导入后待替换的原件」，位于 .dec/ 下，pull 成功装好 dec-* 版本后消费。
+const importPendingFileName = "import_pending.yaml"
+
+type pendingImportReplacement struct {
+	Type   string   `yaml:"type"`
+	Name   string   `yaml:"name"`
+	Bundle string   `yaml:"bundle"`
+	IDEs   []string `yaml:"ides"`
+	Paths  []string `yaml:"paths"`
+}
+
+type pendingImportFile struct {
+	Pending []pendingImportReplacement `yaml:"pending"`
+}
+
+func importPendingPath(workspace Workspace) string {
+	return filepath.Join(workspaceCacheRoot(workspace), ".dec", importPendingFileName)
+}
+
+func loadPendingImports(workspace Workspace) (*pendingImportFile, error) {
+	data, err := os.ReadFile(importPendingPath(workspace))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &pendingImportFile{}, nil
+		}
+		return nil, err
+	}
+	var file pendingImportFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", importPendingFileName, err)
+	}
+	return &file, nil
+}
+
+func savePendingImports(workspace Workspace, file *pendingImportFile) error {
+	path := importPendingPath(workspace)
+	if len(file.Pending) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	header := "# Dec 导入后待替换的非托管原件；pull 装好对应 dec-* 资产后自动删除原件并移除条目\n"
+	return os.WriteFile(path, append([]byte(header), data...), 0644)
+}
+
+func addPendingImportReplacements(workspace Workspace, bundleName string, items []UnmanagedAsset) error {
+	file, err := loadPendingImports(workspace)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		entry := pendingImportReplacement{
+			Type:   item.Type,
+			Name:   item.Name,
+			Bundle: bundleName,
+			IDEs:   append([]string(nil), item.IDEs...),
+			Paths:  append([]string(nil), item.Paths...),
+		}
+		replaced := false
+		for i := range file.Pending {
+			if file.Pending[i].Type == item.Type && file.Pending[i].Name == item.Name {
+				file.Pending[i] = entry
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			file.Pending = append(file.Pending, entry)
+		}
+	}
+	return savePendingImports(workspace, file)
+}
+
+// replaceImportedOriginals 在 pull 装好 dec-* 版本后删除登记过的非托管原件。
+// 只处理本轮目标集内、且对应 IDE 里已确认存在托管版本的条目；其余条目留到下次。
+func replaceImportedOriginals(workspace Workspace, installed []types.TypedAssetRef, projectIDEs []ide.IDE) ([]string, error) {
+	file, err := loadPendingImports(workspace)
+	if err != nil || len(file.Pending) == 0 {
+		return nil, err
+	}
+	installedSet := make(map[string]struct{}, len(installed))
+	for _, asset := range installed {
+		installedSet[asset.Vault+":"+asset.Type+":"+asset.Name] = struct{}{}
+	}
+	ideByName := make(map[string]ide.IDE, len(projectIDEs))
+	for _, ideImpl := range projectIDEs {
+		ideByName[ideImpl.Name()] = ideImpl
+	}
+	home, _ := os.UserHomeDir()
+	plane := workspace.IDEPlane()
+
+	var replaced []string
+	remaining := make([]pendingImportReplacement, 0, len(file.Pending))
+	for _, entry := range file.Pending {
+		if _, ok := installedSet[entry.Bundle+":"+entry.Type+":"+entry.Name]; !ok {
+			remaining = append(remaining, entry)
+			continue
+		}
+		left := pendingImportReplacement{Type: entry.Type, Name: entry.Name, Bundle: entry.Bundle}
+		for i, ideName := range entry.IDEs {
+			ideImpl, ok := ideByName[ideName]
+			path := ""
+			if i < len(entry.Paths) {
+				path = entry.Paths[i]
+			}
+			if !ok || !managedEquivalentInstalled(entry.Type, entry.Name, workspace, ideImpl) {
+				left.IDEs = append(left.IDEs, ideName)
+				left.Paths = append(left.Paths, path)
+				continue
+			}
+			if entry.Type == "mcp" {
+				mcpConfig, err := ideImpl.LoadMCPConfigForPlane(plane, workspace.Root, home)
+				if err != nil {
+					left.IDEs = append(left.IDEs, ideName)
+					left.Paths = append(left.Paths, path)
+					continue
+				}
+				if _, exists := mcpConfig.MCPServers[entry.Name]; exists {
+					delete(mcpConfig.MCPServers, entry.Name)
+					if err := ideImpl.WriteMCPConfigForPlane(plane, workspace.Root, home, mcpConfig); err != nil {
+						left.IDEs = append(left.IDEs, ideName)
+						left.Paths = append(left.Paths, path)
+						continue
+					}
+				}
+			} else if path != "" && !strings.HasPrefix(filepath.Base(path), "dec-") {
+				if err := os.RemoveAll(path); err != nil {
+					left.IDEs = append(left.IDEs, ideName)
+					left.Paths = append(left.Paths, path)
+					continue
+				}
+			}
+			replaced = append(replaced, fmt.Sprintf("[%-5s] %s (%s)", entry.Type, entry.Name, ideName))
+		}
+		if len(left.IDEs) > 0 {
+			remaining = append(remaining, left)
+		}
+	}
+	file.Pending = remaining
+	return replaced, savePendingImports(workspace, file)
+}
+
+func managedEquivalentInstalled(itemType, name string, workspace Workspace, ideImpl ide.IDE) bool {
+	if itemType == "mcp" {
+		home, _ := os.UserHomeDir()
+		mcpConfig, err := ideImpl.LoadMCPConfigForPlane(workspace.IDEPlane(), workspace.Root, home)
+		if err != nil {
+			return false
+		}
+		_, ok := mcpConfig.MCPServers[managedName(name)]
+		return ok
+	}
+	path := ideAssetPath(itemType, name, workspace, ideImpl)
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
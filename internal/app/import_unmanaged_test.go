@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestScanUnmanagedAssetsSkipsManagedAndFlagsSecrets(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs: []string{"cursor", "claude"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+
+	writeFile(t, filepath.Join(projectRoot, ".cursor", "rules", "style.mdc"), "cursor style\n")
+	writeFile(t, filepath.Join(projectRoot, ".claude", "rules", "style.md"), "claude style\n")
+	writeFile(t, filepath.Join(projectRoot, ".cursor", "rules", "dec-managed.mdc"), "managed\n")
+	writeFile(t, filepath.Join(projectRoot, ".claude", "skills", "helper", "SKILL.md"), "---\nname: helper\n---\n")
+	writeFile(t, filepath.Join(projectRoot, ".cursor", "mcp.json"), `{"mcpServers":{
+  "github": {"command": "npx", "args": ["gh-mcp"], "env": {"GITHUB_TOKEN": "ghp_literal", "LOG_LEVEL": "debug", "API_KEY": "${API_KEY}"}},
+  "dec-other": {"command": "x"}
+}}`)
+
+	scan, err := ScanUnmanagedAssets(NewWorkspace(WorkspaceProject, projectRoot), nil)
+	if err != nil {
+		t.Fatalf("ScanUnmanagedAssets() 失败: %v", err)
+	}
+	got := make(map[string]UnmanagedAsset, len(scan.Items))
+	for _, item := range scan.Items {
+		got[item.Type+":"+item.Name] = item
+	}
+	if len(got) != 3 {
+		t.Fatalf("应只发现 3 项非托管资产, got %#v", scan.Items)
+	}
+	style, ok := got["rule:style"]
+	if !ok || len(style.IDEs) != 2 || !style.Divergent {
+		t.Fatalf("rule style 应在两个 IDE 中发现且标为不一致: %#v", style)
+	}
+	if _, ok := got["skill:helper"]; !ok {
+		t.Fatalf("应发现 skill helper: %#v", scan.Items)
+	}
+	github := got["mcp:github"]
+	if strings.Join(github.SecretEnvKeys, ",") != "GITHUB_TOKEN" {
+		t.Fatalf("只有字面量凭据应被标出, got %#v", github.SecretEnvKeys)
+	}
+}
+
+func TestImportUnmanagedAssetsWritesVaultAndMovesSecrets(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/existing/rules/keep.mdc": "keep\n",
+		"bundles/existing/bundle.yaml":    "name: existing\nmembers:\n  - rules/keep\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"existing"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	originalRule := filepath.Join(projectRoot, ".cursor", "rules", "style.md")
+	writeFile(t, originalRule, "---\ndescription: style\n---\nuse tabs\n")
+	writeFile(t, filepath.Join(projectRoot, ".cursor", "mcp.json"),
+		`{"mcpServers":{"github":{"command":"npx","args":["gh-mcp"],"env":{"GITHUB_TOKEN":"ghp_literal","LOG_LEVEL":"debug"}}}}`)
+
+	result, err := ImportUnmanagedAssets(context.Background(), ImportUnmanagedInput{
+		ProjectRoot:      projectRoot,
+		Bundle:           "team",
+		Items:            []ImportUnmanagedRef{{Type: "rule", Name: "style"}, {Type: "mcp", Name: "github"}},
+		ReplaceOriginals: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("ImportUnmanagedAssets() 失败: %v", err)
+	}
+	if !result.BundleCreated || !result.BundleEnabled || len(result.Imported) != 2 || result.VersionCommit == "" {
+		t.Fatalf("导入结果不符合预期: %#v", result)
+	}
+	if strings.Join(result.SecretKeys, ",") != "GITHUB_TOKEN" {
+		t.Fatalf("SecretKeys = %#v", result.SecretKeys)
+	}
+
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	cachedMCP, err := os.ReadFile(getWorkspaceCachePath(workspace, "team", "mcp", "github"))
+	if err != nil {
+		t.Fatalf("cache 中应有 mcp/github.json: %v", err)
+	}
+	if strings.Contains(string(cachedMCP), "ghp_literal") {
+		t.Fatalf("凭据不应写进 bundle: %s", cachedMCP)
+	}
+	var server types.MCPServer
+	if err := json.Unmarshal(cachedMCP, &server); err != nil || server.Env["LOG_LEVEL"] != "debug" {
+		t.Fatalf("非凭据 env 应保留: server=%#v err=%v", server, err)
+	}
+	envData, err := os.ReadFile(filepath.Join(projectRoot, ".secrets", "bundles", "team", ".env", "github.env"))
+	if err != nil || !strings.Contains(string(envData), "GITHUB_TOKEN=ghp_literal") {
+		t.Fatalf("凭据应写入 bundle .env: data=%q err=%v", envData, err)
+	}
+
+	enabled, err := loadWorkspaceBundleConfig(workspace)
+	if err != nil || strings.Join(enabled.EnabledBundles, ",") != "existing,team" {
+		t.Fatalf("目标 bundle 应被追加启用: %#v err=%v", enabled, err)
+	}
+
+	// 再次导入同名资产应被拒绝，不能覆盖 vault 里已有的成员。
+	if _, err := ImportUnmanagedAssets(context.Background(), ImportUnmanagedInput{
+		ProjectRoot: projectRoot,
+		Bundle:      "team",
+		Items:       []ImportUnmanagedRef{{Type: "rule", Name: "style"}},
+	}, nil); err == nil || !strings.Contains(err.Error(), "已存在") {
+		t.Fatalf("重复导入应报错, got %v", err)
+	}
+
+	pulled, err := PullProjectAssets(context.Background(), projectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	if len(pulled.ReplacedOriginals) != 2 {
+		t.Fatalf("pull 后应替换 2 个原件, got %#v", pulled.ReplacedOriginals)
+	}
+	if _, err := os.Stat(originalRule); !os.IsNotExist(err) {
+		t.Fatalf("原 rule 应被删除, err=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectRoot, ".cursor", "rules", "dec-style.mdc")); err != nil {
+		t.Fatalf("托管 rule 应已安装: %v", err)
+	}
+	mcpData, err := os.ReadFile(filepath.Join(projectRoot, ".cursor", "mcp.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(mcpData), `"dec-github"`) || strings.Contains(string(mcpData), `"github"`) {
+		t.Fatalf("MCP 原条目应被托管条目替换: %s", mcpData)
+	}
+	if _, err := os.Stat(importPendingPath(workspace)); !os.IsNotExist(err) {
+		t.Fatalf("替换完成后待办文件应被删除, err=%v", err)
+	}
+}
+
+func TestImportUnmanagedMCPMovesHeaderSecrets(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/existing/rules/keep.mdc": "keep\n",
+		"bundles/existing/bundle.yaml":    "name: existing\nmembers:\n  - rules/keep\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs: []string{"cursor"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	writeFile(t, filepath.Join(projectRoot, ".cursor", "mcp.json"),
+		`{"mcpServers":{"linear":{"url":"https://mcp.linear.app/mcp","headers":{"Authorization":"Bearer lin_api_literal","X-Client":"dec"}}}}`)
+
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	scan, err := ScanUnmanagedAssets(workspace, nil)
+	if err != nil || len(scan.Items) != 1 || strings.Join(scan.Items[0].SecretHeaders, ",") != "Authorization" {
+		t.Fatalf("扫描应标出字面量凭据请求头: %#v err=%v", scan, err)
+	}
+
+	result, err := ImportUnmanagedAssets(context.Background(), ImportUnmanagedInput{
+		ProjectRoot: projectRoot,
+		Bundle:      "team",
+		Items:       []ImportUnmanagedRef{{Type: "mcp", Name: "linear"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("ImportUnmanagedAssets() 失败: %v", err)
+	}
+	if strings.Join(result.SecretKeys, ",") != "LINEAR_TOKEN" {
+		t.Fatalf("SecretKeys = %#v", result.SecretKeys)
+	}
+	vaultMCP := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/team/mcp/linear.json")
+	if strings.Contains(vaultMCP, "lin_api_literal") {
+		t.Fatalf("bearer 凭据不应写进 vault: %s", vaultMCP)
+	}
+	var server types.MCPServer
+	if err := json.Unmarshal([]byte(vaultMCP), &server); err != nil {
+		t.Fatal(err)
+	}
+	if server.HTTPHeaders["Authorization"] != "Bearer ${LINEAR_TOKEN}" || server.HTTPHeaders["X-Client"] != "dec" || len(server.Headers) != 0 {
+		t.Fatalf("请求头应改为 ${VAR} 引用并保留非凭据头: %#v", server)
+	}
+	envData, err := os.ReadFile(filepath.Join(projectRoot, ".secrets", "bundles", "team", ".env", "linear.env"))
+	if err != nil || !strings.Contains(string(envData), "LINEAR_TOKEN=lin_api_literal") {
+		t.Fatalf("凭据应写入 bundle .env: data=%q err=%v", envData, err)
+	}
+}
@@ -0,0 +1,282 @@
+package app
+
+// install_mode.go 实现 install_mode: symlink。
+//
+// 做法是「先照常按 IDE 复制并渲染，再收拢成链接」：每个 IDE 先得到一份完整渲染副本
+// （含勿编辑 header 与 vars 替换），若所有 IDE 的副本逐字节一致，就把其中一份搬进
+// .dec/rendered/ 作为唯一实体，各 IDE 目录换成指向它的相对符号链接；否则保留副本。
+// 这样渲染逻辑只有一份，symlink 模式不会和 copy 模式渲染出不同的内容。
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/bundle"
+	"github.com/shichao402/Dec/internal/ide"
+)
+
+// renderedDirName 是 symlink 模式下渲染结果在 .dec/ 内的目录名。
+const renderedDirName = "rendered"
+
+// supportsSymlinkInstall 报告资产类型能否以链接方式落地；MCP 是写进 IDE 配置文件的条目，始终按值写入。
+func supportsSymlinkInstall(itemType string) bool {
+	switch itemType {
+	case "skill", "command", "rule":
+		return true
+	default:
+		return false
+	}
+}
+
+// workspaceRenderedDir 返回 symlink 模式渲染区根目录：项目平面为 <project>/.dec/rendered，用户平面为 ~/.dec/rendered。
+func workspaceRenderedDir(workspace Workspace) string {
+	return filepath.Join(workspaceCacheRoot(workspace), ".dec", renderedDirName)
+}
+
+// stagedAssetPath 返回资产在渲染区中的路径，文件名与 IDE 目录内的落地名一致。
+func stagedAssetPath(workspace Workspace, itemType, assetName string) string {
+	kind, ok := bundle.KindByType(itemType)
+	if !ok {
+		return ""
+	}
+	name := managedName(assetName)
+	if itemType == "rule" {
+		name += ".mdc"
+	}
+	return filepath.Join(workspaceRenderedDir(workspace), kind.Dir, name)
+}
+
+// ideAssetPath 返回资产在某个 IDE 中的落地路径；MCP 等不以独立文件落地的类型返回空串。
+func ideAssetPath(itemType, assetName string, workspace Workspace, ideImpl ide.IDE) string {
+	home, _ := os.UserHomeDir()
+	plane := workspace.IDEPlane()
+	projectRoot := workspace.Root
+	managed := managedName(assetName)
+
+	switch itemType {
+	case "skill":
+		return filepath.Join(ideImpl.SkillsDirForPlane(plane, projectRoot, home), managed)
+	case "command":
+		return filepath.Join(ideImpl.CommandsDirForPlane(plane, projectRoot, home), managed)
+	case "rule":
+		return filepath.Join(ideImpl.RulesDirForPlane(plane, projectRoot, home), managed+".mdc")
+	default:
+		return ""
+	}
+}
+
+// isDecOwnedLink 报告 path 是否为指向本工作区渲染区内的符号链接。
+// 只有这类链接由 Dec 负责删除；用户自己建的同名链接（指向别处）一律不动。
+func isDecOwnedLink(path string, workspace Workspace) bool {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(resolveDirPath(filepath.Dir(path)), target)
+	}
+	return pathWithin(filepath.Clean(target), resolveDirPath(workspaceRenderedDir(workspace)))
+}
+
+// resolveDirPath 尽量解析目录路径上的符号链接；目录不存在时退回词法路径。
+func resolveDirPath(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return filepath.Clean(dir)
+}
+
+func pathWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// unlinkDecOwned 在按值写入前摘掉 Dec 自己的链接，避免复制时穿透链接改写渲染区。
+func unlinkDecOwned(path string, workspace Workspace) error {
+	if !isDecOwnedLink(path, workspace) {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// removeStagedAsset 删除渲染区里的资产实体，并收掉空的类型目录。
+func removeStagedAsset(workspace Workspace, itemType, assetName string) {
+	staged := stagedAssetPath(workspace, itemType, assetName)
+	if staged == "" {
+		return
+	}
+	if _, err := os.Lstat(staged); err != nil {
+		return
+	}
+	_ = os.RemoveAll(staged)
+	_ = removeDirIfEmpty(filepath.Dir(staged))
+	_ = removeDirIfEmpty(workspaceRenderedDir(workspace))
+}
+
+// linkInstalledAsset 把已按值安装到各 IDE 的副本收拢为渲染区中的一份实体 + 相对符号链接。
+//
+// 返回 linked=false 时各 IDE 保持副本（copy 回退），reason 说明原因；
+// 回退不是错误——符号链接在某些文件系统 / Windows 权限下本就不可用。
+func linkInstalledAsset(itemType, assetName string, workspace Workspace, projectIDEs []ide.IDE) (bool, string) {
+	if !supportsSymlinkInstall(itemType) {
+		return false, ""
+	}
+
+	var paths []string
+	seen := make(map[string]struct{}, len(projectIDEs))
+	for _, ideImpl := range projectIDEs {
+		if !ide.SupportsAssetType(ideImpl, itemType) {
+			continue
+		}
+		path := filepath.Clean(ideAssetPath(itemType, assetName, workspace, ideImpl))
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return false, ""
+	}
+
+	var digest string
+	for _, path := range paths {
+		sum, err := renderedDigest(path)
+		if err != nil {
+			removeStagedAsset(workspace, itemType, assetName)
+			return false, fmt.Sprintf("读取渲染结果失败: %v", err)
+		}
+		if digest == "" {
+			digest = sum
+			continue
+		}
+		if sum != digest {
+			removeStagedAsset(workspace, itemType, assetName)
+			return false, "各 IDE 的渲染结果不同（vars 替换后内容有差异）"
+		}
+	}
+
+	staged := stagedAssetPath(workspace, itemType, assetName)
+	if err := os.RemoveAll(staged); err != nil {
+		return false, fmt.Sprintf("清理渲染区失败: %v", err)
+	}
+	var err error
+	if itemType == "rule" {
+		err = copyFile(paths[0], staged)
+	} else {
+		err = copyDir(paths[0], staged)
+	}
+	if err != nil {
+		removeStagedAsset(workspace, itemType, assetName)
+		return false, fmt.Sprintf("写入渲染区失败: %v", err)
+	}
+
+	linked := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if err := replaceWithRelativeLink(path, staged); err != nil {
+			restoreLinkedCopies(itemType, linked, staged)
+			removeStagedAsset(workspace, itemType, assetName)
+			return false, fmt.Sprintf("创建符号链接失败: %v", err)
+		}
+		linked = append(linked, path)
+	}
+	return true, ""
+}
+
+// replaceWithRelativeLink 用指向 target 的相对链接替换 path 处的副本。
+// 先在旁边建好临时链接再换名，链接建不出来时原副本保持完好。
+func replaceWithRelativeLink(path, target string) error {
+	rel, err := filepath.Rel(resolveDirPath(filepath.Dir(path)), resolveDirPath(filepath.Dir(target)))
+	if err != nil {
+		return err
+	}
+	rel = filepath.Join(rel, filepath.Base(target))
+
+	tmp := path + ".dec-link"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(rel, tmp); err != nil {
+		return err
+	}
+	if _, err := os.Stat(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("链接无法解析到渲染区: %w", err)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// restoreLinkedCopies 把已经换成链接的 IDE 路径恢复为渲染区内容的副本。
+func restoreLinkedCopies(itemType string, paths []string, staged string) {
+	for _, path := range paths {
+		_ = os.Remove(path)
+		if itemType == "rule" {
+			_ = copyFile(staged, path)
+		} else {
+			_ = copyDir(staged, path)
+		}
+	}
+}
+
+// renderedDigest 计算单个文件或整个目录（相对路径 + 内容）的摘要，用于比较各 IDE 的渲染结果。
+func renderedDigest(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if !info.IsDir() {
+		if err := hashFileInto(h, path); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	var files []string
+	if err := filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		rel, _ := filepath.Rel(path, file)
+		_, _ = io.WriteString(h, filepath.ToSlash(rel)+"\x00")
+		if err := hashFileInto(h, file); err != nil {
+			return "", err
+		}
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFileInto(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
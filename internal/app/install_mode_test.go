@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/ide"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestPullProjectAssetsSymlinkModeLinksIDEsToRenderedDir(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/skills/bundle-skill/SKILL.md": "---\nname: bundle-skill\n---\n",
+		"bundles/combo/rules/bundle-rule.mdc":        "---\ndescription: rule\n---\n",
+		"bundles/combo/bundle.yaml": `name: combo
+members:
+  - skill/bundle-skill
+  - rule/bundle-rule
+`,
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+
+	projectRoot := t.TempDir()
+	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor", "claude"},
+		EnabledBundles: []string{"combo"},
+		InstallMode:    types.InstallModeSymlink,
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+
+	result, err := PullProjectAssets(context.Background(), projectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	if result.InstallMode != types.InstallModeSymlink || len(result.CopyFallbacks) != 0 {
+		t.Fatalf("InstallMode = %q, CopyFallbacks = %#v", result.InstallMode, result.CopyFallbacks)
+	}
+
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	stagedSkill := filepath.Join(projectRoot, ".dec", "rendered", "skills", "dec-bundle-skill")
+	for _, linkPath := range []string{
+		filepath.Join(projectRoot, ".cursor", "skills", "dec-bundle-skill"),
+		filepath.Join(projectRoot, ".claude", "skills", "dec-bundle-skill"),
+		filepath.Join(projectRoot, ".cursor", "rules", "dec-bundle-rule.mdc"),
+	} {
+		target, err := os.Readlink(linkPath)
+		if err != nil {
+			t.Fatalf("%s 应为符号链接: %v", linkPath, err)
+		}
+		if filepath.IsAbs(target) {
+			t.Fatalf("%s 应为相对链接, 得到 %s", linkPath, target)
+		}
+		if !isDecOwnedLink(linkPath, workspace) {
+			t.Fatalf("%s 应识别为 Dec 自有链接（目标 %s）", linkPath, target)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(projectRoot, ".claude", "skills", "dec-bundle-skill", "SKILL.md"))
+	if err != nil {
+		t.Fatalf("经链接读取 skill 失败: %v", err)
+	}
+	if !strings.Contains(string(data), renderedHeaderMarker) {
+		t.Fatalf("渲染区内容应带勿编辑 header: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(stagedSkill, "SKILL.md")); err != nil {
+		t.Fatalf("渲染区实体应存在: %v", err)
+	}
+
+	// 再拉一次不应穿透链接把内容复制进渲染区自身。
+	if _, err := PullProjectAssets(context.Background(), projectRoot, "", nil); err != nil {
+		t.Fatalf("重复 pull 失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(stagedSkill, "dec-bundle-skill")); !os.IsNotExist(err) {
+		t.Fatalf("渲染区不应出现嵌套副本, err=%v", err)
+	}
+
+	// 切回 copy：链接换成真实目录，渲染区被收掉。
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor", "claude"},
+		EnabledBundles: []string{"combo"},
+		InstallMode:    types.InstallModeCopy,
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	if _, err := PullProjectAssets(context.Background(), projectRoot, "", nil); err != nil {
+		t.Fatalf("copy 模式 pull 失败: %v", err)
+	}
+	info, err := os.Lstat(filepath.Join(projectRoot, ".cursor", "skills", "dec-bundle-skill"))
+	if err != nil || info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		t.Fatalf("copy 模式下应为真实目录: info=%v err=%v", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(projectRoot, ".dec", "rendered")); !os.IsNotExist(err) {
+		t.Fatalf("copy 模式下渲染区应被清空, err=%v", err)
+	}
+}
+
+func TestLinkInstalledAssetFallsBackWhenRenderedContentDiffers(t *testing.T) {
+	projectRoot := t.TempDir()
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	projectIDEs := []ide.IDE{ide.Get("cursor"), ide.Get("claude")}
+
+	cursorRule := filepath.Join(projectRoot, ".cursor", "rules", "dec-style.mdc")
+	claudeRule := filepath.Join(projectRoot, ".claude", "rules", "dec-style.mdc")
+	for path, content := range map[string]string{cursorRule: "ide: cursor\n", claudeRule: "ide: claude\n"} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	linked, reason := linkInstalledAsset("rule", "style", workspace, projectIDEs)
+	if linked || reason == "" {
+		t.Fatalf("内容不同应回退副本: linked=%v reason=%q", linked, reason)
+	}
+	for _, path := range []string{cursorRule, claudeRule} {
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink != 0 {
+			t.Fatalf("%s 应保持为副本: info=%v err=%v", path, info, err)
+		}
+	}
+	if _, err := os.Stat(stagedAssetPath(workspace, "rule", "style")); !os.IsNotExist(err) {
+		t.Fatalf("回退时不应留下渲染区实体, err=%v", err)
+	}
+}
+
+func TestRemoveAssetFromIDELeavesForeignSymlink(t *testing.T) {
+	projectRoot := t.TempDir()
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+
+	foreign := filepath.Join(projectRoot, "my-skills", "shared")
+	if err := os.MkdirAll(foreign, 0755); err != nil {
+		t.Fatal(err)
+	}
+	linkPath := filepath.Join(projectRoot, ".cursor", "skills", "dec-shared")
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "..", "my-skills", "shared"), linkPath); err != nil {
+		t.Skipf("当前环境无法创建符号链接: %v", err)
+	}
+
+	removed, err := removeAssetFromIDE("skill", "shared", workspace, ide.Get("cursor"))
+	if err != nil || removed {
+		t.Fatalf("非 Dec 链接不应被删除: removed=%v err=%v", removed, err)
+	}
+	if _, err := os.Lstat(linkPath); err != nil {
+		t.Fatalf("外部链接应保留: %v", err)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Fatalf("外部链接目标应保留: %v", err)
+	}
+}
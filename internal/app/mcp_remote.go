@@ -0,0 +1,337 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/ide"
+	"github.com/shichao402/Dec/internal/mcpbridge"
+	"github.com/shichao402/Dec/internal/secrets"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+var (
+	remoteExactEnvRefRe  = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+	remoteBearerEnvRefRe = regexp.MustCompile(`^(?i:bearer)\s+\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+)
+
+// remoteMCPSpec 是 vault 中远端 MCP server 的规范化描述。
+//
+// vault 里的 mcp/<name>.json 可以用任意一种写法描述远端 server：JSON 风格的 type + headers，
+// 或 Codex 风格的 http_headers / env_http_headers / bearer_token_env_var。这里统一成
+// 明文请求头 + 从 bundle .env 取值的请求头两部分，再按目标 IDE 翻译。
+type remoteMCPSpec struct {
+	URL       string
+	Transport string
+	// Headers 是可以明文写进 IDE 配置的请求头。
+	Headers map[string]string
+	// EnvHeaders 是 请求头 -> 变量名，值在 dec-exec 里从 bundle .env 解析。
+	EnvHeaders map[string]string
+	// BearerEnv 非空时，Authorization: Bearer <BearerEnv 的值>。
+	BearerEnv string
+}
+
+// hasSecrets 表示是否有需要从 .env 取值的请求头；有则只能走 dec-exec 桥接。
+func (s remoteMCPSpec) hasSecrets() bool {
+	return len(s.EnvHeaders) > 0 || s.BearerEnv != ""
+}
+
+// parseRemoteMCPSpec 判断 server 是否为远端 server（有 URL、无 command），并规范化其请求头。
+// 请求头的值只接受明文、整值 ${VAR} 或 Bearer ${VAR}；其它夹带 ${...} 的写法无法在所有 IDE 上表达，直接报错。
+func parseRemoteMCPSpec(server types.MCPServer) (remoteMCPSpec, bool, error) {
+	if strings.TrimSpace(server.URL) == "" || strings.TrimSpace(server.Command) != "" {
+		return remoteMCPSpec{}, false, nil
+	}
+	spec := remoteMCPSpec{
+		URL:        strings.TrimSpace(server.URL),
+		Transport:  ide.RemoteTransportHTTP,
+		Headers:    make(map[string]string),
+		EnvHeaders: make(map[string]string),
+		BearerEnv:  strings.TrimSpace(server.BearerTokenEnvVar),
+	}
+	if strings.EqualFold(strings.TrimSpace(server.Type), ide.RemoteTransportSSE) {
+		spec.Transport = ide.RemoteTransportSSE
+	}
+	for name, envName := range server.EnvHTTPHeaders {
+		if strings.TrimSpace(envName) != "" {
+			spec.EnvHeaders[name] = strings.TrimSpace(envName)
+		}
+	}
+
+	merged := make(map[string]string, len(server.HTTPHeaders)+len(server.Headers))
+	for name, value := range server.HTTPHeaders {
+		merged[name] = value
+	}
+	for name, value := range server.Headers {
+		merged[name] = value
+	}
+	for name, value := range merged {
+		trimmed := strings.TrimSpace(value)
+		if strings.EqualFold(name, "Authorization") && spec.BearerEnv == "" {
+			if matches := remoteBearerEnvRefRe.FindStringSubmatch(trimmed); len(matches) == 2 {
+				spec.BearerEnv = matches[1]
+				continue
+			}
+		}
+		if matches := remoteExactEnvRefRe.FindStringSubmatch(trimmed); len(matches) == 2 {
+			if _, exists := spec.EnvHeaders[name]; !exists {
+				spec.EnvHeaders[name] = matches[1]
+			}
+			continue
+		}
+		if strings.Contains(value, "${") {
+			return remoteMCPSpec{}, true, fmt.Errorf("远端 MCP 请求头 %s 只支持明文、${VAR} 或 Bearer ${VAR}", name)
+		}
+		spec.Headers[name] = value
+	}
+	if spec.BearerEnv != "" {
+		for name := range spec.Headers {
+			if strings.EqualFold(name, "Authorization") {
+				delete(spec.Headers, name)
+			}
+		}
+	}
+	return spec, true, nil
+}
+
+// renderMCPServerForIDE 把 vault 中的 MCP 条目渲染成写入目标 IDE 的条目，
+// 同时返回该 IDE 无法表达的环境变量引用（供 lint 报告，不阻断安装）。
+//
+// 先翻译引用再包 dec-exec：Codex 的 env.K = "${K}" 要在包装剥掉 ${...} env 之前转成 env_vars。
+// env 中带默认值、改名或拼接的引用不经 IDE 翻译，原样交给 dec-exec 的 --env 展开，默认值不会丢；
+// IDE 无法表达的 ${input:id} env 条目不写入，并在 issue 中如实说明。
+func renderMCPServerForIDE(server types.MCPServer, vaultName string, workspace Workspace, ideImpl ide.IDE) (types.MCPServer, []ide.EnvRefIssue, error) {
+	remote, ok, err := parseRemoteMCPSpec(server)
+	if err != nil {
+		return types.MCPServer{}, nil, err
+	}
+	if ok {
+		server = remoteMCPServerForIDE(server, remote, ideImpl, workspace.Root, vaultName, workspace.SecretsPlane())
+		server, issues := ideImpl.TranslateEnvRefs(server)
+		return server, issues, nil
+	}
+	var execEnv map[string]string
+	if len(server.Env) > 0 {
+		env := make(map[string]string, len(server.Env))
+		execEnv = make(map[string]string)
+		for k, v := range server.Env {
+			if ide.HasEnvRefs(v) && !ide.IsInheritedEnvRef(k, v) && !ide.HasInputRefs(v) {
+				execEnv[k] = v
+				continue
+			}
+			env[k] = v
+		}
+		server.Env = env
+	}
+	server, issues := ideImpl.TranslateEnvRefs(server)
+	for i, issue := range issues {
+		key, isEnv := strings.CutPrefix(issue.Field, "env.")
+		if !isEnv {
+			continue
+		}
+		delete(server.Env, key)
+		issues[i].Reason += "，该 env 条目未写入"
+	}
+	if len(execEnv) > 0 && server.Env == nil {
+		server.Env = make(map[string]string, len(execEnv))
+	}
+	for k, v := range execEnv {
+		server.Env[k] = v
+	}
+	cmd, args := stripExternalEnvLauncher(server.Command, server.Args)
+	server.Command, server.Args, server.Env = WrapMCPServerWithExecForPlane(workspace.Root, vaultName, workspace.SecretsPlane(), "dec-exec", cmd, args, server.Env)
+	return server, issues, nil
+}
+
+// lintMCPEnvRefs 检查 MCP 资产在各目标 IDE 中无法表达的环境变量引用，返回可直接展示的提示。
+func lintMCPEnvRefs(srcPath, vaultName string, workspace Workspace, ides []ide.IDE) ([]string, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	var server types.MCPServer
+	if err := json.Unmarshal(data, &server); err != nil {
+		return nil, fmt.Errorf("解析 MCP 配置失败: %w", err)
+	}
+	var messages []string
+	for _, ideImpl := range ides {
+		_, issues, err := renderMCPServerForIDE(server, vaultName, workspace, ideImpl)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %v", ideImpl.Name(), err))
+			continue
+		}
+		for _, issue := range issues {
+			messages = append(messages, fmt.Sprintf("%s: %s", ideImpl.Name(), issue))
+		}
+	}
+	return messages, nil
+}
+
+// emitMCPEnvRefLint 把 lint 结果作为警告发给 Reporter；文件不存在时静默跳过。
+func emitMCPEnvRefLint(reporter Reporter, scope, srcPath string, asset types.TypedAssetRef, workspace Workspace, ides []ide.IDE) {
+	if _, err := os.Stat(srcPath); err != nil {
+		return
+	}
+	messages, err := lintMCPEnvRefs(srcPath, asset.Vault, workspace, ides)
+	if err != nil {
+		emit(reporter, EventWarn, scope, fmt.Sprintf("⚠️  [mcp  ] %s 检查环境变量引用失败: %v", asset.Name, err), nil)
+		return
+	}
+	for _, message := range messages {
+		emit(reporter, EventWarn, scope, fmt.Sprintf("⚠️  [mcp  ] %s — %s", asset.Name, message), nil)
+	}
+}
+
+// remoteMCPServerForIDE 把远端 server 翻译成目标 IDE 的条目。
+//
+// 没有凭据请求头时按 IDE 方言原生写入（Cursor url + headers、Claude / VS Code type: http|sse、
+// Codex url + http_headers）；有凭据请求头时一律改写为 dec-exec 桥接的 stdio 条目，令牌不落进 IDE 配置。
+func remoteMCPServerForIDE(original types.MCPServer, spec remoteMCPSpec, ideImpl ide.IDE, projectRoot, bundle string, plane secrets.SyncPlane) types.MCPServer {
+	var server types.MCPServer
+	if spec.hasSecrets() {
+		server.Command, server.Args = WrapRemoteMCPServerWithExecForPlane(projectRoot, bundle, plane, "dec-exec", spec)
+	} else {
+		server = ideImpl.RemoteMCPServer(ide.RemoteMCP{URL: spec.URL, Transport: spec.Transport, Headers: spec.Headers})
+	}
+	if isCodexIDE(ideImpl.Name()) {
+		// 只有 Codex 认这些字段，其它 IDE 写进去会被当成未知键。
+		server.StartupTimeoutSec = original.StartupTimeoutSec
+		server.ToolTimeoutSec = original.ToolTimeoutSec
+		server.Enabled = original.Enabled
+		server.Required = original.Required
+		server.EnabledTools = original.EnabledTools
+		server.DisabledTools = original.DisabledTools
+		server.Scopes = original.Scopes
+	}
+	return server
+}
+
+// WrapRemoteMCPServerWithExecForPlane 生成以 dec-exec 作为 stdio→HTTP 桥的启动参数。
+//
+// 凭据请求头只以变量名出现（--mcp-env-header NAME=VAR、--mcp-bearer-env VAR），
+// 参数里不出现 ${...}，避免被 IDE 自己的环境变量展开抢先替换。
+func WrapRemoteMCPServerWithExecForPlane(projectRoot, bundle string, plane secrets.SyncPlane, decBin string, spec remoteMCPSpec) (string, []string) {
+	if strings.TrimSpace(decBin) == "" {
+		decBin = "dec-exec"
+	}
+	rootRef := "${workspaceFolder}"
+	if secrets.IsMachinePlane(plane) {
+		if home, err := os.UserHomeDir(); err == nil && strings.TrimSpace(home) != "" {
+			rootRef = home
+		}
+	}
+	args := []string{"--project-root", rootRef}
+	if secrets.IsMachinePlane(plane) {
+		args = append(args, "--plane", "user")
+	}
+	if strings.TrimSpace(bundle) != "" {
+		args = append(args, "--bundle", bundle)
+	}
+	args = append(args, "--mcp-url", spec.URL, "--mcp-transport", spec.Transport)
+	for _, name := range sortedHeaderNames(spec.Headers) {
+		args = append(args, "--mcp-header", name+": "+spec.Headers[name])
+	}
+	for _, name := range sortedHeaderNames(spec.EnvHeaders) {
+		args = append(args, "--mcp-env-header", name+"="+spec.EnvHeaders[name])
+	}
+	if spec.BearerEnv != "" {
+		args = append(args, "--mcp-bearer-env", spec.BearerEnv)
+	}
+	return decBin, args
+}
+
+// MCPBridgeInput 描述一次 dec-exec 远端 MCP 桥接。
+type MCPBridgeInput struct {
+	ProjectRoot string
+	Bundle      string
+	Plane       secrets.SyncPlane // 空则默认 project
+	URL         string
+	Transport   string
+	// Headers 是 "Name: value" 形式的明文请求头。
+	Headers []string
+	// EnvHeaders 是 "Name=VAR" 形式的凭据请求头。
+	EnvHeaders []string
+	BearerEnv  string
+}
+
+// RunMCPBridgeWithSecrets 从 bundle .env（缺省回落到进程环境）解析凭据请求头，
+// 然后把 stdin/stdout 上的 MCP 会话转发到远端。
+func RunMCPBridgeWithSecrets(ctx context.Context, input MCPBridgeInput) error {
+	if strings.TrimSpace(input.ProjectRoot) == "" {
+		return fmt.Errorf("project-root 不能为空")
+	}
+	plane := input.Plane
+	if plane == "" {
+		plane = secrets.SyncPlaneProject
+	}
+	vars, err := secrets.LoadEnvForBundle(input.ProjectRoot, input.Bundle, plane)
+	if err != nil {
+		return err
+	}
+	header, err := buildMCPBridgeHeader(input, func(name string) (string, bool) {
+		if value, ok := vars[name]; ok {
+			return value, true
+		}
+		return os.LookupEnv(name)
+	})
+	if err != nil {
+		return err
+	}
+	return mcpbridge.Run(ctx, mcpbridge.Options{
+		URL:       input.URL,
+		Transport: input.Transport,
+		Header:    header,
+		Stdin:     os.Stdin,
+		Stdout:    os.Stdout,
+	})
+}
+
+func buildMCPBridgeHeader(input MCPBridgeInput, lookup func(string) (string, bool)) (http.Header, error) {
+	header := http.Header{}
+	for _, raw := range input.Headers {
+		name, value, ok := strings.Cut(raw, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("无效的 --mcp-header: %s", raw)
+		}
+		header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	var missing []string
+	resolve := func(envName string) string {
+		value, ok := lookup(envName)
+		if !ok || value == "" {
+			missing = append(missing, envName)
+		}
+		return value
+	}
+	for _, raw := range input.EnvHeaders {
+		name, envName, ok := strings.Cut(raw, "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(envName) == "" {
+			return nil, fmt.Errorf("无效的 --mcp-env-header: %s", raw)
+		}
+		header.Set(strings.TrimSpace(name), resolve(strings.TrimSpace(envName)))
+	}
+	if envName := strings.TrimSpace(input.BearerEnv); envName != "" {
+		header.Set("Authorization", "Bearer "+resolve(envName))
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("缺少远端 MCP 凭据变量: %s（请在 bundle .env 中配置）", strings.Join(missing, ", "))
+	}
+	return header, nil
+}
+
+func sortedHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
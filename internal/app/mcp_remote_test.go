@@ -0,0 +1,233 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/ide"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestInstallRemoteMCPTranslatesPerIDE(t *testing.T) {
+	projectRoot := t.TempDir()
+	srcPath := filepath.Join(t.TempDir(), "docs.json")
+	writeFile(t, srcPath, `{"type":"sse","url":"https://mcp.example.com/sse","headers":{"X-Team":"dec"}}`)
+
+	for _, name := range []string{"cursor", "claude", "vscode", "codex"} {
+		if err := installAssetToIDE("mcp", "docs", "team", srcPath, projectRoot, ide.Get(name)); err != nil {
+			t.Fatalf("安装到 %s 失败: %v", name, err)
+		}
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(projectRoot, rel))
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", rel, err)
+		}
+		return string(data)
+	}
+	cursor := read(filepath.Join(".cursor", "mcp.json"))
+	if !strings.Contains(cursor, `"url": "https://mcp.example.com/sse"`) || !strings.Contains(cursor, `"X-Team": "dec"`) || strings.Contains(cursor, `"type"`) {
+		t.Fatalf("Cursor 应写 url + headers 且不写 type:\n%s", cursor)
+	}
+	claude := read(filepath.Join(".claude", "mcp.json"))
+	if !strings.Contains(claude, `"type": "sse"`) || strings.Contains(claude, "dec-exec") {
+		t.Fatalf("Claude 应原生写 type: sse:\n%s", claude)
+	}
+	vscode := read(filepath.Join(".vscode", "mcp.json"))
+	if !strings.Contains(vscode, `"servers"`) || !strings.Contains(vscode, `"dec-docs"`) || !strings.Contains(vscode, `"type": "sse"`) {
+		t.Fatalf("VS Code 应写入 servers 且带 type:\n%s", vscode)
+	}
+	codex := read(filepath.Join(".codex", "config.toml"))
+	if !strings.Contains(codex, `url = "https://mcp.example.com/sse"`) || !strings.Contains(codex, "http_headers") {
+		t.Fatalf("Codex 应写 url + http_headers:\n%s", codex)
+	}
+}
+
+func TestInstallRemoteMCPWithSecretHeadersUsesExecBridge(t *testing.T) {
+	projectRoot := t.TempDir()
+	srcPath := filepath.Join(t.TempDir(), "tracker.json")
+	writeFile(t, srcPath, `{"url":"https://tracker.example.com/mcp","headers":{"Authorization":"Bearer ${TRACKER_TOKEN}","X-Org":"${TRACKER_ORG}","X-Team":"dec"}}`)
+
+	for _, name := range []string{"claude", "codex"} {
+		if err := installAssetToIDE("mcp", "tracker", "team", srcPath, projectRoot, ide.Get(name)); err != nil {
+			t.Fatalf("安装到 %s 失败: %v", name, err)
+		}
+	}
+	config, err := ide.Get("claude").LoadMCPConfig(projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := config.MCPServers["dec-tracker"]
+	joined := strings.Join(server.Args, " ")
+	if server.Command != "dec-exec" || server.URL != "" || len(server.Headers) != 0 {
+		t.Fatalf("带凭据请求头的远端 server 应改为 dec-exec stdio 条目: %#v", server)
+	}
+	for _, want := range []string{
+		"--bundle team",
+		"--mcp-url https://tracker.example.com/mcp",
+		"--mcp-transport http",
+		"--mcp-header X-Team: dec",
+		"--mcp-env-header X-Org=TRACKER_ORG",
+		"--mcp-bearer-env TRACKER_TOKEN",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("桥接参数缺少 %q: %#v", want, server.Args)
+		}
+	}
+	if strings.Contains(joined, "${TRACKER") {
+		t.Fatalf("桥接参数不应包含变量占位符: %#v", server.Args)
+	}
+	codex, err := os.ReadFile(filepath.Join(projectRoot, ".codex", "config.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(codex), "bearer_token_env_var") || !strings.Contains(string(codex), "dec-exec") {
+		t.Fatalf("Codex 也应走 dec-exec 桥接:\n%s", codex)
+	}
+
+	if err := os.WriteFile(srcPath, []byte(`{"url":"https://x","headers":{"X-Key":"key-${TRACKER_TOKEN}"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := installAssetToIDE("mcp", "tracker", "team", srcPath, projectRoot, ide.Get("cursor")); err == nil {
+		t.Fatalf("部分引用变量的请求头应报错")
+	}
+}
+
+func TestBuildMCPBridgeHeaderResolvesSecrets(t *testing.T) {
+	env := map[string]string{"TRACKER_TOKEN": "t0k", "TRACKER_ORG": "acme"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	header, err := buildMCPBridgeHeader(MCPBridgeInput{
+		Headers:    []string{"X-Team: dec"},
+		EnvHeaders: []string{"X-Org=TRACKER_ORG"},
+		BearerEnv:  "TRACKER_TOKEN",
+	}, lookup)
+	if err != nil {
+		t.Fatalf("buildMCPBridgeHeader() 失败: %v", err)
+	}
+	if header.Get("Authorization") != "Bearer t0k" || header.Get("X-Org") != "acme" || header.Get("X-Team") != "dec" {
+		t.Fatalf("请求头 = %#v", header)
+	}
+
+	_, err = buildMCPBridgeHeader(MCPBridgeInput{BearerEnv: "MISSING_TOKEN"}, lookup)
+	if err == nil || !strings.Contains(err.Error(), "MISSING_TOKEN") {
+		t.Fatalf("缺少变量应报错并列出变量名, got %v", err)
+	}
+}
+
+func TestLintMCPEnvRefsReportsUnsupportedReferences(t *testing.T) {
+	projectRoot := t.TempDir()
+	srcPath := filepath.Join(t.TempDir(), "db.json")
+	writeFile(t, srcPath, `{"command":"db-mcp","args":["--schema=${DB_SCHEMA:-public}"],"env":{"DATABASE_URL":"${DATABASE_URL}"}}`)
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+
+	messages, err := lintMCPEnvRefs(srcPath, "team", workspace, []ide.IDE{ide.Get("claude"), ide.Get("cursor"), ide.Get("codex")})
+	if err != nil {
+		t.Fatalf("lintMCPEnvRefs() 失败: %v", err)
+	}
+	joined := strings.Join(messages, "\n")
+	if strings.Contains(joined, "claude:") || !strings.Contains(joined, "cursor: args[0]") || !strings.Contains(joined, "codex: args[0]") {
+		t.Fatalf("只有 cursor / codex 应报告 args 中的默认值引用:\n%s", joined)
+	}
+
+	if err := installAssetToIDE("mcp", "db", "team", srcPath, projectRoot, ide.Get("cursor")); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ide.Get("cursor").LoadMCPConfig(projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := config.MCPServers["dec-db"]
+	if server.Command != "dec-exec" || server.Args[len(server.Args)-1] != "--schema=${env:DB_SCHEMA}" {
+		t.Fatalf("Cursor 条目应使用 ${env:VAR} 写法: %#v", server)
+	}
+}
+
+func TestRenderMCPServerPassesEnvDefaultsThroughDecExec(t *testing.T) {
+	workspace := NewWorkspace(WorkspaceProject, t.TempDir())
+	server := types.MCPServer{
+		Command: "db-mcp",
+		Env: map[string]string{
+			"DATABASE_URL": "${DATABASE_URL}",
+			"DB_SCHEMA":    "${DB_SCHEMA:-public}",
+			"API_KEY":      "${input:apiKey}",
+		},
+	}
+	for _, name := range []string{"cursor", "codex"} {
+		got, issues, err := renderMCPServerForIDE(server, "team", workspace, ide.Get(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		joined := strings.Join(got.Args, " ")
+		if !strings.Contains(joined, "--env DB_SCHEMA=${DB_SCHEMA:-public} --") {
+			t.Fatalf("%s: 带默认值的 env 应原样交给 dec-exec: %#v", name, got.Args)
+		}
+		if _, ok := got.Env["API_KEY"]; ok {
+			t.Fatalf("%s: 无法表达的 ${input:} env 不应写入: %#v", name, got.Env)
+		}
+		if len(issues) != 1 || issues[0].Field != "env.API_KEY" || !strings.Contains(issues[0].Reason, "未写入") {
+			t.Fatalf("%s: 只应报告未写入的 ${input:} env: %#v", name, issues)
+		}
+	}
+
+	got, issues, err := renderMCPServerForIDE(server, "team", workspace, ide.Get("vscode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 || got.Env["API_KEY"] != "${input:apiKey}" {
+		t.Fatalf("VS Code 应保留 ${input:} env: env=%#v issues=%#v", got.Env, issues)
+	}
+}
+
+func TestPullInstallsOnlyMCPIntoVSCode(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/bundle.yaml":            "name: combo\nmembers:\n  - skill/writer\n  - rule/style\n  - command/ship\n  - mcp/docs\n",
+		"bundles/combo/skills/writer/SKILL.md": "---\nname: writer\n---\nwrite\n",
+		"bundles/combo/rules/style.mdc":        "---\ndescription: style\n---\ntabs\n",
+		"bundles/combo/commands/ship/ship.md":  "ship it\n",
+		"bundles/combo/mcp/docs.json":          `{"url":"https://mcp.example.com/mcp"}`,
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor", "vscode"},
+		EnabledBundles: []string{"combo"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PullProjectAssets(context.Background(), projectRoot, "", nil); err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+
+	for _, dir := range []string{"skills", "rules", "commands"} {
+		if _, err := os.Stat(filepath.Join(projectRoot, ".vscode", dir)); !os.IsNotExist(err) {
+			t.Fatalf(".vscode/%s 不应被写入: err=%v", dir, err)
+		}
+		if _, err := os.Stat(filepath.Join(projectRoot, ".cursor", dir)); err != nil {
+			t.Fatalf("Cursor 仍应安装 %s: %v", dir, err)
+		}
+	}
+	mcpConfig, err := ide.Get("vscode").LoadMCPConfig(projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mcpConfig.MCPServers["dec-docs"]; !ok {
+		t.Fatalf("VS Code 应安装 MCP: %#v", mcpConfig.MCPServers)
+	}
+	if ide.SupportsAssetType(ide.Get("vscode"), "skill") || !ide.SupportsAssetType(ide.Get("cursor"), "skill") {
+		t.Fatal("只有 VS Code 应限制为 MCP")
+	}
+}
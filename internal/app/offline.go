@@ -0,0 +1,114 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shichao402/Dec/internal/repo"
+)
+
+// 离线模式（repo.Offline*），供展示层判断。
+const (
+	OfflineAuto = repo.OfflineAuto
+	OfflineOn   = repo.OfflineOn
+	OfflineOff  = repo.OfflineOff
+)
+
+// PushQueueEntry 是离线时排队、尚未送到远端的 push（按目标分支汇总）。
+type PushQueueEntry struct {
+	// Target 是要推送到的 vault 分支（或评审分支）。
+	Target string
+	// Subjects 是排队提交的标题，新的在前。
+	Subjects []string
+}
+
+// LoadPushQueue 列出本地 bare repo 中排队的离线 push；仓库未连接时为空。
+func LoadPushQueue() ([]PushQueueEntry, error) {
+	queue, err := repo.PushQueue()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]PushQueueEntry, 0, len(queue))
+	for _, item := range queue {
+		entry := PushQueueEntry{Target: item.Target}
+		for _, commit := range item.Commits {
+			entry.Subjects = append(entry.Subjects, commit.Subject)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// flushPushQueue 在 push 前把之前排队的离线提交送到远端，返回送出的提交数。
+// 仍离线时什么都不做；发送失败（例如与远端冲突）只告警，队列保留，本次 push 照常进行。
+// 目标分支的 vault 格式高于本版本支持时返回错误，由调用方中止本次 push。
+func flushPushQueue(reporter Reporter, scope string) (int, error) {
+	sent, err := repo.FlushPushQueue(ensureVaultBranchWritable)
+	count := 0
+	for _, item := range sent {
+		count += len(item.Commits)
+		emit(reporter, EventInfo, scope, fmt.Sprintf("已发送 %d 个离线排队的提交到 %s", len(item.Commits), item.Target), nil)
+	}
+	if errors.Is(err, errVaultFormatTooNew) {
+		return count, err
+	}
+	if err != nil {
+		emit(reporter, EventWarn, scope, fmt.Sprintf("⚠️  离线排队的提交未能发送，保留在队列中: %v", err), nil)
+	}
+	return count, nil
+}
+
+// discardSupersededQueue 在线推送 head 成功后丢弃同一目标上仍未送出的队列：
+// push 按缓存内容三方合并，若队列里的改动已全部包含在 head 中，队列就不必再发送。
+// 队列按目标分支汇总，可能混有其他项目的提交；未被 head 覆盖时保留，等下次发送。
+func discardSupersededQueue(target, head string, reporter Reporter, scope string) {
+	queue, err := repo.PushQueue()
+	if err != nil {
+		return
+	}
+	for _, item := range queue {
+		if item.Target != target {
+			continue
+		}
+		superseded, err := repo.PushQueueSupersededBy(item, head)
+		if err != nil || !superseded {
+			emit(reporter, EventWarn, scope, fmt.Sprintf("%s 上仍有 %d 个离线提交未发送，保留在队列中", target, len(item.Commits)), nil)
+			continue
+		}
+		if err := repo.DiscardPushQueue(target); err == nil {
+			emit(reporter, EventInfo, scope, fmt.Sprintf("%s 上未能发送的 %d 个离线提交已由本次推送取代", target, len(item.Commits)), nil)
+		}
+	}
+}
+
+// CacheAgeLabel 描述本地 bare repo 距最近一次同步远端有多久，用于离线结果与 Home 页。
+func CacheAgeLabel(syncedAt, now time.Time) string {
+	if syncedAt.IsZero() {
+		return "同步时间未知"
+	}
+	age := now.Sub(syncedAt)
+	switch {
+	case age < time.Minute:
+		return "刚刚同步"
+	case age < time.Hour:
+		return fmt.Sprintf("%d 分钟前同步", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%d 小时前同步", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%d 天前同步", int(age.Hours()/24))
+	}
+}
+
+// applyOfflineRead 把只读事务的离线状态记到 pull 结果上，并给出陈旧度告警。
+func applyOfflineRead(result *PullProjectAssetsResult, tx *repo.Transaction, reporter Reporter) {
+	if !tx.Offline() {
+		return
+	}
+	result.Offline = true
+	result.OfflineReason = tx.OfflineReason()
+	result.CacheSyncedAt, _ = repo.LastFetchTime()
+	warning := fmt.Sprintf("离线：使用本地缓存（%s）；%s", CacheAgeLabel(result.CacheSyncedAt, time.Now()), result.OfflineReason)
+	result.NonFatalWarnings = append(result.NonFatalWarnings, warning)
+	emit(reporter, EventWarn, "pull.prepare", warning, nil)
+}
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestOfflinePullAndQueuedPush(t *testing.T) {
+	base := "---\ndescription: team\n---\nline 1\n"
+	projectRoot, remote, _ := setupPulledProjectForMerge(t, base)
+
+	// 远端不可达：pull 回落到本地 bare repo，push 只排队
+	unreachable := remote + ".away"
+	if err := os.Rename(remote, unreachable); err != nil {
+		t.Fatal(err)
+	}
+
+	pulled, err := PullProjectAssets(context.Background(), projectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("离线 pull 应回落到本地缓存: %v", err)
+	}
+	if !pulled.Offline || pulled.OfflineReason == "" || pulled.CacheSyncedAt.IsZero() {
+		t.Fatalf("离线 pull 应标记来源与同步时间: %+v", pulled)
+	}
+	if !strings.Contains(strings.Join(pulled.NonFatalWarnings, "\n"), "离线：使用本地缓存") {
+		t.Fatalf("离线 pull 应给出陈旧度告警: %v", pulled.NonFatalWarnings)
+	}
+
+	writeFileProjectTest(t, getCachePath(projectRoot, "combo", "rule", "team"), base+"offline edit\n")
+	pushed, err := PushProjectAssets(context.Background(), projectRoot, nil)
+	if err != nil {
+		t.Fatalf("离线 push 应排队而不是失败: %v", err)
+	}
+	if !pushed.DecQueued || pushed.DecPushedCount != 1 {
+		t.Fatalf("离线 push 应排队 1 个资产: %+v", pushed)
+	}
+	overview, err := LoadProjectOverview(projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overview.PushQueue) != 1 || overview.PushQueue[0].Target != "main" || len(overview.PushQueue[0].Subjects) != 1 {
+		t.Fatalf("Home 应显示 1 个排队提交: %+v", overview.PushQueue)
+	}
+
+	// 恢复在线：下一次 push 先把队列送出去
+	if err := os.Rename(unreachable, remote); err != nil {
+		t.Fatal(err)
+	}
+	pushed, err = PushProjectAssets(context.Background(), projectRoot, nil)
+	if err != nil {
+		t.Fatalf("在线 push 失败: %v", err)
+	}
+	if pushed.DecQueueSent != 1 || pushed.DecQueued {
+		t.Fatalf("在线 push 应送出排队的提交: %+v", pushed)
+	}
+	got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/rules/team.mdc")
+	if !strings.Contains(got, "offline edit") {
+		t.Fatalf("远端应收到离线时的改动: %q", got)
+	}
+	overview, err = LoadProjectOverview(projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overview.PushQueue) != 0 {
+		t.Fatalf("送出后队列应为空: %+v", overview.PushQueue)
+	}
+}
+
+func TestOnlinePushKeepsOtherProjectsQueuedCommits(t *testing.T) {
+	base := "---\ndescription: team\n---\nline 1\n"
+	projectRoot, _, seed := setupPulledProjectForMerge(t, base)
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/other/rules/solo.mdc"), "---\ndescription: solo\n---\n")
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/other/bundle.yaml"), "name: other\nmembers:\n  - rule/solo\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "other bundle")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+	otherRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(otherRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"other"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PullProjectAssets(context.Background(), otherRoot, "", nil); err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+
+	// 第一个项目离线排队；之后队友改了同一文件，队列发送会冲突
+	setEnvForProjectTest(t, "DEC_OFFLINE", "on")
+	writeFileProjectTest(t, getCachePath(projectRoot, "combo", "rule", "team"), base+"offline edit\n")
+	if pushed, err := PushProjectAssets(context.Background(), projectRoot, nil); err != nil || !pushed.DecQueued {
+		t.Fatalf("离线 push 应排队: %+v, %v", pushed, err)
+	}
+	setEnvForProjectTest(t, "DEC_OFFLINE", "off")
+	runGitProjectTest(t, seed, "pull", "origin", "main")
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/rules/team.mdc"), base+"teammate edit\n")
+	runGitProjectTest(t, seed, "commit", "-am", "teammate")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+
+	// 另一个项目在线推送成功，不应丢掉与它无关的排队提交
+	writeFileProjectTest(t, getCachePath(otherRoot, "other", "rule", "solo"), "---\ndescription: solo\n---\nmine\n")
+	pushed, err := PushProjectAssets(context.Background(), otherRoot, nil)
+	if err != nil {
+		t.Fatalf("在线 push 失败: %v", err)
+	}
+	if pushed.DecQueueSent != 0 || pushed.DecPushedCount != 1 {
+		t.Fatalf("队列冲突时本次 push 仍应照常推送: %+v", pushed)
+	}
+	queue, err := LoadPushQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Target != "main" || len(queue[0].Subjects) != 1 {
+		t.Fatalf("未被本次推送包含的排队提交应保留: %+v", queue)
+	}
+}
+
+func TestOnlinePushAbortsWhenQueuedTargetFormatIsNewer(t *testing.T) {
+	base := "---\ndescription: team\n---\nline 1\n"
+	projectRoot, remote, seed := setupPulledProjectForMerge(t, base)
+
+	setEnvForProjectTest(t, "DEC_OFFLINE", "on")
+	writeFileProjectTest(t, getCachePath(projectRoot, "combo", "rule", "team"), base+"offline edit\n")
+	if pushed, err := PushProjectAssets(context.Background(), projectRoot, nil); err != nil || !pushed.DecQueued {
+		t.Fatalf("离线 push 应排队: %+v, %v", pushed, err)
+	}
+	setEnvForProjectTest(t, "DEC_OFFLINE", "off")
+	writeFileProjectTest(t, filepath.Join(seed, types.VaultMetaFileName), "format: 99\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "future format")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+	mainHead := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main")
+
+	// 队列目标的格式更高：发送前检查拒绝，本次 push 整体中止，队列保留
+	_, err := PushProjectAssets(context.Background(), projectRoot, nil)
+	if err == nil || !strings.Contains(err.Error(), "发送排队的提交到 main 前检查失败") || !strings.Contains(err.Error(), "请先升级 Dec") {
+		t.Fatalf("队列目标格式更高时 push 应中止，得到 %v", err)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main"); got != mainHead {
+		t.Fatalf("远端不应收到任何提交: %s -> %s", mainHead, got)
+	}
+	queue, err := LoadPushQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Target != "main" {
+		t.Fatalf("被拒绝的排队提交应保留: %+v", queue)
+	}
+
+	// 只有确认不存在的分支（尚未创建的评审分支）才跳过格式检查
+	if err := ensureVaultBranchWritable("dec/someone/demo/20260101-000000-abcdef"); err != nil {
+		t.Fatalf("不存在的评审分支应跳过检查: %v", err)
+	}
+	if err := ensureVaultBranchWritable("main"); !errors.Is(err, errVaultFormatTooNew) {
+		t.Fatalf("格式更高的分支应被拒绝，得到 %v", err)
+	}
+}
+
+func TestOfflineModeForcedByEnv(t *testing.T) {
+	projectRoot, _, seed := setupPulledProjectForMerge(t, "---\ndescription: team\n---\nline 1\n")
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/rules/team.mdc"), "---\ndescription: team\n---\nteammate\n")
+	runGitProjectTest(t, seed, "commit", "-am", "teammate")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+
+	setEnvForProjectTest(t, "DEC_OFFLINE", "on")
+	pulled, err := PullProjectAssets(context.Background(), projectRoot, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pulled.Offline {
+		t.Fatalf("DEC_OFFLINE=on 时 pull 应离线: %+v", pulled)
+	}
+	data, err := os.ReadFile(getCachePath(projectRoot, "combo", "rule", "team"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "teammate") {
+		t.Fatalf("离线 pull 不应看到远端的新提交: %q", data)
+	}
+	overview, err := LoadProjectOverview(projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overview.OfflineMode != OfflineOn {
+		t.Fatalf("OfflineMode = %q, want on", overview.OfflineMode)
+	}
+}
+
+func TestCacheAgeLabel(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[time.Duration]string{
+		10 * time.Second: "刚刚同步",
+		5 * time.Minute:  "5 分钟前同步",
+		3 * time.Hour:    "3 小时前同步",
+		72 * time.Hour:   "3 天前同步",
+	}
+	for age, want := range cases {
+		if got := CacheAgeLabel(now.Add(-age), now); got != want {
+			t.Errorf("CacheAgeLabel(%v) = %q, want %q", age, got, want)
+		}
+	}
+	if got := CacheAgeLabel(time.Time{}, now); got != "同步时间未知" {
+		t.Errorf("零值应为同步时间未知，得到 %q", got)
+	}
+}
@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -13,6 +12,7 @@ import (
 
 	"github.com/shichao402/Dec/internal/bundle"
 	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/freshness"
 	"github.com/shichao402/Dec/internal/ide"
 	"github.com/shichao402/Dec/internal/repo"
 	"github.com/shichao402/Dec/internal/secrets"
@@ -48,6 +48,30 @@ type PullProjectAssetsResult struct {
 	OrphanSSHKeys        []string
 	OrphanClearedBundles []string
 	OrphanReportedOnly   []string
+	// InstallMode 为本轮生效的安装方式（copy | symlink）。
+	InstallMode string
+	// VaultBranch 为本轮跟随的 vault 分支（未配置 vault_branch 时是远端默认分支）。
+	VaultBranch string
+	// PreviewBranch 非空表示本轮按评审分支预览拉取（PullWorkspaceBranchPreview），安装的是未合并的内容。
+	PreviewBranch string
+	// CopyFallbacks 列出 symlink 模式下回退为副本安装的资产及原因。
+	CopyFallbacks []string
+	// ReplacedOriginals 列出本轮按导入登记删除的非托管原件（dec-* 版本已装好）。
+	ReplacedOriginals []string
+	// Held 表示本轮拉取后项目固定在 VersionCommit（回滚或此前已固定），不跟随远端。
+	Held bool
+	// LockMismatches 仅回滚时填写：与当时记录的资产摘要不一致的资产（"type:vault:name"）。
+	LockMismatches []string
+	// Signer 是拉取版本（VersionCommit）的签名者，SignatureStatus 取值见 Signature*。
+	Signer          string
+	SignatureStatus string
+	// SignatureIssues 是 commit_trust 为 warn 时未通过校验、但仍照常安装的提交。
+	SignatureIssues []CommitSignatureIssue
+	// Offline 表示本轮没有同步远端，读的是本地 bare repo（离线模式或远端不可达）；
+	// CacheSyncedAt 是本地 bare repo 最近一次同步远端的时间，零值表示未知。
+	Offline       bool
+	OfflineReason string
+	CacheSyncedAt time.Time
 }
 
 func PullProjectAssets(ctx context.Context, projectRoot, version string, reporter Reporter) (*PullProjectAssetsResult, error) {
@@ -55,10 +79,24 @@ func PullProjectAssets(ctx context.Context, projectRoot, version string, reporte
 }
 
 // PullWorkspaceAssets 拉取并安装当前工作空间平面的公开资产与 secrets。
+// 项目已固定（RollbackWorkspacePull）时不跟随远端，仍拉取固定的版本。
 func PullWorkspaceAssets(ctx context.Context, workspace Workspace, version string, reporter Reporter) (*PullProjectAssetsResult, error) {
+	return pullWorkspaceAssets(ctx, workspace, pullRequest{version: version, source: PullSourcePull}, reporter)
+}
+
+// pullRequest 是一次拉取的版本与记录方式。
+type pullRequest struct {
+	// version 非空时拉取该版本，否则跟随 vault 分支
+	version string
+	// source 写入拉取记录，取值见 PullSource*
+	source string
+	// hold 为 true 时拉取后把项目固定在该版本
+	hold bool
+}
+
+func pullWorkspaceAssets(ctx context.Context, workspace Workspace, req pullRequest, reporter Reporter) (*PullProjectAssetsResult, error) {
 	reporter = defaultReporter(reporter)
 	projectRoot := workspace.Root
-	mgr := config.NewProjectConfigManager(projectRoot)
 	projectConfig, err := loadWorkspaceBundleConfig(workspace)
 	if err != nil {
 		return nil, err
@@ -80,6 +118,29 @@ func PullWorkspaceAssets(ctx context.Context, workspace Workspace, version strin
 	projectIDEs := uniqueWorkspaceIDEs(workspace, ideSelection.IDEs)
 	result.EffectiveIDEs = projectIDENames(projectIDEs)
 
+	installMode, err := config.ResolveInstallMode(projectConfig)
+	if err != nil {
+		return nil, fmt.Errorf("解析安装方式失败: %w", err)
+	}
+	result.InstallMode = installMode.Mode
+	result.IDEWarnings = append(result.IDEWarnings, installMode.Warnings...)
+	for _, warning := range installMode.Warnings {
+		emit(reporter, EventWarn, "pull.ide", warning, nil)
+	}
+
+	vaultBranch, err := resolveVaultBranch(projectConfig, reporter, "pull.prepare")
+	if err != nil {
+		return nil, err
+	}
+	if held := heldPullVersion(workspace); held != "" {
+		req.hold = true
+		if req.version == "" {
+			req.version = held
+			emit(reporter, EventWarn, "pull.prepare", fmt.Sprintf("📌 项目已固定在 %s，解除固定后才会拉取最新", shortCommit(held)), nil)
+		}
+	}
+	version := req.version
+
 	var migrationNotes []string
 	if workspace.EffectivePlane() == WorkspaceProject {
 		migrationNotes, err = migrateLegacyProjectLayouts(projectRoot, projectIDEs)
@@ -104,20 +165,26 @@ func PullWorkspaceAssets(ctx context.Context, workspace Workspace, version strin
 		return result, nil
 	}
 
-	createTx := func() (*repo.Transaction, error) {
-		if strings.TrimSpace(version) != "" {
-			return repo.NewReadTransactionAt(version)
-		}
-		return repo.NewReadTransaction()
-	}
-
-	tx, err := createTx()
+	tx, err := newPullReadTransaction(vaultBranch, version, projectEnabled)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Close()
+	applyOfflineRead(result, tx, reporter)
+	// 指定版本时事务停在默认分支上，.dec/.version 仍记录配置的频道，陈旧度检查才会对准它。
+	result.VaultBranch = tx.Branch()
+	if vaultBranch != "" {
+		result.VaultBranch = vaultBranch
+		emit(reporter, EventInfo, "pull.prepare", fmt.Sprintf("跟随 vault 分支 %s", vaultBranch), nil)
+	}
+
+	// 先校验签名再动本地文件：enforce 拒绝时连孤儿清理也不做。
+	if err := verifyPullSignatures(result, workspace, &pullConfig, tx.CommitHash(), projectEnabled, reporter); err != nil {
+		return nil, err
+	}
 
 	repoDir := tx.WorkDir()
+	warnNewerVaultFormat(result, repoDir, reporter)
 
 	resolved, err := resolveDesiredAssetsForPlane(&pullConfig, repoDir, workspace.EffectivePlane(), reporter)
 	if err != nil {
@@ -193,12 +260,19 @@ func PullWorkspaceAssets(ctx context.Context, workspace Workspace, version strin
 		return nil, err
 	}
 
-	// 阶段 2：从 cache 渲染安装到 IDE，并执行非敏感 vars 替换
+	// 阶段 2：在暂存区渲染全部资产并校验。模板错误或渲染后格式损坏的资产整个跳过，
+	// IDE 目录里保留上一次的版本，不会出现替换到一半的文件。
+	stage, err := prepareRenderStage(workspace, projectConfig, repoDir, projectEnabled, projectIDEs, reporter)
+	if err != nil {
+		return nil, err
+	}
+	defer stage.Close()
+	pendingAssets := make([]*pendingAsset, 0, len(validAssets))
 	for idx, asset := range validAssets {
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
-		progress := &Progress{Phase: "install", Current: idx + 1, Total: len(validAssets)}
+		progress := &Progress{Phase: "render", Current: idx + 1, Total: len(validAssets)}
 		fullPath := resolveAssetFile(repoDir, asset.Vault, asset.Type, asset.Name)
 		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
 			continue
@@ -208,40 +282,67 @@ func PullWorkspaceAssets(ctx context.Context, workspace Workspace, version strin
 			continue
 		}
 
-		if err := installAssetToIDEs(asset.Type, asset.Name, asset.Vault, fullPath, workspace, projectIDEs); err != nil {
+		if asset.Type == "mcp" {
+			emitMCPEnvRefLint(reporter, "pull.lint", fullPath, asset, workspace, projectIDEs)
+		}
+		pending, err := stage.render(asset, fullPath, reporter)
+		if err != nil {
+			result.FailedCount++
+			emit(reporter, EventWarn, "pull.asset", fmt.Sprintf("⚠️  [%-5s] %s 渲染失败，未安装: %v", asset.Type, asset.Name, err), progress)
+			continue
+		}
+		pendingAssets = append(pendingAssets, pending)
+	}
+
+	// 阶段 3：全部渲染完成后再落地到 IDE
+	installedAssets := make([]types.TypedAssetRef, 0, len(pendingAssets))
+	for idx, pending := range pendingAssets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		asset := pending.Asset
+		progress := &Progress{Phase: "install", Current: idx + 1, Total: len(pendingAssets)}
+		if err := stage.install(pending); err != nil {
 			result.FailedCount++
 			emit(reporter, EventWarn, "pull.asset", fmt.Sprintf("⚠️  [%-5s] %s (%v)", asset.Type, asset.Name, err), progress)
 			continue
 		}
 
-		if workspace.EffectivePlane() == WorkspaceProject {
-			substituteAssetVars(asset.Type, asset.Name, projectRoot, projectIDEs, mgr, reporter)
+		if result.InstallMode == types.InstallModeSymlink {
+			if linked, reason := linkInstalledAsset(asset.Type, asset.Name, workspace, projectIDEs); !linked && reason != "" {
+				result.CopyFallbacks = append(result.CopyFallbacks, fmt.Sprintf("[%-5s] %s：%s", asset.Type, asset.Name, reason))
+				emit(reporter, EventWarn, "pull.asset", fmt.Sprintf("↩️  [%-5s] %s 回退为副本安装：%s", asset.Type, asset.Name, reason), progress)
+			}
+		} else {
+			removeStagedAsset(workspace, asset.Type, asset.Name)
 		}
 
 		result.PulledCount++
+		installedAssets = append(installedAssets, asset)
 		emit(reporter, EventInfo, "pull.asset", fmt.Sprintf("✅ [%-5s] %s (vault: %s)", asset.Type, asset.Name, asset.Vault), progress)
 	}
 
-	// 阶段 3：secrets 放在公开资产之后。Bitwarden 不可用（未解锁、网络故障）
+	replaced, err := replaceImportedOriginals(workspace, installedAssets, projectIDEs)
+	if err != nil {
+		emit(reporter, EventWarn, "pull.import", fmt.Sprintf("替换导入原件失败: %v", err), nil)
+	}
+	result.ReplacedOriginals = replaced
+	for _, item := range replaced {
+		emit(reporter, EventInfo, "pull.import", fmt.Sprintf("♻️  %s 原件已由 Dec 托管版本替换", item), nil)
+	}
+
+	// 阶段 4：secrets 放在公开资产之后。Bitwarden 不可用（未解锁、网络故障）
 	// 不应连累已经就绪的 skill / rule / mcp，否则一次解锁失败会让整个项目看起来没装过资产。
 	// 契约：公开资产已落地时 secrets 失败 → result + NonFatalWarnings，error 为 nil。
 	if err := applySecretsPull(ctx, result, workspace, enabledBundleNames, reporter); err != nil {
 		emit(reporter, EventWarn, "pull.secrets",
 			fmt.Sprintf("已安装 %d 个公开资产；Secrets 未同步", result.PulledCount), nil)
 		result.NonFatalWarnings = append(result.NonFatalWarnings, err.Error())
-		commitHash := tx.CommitHash()
-		if commitHash != "" {
-			result.VersionCommit = commitHash
-			saveVersionMeta(workspaceCacheRoot(workspace), commitHash)
-		}
+		finishPullVersion(result, workspace, tx.CommitHash(), req, projectEnabled, validAssets, reporter)
 		return result, nil
 	}
 
-	commitHash := tx.CommitHash()
-	if commitHash != "" {
-		result.VersionCommit = commitHash
-		saveVersionMeta(workspaceCacheRoot(workspace), commitHash)
-	}
+	finishPullVersion(result, workspace, tx.CommitHash(), req, projectEnabled, validAssets, reporter)
 
 	summary := fmt.Sprintf("✅ 完成：%d 个资产已拉取", result.PulledCount)
 	if result.FailedCount > 0 {
@@ -259,6 +360,21 @@ func PullWorkspaceAssets(ctx context.Context, workspace Workspace, version strin
 	return result, nil
 }
 
+// prepareRenderStage 读取本轮渲染要用的变量层并建好暂存区。两个平面都做 vars 替换：
+// 项目平面用项目 vars、active profile 与 vault 项目共享变量，用户平面只有本机 vars 与 bundle 共享变量。
+func prepareRenderStage(workspace Workspace, projectConfig *types.ProjectConfig, repoDir string, enabledBundles []string, ides []ide.IDE, reporter Reporter) (*renderStage, error) {
+	projectName := ""
+	if workspace.EffectivePlane() == WorkspaceProject {
+		projectName, _ = ResolveProjectName(workspace.Root, projectConfig)
+	}
+	shared, warnings := loadVaultSharedVars(repoDir, projectName, enabledBundles)
+	rv, varsWarnings := loadRenderVars(workspace, projectConfig, shared)
+	for _, warning := range append(warnings, varsWarnings...) {
+		emit(reporter, EventWarn, "pull.vars", warning, nil)
+	}
+	return newRenderStage(workspace, ides, rv, enabledBundles)
+}
+
 // missingEnabledBundleNames 返回启用列表里在本平面 vault 中找不到声明的 bundle 名（保序）。
 func missingEnabledBundleNames(enabled []string, resolved []BundleOverview) []string {
 	if len(enabled) == 0 {
@@ -413,6 +529,7 @@ func cleanupRemovedAssets(workspace Workspace, enabledAssets []types.TypedAssetR
 				for _, ideImpl := range projectIDEs {
 					_, _ = removeAssetFromIDE(assetType, name, workspace, ideImpl)
 				}
+				removeStagedAsset(workspace, assetType, name)
 				_ = os.RemoveAll(filepath.Join(subDir, entry.Name()))
 				removed = append(removed, fmt.Sprintf("[%-5s] %s (vault: %s)", assetType, name, vaultName))
 			}
@@ -499,23 +616,6 @@ func managedName(name string) string {
 	return "dec-" + name
 }
 
-func installAssetToIDEs(itemType, assetName, vaultName, srcPath string, workspace Workspace, projectIDEs []ide.IDE) error {
-	installed := make([]ide.IDE, 0, len(projectIDEs))
-
-	for _, ideImpl := range projectIDEs {
-		if err := installAssetToIDEForWorkspace(itemType, assetName, vaultName, srcPath, workspace, ideImpl); err != nil {
-			rollbackErrors := rollbackInstalledAsset(itemType, assetName, workspace, installed)
-			if len(rollbackErrors) > 0 {
-				return fmt.Errorf("安装到 %s 失败: %v；回滚失败: %s", ideImpl.Name(), err, strings.Join(rollbackErrors, "; "))
-			}
-			return fmt.Errorf("安装到 %s 失败: %w", ideImpl.Name(), err)
-		}
-		installed = append(installed, ideImpl)
-	}
-
-	return nil
-}
-
 func rollbackInstalledAsset(itemType, assetName string, workspace Workspace, installed []ide.IDE) []string {
 	var rollbackErrors []string
 	for i := len(installed) - 1; i >= 0; i-- {
@@ -534,58 +634,21 @@ func installAssetToIDE(itemType, assetName, vaultName, srcPath, projectRoot stri
 	return installAssetToIDEForWorkspace(itemType, assetName, vaultName, srcPath, NewWorkspace(WorkspaceProject, projectRoot), ideImpl)
 }
 
+// installAssetToIDEForWorkspace 不做 vars 替换，直接把资产装到一个 IDE（经同一暂存流程）。
 func installAssetToIDEForWorkspace(itemType, assetName, vaultName, srcPath string, workspace Workspace, ideImpl ide.IDE) error {
-	managed := managedName(assetName)
-	home, _ := os.UserHomeDir()
-	plane := workspace.IDEPlane()
-	projectRoot := workspace.Root
-
-	switch itemType {
-	case "skill":
-		destDir := filepath.Join(ideImpl.SkillsDirForPlane(plane, projectRoot, home), managed)
-		if err := copyDir(srcPath, destDir); err != nil {
-			return err
-		}
-		return injectRenderedHeaderDir(destDir, vaultName)
-	case "command":
-		destDir := filepath.Join(ideImpl.CommandsDirForPlane(plane, projectRoot, home), managed)
-		if err := copyDir(srcPath, destDir); err != nil {
-			return err
-		}
-		return injectRenderedHeaderDir(destDir, vaultName)
-	case "rule":
-		destDir := ideImpl.RulesDirForPlane(plane, projectRoot, home)
-		if err := os.MkdirAll(destDir, 0755); err != nil {
-			return err
-		}
-		destPath := filepath.Join(destDir, managed+".mdc")
-		if err := copyFile(srcPath, destPath); err != nil {
-			return err
-		}
-		return injectRenderedHeaderFile(destPath, vaultName)
-	case "mcp":
-		data, err := os.ReadFile(srcPath)
-		if err != nil {
-			return fmt.Errorf("读取 MCP 配置失败: %w", err)
-		}
-		var server types.MCPServer
-		if err := json.Unmarshal(data, &server); err != nil {
-			return fmt.Errorf("解析 MCP 配置失败: %w", err)
-		}
-		cmd, args := stripExternalEnvLauncher(server.Command, server.Args)
-		server.Command, server.Args, server.Env = WrapMCPServerWithExecForPlane(projectRoot, vaultName, workspace.SecretsPlane(), "dec-exec", cmd, args, server.Env)
-		existingConfig, err := ideImpl.LoadMCPConfigForPlane(plane, projectRoot, home)
-		if err != nil {
-			return fmt.Errorf("加载 IDE MCP 配置失败: %w", err)
-		}
-		if existingConfig.MCPServers == nil {
-			existingConfig.MCPServers = make(map[string]types.MCPServer)
-		}
-		existingConfig.MCPServers[managed] = server
-		return ideImpl.WriteMCPConfigForPlane(plane, projectRoot, home, existingConfig)
-	default:
+	if !ide.SupportsAssetType(ideImpl, itemType) {
 		return nil
 	}
+	stageRoot, err := os.MkdirTemp("", "dec-stage-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stageRoot)
+	out, err := stageAssetOutput(itemType, assetName, vaultName, srcPath, filepath.Join(stageRoot, filepath.Base(srcPath)), workspace, ideImpl)
+	if err != nil {
+		return err
+	}
+	return installPendingOutput(itemType, assetName, out, workspace)
 }
 
 func removeAssetFromIDE(itemType, assetName string, workspace Workspace, ideImpl ide.IDE) (bool, error) {
@@ -595,30 +658,23 @@ func removeAssetFromIDE(itemType, assetName string, workspace Workspace, ideImpl
 	projectRoot := workspace.Root
 
 	switch itemType {
-	case "skill":
-		destDir := filepath.Join(ideImpl.SkillsDirForPlane(plane, projectRoot, home), managed)
-		if _, err := os.Stat(destDir); os.IsNotExist(err) {
-			return false, nil
-		} else if err != nil {
-			return false, err
-		}
-		return true, os.RemoveAll(destDir)
-	case "command":
-		destDir := filepath.Join(ideImpl.CommandsDirForPlane(plane, projectRoot, home), managed)
-		if _, err := os.Stat(destDir); os.IsNotExist(err) {
+	case "skill", "command", "rule":
+		// 用 Lstat：symlink 模式下链接目标可能已被清掉，悬空链接也要能识别并删除。
+		destPath := ideAssetPath(itemType, assetName, workspace, ideImpl)
+		info, err := os.Lstat(destPath)
+		if os.IsNotExist(err) {
 			return false, nil
 		} else if err != nil {
 			return false, err
 		}
-		return true, os.RemoveAll(destDir)
-	case "rule":
-		destPath := filepath.Join(ideImpl.RulesDirForPlane(plane, projectRoot, home), managed+".mdc")
-		if err := os.Remove(destPath); os.IsNotExist(err) {
-			return false, nil
-		} else if err != nil {
-			return false, err
+		if info.Mode()&os.ModeSymlink != 0 {
+			// 只删指向渲染区的链接；同名但指向别处的链接不是 Dec 建的。
+			if !isDecOwnedLink(destPath, workspace) {
+				return false, nil
+			}
+			return true, os.Remove(destPath)
 		}
-		return true, nil
+		return true, os.RemoveAll(destPath)
 	case "mcp":
 		existingConfig, err := ideImpl.LoadMCPConfigForPlane(plane, projectRoot, home)
 		if err != nil {
@@ -634,96 +690,95 @@ func removeAssetFromIDE(itemType, assetName string, workspace Workspace, ideImpl
 	}
 }
 
-func substituteAssetVars(itemType, assetName, projectRoot string, projectIDEs []ide.IDE, mgr *config.ProjectConfigManager, reporter Reporter) {
-	globalVars, err := config.LoadGlobalVars()
-	if err != nil {
-		emit(reporter, EventWarn, "pull.vars", fmt.Sprintf("读取全局变量失败: %v", err), nil)
-		globalVars = nil
-	}
-	projectVars, err := mgr.LoadVarsConfig()
-	if err != nil {
-		emit(reporter, EventWarn, "pull.vars", fmt.Sprintf("解析 %s 失败: %v", mgr.GetVarsPath(), err), nil)
-		projectVars = nil
+// substituteAssetVars 对暂存区里资产的每份 IDE 副本做 vars 替换。DEC_BUNDLE / DEC_IDE 按 bundle 与
+// IDE 逐份补上，因此各 IDE 的输出可能不同。选择了模板（tmpl）的文件改走 text/template，见 substituteAssetFiles。
+// 缺失变量只告警；模板错误会让整个资产渲染失败，由调用方跳过安装。
+func substituteAssetVars(pending *pendingAsset, rv *renderVars, tmpl assetTemplate, reporter Reporter) error {
+	asset := pending.Asset
+	layers := rv.layers(asset.Vault)
+
+	// 同一模板错误在每个 IDE 的副本上都会出现，只报一次
+	var templateErrs []string
+	reported := make(map[string]bool)
+	collect := func(errs []error) {
+		for _, err := range errs {
+			if msg := err.Error(); !reported[msg] {
+				reported[msg] = true
+				templateErrs = append(templateErrs, msg)
+			}
+		}
 	}
-	projectVarsPath := mgr.GetVarsPath()
-	globalVarsPath, _ := config.GetGlobalVarsPath()
 
-	if (globalVars == nil || len(globalVars.Vars) == 0) && (projectVars == nil || len(projectVars.Vars) == 0) {
-		if globalVars != nil && globalVars.Assets != nil {
-			// 可能有资产级变量，继续。
-		} else if projectVars != nil && projectVars.Assets != nil {
-			// 可能有资产级变量，继续。
-		} else {
-			return
+	// 即使没有定义任何变量也要继续：default 与转义形式同样需要改写。
+	for _, out := range pending.Outputs {
+		ideName := out.IDE.Name()
+		scoped := layers
+		scoped.Builtins = vars.WithBuiltinScope(layers.Builtins, asset.Vault, ideName)
+		data := tmpl.data(scoped, asset.Type, asset.Name, asset.Vault, ideName)
+
+		if out.Server != nil {
+			missing, locations, errs := substituteMCPVars(out.Server, asset.Name, out.Dest, scoped, tmpl, data)
+			collect(errs)
+			emitMissingVars(reporter, asset.Type, asset.Name, missing, locations, rv.ProjectVarsPath, rv.GlobalVarsPath)
+			continue
+		}
+		// Walk 对单文件同样适用，rule 与 skill / command 走同一路径
+		placeholders := vars.ExtractPlaceholdersFromDir(out.Path)
+		locations := sourcePlaceholderLocations(vars.ExtractPlaceholderLocationsFromDir(out.Path), out.Path, tmpl.Source)
+		resolved := vars.ResolveLayeredVars(scoped, asset.Type, asset.Name, placeholders)
+		missing, errs, err := substituteAssetFiles(out.Path, resolved, tmpl, data)
+		if err != nil {
+			return fmt.Errorf("变量替换失败 (%s): %w", ideName, err)
 		}
+		collect(errs)
+		emitMissingVars(reporter, asset.Type, asset.Name, missing, locations, rv.ProjectVarsPath, rv.GlobalVarsPath)
+	}
+	if len(templateErrs) > 0 {
+		return fmt.Errorf("模板渲染失败: %s", strings.Join(templateErrs, "; "))
 	}
+	return nil
+}
 
-	for _, ideImpl := range projectIDEs {
-		ideName := ideImpl.Name()
-
-		switch itemType {
-		case "skill":
-			localPath := filepath.Join(ideImpl.SkillsDir(projectRoot), managedName(assetName))
-			placeholders := vars.ExtractPlaceholdersFromDir(localPath)
-			locations := vars.ExtractPlaceholderLocationsFromDir(localPath)
-			if len(placeholders) == 0 {
-				continue
-			}
-			resolved := vars.ResolveVars(globalVars, projectVars, itemType, assetName, placeholders)
-			_, missing, err := vars.SubstituteDir(localPath, resolved)
-			if err != nil {
-				emit(reporter, EventWarn, "pull.vars", fmt.Sprintf("变量替换失败 (%s): %v", ideName, err), nil)
-				continue
-			}
-			emitMissingVars(reporter, itemType, assetName, missing, locations, projectVarsPath, globalVarsPath)
-		case "command":
-			localPath := filepath.Join(ideImpl.CommandsDir(projectRoot), managedName(assetName))
-			placeholders := vars.ExtractPlaceholdersFromDir(localPath)
-			locations := vars.ExtractPlaceholderLocationsFromDir(localPath)
-			if len(placeholders) == 0 {
-				continue
-			}
-			resolved := vars.ResolveVars(globalVars, projectVars, itemType, assetName, placeholders)
-			_, missing, err := vars.SubstituteDir(localPath, resolved)
-			if err != nil {
-				emit(reporter, EventWarn, "pull.vars", fmt.Sprintf("变量替换失败 (%s): %v", ideName, err), nil)
-				continue
+// sourcePlaceholderLocations 把暂存副本中的位置换算成 cache 中的路径，提示用户该改哪个文件。
+func sourcePlaceholderLocations(locations map[string][]string, stagePath, source string) map[string][]string {
+	for name, paths := range locations {
+		for idx, path := range paths {
+			if rel, err := filepath.Rel(stagePath, path); err == nil && rel != "." {
+				paths[idx] = filepath.Join(source, rel)
+			} else {
+				paths[idx] = source
 			}
-			emitMissingVars(reporter, itemType, assetName, missing, locations, projectVarsPath, globalVarsPath)
-		case "rule":
-			localPath := filepath.Join(ideImpl.RulesDir(projectRoot), managedName(assetName)+".mdc")
-			placeholders := vars.ExtractPlaceholdersFromFile(localPath)
-			locations := vars.ExtractPlaceholderLocationsFromFile(localPath)
-			if len(placeholders) == 0 {
-				continue
-			}
-			resolved := vars.ResolveVars(globalVars, projectVars, itemType, assetName, placeholders)
-			_, missing, err := vars.SubstituteFile(localPath, resolved)
-			if err != nil {
-				emit(reporter, EventWarn, "pull.vars", fmt.Sprintf("变量替换失败 (%s): %v", ideName, err), nil)
-				continue
-			}
-			emitMissingVars(reporter, itemType, assetName, missing, locations, projectVarsPath, globalVarsPath)
-		case "mcp":
-			_, missing, locations := substituteMCPVars(assetName, projectRoot, ideImpl, globalVars, projectVars, reporter)
-			emitMissingVars(reporter, itemType, assetName, missing, locations, projectVarsPath, globalVarsPath)
 		}
+		locations[name] = paths
 	}
+	return locations
 }
 
-func substituteMCPVars(assetName, projectRoot string, ideImpl ide.IDE, globalVars, projectVars *types.VarsConfig, reporter Reporter) (map[string]string, []string, map[string][]string) {
-	managed := managedName(assetName)
-	configPath := ideImpl.MCPConfigPath(projectRoot)
-
-	existingConfig, err := ideImpl.LoadMCPConfig(projectRoot)
-	if err != nil {
-		emit(reporter, EventWarn, "pull.vars", fmt.Sprintf("加载 MCP 配置失败: %v", err), nil)
-		return nil, nil, nil
-	}
-
-	server, ok := existingConfig.MCPServers[managed]
-	if !ok {
-		return nil, nil, nil
+// substituteMCPVars 替换 MCP 条目的 env / args / command，直接改写 server。
+// bundle.yaml 声明了模板时，这些字段逐个按 text/template 渲染，错误名形如 "<cache 路径>#env.KEY"。
+func substituteMCPVars(server *types.MCPServer, assetName, configPath string, layers vars.Layers, tmpl assetTemplate, data vars.TemplateData) ([]string, map[string][]string, []error) {
+	if tmpl.OptIn {
+		var templateErrs []error
+		render := func(field, value string) string {
+			rendered, err := vars.RenderTemplate(tmpl.Source+"#"+field, value, data)
+			if err != nil {
+				templateErrs = append(templateErrs, err)
+				return value
+			}
+			return rendered
+		}
+		if server.Env != nil {
+			newEnv := make(map[string]string, len(server.Env))
+			for key, value := range server.Env {
+				newEnv[key] = render("env."+key, value)
+			}
+			server.Env = newEnv
+		}
+		for idx, arg := range server.Args {
+			server.Args[idx] = render(fmt.Sprintf("args[%d]", idx), arg)
+		}
+		server.Command = render("command", server.Command)
+		return nil, nil, templateErrs
 	}
 
 	var allContent string
@@ -737,57 +792,43 @@ func substituteMCPVars(assetName, projectRoot string, ideImpl ide.IDE, globalVar
 	}
 	allContent += server.Command
 
-	placeholders := vars.ExtractPlaceholders(allContent)
-	if len(placeholders) == 0 {
+	if err := vars.CheckFilters(allContent); err != nil {
+		return nil, nil, []error{fmt.Errorf("%s: %w", tmpl.Source, err)}
+	}
+	if !vars.HasPlaceholders(allContent) {
 		return nil, nil, nil
 	}
+	placeholders := vars.ExtractPlaceholders(allContent)
 
 	locations := make(map[string][]string, len(placeholders))
 	for _, placeholder := range placeholders {
 		locations[placeholder] = []string{configPath}
 	}
 
-	resolved := vars.ResolveVars(globalVars, projectVars, "mcp", assetName, placeholders)
-	used := make(map[string]string)
+	resolved := vars.ResolveLayeredVars(layers, "mcp", assetName, placeholders)
 	var missing []string
 
 	if server.Env != nil {
 		newEnv := make(map[string]string)
 		for key, value := range server.Env {
-			newVal, usedVars, missingVars := vars.Substitute(value, resolved)
+			newVal, _, missingVars := vars.Substitute(value, resolved)
 			newEnv[key] = newVal
-			for usedKey, usedValue := range usedVars {
-				used[usedKey] = usedValue
-			}
 			missing = append(missing, missingVars...)
 		}
 		server.Env = newEnv
 	}
 
 	for idx, arg := range server.Args {
-		newArg, usedVars, missingVars := vars.Substitute(arg, resolved)
+		newArg, _, missingVars := vars.Substitute(arg, resolved)
 		server.Args[idx] = newArg
-		for usedKey, usedValue := range usedVars {
-			used[usedKey] = usedValue
-		}
 		missing = append(missing, missingVars...)
 	}
 
-	newCommand, usedVars, missingVars := vars.Substitute(server.Command, resolved)
+	newCommand, _, missingVars := vars.Substitute(server.Command, resolved)
 	server.Command = newCommand
-	for usedKey, usedValue := range usedVars {
-		used[usedKey] = usedValue
-	}
 	missing = append(missing, missingVars...)
 
-	if len(used) > 0 {
-		existingConfig.MCPServers[managed] = server
-		if err := ideImpl.WriteMCPConfig(projectRoot, existingConfig); err != nil {
-			emit(reporter, EventWarn, "pull.vars", fmt.Sprintf("写入 MCP 配置失败: %v", err), nil)
-		}
-	}
-
-	return used, missing, locations
+	return missing, locations, nil
 }
 
 func emitMissingVars(reporter Reporter, itemType, assetName string, missing []string, locations map[string][]string, projectVarsPath, globalVarsPath string) {
@@ -803,7 +844,9 @@ func emitMissingVars(reporter Reporter, itemType, assetName string, missing []st
 		for _, location := range formatPlaceholderLocations(locations[placeholder]) {
 			lines = append(lines, "来源: "+location)
 		}
-		lines = append(lines, fmt.Sprintf("项目级: %s -> vars.%s 或 assets.%s.%s.vars.%s", projectVarsPath, placeholder, itemType, assetName, placeholder))
+		if strings.TrimSpace(projectVarsPath) != "" {
+			lines = append(lines, fmt.Sprintf("项目级: %s -> vars.%s 或 assets.%s.%s.vars.%s", projectVarsPath, placeholder, itemType, assetName, placeholder))
+		}
 		if strings.TrimSpace(globalVarsPath) != "" {
 			lines = append(lines, fmt.Sprintf("本机级: %s -> vars.%s 或 assets.%s.%s.vars.%s", globalVarsPath, placeholder, itemType, assetName, placeholder))
 		}
@@ -947,11 +990,43 @@ func enabledBundleNamesFromConfig(projectConfig *types.ProjectConfig, overviews
 	return names
 }
 
-func saveVersionMeta(projectRoot, commitHash string) {
+// finishPullVersion 记录本轮拉取的版本：写 .dec/.version 并追加拉取记录。
+func finishPullVersion(result *PullProjectAssetsResult, workspace Workspace, commitHash string, req pullRequest, enabled []string, assets []types.TypedAssetRef, reporter Reporter) {
+	if commitHash == "" {
+		return
+	}
+	result.VersionCommit = commitHash
+	result.Held = req.hold
+	now := time.Now()
+	_ = writeVersionMeta(workspaceCacheRoot(workspace), freshness.VersionMeta{
+		Commit:   commitHash,
+		PulledAt: now.Format(time.RFC3339),
+		Branch:   result.VaultBranch,
+		Held:     req.hold,
+	})
+	recordPullHistory(workspace, PullHistoryEntry{
+		Commit:         commitHash,
+		Branch:         result.VaultBranch,
+		PulledAt:       now,
+		Source:         req.source,
+		EnabledBundles: append([]string(nil), enabled...),
+		Locks:          workspaceAssetLocks(workspace, assets),
+	}, reporter)
+}
+
+func writeVersionMeta(projectRoot string, meta freshness.VersionMeta) error {
 	versionPath := filepath.Join(projectRoot, ".dec", ".version")
-	content := fmt.Sprintf("commit: %s\npulled_at: %q\n", commitHash, time.Now().Format(time.RFC3339))
-	_ = os.MkdirAll(filepath.Dir(versionPath), 0755)
-	_ = os.WriteFile(versionPath, []byte(content), 0644)
+	content := fmt.Sprintf("commit: %s\npulled_at: %q\n", meta.Commit, meta.PulledAt)
+	if meta.Branch != "" {
+		content += fmt.Sprintf("branch: %s\n", meta.Branch)
+	}
+	if meta.Held {
+		content += "held: true\n"
+	}
+	if err := os.MkdirAll(filepath.Dir(versionPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(versionPath, []byte(content), 0644)
 }
 
 // stripExternalEnvLauncher 去掉历史外部启动器外壳（如 mise exec ... --），返回真实命令。
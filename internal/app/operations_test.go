@@ -3,12 +3,14 @@ package app
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/ide"
 	"github.com/shichao402/Dec/internal/repo"
 	"github.com/shichao402/Dec/internal/secrets"
 	"github.com/shichao402/Dec/internal/types"
@@ -214,10 +216,10 @@ members:
 	}
 }
 
-// substituteAssetVars 之前会用 `_` 吞掉 LoadVarsConfig 的 error，
+// 变量层之前会用 `_` 吞掉 LoadVarsConfig 的 error，
 // 导致 YAML 语法错误下变量被静默丢弃。本用例覆盖修复后的感知路径：
-// 解析失败必须通过 reporter 发一个 EventWarn 事件，且消息里带 vars.yaml 路径。
-func TestSubstituteAssetVarsReportsProjectVarsParseError(t *testing.T) {
+// 解析失败必须作为告警返回（pull 以 pull.vars 事件发出），且消息里带 vars.yaml 路径。
+func TestLoadRenderVarsReportsProjectVarsParseError(t *testing.T) {
 	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
 
 	projectRoot := t.TempDir()
@@ -231,28 +233,172 @@ func TestSubstituteAssetVarsReportsProjectVarsParseError(t *testing.T) {
 		t.Fatalf("写入损坏的 vars.yaml 失败: %v", err)
 	}
 
+	rv, warnings := loadRenderVars(NewWorkspace(WorkspaceProject, projectRoot), &types.ProjectConfig{}, nil)
+	if rv.Project != nil {
+		t.Fatalf("解析失败时不应带出项目变量: %#v", rv.Project)
+	}
+	var sawWarn bool
+	for _, warning := range warnings {
+		if strings.Contains(warning, varsPath) && strings.Contains(warning, "解析") {
+			sawWarn = true
+			break
+		}
+	}
+	if !sawWarn {
+		t.Fatalf("期望收到包含 vars.yaml 路径的告警, 实际: %#v", warnings)
+	}
+}
+
+// 内置变量 DEC_IDE / DEC_BUNDLE 按每份 IDE 输出分别渲染，用户同名定义优先于内置值。
+func TestRenderStageRendersBuiltinsPerIDE(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	projectRoot := t.TempDir()
+	writeFile(t, filepath.Join(projectRoot, ".dec", "vars.yaml"), "vars:\n  DEC_OS: custom-os\n")
+	srcPath := filepath.Join(t.TempDir(), "style.mdc")
+	writeFile(t, srcPath, "ide={{DEC_IDE}} bundle={{DEC_BUNDLE}} name={{DEC_PROJECT_NAME}} os={{DEC_OS}}")
+
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	ides := []ide.IDE{ide.Get("cursor"), ide.Get("claude")}
+	rv, _ := loadRenderVars(workspace, &types.ProjectConfig{}, nil)
+	stage, err := newRenderStage(workspace, ides, rv, []string{"team"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stage.Close()
+	pending, err := stage.render(types.TypedAssetRef{Type: "rule", AssetRef: types.AssetRef{Name: "style", Vault: "team"}}, srcPath, nil)
+	if err != nil {
+		t.Fatalf("render() 失败: %v", err)
+	}
+	if err := stage.install(pending); err != nil {
+		t.Fatalf("install() 失败: %v", err)
+	}
+
+	for _, name := range []string{"cursor", "claude"} {
+		data, err := os.ReadFile(filepath.Join(projectRoot, "."+name, "rules", "dec-style.mdc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf("ide=%s bundle=team name=%s os=custom-os", name, filepath.Base(projectRoot))
+		if !strings.HasSuffix(string(data), want) {
+			t.Fatalf("%s 渲染结果 = %q, 期望以 %q 结尾", name, data, want)
+		}
+	}
+}
+
+func TestPullProjectAssetsAppliesVaultSharedVars(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/team/rules/links.mdc": "jira={{JIRA_URL}} region={{REGION}} owner={{OWNER}}\n",
+		"bundles/team/vars.yaml":       "vars:\n  JIRA_URL: https://jira.example.com\n  REGION: bundle-region\n  OWNER: bundle-owner\n",
+		"projects/app.yaml":            "name: app\nbundles:\n  - team\nvars:\n  REGION: project-region\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+
+	projectRoot := t.TempDir()
 	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{
+		ProjectName:    "app",
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"team"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	writeFile(t, mgr.GetVarsPath(), "vars:\n  OWNER: local-owner\n")
 
-	var events []OperationEvent
-	reporter := ReporterFunc(func(event OperationEvent) {
-		events = append(events, event)
+	if _, err := PullProjectAssets(context.Background(), projectRoot, "", nil); err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(projectRoot, ".cursor", "rules", "dec-links.mdc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "jira=https://jira.example.com region=project-region owner=local-owner\n"; !strings.HasSuffix(string(data), want) {
+		t.Fatalf("vault 共享变量应低于本地层、project 高于 bundle:\n got %q\nwant %q", data, want)
+	}
+
+	view, err := LoadProjectVarsView(projectRoot)
+	if err != nil {
+		t.Fatalf("LoadProjectVarsView() 失败: %v", err)
+	}
+	wantSources := map[string]PlaceholderStatus{
+		"JIRA_URL": {Name: "JIRA_URL", Value: "https://jira.example.com", Source: PlaceholderSourceVaultBundle, Origin: "team"},
+		"REGION":   {Name: "REGION", Value: "project-region", Source: PlaceholderSourceVaultProject},
+		"OWNER":    {Name: "OWNER", Value: "local-owner", Source: PlaceholderSourceProject},
+	}
+	for name, want := range wantSources {
+		if got := view.ResolvedVars[name]; got != want {
+			t.Fatalf("ResolvedVars[%s] = %+v, 期望 %+v", name, got, want)
+		}
+	}
+}
+
+func TestPullProjectAssetsRendersOptInTemplates(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/team/bundle.yaml":       "name: team\nmembers:\n  - rules/listing\n  - rules/ide\n  - rules/plain\n  - rules/broken\n  - rules/filter\ntemplates:\n  - rules/listing\n",
+		"bundles/team/rules/listing.mdc": "{{range .Bundles}}[{{.}}]{{end}} {{.Vars.REGION}} {{.Plane}}\n",
+		"bundles/team/rules/ide.mdc":     "---\ndec_template: true\n---\n{{if eq .IDE \"codex\"}}codex{{else}}other{{end}}\n",
+		"bundles/team/rules/plain.mdc":   "region={{REGION}}\n",
+		"bundles/team/rules/broken.mdc":  "---\ndec_template: true\n---\nok\n{{.Vars.NOPE}}\n",
+		"bundles/team/rules/filter.mdc":  "region={{REGION|reverse}}\n",
 	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
 
-	// projectIDEs 留空即可：LoadVarsConfig 的 error 在进入 IDE 循环之前就应该被报告。
-	substituteAssetVars("skill", "any-asset", projectRoot, nil, mgr, reporter)
+	projectRoot := t.TempDir()
+	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"team"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	writeFile(t, mgr.GetVarsPath(), "vars:\n  REGION: eu\n")
 
-	var sawWarn bool
-	for _, event := range events {
-		if event.Level != EventWarn || event.Scope != "pull.vars" {
-			continue
+	var warnings []string
+	reporter := ReporterFunc(func(event OperationEvent) {
+		if event.Level == EventWarn {
+			warnings = append(warnings, event.Message)
 		}
-		if strings.Contains(event.Message, varsPath) && strings.Contains(event.Message, "解析") {
-			sawWarn = true
-			break
+	})
+	result, err := PullProjectAssets(context.Background(), projectRoot, "", reporter)
+	if err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	rulesDir := filepath.Join(projectRoot, ".cursor", "rules")
+	for file, want := range map[string]string{
+		"dec-listing.mdc": "[team] eu project\n",
+		"dec-ide.mdc":     "---\ndec_template: true\n---\nother\n",
+		"dec-plain.mdc":   "region=eu\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(rulesDir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(string(data), want) {
+			t.Fatalf("%s = %q, 期望以 %q 结尾", file, data, want)
 		}
 	}
-	if !sawWarn {
-		t.Fatalf("期望收到包含 vars.yaml 路径的 pull.vars warn 事件, 实际事件: %#v", events)
+	if !strings.Contains(strings.Join(warnings, "\n"), ".dec/cache/team/rules/broken.mdc:5") {
+		t.Fatalf("模板错误应指出 cache 文件与行号, warnings = %v", warnings)
+	}
+	// 渲染失败的资产整个不安装，IDE 目录里不留半成品
+	if _, err := os.Stat(filepath.Join(rulesDir, "dec-broken.mdc")); !os.IsNotExist(err) {
+		t.Fatalf("模板错误的资产不应安装, stat err = %v", err)
+	}
+	if !strings.Contains(strings.Join(warnings, "\n"), "未知过滤器: {{REGION|reverse}}") {
+		t.Fatalf("未知过滤器应报告, warnings = %v", warnings)
+	}
+	if _, err := os.Stat(filepath.Join(rulesDir, "dec-filter.mdc")); !os.IsNotExist(err) {
+		t.Fatalf("使用未知过滤器的资产不应安装, stat err = %v", err)
+	}
+	if result.FailedCount != 2 || result.PulledCount != 3 {
+		t.Fatalf("PulledCount/FailedCount = %d/%d, 期望 3/2", result.PulledCount, result.FailedCount)
 	}
 }
 
@@ -5,8 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/freshness"
 	"github.com/shichao402/Dec/internal/repo"
 	"github.com/shichao402/Dec/internal/secrets"
 	"github.com/shichao402/Dec/internal/types"
@@ -26,6 +28,32 @@ type ProjectOverview struct {
 	ProjectConfigReady    bool
 	VarsPath              string
 	VarsFileReady         bool
+	// VaultBranch 是当前平面跟随的 vault 分支（频道）；未配置 vault_branch 时为远端默认分支名，未连接时为空。
+	VaultBranch string
+	// VaultBranchSource 取值 project | global | default（types.VaultBranchSource*），标识 VaultBranch 的来源。
+	VaultBranchSource string
+	// PulledBranch 是 .dec/.version 记录的上次拉取分支；与 VaultBranch 不同说明切换频道后尚未重新拉取。
+	PulledBranch string
+	// PulledCommit 是 .dec/.version 记录的上次拉取版本；PullHeld 表示项目被固定在该版本（回滚后）。
+	PulledCommit string
+	PullHeld     bool
+	// PulledSigner 是 PulledCommit 的签名者，PulledSignatureStatus 取值见 Signature*；
+	// 仓库未连接或本地 bare 已找不到该提交时为空。CommitTrustPolicy 是 commit_trust 策略（types.CommitTrust*）。
+	PulledSigner          string
+	PulledSignatureStatus string
+	CommitTrustPolicy     string
+	// OfflineMode 是生效的离线模式（repo.Offline*: auto | on | off）；CacheSyncedAt 是本地 bare repo 最近一次同步远端的时间。
+	OfflineMode   string
+	CacheSyncedAt time.Time
+	// PushQueue 是离线时排队、尚未送到远端的 push，下次在线 push 时发送。
+	PushQueue []PushQueueEntry
+	// PushPolicy 是推送方式（types.PushPolicy*）；branch 时 push 进评审分支而不是 VaultBranch。
+	PushPolicy string
+	// VaultFormat 是 VaultBranch 上的 vault 格式（.dec-vault.yaml）；仅在 IncludeVaultBundles 时读取，读取失败为 nil。
+	VaultFormat *VaultFormatStatus
+	// PendingBranches 是本项目尚未合入 VaultBranch 的评审分支（dec/<user>/<project>/<timestamp>），
+	// 仅在 IncludeVaultBundles 时从本地 bare repo 读取。
+	PendingBranches []string
 	// AvailableBundleCount 是仓库里扫描到的 bundle 总数（含未启用）。
 	AvailableBundleCount int
 	// EnabledBundleCount 记录 project config 中 enabled_bundles 声明的数量。
@@ -103,11 +131,46 @@ func LoadWorkspaceOverviewOpts(workspace Workspace, opts OverviewLoadOpts) (*Pro
 		overview.ProjectName, overview.ProjectNameFromConfig = ResolveProjectName(projectRoot, projectConfig)
 	}
 
+	vaultBranch, err := config.ResolveVaultBranch(projectConfig)
+	if err != nil {
+		return nil, err
+	}
+	overview.VaultBranch = vaultBranch.Branch
+	overview.VaultBranchSource = vaultBranch.Source
+	if overview.VaultBranch == "" && connected {
+		overview.VaultBranch, _ = repo.GetDefaultBranch()
+	}
+	if meta, metaErr := freshness.LoadVersionMeta(workspaceCacheRoot(workspace)); metaErr == nil && meta != nil && meta.Commit != "" {
+		overview.PulledBranch = meta.Branch
+		overview.PulledCommit = meta.Commit
+		overview.PullHeld = meta.Held
+	}
+	overview.OfflineMode = repo.OfflineMode()
+	if connected {
+		overview.CacheSyncedAt, _ = repo.LastFetchTime()
+		overview.PushQueue, _ = LoadPushQueue()
+	}
+	if trust, trustErr := config.ResolveCommitTrust(); trustErr == nil {
+		overview.CommitTrustPolicy = trust.Policy
+		if connected && overview.PulledCommit != "" {
+			if sig, sigErr := repo.VerifyCommitSignature(overview.PulledCommit, trust.Signers); sigErr == nil {
+				overview.PulledSigner = sig.Signer
+				overview.PulledSignatureStatus = sig.Status
+			}
+		}
+	}
+	if pushPolicy, policyErr := config.ResolvePushPolicy(); policyErr == nil {
+		overview.PushPolicy = pushPolicy.Policy
+	}
+
 	// 仓库已连接时扫描 vault 内的 bundle 声明，并根据 EnabledBundles 标记启用状态。
 	// 失败时不阻塞 overview（bundle 是增量能力，项目级配置仍应可读）。
 	if connected && opts.IncludeVaultBundles {
-		tx, txErr := repo.NewLocalReadTransaction()
+		tx, txErr := newManifestReadTransaction(vaultBranch.Branch)
 		if txErr == nil {
+			if meta, metaErr := loadVaultMeta(tx.WorkDir()); metaErr == nil {
+				overview.VaultFormat = newVaultFormatStatus(tx.Branch(), meta.Format)
+			}
 			resolved, resolveErr := resolveDesiredAssetsForPlane(projectConfig, tx.WorkDir(), workspace.EffectivePlane(), nil)
 			if resolveErr == nil {
 				overview.Bundles = resolved.Bundles
@@ -120,6 +183,10 @@ func LoadWorkspaceOverviewOpts(workspace Workspace, opts OverviewLoadOpts) (*Pro
 			}
 			tx.Close()
 		}
+		// 评审分支同样是增量信息，读取失败不影响 overview。
+		if pending, pendingErr := pendingReviewBranches(workspace, projectConfig, overview.VaultBranch); pendingErr == nil {
+			overview.PendingBranches = pending
+		}
 	}
 
 	selection, err := config.ResolveEffectiveIDEs(projectConfig)
@@ -62,7 +62,8 @@ func PrepareProjectConfigInit(projectRoot string, reporter Reporter) (*ConfigIni
 		}
 	}
 
-	allAssets, err := ScanAvailableAssets(reporter)
+	vaultBranch := emitLocalReadVaultBranch(existingConfig, reporter, "project.init")
+	allAssets, err := ScanAvailableAssets(vaultBranch, reporter)
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +100,7 @@ func PrepareProjectConfigInit(projectRoot string, reporter Reporter) (*ConfigIni
 	}
 
 	// 扫描 bundle（含 vault 级隐式 bundle），供 init 提示与 TUI 使用。
-	if err := withLocalReadRepoDir(func(repoDir string) error {
+	if err := withLocalReadRepoDir(vaultBranch, func(repoDir string) error {
 		_, bundleOverviews, scanErr := scanVaultBundles(repoDir, reporter)
 		if scanErr != nil {
 			return scanErr
@@ -169,12 +170,13 @@ func EnsureLocalProjectConfig(projectRoot string, reporter Reporter) (*ConfigIni
 	return prepared, nil
 }
 
-func ScanAvailableAssets(reporter Reporter) ([]AssetInfo, error) {
+// ScanAvailableAssets 列出本地 vault 镜像中 branch（空串为远端默认分支）上的全部资产。
+func ScanAvailableAssets(branch string, reporter Reporter) ([]AssetInfo, error) {
 	reporter = defaultReporter(reporter)
 	emit(reporter, EventInfo, "repo.scan", "开始扫描仓库资产", nil)
 
 	var allAssets []AssetInfo
-	if err := withLocalReadRepoDir(func(repoDir string) error {
+	if err := withLocalReadRepoDir(branch, func(repoDir string) error {
 		folders, err := readBundleEntries(repoDir)
 		if err != nil {
 			return fmt.Errorf("读取仓库失败: %w", err)
@@ -277,9 +279,9 @@ func withReadRepoDir(fn func(string) error) error {
 	return fn(tx.WorkDir())
 }
 
-// withLocalReadRepoDir 只读 vault worktree，不 FetchBare。
+// withLocalReadRepoDir 只读 vault worktree，不 FetchBare；branch 为空时跟随远端默认分支。
 // TUI 概览 / 推断 / Remote 列表等可接受略旧 refs 的路径应走这里，避免启动被网络卡住。
-func withLocalReadRepoDir(fn func(string) error) error {
+func withLocalReadRepoDir(branch string, fn func(string) error) error {
 	globalConfig, err := config.LoadGlobalConfig()
 	if err == nil {
 		if err := repo.EnsureConnectedRepoMatches(globalConfig.RepoURL); err != nil {
@@ -287,7 +289,7 @@ func withLocalReadRepoDir(fn func(string) error) error {
 		}
 	}
 
-	tx, err := repo.NewLocalReadTransaction()
+	tx, err := repo.NewLocalReadTransactionOn(branch)
 	if err != nil {
 		return err
 	}
@@ -295,3 +297,32 @@ func withLocalReadRepoDir(fn func(string) error) error {
 
 	return fn(tx.WorkDir())
 }
+
+// localReadVaultBranch 解析本地只读路径应读取的 vault 分支（projectConfig 为 nil 时只看全局配置），
+// 告警由调用方展示。全局配置读不出时按远端默认分支读取，本地只读不因此中断。
+func localReadVaultBranch(projectConfig *types.ProjectConfig) (string, []string) {
+	resolved, err := config.ResolveVaultBranch(projectConfig)
+	if err != nil {
+		return "", []string{fmt.Sprintf("解析 vault 分支失败，按远端默认分支读取: %v", err)}
+	}
+	return resolved.Branch, resolved.Warnings
+}
+
+// workspaceLocalReadVaultBranch 解析工作区当前平面跟随的 vault 分支，供本地只读路径使用。
+func workspaceLocalReadVaultBranch(workspace Workspace, reporter Reporter, scope string) string {
+	projectConfig, err := loadWorkspaceBundleConfig(workspace)
+	if err != nil {
+		emit(reporter, EventWarn, scope, fmt.Sprintf("读取配置失败，按远端默认分支读取 vault: %v", err), nil)
+		return ""
+	}
+	return emitLocalReadVaultBranch(projectConfig, reporter, scope)
+}
+
+// emitLocalReadVaultBranch 同 localReadVaultBranch，告警直接发给 reporter。
+func emitLocalReadVaultBranch(projectConfig *types.ProjectConfig, reporter Reporter, scope string) string {
+	branch, warnings := localReadVaultBranch(projectConfig)
+	for _, warning := range warnings {
+		emit(reporter, EventWarn, scope, warning, nil)
+	}
+	return branch
+}
@@ -24,12 +24,12 @@ func projectRejectedNames(rejected []projectEnableRejection) []string {
 	return names
 }
 
-// validateProjectEnabledBundles 校验项目平面的勾选：bundle 必须在 vault 里本平面可见。
+// validateProjectEnabledBundles 校验项目平面的勾选：bundle 必须在项目跟随的 vault 分支里本平面可见。
 //
 // 与用户平面不同，这里只校验、不修复：project bundle 由 vault 显式维护，勾选不该
 // 创建占位，也不该改写别人的 scope（ADR 0013）。仓库未连接时无从校验，沿用旧行为
 // 直接放行，避免离线时保存不了。
-func validateProjectEnabledBundles(names []string, reporter Reporter) ([]projectEnableRejection, error) {
+func validateProjectEnabledBundles(projectConfig *types.ProjectConfig, names []string, reporter Reporter) ([]projectEnableRejection, error) {
 	reporter = defaultReporter(reporter)
 	names = secrets.NormalizeBundleNames(names)
 	if len(names) == 0 {
@@ -44,7 +44,7 @@ func validateProjectEnabledBundles(names []string, reporter Reporter) ([]project
 		return nil, nil
 	}
 
-	tx, err := repo.NewLocalReadTransaction()
+	tx, err := newManifestReadTransaction(emitLocalReadVaultBranch(projectConfig, reporter, "assets.save"))
 	if err != nil {
 		return nil, err
 	}
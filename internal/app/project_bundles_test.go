@@ -56,7 +56,7 @@ func TestSaveWorkspaceEnabledBundles_ProjectPlaneRejectsUnknownBundle(t *testing
 func TestValidateProjectEnabledBundles_AllowsWhenRepoDisconnected(t *testing.T) {
 	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
 
-	rejected, err := validateProjectEnabledBundles([]string{"anything"}, nil)
+	rejected, err := validateProjectEnabledBundles(nil, []string{"anything"}, nil)
 	if err != nil {
 		t.Fatalf("validateProjectEnabledBundles() 失败: %v", err)
 	}
@@ -78,7 +78,7 @@ func TestValidateProjectEnabledBundles_AcceptsSynthesizedBundle(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	rejected, err := validateProjectEnabledBundles([]string{"implicit"}, nil)
+	rejected, err := validateProjectEnabledBundles(nil, []string{"implicit"}, nil)
 	if err != nil {
 		t.Fatalf("validateProjectEnabledBundles() 失败: %v", err)
 	}
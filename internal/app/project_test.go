@@ -297,6 +297,7 @@ bundles:
 ides:
   - cursor
 editor: code --wait
+vault_branch: stable
 `,
 		"bundles/vikunja/skills/vikunja-workflow/SKILL.md": "---\nname: vikunja-workflow\n---\n",
 		"bundles/cli/rules/cli-release-rules.mdc":          "---\ndescription: test\n---\n",
@@ -346,6 +347,9 @@ members:
 	if len(loaded.EnabledBundles) != 2 {
 		t.Fatalf("EnabledBundles = %#v, 期望 2 个", loaded.EnabledBundles)
 	}
+	if loaded.VaultBranch != "stable" {
+		t.Fatalf("VaultBranch = %q, 期望从 vault project 带入 stable", loaded.VaultBranch)
+	}
 	if _, err := os.Stat(result.VarsPath); err != nil {
 		t.Fatalf("应创建 vars 模板: %v", err)
 	}
@@ -8,35 +8,55 @@ import (
 	"strings"
 
 	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
 	"github.com/shichao402/Dec/internal/vars"
 )
 
 // PlaceholderSourceAsset 表示占位符来自 .dec/cache/ 下的资产模板（项目级按资产覆盖）。
 // 其余 source 可直接用字符串常量表达，定义 Asset 是因为构造 key 时需要带上 type/name。
 const (
-	PlaceholderSourceProject = "project"
-	PlaceholderSourceGlobal  = "global"
-	PlaceholderSourceMissing = "missing"
+	PlaceholderSourceProfile      = vars.SourceProfile
+	PlaceholderSourceProject      = "project"
+	PlaceholderSourceGlobal       = "global"
+	PlaceholderSourceVaultProject = vars.SourceVaultProject
+	PlaceholderSourceVaultBundle  = vars.SourceVaultBundle
+	PlaceholderSourceBuiltin      = "builtin"
+	PlaceholderSourceDefault      = "default"
+	PlaceholderSourceMissing      = "missing"
 )
 
 // PlaceholderStatus 描述单个占位符当前的解析结果。
 type PlaceholderStatus struct {
 	Name   string
 	Value  string
-	Source string // project | global | missing
+	Source string // profile | project | global | vault-project | vault-bundle | builtin | default | missing
+	// Origin 补充来源细节：profile 时为 profile 名，vault-bundle 时为提供该值的 bundle 名
+	Origin string
 }
 
 // ProjectVarsView 提供 Project 页变量区块所需的只读数据。
 type ProjectVarsView struct {
-	VarsPath         string
-	VarsFileReady    bool
-	ProjectVars      map[string]string
-	GlobalVars       map[string]string
+	VarsPath      string
+	VarsFileReady bool
+	ProjectVars   map[string]string
+	GlobalVars    map[string]string
+	// Profiles 是 vars.yaml 中定义的 profile 名（字典序）；ActiveProfile 来自 .dec/config.yaml
+	Profiles      []string
+	ActiveProfile string
+	ProfileVars   map[string]string
+	// VaultProjectVars / VaultBundleVars 读自本地 vault 镜像（不联网），未连接仓库时为空
+	VaultProjectVars map[string]string
+	VaultBundleVars  map[string]map[string]string
 	UsedPlaceholders []string
-	ResolvedVars     map[string]PlaceholderStatus
-	CacheExists      bool
-	EditorCommand    string
-	Warnings         []string
+	// PlaceholderAssets 是 .dec/cache 中含占位符的资产，供 Project 页逐个做 explain_vars
+	PlaceholderAssets []types.TypedAssetRef
+	ResolvedVars      map[string]PlaceholderStatus
+	// BuiltinVars 按 vars.BuiltinNames 顺序列出全部内置变量；DEC_BUNDLE / DEC_IDE 的 Value 是以 " | " 连接的各份取值
+	BuiltinVars   []PlaceholderStatus
+	CacheExists   bool
+	EditorCommand string
+	Warnings      []string
 }
 
 // LoadProjectVarsView 读取项目级变量定义 + 扫描 .dec/cache/ 里已用占位符，返回只读视图。
@@ -71,6 +91,7 @@ func LoadProjectVarsView(projectRoot string) (*ProjectVarsView, error) {
 			view.ProjectVars[k] = v
 		}
 	}
+	view.Profiles = projectVars.ProfileNames()
 
 	// 机器级 vars
 	globalVars, err := config.LoadGlobalVars()
@@ -82,33 +103,105 @@ func LoadProjectVarsView(projectRoot string) (*ProjectVarsView, error) {
 		}
 	}
 
+	projectConfig, loadErr := mgr.LoadProjectConfig()
+	if loadErr != nil {
+		view.Warnings = append(view.Warnings, fmt.Sprintf("加载项目配置失败: %v", loadErr))
+	}
+
+	// 当前 profile 与 vault 共享默认值
+	var shared *vaultSharedVars
+	if projectConfig != nil {
+		view.ActiveProfile = projectConfig.ActiveProfile
+		if profileVars, ok := projectVars.ProfileVars(view.ActiveProfile); ok {
+			view.ProfileVars = profileVars
+		} else if view.ActiveProfile != "" {
+			view.Warnings = append(view.Warnings, fmt.Sprintf("active_profile %q 未在 %s 的 profiles 中定义，已忽略", view.ActiveProfile, view.VarsPath))
+		}
+		var sharedWarnings []string
+		shared, sharedWarnings = loadLocalVaultSharedVars(projectRoot, projectConfig)
+		view.Warnings = append(view.Warnings, sharedWarnings...)
+		if shared != nil {
+			view.VaultProjectVars = shared.Project
+			view.VaultBundleVars = shared.Bundles
+		}
+	}
+
 	// 扫描 .dec/cache/ 中的占位符（若存在）
+	var defaults map[string]string
 	cacheDir := filepath.Join(mgr.GetDecDir(), "cache")
 	if info, err := os.Stat(cacheDir); err == nil && info.IsDir() {
 		view.CacheExists = true
 		placeholders := vars.ExtractPlaceholdersFromDir(cacheDir)
 		sort.Strings(placeholders)
 		view.UsedPlaceholders = placeholders
+		defaults = vars.ExtractPlaceholderDefaultsFromDir(cacheDir)
+		workspace := NewWorkspace(WorkspaceProject, projectRoot)
+		for _, asset := range listCachedAssets(cacheDir) {
+			cachePath := getWorkspaceCachePath(workspace, asset.Vault, asset.Type, asset.Name)
+			// Walk 对单文件同样适用，rule / mcp 不必另行分支
+			if len(vars.ExtractPlaceholdersFromDir(cachePath)) > 0 {
+				view.PlaceholderAssets = append(view.PlaceholderAssets, asset)
+			}
+		}
+	}
+
+	// 内置变量：项目级值直接计算；DEC_BUNDLE / DEC_IDE 每份输出各不相同，这里列出全部取值
+	builtins := projectBuiltinVars(projectRoot)
+	for _, name := range vars.BuiltinNames() {
+		status := PlaceholderStatus{Name: name, Value: builtins[name], Source: PlaceholderSourceBuiltin}
+		if projectConfig != nil {
+			switch name {
+			case vars.BuiltinBundle:
+				status.Value = strings.Join(projectConfig.EnabledBundles, " | ")
+			case vars.BuiltinIDE:
+				if selection, err := config.ResolveEffectiveIDEs(projectConfig); err == nil {
+					status.Value = strings.Join(selection.IDEs, " | ")
+				}
+			}
+		}
+		view.BuiltinVars = append(view.BuiltinVars, status)
 	}
 
 	// 解析 resolve 结果（仅限当前用中的占位符）
 	for _, name := range view.UsedPlaceholders {
 		status := PlaceholderStatus{Name: name, Source: PlaceholderSourceMissing}
-		if v, ok := view.ProjectVars[name]; ok {
+		if v, ok := view.ProfileVars[name]; ok {
+			status.Value = v
+			status.Source = PlaceholderSourceProfile
+			status.Origin = view.ActiveProfile
+		} else if v, ok := view.ProjectVars[name]; ok {
 			status.Value = v
 			status.Source = PlaceholderSourceProject
 		} else if v, ok := view.GlobalVars[name]; ok {
 			status.Value = v
 			status.Source = PlaceholderSourceGlobal
+		} else if v, ok := view.VaultProjectVars[name]; ok {
+			status.Value = v
+			status.Source = PlaceholderSourceVaultProject
+		} else if bundleName, v, ok := shared.lookupBundle(name); ok {
+			status.Value = v
+			status.Source = PlaceholderSourceVaultBundle
+			status.Origin = bundleName
+		} else if v, ok := builtins[name]; ok {
+			status.Value = v
+			status.Source = PlaceholderSourceBuiltin
+		} else if vars.IsPerOutputBuiltin(name) {
+			status.Source = PlaceholderSourceBuiltin
+		} else if v, ok := defaults[name]; ok {
+			status.Value = v
+			status.Source = PlaceholderSourceDefault
+		}
+		// 与 Substitute 一致：定义为空值时模板 default 生效
+		if v, ok := defaults[name]; ok && status.Value == "" && status.Source != PlaceholderSourceMissing && !vars.IsPerOutputBuiltin(name) {
+			status.Value = v
+			status.Source = PlaceholderSourceDefault
+			status.Origin = ""
 		}
 		view.ResolvedVars[name] = status
 	}
 
 	// 有效 editor 命令
-	projectConfig, loadErr := mgr.LoadProjectConfig()
-	if loadErr != nil {
-		view.Warnings = append(view.Warnings, fmt.Sprintf("加载项目配置失败: %v", loadErr))
-	} else {
+	if projectConfig != nil {
 		editorCmd, err := config.GetEffectiveEditor(projectConfig)
 		if err != nil {
 			view.Warnings = append(view.Warnings, fmt.Sprintf("解析编辑器命令失败: %v", err))
@@ -156,3 +249,30 @@ func (v *ProjectVarsView) MissingPlaceholders() []string {
 	}
 	return missing
 }
+
+// loadLocalVaultSharedVars 从本地 vault 镜像中项目跟随的分支读取共享变量；未连接仓库时返回 nil 且不告警。
+func loadLocalVaultSharedVars(projectRoot string, projectConfig *types.ProjectConfig) (*vaultSharedVars, []string) {
+	connected, err := repo.IsConnected()
+	if err != nil || !connected {
+		return nil, nil
+	}
+	projectName, _ := ResolveProjectName(projectRoot, projectConfig)
+	branch, warnings := localReadVaultBranch(projectConfig)
+	var (
+		shared         *vaultSharedVars
+		sharedWarnings []string
+	)
+	if err := withLocalReadRepoDir(branch, func(repoDir string) error {
+		shared, sharedWarnings = loadVaultSharedVars(repoDir, projectName, config.NormalizeBundleNames(projectConfig.EnabledBundles))
+		return nil
+	}); err != nil {
+		return nil, append(warnings, fmt.Sprintf("读取 vault 共享变量失败: %v", err))
+	}
+	return shared, append(warnings, sharedWarnings...)
+}
+
+// projectBuiltinVars 计算项目级内置变量（git 信息只取一次，供整次 pull 复用）。
+func projectBuiltinVars(projectRoot string) map[string]string {
+	decHome, _ := repo.GetRootDir()
+	return vars.BuiltinVars(vars.BuiltinContext{ProjectRoot: projectRoot, DecHome: decHome})
+}
@@ -140,6 +140,33 @@ func TestLoadProjectVarsView_GlobalFallback(t *testing.T) {
 	}
 }
 
+// TestLoadProjectVarsView_DefaultNotMissing 带 default 的占位符显示默认值，不计入缺失。
+func TestLoadProjectVarsView_DefaultNotMissing(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	projectRoot := t.TempDir()
+
+	writeFile(t, filepath.Join(projectRoot, ".dec", "cache", "team", "skills", "demo", "SKILL.md"),
+		`region {{REGION|default:"us-east-1"}}, zone {{ZONE|default:"a"}}, token {{TOKEN}}, literal \{{LITERAL}}`)
+	writeFile(t, filepath.Join(projectRoot, ".dec", "vars.yaml"), "vars:\n  ZONE: \"\"\n")
+
+	view, err := LoadProjectVarsView(projectRoot)
+	if err != nil {
+		t.Fatalf("LoadProjectVarsView() 失败: %v", err)
+	}
+	if got := view.ResolvedVars["REGION"]; got.Source != PlaceholderSourceDefault || got.Value != "us-east-1" {
+		t.Fatalf("REGION = %#v, 期望 default us-east-1", got)
+	}
+	if got := view.ResolvedVars["ZONE"]; got.Source != PlaceholderSourceDefault || got.Value != "a" {
+		t.Fatalf("ZONE 定义为空值时应显示 default, got %#v", got)
+	}
+	if _, ok := view.ResolvedVars["LITERAL"]; ok {
+		t.Fatalf("转义形式不应出现在占位符列表: %#v", view.UsedPlaceholders)
+	}
+	if missing := view.MissingPlaceholders(); len(missing) != 1 || missing[0] != "TOKEN" {
+		t.Fatalf("MissingPlaceholders() = %#v, 期望只有 TOKEN", missing)
+	}
+}
+
 // TestEnsureProjectVarsFile_CreateThenIdempotent 覆盖首次创建 + 再次调用不覆盖。
 func TestEnsureProjectVarsFile_CreateThenIdempotent(t *testing.T) {
 	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
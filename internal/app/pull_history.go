@@ -0,0 +1,283 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/freshness"
+	"github.com/shichao402/Dec/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// pullHistoryFileName 记录最近几次拉取（版本、启用的 bundle、资产摘要），位于 .dec/ 下，供回滚使用。
+const pullHistoryFileName = "pull_history.yaml"
+
+// maxPullHistoryEntries 是每个工作空间保留的拉取记录上限，超出时丢弃最旧的。
+const maxPullHistoryEntries = 20
+
+// 拉取记录的来源取值。
+const (
+	PullSourcePull     = "pull"
+	PullSourcePreview  = "preview"
+	PullSourceRollback = "rollback"
+)
+
+// PullHistoryEntry 是一次成功拉取的快照。
+type PullHistoryEntry struct {
+	Commit   string    `yaml:"commit"`
+	Branch   string    `yaml:"branch,omitempty"`
+	PulledAt time.Time `yaml:"pulled_at"`
+	// Source 取值 pull | preview | rollback
+	Source         string   `yaml:"source"`
+	EnabledBundles []string `yaml:"enabled_bundles"`
+	// Locks 以 "type:vault:name" 为 key，值是该资产缓存内容的 sha256，回滚后用来核对内容是否一致。
+	Locks map[string]string `yaml:"locks,omitempty"`
+}
+
+// PullHistory 是工作空间的拉取记录，新的在前。
+type PullHistory struct {
+	Entries []PullHistoryEntry
+	// CurrentCommit 是 .dec/.version 记录的当前版本。
+	CurrentCommit string
+	// Held 表示项目已固定在 CurrentCommit：普通拉取不再跟随远端，新鲜度检查也不再提示。
+	Held bool
+}
+
+type pullHistoryFile struct {
+	Entries []PullHistoryEntry `yaml:"entries"`
+}
+
+func pullHistoryPath(workspace Workspace) string {
+	return filepath.Join(workspaceCacheRoot(workspace), ".dec", pullHistoryFileName)
+}
+
+func loadPullHistoryFile(workspace Workspace) (*pullHistoryFile, error) {
+	data, err := os.ReadFile(pullHistoryPath(workspace))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &pullHistoryFile{}, nil
+		}
+		return nil, err
+	}
+	var file pullHistoryFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", pullHistoryFileName, err)
+	}
+	return &file, nil
+}
+
+func savePullHistoryFile(workspace Workspace, file *pullHistoryFile) error {
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+	path := pullHistoryPath(workspace)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	header := "# Dec 最近的拉取记录（新的在前）；Run 页 H 可回滚到其中任意一次\n"
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append([]byte(header), data...), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadPullHistory 读取工作空间的拉取记录与当前固定状态。
+func LoadPullHistory(workspace Workspace) (*PullHistory, error) {
+	file, err := loadPullHistoryFile(workspace)
+	if err != nil {
+		return nil, err
+	}
+	history := &PullHistory{Entries: file.Entries}
+	meta, err := freshness.LoadVersionMeta(workspaceCacheRoot(workspace))
+	if err != nil {
+		return nil, fmt.Errorf("读取拉取记录失败: %w", err)
+	}
+	if meta != nil {
+		history.CurrentCommit = meta.Commit
+		history.Held = meta.Held
+	}
+	return history, nil
+}
+
+// recordPullHistory 把本轮拉取追加到记录头部。与上一条版本和 bundle 都相同时原地更新
+// （保留最初的来源），避免反复 pull 同一版本把有用的旧记录挤掉。写失败只告警，不影响已完成的拉取。
+func recordPullHistory(workspace Workspace, entry PullHistoryEntry, reporter Reporter) {
+	file, err := loadPullHistoryFile(workspace)
+	if err != nil {
+		emit(reporter, EventWarn, "pull.history", fmt.Sprintf("读取拉取记录失败，将重建: %v", err), nil)
+		file = &pullHistoryFile{}
+	}
+	entries := file.Entries
+	if len(entries) > 0 && entries[0].Commit == entry.Commit && sameBundleNames(entries[0].EnabledBundles, entry.EnabledBundles) {
+		entry.Source = entries[0].Source
+		entries = entries[1:]
+	}
+	entries = append([]PullHistoryEntry{entry}, entries...)
+	if len(entries) > maxPullHistoryEntries {
+		entries = entries[:maxPullHistoryEntries]
+	}
+	file.Entries = entries
+	if err := savePullHistoryFile(workspace, file); err != nil {
+		emit(reporter, EventWarn, "pull.history", fmt.Sprintf("写入拉取记录失败: %v", err), nil)
+	}
+}
+
+func sameBundleNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// workspaceAssetLocks 计算资产缓存内容的摘要；缓存缺失的资产不记录。
+func workspaceAssetLocks(workspace Workspace, assets []types.TypedAssetRef) map[string]string {
+	locks := make(map[string]string, len(assets))
+	for _, asset := range assets {
+		digest, err := renderedDigest(getWorkspaceCachePath(workspace, asset.Vault, asset.Type, asset.Name))
+		if err != nil {
+			continue
+		}
+		locks[assetKey(asset)] = digest
+	}
+	return locks
+}
+
+// heldPullVersion 返回项目固定的版本；未固定时返回空串。
+func heldPullVersion(workspace Workspace) string {
+	meta, err := freshness.LoadVersionMeta(workspaceCacheRoot(workspace))
+	if err != nil || meta == nil || !meta.Held {
+		return ""
+	}
+	return meta.Commit
+}
+
+// RollbackWorkspacePull 按拉取记录回到更早的一次拉取：恢复当时启用的 bundle，
+// 以 NewReadTransactionAt 的语义（只读、按版本、只物化启用的 bundle）重新拉取该版本，
+// 然后把项目固定在该版本，直到 UnholdWorkspacePull。commit 可以是记录里版本的前缀。
+func RollbackWorkspacePull(ctx context.Context, workspace Workspace, commit string, reporter Reporter) (*PullProjectAssetsResult, error) {
+	reporter = defaultReporter(reporter)
+	commit = strings.TrimSpace(commit)
+	if commit == "" {
+		return nil, fmt.Errorf("请指定要回滚到的拉取版本")
+	}
+	history, err := LoadPullHistory(workspace)
+	if err != nil {
+		return nil, err
+	}
+	var entry *PullHistoryEntry
+	for i := range history.Entries {
+		if strings.HasPrefix(history.Entries[i].Commit, commit) {
+			entry = &history.Entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("拉取记录中没有版本 %s", commit)
+	}
+
+	projectConfig, err := loadWorkspaceBundleConfig(workspace)
+	if err != nil {
+		return nil, err
+	}
+	current := []string{}
+	if projectConfig != nil {
+		current = config.NormalizeBundleNames(projectConfig.EnabledBundles)
+	}
+	wanted := config.NormalizeBundleNames(entry.EnabledBundles)
+	if !sameBundleNames(current, wanted) {
+		// 不走 SaveWorkspaceEnabledBundles：它按远端最新声明校验，旧版本里才有的 bundle 会被拒绝。
+		if err := restoreWorkspaceEnabledBundles(workspace, wanted); err != nil {
+			return nil, fmt.Errorf("恢复启用的 bundle 失败: %w", err)
+		}
+		emit(reporter, EventInfo, "pull.rollback", fmt.Sprintf("启用的 bundle 恢复为：%s", strings.Join(wanted, ", ")), nil)
+	}
+
+	emit(reporter, EventInfo, "pull.rollback", fmt.Sprintf("回滚到 %s 的拉取 %s", entry.PulledAt.Local().Format("2006-01-02 15:04"), shortCommit(entry.Commit)), nil)
+	result, err := pullWorkspaceAssets(ctx, workspace, pullRequest{version: entry.Commit, source: PullSourceRollback, hold: true}, reporter)
+	if err != nil {
+		return nil, err
+	}
+
+	if after, loadErr := loadPullHistoryFile(workspace); loadErr == nil && len(after.Entries) > 0 && after.Entries[0].Commit == result.VersionCommit {
+		result.LockMismatches = diffAssetLocks(entry.Locks, after.Entries[0].Locks)
+		for _, mismatch := range result.LockMismatches {
+			emit(reporter, EventWarn, "pull.rollback", fmt.Sprintf("⚠️  %s 与当时拉取的内容不一致", mismatch), nil)
+		}
+	}
+	_ = freshness.InvalidateCache(workspaceCacheRoot(workspace))
+	emit(reporter, EventInfo, "pull.rollback", fmt.Sprintf("📌 已固定在 %s，解除固定前不再跟随远端", shortCommit(result.VersionCommit)), nil)
+	return result, nil
+}
+
+// UnholdWorkspacePull 解除固定；下次拉取重新跟随 vault 分支，新鲜度检查也恢复提示。
+func UnholdWorkspacePull(workspace Workspace) (*PullHistory, error) {
+	root := workspaceCacheRoot(workspace)
+	meta, err := freshness.LoadVersionMeta(root)
+	if err != nil {
+		return nil, fmt.Errorf("读取拉取记录失败: %w", err)
+	}
+	if meta != nil && meta.Held {
+		meta.Held = false
+		if err := writeVersionMeta(root, *meta); err != nil {
+			return nil, fmt.Errorf("写入 .dec/.version 失败: %w", err)
+		}
+		_ = freshness.InvalidateCache(root)
+	}
+	return LoadPullHistory(workspace)
+}
+
+// restoreWorkspaceEnabledBundles 原样写回启用列表，不做仓库声明校验。
+func restoreWorkspaceEnabledBundles(workspace Workspace, bundles []string) error {
+	if workspace.EffectivePlane() == WorkspaceUser {
+		globalConfig, err := config.LoadGlobalConfig()
+		if err != nil {
+			return err
+		}
+		globalConfig.EnabledBundles = bundles
+		return config.SaveGlobalConfig(globalConfig)
+	}
+	mgr := config.NewProjectConfigManager(workspace.Root)
+	projectConfig, err := mgr.LoadProjectConfig()
+	if err != nil {
+		return err
+	}
+	if projectConfig == nil {
+		projectConfig = &types.ProjectConfig{}
+	}
+	projectConfig.EnabledBundles = bundles
+	return mgr.SaveProjectConfig(projectConfig)
+}
+
+// diffAssetLocks 列出两份摘要中内容不同或只在一边出现的资产，按 key 排序。
+func diffAssetLocks(want, got map[string]string) []string {
+	var mismatches []string
+	for key, digest := range want {
+		actual, ok := got[key]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, key+"（本次未拉取）")
+		case actual != digest:
+			mismatches = append(mismatches, key)
+		}
+	}
+	for key := range got {
+		if _, ok := want[key]; !ok {
+			mismatches = append(mismatches, key+"（当时没有）")
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/freshness"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestRollbackWorkspacePullHoldsVersion(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/alpha.mdc": "---\ndescription: alpha\n---\nalpha v1\n",
+		"bundles/combo/bundle.yaml":     "name: combo\nmembers:\n  - rule/alpha\n",
+		"bundles/extra/rules/beta.mdc":  "---\ndescription: beta\n---\nbeta v1\n",
+		"bundles/extra/bundle.yaml":     "name: extra\nmembers:\n  - rule/beta\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{IDEs: []string{"cursor"}, EnabledBundles: []string{"combo"}}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	first, err := PullWorkspaceAssets(context.Background(), workspace, "", nil)
+	if err != nil {
+		t.Fatalf("PullWorkspaceAssets() 失败: %v", err)
+	}
+
+	alphaCache := getWorkspaceCachePath(workspace, "combo", "rule", "alpha")
+	writeFileProjectTest(t, alphaCache, "---\ndescription: alpha\n---\nalpha v2\n")
+	if _, err := PushWorkspaceAssets(context.Background(), workspace, nil); err != nil {
+		t.Fatalf("PushWorkspaceAssets() 失败: %v", err)
+	}
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{IDEs: []string{"cursor"}, EnabledBundles: []string{"combo", "extra"}}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	latest, err := PullWorkspaceAssets(context.Background(), workspace, "", nil)
+	if err != nil {
+		t.Fatalf("PullWorkspaceAssets() 失败: %v", err)
+	}
+	if latest.VersionCommit == first.VersionCommit {
+		t.Fatalf("push 后应拉到新版本")
+	}
+
+	history, err := LoadPullHistory(workspace)
+	if err != nil {
+		t.Fatalf("LoadPullHistory() 失败: %v", err)
+	}
+	if len(history.Entries) != 2 || history.Entries[0].Commit != latest.VersionCommit || history.Held {
+		t.Fatalf("拉取记录 = %+v", history)
+	}
+	if got := history.Entries[1]; got.Commit != first.VersionCommit || got.Source != PullSourcePull ||
+		strings.Join(got.EnabledBundles, ",") != "combo" || got.Locks["rule:combo:alpha"] == "" {
+		t.Fatalf("第一条拉取记录 = %+v", got)
+	}
+
+	rolled, err := RollbackWorkspacePull(context.Background(), workspace, first.VersionCommit[:10], nil)
+	if err != nil {
+		t.Fatalf("RollbackWorkspacePull() 失败: %v", err)
+	}
+	if rolled.VersionCommit != first.VersionCommit || !rolled.Held || len(rolled.LockMismatches) != 0 {
+		t.Fatalf("回滚结果 = %+v", rolled)
+	}
+	if data, err := os.ReadFile(alphaCache); err != nil || !strings.Contains(string(data), "alpha v1") {
+		t.Fatalf("缓存应回到 v1: %q, %v", data, err)
+	}
+	if cfg, err := mgr.LoadProjectConfig(); err != nil || strings.Join(cfg.EnabledBundles, ",") != "combo" {
+		t.Fatalf("启用的 bundle 应恢复为当时的选择: %+v, %v", cfg, err)
+	}
+	if !freshness.IsHeld(projectRoot) {
+		t.Fatalf(".dec/.version 应标记为固定")
+	}
+
+	// 固定期间的普通拉取停在固定版本，评审分支预览被拒绝。
+	pinned, err := PullWorkspaceAssets(context.Background(), workspace, "", nil)
+	if err != nil {
+		t.Fatalf("PullWorkspaceAssets() 失败: %v", err)
+	}
+	if pinned.VersionCommit != first.VersionCommit || !pinned.Held {
+		t.Fatalf("固定期间应拉取固定版本: %+v", pinned)
+	}
+	if _, err := PullWorkspaceBranchPreview(context.Background(), workspace, types.ReviewBranchPrefix+"amy/demo/1", nil); err == nil {
+		t.Fatalf("固定期间不应允许评审分支预览")
+	}
+	history, _ = LoadPullHistory(workspace)
+	if len(history.Entries) != 3 || history.Entries[0].Source != PullSourceRollback || !history.Held {
+		t.Fatalf("回滚后的拉取记录 = %+v", history)
+	}
+
+	unheld, err := UnholdWorkspacePull(workspace)
+	if err != nil || unheld.Held {
+		t.Fatalf("UnholdWorkspacePull() = %+v, %v", unheld, err)
+	}
+	after, err := PullWorkspaceAssets(context.Background(), workspace, "", nil)
+	if err != nil {
+		t.Fatalf("PullWorkspaceAssets() 失败: %v", err)
+	}
+	if after.VersionCommit != latest.VersionCommit || after.Held {
+		t.Fatalf("解除固定后应拉取最新: %+v", after)
+	}
+	if data, _ := os.ReadFile(alphaCache); !strings.Contains(string(data), "alpha v2") {
+		t.Fatalf("解除固定后缓存应为 v2: %q", data)
+	}
+
+	if _, err := RollbackWorkspacePull(context.Background(), workspace, "deadbeef", nil); err == nil {
+		t.Fatalf("记录中没有的版本不应允许回滚")
+	}
+}
+
+func TestRecordPullHistoryIsBounded(t *testing.T) {
+	workspace := NewWorkspace(WorkspaceProject, t.TempDir())
+	for i := 0; i < maxPullHistoryEntries+5; i++ {
+		recordPullHistory(workspace, PullHistoryEntry{Commit: fmt.Sprintf("%040d", i), PulledAt: time.Now(), Source: PullSourcePull}, nil)
+	}
+	// 与上一条相同的版本只更新，不新增。
+	recordPullHistory(workspace, PullHistoryEntry{Commit: fmt.Sprintf("%040d", maxPullHistoryEntries+4), PulledAt: time.Now(), Source: PullSourcePull}, nil)
+	history, err := LoadPullHistory(workspace)
+	if err != nil {
+		t.Fatalf("LoadPullHistory() 失败: %v", err)
+	}
+	if len(history.Entries) != maxPullHistoryEntries {
+		t.Fatalf("记录条数 = %d", len(history.Entries))
+	}
+	if history.Entries[0].Commit != fmt.Sprintf("%040d", maxPullHistoryEntries+4) || history.Entries[maxPullHistoryEntries-1].Commit != fmt.Sprintf("%040d", 5) {
+		t.Fatalf("应保留最新的记录: %s … %s", history.Entries[0].Commit, history.Entries[maxPullHistoryEntries-1].Commit)
+	}
+}
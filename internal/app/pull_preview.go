@@ -0,0 +1,349 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/ide"
+	"github.com/shichao402/Dec/internal/textdiff"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// 渲染文件在 dry-run 中的变化类型
+const (
+	RenderedFileAdded    = "added"
+	RenderedFileModified = "modified"
+	RenderedFileDeleted  = "deleted"
+)
+
+// RenderedFileDiff 是 dry-run 中单个 IDE 文件的变化。
+type RenderedFileDiff struct {
+	// Asset 格式 "[type] name (bundle)"
+	Asset string
+	IDE   string
+	// Path 是展示路径：项目平面相对项目根，用户平面以 ~ 开头；MCP 为 "<配置文件>#mcpServers.<条目>"
+	Path   string
+	Status string
+	// Diff 是 unified diff；MCP 条目按缩进 JSON 比较
+	Diff string
+}
+
+// PullPreview 是 pull dry-run 的结果：按 IDE 文件列出 pull 将带来的变化，不写任何文件。
+// cache、secrets 与 .dec/.version 同样不动。
+type PullPreview struct {
+	ProjectRoot   string
+	Plane         string
+	VersionCommit string
+	// VaultBranch 是本次预览跟随的 vault 分支
+	VaultBranch   string
+	EffectiveIDEs []string
+	// Files 只含有变化的文件，按路径排序；孤儿资产的删除也在其中
+	Files []RenderedFileDiff
+	// Unchanged 是渲染结果与现有文件一致的文件数
+	Unchanged int
+	// FailedAssets 是渲染或校验失败的资产：真正 pull 时它们不会安装，IDE 中保留现有版本
+	FailedAssets []string
+	Warnings     []string
+}
+
+// PreviewPullWorkspaceAssets 按真实 pull 的流程在暂存区渲染最新版本，返回与 IDE 现有文件的差异。
+func PreviewPullWorkspaceAssets(ctx context.Context, workspace Workspace, reporter Reporter) (*PullPreview, error) {
+	base := defaultReporter(reporter)
+	preview := &PullPreview{
+		ProjectRoot: workspace.Root,
+		Plane:       string(workspace.EffectivePlane()),
+	}
+	// 预览结果要自带告警：调用方（MCP / TUI）不一定保留事件流
+	reporter = ReporterFunc(func(event OperationEvent) {
+		if event.Level == EventWarn {
+			preview.Warnings = append(preview.Warnings, event.Message)
+		}
+		base.Emit(event)
+	})
+
+	projectConfig, err := loadWorkspaceBundleConfig(workspace)
+	if err != nil {
+		return nil, err
+	}
+	ideSelection, err := config.ResolveEffectiveIDEs(projectConfig)
+	if err != nil {
+		return nil, fmt.Errorf("解析有效 IDE 失败: %w", err)
+	}
+	for _, warning := range ideSelection.Warnings {
+		emit(reporter, EventWarn, "pull.ide", warning, nil)
+	}
+	projectIDEs := uniqueWorkspaceIDEs(workspace, ideSelection.IDEs)
+	preview.EffectiveIDEs = projectIDENames(projectIDEs)
+
+	projectEnabled := config.NormalizeBundleNames(projectConfig.EnabledBundles)
+	pullConfig := *projectConfig
+	pullConfig.EnabledBundles = projectEnabled
+
+	vaultBranch, err := resolveVaultBranch(projectConfig, reporter, "pull.prepare")
+	if err != nil {
+		return nil, err
+	}
+
+	var desired []types.TypedAssetRef
+	if len(projectEnabled) > 0 {
+		// 项目已固定时真实 pull 拉的是固定版本，预览也对准它。
+		tx, err := newPullReadTransaction(vaultBranch, heldPullVersion(workspace), projectEnabled)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Close()
+		repoDir := tx.WorkDir()
+		preview.VersionCommit = tx.CommitHash()
+		preview.VaultBranch = tx.Branch()
+
+		resolved, err := resolveDesiredAssetsForPlane(&pullConfig, repoDir, workspace.EffectivePlane(), reporter)
+		if err != nil {
+			return nil, err
+		}
+		desired = resolved.Assets
+
+		stage, err := prepareRenderStage(workspace, projectConfig, repoDir, projectEnabled, projectIDEs, reporter)
+		if err != nil {
+			return nil, err
+		}
+		defer stage.Close()
+		for _, asset := range desired {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			label := renderedAssetLabel(asset)
+			fullPath := resolveAssetFile(repoDir, asset.Vault, asset.Type, asset.Name)
+			if _, err := os.Stat(fullPath); err != nil {
+				preview.FailedAssets = append(preview.FailedAssets, label+"：远程不存在")
+				continue
+			}
+			pending, err := stage.render(asset, fullPath, reporter)
+			if err != nil {
+				preview.FailedAssets = append(preview.FailedAssets, fmt.Sprintf("%s：%v", label, err))
+				continue
+			}
+			if err := preview.addPending(pending, workspace); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 已不在目标集里的 cache 资产，pull 会把它们从 IDE 中清掉
+	enabledSet := make(map[string]bool, len(desired))
+	for _, asset := range desired {
+		enabledSet[assetKey(asset)] = true
+	}
+	for _, asset := range listCachedAssets(workspaceCacheDir(workspace)) {
+		if !enabledSet[assetKey(asset)] {
+			if err := preview.addRemoval(asset, workspace, projectIDEs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.SliceStable(preview.Files, func(i, j int) bool {
+		return preview.Files[i].Path < preview.Files[j].Path
+	})
+	added, removed := 0, 0
+	for _, file := range preview.Files {
+		a, r := textdiff.Stats(file.Diff)
+		added += a
+		removed += r
+	}
+	summary := fmt.Sprintf("🔍 dry-run：%d 个文件将变化（+%d −%d 行），%d 个不变", len(preview.Files), added, removed, preview.Unchanged)
+	if len(preview.FailedAssets) > 0 {
+		summary += fmt.Sprintf("，%d 个资产渲染失败", len(preview.FailedAssets))
+	}
+	emit(reporter, EventInfo, "pull.preview", summary, &Progress{Phase: "done"})
+	return preview, nil
+}
+
+// addPending 比较一个已渲染资产的每份 IDE 副本与现有文件。
+func (p *PullPreview) addPending(pending *pendingAsset, workspace Workspace) error {
+	label := renderedAssetLabel(pending.Asset)
+	seen := make(map[string]bool, len(pending.Outputs))
+	for _, out := range pending.Outputs {
+		// 多个 IDE 共用同一目录时只比较一次
+		if seen[out.Dest] {
+			continue
+		}
+		seen[out.Dest] = true
+
+		if out.Server != nil {
+			oldText, err := existingMCPEntryText(out.IDE, pending.Asset.Name, workspace)
+			if err != nil {
+				return err
+			}
+			newText, err := mcpEntryText(*out.Server)
+			if err != nil {
+				return err
+			}
+			p.add(label, out.IDE.Name(), mcpEntryDisplayPath(out.Dest, pending.Asset.Name, workspace), oldText, newText)
+			continue
+		}
+		oldFiles, err := readTreeFiles(out.Dest)
+		if err != nil {
+			return err
+		}
+		newFiles, err := readTreeFiles(out.Path)
+		if err != nil {
+			return err
+		}
+		for _, rel := range unionKeys(oldFiles, newFiles) {
+			p.add(label, out.IDE.Name(), displayWorkspacePath(workspace, filepath.Join(out.Dest, rel)), oldFiles[rel], newFiles[rel])
+		}
+	}
+	return nil
+}
+
+// addRemoval 记录孤儿资产在各 IDE 中将被删除的文件。
+func (p *PullPreview) addRemoval(asset types.TypedAssetRef, workspace Workspace, ides []ide.IDE) error {
+	label := renderedAssetLabel(asset)
+	seen := make(map[string]bool, len(ides))
+	for _, ideImpl := range ides {
+		if asset.Type == "mcp" {
+			oldText, err := existingMCPEntryText(ideImpl, asset.Name, workspace)
+			if err != nil {
+				return err
+			}
+			home, _ := os.UserHomeDir()
+			configPath := ideImpl.MCPConfigPathForPlane(workspace.IDEPlane(), workspace.Root, home)
+			p.add(label, ideImpl.Name(), mcpEntryDisplayPath(configPath, asset.Name, workspace), oldText, nil)
+			continue
+		}
+		dest := ideAssetPath(asset.Type, asset.Name, workspace, ideImpl)
+		if dest == "" || seen[dest] {
+			continue
+		}
+		seen[dest] = true
+		oldFiles, err := readTreeFiles(dest)
+		if err != nil {
+			return err
+		}
+		for _, rel := range unionKeys(oldFiles, nil) {
+			p.add(label, ideImpl.Name(), displayWorkspacePath(workspace, filepath.Join(dest, rel)), oldFiles[rel], nil)
+		}
+	}
+	return nil
+}
+
+// add 记录一个文件的变化；oldText / newText 为 nil 表示文件不存在。
+func (p *PullPreview) add(asset, ideName, path string, oldText, newText *string) {
+	diff := RenderedFileDiff{Asset: asset, IDE: ideName, Path: path}
+	oldName, newName := "a/"+path, "b/"+path
+	var before, after string
+	switch {
+	case oldText == nil && newText == nil:
+		return
+	case oldText == nil:
+		diff.Status = RenderedFileAdded
+		oldName, after = "/dev/null", *newText
+	case newText == nil:
+		diff.Status = RenderedFileDeleted
+		newName, before = "/dev/null", *oldText
+	default:
+		diff.Status = RenderedFileModified
+		before, after = *oldText, *newText
+	}
+	diff.Diff = textdiff.Unified(oldName, newName, before, after)
+	if diff.Status == RenderedFileModified && diff.Diff == "" {
+		p.Unchanged++
+		return
+	}
+	p.Files = append(p.Files, diff)
+}
+
+// readTreeFiles 读取文件或目录下的全部文件，键为相对路径（单文件为 "."）；不存在时返回空。
+func readTreeFiles(root string) (map[string]*string, error) {
+	files := map[string]*string{}
+	// symlink 模式下 IDE 路径是指向渲染区的链接，先解析到实体
+	resolved, err := filepath.EvalSymlinks(root)
+	if os.IsNotExist(err) {
+		return files, nil
+	} else if err != nil {
+		return nil, err
+	}
+	err = filepath.Walk(resolved, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(resolved, path)
+		if err != nil {
+			return err
+		}
+		content := string(data)
+		files[rel] = &content
+		return nil
+	})
+	return files, err
+}
+
+func unionKeys(a, b map[string]*string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func existingMCPEntryText(ideImpl ide.IDE, assetName string, workspace Workspace) (*string, error) {
+	home, _ := os.UserHomeDir()
+	existing, err := ideImpl.LoadMCPConfigForPlane(workspace.IDEPlane(), workspace.Root, home)
+	if err != nil {
+		return nil, nil
+	}
+	server, ok := existing.MCPServers[managedName(assetName)]
+	if !ok {
+		return nil, nil
+	}
+	return mcpEntryText(server)
+}
+
+func mcpEntryText(server types.MCPServer) (*string, error) {
+	data, err := json.MarshalIndent(server, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化 MCP 条目失败: %w", err)
+	}
+	text := string(data) + "\n"
+	return &text, nil
+}
+
+func mcpEntryDisplayPath(configPath, assetName string, workspace Workspace) string {
+	return displayWorkspacePath(workspace, configPath) + "#mcpServers." + managedName(assetName)
+}
+
+// displayWorkspacePath 把 IDE 路径缩成展示用的短路径，避免把 home 绝对路径打进 TUI / MCP 输出。
+func displayWorkspacePath(workspace Workspace, path string) string {
+	path = filepath.Clean(path)
+	if workspace.EffectivePlane() == WorkspaceProject {
+		if rel, err := filepath.Rel(workspace.Root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return "~/" + filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+func renderedAssetLabel(asset types.TypedAssetRef) string {
+	return fmt.Sprintf("[%s] %s (%s)", asset.Type, asset.Name, asset.Vault)
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/freshness"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// maxTrustCheckCommits 是一次 pull 最多校验的提交数；超过时按未通过处理（范围被截断，不能只看一部分）。
+// 首次拉取历史较长的 vault 可用 commit_trust.anchor 指定起点。
+const maxTrustCheckCommits = 500
+
+// 签名状态（repo.Signature*），供展示层判断，不必直接依赖 repo 包。
+const (
+	SignatureUnsigned  = repo.SignatureUnsigned
+	SignatureTrusted   = repo.SignatureTrusted
+	SignatureUntrusted = repo.SignatureUntrusted
+	SignatureInvalid   = repo.SignatureInvalid
+)
+
+// CommitSignatureIssue 是一个未通过签名信任策略的提交。
+type CommitSignatureIssue struct {
+	Commit  string
+	Subject string
+	// Status 取值见 Signature*。
+	Status string
+	Signer string
+	Reason string
+}
+
+// verifyPullSignatures 按 commit_trust 策略校验本轮要安装的内容：
+// 自上次 pull 以来改动过启用 bundle、项目声明或 vault 格式声明的提交都必须由受信签名者签名；
+// 没有上次 pull 的记录（或该提交已不在本地仓库）时校验到 commit_trust.anchor，没有 anchor 时校验全部历史。
+// 范围超过 maxTrustCheckCommits 时同样按未通过处理。
+// enforce 下有问题的提交会让 pull 失败；warn 下只记告警。同时把拉取版本的签名者记到 result 上。
+func verifyPullSignatures(result *PullProjectAssetsResult, workspace Workspace, projectConfig *types.ProjectConfig, commitHash string, enabled []string, reporter Reporter) error {
+	trust, err := config.ResolveCommitTrust()
+	if err != nil {
+		return fmt.Errorf("读取签名信任策略失败: %w", err)
+	}
+	for _, warning := range trust.Warnings {
+		result.NonFatalWarnings = append(result.NonFatalWarnings, warning)
+		emit(reporter, EventWarn, "pull.trust", warning, nil)
+	}
+	if sig, err := repo.VerifyCommitSignature(commitHash, trust.Signers); err == nil {
+		result.Signer = sig.Signer
+		result.SignatureStatus = sig.Status
+	}
+	if !trust.Enabled() || commitHash == "" {
+		return nil
+	}
+
+	paths := trustCheckPaths(workspace, projectConfig, enabled)
+	base := trustCheckBase(result, workspace, trust.Anchor, reporter)
+	commits, truncated, err := repo.CommitsTouching(commitHash, base, paths, maxTrustCheckCommits)
+	if err != nil {
+		return fmt.Errorf("列出待校验的提交失败: %w", err)
+	}
+	if len(commits) == 0 && base != "" {
+		// 回滚或重装旧版本：没有新提交，仍校验当前内容最后一次改动的提交
+		commits, err = repo.History(commitHash, paths, 1)
+		if err != nil {
+			return fmt.Errorf("列出待校验的提交失败: %w", err)
+		}
+	}
+	for _, commit := range commits {
+		sig, err := repo.VerifyCommitSignature(commit.Hash, trust.Signers)
+		if err != nil {
+			return fmt.Errorf("校验提交 %s 的签名失败: %w", shortCommit(commit.Hash), err)
+		}
+		if sig.Status == SignatureTrusted {
+			continue
+		}
+		result.SignatureIssues = append(result.SignatureIssues, CommitSignatureIssue{
+			Commit: commit.Hash, Subject: commit.Subject, Status: sig.Status, Signer: sig.Signer, Reason: sig.Reason,
+		})
+	}
+	if len(result.SignatureIssues) == 0 && !truncated {
+		emit(reporter, EventInfo, "pull.trust", fmt.Sprintf("已校验 %d 个改动启用 bundle 的提交，签名均受信任", len(commits)), nil)
+		return nil
+	}
+
+	var parts []string
+	if len(result.SignatureIssues) > 0 {
+		parts = append(parts, fmt.Sprintf("%d 个改动启用 bundle 的提交未通过签名校验：%s",
+			len(result.SignatureIssues), describeSignatureIssues(result.SignatureIssues)))
+	}
+	if truncated {
+		parts = append(parts, fmt.Sprintf("待校验的提交超过 %d 个，未能完整校验（可在 commit_trust.anchor 填写已审计的提交作为起点）", maxTrustCheckCommits))
+	}
+	summary := strings.Join(parts, "；")
+	if trust.Policy == types.CommitTrustEnforce {
+		return fmt.Errorf("commit_trust 为 enforce，拒绝拉取：%s", summary)
+	}
+	result.NonFatalWarnings = append(result.NonFatalWarnings, summary)
+	emit(reporter, EventWarn, "pull.trust", summary, nil)
+	return nil
+}
+
+// trustCheckPaths 是签名校验覆盖的路径：启用的 bundle、本项目的 vault 声明与 vault 格式声明。
+func trustCheckPaths(workspace Workspace, projectConfig *types.ProjectConfig, enabled []string) []string {
+	paths := make([]string, 0, len(enabled)+2)
+	for _, name := range enabled {
+		paths = append(paths, types.VaultBundleDir(name))
+	}
+	if workspace.EffectivePlane() == WorkspaceProject {
+		name, _ := ResolveProjectName(workspace.Root, projectConfig)
+		paths = append(paths, types.VaultProjectPath(name))
+	}
+	return append(paths, types.VaultMetaFileName)
+}
+
+// trustCheckBase 决定校验范围的起点：上次 pull 的提交，其次是 commit_trust.anchor；都没有时为空串（校验全部历史）。
+// 起点不在本地仓库时告警并往后退，不会因此放宽校验。
+func trustCheckBase(result *PullProjectAssetsResult, workspace Workspace, anchor string, reporter Reporter) string {
+	warn := func(message string) {
+		result.NonFatalWarnings = append(result.NonFatalWarnings, message)
+		emit(reporter, EventWarn, "pull.trust", message, nil)
+	}
+	if meta, err := freshness.LoadVersionMeta(workspaceCacheRoot(workspace)); err == nil && meta != nil && meta.Commit != "" {
+		if _, err := repo.ResolveCommit(meta.Commit); err == nil {
+			return meta.Commit
+		}
+		warn(fmt.Sprintf("上次拉取的提交 %s 已不在本地仓库，改为从 commit_trust.anchor 或完整历史开始校验签名", shortCommit(meta.Commit)))
+	}
+	if anchor == "" {
+		return ""
+	}
+	if _, err := repo.ResolveCommit(anchor); err != nil {
+		warn(fmt.Sprintf("commit_trust.anchor %s 不在本地仓库，改为校验完整历史", anchor))
+		return ""
+	}
+	return anchor
+}
+
+func describeSignatureIssues(issues []CommitSignatureIssue) string {
+	parts := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		part := fmt.Sprintf("%s %s（%s", shortCommit(issue.Commit), issue.Subject, signatureStatusLabel(issue.Status))
+		if issue.Signer != "" {
+			part += "，" + issue.Signer
+		}
+		parts = append(parts, part+"）")
+	}
+	return strings.Join(parts, "；")
+}
+
+func signatureStatusLabel(status string) string {
+	switch status {
+	case SignatureTrusted:
+		return "受信签名"
+	case SignatureUntrusted:
+		return "签名者不受信任"
+	case SignatureInvalid:
+		return "签名无效"
+	case SignatureUnsigned:
+		return "未签名"
+	default:
+		return status
+	}
+}
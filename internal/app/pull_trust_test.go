@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPullWorkspaceAssetsCommitTrust(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/alpha.mdc": "---\ndescription: alpha\n---\nalpha v1\n",
+		"bundles/combo/bundle.yaml":     "name: combo\nmembers:\n  - rule/alpha\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{IDEs: []string{"cursor"}, EnabledBundles: []string{"combo"}}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	keyPath, pubLine := writeSigningKeyProjectTest(t, "vault-bot")
+	saveTrust := func(policy string) {
+		t.Helper()
+		if err := config.SaveGlobalConfig(&types.GlobalConfig{
+			RepoURL:     remote,
+			CommitTrust: types.CommitTrustConfig{Policy: policy, Signers: []string{pubLine}},
+		}); err != nil {
+			t.Fatalf("SaveGlobalConfig() 失败: %v", err)
+		}
+	}
+
+	// 初始提交未签名：enforce 拒绝，且不写入任何资产
+	saveTrust(types.CommitTrustEnforce)
+	if _, err := PullWorkspaceAssets(context.Background(), workspace, "", nil); err == nil || !strings.Contains(err.Error(), "未签名") {
+		t.Fatalf("enforce 下未签名提交应拒绝拉取, err = %v", err)
+	}
+	alphaCache := getWorkspaceCachePath(workspace, "combo", "rule", "alpha")
+	if _, err := os.Stat(alphaCache); !os.IsNotExist(err) {
+		t.Fatalf("拒绝拉取时不应写入缓存: %v", err)
+	}
+
+	// warn 照常安装，记下有问题的提交
+	saveTrust(types.CommitTrustWarn)
+	warned, err := PullWorkspaceAssets(context.Background(), workspace, "", nil)
+	if err != nil {
+		t.Fatalf("warn 下 PullWorkspaceAssets() 失败: %v", err)
+	}
+	if len(warned.SignatureIssues) != 1 || warned.SignatureIssues[0].Status != SignatureUnsigned || warned.SignatureStatus != SignatureUnsigned {
+		t.Fatalf("warn 结果 = %+v", warned)
+	}
+	if !strings.Contains(strings.Join(warned.NonFatalWarnings, "\n"), "未通过签名校验") {
+		t.Fatalf("warn 应记告警: %v", warned.NonFatalWarnings)
+	}
+
+	// Dec 自己签名推送后，enforce 只校验上次拉取之后的新提交
+	if err := repo.SetCommitSigning(repo.SigningOptions{Mode: repo.SigningSSH, Key: keyPath}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = repo.SetCommitSigning(repo.SigningOptions{Mode: repo.SigningOff}) })
+	writeFileProjectTest(t, alphaCache, "---\ndescription: alpha\n---\nalpha v2\n")
+	if _, err := PushWorkspaceAssets(context.Background(), workspace, nil); err != nil {
+		t.Fatalf("PushWorkspaceAssets() 失败: %v", err)
+	}
+	saveTrust(types.CommitTrustEnforce)
+	signed, err := PullWorkspaceAssets(context.Background(), workspace, "", nil)
+	if err != nil {
+		t.Fatalf("签名推送后 enforce 拉取失败: %v", err)
+	}
+	if signed.SignatureStatus != SignatureTrusted || !strings.Contains(signed.Signer, "vault-bot") || len(signed.SignatureIssues) != 0 {
+		t.Fatalf("签名拉取结果 = %+v", signed)
+	}
+
+	overview, err := LoadWorkspaceOverviewOpts(workspace, OverviewLoadOpts{})
+	if err != nil {
+		t.Fatalf("LoadWorkspaceOverviewOpts() 失败: %v", err)
+	}
+	if overview.PulledSignatureStatus != SignatureTrusted || !strings.Contains(overview.PulledSigner, "vault-bot") || overview.CommitTrustPolicy != types.CommitTrustEnforce {
+		t.Fatalf("overview 签名 = %q %q %q", overview.PulledSignatureStatus, overview.PulledSigner, overview.CommitTrustPolicy)
+	}
+}
+
+func TestFirstPullVerifiesWholeHistoryUnlessAnchored(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/alpha.mdc": "---\ndescription: alpha\n---\nalpha v1\n",
+		"bundles/combo/bundle.yaml":     "name: combo\nmembers:\n  - rule/alpha\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	initial := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main")
+	keyPath, pubLine := writeSigningKeyProjectTest(t, "vault-bot")
+	saveTrust := func(anchor string) {
+		t.Helper()
+		if err := config.SaveGlobalConfig(&types.GlobalConfig{
+			RepoURL:     remote,
+			CommitTrust: types.CommitTrustConfig{Policy: types.CommitTrustEnforce, Signers: []string{pubLine}, Anchor: anchor},
+		}); err != nil {
+			t.Fatalf("SaveGlobalConfig() 失败: %v", err)
+		}
+	}
+	newWorkspace := func(name string) Workspace {
+		t.Helper()
+		root := t.TempDir()
+		if err := config.NewProjectConfigManager(root).SaveProjectConfig(&types.ProjectConfig{
+			ProjectName: name, IDEs: []string{"cursor"}, EnabledBundles: []string{"combo"},
+		}); err != nil {
+			t.Fatalf("SaveProjectConfig() 失败: %v", err)
+		}
+		return NewWorkspace(WorkspaceProject, root)
+	}
+
+	// 受信签名的提交压在未签名的初始提交之上
+	writer := newWorkspace("writer")
+	if _, err := PullWorkspaceAssets(context.Background(), writer, "", nil); err != nil {
+		t.Fatalf("PullWorkspaceAssets() 失败: %v", err)
+	}
+	if err := repo.SetCommitSigning(repo.SigningOptions{Mode: repo.SigningSSH, Key: keyPath}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = repo.SetCommitSigning(repo.SigningOptions{Mode: repo.SigningOff}) })
+	writeFileProjectTest(t, getWorkspaceCachePath(writer, "combo", "rule", "alpha"), "---\ndescription: alpha\n---\nalpha v2\n")
+	if _, err := PushWorkspaceAssets(context.Background(), writer, nil); err != nil {
+		t.Fatalf("PushWorkspaceAssets() 失败: %v", err)
+	}
+
+	// 首次拉取不能只看最近一次提交：下面未签名的初始提交同样要校验
+	saveTrust("")
+	reader := newWorkspace("reader")
+	if _, err := PullWorkspaceAssets(context.Background(), reader, "", nil); err == nil || !strings.Contains(err.Error(), shortCommit(initial)) {
+		t.Fatalf("首次拉取应校验完整历史并拒绝未签名的初始提交, err = %v", err)
+	}
+
+	// 指定已审计的 anchor 后只校验它之后的提交
+	saveTrust(initial)
+	if _, err := PullWorkspaceAssets(context.Background(), reader, "", nil); err != nil {
+		t.Fatalf("anchor 之后的提交均已签名，拉取应成功: %v", err)
+	}
+
+	// 未签名地改动本项目的 vault 声明同样会被拒绝
+	seed := filepath.Join(t.TempDir(), "seed")
+	runGitNoDirProjectTest(t, "clone", remote, seed)
+	configureGitUserProjectTest(t, seed)
+	writeFileProjectTest(t, filepath.Join(seed, types.VaultProjectPath("reader")), "name: reader\nbundles:\n  - combo\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "unsigned project declaration")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+	if _, err := PullWorkspaceAssets(context.Background(), reader, "", nil); err == nil || !strings.Contains(err.Error(), "unsigned project declaration") {
+		t.Fatalf("未签名的项目声明改动应拒绝拉取, err = %v", err)
+	}
+}
+
+// writeSigningKeyProjectTest 生成 ed25519 私钥文件，返回路径与带备注的公钥行。
+func writeSigningKeyProjectTest(t *testing.T, comment string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " " + comment
+}
@@ -1,19 +1,37 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/shichao402/Dec/internal/bundle"
+	"github.com/shichao402/Dec/internal/config"
 	"github.com/shichao402/Dec/internal/repo"
 	"github.com/shichao402/Dec/internal/types"
 )
 
 type PushProjectAssetsResult struct {
-	DecPushedCount       int
-	DecSkippedReason     string
+	DecPushedCount   int
+	DecSkippedReason string
+	// DecConflicts 是三方合并无法自动解决的文件；非空时本次 Dec 推送整体放弃，vault 不变。
+	DecConflicts []PushConflict
+	// DecMergeBase 是作为合并基准的拉取版本（.dec/.version），为空表示以本地缓存覆盖。
+	DecMergeBase string
+	// DecReviewBranch 非空表示按 push_policy: branch 推到了该评审分支，合并到 vault 分支后才生效。
+	DecReviewBranch string
+	// DecSelected 为 true 表示按 PushSelection 只提交了部分改动，DecPushedCount 此时是文件数。
+	DecSelected bool
+	// DecCommitMessage 是本次 Dec 提交的完整说明（按 commit_template 渲染），未提交时为空。
+	DecCommitMessage string
+	// DecQueued 表示离线时提交只进了本地队列，下次在线 push 时发送；DecOfflineReason 是离线原因。
+	DecQueued        bool
+	DecOfflineReason string
+	// DecQueueSent 是本次 push 前送出的、此前离线排队的提交数。
+	DecQueueSent         int
 	VersionCommit        string
 	SecretsCreatedCount  int
 	SecretsUpdatedCount  int
@@ -28,24 +46,51 @@ func PushProjectAssets(ctx context.Context, projectRoot string, reporter Reporte
 // PushWorkspaceAssets 把当前平面的本地缓存与 secrets 落地文件推回远端。
 // 用户平面读 ~/.dec/cache 与 ~/.dec/secrets，只涉及 scope: user 的 bundle。
 func PushWorkspaceAssets(ctx context.Context, workspace Workspace, reporter Reporter) (*PushProjectAssetsResult, error) {
+	return PushWorkspaceWith(ctx, workspace, PushOptions{}, reporter)
+}
+
+// PushOptions 是一次 push 的可选参数，零值等同 PushWorkspaceAssets。
+type PushOptions struct {
+	// Selection 限定提交进 vault 的改动；非空时只针对 Git vault 中的文件，secrets 不在选择粒度内，本次跳过。
+	Selection PushSelection `json:"selection"`
+	// Message 是用户填写的提交说明，填入提交模板的 Title / Body；为空时用自动摘要。
+	Message string `json:"message,omitempty"`
+}
+
+// PushWorkspaceWith 按 opts 推送当前平面的 Dec 改动与 secrets。
+func PushWorkspaceWith(ctx context.Context, workspace Workspace, opts PushOptions, reporter Reporter) (*PushProjectAssetsResult, error) {
 	reporter = defaultReporter(reporter)
+	selection := opts.Selection
 	result := &PushProjectAssetsResult{}
 
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	decPushed, decSkipped, commit, err := pushDecBundles(ctx, workspace, reporter)
+	dec, err := pushDecBundles(ctx, workspace, opts, reporter)
 	if err != nil {
 		return nil, fmt.Errorf("push.dec 失败: %w", err)
 	}
-	result.DecPushedCount = decPushed
-	result.DecSkippedReason = decSkipped
-	result.VersionCommit = commit
+	result.DecPushedCount = dec.pushedCount
+	result.DecSkippedReason = dec.skippedReason
+	result.DecConflicts = dec.conflicts
+	result.DecMergeBase = dec.mergeBase
+	result.DecReviewBranch = dec.reviewBranch
+	result.DecSelected = !selection.IsEmpty()
+	result.VersionCommit = dec.versionCommit
+	result.DecCommitMessage = dec.commitMessage
+	result.DecQueued = dec.queued
+	result.DecOfflineReason = dec.offlineReason
+	result.DecQueueSent = dec.queueSent
 
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	if !selection.IsEmpty() {
+		result.SecretsSkippedReason = "按选择推送 Dec 改动，secrets 未推送"
+		emit(reporter, EventInfo, "push.secrets", result.SecretsSkippedReason, nil)
+		return result, nil
+	}
 
 	secretsResult, err := PushWorkspaceSecretsBundles(ctx, workspace, reporter)
 	if err != nil {
@@ -59,24 +104,60 @@ func PushWorkspaceAssets(ctx context.Context, workspace Workspace, reporter Repo
 	return result, nil
 }
 
-func pushDecBundles(ctx context.Context, workspace Workspace, reporter Reporter) (pushedCount int, skippedReason, versionCommit string, err error) {
+// decPushOutcome 汇总 Dec vault 推送阶段的结果。
+type decPushOutcome struct {
+	pushedCount   int
+	skippedReason string
+	conflicts     []PushConflict
+	mergeBase     string
+	reviewBranch  string
+	versionCommit string
+	commitMessage string
+	queued        bool
+	offlineReason string
+	queueSent     int
+}
+
+func pushDecBundles(ctx context.Context, workspace Workspace, opts PushOptions, reporter Reporter) (decPushOutcome, error) {
+	var out decPushOutcome
+	selection := opts.Selection
 	projectConfig, err := loadWorkspaceBundleConfig(workspace)
 	if err != nil {
-		return 0, "", "", err
+		return out, err
 	}
 
 	if len(projectConfig.EnabledBundles) == 0 {
-		skippedReason = "无已启用 bundle"
+		out.skippedReason = "无已启用 bundle"
 		emit(reporter, EventInfo, "push.dec", "无已启用 bundle，跳过 Dec 推送", nil)
-		return 0, skippedReason, "", nil
+		return out, nil
+	}
+
+	vaultBranch, err := resolveVaultBranch(projectConfig, reporter, "push.dec")
+	if err != nil {
+		return out, err
+	}
+	if vaultBranch != "" {
+		emit(reporter, EventInfo, "push.dec", fmt.Sprintf("推送目标 vault 分支 %s", vaultBranch), nil)
+	}
+	pushPolicy, err := resolvePushPolicy(reporter, "push.dec")
+	if err != nil {
+		return out, err
 	}
 
 	emit(reporter, EventInfo, "push.dec", fmt.Sprintf("检查 %s 变更…", displayCacheDir(workspace)), nil)
+	out.queueSent, err = flushPushQueue(reporter, "push.dec")
+	if err != nil {
+		return out, err
+	}
 
-	err = withAppWriteRepo(func(tx *repo.Transaction) error {
+	err = withAppWriteRepo(vaultBranch, func(tx *repo.Transaction) error {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
+		if tx.Offline() {
+			out.offlineReason = tx.OfflineReason()
+			emit(reporter, EventWarn, "push.dec", fmt.Sprintf("离线：基于本地缓存的 vault 提交，推送将排队（%s）", out.offlineReason), nil)
+		}
 		repoDir := tx.WorkDir()
 		resolved, resolveErr := resolveDesiredAssetsForPlane(projectConfig, repoDir, workspace.EffectivePlane(), reporter)
 		if resolveErr != nil {
@@ -85,8 +166,8 @@ func pushDecBundles(ctx context.Context, workspace Workspace, reporter Reporter)
 
 		assets := resolved.Assets
 		if len(assets) == 0 && len(projectConfig.EnabledBundles) == 0 {
-			skippedReason = "没有可推送的有效资产"
-			emit(reporter, EventInfo, "push.dec", skippedReason, nil)
+			out.skippedReason = "没有可推送的有效资产"
+			emit(reporter, EventInfo, "push.dec", out.skippedReason, nil)
 			return nil
 		}
 
@@ -94,61 +175,172 @@ func pushDecBundles(ctx context.Context, workspace Workspace, reporter Reporter)
 			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("同步 %d 个 Dec 资产", len(assets)), &Progress{Phase: "dec", Current: 0, Total: len(assets)})
 		}
 
-		synced, pruned, syncErr := syncDecVaultFromCache(workspace, repoDir, projectConfig, resolved, reporter)
-		if syncErr != nil {
-			return syncErr
+		if selection, ideErr := config.ResolveEffectiveIDEs(projectConfig); ideErr == nil {
+			targetIDEs := uniqueWorkspaceIDEs(workspace, selection.IDEs)
+			for _, asset := range assets {
+				if asset.Type == "mcp" {
+					emitMCPEnvRefLint(reporter, "push.lint", getWorkspaceCachePath(workspace, asset.Vault, asset.Type, asset.Name), asset, workspace, targetIDEs)
+				}
+			}
 		}
 
-		for _, bundleName := range projectConfig.EnabledBundles {
-			if err := ctx.Err(); err != nil {
-				return err
-			}
-			ok, pushErr := pushBundleYAMLFromCache(workspace, repoDir, bundleName, reporter)
-			if pushErr != nil {
-				return pushErr
-			}
-			if ok {
-				synced++
-			}
+		merge, mergeErr := mergeDecCacheIntoVault(ctx, workspace, repoDir, projectConfig, resolved, reporter)
+		if mergeErr != nil {
+			return mergeErr
+		}
+		out.mergeBase = merge.base
+		if !selection.IsEmpty() {
+			merge.conflicts = filterSelectedConflicts(merge.conflicts, selection)
+		}
+		if len(merge.conflicts) > 0 {
+			out.conflicts = merge.conflicts
+			out.skippedReason = fmt.Sprintf("%d 个文件与远端冲突，未推送", len(merge.conflicts))
+			emit(reporter, EventWarn, "push.dec", out.skippedReason+"：请先拉取并处理冲突后再推送", nil)
+			return nil
 		}
 
-		git := repo.NewGitOps(repoDir)
-		clean, cleanErr := git.IsClean()
+		changes, changesErr := pushedVaultChanges(tx, resolved, projectConfig, selection, reporter)
+		if changesErr != nil {
+			return changesErr
+		}
+
+		clean, cleanErr := tx.IsClean()
 		if cleanErr != nil {
 			return cleanErr
 		}
 		if clean {
-			skippedReason = "无本地变更"
+			out.skippedReason = "无本地变更"
 			emit(reporter, EventInfo, "push.dec", "无本地变更，跳过 Dec 推送", nil)
 			return nil
 		}
 
-		commitMsg := fmt.Sprintf("push: 更新 %d 项", synced)
-		committed, commitErr := tx.CommitAndPush(commitMsg)
+		target := tx.Branch()
+		if pushPolicy == types.PushPolicyBranch {
+			out.reviewBranch = reviewBranchName(workspace, projectConfig, time.Now())
+			target = out.reviewBranch
+		}
+		commitMsg := renderCommitMessage(newCommitMessageData(workspace, workspaceProjectName(workspace, projectConfig), target, opts.Message, changes), reporter, "push.dec")
+		var committed bool
+		var commitErr error
+		if out.reviewBranch != "" {
+			committed, commitErr = tx.CommitAndPushToBranch(commitMsg, out.reviewBranch)
+		} else {
+			committed, commitErr = tx.CommitAndPush(commitMsg)
+		}
 		if commitErr != nil {
 			return commitErr
 		}
 		if !committed {
-			skippedReason = "无本地变更"
+			out.reviewBranch = ""
+			out.skippedReason = "无本地变更"
 			emit(reporter, EventInfo, "push.dec", "无本地变更，跳过 Dec 推送", nil)
 			return nil
 		}
-		pushedCount = synced + pruned
-		versionCommit = tx.CommitHash()
-		if pruned > 0 {
-			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("Dec 推送完成：%d 项更新 · %d 项删除", synced, pruned), &Progress{Phase: "done", Current: pushedCount, Total: pushedCount})
+		if tx.Queued() {
+			out.queued = true
+			emit(reporter, EventWarn, "push.dec", fmt.Sprintf("离线：提交已加入本地队列，下次在线 push 时发送到 %s", target), nil)
 		} else {
-			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("Dec 推送完成：%d 项", synced), &Progress{Phase: "done", Current: synced, Total: synced})
+			discardSupersededQueue(target, tx.CommitHash(), reporter, "push.dec")
+			if out.reviewBranch != "" {
+				emit(reporter, EventInfo, "push.dec", fmt.Sprintf("已推送到评审分支 %s，合并到 %s 后生效", out.reviewBranch, tx.Branch()), nil)
+			}
+		}
+		out.pushedCount = merge.synced + merge.pruned
+		out.versionCommit = tx.CommitHash()
+		out.commitMessage = commitMsg
+		if !selection.IsEmpty() {
+			out.pushedCount = len(changes)
+			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("Dec 推送完成：按选择提交 %d 个文件", len(changes)), &Progress{Phase: "done", Current: len(changes), Total: len(changes)})
+		} else if merge.pruned > 0 {
+			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("Dec 推送完成：%d 项更新 · %d 项删除", merge.synced, merge.pruned), &Progress{Phase: "done", Current: out.pushedCount, Total: out.pushedCount})
+		} else {
+			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("Dec 推送完成：%d 项", merge.synced), &Progress{Phase: "done", Current: merge.synced, Total: merge.synced})
 		}
 		return nil
 	})
 	if err != nil {
-		return 0, "", "", err
+		return decPushOutcome{}, err
 	}
-	return pushedCount, skippedReason, versionCommit, nil
+	return out, nil
 }
 
-func pushBundleYAMLFromCache(workspace Workspace, repoDir, bundleName string, reporter Reporter) (bool, error) {
+// pushedVaultChanges 列出合并后工作区相对 vault 最新提交的文件改动，用于生成提交说明；
+// 选择非空时先撤回范围外的改动，返回保留下来的部分。
+func pushedVaultChanges(tx *repo.Transaction, resolved *ResolvedAssets, projectConfig *types.ProjectConfig, selection PushSelection, reporter Reporter) ([]VaultFileChange, error) {
+	bundles := sortedBundleNames(projectConfig, resolved.Assets)
+	head, err := openVaultHeadForDiff(tx, bundles)
+	if err != nil {
+		return nil, err
+	}
+	defer head.Close()
+	changes, err := collectVaultChanges(tx.WorkDir(), head.WorkDir(), bundles)
+	if err != nil {
+		return nil, err
+	}
+	if selection.IsEmpty() {
+		return changes, nil
+	}
+	for _, miss := range selectionMisses(selection, changes) {
+		emit(reporter, EventWarn, "push.dec", fmt.Sprintf("⚠️  选择的 %s 没有待推送的改动，已忽略", miss), nil)
+	}
+	kept, err := restrictVaultChanges(tx.WorkDir(), head.WorkDir(), changes, selection)
+	if err != nil {
+		return nil, err
+	}
+	if skipped := len(changes) - len(kept); skipped > 0 {
+		emit(reporter, EventInfo, "push.dec", fmt.Sprintf("按选择推送 %d 个文件，其余 %d 个改动留在本地", len(kept), skipped), nil)
+	}
+	return kept, nil
+}
+
+// decCacheMerge 是把本地 cache 合入 vault 工作区后的统计。
+type decCacheMerge struct {
+	synced    int
+	pruned    int
+	conflicts []PushConflict
+	base      string
+}
+
+// mergeDecCacheIntoVault 以拉取版本为基准，把 cache 中的资产与 bundle 声明改动合入 repoDir。
+// push 与 push 预览共用，conflicts 非空时调用方不应提交。
+func mergeDecCacheIntoVault(ctx context.Context, workspace Workspace, repoDir string, projectConfig *types.ProjectConfig, resolved *ResolvedAssets, reporter Reporter) (decCacheMerge, error) {
+	var merge decCacheMerge
+	baseTx, err := openPushMergeBase(workspace, projectConfig.EnabledBundles, reporter)
+	if err != nil {
+		return merge, err
+	}
+	baseDir := ""
+	if baseTx != nil {
+		defer baseTx.Close()
+		baseDir = baseTx.WorkDir()
+		merge.base = baseTx.CommitHash()
+	}
+
+	merge.synced, merge.pruned, merge.conflicts, err = syncDecVaultFromCache(workspace, repoDir, baseDir, projectConfig, resolved, reporter)
+	if err != nil {
+		return merge, err
+	}
+
+	for _, bundleName := range projectConfig.EnabledBundles {
+		if err := ctx.Err(); err != nil {
+			return merge, err
+		}
+		ok, conflict, pushErr := pushBundleYAMLFromCache(workspace, repoDir, baseDir, bundleName, reporter)
+		if pushErr != nil {
+			return merge, pushErr
+		}
+		if conflict != nil {
+			merge.conflicts = append(merge.conflicts, *conflict)
+		}
+		if ok {
+			merge.synced++
+		}
+	}
+	return merge, nil
+}
+
+// pushBundleYAMLFromCache 把 cache 中的 bundle 声明合入 vault；baseDir 语义同 syncDecVaultFromCache。
+func pushBundleYAMLFromCache(workspace Workspace, repoDir, baseDir, bundleName string, reporter Reporter) (bool, *PushConflict, error) {
 	cacheDir := filepath.Join(workspaceCacheDir(workspace), bundleName)
 	var cachePath string
 	for _, name := range []string{"bundle.yaml", "bundle.yml"} {
@@ -159,23 +351,56 @@ func pushBundleYAMLFromCache(workspace Workspace, repoDir, bundleName string, re
 		}
 	}
 	if cachePath == "" {
-		return false, nil
+		return false, nil, nil
 	}
 
 	data, err := os.ReadFile(cachePath)
 	if err != nil {
 		emit(reporter, EventWarn, "push.dec", fmt.Sprintf("⚠️  读取 bundle %s 声明失败: %v", bundleName, err), nil)
-		return false, nil
+		return false, nil, nil
 	}
 	if _, err := bundle.Validate(data, cachePath); err != nil {
 		emit(reporter, EventWarn, "push.dec", fmt.Sprintf("⚠️  bundle %s 校验失败: %v", bundleName, err), nil)
-		return false, nil
+		return false, nil, nil
 	}
 
-	destPath := filepath.Join(repoDir, types.VaultBundlesDir, bundleName, "bundle.yaml")
-	if err := copyFile(cachePath, destPath); err != nil {
-		return false, fmt.Errorf("推送 bundle %s 声明失败: %w", bundleName, err)
+	manifestRel := types.VaultBundleManifestPath(bundleName)
+	destPath := filepath.Join(repoDir, filepath.FromSlash(manifestRel))
+	theirs, theirsOK, err := readOptionalFile(destPath)
+	if err != nil {
+		return false, nil, fmt.Errorf("读取 vault 中 bundle %s 声明失败: %w", bundleName, err)
+	}
+	merged := data
+	if baseDir != "" {
+		base, baseOK, err := readOptionalFile(filepath.Join(baseDir, filepath.FromSlash(manifestRel)))
+		if err != nil {
+			return false, nil, fmt.Errorf("读取合并基准中 bundle %s 声明失败: %w", bundleName, err)
+		}
+		var (
+			mergedOK bool
+			reason   string
+		)
+		merged, mergedOK, reason = mergeFileContents(base, data, theirs, baseOK, true, theirsOK)
+		if reason != "" {
+			conflict := &PushConflict{Path: manifestRel, Reason: reason}
+			emit(reporter, EventWarn, "push.dec", fmt.Sprintf("  ✗ %s：%s", conflict.Path, conflict.Reason), nil)
+			return false, conflict, nil
+		}
+		if !mergedOK {
+			// 远端已删除、本地未改动：保持删除，不把声明推回成空文件。
+			return false, nil, nil
+		}
+	}
+	if theirsOK && bytes.Equal(merged, theirs) {
+		return false, nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return false, nil, fmt.Errorf("推送 bundle %s 声明失败: %w", bundleName, err)
+	}
+	if err := os.WriteFile(destPath, merged, 0644); err != nil {
+		return false, nil, fmt.Errorf("推送 bundle %s 声明失败: %w", bundleName, err)
 	}
 	emit(reporter, EventInfo, "push.dec", fmt.Sprintf("  [bundle] %s", bundleName), nil)
-	return true, nil
+	return true, nil, nil
 }
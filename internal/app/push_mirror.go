@@ -1,95 +1,332 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/shichao402/Dec/internal/freshness"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/textdiff"
 	"github.com/shichao402/Dec/internal/types"
 )
 
-// syncDecVaultFromCache 将 enabled 范围内的 vault 资产与本地 cache 对齐：复制存在项、删除 cache 已缺失项。
-func syncDecVaultFromCache(workspace Workspace, repoDir string, projectConfig *types.ProjectConfig, resolved *ResolvedAssets, reporter Reporter) (synced, pruned int, err error) {
+// PushConflict 是一处无法自动合并的文件：自拉取以来本地与远端都改过它，且改动重叠。
+type PushConflict struct {
+	// Path 是 vault 内的相对路径（/ 分隔），如 bundles/combo/rules/a.mdc
+	Path   string
+	Reason string
+}
+
+// openPushMergeBase 打开 .dec/.version 记录的拉取版本，作为推送三方合并的基准。
+//
+// 没有拉取记录时返回 nil：推送退化为以本地缓存覆盖 vault（旧行为），并发出告警。
+// 有记录但该提交已不在本地仓库（远端被强推等）时报错，要求先重新拉取。
+func openPushMergeBase(workspace Workspace, enabledBundles []string, reporter Reporter) (*repo.Transaction, error) {
+	meta, err := freshness.LoadVersionMeta(workspaceCacheRoot(workspace))
+	if err != nil {
+		return nil, fmt.Errorf("读取拉取记录失败: %w", err)
+	}
+	if meta == nil || meta.Commit == "" {
+		emit(reporter, EventWarn, "push.dec", "⚠️  没有拉取记录（.dec/.version），无法与远端三方合并，将以本地缓存覆盖", nil)
+		return nil, nil
+	}
+	tx, err := repo.NewReadTransactionWith(repo.ReadOptions{
+		Ref:    meta.Commit,
+		Local:  true,
+		Select: vaultReadSelection(enabledBundles),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("合并基准 %s 已不在仓库中，请先拉取再推送: %w", shortCommit(meta.Commit), err)
+	}
+	emit(reporter, EventInfo, "push.dec", fmt.Sprintf("以拉取版本 %s 为基准合并本地改动", shortCommit(meta.Commit)), nil)
+	return tx, nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// syncDecVaultFromCache 把 enabled 范围内本地 cache 的改动合入 vault 工作区。
+//
+// baseDir 为拉取版本的只读工作区：只有 cache 相对它的改动才会写入 vault，远端在此之后的修改保留；
+// 两边改了同一处时记为冲突，调用方不应再提交。baseDir 为空时退化为以 cache 覆盖 vault。
+func syncDecVaultFromCache(workspace Workspace, repoDir, baseDir string, projectConfig *types.ProjectConfig, resolved *ResolvedAssets, reporter Reporter) (synced, pruned int, conflicts []PushConflict, err error) {
 	assets := resolved.Assets
 	if len(assets) == 0 && len(projectConfig.EnabledBundles) == 0 {
-		return 0, 0, nil
+		return 0, 0, nil, nil
 	}
 
+	candidates := append([]types.TypedAssetRef(nil), assets...)
 	bundlesToScan := collectEnabledBundleNames(projectConfig, assets)
+	bundleNames := make([]string, 0, len(bundlesToScan))
+	for name := range bundlesToScan {
+		bundleNames = append(bundleNames, name)
+	}
+	sort.Strings(bundleNames)
+	// 远端或基准里存在、但不在本次解析结果中的成员：cache 删除了它们，或远端删除后本地仍在修改
+	for _, bundleName := range bundleNames {
+		members := listBundleAssetMembers(repoDir, bundleName)
+		if baseDir != "" {
+			members = append(members, listBundleAssetMembers(baseDir, bundleName)...)
+		}
+		for _, member := range members {
+			parts := strings.SplitN(member, "/", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			itemType := memberPrefixToAssetType(parts[0])
+			if itemType == "" || assetInResolved(candidates, bundleName, itemType, parts[1]) {
+				continue
+			}
+			candidates = append(candidates, types.TypedAssetRef{Type: itemType, AssetRef: types.AssetRef{Name: parts[1], Vault: bundleName}})
+		}
+	}
 
-	for idx, asset := range assets {
-		progress := &Progress{Phase: "dec", Current: idx + 1, Total: len(assets)}
-		cachePath := getWorkspaceCachePath(workspace, asset.Vault, asset.Type, asset.Name)
+	for idx, asset := range candidates {
+		var progress *Progress
+		if idx < len(assets) {
+			progress = &Progress{Phase: "dec", Current: idx + 1, Total: len(assets)}
+		}
 		destPath := resolveAssetFile(repoDir, asset.Vault, asset.Type, asset.Name)
 		if destPath == "" {
 			continue
 		}
+		basePath := ""
+		if baseDir != "" {
+			basePath = resolveAssetFile(baseDir, asset.Vault, asset.Type, asset.Name)
+		}
+		cachePath := getWorkspaceCachePath(workspace, asset.Vault, asset.Type, asset.Name)
 
-		if _, statErr := os.Stat(cachePath); os.IsNotExist(statErr) {
-			if _, vaultErr := os.Stat(destPath); vaultErr == nil {
-				if rmErr := os.RemoveAll(destPath); rmErr != nil {
-					emit(reporter, EventWarn, "push.dec", fmt.Sprintf("⚠️  [%s] %s 远端删除失败: %v", asset.Type, asset.Name, rmErr), progress)
-					continue
-				}
-				pruned++
-				emit(reporter, EventInfo, "push.dec", fmt.Sprintf("  − [%s] %s（cache 已删）", asset.Type, asset.Name), progress)
-			}
+		outcome, mergeErr := mergeAssetIntoVault(cachePath, basePath, destPath, baseDir != "")
+		if mergeErr != nil {
+			emit(reporter, EventWarn, "push.dec", fmt.Sprintf("⚠️  [%s] %s 推送失败: %v", asset.Type, asset.Name, mergeErr), progress)
 			continue
 		}
+		for _, c := range outcome.conflicts {
+			conflict := PushConflict{Path: vaultRelPath(repoDir, destPath, c.rel), Reason: c.reason}
+			conflicts = append(conflicts, conflict)
+			emit(reporter, EventWarn, "push.dec", fmt.Sprintf("  ✗ %s：%s", conflict.Path, conflict.Reason), progress)
+		}
+		switch {
+		case len(outcome.conflicts) > 0:
+		case outcome.removed:
+			pruned++
+			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("  − [%s] %s / %s（cache 已删）", asset.Type, asset.Name, asset.Vault), progress)
+		case outcome.changed:
+			synced++
+			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("  [%s] %s → %s", asset.Type, asset.Name, asset.Vault), progress)
+		}
+	}
+	return synced, pruned, conflicts, nil
+}
 
-		switch asset.Type {
-		case "skill", "command":
-			if copyErr := copyDir(cachePath, destPath); copyErr != nil {
-				emit(reporter, EventWarn, "push.dec", fmt.Sprintf("⚠️  [%s] %s 推送失败: %v", asset.Type, asset.Name, copyErr), progress)
-				continue
-			}
-		case "rule", "mcp":
-			if copyErr := copyFile(cachePath, destPath); copyErr != nil {
-				emit(reporter, EventWarn, "push.dec", fmt.Sprintf("⚠️  [%s] %s 推送失败: %v", asset.Type, asset.Name, copyErr), progress)
-				continue
-			}
-		default:
-			continue
+type fileConflict struct {
+	rel    string
+	reason string
+}
+
+type assetMergeOutcome struct {
+	changed   bool
+	removed   bool
+	conflicts []fileConflict
+}
+
+// mergeAssetIntoVault 逐文件把 mine（cache）相对 base 的改动合入 theirs（vault 工作区）。
+// 三个路径都可能是单个文件（rule/mcp）或目录（skill/command），也可能不存在。
+//
+// hasBase=false 时沿用旧的覆盖语义：cache 里有的文件覆盖 vault，cache 整个缺失时删除 vault 资产，
+// 目录内 vault 独有的文件保留。
+func mergeAssetIntoVault(mine, base, theirs string, hasBase bool) (assetMergeOutcome, error) {
+	var outcome assetMergeOutcome
+	mineFiles, err := listAssetFiles(mine)
+	if err != nil {
+		return outcome, err
+	}
+	baseFiles, err := listAssetFiles(base)
+	if err != nil {
+		return outcome, err
+	}
+	theirsFiles, err := listAssetFiles(theirs)
+	if err != nil {
+		return outcome, err
+	}
+	if !hasBase && len(mineFiles) > 0 {
+		// 旧语义：cache 存在时只覆盖，不删除 vault 目录内多出的文件
+		baseFiles = nil
+	}
+
+	rels := make(map[string]struct{})
+	for _, set := range []map[string]string{mineFiles, baseFiles, theirsFiles} {
+		for rel := range set {
+			rels[rel] = struct{}{}
 		}
-		synced++
-		emit(reporter, EventInfo, "push.dec", fmt.Sprintf("  [%s] %s → %s", asset.Type, asset.Name, asset.Vault), progress)
 	}
+	ordered := make([]string, 0, len(rels))
+	for rel := range rels {
+		ordered = append(ordered, rel)
+	}
+	sort.Strings(ordered)
 
-	for bundleName := range bundlesToScan {
-		members := listBundleAssetMembers(repoDir, bundleName)
-		for _, member := range members {
-			parts := strings.SplitN(member, "/", 2)
-			if len(parts) != 2 {
-				continue
-			}
-			itemType := memberPrefixToAssetType(parts[0])
-			if itemType == "" {
-				continue
-			}
-			assetName := parts[1]
-			if assetInResolved(assets, bundleName, itemType, assetName) {
-				continue
+	remaining := len(theirsFiles)
+	for _, rel := range ordered {
+		m, mOK, err := readOptionalFile(mineFiles[rel])
+		if err != nil {
+			return outcome, err
+		}
+		t, tOK, err := readOptionalFile(theirsFiles[rel])
+		if err != nil {
+			return outcome, err
+		}
+		var merged []byte
+		var mergedOK bool
+		var reason string
+		if hasBase {
+			b, bOK, err := readOptionalFile(baseFiles[rel])
+			if err != nil {
+				return outcome, err
 			}
-			cachePath := getWorkspaceCachePath(workspace, bundleName, itemType, assetName)
-			vaultPath := resolveAssetFile(repoDir, bundleName, itemType, assetName)
-			if vaultPath == "" {
-				continue
+			merged, mergedOK, reason = mergeFileContents(b, m, t, bOK, mOK, tOK)
+		} else if mOK || len(mineFiles) == 0 {
+			merged, mergedOK = m, mOK
+		} else {
+			merged, mergedOK = t, tOK
+		}
+		if reason != "" {
+			outcome.conflicts = append(outcome.conflicts, fileConflict{rel: rel, reason: reason})
+			continue
+		}
+
+		target := filepath.Join(theirs, filepath.FromSlash(rel))
+		switch {
+		case !mergedOK && tOK:
+			if err := os.Remove(target); err != nil {
+				return outcome, err
 			}
-			if _, cacheErr := os.Stat(cachePath); !os.IsNotExist(cacheErr) {
-				continue
+			remaining--
+			outcome.changed = true
+		case mergedOK && (!tOK || !bytes.Equal(merged, t)):
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return outcome, err
 			}
-			if _, vaultErr := os.Stat(vaultPath); os.IsNotExist(vaultErr) {
-				continue
+			if err := os.WriteFile(target, merged, assetFileMode(mineFiles[rel], theirsFiles[rel])); err != nil {
+				return outcome, err
 			}
-			if rmErr := os.RemoveAll(vaultPath); rmErr != nil {
-				emit(reporter, EventWarn, "push.dec", fmt.Sprintf("⚠️  [%s] %s 远端删除失败: %v", itemType, assetName, rmErr), nil)
-				continue
+			if !tOK {
+				remaining++
 			}
-			pruned++
-			emit(reporter, EventInfo, "push.dec", fmt.Sprintf("  − [%s] %s / %s（cache 已删）", itemType, assetName, bundleName), nil)
+			outcome.changed = true
 		}
 	}
-	return synced, pruned, nil
+	if outcome.changed && remaining == 0 && len(outcome.conflicts) == 0 {
+		outcome.removed = true
+		if err := os.RemoveAll(theirs); err != nil {
+			return outcome, err
+		}
+	}
+	return outcome, nil
+}
+
+// mergeFileContents 对单个文件做三方合并，reason 非空表示冲突。
+func mergeFileContents(base, mine, theirs []byte, baseOK, mineOK, theirsOK bool) (merged []byte, ok bool, reason string) {
+	same := func(a, b []byte, aOK, bOK bool) bool {
+		return aOK == bOK && bytes.Equal(a, b)
+	}
+	switch {
+	case same(mine, base, mineOK, baseOK), same(mine, theirs, mineOK, theirsOK):
+		return theirs, theirsOK, ""
+	case same(theirs, base, theirsOK, baseOK):
+		return mine, mineOK, ""
+	case !mineOK:
+		return nil, false, "本地已删除，远端在拉取后修改过"
+	case !theirsOK:
+		return nil, false, "远端已删除，本地有修改"
+	case !baseOK:
+		return nil, false, "本地与远端各自新增了内容不同的同名文件"
+	case bytes.IndexByte(base, 0) >= 0 || bytes.IndexByte(mine, 0) >= 0 || bytes.IndexByte(theirs, 0) >= 0:
+		return nil, false, "二进制文件在本地与远端都被修改"
+	}
+	text, merged3 := textdiff.Merge3(string(base), string(mine), string(theirs))
+	if !merged3 {
+		return nil, false, "本地与远端修改了相同的行"
+	}
+	return []byte(text), true, ""
+}
+
+// listAssetFiles 列出资产下的全部文件：相对路径（/ 分隔；单文件资产为空串）→ 绝对路径。
+func listAssetFiles(root string) (map[string]string, error) {
+	files := make(map[string]string)
+	if root == "" {
+		return files, nil
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return files, nil
+		}
+		return nil, err
+	}
+	if !info.IsDir() {
+		files[""] = root
+		return files, nil
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return relErr
+		}
+		files[filepath.ToSlash(rel)] = path
+		return nil
+	})
+	return files, err
+}
+
+func readOptionalFile(path string) ([]byte, bool, error) {
+	if path == "" {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// assetFileMode 沿用 cache（其次 vault）文件的可执行位。
+func assetFileMode(paths ...string) os.FileMode {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			return info.Mode().Perm() | 0644
+		}
+	}
+	return 0644
+}
+
+func vaultRelPath(repoDir, assetPath, rel string) string {
+	full := filepath.Join(assetPath, filepath.FromSlash(rel))
+	if r, err := filepath.Rel(repoDir, full); err == nil {
+		return filepath.ToSlash(r)
+	}
+	return filepath.ToSlash(full)
 }
 
 func collectEnabledBundleNames(projectConfig *types.ProjectConfig, assets []types.TypedAssetRef) map[string]struct{} {
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/shichao402/Dec/internal/repo"
 	"github.com/shichao402/Dec/internal/secrets"
@@ -13,14 +14,23 @@ import (
 // 无 secrets 文件数：待推文件由远端 folder 的 note 列表决定，数不出来又不联网，
 // 这里只报会涉及几个 folder。
 type PushProjectAssetsPreview struct {
-	EnabledBundleCount  int
-	EnabledBundleNames  []string
-	ProjectSecretsName  string
-	SecretsTargetCount  int
-	DecCandidateCount   int
-	DecHasChanges       bool
-	DecSkippedReason    string
+	EnabledBundleCount int
+	EnabledBundleNames []string
+	ProjectSecretsName string
+	SecretsTargetCount int
+	DecCandidateCount  int
+	DecHasChanges      bool
+	DecSkippedReason   string
+	// DecConflicts 是以拉取版本为基准合并时会冲突的文件，推送会因此放弃。
+	DecConflicts []PushConflict
+	// DecChanges 是相对 vault 分支最新提交的逐文件改动（含 unified diff），按路径排序；
+	// Run 页据此勾选 bundle / 文件，再经 PushSelection 只提交选中的部分。
+	DecChanges          []VaultFileChange
 	BitwardenConfigured bool
+	// VaultBranch 是 Dec 资产将推送到的 vault 分支，空串表示远端默认分支。
+	VaultBranch string
+	// PushPolicy 为 branch 时推送会新建评审分支 dec/<user>/<project>/<timestamp>，VaultBranch 只作合并目标。
+	PushPolicy string
 }
 
 // PreviewPushProjectAssets 轻量检测 Push 将涉及的内容，供 TUI 确认页展示。
@@ -39,6 +49,14 @@ func PreviewPushWorkspaceAssets(workspace Workspace) (*PushProjectAssetsPreview,
 
 	preview.EnabledBundleNames = append([]string(nil), projectConfig.EnabledBundles...)
 	preview.EnabledBundleCount = len(preview.EnabledBundleNames)
+	preview.VaultBranch, err = resolveVaultBranch(projectConfig, nil, "push.preview")
+	if err != nil {
+		return nil, err
+	}
+	preview.PushPolicy, err = resolvePushPolicy(nil, "push.preview")
+	if err != nil {
+		return nil, err
+	}
 
 	configured, err := secrets.IsConfigured()
 	if err != nil {
@@ -62,25 +80,38 @@ func PreviewPushWorkspaceAssets(workspace Workspace) (*PushProjectAssetsPreview,
 		}
 	}
 
-	candidate, hasChanges, skipped, decErr := previewDecPushChanges(context.Background(), workspace, projectConfig, nil)
+	dec, decErr := previewDecPushChanges(context.Background(), workspace, projectConfig, preview.VaultBranch, nil)
 	if decErr != nil {
 		preview.DecSkippedReason = decErr.Error()
 	} else {
-		preview.DecCandidateCount = candidate
-		preview.DecHasChanges = hasChanges
-		if skipped != "" {
-			preview.DecSkippedReason = skipped
+		preview.DecCandidateCount = dec.candidateCount
+		preview.DecHasChanges = dec.hasChanges
+		preview.DecConflicts = dec.conflicts
+		preview.DecChanges = dec.changes
+		if dec.skippedReason != "" {
+			preview.DecSkippedReason = dec.skippedReason
 		}
 	}
 	return preview, nil
 }
 
-func previewDecPushChanges(ctx context.Context, workspace Workspace, projectConfig *types.ProjectConfig, reporter Reporter) (candidateCount int, hasChanges bool, skippedReason string, err error) {
+// decPushPreview 是 Dec 推送预览阶段的结果。
+type decPushPreview struct {
+	candidateCount int
+	hasChanges     bool
+	skippedReason  string
+	conflicts      []PushConflict
+	changes        []VaultFileChange
+}
+
+func previewDecPushChanges(ctx context.Context, workspace Workspace, projectConfig *types.ProjectConfig, vaultBranch string, reporter Reporter) (decPushPreview, error) {
+	var out decPushPreview
 	if len(projectConfig.EnabledBundles) == 0 {
-		return 0, false, "无已启用 bundle", nil
+		out.skippedReason = "无已启用 bundle"
+		return out, nil
 	}
 
-	err = withAppWriteRepo(func(tx *repo.Transaction) error {
+	err := withAppWriteRepo(vaultBranch, func(tx *repo.Transaction) error {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
@@ -92,40 +123,43 @@ func previewDecPushChanges(ctx context.Context, workspace Workspace, projectConf
 
 		assets := resolved.Assets
 		if len(assets) == 0 && len(projectConfig.EnabledBundles) == 0 {
-			skippedReason = "没有可推送的有效资产"
+			out.skippedReason = "没有可推送的有效资产"
 			return nil
 		}
 
-		synced, pruned, syncErr := syncDecVaultFromCache(workspace, repoDir, projectConfig, resolved, reporter)
-		if syncErr != nil {
-			return syncErr
+		merge, mergeErr := mergeDecCacheIntoVault(ctx, workspace, repoDir, projectConfig, resolved, reporter)
+		if mergeErr != nil {
+			return mergeErr
 		}
-
-		for _, bundleName := range projectConfig.EnabledBundles {
-			if err := ctx.Err(); err != nil {
-				return err
-			}
-			ok, pushErr := pushBundleYAMLFromCache(workspace, repoDir, bundleName, reporter)
-			if pushErr != nil {
-				return pushErr
-			}
-			if ok {
-				synced++
-			}
+		// 冲突文件不会写入工作区，其余改动照常列出：选择性推送可以绕开冲突文件先推别的。
+		bundles := sortedBundleNames(projectConfig, assets)
+		head, headErr := openVaultHeadForDiff(tx, bundles)
+		if headErr != nil {
+			return headErr
+		}
+		defer head.Close()
+		changes, diffErr := collectVaultChanges(repoDir, head.WorkDir(), bundles)
+		if diffErr != nil {
+			return diffErr
+		}
+		out.changes = changes
+		if len(merge.conflicts) > 0 {
+			out.conflicts = merge.conflicts
+			out.skippedReason = fmt.Sprintf("%d 个文件与远端冲突", len(merge.conflicts))
+			return nil
 		}
 
-		git := repo.NewGitOps(repoDir)
-		clean, cleanErr := git.IsClean()
+		clean, cleanErr := tx.IsClean()
 		if cleanErr != nil {
 			return cleanErr
 		}
 		if clean {
-			skippedReason = "无本地变更"
+			out.skippedReason = "无本地变更"
 			return nil
 		}
-		candidateCount = synced + pruned
-		hasChanges = true
+		out.candidateCount = merge.synced + merge.pruned
+		out.hasChanges = true
 		return nil
 	})
-	return candidateCount, hasChanges, skippedReason, err
+	return out, err
 }
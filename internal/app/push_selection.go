@@ -0,0 +1,219 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/textdiff"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// PushSelection 限定一次 push 提交进 vault 的范围；Bundles 与 Files 都为空表示全部改动。
+// 两者取并集：Bundles 整包纳入，Files 按 vault 相对路径逐个纳入。
+type PushSelection struct {
+	// Bundles 是整包纳入的 bundle 短名。
+	Bundles []string `json:"bundles,omitempty"`
+	// Files 是单独纳入的 vault 相对路径（/ 分隔），取自预览的 VaultFileChange.Path。
+	Files []string `json:"files,omitempty"`
+}
+
+// IsEmpty 报告是否未做任何选择（即推送全部改动）。
+func (s PushSelection) IsEmpty() bool {
+	return len(s.Bundles) == 0 && len(s.Files) == 0
+}
+
+// includes 判断 vault 相对路径是否在选择范围内；空选择包含一切。
+func (s PushSelection) includes(rel string) bool {
+	if s.IsEmpty() {
+		return true
+	}
+	for _, file := range s.Files {
+		if path.Clean(strings.TrimSpace(file)) == rel {
+			return true
+		}
+	}
+	if name, _, ok := splitVaultBundlePath(rel); ok {
+		for _, bundleName := range s.Bundles {
+			if strings.TrimSpace(bundleName) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// VaultFileChange 是 vault 内一处文件改动：push 预览相对 vault 分支最新提交计算，
+// 资产历史则是两个版本之间的差异。
+type VaultFileChange struct {
+	Bundle string
+	// Path 是 vault 内的相对路径（/ 分隔），如 bundles/combo/rules/a.mdc
+	Path string
+	// Status 取值同 RenderedFile*：added | modified | deleted
+	Status string
+	// Diff 是 unified diff；二进制文件为空，Binary=true
+	Diff   string
+	Binary bool
+}
+
+// openVaultHeadForDiff 打开写事务当前 HEAD 的只读快照，只物化涉及的 bundle，作为改动列表的对照。
+func openVaultHeadForDiff(tx *repo.Transaction, bundles []string) (*repo.Transaction, error) {
+	head, err := repo.NewReadTransactionWith(repo.ReadOptions{
+		Ref:    tx.CommitHash(),
+		Local:  true,
+		Select: vaultReadSelection(bundles),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取 vault 当前版本失败: %w", err)
+	}
+	return head, nil
+}
+
+// collectVaultChanges 比较 repoDir 与 headDir 中 bundles 目录下的文件，列出新增 / 修改 / 删除。
+func collectVaultChanges(repoDir, headDir string, bundles []string) ([]VaultFileChange, error) {
+	var changes []VaultFileChange
+	for _, bundleName := range bundles {
+		rel := path.Join(types.VaultBundlesDir, bundleName)
+		mine, err := listAssetFiles(filepath.Join(repoDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		head, err := listAssetFiles(filepath.Join(headDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		names := make(map[string]struct{}, len(mine)+len(head))
+		for name := range mine {
+			names[name] = struct{}{}
+		}
+		for name := range head {
+			names[name] = struct{}{}
+		}
+		for name := range names {
+			after, afterOK, err := readOptionalFile(mine[name])
+			if err != nil {
+				return nil, err
+			}
+			before, beforeOK, err := readOptionalFile(head[name])
+			if err != nil {
+				return nil, err
+			}
+			if afterOK == beforeOK && bytes.Equal(before, after) {
+				continue
+			}
+			changes = append(changes, newVaultFileChange(bundleName, path.Join(rel, name), before, after, beforeOK, afterOK))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func newVaultFileChange(bundleName, rel string, before, after []byte, beforeOK, afterOK bool) VaultFileChange {
+	change := VaultFileChange{Bundle: bundleName, Path: rel}
+	oldName, newName := "a/"+rel, "b/"+rel
+	switch {
+	case !beforeOK:
+		change.Status = RenderedFileAdded
+		oldName = "/dev/null"
+	case !afterOK:
+		change.Status = RenderedFileDeleted
+		newName = "/dev/null"
+	default:
+		change.Status = RenderedFileModified
+	}
+	if bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0 {
+		change.Binary = true
+		return change
+	}
+	change.Diff = textdiff.Unified(oldName, newName, string(before), string(after))
+	return change
+}
+
+// restrictVaultChanges 把选择范围外的改动在 repoDir 中恢复为 headDir 的版本，返回保留下来的改动。
+func restrictVaultChanges(repoDir, headDir string, changes []VaultFileChange, selection PushSelection) ([]VaultFileChange, error) {
+	var kept []VaultFileChange
+	for _, change := range changes {
+		if selection.includes(change.Path) {
+			kept = append(kept, change)
+			continue
+		}
+		target := filepath.Join(repoDir, filepath.FromSlash(change.Path))
+		if change.Status == RenderedFileAdded {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("撤回未选择的 %s 失败: %w", change.Path, err)
+			}
+			continue
+		}
+		source := filepath.Join(headDir, filepath.FromSlash(change.Path))
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("撤回未选择的 %s 失败: %w", change.Path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, data, assetFileMode(source)); err != nil {
+			return nil, fmt.Errorf("撤回未选择的 %s 失败: %w", change.Path, err)
+		}
+	}
+	return kept, nil
+}
+
+// selectionMisses 列出选择里没有对应改动的条目，提示调用方选择可能已过期。
+func selectionMisses(selection PushSelection, changes []VaultFileChange) []string {
+	var misses []string
+	for _, bundleName := range selection.Bundles {
+		bundleName = strings.TrimSpace(bundleName)
+		found := false
+		for _, change := range changes {
+			if change.Bundle == bundleName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			misses = append(misses, "bundle "+bundleName)
+		}
+	}
+	for _, file := range selection.Files {
+		file = path.Clean(strings.TrimSpace(file))
+		found := false
+		for _, change := range changes {
+			if change.Path == file {
+				found = true
+				break
+			}
+		}
+		if !found {
+			misses = append(misses, file)
+		}
+	}
+	return misses
+}
+
+// filterSelectedConflicts 只保留选择范围内的冲突：范围外的冲突文件本就不会提交，不阻断推送。
+func filterSelectedConflicts(conflicts []PushConflict, selection PushSelection) []PushConflict {
+	var kept []PushConflict
+	for _, conflict := range conflicts {
+		if selection.includes(conflict.Path) {
+			kept = append(kept, conflict)
+		}
+	}
+	return kept
+}
+
+// sortedBundleNames 返回 collectEnabledBundleNames 的有序列表。
+func sortedBundleNames(projectConfig *types.ProjectConfig, assets []types.TypedAssetRef) []string {
+	set := collectEnabledBundleNames(projectConfig, assets)
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
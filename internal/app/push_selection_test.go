@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestPushWorkspaceSelectionCommitsOnlySelectedFiles(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/alpha.mdc": "---\ndescription: alpha\n---\nalpha v1\n",
+		"bundles/combo/rules/beta.mdc":  "---\ndescription: beta\n---\nbeta v1\n",
+		"bundles/combo/bundle.yaml":     "name: combo\nmembers:\n  - rule/alpha\n  - rule/beta\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"combo"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	if _, err := PullProjectAssets(context.Background(), projectRoot, "", nil); err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	writeFileProjectTest(t, getWorkspaceCachePath(workspace, "combo", "rule", "alpha"), "---\ndescription: alpha\n---\nalpha v2\n")
+	writeFileProjectTest(t, getWorkspaceCachePath(workspace, "combo", "rule", "beta"), "---\ndescription: beta\n---\nbeta v2\n")
+
+	preview, err := PreviewPushWorkspaceAssets(workspace)
+	if err != nil {
+		t.Fatalf("PreviewPushWorkspaceAssets() 失败: %v", err)
+	}
+	if len(preview.DecChanges) != 2 {
+		t.Fatalf("预览应列出 2 个文件改动，得到 %+v", preview.DecChanges)
+	}
+	alpha := preview.DecChanges[0]
+	if alpha.Bundle != "combo" || alpha.Path != "bundles/combo/rules/alpha.mdc" || alpha.Status != RenderedFileModified {
+		t.Fatalf("alpha 改动 = %+v", alpha)
+	}
+	if !strings.Contains(alpha.Diff, "-alpha v1") || !strings.Contains(alpha.Diff, "+alpha v2") {
+		t.Fatalf("alpha diff 不完整: %q", alpha.Diff)
+	}
+
+	result, err := PushWorkspaceWith(context.Background(), workspace, PushOptions{
+		Selection: PushSelection{Files: []string{alpha.Path}},
+		Message:   "alpha 改为 v2",
+	}, nil)
+	if err != nil {
+		t.Fatalf("PushWorkspaceWith() 失败: %v", err)
+	}
+	if !result.DecSelected || result.DecPushedCount != 1 {
+		t.Fatalf("按选择推送结果 = %+v", result)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/rules/alpha.mdc"); !strings.Contains(got, "alpha v2") {
+		t.Fatalf("选中的 alpha 应已推送: %q", got)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/rules/beta.mdc"); !strings.Contains(got, "beta v1") {
+		t.Fatalf("未选中的 beta 不应推送: %q", got)
+	}
+	logMessage := runGitNoDirProjectTest(t, "--git-dir", remote, "log", "-1", "--format=%B", "main")
+	for _, want := range []string{"alpha 改为 v2", "- combo: rule/alpha", "Dec-Host: ", "Dec-Version: "} {
+		if !strings.Contains(logMessage, want) {
+			t.Fatalf("提交说明缺少 %q:\n%s", want, logMessage)
+		}
+	}
+	if strings.Contains(logMessage, "rule/beta") || result.DecCommitMessage == "" {
+		t.Fatalf("提交说明只应列出选中的资产:\n%s", logMessage)
+	}
+
+	// 剩下的 beta 仍是待推送改动。
+	preview, err = PreviewPushWorkspaceAssets(workspace)
+	if err != nil {
+		t.Fatalf("PreviewPushWorkspaceAssets() 失败: %v", err)
+	}
+	if len(preview.DecChanges) != 1 || preview.DecChanges[0].Path != "bundles/combo/rules/beta.mdc" {
+		t.Fatalf("推送后剩余改动 = %+v", preview.DecChanges)
+	}
+}
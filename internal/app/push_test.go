@@ -143,3 +143,121 @@ members:
 		t.Fatalf("DecSkippedReason = %q, want 无本地变更", result.DecSkippedReason)
 	}
 }
+
+// setupPulledProjectForMerge 连接远端、在 combo 上拉取一次，并返回一个可以模拟队友提交的 seed 克隆。
+func setupPulledProjectForMerge(t *testing.T, rule string) (projectRoot, remote, seed string) {
+	t.Helper()
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote = setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/team.mdc": rule,
+		"bundles/combo/bundle.yaml":    "name: combo\nmembers:\n  - rule/team\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot = t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"combo"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PullProjectAssets(context.Background(), projectRoot, "", nil); err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	seed = filepath.Join(t.TempDir(), "seed")
+	runGitNoDirProjectTest(t, "clone", remote, seed)
+	configureGitUserProjectTest(t, seed)
+	return projectRoot, remote, seed
+}
+
+func TestPushProjectAssets_MergesOntoNewerVaultChanges(t *testing.T) {
+	base := "---\ndescription: team\n---\nline 1\nline 2\nline 3\nline 4\nline 5\n"
+	projectRoot, remote, seed := setupPulledProjectForMerge(t, base)
+
+	// 队友在拉取之后改了第 1 行，并新增了一条规则
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/rules/team.mdc"), strings.Replace(base, "line 1", "line 1 (teammate)", 1))
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/rules/extra.mdc"), "---\ndescription: extra\n---\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "teammate")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+
+	cachePath := getCachePath(projectRoot, "combo", "rule", "team")
+	writeFileProjectTest(t, cachePath, strings.Replace(base, "line 5", "line 5 (mine)", 1))
+
+	result, err := PushProjectAssets(context.Background(), projectRoot, nil)
+	if err != nil {
+		t.Fatalf("PushProjectAssets() 失败: %v", err)
+	}
+	if len(result.DecConflicts) != 0 || result.DecPushedCount != 1 || result.DecMergeBase == "" {
+		t.Fatalf("应无冲突地合并推送: %+v", result)
+	}
+	got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/rules/team.mdc")
+	if !strings.Contains(got, "line 1 (teammate)") || !strings.Contains(got, "line 5 (mine)") {
+		t.Fatalf("远端应同时保留两边的改动: %q", got)
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatal(err)
+	}
+	if out := runGitNoDirProjectTest(t, "--git-dir", remote, "ls-tree", "--name-only", "main", "bundles/combo/rules/"); !strings.Contains(out, "extra.mdc") {
+		t.Fatalf("队友新增的规则不应被本地缓存删除: %q", out)
+	}
+}
+
+func TestPushProjectAssets_ReportsConflictsInsteadOfOverwriting(t *testing.T) {
+	base := "---\ndescription: team\n---\nshared line\n"
+	projectRoot, remote, seed := setupPulledProjectForMerge(t, base)
+
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/rules/team.mdc"), strings.Replace(base, "shared line", "teammate line", 1))
+	runGitProjectTest(t, seed, "commit", "-am", "teammate")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+	remoteHead := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main")
+
+	writeFileProjectTest(t, getCachePath(projectRoot, "combo", "rule", "team"), strings.Replace(base, "shared line", "my line", 1))
+
+	var events []OperationEvent
+	result, err := PushProjectAssets(context.Background(), projectRoot, captureEvents(&events))
+	if err != nil {
+		t.Fatalf("PushProjectAssets() 失败: %v", err)
+	}
+	if len(result.DecConflicts) != 1 || result.DecConflicts[0].Path != "bundles/combo/rules/team.mdc" {
+		t.Fatalf("DecConflicts = %+v", result.DecConflicts)
+	}
+	if result.DecPushedCount != 0 || !strings.Contains(result.DecSkippedReason, "冲突") {
+		t.Fatalf("冲突时不应推送: %+v", result)
+	}
+	if head := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main"); head != remoteHead {
+		t.Fatalf("冲突时远端不应变化: %s → %s", remoteHead, head)
+	}
+	if !containsScopeMessage(events, "push.dec", "team.mdc") {
+		t.Fatalf("应逐文件报告冲突: %#v", events)
+	}
+
+	preview, err := PreviewPushProjectAssets(projectRoot)
+	if err != nil {
+		t.Fatalf("PreviewPushProjectAssets() 失败: %v", err)
+	}
+	if len(preview.DecConflicts) != 1 || preview.DecHasChanges {
+		t.Fatalf("预览应提前列出冲突: %+v", preview)
+	}
+}
+
+func TestPushProjectAssets_KeepsUpstreamDeletedBundleManifestDeleted(t *testing.T) {
+	base := "---\ndescription: team\n---\nline 1\n"
+	projectRoot, remote, seed := setupPulledProjectForMerge(t, base)
+	// 缓存里的 bundle 声明与拉取时的 vault 内容一致
+	writeFileProjectTest(t, filepath.Join(workspaceCacheDir(NewWorkspace(WorkspaceProject, projectRoot)), "combo", "bundle.yaml"), "name: combo\nmembers:\n  - rule/team\n")
+
+	// 队友在拉取之后删除了整个 bundle，本地声明未改动
+	runGitProjectTest(t, seed, "rm", "-r", "-q", "bundles/combo")
+	runGitProjectTest(t, seed, "commit", "-m", "drop combo")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+
+	if _, err := PushProjectAssets(context.Background(), projectRoot, nil); err != nil {
+		t.Fatalf("PushProjectAssets() 失败: %v", err)
+	}
+	if tree := runGitNoDirProjectTest(t, "--git-dir", remote, "ls-tree", "-r", "--name-only", "main"); strings.Contains(tree, "bundles/combo/bundle.yaml") {
+		t.Fatalf("远端已删除的 bundle 不应被推送成空声明: %q", tree)
+	}
+}
@@ -32,7 +32,8 @@ func ListRemoteInventory(ctx context.Context, workspace Workspace, includeRemote
 	seenDecRemote := make(map[string]struct{})
 	seenDecLocal := make(map[string]struct{})
 	groupCtx := newDeleteGroupContext(workspace, projectConfig)
-	scopeByBundle := resolveVaultScopeTags(reporter)
+	vaultBranch := emitLocalReadVaultBranch(projectConfig, reporter, "delete.list")
+	scopeByBundle := resolveVaultScopeTags(vaultBranch, reporter)
 	enabledBundles := config.NormalizeBundleNames(projectConfig.EnabledBundles)
 
 	addDec := func(kind DeleteItemKind, itemType, name, vault string, orphan bool, partition RemotePartition, scopeTag string) {
@@ -127,7 +128,7 @@ func ListRemoteInventory(ctx context.Context, workspace Workspace, includeRemote
 	}
 
 	// 远端分区：Git vault 全量 bundles（scope 仅作分组标签，enabled 与否都展示）。
-	_ = withAppReadRepo(func(tx *repo.Transaction) error {
+	_ = withAppReadRepo(vaultBranch, func(tx *repo.Transaction) error {
 		repoDir := tx.WorkDir()
 		vaultBundles, _, scanErr := scanVaultBundles(repoDir, reporter)
 		if scanErr != nil {
@@ -386,9 +387,9 @@ func (g *deleteGroupContext) groupTitleWithScope(groupBundle, scopeTag string) s
 }
 
 // resolveVaultScopeTags 返回 vault 内全部 bundle 的 scope 标签（不按平面过滤）。
-func resolveVaultScopeTags(reporter Reporter) map[string]string {
+func resolveVaultScopeTags(branch string, reporter Reporter) map[string]string {
 	out := make(map[string]string)
-	_ = withAppReadRepo(func(tx *repo.Transaction) error {
+	_ = withAppReadRepo(branch, func(tx *repo.Transaction) error {
 		vaultBundles, _, scanErr := scanVaultBundles(tx.WorkDir(), reporter)
 		if scanErr != nil {
 			return nil
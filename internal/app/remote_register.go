@@ -278,7 +278,7 @@ func resolveRemoteRegisterTarget(workspace Workspace, folder string, ensureBundl
 		}
 		plane := workspace.EffectivePlane()
 		if ensureBundle {
-			resolvedPlane, err := ensureRemoteBundleManifest(name, plane, reporter)
+			resolvedPlane, err := ensureRemoteBundleManifest(workspace, name, reporter)
 			if err != nil {
 				return secrets.SyncTarget{}, err
 			}
@@ -306,14 +306,14 @@ func validateRemoteOwnerName(kind, name string) error {
 	return nil
 }
 
-func ensureRemoteBundleManifest(name string, fallbackPlane WorkspacePlane, reporter Reporter) (WorkspacePlane, error) {
+func ensureRemoteBundleManifest(workspace Workspace, name string, reporter Reporter) (WorkspacePlane, error) {
 	reporter = defaultReporter(reporter)
-	resolvedPlane := fallbackPlane
+	resolvedPlane := workspace.EffectivePlane()
 	if resolvedPlane != WorkspaceUser {
 		resolvedPlane = WorkspaceProject
 	}
 	created := false
-	err := withAppWriteRepo(func(tx *repo.Transaction) error {
+	err := withWorkspaceWriteRepo(workspace, reporter, "remote.register", func(tx *repo.Transaction, commit vaultCommitFunc) error {
 		manifestRel := types.VaultBundleManifestPath(name)
 		manifestAbs := filepath.Join(tx.WorkDir(), filepath.FromSlash(manifestRel))
 		data, readErr := os.ReadFile(manifestAbs)
@@ -353,7 +353,7 @@ func ensureRemoteBundleManifest(name string, fallbackPlane WorkspacePlane, repor
 		if err := os.WriteFile(manifestAbs, append([]byte(header), body...), 0o644); err != nil {
 			return fmt.Errorf("写入 %s 失败: %w", manifestRel, err)
 		}
-		if _, err := tx.CommitAndPush("chore(bundles): add remote registration placeholder " + name); err != nil {
+		if _, err := commit("chore(bundles): add remote registration placeholder " + name); err != nil {
 			return fmt.Errorf("推送 bundle %q 占位失败: %w", name, err)
 		}
 		created = true
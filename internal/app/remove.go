@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/shichao402/Dec/internal/bundle"
 	"github.com/shichao402/Dec/internal/config"
@@ -97,7 +98,7 @@ func RemoveBundle(input RemoveBundleInput, reporter Reporter) (*RemoveBundleResu
 
 	// Stage 1: 远端删除整包 + 摘掉 projects/*.yaml 引用（同一次 commit）。
 	emit(reporter, EventInfo, "remove.repo", "连接资产仓库...", nil)
-	if err := withAppWriteRepo(func(tx *repo.Transaction) error {
+	if err := withWorkspaceWriteRepo(workspace, reporter, "remove.repo", func(tx *repo.Transaction, commit vaultCommitFunc) error {
 		repoDir := tx.WorkDir()
 		bundlePath := filepath.Join(repoDir, types.VaultBundlesDir, bundleName)
 		if _, err := os.Stat(bundlePath); err != nil {
@@ -122,7 +123,7 @@ func RemoveBundle(input RemoveBundleInput, reporter Reporter) (*RemoveBundleResu
 				fmt.Sprintf("已从 projects 声明移除: %s", strings.Join(pruned, ", ")), nil)
 		}
 		commitMsg := fmt.Sprintf("remove bundle: %s", bundleName)
-		if _, err := tx.CommitAndPush(commitMsg); err != nil {
+		if _, err := commit(commitMsg); err != nil {
 			return fmt.Errorf("提交失败: %w", err)
 		}
 		result.VersionCommit = tx.CommitHash()
@@ -149,6 +150,7 @@ func RemoveBundle(input RemoveBundleInput, reporter Reporter) (*RemoveBundleResu
 				removedIDEs[ideImpl.Name()] = struct{}{}
 			}
 		}
+		removeStagedAsset(workspace, member.Type, member.Name)
 	}
 	if len(removedIDEs) > 0 {
 		ideNames := make([]string, 0, len(removedIDEs))
@@ -281,7 +283,7 @@ func RemoveAsset(input RemoveAssetInput, reporter Reporter) (*RemoveAssetResult,
 
 	// Stage 1: 远端删除（最关键，失败直接返回错误）。
 	emit(reporter, EventInfo, "remove.repo", "连接资产仓库...", nil)
-	if err := withAppWriteRepo(func(tx *repo.Transaction) error {
+	if err := withWorkspaceWriteRepo(workspace, reporter, "remove.repo", func(tx *repo.Transaction, commit vaultCommitFunc) error {
 		repoDir := tx.WorkDir()
 
 		foundVault, fullPath, err := locateAssetInRepo(repoDir, itemType, assetName, vaultHint)
@@ -311,7 +313,7 @@ func RemoveAsset(input RemoveAssetInput, reporter Reporter) (*RemoveAssetResult,
 		}
 
 		commitMsg := fmt.Sprintf("remove: %s/%s", foundVault, assetName)
-		if _, err := tx.CommitAndPush(commitMsg); err != nil {
+		if _, err := commit(commitMsg); err != nil {
 			return fmt.Errorf("提交失败: %w", err)
 		}
 
@@ -335,6 +337,7 @@ func RemoveAsset(input RemoveAssetInput, reporter Reporter) (*RemoveAssetResult,
 			result.RemovedFromIDEs = append(result.RemovedFromIDEs, ideImpl.Name())
 		}
 	}
+	removeStagedAsset(workspace, itemType, assetName)
 	if len(result.RemovedFromIDEs) > 0 {
 		emit(reporter, EventInfo, "remove.ide", fmt.Sprintf("🧹 已清理 IDE: %s", strings.Join(result.RemovedFromIDEs, ", ")), nil)
 	}
@@ -355,7 +358,7 @@ func RemoveAsset(input RemoveAssetInput, reporter Reporter) (*RemoveAssetResult,
 
 	// 远端 bundle 已空时，收敛清理本机登记，避免 known / enabled / secrets 残留被 push 写回。
 	if result.Vault != "" {
-		_ = withAppReadRepo(func(tx *repo.Transaction) error {
+		_ = withAppReadRepo(workspaceLocalReadVaultBranch(workspace, reporter, "remove.cleanup"), func(tx *repo.Transaction) error {
 			bundlePath := filepath.Join(tx.WorkDir(), types.VaultBundlesDir, result.Vault)
 			if _, err := os.Stat(bundlePath); os.IsNotExist(err) {
 				cleanupDeletedBundleLocalState(workspace, result.Vault, reporter)
@@ -410,22 +413,75 @@ func locateAssetInRepo(repoDir, itemType, assetName, vaultHint string) (string,
 }
 
 // withAppWriteRepo 等价于 cmd/vault.go 中 withWriteRepo 的实现，但位于 internal/app 包内，
-// 避免用例层反向依赖 cmd 包。
-func withAppWriteRepo(fn func(*repo.Transaction) error) error {
+// 避免用例层反向依赖 cmd 包。branch 为空时跟随远端默认分支。
+func withAppWriteRepo(branch string, fn func(*repo.Transaction) error) error {
 	if globalConfig, err := config.LoadGlobalConfig(); err == nil {
 		if err := repo.EnsureConnectedRepoMatches(globalConfig.RepoURL); err != nil {
 			return err
 		}
 	}
 
-	tx, err := repo.NewWriteTransaction()
+	tx, err := repo.NewWriteTransactionOn(branch)
 	if err != nil {
 		return err
 	}
 	defer tx.Close()
+	if err := ensureVaultWritable(tx.WorkDir()); err != nil {
+		return err
+	}
 	return fn(tx)
 }
 
+// vaultCommitFunc 按当前平面的 push_policy 提交并推送事务里的改动。
+type vaultCommitFunc func(message string) (committed bool, err error)
+
+// withWorkspaceWriteRepo 在工作区跟随的 vault 分支上开可写事务，
+// fn 拿到的 commit 与 push 一样遵守 push_policy：branch 策略下推到新的评审分支。
+func withWorkspaceWriteRepo(workspace Workspace, reporter Reporter, scope string, fn func(tx *repo.Transaction, commit vaultCommitFunc) error) error {
+	projectConfig, err := loadWorkspaceBundleConfig(workspace)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	branch, err := resolveVaultBranch(projectConfig, reporter, scope)
+	if err != nil {
+		return err
+	}
+	pushPolicy, err := resolvePushPolicy(reporter, scope)
+	if err != nil {
+		return err
+	}
+	return withAppWriteRepo(branch, func(tx *repo.Transaction) error {
+		commit := func(message string) (bool, error) {
+			target := tx.Branch()
+			reviewBranch := ""
+			if pushPolicy == types.PushPolicyBranch {
+				reviewBranch = reviewBranchName(workspace, projectConfig, time.Now())
+				target = reviewBranch
+			}
+			var committed bool
+			var err error
+			if reviewBranch != "" {
+				committed, err = tx.CommitAndPushToBranch(message, reviewBranch)
+			} else {
+				committed, err = tx.CommitAndPush(message)
+			}
+			if err != nil || !committed {
+				return committed, err
+			}
+			if tx.Queued() {
+				emit(reporter, EventWarn, scope, fmt.Sprintf("离线：提交已加入本地队列，下次在线 push 时发送到 %s", target), nil)
+				return true, nil
+			}
+			discardSupersededQueue(target, tx.CommitHash(), reporter, scope)
+			if reviewBranch != "" {
+				emit(reporter, EventInfo, scope, fmt.Sprintf("已推送到评审分支 %s，合并到 %s 后生效", reviewBranch, tx.Branch()), nil)
+			}
+			return true, nil
+		}
+		return fn(tx, commit)
+	})
+}
+
 // resolveProjectIDEs 解析当前项目可用的 IDE 列表用于资产清理。
 func resolveProjectIDEs(projectRoot string, reporter Reporter) []ide.IDE {
 	return resolveWorkspaceIDEs(NewWorkspace(WorkspaceProject, projectRoot), reporter)
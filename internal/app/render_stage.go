@@ -0,0 +1,313 @@
+package app
+
+// 渲染暂存区：pull 先把资产按 IDE 逐份渲染到 .dec/staging-* 并校验，校验通过后才写入 IDE 目录。
+// 渲染或校验失败的资产不会在 IDE 目录留下半成品；dry-run 只比较暂存结果与现有文件，不落地。
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/ide"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+	"github.com/shichao402/Dec/internal/vars"
+	"gopkg.in/yaml.v3"
+)
+
+// renderVars 是一次渲染用到的变量来源：本地 vars 文件只读一次，供本轮全部资产复用。
+type renderVars struct {
+	// Project / Profile 只在项目平面存在；用户平面只有本机 vars、vault 共享层与内置变量
+	Project  *types.VarsConfig
+	Profile  map[string]string
+	Global   *types.VarsConfig
+	Shared   *vaultSharedVars
+	Builtins map[string]string
+	// ProjectVarsPath 为空表示没有项目级 vars（用户平面），缺失提示不再指向项目文件
+	ProjectVarsPath string
+	GlobalVarsPath  string
+}
+
+// loadRenderVars 读取工作空间平面可见的本地变量层。vars 文件损坏只产生告警：
+// 缺了变量最多是占位符原样保留，不应阻断整次 pull。
+func loadRenderVars(workspace Workspace, projectConfig *types.ProjectConfig, shared *vaultSharedVars) (*renderVars, []string) {
+	rv := &renderVars{Shared: shared}
+	var warnings []string
+
+	rv.GlobalVarsPath, _ = config.GetGlobalVarsPath()
+	if globalVars, err := config.LoadGlobalVars(); err != nil {
+		warnings = append(warnings, fmt.Sprintf("读取全局变量失败: %v", err))
+	} else {
+		rv.Global = globalVars
+	}
+
+	if workspace.EffectivePlane() != WorkspaceProject {
+		decHome, _ := repo.GetRootDir()
+		rv.Builtins = vars.BuiltinVars(vars.BuiltinContext{DecHome: decHome})
+		return rv, warnings
+	}
+
+	mgr := config.NewProjectConfigManager(workspace.Root)
+	rv.ProjectVarsPath = mgr.GetVarsPath()
+	rv.Builtins = projectBuiltinVars(workspace.Root)
+	projectVars, err := mgr.LoadVarsConfig()
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("解析 %s 失败: %v", rv.ProjectVarsPath, err))
+		return rv, warnings
+	}
+	rv.Project = projectVars
+
+	// profile 未定义只告警，与缺失占位符一样不阻断 pull
+	if profile := strings.TrimSpace(projectConfig.ActiveProfile); profile != "" {
+		if profileVars, ok := projectVars.ProfileVars(profile); ok {
+			rv.Profile = profileVars
+		} else {
+			warnings = append(warnings, fmt.Sprintf("active_profile %q 未在 %s 的 profiles 中定义，已忽略", profile, rv.ProjectVarsPath))
+		}
+	}
+	return rv, warnings
+}
+
+// layers 返回某个 bundle 内资产的完整变量层（DEC_BUNDLE / DEC_IDE 由渲染时逐份补上）。
+func (rv *renderVars) layers(bundleName string) vars.Layers {
+	layers := rv.Shared.layers(bundleName)
+	layers.Project = rv.Project
+	layers.Profile = rv.Profile
+	layers.Global = rv.Global
+	layers.Builtins = rv.Builtins
+	return layers
+}
+
+// pendingOutput 是资产在某个 IDE 的渲染结果，尚未落地。
+type pendingOutput struct {
+	IDE ide.IDE
+	// Path 是暂存区中的副本（skill / command 为目录，rule 为文件）；mcp 没有文件副本
+	Path string
+	// Dest 是落地位置；mcp 为该 IDE 的 MCP 配置文件
+	Dest string
+	// Server 是 mcp 渲染后的条目
+	Server *types.MCPServer
+}
+
+// pendingAsset 是一个已渲染、已校验、等待安装的资产。
+type pendingAsset struct {
+	Asset   types.TypedAssetRef
+	Outputs []pendingOutput
+}
+
+// renderStage 管理一次 pull（或 profile 重渲染）的暂存目录。
+type renderStage struct {
+	root           string
+	workspace      Workspace
+	ides           []ide.IDE
+	vars           *renderVars
+	enabledBundles []string
+}
+
+// newRenderStage 在 .dec 下建临时暂存目录：与 IDE 目录同盘，落地时可以直接 rename。
+func newRenderStage(workspace Workspace, ides []ide.IDE, rv *renderVars, enabledBundles []string) (*renderStage, error) {
+	base := filepath.Dir(workspaceCacheDir(workspace))
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return nil, fmt.Errorf("创建暂存目录失败: %w", err)
+	}
+	root, err := os.MkdirTemp(base, "staging-")
+	if err != nil {
+		return nil, fmt.Errorf("创建暂存目录失败: %w", err)
+	}
+	return &renderStage{root: root, workspace: workspace, ides: ides, vars: rv, enabledBundles: enabledBundles}, nil
+}
+
+func (s *renderStage) Close() {
+	_ = os.RemoveAll(s.root)
+}
+
+// render 把 srcPath 渲染成每个 IDE 的副本并校验。返回 error 时资产不应安装；
+// 缺失变量只告警（占位符原样保留），模板错误与渲染后格式损坏则视为失败。
+func (s *renderStage) render(asset types.TypedAssetRef, srcPath string, reporter Reporter) (*pendingAsset, error) {
+	pending := &pendingAsset{Asset: asset}
+	for _, ideImpl := range s.ides {
+		if !ide.SupportsAssetType(ideImpl, asset.Type) {
+			continue
+		}
+		stagePath := filepath.Join(s.root, ideImpl.Name(), typeSubDir(asset.Type), managedName(asset.Name))
+		if asset.Type == "rule" {
+			stagePath += ".mdc"
+		}
+		out, err := stageAssetOutput(asset.Type, asset.Name, asset.Vault, srcPath, stagePath, s.workspace, ideImpl)
+		if err != nil {
+			return nil, fmt.Errorf("渲染到 %s 失败: %w", ideImpl.Name(), err)
+		}
+		pending.Outputs = append(pending.Outputs, out)
+	}
+
+	tmpl := newAssetTemplate(s.workspace, s.vars.Shared, s.enabledBundles, asset.Vault, asset.Type, asset.Name)
+	if err := substituteAssetVars(pending, s.vars, tmpl, reporter); err != nil {
+		return nil, err
+	}
+	if err := validatePendingAsset(pending, srcPath); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// install 把已校验的副本写入各 IDE；任一 IDE 失败时回滚已写入的部分。
+func (s *renderStage) install(pending *pendingAsset) error {
+	asset := pending.Asset
+	installed := make([]ide.IDE, 0, len(pending.Outputs))
+	for _, out := range pending.Outputs {
+		if err := installPendingOutput(asset.Type, asset.Name, out, s.workspace); err != nil {
+			rollbackErrors := rollbackInstalledAsset(asset.Type, asset.Name, s.workspace, installed)
+			if len(rollbackErrors) > 0 {
+				return fmt.Errorf("安装到 %s 失败: %v；回滚失败: %s", out.IDE.Name(), err, strings.Join(rollbackErrors, "; "))
+			}
+			return fmt.Errorf("安装到 %s 失败: %w", out.IDE.Name(), err)
+		}
+		installed = append(installed, out.IDE)
+	}
+	return nil
+}
+
+// stageAssetOutput 生成资产在某个 IDE 的未替换副本：复制源文件并注入「勿编辑」注释，
+// mcp 则翻译成该 IDE 的条目。
+func stageAssetOutput(itemType, assetName, vaultName, srcPath, stagePath string, workspace Workspace, ideImpl ide.IDE) (pendingOutput, error) {
+	out := pendingOutput{IDE: ideImpl, Path: stagePath, Dest: ideAssetPath(itemType, assetName, workspace, ideImpl)}
+	switch itemType {
+	case "skill", "command":
+		if err := copyDir(srcPath, stagePath); err != nil {
+			return out, err
+		}
+		return out, injectRenderedHeaderDir(stagePath, vaultName)
+	case "rule":
+		if err := copyFile(srcPath, stagePath); err != nil {
+			return out, err
+		}
+		return out, injectRenderedHeaderFile(stagePath, vaultName)
+	case "mcp":
+		data, err := os.ReadFile(srcPath)
+		if err != nil {
+			return out, fmt.Errorf("读取 MCP 配置失败: %w", err)
+		}
+		var server types.MCPServer
+		if err := json.Unmarshal(data, &server); err != nil {
+			return out, fmt.Errorf("解析 MCP 配置失败: %w", err)
+		}
+		server, _, err = renderMCPServerForIDE(server, vaultName, workspace, ideImpl)
+		if err != nil {
+			return out, err
+		}
+		home, _ := os.UserHomeDir()
+		out.Path = ""
+		out.Dest = ideImpl.MCPConfigPathForPlane(workspace.IDEPlane(), workspace.Root, home)
+		out.Server = &server
+		return out, nil
+	default:
+		return out, fmt.Errorf("不支持的资产类型 %q", itemType)
+	}
+}
+
+// installPendingOutput 把一份副本写到 IDE。目录整体替换，上一版残留的文件不会留下。
+func installPendingOutput(itemType, assetName string, out pendingOutput, workspace Workspace) error {
+	switch itemType {
+	case "skill", "command", "rule":
+		if err := unlinkDecOwned(out.Dest, workspace); err != nil {
+			return err
+		}
+		return moveIntoPlace(out.Path, out.Dest)
+	case "mcp":
+		home, _ := os.UserHomeDir()
+		plane := workspace.IDEPlane()
+		existingConfig, err := out.IDE.LoadMCPConfigForPlane(plane, workspace.Root, home)
+		if err != nil {
+			return fmt.Errorf("加载 IDE MCP 配置失败: %w", err)
+		}
+		if existingConfig.MCPServers == nil {
+			existingConfig.MCPServers = make(map[string]types.MCPServer)
+		}
+		existingConfig.MCPServers[managedName(assetName)] = *out.Server
+		return out.IDE.WriteMCPConfigForPlane(plane, workspace.Root, home, existingConfig)
+	default:
+		return nil
+	}
+}
+
+// moveIntoPlace 用暂存副本替换 dest；跨设备无法 rename 时退回复制。
+func moveIntoPlace(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return copyDir(src, dest)
+	}
+	return copyFile(src, dest)
+}
+
+// validatePendingAsset 检查渲染没有破坏文件格式：源文件是合法 JSON 的，渲染后仍须合法；
+// 源文件 frontmatter 可解析的，渲染后仍须可解析。典型来源是变量值里带了引号或换行。
+func validatePendingAsset(pending *pendingAsset, srcPath string) error {
+	for _, out := range pending.Outputs {
+		if out.Path == "" {
+			continue
+		}
+		err := filepath.Walk(out.Path, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			source := srcPath
+			if rel, err := filepath.Rel(out.Path, path); err == nil && rel != "." {
+				source = filepath.Join(srcPath, rel)
+			}
+			original, err := os.ReadFile(source)
+			if err != nil {
+				return nil
+			}
+			rendered, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if problem := renderedFormatProblem(path, string(original), string(rendered)); problem != "" {
+				rel, _ := filepath.Rel(out.Path, path)
+				return fmt.Errorf("%s 渲染结果校验失败 (%s): %s", filepath.ToSlash(filepath.Join(managedName(pending.Asset.Name), rel)), out.IDE.Name(), problem)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderedFormatProblem(path, original, rendered string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if json.Valid([]byte(original)) && !json.Valid([]byte(rendered)) {
+			return "不再是合法 JSON"
+		}
+		return ""
+	}
+	_, body := splitRenderedHeader(rendered)
+	block, ok := vars.Frontmatter(original)
+	if !ok || yaml.Unmarshal([]byte(block), &map[string]any{}) != nil {
+		return ""
+	}
+	block, ok = vars.Frontmatter(body)
+	if !ok {
+		return "frontmatter 丢失"
+	}
+	if err := yaml.Unmarshal([]byte(block), &map[string]any{}); err != nil {
+		return fmt.Sprintf("frontmatter 不再是合法 YAML: %v", err)
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// 变量值里的引号让 frontmatter 不再是合法 YAML：资产整个跳过，IDE 中保留上一次安装的版本。
+func TestPullKeepsInstalledVersionWhenRenderValidationFails(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/team/rules/meta.mdc": "---\ndescription: \"{{DESC}}\"\n---\nbody\n",
+		"bundles/team/rules/ok.mdc":   "ok={{DESC}}\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+
+	projectRoot := t.TempDir()
+	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"team"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	writeFile(t, mgr.GetVarsPath(), "vars:\n  DESC: 'say \"hi\"'\n")
+	installed := filepath.Join(projectRoot, ".cursor", "rules", "dec-meta.mdc")
+	writeFile(t, installed, "previous\n")
+
+	var warnings []string
+	reporter := ReporterFunc(func(event OperationEvent) {
+		if event.Level == EventWarn {
+			warnings = append(warnings, event.Message)
+		}
+	})
+	result, err := PullProjectAssets(context.Background(), projectRoot, "", reporter)
+	if err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	if result.FailedCount != 1 || result.PulledCount != 1 {
+		t.Fatalf("PulledCount/FailedCount = %d/%d, 期望 1/1", result.PulledCount, result.FailedCount)
+	}
+	if data, _ := os.ReadFile(installed); string(data) != "previous\n" {
+		t.Fatalf("校验失败时不应改动已安装文件, got %q", data)
+	}
+	if !strings.Contains(strings.Join(warnings, "\n"), "frontmatter") {
+		t.Fatalf("应说明校验失败原因, warnings = %v", warnings)
+	}
+	if data, _ := os.ReadFile(filepath.Join(projectRoot, ".cursor", "rules", "dec-ok.mdc")); !strings.HasSuffix(string(data), "ok=say \"hi\"\n") {
+		t.Fatalf("其它资产应照常安装, got %q", data)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(projectRoot, ".dec", "staging-*")); len(matches) != 0 {
+		t.Fatalf("暂存目录应在 pull 结束后清理: %v", matches)
+	}
+}
+
+// 用户平面同样做 vars 替换，取值来自本机 vars。
+func TestPullUserPlaneSubstitutesMachineVars(t *testing.T) {
+	decHome := t.TempDir()
+	home := t.TempDir()
+	setEnvForProjectTest(t, "DEC_HOME", decHome)
+	setEnvForProjectTest(t, "HOME", home)
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/tools/bundle.yaml":       "name: tools\nscope: user\nmembers:\n  - rules/editor\n",
+		"bundles/tools/rules/editor.mdc":  "editor={{EDITOR_CMD}} home={{DEC_HOME}}\n",
+		"bundles/tools/vars.yaml":         "vars:\n  EDITOR_CMD: vi\n",
+		"bundles/tools/rules/ignored.mdc": "unused\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	if err := config.SaveGlobalConfig(&types.GlobalConfig{
+		RepoURL:        remote,
+		EnabledBundles: []string{"tools"},
+		IDEs:           []string{"cursor"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	globalVarsPath, _ := config.GetGlobalVarsPath()
+	writeFile(t, globalVarsPath, "vars:\n  EDITOR_CMD: nvim\n")
+
+	if _, err := PullWorkspaceAssets(context.Background(), NewWorkspace(WorkspaceUser, ""), "", nil); err != nil {
+		t.Fatalf("PullWorkspaceAssets(user) 失败: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".cursor", "rules", "dec-editor.mdc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "editor=nvim home=" + decHome + "\n"
+	if !strings.HasSuffix(string(data), want) {
+		t.Fatalf("用户平面渲染结果 = %q, 期望以 %q 结尾", data, want)
+	}
+}
+
+// dry-run 返回每个 IDE 文件的 diff（新增 / 修改 / 孤儿删除），且不写任何文件。
+func TestPreviewPullWorkspaceAssetsReturnsDiffsWithoutWriting(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/team/rules/style.mdc":         "line1\nregion={{REGION}}\n",
+		"bundles/team/skills/deploy/SKILL.md":  "---\nname: deploy\n---\nrun\n",
+		"bundles/team/mcp/tracker.json":        `{"command":"tracker","args":["--region","{{REGION}}"]}`,
+		"bundles/other/rules/unrelated.mdc":    "x\n",
+		"bundles/team/skills/deploy/notes.txt": "notes\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+
+	projectRoot := t.TempDir()
+	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"team"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	writeFile(t, mgr.GetVarsPath(), "vars:\n  REGION: eu\n")
+	stylePath := filepath.Join(projectRoot, ".cursor", "rules", "dec-style.mdc")
+	writeFile(t, stylePath, "line1\nregion=us\n")
+	// cache 中有、目标集里已没有的资产：pull 会清掉它的 IDE 副本
+	writeFile(t, filepath.Join(projectRoot, ".dec", "cache", "old", "rules", "gone.mdc"), "gone\n")
+	gonePath := filepath.Join(projectRoot, ".cursor", "rules", "dec-gone.mdc")
+	writeFile(t, gonePath, "gone\n")
+
+	preview, err := PreviewPullWorkspaceAssets(context.Background(), NewWorkspace(WorkspaceProject, projectRoot), nil)
+	if err != nil {
+		t.Fatalf("PreviewPullWorkspaceAssets() 失败: %v", err)
+	}
+
+	byPath := make(map[string]RenderedFileDiff, len(preview.Files))
+	for _, file := range preview.Files {
+		byPath[file.Path] = file
+	}
+	style := byPath[".cursor/rules/dec-style.mdc"]
+	if style.Status != RenderedFileModified || !strings.Contains(style.Diff, "-region=us\n+region=eu\n") {
+		t.Fatalf("style diff = %#v", style)
+	}
+	if skill := byPath[".cursor/skills/dec-deploy/notes.txt"]; skill.Status != RenderedFileAdded || !strings.Contains(skill.Diff, "+notes\n") {
+		t.Fatalf("skill 新文件 diff = %#v", skill)
+	}
+	if mcp := byPath[".cursor/mcp.json#mcpServers.dec-tracker"]; mcp.Status != RenderedFileAdded || !strings.Contains(mcp.Diff, `"eu"`) {
+		t.Fatalf("mcp 条目 diff = %#v, files = %v", mcp, preview.Files)
+	}
+	if gone := byPath[".cursor/rules/dec-gone.mdc"]; gone.Status != RenderedFileDeleted {
+		t.Fatalf("孤儿资产应列为删除, got %#v", gone)
+	}
+
+	if data, _ := os.ReadFile(stylePath); string(data) != "line1\nregion=us\n" {
+		t.Fatalf("dry-run 不应改动 IDE 文件, got %q", data)
+	}
+	for _, path := range []string{
+		gonePath,
+		filepath.Join(projectRoot, ".dec", "cache", "old", "rules", "gone.mdc"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("dry-run 不应删除 %s: %v", path, err)
+		}
+	}
+	for _, path := range []string{
+		filepath.Join(projectRoot, ".cursor", "skills", "dec-deploy"),
+		filepath.Join(projectRoot, ".cursor", "mcp.json"),
+		filepath.Join(projectRoot, ".dec", "cache", "team"),
+		filepath.Join(projectRoot, ".dec", ".version"),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("dry-run 不应写入 %s, stat err = %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// reviewBranchTimeLayout 是评审分支名里的时间戳格式（UTC），字典序即时间序。
+const reviewBranchTimeLayout = "20060102-150405"
+
+// resolvePushPolicy 读取当前 vault 的推送方式，配置告警以 scope 事件抛出。
+func resolvePushPolicy(reporter Reporter, scope string) (string, error) {
+	resolved, err := config.ResolvePushPolicy()
+	if err != nil {
+		return "", fmt.Errorf("解析推送方式失败: %w", err)
+	}
+	for _, warning := range resolved.Warnings {
+		emit(reporter, EventWarn, scope, warning, nil)
+	}
+	return resolved.Policy, nil
+}
+
+// reviewBranchName 生成 branch 策略下的评审分支名 dec/<user>/<project>/<timestamp>-<suffix>。
+// 时间戳只到秒，同一秒内的两次推送靠随机后缀区分，避免推到同一个分支上。
+func reviewBranchName(workspace Workspace, projectConfig *types.ProjectConfig, now time.Time) string {
+	return types.ReviewBranchPrefix + branchSegment(reviewBranchUser()) + "/" +
+		branchSegment(workspaceProjectName(workspace, projectConfig)) + "/" +
+		now.UTC().Format(reviewBranchTimeLayout) + "-" + reviewBranchSuffix()
+}
+
+// reviewBranchSuffix 返回评审分支名末尾的随机后缀（6 位十六进制）。
+func reviewBranchSuffix() string {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%06x", time.Now().UnixNano()&0xffffff)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// workspaceProjectName 是评审分支里的项目段；用户平面固定为 user，与 overview 一致。
+func workspaceProjectName(workspace Workspace, projectConfig *types.ProjectConfig) string {
+	if workspace.EffectivePlane() == WorkspaceUser {
+		return "user"
+	}
+	name, _ := ResolveProjectName(workspace.Root, projectConfig)
+	return name
+}
+
+// reviewBranchUser 取本机登录名；取不到时回退环境变量，最后是 unknown。
+func reviewBranchUser() string {
+	if current, err := user.Current(); err == nil && strings.TrimSpace(current.Username) != "" {
+		return current.Username
+	}
+	for _, key := range []string{"USER", "USERNAME"} {
+		if name := strings.TrimSpace(os.Getenv(key)); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// branchSegment 把任意名字收敛成分支名里的一段：小写字母数字与 . _ -，其余字符换成 -。
+func branchSegment(raw string) string {
+	// Windows 登录名形如 DOMAIN\user，只保留用户部分。
+	if idx := strings.LastIndexAny(raw, `\/`); idx >= 0 {
+		raw = raw[idx+1:]
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(raw)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		case r == '.' && b.Len() > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	segment := strings.Trim(b.String(), "-.")
+	for strings.Contains(segment, "..") {
+		segment = strings.ReplaceAll(segment, "..", ".")
+	}
+	segment = strings.TrimSuffix(segment, ".lock")
+	if segment == "" {
+		return "unknown"
+	}
+	return segment
+}
+
+// pendingReviewBranches 列出本项目尚未合入 vaultBranch 的评审分支（任何用户推的都算）。
+// 只读本地 bare repo，结果新旧取决于上次 fetch。
+func pendingReviewBranches(workspace Workspace, projectConfig *types.ProjectConfig, vaultBranch string) ([]string, error) {
+	names, err := repo.UnmergedBranches(types.ReviewBranchPrefix, vaultBranch)
+	if err != nil {
+		return nil, err
+	}
+	project := branchSegment(workspaceProjectName(workspace, projectConfig))
+	var pending []string
+	for _, name := range names {
+		parts := strings.Split(strings.TrimPrefix(name, types.ReviewBranchPrefix), "/")
+		if len(parts) == 3 && parts[1] == project {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
+
+// PullWorkspaceBranchPreview 按评审分支的内容拉取安装，用于合并前试用。
+// 读取走 NewReadTransactionAt 的同一路径（refs/heads/<branch>）；.dec/.version 记录评审提交，
+// 但频道仍是配置的 vault 分支，因此陈旧度检查会提示回到主线版本。
+func PullWorkspaceBranchPreview(ctx context.Context, workspace Workspace, branch string, reporter Reporter) (*PullProjectAssetsResult, error) {
+	reporter = defaultReporter(reporter)
+	normalized, ok := config.NormalizeVaultBranch(branch)
+	if !ok || normalized == "" {
+		return nil, fmt.Errorf("评审分支 %q 不是合法分支名", branch)
+	}
+	if !strings.HasPrefix(normalized, types.ReviewBranchPrefix) {
+		return nil, fmt.Errorf("只能预览 %s 开头的评审分支，收到 %s", types.ReviewBranchPrefix, normalized)
+	}
+	if held := heldPullVersion(workspace); held != "" {
+		return nil, fmt.Errorf("项目已固定在 %s，先解除固定再预览评审分支", shortCommit(held))
+	}
+	emit(reporter, EventInfo, "pull.preview", fmt.Sprintf("按评审分支 %s 预览拉取", normalized), nil)
+	result, err := pullWorkspaceAssets(ctx, workspace, pullRequest{version: "refs/heads/" + normalized, source: PullSourcePreview}, reporter)
+	if err != nil {
+		return nil, err
+	}
+	result.PreviewBranch = normalized
+	return result, nil
+}
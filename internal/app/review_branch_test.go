@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestPushBranchPolicyCreatesReviewBranchForPreview(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/bundle-rule.mdc": "---\ndescription: rule\n---\nmain body\n",
+		"bundles/combo/bundle.yaml":           "name: combo\nmembers:\n  - rule/bundle-rule\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	if err := config.SaveGlobalConfig(&types.GlobalConfig{PushPolicy: types.PushPolicyBranch}); err != nil {
+		t.Fatalf("SaveGlobalConfig() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		ProjectName:    "Demo App",
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"combo"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	if _, err := PullProjectAssets(context.Background(), projectRoot, "", nil); err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+
+	cachePath := getWorkspaceCachePath(NewWorkspace(WorkspaceProject, projectRoot), "combo", "rule", "bundle-rule")
+	writeFileProjectTest(t, cachePath, "---\ndescription: rule\n---\nreview body\n")
+	pushed, err := PushProjectAssets(context.Background(), projectRoot, nil)
+	if err != nil {
+		t.Fatalf("PushProjectAssets() 失败: %v", err)
+	}
+	review := pushed.DecReviewBranch
+	parts := strings.Split(review, "/")
+	if len(parts) != 4 || parts[0] != "dec" || parts[2] != "demo-app" || pushed.DecPushedCount == 0 {
+		t.Fatalf("branch 策略应推到 dec/<user>/demo-app/<timestamp>，得到 %+v", pushed)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", review+":bundles/combo/rules/bundle-rule.mdc"); !strings.Contains(got, "review body") {
+		t.Fatalf("评审分支应包含本地修改: %q", got)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/rules/bundle-rule.mdc"); !strings.Contains(got, "main body") {
+		t.Fatalf("main 不应被改动: %q", got)
+	}
+
+	overview, err := LoadProjectOverview(projectRoot)
+	if err != nil {
+		t.Fatalf("LoadProjectOverview() 失败: %v", err)
+	}
+	if overview.PushPolicy != types.PushPolicyBranch || len(overview.PendingBranches) != 1 || overview.PendingBranches[0] != review {
+		t.Fatalf("overview 待合并分支 = %q / %v", overview.PushPolicy, overview.PendingBranches)
+	}
+
+	// 本地改回主线内容后按评审分支预览拉取，应装上评审分支的版本。
+	writeFileProjectTest(t, cachePath, "---\ndescription: rule\n---\nmain body\n")
+	preview, err := PullWorkspaceBranchPreview(context.Background(), NewWorkspace(WorkspaceProject, projectRoot), review, nil)
+	if err != nil {
+		t.Fatalf("PullWorkspaceBranchPreview() 失败: %v", err)
+	}
+	if preview.PreviewBranch != review || preview.VaultBranch != "main" {
+		t.Fatalf("预览结果 = %q@%q", preview.PreviewBranch, preview.VaultBranch)
+	}
+	data, err := os.ReadFile(filepath.Join(projectRoot, ".cursor", "rules", "dec-bundle-rule.mdc"))
+	if err != nil || !strings.Contains(string(data), "review body") {
+		t.Fatalf("应安装评审分支的内容: %q, %v", data, err)
+	}
+
+	if _, err := PullWorkspaceBranchPreview(context.Background(), NewWorkspace(WorkspaceProject, projectRoot), "main", nil); err == nil {
+		t.Fatalf("非 dec/ 分支不应允许预览")
+	}
+
+	// 评审分支以 squash 方式合入，且 main 随后还有别的改动：不再算待合并。
+	seed := filepath.Join(t.TempDir(), "seed")
+	runGitNoDirProjectTest(t, "clone", remote, seed)
+	configureGitUserProjectTest(t, seed)
+	runGitProjectTest(t, seed, "merge", "--squash", "origin/"+review)
+	runGitProjectTest(t, seed, "commit", "-m", "squash review")
+	writeFileProjectTest(t, filepath.Join(seed, "notes.txt"), "later\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "later change")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+	if err := repo.FetchBare(); err != nil {
+		t.Fatalf("FetchBare() 失败: %v", err)
+	}
+	overview, err = LoadProjectOverview(projectRoot)
+	if err != nil {
+		t.Fatalf("LoadProjectOverview() 失败: %v", err)
+	}
+	if len(overview.PendingBranches) != 0 {
+		t.Fatalf("squash 合入后不应再待合并: %v", overview.PendingBranches)
+	}
+}
+
+func TestReviewBranchNameSanitizesSegments(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("CST", 8*3600))
+	name := reviewBranchName(NewWorkspace(WorkspaceUser, ""), nil, now)
+	if !regexp.MustCompile(`^dec/[^/]+/user/20260101-190405-[0-9a-f]{6}$`).MatchString(name) {
+		t.Fatalf("reviewBranchName() = %q", name)
+	}
+	if again := reviewBranchName(NewWorkspace(WorkspaceUser, ""), nil, now); again == name {
+		t.Fatalf("同一秒内的两次推送应得到不同的评审分支名: %q", again)
+	}
+	if _, ok := config.NormalizeVaultBranch(name); !ok {
+		t.Fatalf("评审分支名应是合法分支名: %q", name)
+	}
+	for raw, want := range map[string]string{
+		`CORP\Alice`:  "alice",
+		"My Project!": "my-project",
+		"..a..b.lock": "a.b",
+		"  ":          "unknown",
+		"中文":          "unknown",
+	} {
+		if got := branchSegment(raw); got != want {
+			t.Errorf("branchSegment(%q) = %q, 期望 %q", raw, got, want)
+		}
+	}
+}
@@ -114,7 +114,7 @@ func loadVaultBundleScopes(workspace Workspace, reporter Reporter) vaultBundleSc
 		inPlane:    make(map[string]struct{}),
 		otherPlane: make(map[string]struct{}),
 	}
-	_ = withAppReadRepo(func(tx *repo.Transaction) error {
+	_ = withAppReadRepo(workspaceLocalReadVaultBranch(workspace, reporter, "secrets.browse"), func(tx *repo.Transaction) error {
 		vaultBundles, _, scanErr := scanVaultBundles(tx.WorkDir(), reporter)
 		if scanErr != nil {
 			emit(reporter, EventWarn, "secrets.browse", "扫描 vault bundles 失败（secrets 浏览不含 vault 包）: "+scanErr.Error(), nil)
@@ -546,7 +546,7 @@ func pullEnabledSecretsBundlesForWorkspace(ctx context.Context, workspace Worksp
 func vaultPresentInPlane(workspace Workspace, reporter Reporter) (map[string]struct{}, bool) {
 	present := make(map[string]struct{})
 	ok := false
-	err := withAppReadRepo(func(tx *repo.Transaction) error {
+	err := withAppReadRepo(workspaceLocalReadVaultBranch(workspace, reporter, "pull.reconcile"), func(tx *repo.Transaction) error {
 		ok = true
 		vaultBundles, _, scanErr := scanVaultBundles(tx.WorkDir(), reporter)
 		if scanErr != nil {
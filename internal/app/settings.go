@@ -243,7 +243,8 @@ func listConnectedVaultBundleNames(reporter Reporter) []string {
 	if err != nil || !connected {
 		return nil
 	}
-	tx, err := repo.NewLocalReadTransaction()
+	// Settings 管理的是用户级 secrets bundle，按全局 vault_branch 读取
+	tx, err := repo.NewLocalReadTransactionOn(emitLocalReadVaultBranch(nil, reporter, "settings.secrets"))
 	if err != nil {
 		emit(reporter, EventWarn, "settings.secrets",
 			fmt.Sprintf("打开仓库只读事务失败，Settings 将不展示 vault bundle: %v", err), nil)
@@ -534,11 +535,15 @@ func InstallBuiltinAssetsForIDE(ideName string) error {
 	ideImpl := ide.Get(ideName)
 	bundle := assets.GlobalAssets()
 
-	if err := installBuiltinSkills(ideImpl.SkillsDirForPlane(ide.PlaneUser, "", homeDir), bundle.Skills); err != nil {
-		return fmt.Errorf("安装内置 skills 失败: %w", err)
+	if ide.SupportsAssetType(ideImpl, "skill") {
+		if err := installBuiltinSkills(ideImpl.SkillsDirForPlane(ide.PlaneUser, "", homeDir), bundle.Skills); err != nil {
+			return fmt.Errorf("安装内置 skills 失败: %w", err)
+		}
 	}
-	if err := installBuiltinRules(ideImpl.RulesDirForPlane(ide.PlaneUser, "", homeDir), bundle.Rules); err != nil {
-		return fmt.Errorf("安装内置 rules 失败: %w", err)
+	if ide.SupportsAssetType(ideImpl, "rule") {
+		if err := installBuiltinRules(ideImpl.RulesDirForPlane(ide.PlaneUser, "", homeDir), bundle.Rules); err != nil {
+			return fmt.Errorf("安装内置 rules 失败: %w", err)
+		}
 	}
 	if err := installBuiltinMCPs(ideName, homeDir, bundle.MCPs); err != nil {
 		return err
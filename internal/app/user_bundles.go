@@ -58,12 +58,24 @@ func ensureVaultBundlesForUserEnable(names []string, reporter Reporter) (*userEn
 		return nil, fmt.Errorf("仓库未连接，无法创建个人 bundle")
 	}
 
-	tx, err := repo.NewWriteTransaction()
+	// 用户平面跟随全局 vault 分支；走 withAppWriteRepo 以便格式更高的 vault 拒绝写入。
+	branch, err := resolveVaultBranch(nil, reporter, "settings.vault")
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Close()
+	var repair *userEnableRepair
+	if err := withAppWriteRepo(branch, func(tx *repo.Transaction) error {
+		var repairErr error
+		repair, repairErr = repairVaultBundlesForUserEnable(tx, names, reporter)
+		return repairErr
+	}); err != nil {
+		return nil, err
+	}
+	return repair, nil
+}
 
+// repairVaultBundlesForUserEnable 在写事务中执行 ensureVaultBundlesForUserEnable 的修复并推送。
+func repairVaultBundlesForUserEnable(tx *repo.Transaction, names []string, reporter Reporter) (*userEnableRepair, error) {
 	projectRefs, err := listVaultProjectBundleRefs(tx.WorkDir())
 	if err != nil {
 		return nil, err
@@ -435,3 +435,22 @@ members:
 		t.Fatalf("SkippedReason = %q", result.SkippedReason)
 	}
 }
+
+func TestEnsureVaultBundlesForUserEnable_RefusesNewerVaultFormat(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		types.VaultMetaFileName:   "format: 99\n",
+		"bundles/cli/bundle.yaml": "name: cli\nmembers: []\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	before := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main")
+
+	if _, err := ensureVaultBundlesForUserEnable([]string{"woa"}, nil); err == nil || !strings.Contains(err.Error(), "请先升级 Dec") {
+		t.Fatalf("向格式更高的 vault 写入个人 bundle 应被拒绝，得到 %v", err)
+	}
+	if after := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main"); after != before {
+		t.Fatalf("远端不应收到提交: %s -> %s", before, after)
+	}
+}
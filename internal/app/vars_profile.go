@@ -0,0 +1,179 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/types"
+	"github.com/shichao402/Dec/internal/vars"
+)
+
+// SwitchVarsProfileResult 描述一次 profile 切换。
+type SwitchVarsProfileResult struct {
+	ProjectRoot string
+	Previous    string
+	Active      string
+	// AffectedVars 是新旧 profile 定义过的变量名并集；只有用到它们的资产会重新渲染
+	AffectedVars []string
+	// Rerendered 是重新渲染的资产，格式 "[type] name (bundle)"
+	Rerendered    []string
+	CopyFallbacks []string
+	Warnings      []string
+}
+
+// SwitchVarsProfile 写入 .dec/config.yaml 的 active_profile，并只从本地 .dec/cache 重新渲染
+// 用到新旧 profile 变量的资产。不联网：vault 共享变量读本地镜像。profile 为空表示关闭 profile。
+func SwitchVarsProfile(projectRoot, profile string, reporter Reporter) (*SwitchVarsProfileResult, error) {
+	reporter = defaultReporter(reporter)
+	if strings.TrimSpace(projectRoot) == "" {
+		return nil, fmt.Errorf("切换变量 profile 需要项目根目录：profile 只属于项目平面")
+	}
+	profile = strings.TrimSpace(profile)
+
+	mgr := config.NewProjectConfigManager(projectRoot)
+	projectConfig, err := mgr.LoadProjectConfig()
+	if err != nil {
+		return nil, fmt.Errorf("加载项目配置失败: %w", err)
+	}
+	varsConfig, err := mgr.LoadVarsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", mgr.GetVarsPath(), err)
+	}
+	newVars, ok := varsConfig.ProfileVars(profile)
+	if profile != "" && !ok {
+		return nil, fmt.Errorf("profile %q 未在 %s 中定义（已定义: %s）", profile, mgr.GetVarsPath(), profileLabel(strings.Join(varsConfig.ProfileNames(), ", ")))
+	}
+
+	result := &SwitchVarsProfileResult{
+		ProjectRoot: projectRoot,
+		Previous:    projectConfig.ActiveProfile,
+		Active:      profile,
+	}
+	if result.Previous == profile {
+		return result, nil
+	}
+	oldVars, _ := varsConfig.ProfileVars(result.Previous)
+
+	projectConfig.ActiveProfile = profile
+	if err := mgr.SaveProjectConfig(projectConfig); err != nil {
+		return nil, err
+	}
+	emit(reporter, EventInfo, "vars.profile", fmt.Sprintf("active_profile: %s → %s", profileLabel(result.Previous), profileLabel(profile)), nil)
+
+	affected := make(map[string]bool, len(oldVars)+len(newVars))
+	for name := range oldVars {
+		affected[name] = true
+	}
+	for name := range newVars {
+		affected[name] = true
+	}
+	for name := range affected {
+		result.AffectedVars = append(result.AffectedVars, name)
+	}
+	sort.Strings(result.AffectedVars)
+	if len(affected) == 0 {
+		return result, nil
+	}
+
+	if err := rerenderAssetsForVars(result, projectConfig, affected, reporter); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// rerenderAssetsForVars 从 .dec/cache 重新渲染用到 affected 中任一变量的已启用资产：
+// 与 pull 一样先在暂存区全部渲染、校验，再落地到 IDE。
+func rerenderAssetsForVars(result *SwitchVarsProfileResult, projectConfig *types.ProjectConfig, affected map[string]bool, reporter Reporter) error {
+	projectRoot := result.ProjectRoot
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+	mgr := config.NewProjectConfigManager(projectRoot)
+
+	ideSelection, err := config.ResolveEffectiveIDEs(projectConfig)
+	if err != nil {
+		return fmt.Errorf("解析有效 IDE 失败: %w", err)
+	}
+	projectIDEs := uniqueWorkspaceIDEs(workspace, ideSelection.IDEs)
+	installMode, err := config.ResolveInstallMode(projectConfig)
+	if err != nil {
+		return fmt.Errorf("解析安装方式失败: %w", err)
+	}
+
+	shared, sharedWarnings := loadLocalVaultSharedVars(projectRoot, projectConfig)
+	rv, varsWarnings := loadRenderVars(workspace, projectConfig, shared)
+	result.Warnings = append(result.Warnings, sharedWarnings...)
+	result.Warnings = append(result.Warnings, varsWarnings...)
+
+	enabledBundles := config.NormalizeBundleNames(projectConfig.EnabledBundles)
+	enabled := make(map[string]bool, len(enabledBundles))
+	for _, name := range enabledBundles {
+		enabled[name] = true
+	}
+	stage, err := newRenderStage(workspace, projectIDEs, rv, enabledBundles)
+	if err != nil {
+		return err
+	}
+	defer stage.Close()
+
+	var pendingAssets []*pendingAsset
+	warn := func(asset types.TypedAssetRef, err error) {
+		label := fmt.Sprintf("[%-5s] %s (%s)", asset.Type, asset.Name, asset.Vault)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s 重新渲染失败: %v", label, err))
+		emit(reporter, EventWarn, "vars.profile", fmt.Sprintf("⚠️  %s 重新渲染失败: %v", label, err), nil)
+	}
+	cacheDir := filepath.Join(mgr.GetDecDir(), "cache")
+	for _, asset := range listCachedAssets(cacheDir) {
+		if !enabled[asset.Vault] {
+			continue
+		}
+		cachePath := getWorkspaceCachePath(workspace, asset.Vault, asset.Type, asset.Name)
+		tmpl := newAssetTemplate(workspace, shared, enabledBundles, asset.Vault, asset.Type, asset.Name)
+		// 模板通过 .Vars 取值，无法从占位符判断用到了哪些变量，一律重新渲染
+		if !usesAnyVar(vars.ExtractPlaceholdersFromDir(cachePath), affected) && !assetUsesTemplate(cachePath, tmpl) {
+			continue
+		}
+		pending, err := stage.render(asset, cachePath, reporter)
+		if err != nil {
+			warn(asset, err)
+			continue
+		}
+		pendingAssets = append(pendingAssets, pending)
+	}
+
+	for _, pending := range pendingAssets {
+		asset := pending.Asset
+		if err := stage.install(pending); err != nil {
+			warn(asset, err)
+			continue
+		}
+		label := fmt.Sprintf("[%-5s] %s (%s)", asset.Type, asset.Name, asset.Vault)
+		if installMode.Mode == types.InstallModeSymlink {
+			if linked, reason := linkInstalledAsset(asset.Type, asset.Name, workspace, projectIDEs); !linked && reason != "" {
+				result.CopyFallbacks = append(result.CopyFallbacks, fmt.Sprintf("%s：%s", label, reason))
+			}
+		} else {
+			removeStagedAsset(workspace, asset.Type, asset.Name)
+		}
+		result.Rerendered = append(result.Rerendered, label)
+		emit(reporter, EventInfo, "vars.profile", "🔁 "+label, nil)
+	}
+	return nil
+}
+
+func usesAnyVar(placeholders []string, names map[string]bool) bool {
+	for _, name := range placeholders {
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func profileLabel(value string) string {
+	if value == "" {
+		return "<无>"
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestSwitchVarsProfileRerendersOnlyAffectedAssets(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/team/rules/api.mdc":   "api={{API_URL}}\n",
+		"bundles/team/rules/style.mdc": "owner={{OWNER}}\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+
+	projectRoot := t.TempDir()
+	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"team"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+	writeFile(t, mgr.GetVarsPath(), "vars:\n  API_URL: https://prod.example.com\n  OWNER: me\nprofiles:\n  staging:\n    vars:\n      API_URL: https://staging.example.com\n")
+
+	if _, err := PullProjectAssets(context.Background(), projectRoot, "", nil); err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	// 切换 profile 不应联网：删掉远端仓库后仍须成功
+	if err := os.RemoveAll(remote); err != nil {
+		t.Fatal(err)
+	}
+	apiPath := filepath.Join(projectRoot, ".cursor", "rules", "dec-api.mdc")
+	stylePath := filepath.Join(projectRoot, ".cursor", "rules", "dec-style.mdc")
+	styleBefore, err := os.Stat(stylePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := SwitchVarsProfile(projectRoot, "staging", nil)
+	if err != nil {
+		t.Fatalf("SwitchVarsProfile() 失败: %v", err)
+	}
+	if !reflect.DeepEqual(result.AffectedVars, []string{"API_URL"}) {
+		t.Fatalf("AffectedVars = %v", result.AffectedVars)
+	}
+	if len(result.Rerendered) != 1 || !strings.Contains(result.Rerendered[0], "api") {
+		t.Fatalf("只应重新渲染 api 规则, got %v", result.Rerendered)
+	}
+	if data, err := os.ReadFile(apiPath); err != nil || !strings.HasSuffix(string(data), "api=https://staging.example.com\n") {
+		t.Fatalf("api 规则应使用 staging 值: %q, %v", data, err)
+	}
+	if styleAfter, err := os.Stat(stylePath); err != nil || !styleAfter.ModTime().Equal(styleBefore.ModTime()) {
+		t.Fatalf("未受影响的 style 规则不应被重写: %v", err)
+	}
+	projectConfig, err := mgr.LoadProjectConfig()
+	if err != nil || projectConfig.ActiveProfile != "staging" {
+		t.Fatalf("active_profile 未写入: %+v, %v", projectConfig, err)
+	}
+
+	view, err := LoadProjectVarsView(projectRoot)
+	if err != nil {
+		t.Fatalf("LoadProjectVarsView() 失败: %v", err)
+	}
+	if got := view.ResolvedVars["API_URL"]; got.Source != PlaceholderSourceProfile || got.Origin != "staging" {
+		t.Fatalf("ResolvedVars[API_URL] = %+v", got)
+	}
+
+	if _, err := SwitchVarsProfile(projectRoot, "", nil); err != nil {
+		t.Fatalf("关闭 profile 失败: %v", err)
+	}
+	if data, err := os.ReadFile(apiPath); err != nil || !strings.HasSuffix(string(data), "api=https://prod.example.com\n") {
+		t.Fatalf("关闭 profile 后应回到基础值: %q, %v", data, err)
+	}
+	if _, err := SwitchVarsProfile(projectRoot, "nope", nil); err == nil {
+		t.Fatal("未定义的 profile 应报错")
+	}
+}
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/freshness"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestPullAndPushFollowProjectVaultBranch(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/bundle-rule.mdc": "---\ndescription: rule\n---\nstable body\n",
+		"bundles/combo/bundle.yaml":           "name: combo\nmembers:\n  - rule/bundle-rule\n",
+	})
+	// stable 停在初始提交，main 继续前进。
+	runGitNoDirProjectTest(t, "--git-dir", remote, "branch", "stable", "main")
+	seed := filepath.Join(t.TempDir(), "seed")
+	runGitNoDirProjectTest(t, "clone", remote, seed)
+	configureGitUserProjectTest(t, seed)
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/rules/bundle-rule.mdc"), "---\ndescription: rule\n---\nnext body\n")
+	runGitProjectTest(t, seed, "commit", "-am", "main only")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	mgr := config.NewProjectConfigManager(projectRoot)
+	if err := mgr.SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"combo"},
+		VaultBranch:    "stable",
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+
+	result, err := PullProjectAssets(context.Background(), projectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	if result.VaultBranch != "stable" {
+		t.Fatalf("VaultBranch = %q, 期望 stable", result.VaultBranch)
+	}
+	data, err := os.ReadFile(filepath.Join(projectRoot, ".cursor", "rules", "dec-bundle-rule.mdc"))
+	if err != nil {
+		t.Fatalf("读取安装结果失败: %v", err)
+	}
+	if !strings.Contains(string(data), "stable body") {
+		t.Fatalf("应安装 stable 分支的内容: %q", data)
+	}
+	meta, err := freshness.LoadVersionMeta(projectRoot)
+	if err != nil || meta == nil {
+		t.Fatalf("LoadVersionMeta() = %v, %v", meta, err)
+	}
+	stableHead := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "stable")
+	if meta.Branch != "stable" || meta.Commit != stableHead {
+		t.Fatalf(".dec/.version = %+v, 期望 stable@%s", meta, stableHead)
+	}
+
+	overview, err := LoadProjectOverview(projectRoot)
+	if err != nil {
+		t.Fatalf("LoadProjectOverview() 失败: %v", err)
+	}
+	if overview.VaultBranch != "stable" || overview.VaultBranchSource != types.VaultBranchSourceProject || overview.PulledBranch != "stable" {
+		t.Fatalf("overview 分支 = %q/%q/%q", overview.VaultBranch, overview.VaultBranchSource, overview.PulledBranch)
+	}
+
+	cachePath := getWorkspaceCachePath(NewWorkspace(WorkspaceProject, projectRoot), "combo", "rule", "bundle-rule")
+	writeFileProjectTest(t, cachePath, "---\ndescription: rule\n---\nstable fix\n")
+	pushed, err := PushProjectAssets(context.Background(), projectRoot, nil)
+	if err != nil {
+		t.Fatalf("PushProjectAssets() 失败: %v", err)
+	}
+	if pushed.DecPushedCount == 0 {
+		t.Fatalf("应推送本地修改: %+v", pushed)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "stable:bundles/combo/rules/bundle-rule.mdc"); !strings.Contains(got, "stable fix") {
+		t.Fatalf("修改应推到 stable: %q", got)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/rules/bundle-rule.mdc"); !strings.Contains(got, "next body") {
+		t.Fatalf("main 不应被改动: %q", got)
+	}
+}
+
+func TestPullReportsMissingVaultBranch(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/bundle-rule.mdc": "---\ndescription: rule\n---\n",
+		"bundles/combo/bundle.yaml":           "name: combo\nmembers:\n  - rule/bundle-rule\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	if err := config.SaveGlobalConfig(&types.GlobalConfig{VaultBranch: "next"}); err != nil {
+		t.Fatalf("SaveGlobalConfig() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"combo"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+
+	_, err := PullProjectAssets(context.Background(), projectRoot, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "vault 分支 next 不存在") {
+		t.Fatalf("全局 vault_branch 指向不存在的分支时应报错, got %v", err)
+	}
+}
+
+func TestLocalReadsFollowProjectVaultBranch(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/bundle-rule.mdc": "---\ndescription: rule\n---\n",
+		"bundles/combo/bundle.yaml":           "name: combo\nmembers:\n  - rule/bundle-rule\n",
+		"bundles/combo/vars.yaml":             "vars:\n  ZONE: stable\n",
+	})
+	runGitNoDirProjectTest(t, "--git-dir", remote, "branch", "stable", "main")
+	seed := filepath.Join(t.TempDir(), "seed")
+	runGitNoDirProjectTest(t, "clone", remote, seed)
+	configureGitUserProjectTest(t, seed)
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/vars.yaml"), "vars:\n  ZONE: main\n")
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/rules/main-only.mdc"), "---\ndescription: main\n---\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "main only")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"combo"},
+		VaultBranch:    "stable",
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+
+	view, err := LoadProjectVarsView(projectRoot)
+	if err != nil {
+		t.Fatalf("LoadProjectVarsView() 失败: %v", err)
+	}
+	if got := view.VaultBundleVars["combo"]["ZONE"]; got != "stable" {
+		t.Fatalf("共享变量应读自 stable 分支, got %q (warnings=%v)", got, view.Warnings)
+	}
+
+	assets, err := ScanAvailableAssets("stable", nil)
+	if err != nil {
+		t.Fatalf("ScanAvailableAssets() 失败: %v", err)
+	}
+	for _, asset := range assets {
+		if asset.Name == "main-only" {
+			t.Fatalf("stable 分支不应列出 main 独有资产: %+v", assets)
+		}
+	}
+}
+
+func TestBundlesPageAndRemoveFollowProjectVaultBranch(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/combo/rules/bundle-rule.mdc": "---\ndescription: rule\n---\n",
+		"bundles/combo/bundle.yaml":           "name: combo\nmembers:\n  - rule/bundle-rule\n",
+	})
+	// stable 上多一个只存在于该分支的 bundle，main 保持原样。
+	runGitNoDirProjectTest(t, "--git-dir", remote, "branch", "stable", "main")
+	seed := filepath.Join(t.TempDir(), "seed")
+	runGitNoDirProjectTest(t, "clone", "--branch", "stable", remote, seed)
+	configureGitUserProjectTest(t, seed)
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/solo/rules/solo-rule.mdc"), "---\ndescription: solo\n---\n")
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/solo/rules/solo-extra.mdc"), "---\ndescription: extra\n---\n")
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/solo/bundle.yaml"), "name: solo\nmembers:\n  - rule/solo-rule\n  - rule/solo-extra\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "stable only")
+	runGitProjectTest(t, seed, "push", "origin", "stable")
+	mainHead := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main")
+
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:        []string{"cursor"},
+		VaultBranch: "stable",
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+
+	state, err := LoadAssetSelection(projectRoot, nil)
+	if err != nil {
+		t.Fatalf("LoadAssetSelection() 失败: %v", err)
+	}
+	found := false
+	for _, option := range state.Bundles {
+		found = found || option.Name == "solo"
+	}
+	if !found {
+		t.Fatalf("Bundles 页应列出 stable 分支上的 bundle: %+v", state.Bundles)
+	}
+	saved, err := SaveEnabledBundles(projectRoot, []string{"solo"}, nil)
+	if err != nil {
+		t.Fatalf("SaveEnabledBundles() 失败: %v", err)
+	}
+	if len(saved.RejectedBundles) != 0 || saved.EnabledBundleCount != 1 {
+		t.Fatalf("stable 分支上的 bundle 应能启用: %+v", saved)
+	}
+
+	if _, err := RemoveAsset(RemoveAssetInput{ProjectRoot: projectRoot, Type: "rule", Name: "solo-rule", Confirmed: true}, nil); err != nil {
+		t.Fatalf("RemoveAsset() 失败: %v", err)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "ls-tree", "-r", "--name-only", "stable"); strings.Contains(got, "solo-rule") || !strings.Contains(got, "solo-extra") {
+		t.Fatalf("删除应落在 stable 分支: %q", got)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "main"); got != mainHead {
+		t.Fatalf("main 不应被改动: %s -> %s", mainHead, got)
+	}
+
+	// branch 策略下删除同样推到评审分支，跟随的 stable 保持不动。
+	if err := config.SaveGlobalConfig(&types.GlobalConfig{PushPolicy: types.PushPolicyBranch}); err != nil {
+		t.Fatalf("SaveGlobalConfig() 失败: %v", err)
+	}
+	stableHead := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "stable")
+	if _, err := RemoveAsset(RemoveAssetInput{ProjectRoot: projectRoot, Type: "rule", Name: "solo-extra", Confirmed: true}, nil); err != nil {
+		t.Fatalf("RemoveAsset() 失败: %v", err)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "rev-parse", "stable"); got != stableHead {
+		t.Fatalf("branch 策略下 stable 不应被改动: %s -> %s", stableHead, got)
+	}
+	review := runGitNoDirProjectTest(t, "--git-dir", remote, "for-each-ref", "--format=%(refname:short)", "refs/heads/dec/")
+	if review == "" || strings.Contains(review, "\n") {
+		t.Fatalf("应新建一个评审分支, got %q", review)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "ls-tree", "-r", "--name-only", review); strings.Contains(got, "solo-extra") {
+		t.Fatalf("评审分支应包含删除: %q", got)
+	}
+}
@@ -0,0 +1,443 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// vaultMigration 把 vault 从 To-1 格式升级到 To 格式。Apply 只改写事务工作区里的文件，改动经 vaultEdits 记录。
+type vaultMigration struct {
+	To      int
+	Summary string
+	Apply   func(repoDir string, edits *vaultEdits, reporter Reporter) error
+}
+
+// vaultMigrations 按格式版本升序排列。新增格式时在末尾追加迁移，并同步提高 types.VaultFormatCurrent。
+var vaultMigrations = []vaultMigration{
+	{To: 1, Summary: "bundle.yaml 显式声明 scope（缺省按 project，ADR 0009）", Apply: migrateBundleScope},
+	{To: 2, Summary: "bundle 内 mcps/ 目录更名为 mcp/", Apply: migrateMCPDir},
+}
+
+// VaultFormatStatus 描述 vault 分支上的格式版本。
+type VaultFormatStatus struct {
+	Branch string
+	// Format 是 .dec-vault.yaml 声明的格式，没有声明文件时为 0。
+	Format int
+	// Supported 是本版本 Dec 读写的格式。
+	Supported int
+	// Pending 是升级到 Supported 需要依次执行的迁移说明。
+	Pending []string
+}
+
+// Newer 判断 vault 格式是否高于本版本 Dec 支持的格式；此时只能读取，推送会被拒绝。
+func (s *VaultFormatStatus) Newer() bool {
+	return s != nil && s.Format > s.Supported
+}
+
+// MigrateVaultInput 描述一次 vault 迁移。
+type MigrateVaultInput struct {
+	// DryRun 为 true 时只在写事务里执行迁移并返回逐文件 diff，不提交。
+	DryRun bool
+}
+
+// MigrateVaultResult 汇报 vault 迁移的结果。
+type MigrateVaultResult struct {
+	Branch string
+	From   int
+	To     int
+	DryRun bool
+	// Applied 是执行的迁移说明，按执行顺序。
+	Applied []string
+	// Changes 逐文件列出迁移的改动（含 .dec-vault.yaml）。
+	Changes []VaultFileChange
+	// Committed 为 true 表示迁移提交已推到远端。
+	Committed bool
+	Commit    string
+}
+
+// LoadVaultFormat 读取跟随的 vault 分支上的格式版本（不 fetch）。
+func LoadVaultFormat(workspace Workspace, reporter Reporter) (*VaultFormatStatus, error) {
+	reporter = defaultReporter(reporter)
+	branch, err := workspaceVaultBranch(workspace, reporter)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := repo.NewReadTransactionWith(repo.ReadOptions{Branch: branch, Local: true, Select: vaultMetaSelection})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	meta, err := loadVaultMeta(tx.WorkDir())
+	if err != nil {
+		return nil, err
+	}
+	return newVaultFormatStatus(tx.Branch(), meta.Format), nil
+}
+
+// MigrateVault 在写事务里把 vault 依次迁移到本版本支持的格式，最后写入 .dec-vault.yaml。
+// dry-run 只返回改动，不提交；vault 已是最新格式时什么都不做。
+func MigrateVault(ctx context.Context, workspace Workspace, input MigrateVaultInput, reporter Reporter) (*MigrateVaultResult, error) {
+	reporter = defaultReporter(reporter)
+	branch, err := workspaceVaultBranch(workspace, reporter)
+	if err != nil {
+		return nil, err
+	}
+	result := &MigrateVaultResult{DryRun: input.DryRun, To: types.VaultFormatCurrent}
+	err = withAppWriteRepo(branch, func(tx *repo.Transaction) error {
+		result.Branch = tx.Branch()
+		if tx.Offline() && !input.DryRun {
+			return fmt.Errorf("离线时不能迁移 vault（%s）", tx.OfflineReason())
+		}
+		repoDir := tx.WorkDir()
+		meta, err := loadVaultMeta(repoDir)
+		if err != nil {
+			return err
+		}
+		result.From = meta.Format
+		if meta.Format >= types.VaultFormatCurrent {
+			result.To = meta.Format
+			emit(reporter, EventInfo, "vault.migrate", fmt.Sprintf("vault 已是格式 %d，无需迁移", meta.Format), nil)
+			return nil
+		}
+
+		edits := newVaultEdits(repoDir)
+		for _, migration := range pendingVaultMigrations(meta.Format) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			emit(reporter, EventInfo, "vault.migrate", fmt.Sprintf("格式 %d → %d：%s", migration.To-1, migration.To, migration.Summary), nil)
+			if err := migration.Apply(repoDir, edits, reporter); err != nil {
+				return fmt.Errorf("迁移到格式 %d 失败: %w", migration.To, err)
+			}
+			result.Applied = append(result.Applied, migration.Summary)
+		}
+		if err := edits.writeFile(types.VaultMetaFileName, vaultMetaContent(types.VaultFormatCurrent)); err != nil {
+			return err
+		}
+		if result.Changes, err = edits.changes(); err != nil {
+			return err
+		}
+		if input.DryRun {
+			emit(reporter, EventInfo, "vault.migrate", fmt.Sprintf("dry-run：%d 个文件将被改动，未提交", len(result.Changes)), nil)
+			return nil
+		}
+
+		committed, err := tx.CommitAndPush(vaultMigrationMessage(result))
+		if err != nil {
+			return fmt.Errorf("推送 vault 迁移失败: %w", err)
+		}
+		result.Committed = committed
+		result.Commit = tx.CommitHash()
+		emit(reporter, EventInfo, "vault.migrate", fmt.Sprintf("vault 已迁移到格式 %d（%d 个文件）", types.VaultFormatCurrent, len(result.Changes)), nil)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func workspaceVaultBranch(workspace Workspace, reporter Reporter) (string, error) {
+	projectConfig, err := loadWorkspaceBundleConfig(workspace)
+	if err != nil {
+		return "", err
+	}
+	return resolveVaultBranch(projectConfig, reporter, "vault.format")
+}
+
+func newVaultFormatStatus(branch string, format int) *VaultFormatStatus {
+	status := &VaultFormatStatus{Branch: branch, Format: format, Supported: types.VaultFormatCurrent}
+	for _, migration := range pendingVaultMigrations(format) {
+		status.Pending = append(status.Pending, migration.Summary)
+	}
+	return status
+}
+
+func pendingVaultMigrations(format int) []vaultMigration {
+	var pending []vaultMigration
+	for _, migration := range vaultMigrations {
+		if migration.To > format && migration.To <= types.VaultFormatCurrent {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// vaultMetaSelection 只物化 vault 根目录的格式声明。
+func vaultMetaSelection(files []string) []string {
+	for _, file := range files {
+		if file == types.VaultMetaFileName {
+			return []string{file}
+		}
+	}
+	return nil
+}
+
+// loadVaultMeta 读取 repoDir 下的 .dec-vault.yaml；文件不存在时为格式 0。
+func loadVaultMeta(repoDir string) (*types.VaultMeta, error) {
+	meta := &types.VaultMeta{}
+	data, err := os.ReadFile(filepath.Join(repoDir, types.VaultMetaFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return meta, nil
+		}
+		return nil, fmt.Errorf("读取 %s 失败: %w", types.VaultMetaFileName, err)
+	}
+	if err := yaml.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", types.VaultMetaFileName, err)
+	}
+	if meta.Format < 0 {
+		return nil, fmt.Errorf("%s 的 format %d 非法", types.VaultMetaFileName, meta.Format)
+	}
+	return meta, nil
+}
+
+func vaultMetaContent(format int) []byte {
+	return []byte(fmt.Sprintf("# Dec vault 格式声明：由 Dec 的 vault 迁移维护，请勿手动修改\nformat: %d\n", format))
+}
+
+// ensureVaultWritable 拒绝向格式高于本版本支持的 vault 推送：旧版本不认识新布局，写入会破坏它。
+// errVaultFormatTooNew 标记 vault 格式高于本版本支持的格式：写入与离线队列发送都必须中止。
+var errVaultFormatTooNew = errors.New("请先升级 Dec 再推送")
+
+func ensureVaultWritable(repoDir string) error {
+	meta, err := loadVaultMeta(repoDir)
+	if err != nil {
+		return err
+	}
+	if meta.Format > types.VaultFormatCurrent {
+		return fmt.Errorf("vault 格式为 %d，高于本版本 Dec 支持的格式 %d：%w", meta.Format, types.VaultFormatCurrent, errVaultFormatTooNew)
+	}
+	return nil
+}
+
+// ensureVaultBranchWritable 在发送离线排队的提交前同步远端并检查目标分支的格式；
+// 只有确认分支在远端还不存在（尚未创建的评审分支）时跳过检查，其余错误一律返回，让队列保留。
+func ensureVaultBranchWritable(branch string) error {
+	tx, err := repo.NewReadTransactionWith(repo.ReadOptions{Branch: branch, Select: vaultMetaSelection})
+	if err != nil {
+		var notFound *repo.BranchNotFoundError
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return err
+	}
+	defer tx.Close()
+	return ensureVaultWritable(tx.WorkDir())
+}
+
+// warnNewerVaultFormat 在 vault 格式高于本版本支持时给 pull 结果加一条非致命告警：仍可读取，但推送会被拒绝。
+func warnNewerVaultFormat(result *PullProjectAssetsResult, repoDir string, reporter Reporter) {
+	meta, err := loadVaultMeta(repoDir)
+	if err != nil || meta.Format <= types.VaultFormatCurrent {
+		return
+	}
+	warning := fmt.Sprintf("vault 格式为 %d，高于本版本 Dec 支持的格式 %d：可以拉取，但推送前请先升级 Dec", meta.Format, types.VaultFormatCurrent)
+	result.NonFatalWarnings = append(result.NonFatalWarnings, warning)
+	emit(reporter, EventWarn, "pull.prepare", warning, nil)
+}
+
+func vaultMigrationMessage(result *MigrateVaultResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "chore(vault): migrate format %d -> %d\n\n", result.From, result.To)
+	for _, summary := range result.Applied {
+		fmt.Fprintf(&b, "- %s\n", summary)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// vaultBundleNames 列出 bundles/ 下的目录名（按字典序，跳过隐藏目录）。
+func vaultBundleNames(repoDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(repoDir, types.VaultBundlesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// migrateBundleScope 给没有 scope 的 bundle.yaml 补上 scope: project。
+// 只在顶层 name 行后插入一行，不重写文件其余内容。
+func migrateBundleScope(repoDir string, edits *vaultEdits, reporter Reporter) error {
+	names, err := vaultBundleNames(repoDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		rel := types.VaultBundleManifestPath(name)
+		data, ok, err := readOptionalFile(filepath.Join(repoDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if _, explicit, err := yamlBundleNameScope(data); err != nil {
+			emit(reporter, EventWarn, "vault.migrate", fmt.Sprintf("跳过无法解析的 %s: %v", rel, err), nil)
+			continue
+		} else if explicit {
+			continue
+		}
+		updated, ok := insertBundleScope(string(data), types.BundleScopeProject)
+		if !ok {
+			emit(reporter, EventWarn, "vault.migrate", fmt.Sprintf("%s 没有顶层 name 行，未补 scope", rel), nil)
+			continue
+		}
+		if err := edits.writeFile(rel, []byte(updated)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertBundleScope 把空的顶层 scope 行替换为 scope 值；没有 scope 行时插在顶层 name 行之后。
+func insertBundleScope(content string, scope types.BundleScope) (string, bool) {
+	lines := strings.SplitAfter(content, "\n")
+	scopeLine := "scope: " + string(scope) + "\n"
+	for i, line := range lines {
+		if key, _, found := strings.Cut(strings.TrimRight(line, "\r\n"), ":"); found && key == "scope" {
+			lines[i] = scopeLine
+			return strings.Join(lines, ""), true
+		}
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(line, "name:") {
+			if !strings.HasSuffix(line, "\n") {
+				lines[i] = line + "\n"
+			}
+			out := append(append(append([]string{}, lines[:i+1]...), scopeLine), lines[i+1:]...)
+			return strings.Join(out, ""), true
+		}
+	}
+	return content, false
+}
+
+// migrateMCPDir 把 bundles/<name>/mcps/ 下的文件移到 mcp/；目标已存在同名文件时保留原样并告警。
+func migrateMCPDir(repoDir string, edits *vaultEdits, reporter Reporter) error {
+	names, err := vaultBundleNames(repoDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		legacy := path.Join(types.VaultBundleDir(name), "mcps")
+		files, err := listAssetFiles(filepath.Join(repoDir, filepath.FromSlash(legacy)))
+		if err != nil {
+			return err
+		}
+		rels := make([]string, 0, len(files))
+		for rel := range files {
+			rels = append(rels, rel)
+		}
+		sort.Strings(rels)
+		for _, rel := range rels {
+			from := path.Join(legacy, rel)
+			to := path.Join(types.VaultBundleDir(name), "mcp", rel)
+			if _, err := os.Lstat(filepath.Join(repoDir, filepath.FromSlash(to))); err == nil {
+				emit(reporter, EventWarn, "vault.migrate", fmt.Sprintf("%s 已存在，保留 %s 未迁移", to, from), nil)
+				continue
+			}
+			if err := edits.rename(from, to); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// vaultEdits 记录迁移改动过的文件与其原始内容，用于生成 dry-run diff。
+type vaultEdits struct {
+	root     string
+	original map[string][]byte
+	existed  map[string]bool
+	order    []string
+}
+
+func newVaultEdits(root string) *vaultEdits {
+	return &vaultEdits{root: root, original: make(map[string][]byte), existed: make(map[string]bool)}
+}
+
+func (e *vaultEdits) abs(rel string) string {
+	return filepath.Join(e.root, filepath.FromSlash(rel))
+}
+
+func (e *vaultEdits) remember(rel string) error {
+	if _, ok := e.existed[rel]; ok {
+		return nil
+	}
+	data, ok, err := readOptionalFile(e.abs(rel))
+	if err != nil {
+		return err
+	}
+	e.original[rel], e.existed[rel] = data, ok
+	e.order = append(e.order, rel)
+	return nil
+}
+
+func (e *vaultEdits) writeFile(rel string, data []byte) error {
+	if err := e.remember(rel); err != nil {
+		return err
+	}
+	target := e.abs(rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, data, assetFileMode(target)); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", rel, err)
+	}
+	return nil
+}
+
+func (e *vaultEdits) rename(from, to string) error {
+	if err := e.remember(from); err != nil {
+		return err
+	}
+	if err := e.remember(to); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.abs(to)), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(e.abs(from), e.abs(to)); err != nil {
+		return fmt.Errorf("移动 %s 到 %s 失败: %w", from, to, err)
+	}
+	_ = os.Remove(filepath.Dir(e.abs(from)))
+	return nil
+}
+
+// changes 按路径列出与原始内容不同的文件。
+func (e *vaultEdits) changes() ([]VaultFileChange, error) {
+	var changes []VaultFileChange
+	for _, rel := range e.order {
+		after, afterOK, err := readOptionalFile(e.abs(rel))
+		if err != nil {
+			return nil, err
+		}
+		before, beforeOK := e.original[rel], e.existed[rel]
+		if afterOK == beforeOK && string(before) == string(after) {
+			continue
+		}
+		bundleName, _, _ := splitVaultBundlePath(rel)
+		changes = append(changes, newVaultFileChange(bundleName, rel, before, after, beforeOK, afterOK))
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestMigrateVaultDryRunThenApply(t *testing.T) {
+	projectRoot, remote, seed := setupPulledProjectForMerge(t, "---\ndescription: team\n---\nline 1\n")
+	writeFileProjectTest(t, filepath.Join(seed, "bundles/combo/mcps/db.json"), "{\"command\": \"db\"}\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "legacy mcps dir")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+	workspace := NewWorkspace(WorkspaceProject, projectRoot)
+
+	preview, err := MigrateVault(context.Background(), workspace, MigrateVaultInput{DryRun: true}, nil)
+	if err != nil {
+		t.Fatalf("dry-run 迁移失败: %v", err)
+	}
+	if preview.From != 0 || preview.To != types.VaultFormatCurrent || len(preview.Applied) != len(vaultMigrations) || preview.Committed {
+		t.Fatalf("dry-run 结果不符合预期: %+v", preview)
+	}
+	statuses := make(map[string]string)
+	for _, change := range preview.Changes {
+		statuses[change.Path] = change.Status
+	}
+	want := map[string]string{
+		types.VaultMetaFileName:      RenderedFileAdded,
+		"bundles/combo/bundle.yaml":  RenderedFileModified,
+		"bundles/combo/mcps/db.json": RenderedFileDeleted,
+		"bundles/combo/mcp/db.json":  RenderedFileAdded,
+	}
+	for path, status := range want {
+		if statuses[path] != status {
+			t.Fatalf("%s 的改动应为 %s，得到 %+v", path, status, statuses)
+		}
+	}
+	if tree := runGitNoDirProjectTest(t, "--git-dir", remote, "ls-tree", "--name-only", "main"); strings.Contains(tree, types.VaultMetaFileName) {
+		t.Fatalf("dry-run 不应提交: %q", tree)
+	}
+
+	result, err := MigrateVault(context.Background(), workspace, MigrateVaultInput{}, nil)
+	if err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	if !result.Committed || result.Commit == "" {
+		t.Fatalf("迁移应提交并推送: %+v", result)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:"+types.VaultMetaFileName); !strings.Contains(got, "format: 2") {
+		t.Fatalf("远端应写入格式声明: %q", got)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/bundle.yaml"); strings.TrimSpace(got) != "name: combo\nscope: project\nmembers:\n  - rule/team" {
+		t.Fatalf("bundle.yaml 应只补 scope 一行: %q", got)
+	}
+	status, err := LoadVaultFormat(workspace, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Format != types.VaultFormatCurrent || len(status.Pending) != 0 || status.Newer() {
+		t.Fatalf("迁移后格式应为最新: %+v", status)
+	}
+
+	again, err := MigrateVault(context.Background(), workspace, MigrateVaultInput{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Committed || len(again.Changes) != 0 {
+		t.Fatalf("已是最新格式时不应再提交: %+v", again)
+	}
+}
+
+func TestPushRefusesNewerVaultFormat(t *testing.T) {
+	base := "---\ndescription: team\n---\nline 1\n"
+	projectRoot, remote, seed := setupPulledProjectForMerge(t, base)
+	writeFileProjectTest(t, filepath.Join(seed, types.VaultMetaFileName), "format: 99\n")
+	runGitProjectTest(t, seed, "add", "-A")
+	runGitProjectTest(t, seed, "commit", "-m", "future format")
+	runGitProjectTest(t, seed, "push", "origin", "main")
+
+	pulled, err := PullProjectAssets(context.Background(), projectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("格式更高的 vault 仍应可以拉取: %v", err)
+	}
+	if !strings.Contains(strings.Join(pulled.NonFatalWarnings, "\n"), "vault 格式为 99") {
+		t.Fatalf("pull 应提示 vault 格式更高: %v", pulled.NonFatalWarnings)
+	}
+
+	writeFileProjectTest(t, getCachePath(projectRoot, "combo", "rule", "team"), base+"mine\n")
+	if _, err := PushProjectAssets(context.Background(), projectRoot, nil); err == nil || !strings.Contains(err.Error(), "请先升级 Dec") {
+		t.Fatalf("向格式更高的 vault 推送应被拒绝，得到 %v", err)
+	}
+	if got := runGitNoDirProjectTest(t, "--git-dir", remote, "show", "main:bundles/combo/rules/team.mdc"); strings.Contains(got, "mine") {
+		t.Fatalf("远端不应收到改动: %q", got)
+	}
+	if _, err := MigrateVault(context.Background(), NewWorkspace(WorkspaceProject, projectRoot), MigrateVaultInput{DryRun: true}, nil); err == nil {
+		t.Fatal("旧版本不应迁移格式更高的 vault")
+	}
+}
+
+func TestInsertBundleScope(t *testing.T) {
+	cases := map[string]string{
+		"name: a\nmembers: []\n":              "name: a\nscope: project\nmembers: []\n",
+		"# head\nname: a":                     "# head\nname: a\nscope: project\n",
+		"name: a\nscope:\nmembers: []\n":      "name: a\nscope: project\nmembers: []\n",
+		"name: a\nscope: \"\"\nmembers: []\n": "name: a\nscope: project\nmembers: []\n",
+	}
+	for in, want := range cases {
+		got, ok := insertBundleScope(in, types.BundleScopeProject)
+		if !ok || got != want {
+			t.Errorf("insertBundleScope(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	if _, ok := insertBundleScope("members: []\n", types.BundleScopeProject); ok {
+		t.Error("没有 name 行时不应插入")
+	}
+}
@@ -33,6 +33,7 @@ type VaultProjectInference struct {
 	EnabledBundles []string
 	IDEs           []string
 	Editor         string
+	VaultBranch    string
 }
 
 // NeedsVaultProjectAutoApply 判断当前项目是否应尝试从 vault 匹配 project。
@@ -106,8 +107,20 @@ func InferVaultProject(projectRoot string, reporter Reporter) (*VaultProjectInfe
 		return nil, nil
 	}
 
+	mgr := config.NewProjectConfigManager(projectRoot)
+	var existingConfig *types.ProjectConfig
+	if mgr.Exists() {
+		loaded, loadErr := mgr.LoadProjectConfig()
+		if loadErr != nil {
+			emit(reporter, EventWarn, "project.infer", fmt.Sprintf("读取现有项目配置失败，继续按 vault project 推断：%v", loadErr), nil)
+		} else {
+			existingConfig = loaded
+		}
+	}
+
+	// projects/<name>.yaml 从已有配置（或全局）指定的分支读取；其中声明的 vault_branch 只影响之后的读取。
 	var vaultProject *types.Project
-	if err := withLocalReadRepoDir(func(repoDir string) error {
+	if err := withLocalReadRepoDir(emitLocalReadVaultBranch(existingConfig, reporter, "project.infer"), func(repoDir string) error {
 		loaded, found, loadErr := LoadVaultProject(repoDir, projectName)
 		if loadErr != nil {
 			return loadErr
@@ -125,17 +138,6 @@ func InferVaultProject(projectRoot string, reporter Reporter) (*VaultProjectInfe
 		return nil, nil
 	}
 
-	mgr := config.NewProjectConfigManager(projectRoot)
-	var existingConfig *types.ProjectConfig
-	if mgr.Exists() {
-		loaded, loadErr := mgr.LoadProjectConfig()
-		if loadErr != nil {
-			emit(reporter, EventWarn, "project.infer", fmt.Sprintf("读取现有项目配置失败，继续按 vault project 推断：%v", loadErr), nil)
-		} else {
-			existingConfig = loaded
-		}
-	}
-
 	enabledBundles := normalizeEnabledBundles(vaultProject.Bundles)
 	if existingConfig != nil && len(existingConfig.EnabledBundles) > 0 {
 		enabledBundles = append([]string(nil), existingConfig.EnabledBundles...)
@@ -152,6 +154,11 @@ func InferVaultProject(projectRoot string, reporter Reporter) (*VaultProjectInfe
 		}
 	}
 
+	vaultBranch := strings.TrimSpace(vaultProject.VaultBranch)
+	if existingConfig != nil && strings.TrimSpace(existingConfig.VaultBranch) != "" {
+		vaultBranch = strings.TrimSpace(existingConfig.VaultBranch)
+	}
+
 	return &VaultProjectInference{
 		ProjectRoot:    projectRoot,
 		ProjectName:    projectName,
@@ -159,6 +166,7 @@ func InferVaultProject(projectRoot string, reporter Reporter) (*VaultProjectInfe
 		EnabledBundles: enabledBundles,
 		IDEs:           projectIDEs,
 		Editor:         projectEditor,
+		VaultBranch:    vaultBranch,
 	}, nil
 }
 
@@ -193,8 +201,10 @@ func ApplyVaultProject(projectRoot string, reporter Reporter) (*VaultProjectAuto
 		}
 	}
 
+	// 与 InferVaultProject 一致，projects/<name>.yaml 与资产列表从已有配置（或全局）指定的分支读取。
+	readBranch := emitLocalReadVaultBranch(existingConfig, reporter, "project.apply")
 	var vaultProject *types.Project
-	if err := withLocalReadRepoDir(func(repoDir string) error {
+	if err := withLocalReadRepoDir(readBranch, func(repoDir string) error {
 		loaded, found, loadErr := LoadVaultProject(repoDir, projectName)
 		if loadErr != nil {
 			return loadErr
@@ -211,7 +221,7 @@ func ApplyVaultProject(projectRoot string, reporter Reporter) (*VaultProjectAuto
 		return result, nil
 	}
 
-	allAssets, err := ScanAvailableAssets(reporter)
+	allAssets, err := ScanAvailableAssets(readBranch, reporter)
 	if err != nil {
 		return nil, err
 	}
@@ -241,14 +251,20 @@ func ApplyVaultProject(projectRoot string, reporter Reporter) (*VaultProjectAuto
 		projectIDEs = append([]string(nil), vaultProject.IDEs...)
 	}
 
+	vaultBranch := inference.VaultBranch
+	if vaultBranch == "" {
+		vaultBranch = strings.TrimSpace(vaultProject.VaultBranch)
+	}
+
 	projectConfig := &types.ProjectConfig{
 		ProjectName:    projectName,
 		IDEs:           projectIDEs,
 		Editor:         projectEditor,
 		EnabledBundles: enabledBundles,
+		VaultBranch:    vaultBranch,
 	}
 
-	if err := withLocalReadRepoDir(func(repoDir string) error {
+	if err := withLocalReadRepoDir(emitLocalReadVaultBranch(projectConfig, reporter, "project.apply"), func(repoDir string) error {
 		_, bundleOverviews, scanErr := scanVaultBundles(repoDir, reporter)
 		if scanErr != nil {
 			return scanErr
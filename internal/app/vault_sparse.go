@@ -0,0 +1,77 @@
+package app
+
+import (
+	"path"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// vaultReadSelection 返回只读事务的物化范围：仓库根文件与 projects/、全部 bundle 声明、
+// 隐式 bundle（没有 bundle.yaml，成员靠目录内容推断）的整个目录，以及 fullBundles 的整个目录。
+//
+// 其余 bundle 只有 bundle.yaml 落盘，成员存在性由 assetFileExists 经 repo.PathExists 按提交索引判断。
+// bundle 名与 bundles/ 下的目录名一致（vault_vars 等处同样依赖这一约定）。
+func vaultReadSelection(fullBundles []string) func(files []string) []string {
+	full := make(map[string]struct{}, len(fullBundles))
+	for _, name := range fullBundles {
+		full[name] = struct{}{}
+	}
+	return func(files []string) []string {
+		declared := make(map[string]struct{})
+		for _, file := range files {
+			if name, rest, ok := splitVaultBundlePath(file); ok && rest == types.BundleManifestFileName {
+				declared[name] = struct{}{}
+			}
+		}
+		picked := make([]string, 0, len(files))
+		for _, file := range files {
+			name, rest, ok := splitVaultBundlePath(file)
+			if !ok {
+				if !strings.Contains(file, "/") || strings.HasPrefix(file, types.VaultProjectsDir+"/") {
+					picked = append(picked, file)
+				}
+				continue
+			}
+			_, isFull := full[name]
+			_, isDeclared := declared[name]
+			if isFull || !isDeclared || rest == types.BundleManifestFileName {
+				picked = append(picked, file)
+			}
+		}
+		return picked
+	}
+}
+
+// splitVaultBundlePath 把 bundles/<name>/<rest> 拆成 name 与 rest。
+func splitVaultBundlePath(file string) (name, rest string, ok bool) {
+	inner, found := strings.CutPrefix(file, types.VaultBundlesDir+"/")
+	if !found {
+		return "", "", false
+	}
+	name, rest, found = strings.Cut(inner, "/")
+	if !found || name == "" || path.Clean(rest) != rest {
+		return "", "", false
+	}
+	return name, rest, true
+}
+
+// newPullReadTransaction 打开拉取用的只读事务，只物化 projects/、bundle 声明与启用的 bundle。
+// version 非空时读取该版本，否则跟随 vaultBranch。
+func newPullReadTransaction(vaultBranch, version string, enabledBundles []string) (*repo.Transaction, error) {
+	return repo.NewReadTransactionWith(repo.ReadOptions{
+		Branch: vaultBranch,
+		Ref:    strings.TrimSpace(version),
+		Select: vaultReadSelection(enabledBundles),
+	})
+}
+
+// newManifestReadTransaction 打开不 fetch、只读 bundle 声明的只读事务，供概览 / Bundles 页扫描。
+func newManifestReadTransaction(vaultBranch string) (*repo.Transaction, error) {
+	return repo.NewReadTransactionWith(repo.ReadOptions{
+		Branch: vaultBranch,
+		Local:  true,
+		Select: vaultReadSelection(nil),
+	})
+}
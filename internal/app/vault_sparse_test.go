@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/config"
+	"github.com/shichao402/Dec/internal/repo"
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestVaultReadSelection_PicksProjectsManifestsAndEnabledBundles(t *testing.T) {
+	files := []string{
+		"README.md",
+		"docs/guide.md",
+		"projects/demo.yaml",
+		"bundles/a/bundle.yaml",
+		"bundles/a/skills/x/SKILL.md",
+		"bundles/b/bundle.yaml",
+		"bundles/b/rules/r.mdc",
+		"bundles/c/rules/implicit.mdc",
+	}
+	got := vaultReadSelection([]string{"a"})(files)
+	want := []string{
+		"README.md",
+		"projects/demo.yaml",
+		"bundles/a/bundle.yaml",
+		"bundles/a/skills/x/SKILL.md",
+		"bundles/b/bundle.yaml",
+		"bundles/c/rules/implicit.mdc",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("vaultReadSelection() = %v, want %v", got, want)
+	}
+}
+
+func TestManifestScansKeepMembersOfUnmaterializedBundles(t *testing.T) {
+	setEnvForProjectTest(t, "DEC_HOME", t.TempDir())
+	useStubSecretsSession(t)
+	remote := setupRemoteBareRepoProjectTest(t, map[string]string{
+		"bundles/a/rules/a-rule.mdc":    "---\ndescription: a\n---\n",
+		"bundles/a/bundle.yaml":         "name: a\nmembers:\n  - rule/a-rule\n",
+		"bundles/b/rules/b-rule.mdc":    "---\ndescription: b\n---\n",
+		"bundles/b/bundle.yaml":         "name: b\nmembers:\n  - rule/b-rule\n",
+		"bundles/c/rules/c-rule.mdc":    "---\ndescription: c\n---\n",
+		"bundles/d/bundle.yaml":         "name: d\nmembers:\n  - rule/gone\n",
+		"bundles/d/rules/d-rule.mdc":    "---\ndescription: d\n---\n",
+		"bundles/d/skills/big/SKILL.md": "---\nname: big\n---\n",
+	})
+	if err := repo.Connect(remote); err != nil {
+		t.Fatalf("repo.Connect() 失败: %v", err)
+	}
+	projectRoot := t.TempDir()
+	if err := config.NewProjectConfigManager(projectRoot).SaveProjectConfig(&types.ProjectConfig{
+		IDEs:           []string{"cursor"},
+		EnabledBundles: []string{"a"},
+	}); err != nil {
+		t.Fatalf("SaveProjectConfig() 失败: %v", err)
+	}
+
+	var events []OperationEvent
+	state, err := LoadAssetSelection(projectRoot, captureEvents(&events))
+	if err != nil {
+		t.Fatalf("LoadAssetSelection() 失败: %v", err)
+	}
+	members := map[string]int{}
+	for _, opt := range state.Bundles {
+		members[opt.Name] = len(opt.Members)
+	}
+	if members["a"] != 1 || members["b"] != 1 || members["c"] != 1 || members["d"] != 0 {
+		t.Fatalf("只读声明时成员判断应与完整检出一致: %v", members)
+	}
+	var missingWarnings []string
+	for _, e := range events {
+		if e.Level == EventWarn && strings.Contains(e.Message, "不存在") {
+			missingWarnings = append(missingWarnings, e.Message)
+		}
+	}
+	if len(missingWarnings) != 1 || !strings.Contains(missingWarnings[0], "rule/gone") {
+		t.Fatalf("只应对真正缺失的成员告警: %v", missingWarnings)
+	}
+
+	result, err := PullProjectAssets(context.Background(), projectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("PullProjectAssets() 失败: %v", err)
+	}
+	if result.FailedCount != 0 {
+		t.Fatalf("拉取不应失败: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(projectRoot, ".cursor", "rules", "dec-a-rule.mdc")); err != nil {
+		t.Fatalf("启用的 bundle 应安装: %v", err)
+	}
+}
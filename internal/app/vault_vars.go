@@ -0,0 +1,89 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/shichao402/Dec/internal/bundle"
+	"github.com/shichao402/Dec/internal/types"
+	"github.com/shichao402/Dec/internal/vars"
+)
+
+// vaultSharedVars 是 vault 中随版本共享的变量默认值：projects/<name>.yaml 的 vars
+// 与各已启用 bundle 的 bundles/<name>/vars.yaml。新机器 pull 即可拿到，无需重建本地 vars。
+// 渲染方式（bundle.yaml 的 templates）同样随 vault 共享，一并在这里读取。
+type vaultSharedVars struct {
+	Project map[string]string
+	// Bundles 按 bundle 短名索引；没有 vars.yaml 的 bundle 不出现
+	Bundles map[string]map[string]string
+	// BundleOrder 是读取时的 bundle 顺序（即 enabled_bundles 顺序），供 Project 页按序查找
+	BundleOrder []string
+	// Templates 是 bundle.yaml 的 templates 声明，键为 "<bundle>/<type>/<name>"
+	Templates map[string]bool
+}
+
+// loadVaultSharedVars 从 vault 工作区读取共享变量。文件缺失不算错误；解析失败只产生告警，
+// 因为共享默认值缺了最多是占位符缺失，不应阻断整次 pull。
+func loadVaultSharedVars(repoDir, projectName string, bundles []string) (*vaultSharedVars, []string) {
+	shared := &vaultSharedVars{Bundles: map[string]map[string]string{}, Templates: map[string]bool{}}
+	var warnings []string
+
+	if project, _, err := LoadVaultProject(repoDir, projectName); err != nil {
+		warnings = append(warnings, err.Error())
+	} else if project != nil && len(project.Vars) > 0 {
+		shared.Project = project.Vars
+	}
+
+	for _, name := range bundles {
+		path := filepath.Join(repoDir, filepath.FromSlash(types.VaultBundleVarsPath(name)))
+		cfg, err := vars.LoadVarsFile(path)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("读取 %s 失败: %v", types.VaultBundleVarsPath(name), err))
+			continue
+		}
+		if len(cfg.Vars) > 0 {
+			shared.Bundles[name] = cfg.Vars
+			shared.BundleOrder = append(shared.BundleOrder, name)
+		}
+
+		// bundle.yaml 解析失败已由 bundle 解析阶段告警，这里只取 templates 声明
+		manifest, _, err := bundle.LoadBundle(filepath.Join(repoDir, filepath.FromSlash(types.VaultBundleDir(name))), nil)
+		if err != nil {
+			continue
+		}
+		for _, ref := range manifest.Templates {
+			shared.Templates[name+"/"+ref] = true
+		}
+	}
+	return shared, warnings
+}
+
+// usesTemplate 判断 bundle.yaml 是否声明该资产走 text/template 渲染
+func (s *vaultSharedVars) usesTemplate(bundleName, assetType, assetName string) bool {
+	if s == nil {
+		return false
+	}
+	return s.Templates[bundleName+"/"+assetType+"/"+assetName]
+}
+
+// layers 返回某个 bundle 内资产可见的 vault 共享层；本地层与内置变量由调用方补上。
+func (s *vaultSharedVars) layers(bundleName string) vars.Layers {
+	if s == nil {
+		return vars.Layers{}
+	}
+	return vars.Layers{VaultProject: s.Project, VaultBundle: s.Bundles[bundleName]}
+}
+
+// lookupBundle 按 enabled_bundles 顺序返回第一个定义了 name 的 bundle 及其值。
+// Project 页没有单个资产上下文，只能给出项目视角下的代表值；pull 时按资产所属 bundle 精确解析。
+func (s *vaultSharedVars) lookupBundle(name string) (string, string, bool) {
+	if s == nil {
+		return "", "", false
+	}
+	for _, bundleName := range s.BundleOrder {
+		if v, ok := s.Bundles[bundleName][name]; ok {
+			return bundleName, v, true
+		}
+	}
+	return "", "", false
+}
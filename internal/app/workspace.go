@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/shichao402/Dec/internal/config"
@@ -74,6 +75,18 @@ func loadWorkspaceBundleConfig(workspace Workspace) (*types.ProjectConfig, error
 	return config.NewProjectConfigManager(workspace.Root).LoadProjectConfig()
 }
 
+// resolveVaultBranch 解析当前平面跟随的 vault 分支（空串表示远端默认分支），无法识别的配置作为告警发出。
+func resolveVaultBranch(projectConfig *types.ProjectConfig, reporter Reporter, scope string) (string, error) {
+	resolved, err := config.ResolveVaultBranch(projectConfig)
+	if err != nil {
+		return "", fmt.Errorf("解析 vault 分支失败: %w", err)
+	}
+	for _, warning := range resolved.Warnings {
+		emit(reporter, EventWarn, scope, warning, nil)
+	}
+	return resolved.Branch, nil
+}
+
 // removeWorkspaceEnabledBundle 从当前平面的启用列表中摘掉一个 bundle。
 // 返回是否发生变更。
 func removeWorkspaceEnabledBundle(workspace Workspace, bundleName string) (bool, error) {
@@ -204,6 +204,14 @@ func parseBundleYAML(data []byte, source string) (types.Bundle, error) {
 		return types.Bundle{}, fmt.Errorf("bundle 文件 %s 的 scope %q 非法，仅允许 user 或 project", source, bundle.Scope)
 	}
 
+	for i, raw := range bundle.Templates {
+		member, err := ParseMember(strings.TrimSpace(raw))
+		if err != nil {
+			return types.Bundle{}, fmt.Errorf("bundle 文件 %s 的 templates[%d]：%w", source, i, err)
+		}
+		bundle.Templates[i] = member.Type + "/" + member.Name
+	}
+
 	if len(bundle.Members) == 0 {
 		// ADR 0003：secrets-only / 本机启用占位允许 members: []。
 		return bundle, nil
@@ -94,6 +94,19 @@ func TestValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("templates 规范化", func(t *testing.T) {
+		b, err := Validate([]byte("name: good\nmembers:\n  - rules/style\ntemplates:\n  - rules/style\n"), "x.yaml")
+		if err != nil {
+			t.Fatalf("意外错误: %v", err)
+		}
+		if len(b.Templates) != 1 || b.Templates[0] != "rule/style" {
+			t.Fatalf("Templates = %#v", b.Templates)
+		}
+		if _, err := Validate([]byte("name: good\ntemplates:\n  - nope\n"), "x.yaml"); err == nil {
+			t.Fatal("非法 templates 引用应报错")
+		}
+	})
+
 	t.Run("YAML 解析失败", func(t *testing.T) {
 		if _, err := Validate([]byte("name: good\nmembers: [not-a-list"), "x.yaml"); err == nil {
 			t.Fatal("期望错误")
@@ -111,7 +111,7 @@ func SaveGlobalConfig(config *types.GlobalConfig) error {
 		return fmt.Errorf("序列化配置失败: %w", err)
 	}
 
-	header := "# Dec 全局配置\n# repo_url: 个人资产仓库地址\n# ides: 默认 IDE 列表，例如：\n#   ides:\n#     - cursor\n#     - codebuddy\n# editor: 交互式编辑器命令（如 vim / vi / code --wait），例如：\n#   editor: code --wait\n# server_idle_timeout: 最后一个门面断开后服务退出前的等待时长（如 30m、1h）\n# enabled_bundles: 用户平面启用的 bundle 短名（scope: user），例如：\n#   enabled_bundles:\n#     - tencent-cloud\n\n"
+	header := "# Dec 全局配置\n# repo_url: 个人资产仓库地址\n# ides: 默认 IDE 列表，例如：\n#   ides:\n#     - cursor\n#     - codebuddy\n# editor: 交互式编辑器命令（如 vim / vi / code --wait），例如：\n#   editor: code --wait\n# server_idle_timeout: 最后一个门面断开后服务退出前的等待时长（如 30m、1h）\n# install_mode: 资产落地方式：copy（默认，每个 IDE 一份副本）或 symlink（IDE 目录链接到 .dec/rendered/）\n# vault_branch: 默认跟随的 vault 分支（如 stable / next）；不填跟随远端默认分支，项目配置可覆盖\n# push_policy: 推送方式：direct（默认，直接推到 vault 分支）或 branch（推到 dec/<user>/<project>/<时间戳> 评审分支）\n# commit_template: push 提交说明模板（Go text/template），可用字段见 README；不填使用默认模板\n# enabled_bundles: 用户平面启用的 bundle 短名（scope: user），例如：\n#   enabled_bundles:\n#     - tencent-cloud\n\n"
 	if err := os.WriteFile(configPath, []byte(header+string(data)), 0644); err != nil {
 		return fmt.Errorf("写入全局配置失败: %w", err)
 	}
@@ -300,6 +300,257 @@ func GetEffectiveEditor(projectConfig *types.ProjectConfig) (string, error) {
 	return editor.DefaultCommand(), nil
 }
 
+// EffectiveInstallMode 是解析后的安装方式与配置里无法识别的取值告警。
+type EffectiveInstallMode struct {
+	Mode     string
+	Warnings []string
+}
+
+// ResolveInstallMode 获取有效的安装方式（项目级覆盖全局，默认 copy）。
+// projectConfig 为 nil 时只看全局配置，供用户平面使用。
+func ResolveInstallMode(projectConfig *types.ProjectConfig) (*EffectiveInstallMode, error) {
+	result := &EffectiveInstallMode{}
+	if projectConfig != nil {
+		if mode, ok := normalizeInstallMode(projectConfig.InstallMode); ok {
+			result.Mode = mode
+			return result, nil
+		} else if strings.TrimSpace(projectConfig.InstallMode) != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("项目配置中的 install_mode %q 无法识别，已忽略", projectConfig.InstallMode))
+		}
+	}
+
+	globalConfig, err := LoadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	if mode, ok := normalizeInstallMode(globalConfig.InstallMode); ok {
+		result.Mode = mode
+		return result, nil
+	} else if strings.TrimSpace(globalConfig.InstallMode) != "" {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("全局配置中的 install_mode %q 无法识别，已忽略", globalConfig.InstallMode))
+	}
+
+	result.Mode = types.InstallModeCopy
+	return result, nil
+}
+
+func normalizeInstallMode(raw string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case types.InstallModeCopy:
+		return types.InstallModeCopy, true
+	case types.InstallModeSymlink:
+		return types.InstallModeSymlink, true
+	default:
+		return "", false
+	}
+}
+
+// EffectivePushPolicy 是解析后的推送方式与配置里无法识别的取值告警。
+type EffectivePushPolicy struct {
+	Policy   string
+	Warnings []string
+}
+
+// ResolvePushPolicy 获取当前 vault 的推送方式（只看全局配置，与 repo_url 同级，默认 direct）。
+func ResolvePushPolicy() (*EffectivePushPolicy, error) {
+	globalConfig, err := LoadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	result := &EffectivePushPolicy{Policy: types.PushPolicyDirect}
+	switch strings.ToLower(strings.TrimSpace(globalConfig.PushPolicy)) {
+	case "", types.PushPolicyDirect:
+	case types.PushPolicyBranch:
+		result.Policy = types.PushPolicyBranch
+	default:
+		result.Warnings = append(result.Warnings, fmt.Sprintf("全局配置中的 push_policy %q 无法识别，已按 direct 处理", globalConfig.PushPolicy))
+	}
+	return result, nil
+}
+
+// ApplyRepoSettings 把全局配置中影响仓库操作的设置（git_backend、offline、commit_signing）同步给 repo 包。
+// 取值有误的项回落到默认值，原因作为告警返回。dec-server 与直接操作仓库的子命令（如后台
+// freshness 检查）启动时都要调用，否则这些进程会忽略用户选择的后端。
+func ApplyRepoSettings() []string {
+	globalConfig, err := LoadGlobalConfig()
+	if err != nil {
+		return []string{fmt.Sprintf("读取全局配置失败: %v", err)}
+	}
+	var warnings []string
+	if err := repo.SetBackendPreference(globalConfig.GitBackend); err != nil {
+		warnings = append(warnings, fmt.Sprintf("git backend: %v", err))
+		_ = repo.SetBackendPreference(repo.BackendAuto)
+	}
+	if err := repo.SetOfflineMode(globalConfig.Offline); err != nil {
+		warnings = append(warnings, fmt.Sprintf("offline: %v", err))
+		_ = repo.SetOfflineMode(repo.OfflineAuto)
+	}
+	signing, err := ResolveCommitSigning()
+	if err != nil {
+		return append(warnings, fmt.Sprintf("commit signing: %v", err))
+	}
+	for _, warning := range signing.Warnings {
+		warnings = append(warnings, "commit signing: "+warning)
+	}
+	if err := repo.SetCommitSigning(signing.Options); err != nil {
+		warnings = append(warnings, fmt.Sprintf("commit signing: %v", err))
+		_ = repo.SetCommitSigning(repo.SigningOptions{Mode: repo.SigningOff})
+	}
+	return warnings
+}
+
+// EffectiveCommitSigning 是解析后的提交签名方式与配置告警。
+type EffectiveCommitSigning struct {
+	Options  repo.SigningOptions
+	Warnings []string
+}
+
+// ResolveCommitSigning 获取 push 提交的签名方式（只看全局配置，默认不签名）。
+func ResolveCommitSigning() (*EffectiveCommitSigning, error) {
+	globalConfig, err := LoadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	result := &EffectiveCommitSigning{Options: repo.SigningOptions{Mode: repo.SigningOff}}
+	cfg := globalConfig.CommitSigning
+	mode, ok := repo.NormalizeSigningMode(cfg.Mode)
+	if !ok {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("全局配置中的 commit_signing.mode %q 无法识别，已按 off 处理", cfg.Mode))
+		return result, nil
+	}
+	key := strings.TrimSpace(cfg.Key)
+	if mode == repo.SigningSSH {
+		if key == "" {
+			result.Warnings = append(result.Warnings, "全局配置中的 commit_signing.mode 为 ssh 但没有配置 key，已按 off 处理")
+			return result, nil
+		}
+		key = expandHome(key)
+	}
+	result.Options = repo.SigningOptions{Mode: mode, Key: key}
+	return result, nil
+}
+
+// EffectiveCommitTrust 是解析后的签名信任策略。
+type EffectiveCommitTrust struct {
+	// Policy 取值见 types.CommitTrust*。
+	Policy  string
+	Signers *repo.TrustedSigners
+	// Anchor 见 types.CommitTrustConfig.Anchor。
+	Anchor   string
+	Warnings []string
+}
+
+// Enabled 判断 pull 是否需要校验签名。
+func (t *EffectiveCommitTrust) Enabled() bool {
+	return t != nil && t.Policy != types.CommitTrustOff
+}
+
+// ResolveCommitTrust 获取 repo_url 这个 vault 的签名信任策略（只看全局配置）。
+// policy 留空时配置了 signers 即为 warn；开启校验却没有可用的受信公钥时同样给出告警，所有签名都会被视为不受信任。
+func ResolveCommitTrust() (*EffectiveCommitTrust, error) {
+	globalConfig, err := LoadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg := globalConfig.CommitTrust
+	result := &EffectiveCommitTrust{Policy: types.CommitTrustOff}
+	switch strings.ToLower(strings.TrimSpace(cfg.Policy)) {
+	case "":
+		if len(cfg.Signers) > 0 {
+			result.Policy = types.CommitTrustWarn
+		}
+	case types.CommitTrustOff:
+	case types.CommitTrustWarn:
+		result.Policy = types.CommitTrustWarn
+	case types.CommitTrustEnforce:
+		result.Policy = types.CommitTrustEnforce
+	default:
+		result.Policy = types.CommitTrustWarn
+		result.Warnings = append(result.Warnings, fmt.Sprintf("全局配置中的 commit_trust.policy %q 无法识别，已按 warn 处理", cfg.Policy))
+	}
+	if result.Policy == types.CommitTrustOff {
+		return result, nil
+	}
+	signers, warnings := repo.ParseTrustedSigners(cfg.Signers)
+	result.Signers = signers
+	result.Anchor = strings.TrimSpace(cfg.Anchor)
+	result.Warnings = append(result.Warnings, warnings...)
+	if signers.Empty() {
+		result.Warnings = append(result.Warnings, "commit_trust 没有可用的受信签名者，所有提交都会被视为不受信任")
+	}
+	return result, nil
+}
+
+// expandHome 展开配置路径开头的 ~。
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// EffectiveVaultBranch 是解析后的 vault 分支与配置里无法识别的取值告警。
+type EffectiveVaultBranch struct {
+	// Branch 为空表示跟随远端默认分支。
+	Branch string
+	// Source 取值见 types.VaultBranchSource*。
+	Source   string
+	Warnings []string
+}
+
+// ResolveVaultBranch 获取有效的 vault 分支（项目级覆盖全局，默认跟随远端 HEAD）。
+// projectConfig 为 nil 时只看全局配置，供用户平面使用。
+func ResolveVaultBranch(projectConfig *types.ProjectConfig) (*EffectiveVaultBranch, error) {
+	result := &EffectiveVaultBranch{}
+	if projectConfig != nil {
+		if branch, ok := NormalizeVaultBranch(projectConfig.VaultBranch); ok && branch != "" {
+			result.Branch = branch
+			result.Source = types.VaultBranchSourceProject
+			return result, nil
+		} else if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("项目配置中的 vault_branch %q 不是合法分支名，已忽略", projectConfig.VaultBranch))
+		}
+	}
+
+	globalConfig, err := LoadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	if branch, ok := NormalizeVaultBranch(globalConfig.VaultBranch); ok && branch != "" {
+		result.Branch = branch
+		result.Source = types.VaultBranchSourceGlobal
+		return result, nil
+	} else if !ok {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("全局配置中的 vault_branch %q 不是合法分支名，已忽略", globalConfig.VaultBranch))
+	}
+
+	result.Source = types.VaultBranchSourceDefault
+	return result, nil
+}
+
+// NormalizeVaultBranch 规范化 vault_branch：去掉 refs/heads/ 前缀，空串合法（表示未配置）。
+// 只拒绝 git 明确不允许的写法，分支是否存在留给事务创建时检查。
+func NormalizeVaultBranch(raw string) (string, bool) {
+	branch := strings.TrimPrefix(strings.TrimSpace(raw), "refs/heads/")
+	if branch == "" {
+		return "", true
+	}
+	if strings.HasPrefix(branch, "-") || strings.HasPrefix(branch, "/") || strings.HasSuffix(branch, "/") ||
+		strings.HasSuffix(branch, ".") || strings.HasSuffix(branch, ".lock") ||
+		strings.Contains(branch, "..") || strings.Contains(branch, "//") || strings.Contains(branch, "@{") ||
+		strings.ContainsAny(branch, " \t~^:?*[\\") {
+		return "", false
+	}
+	for _, r := range branch {
+		if r < 0x20 || r == 0x7f {
+			return "", false
+		}
+	}
+	return branch, true
+}
+
 func getLegacyLocalConfigPath() (string, error) {
 	rootDir, err := repo.GetRootDir()
 	if err != nil {
@@ -390,6 +390,125 @@ func TestGetEffectiveEditor_PrefersProjectThenGlobalThenDefault(t *testing.T) {
 	}
 }
 
+func TestResolveInstallMode_PrefersProjectThenGlobalThenCopy(t *testing.T) {
+	decHome := t.TempDir()
+	setEnvForGlobalTest(t, "DEC_HOME", decHome)
+
+	got, err := ResolveInstallMode(&types.ProjectConfig{})
+	if err != nil {
+		t.Fatalf("ResolveInstallMode() 返回错误: %v", err)
+	}
+	if got.Mode != types.InstallModeCopy || len(got.Warnings) != 0 {
+		t.Fatalf("默认安装方式 = %+v, 期望 copy 且无告警", got)
+	}
+
+	if err := SaveGlobalConfig(&types.GlobalConfig{InstallMode: "Symlink"}); err != nil {
+		t.Fatalf("写入全局配置失败: %v", err)
+	}
+	got, err = ResolveInstallMode(nil)
+	if err != nil {
+		t.Fatalf("ResolveInstallMode() 返回错误: %v", err)
+	}
+	if got.Mode != types.InstallModeSymlink {
+		t.Fatalf("全局安装方式 = %q, 期望 symlink", got.Mode)
+	}
+
+	got, err = ResolveInstallMode(&types.ProjectConfig{InstallMode: "copy"})
+	if err != nil {
+		t.Fatalf("ResolveInstallMode() 返回错误: %v", err)
+	}
+	if got.Mode != types.InstallModeCopy {
+		t.Fatalf("项目覆盖安装方式 = %q, 期望 copy", got.Mode)
+	}
+
+	got, err = ResolveInstallMode(&types.ProjectConfig{InstallMode: "hardlink"})
+	if err != nil {
+		t.Fatalf("ResolveInstallMode() 返回错误: %v", err)
+	}
+	if got.Mode != types.InstallModeSymlink || len(got.Warnings) != 1 {
+		t.Fatalf("无法识别的项目取值应回退到全局并告警，得到 %+v", got)
+	}
+}
+
+func TestResolveVaultBranch_PrefersProjectThenGlobalThenRemoteHead(t *testing.T) {
+	decHome := t.TempDir()
+	setEnvForGlobalTest(t, "DEC_HOME", decHome)
+
+	got, err := ResolveVaultBranch(&types.ProjectConfig{})
+	if err != nil {
+		t.Fatalf("ResolveVaultBranch() 返回错误: %v", err)
+	}
+	if got.Branch != "" || got.Source != types.VaultBranchSourceDefault || len(got.Warnings) != 0 {
+		t.Fatalf("未配置时应跟随远端默认分支，得到 %+v", got)
+	}
+
+	if err := SaveGlobalConfig(&types.GlobalConfig{VaultBranch: "refs/heads/stable"}); err != nil {
+		t.Fatalf("写入全局配置失败: %v", err)
+	}
+	got, err = ResolveVaultBranch(nil)
+	if err != nil {
+		t.Fatalf("ResolveVaultBranch() 返回错误: %v", err)
+	}
+	if got.Branch != "stable" || got.Source != types.VaultBranchSourceGlobal {
+		t.Fatalf("全局分支 = %+v, 期望 stable / global", got)
+	}
+
+	got, err = ResolveVaultBranch(&types.ProjectConfig{VaultBranch: "next"})
+	if err != nil {
+		t.Fatalf("ResolveVaultBranch() 返回错误: %v", err)
+	}
+	if got.Branch != "next" || got.Source != types.VaultBranchSourceProject {
+		t.Fatalf("项目覆盖分支 = %+v, 期望 next / project", got)
+	}
+
+	got, err = ResolveVaultBranch(&types.ProjectConfig{VaultBranch: "bad..name"})
+	if err != nil {
+		t.Fatalf("ResolveVaultBranch() 返回错误: %v", err)
+	}
+	if got.Branch != "stable" || len(got.Warnings) != 1 {
+		t.Fatalf("非法的项目取值应回退到全局并告警，得到 %+v", got)
+	}
+}
+
+func TestResolvePushPolicy_DefaultsToDirect(t *testing.T) {
+	setEnvForGlobalTest(t, "DEC_HOME", t.TempDir())
+
+	got, err := ResolvePushPolicy()
+	if err != nil {
+		t.Fatalf("ResolvePushPolicy() 返回错误: %v", err)
+	}
+	if got.Policy != types.PushPolicyDirect || len(got.Warnings) != 0 {
+		t.Fatalf("未配置时应为 direct，得到 %+v", got)
+	}
+
+	if err := SaveGlobalConfig(&types.GlobalConfig{PushPolicy: " Branch "}); err != nil {
+		t.Fatalf("写入全局配置失败: %v", err)
+	}
+	if got, err = ResolvePushPolicy(); err != nil || got.Policy != types.PushPolicyBranch {
+		t.Fatalf("ResolvePushPolicy() = %+v, %v; 期望 branch", got, err)
+	}
+
+	if err := SaveGlobalConfig(&types.GlobalConfig{PushPolicy: "review"}); err != nil {
+		t.Fatalf("写入全局配置失败: %v", err)
+	}
+	if got, err = ResolvePushPolicy(); err != nil || got.Policy != types.PushPolicyDirect || len(got.Warnings) != 1 {
+		t.Fatalf("无法识别的取值应回退 direct 并告警，得到 %+v, %v", got, err)
+	}
+}
+
+func TestNormalizeVaultBranch(t *testing.T) {
+	for raw, want := range map[string]string{"": "", " stable ": "stable", "refs/heads/next": "next", "team/next": "team/next"} {
+		if got, ok := NormalizeVaultBranch(raw); !ok || got != want {
+			t.Errorf("NormalizeVaultBranch(%q) = %q, %v; 期望 %q", raw, got, ok, want)
+		}
+	}
+	for _, raw := range []string{"-x", "a b", "a..b", "x.lock", "x/", "a:b", "a@{1}"} {
+		if _, ok := NormalizeVaultBranch(raw); ok {
+			t.Errorf("NormalizeVaultBranch(%q) 应判为非法", raw)
+		}
+	}
+}
+
 func TestEnsureGlobalVarsTemplate_CreatesDefaultFile(t *testing.T) {
 	decHome := t.TempDir()
 	setEnvForGlobalTest(t, "DEC_HOME", decHome)
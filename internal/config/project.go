@@ -24,8 +24,9 @@ type ProjectConfigManager struct {
 
 const projectVarsTemplate = `# Dec 项目变量定义
 # 资产模板中的 {{VAR_NAME}} 会在 dec pull 时替换
-# 优先级（由高到低）：assets.<type>.<name>.vars > vars.yaml > vars.d/*.yaml > 机器级变量 (~/.dec/local/vars.yaml)
+# 优先级（由高到低）：assets.<type>.<name>.vars > 当前 profile > vars.yaml > vars.d/*.yaml > 机器级变量 (~/.dec/local/vars.yaml)
 #
+# profiles：可选的命名变量组，由 .dec/config.yaml 的 active_profile 选中一个（TUI Project 页按 v 切换）。
 # vars.d/ 目录：可选，放拆分的变量片段 *.yaml / *.yml，按文件名字典序合并，
 #   主文件 vars.yaml 会覆盖 vars.d/ 中的同名键。fragment 里的 assets: 字段会被忽略。
 
@@ -36,6 +37,14 @@ vars:
   # API_BASE_URL: "https://api.example.com"
   # API_TOKEN: "<TOKEN>"
 
+profiles:
+  # staging:
+  #   vars:
+  #     API_BASE_URL: "https://staging.example.com"
+  # prod:
+  #   vars:
+  #     API_BASE_URL: "https://api.example.com"
+
 assets:
   skill:
     # my-skill:
@@ -192,7 +201,7 @@ func (m *ProjectConfigManager) SaveProjectConfig(config *types.ProjectConfig) er
 		return fmt.Errorf("序列化项目配置失败: %w", err)
 	}
 
-	header := "# Dec 项目配置\n# version: 配置结构版本；当前固定为 v2\n# ides: 项目级 IDE 覆盖（可选），例如：\n#   ides:\n#     - cursor\n#     - codex\n# editor: 项目级交互式编辑器，覆盖全局配置（可选），例如：\n#   editor: code --wait\n#   editor: vim\n# enabled_bundles: 启用的 bundle 列表（唯一的资产启用入口）；bundle 名与 vault 目录同名\n#   enabled_bundles:\n#     - vikunja\n#     - cli\n# 提示：请在 TUI Bundles 页勾选后按 s 保存，不要手工维护本文件。\n\n"
+	header := "# Dec 项目配置\n# version: 配置结构版本；当前固定为 v2\n# ides: 项目级 IDE 覆盖（可选），例如：\n#   ides:\n#     - cursor\n#     - codex\n# editor: 项目级交互式编辑器，覆盖全局配置（可选），例如：\n#   editor: code --wait\n#   editor: vim\n# install_mode: 资产落地方式，覆盖全局配置（可选）：copy（默认）或 symlink\n# vault_branch: 跟随的 vault 分支，覆盖全局配置（可选），例如 stable；不填跟随远端默认分支\n# active_profile: 启用 .dec/vars.yaml 中的哪个 profile（可选；TUI Project 页按 v 切换）\n# enabled_bundles: 启用的 bundle 列表（唯一的资产启用入口）；bundle 名与 vault 目录同名\n#   enabled_bundles:\n#     - vikunja\n#     - cli\n# 提示：请在 TUI Bundles 页勾选后按 s 保存，不要手工维护本文件。\n\n"
 	configPath := filepath.Join(decDir, "config.yaml")
 	if err := os.WriteFile(configPath, []byte(header+string(data)), 0644); err != nil {
 		return fmt.Errorf("写入项目配置失败: %w", err)
@@ -235,8 +244,8 @@ func (m *ProjectConfigManager) EnsureVarsConfigTemplate() (bool, error) {
 //  1. .dec/vars.d/*.yaml 与 *.yml，按文件名字典序依次合并
 //  2. .dec/vars.yaml 主文件（权威最高，覆盖 vars.d/ 的值）
 //
-// 只合并顶层 `vars:` 字段；fragment 里的 `assets:` 会被忽略。
-// 最终返回的 VarsConfig.Assets 仅来自主文件。
+// 只合并顶层 `vars:` 字段；fragment 里的 `assets:` / `profiles:` 会被忽略。
+// 最终返回的 VarsConfig.Assets 与 Profiles 仅来自主文件。
 //
 // 任一 fragment 或主文件解析失败都会整体返回 error。
 // 主文件不存在但 vars.d 存在时仍会返回 fragment 合并结果。
@@ -276,7 +285,8 @@ func (m *ProjectConfigManager) LoadVarsConfig() (*types.VarsConfig, error) {
 	}
 
 	result := &types.VarsConfig{
-		Assets: main.Assets,
+		Assets:   main.Assets,
+		Profiles: main.Profiles,
 	}
 	if len(merged) > 0 {
 		result.Vars = merged
@@ -288,13 +298,40 @@ func (m *ProjectConfigManager) LoadVarsConfig() (*types.VarsConfig, error) {
 //
 // 返回合并后的 map[string]string（无片段或目录不存在时返回 nil, nil）。
 // 任一片段解析失败整体返回 error。
+func (m *ProjectConfigManager) loadVarsDirFragments() (map[string]string, error) {
+	fragments, err := m.LoadVarsFragments()
+	if err != nil {
+		return nil, err
+	}
+
+	var merged map[string]string
+	for _, fragment := range fragments {
+		if merged == nil {
+			merged = make(map[string]string, len(fragment.Vars))
+		}
+		for k, v := range fragment.Vars {
+			merged[k] = v
+		}
+	}
+	return merged, nil
+}
+
+// VarsFragment 是 .dec/vars.d/ 下单个片段文件的 vars 定义
+type VarsFragment struct {
+	Path string
+	Vars map[string]string
+}
+
+// LoadVarsFragments 按文件名字典序（即合并顺序，后者覆盖前者）逐个返回 vars.d 片段，
+// 供变量溯源指出具体文件；没有 vars 的片段不返回。
+//
 // fragment 里的 assets 字段会被忽略（只取 Vars）。
 //
 // 文件过滤规则：
 //   - 仅 *.yaml / *.yml 扩展名
 //   - 跳过目录
 //   - 跳过以 `.` 开头的隐藏文件
-func (m *ProjectConfigManager) loadVarsDirFragments() (map[string]string, error) {
+func (m *ProjectConfigManager) LoadVarsFragments() ([]VarsFragment, error) {
 	dir := m.GetVarsDir()
 	entries, err := os.ReadDir(dir)
 	if err != nil {
@@ -304,7 +341,7 @@ func (m *ProjectConfigManager) loadVarsDirFragments() (map[string]string, error)
 		return nil, fmt.Errorf("读取变量片段目录失败: %w", err)
 	}
 
-	var merged map[string]string
+	var fragments []VarsFragment
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -332,15 +369,10 @@ func (m *ProjectConfigManager) loadVarsDirFragments() (map[string]string, error)
 		if len(fragment.Vars) == 0 {
 			continue
 		}
-		if merged == nil {
-			merged = make(map[string]string, len(fragment.Vars))
-		}
-		for k, v := range fragment.Vars {
-			merged[k] = v
-		}
+		fragments = append(fragments, VarsFragment{Path: fragmentPath, Vars: fragment.Vars})
 	}
 
-	return merged, nil
+	return fragments, nil
 }
 
 func detectProjectConfigVersion(data []byte) (string, error) {
@@ -46,7 +46,7 @@ func StartBackgroundCheck(projectRoot string) {
 // 流程：
 //  1. DEC_FRESHNESS_CHECK=off → 静默返回
 //  2. 抢 lock；抢不到说明另一个后台 fetch 正在跑，直接返回
-//  3. 读 .dec/.version；没 pull 过的项目不写 cache
+//  3. 读 .dec/.version；没 pull 过或已固定（held）的项目不写 cache，记录了 vault 分支则对比该分支
 //  4. throttle 未过窗口 → 不做 fetch，不覆盖已有 cache
 //  5. 调 fetchRemoteHead，把结果（含错误）写进 cache
 //  6. RecordCheck 保持与旧同步路径一致的 throttle 语义
@@ -67,8 +67,9 @@ func RunBackgroundCheck(projectRoot string) error {
 	defer release()
 
 	meta, err := LoadVersionMeta(projectRoot)
-	if err != nil || meta == nil || meta.Commit == "" {
-		// 项目没 pull 过，写空 cache 反而会让 PreRun 读到 Stale=false 的噪声。
+	if err != nil || meta == nil || meta.Commit == "" || meta.Held {
+		// 项目没 pull 过，写空 cache 反而会让 PreRun 读到 Stale=false 的噪声；
+		// 固定在旧版本的项目不需要知道远端更新。
 		return nil
 	}
 
@@ -81,8 +82,9 @@ func RunBackgroundCheck(projectRoot string) error {
 		return nil
 	}
 
-	remote, fetchErr := fetchRemoteHead()
+	remote, fetchErr := fetchRemoteHead(meta.Branch)
 	cached := CachedResult{
+		Branch:       meta.Branch,
 		LocalCommit:  meta.Commit,
 		RemoteCommit: remote,
 		CheckedAt:    time.Now(),
@@ -101,7 +103,7 @@ func RunBackgroundCheck(projectRoot string) error {
 // EmitCachedHint 给调用方（通常是 PersistentPreRun）打印上一次后台检查的结论。
 //
 // 行为：
-//   - DEC_FRESHNESS_CHECK=off / w 为 nil / 无 cache / cache 过期 / 结果 fresh 都静默
+//   - DEC_FRESHNESS_CHECK=off / w 为 nil / 无 cache / cache 过期 / 结果 fresh / 项目已固定都静默
 //   - cache 中 Err 非空（上次 fetch 失败）也静默——不能假设项目落后了就打扰用户
 //   - stale → 复用 FormatHint/WriteHint 保持与旧同步路径一致的文案
 func EmitCachedHint(w io.Writer, projectRoot string) {
@@ -122,7 +124,12 @@ func EmitCachedHint(w io.Writer, projectRoot string) {
 	if !r.IsStale() {
 		return
 	}
+	if IsHeld(projectRoot) {
+		// cache 可能写于固定之前；固定期间一律不提示。
+		return
+	}
 	WriteHint(w, CheckResult{
+		Branch:       r.Branch,
 		LocalCommit:  r.LocalCommit,
 		RemoteCommit: r.RemoteCommit,
 		Stale:        true,
@@ -11,7 +11,7 @@ import (
 )
 
 // withFetchStub 在单测里替换掉 fetchRemoteHead 包级变量，用 t.Cleanup 复原。
-func withFetchStub(t *testing.T, stub func() (string, error)) {
+func withFetchStub(t *testing.T, stub func(string) (string, error)) {
 	t.Helper()
 	prev := fetchRemoteHead
 	fetchRemoteHead = stub
@@ -124,7 +124,7 @@ func TestRunBackgroundCheck_SkipsOnLockBusy(t *testing.T) {
 	defer release()
 
 	fetchCalled := false
-	withFetchStub(t, func() (string, error) {
+	withFetchStub(t, func(string) (string, error) {
 		fetchCalled = true
 		return "remote-commit", nil
 	})
@@ -160,7 +160,7 @@ func TestRunBackgroundCheck_SkipsOnThrottle(t *testing.T) {
 	}
 
 	fetchCalled := false
-	withFetchStub(t, func() (string, error) {
+	withFetchStub(t, func(string) (string, error) {
 		fetchCalled = true
 		return "remote-commit", nil
 	})
@@ -183,7 +183,7 @@ func TestRunBackgroundCheck_WritesCacheOnFetch(t *testing.T) {
 	project := t.TempDir()
 	seedVersionFile(t, project, "abc1234")
 
-	withFetchStub(t, func() (string, error) {
+	withFetchStub(t, func(string) (string, error) {
 		return "def5678", nil
 	})
 
@@ -214,12 +214,50 @@ func TestRunBackgroundCheck_WritesCacheOnFetch(t *testing.T) {
 	}
 }
 
+func TestRunBackgroundCheck_FollowsRecordedBranch(t *testing.T) {
+	t.Setenv("DEC_HOME", t.TempDir())
+	project := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(project, ".dec"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "commit: abc1234\npulled_at: \"2026-04-30T00:00:00Z\"\nbranch: stable\n"
+	if err := os.WriteFile(filepath.Join(project, ".dec", ".version"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fetchedBranch string
+	withFetchStub(t, func(branch string) (string, error) {
+		fetchedBranch = branch
+		return "def5678", nil
+	})
+
+	if err := RunBackgroundCheck(project); err != nil {
+		t.Fatalf("RunBackgroundCheck: %v", err)
+	}
+	if fetchedBranch != "stable" {
+		t.Fatalf("fetch branch = %q, want stable", fetchedBranch)
+	}
+	got, err := ReadCachedResult(project)
+	if err != nil || got == nil {
+		t.Fatalf("ReadCachedResult = %v, %v", got, err)
+	}
+	if got.Branch != "stable" {
+		t.Errorf("cached Branch = %q, want stable", got.Branch)
+	}
+
+	var buf bytes.Buffer
+	EmitCachedHint(&buf, project)
+	if !strings.Contains(buf.String(), "远端 stable") {
+		t.Errorf("hint should name the branch, got %q", buf.String())
+	}
+}
+
 func TestRunBackgroundCheck_RecordsFetchError(t *testing.T) {
 	t.Setenv("DEC_HOME", t.TempDir())
 	project := t.TempDir()
 	seedVersionFile(t, project, "abc1234")
 
-	withFetchStub(t, func() (string, error) {
+	withFetchStub(t, func(string) (string, error) {
 		return "", errors.New("boom")
 	})
 
@@ -251,7 +289,7 @@ func TestRunBackgroundCheck_DisabledIsNoop(t *testing.T) {
 	seedVersionFile(t, project, "abc")
 
 	fetchCalled := false
-	withFetchStub(t, func() (string, error) {
+	withFetchStub(t, func(string) (string, error) {
 		fetchCalled = true
 		return "def", nil
 	})
@@ -270,7 +308,7 @@ func TestRunBackgroundCheck_SkipsWhenNotPulled(t *testing.T) {
 	// 故意不 seedVersionFile：项目没 pull 过
 
 	fetchCalled := false
-	withFetchStub(t, func() (string, error) {
+	withFetchStub(t, func(string) (string, error) {
 		fetchCalled = true
 		return "", nil
 	})
@@ -286,3 +324,44 @@ func TestRunBackgroundCheck_SkipsWhenNotPulled(t *testing.T) {
 		t.Errorf("should not write cache for un-pulled project, got %v", err)
 	}
 }
+
+func TestRunBackgroundCheck_SkipsWhenHeld(t *testing.T) {
+	t.Setenv("DEC_HOME", t.TempDir())
+	project := t.TempDir()
+	seedVersionFile(t, project, "aaaaaaaaaa")
+	versionPath := filepath.Join(project, ".dec", ".version")
+	f, err := os.OpenFile(versionPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("held: true\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	fetchCalled := false
+	withFetchStub(t, func(string) (string, error) {
+		fetchCalled = true
+		return "bbbbbbbbbb", nil
+	})
+	if err := RunBackgroundCheck(project); err != nil {
+		t.Fatal(err)
+	}
+	if fetchCalled {
+		t.Error("held projects should not trigger fetch")
+	}
+
+	// 固定之前写下的 stale cache 也不应再提示。
+	if err := WriteCachedResult(project, CachedResult{
+		LocalCommit:  "aaaaaaaaaa",
+		RemoteCommit: "bbbbbbbbbb",
+		CheckedAt:    time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	EmitCachedHint(&buf, project)
+	if buf.Len() != 0 {
+		t.Errorf("held project should be silent, got %q", buf.String())
+	}
+}
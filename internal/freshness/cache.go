@@ -12,6 +12,7 @@ import (
 //
 // 主命令 PreRun 只读不写；写入发生在 `dec __freshness-check` 子进程里。
 type CachedResult struct {
+	Branch       string    `json:"branch,omitempty"`
 	LocalCommit  string    `json:"local_commit"`
 	RemoteCommit string    `json:"remote_commit"`
 	CheckedAt    time.Time `json:"checked_at"`
@@ -15,8 +15,10 @@ type CheckResult struct {
 	Skipped bool
 	// LocalCommit 来自 .dec/.version，可能为空（项目还没 pull）。
 	LocalCommit string
-	// RemoteCommit 来自 git fetch 后 bare repo 的默认分支。
+	// RemoteCommit 来自 git fetch 后 bare repo 中 Branch 指向的提交。
 	RemoteCommit string
+	// Branch 是 .dec/.version 记录的 vault 分支，空串表示远端默认分支。
+	Branch string
 	// Stale 为 true 表示 LocalCommit != RemoteCommit 且两者都非空。
 	Stale bool
 	// Err 记录首个阻断性错误，但调用方通常应把它当作“沉默略过”信号。
@@ -72,6 +74,10 @@ func Check(ctx context.Context, projectRoot string) CheckResult {
 		// 项目没有 .dec/.version 或读不出来，不属于“过时”的范畴。
 		return CheckResult{Skipped: true, Err: err}
 	}
+	if meta.Held {
+		// 用户有意固定在旧版本，落后远端是预期状态。
+		return CheckResult{Skipped: true, LocalCommit: meta.Commit}
+	}
 
 	stateFile, err := StateFilePath(projectRoot)
 	if err != nil {
@@ -89,7 +95,7 @@ func Check(ctx context.Context, projectRoot string) CheckResult {
 	}
 	ch := make(chan fetchResult, 1)
 	go func() {
-		h, e := FetchRemoteHead()
+		h, e := FetchRemoteHead(meta.Branch)
 		ch <- fetchResult{h, e}
 	}()
 
@@ -104,6 +110,7 @@ func Check(ctx context.Context, projectRoot string) CheckResult {
 		}
 		stale := r.hash != "" && r.hash != meta.Commit
 		return CheckResult{
+			Branch:       meta.Branch,
 			LocalCommit:  meta.Commit,
 			RemoteCommit: r.hash,
 			Stale:        stale,
@@ -116,8 +123,12 @@ func FormatHint(result CheckResult) string {
 	if !result.Stale {
 		return ""
 	}
-	return fmt.Sprintf("💡 当前项目的 Dec 资产已落后远端（本地 %s，远端 %s）。在 TUI Run 页拉取可更新。",
-		ShortHash(result.LocalCommit), ShortHash(result.RemoteCommit))
+	remote := "远端"
+	if result.Branch != "" {
+		remote = "远端 " + result.Branch
+	}
+	return fmt.Sprintf("💡 当前项目的 Dec 资产已落后%s（本地 %s，远端 %s）。在 TUI Run 页拉取可更新。",
+		remote, ShortHash(result.LocalCommit), ShortHash(result.RemoteCommit))
 }
 
 // WriteHint 若检测到过时则把提示写入 w。w 通常是 stderr。
@@ -1,10 +1,12 @@
 // Package freshness 为 dec CLI 提供项目资产陈旧度检测。
 //
 // 它只做三件事：
-//   1. 读取项目的 .dec/.version 里上次 pull 时固化的 commit hash
-//   2. 从本地 bare repo 拉取最新远端 HEAD commit hash
+//   1. 读取项目的 .dec/.version 里上次 pull 时固化的 commit hash 与 vault 分支
+//   2. 从本地 bare repo 拉取该分支（未记录时为远端默认分支）的最新 commit hash
 //   3. 用 ~/.dec/local/last-freshness-check.<hash> 的 mtime 做节流
 //
+// .dec/.version 标了 held（回滚到历史拉取后固定）时三件事都不做，直到解除固定。
+//
 // 该包不会触发 dec pull、不改任何文件（除了 touch 节流文件），
 // 也不会把错误冒泡到命令主流程——所有内部错误只代表“本次不提示”。
 package freshness
@@ -19,7 +21,6 @@ import (
 	"time"
 
 	"github.com/shichao402/Dec/internal/repo"
-	"github.com/shichao402/Dec/internal/sysproc"
 )
 
 // DefaultInterval 默认节流窗口。
@@ -29,6 +30,10 @@ const DefaultInterval = 24 * time.Hour
 type VersionMeta struct {
 	Commit   string
 	PulledAt string
+	// Branch 是 pull 时跟随的 vault 分支；旧版本写的文件没有该字段，视为远端默认分支。
+	Branch string
+	// Held 为 true 表示项目被有意固定在 Commit（回滚到历史拉取），不再提示落后远端。
+	Held bool
 }
 
 // LoadVersionMeta 读取 .dec/.version。
@@ -62,17 +67,27 @@ func LoadVersionMeta(projectRoot string) (*VersionMeta, error) {
 			meta.Commit = val
 		case "pulled_at":
 			meta.PulledAt = val
+		case "branch":
+			meta.Branch = val
+		case "held":
+			meta.Held = val == "true"
 		}
 	}
 	return meta, nil
 }
 
-// FetchRemoteHead 获取远端默认分支的最新 commit hash。
+// IsHeld 判断项目是否被固定在某次拉取；读不到 .dec/.version 时视为未固定。
+func IsHeld(projectRoot string) bool {
+	meta, err := LoadVersionMeta(projectRoot)
+	return err == nil && meta != nil && meta.Held
+}
+
+// FetchRemoteHead 获取远端 branch 分支的最新 commit hash，branch 为空时取远端默认分支。
 //
 // 会阻塞于 git fetch（走 bare repo），调用方必须给出超时 ctx 或在 goroutine 中使用。
 // 任何错误（未连接仓库、网络不可达、分支找不到）都返回空字符串 + error，
 // 调用方据此决定是否沉默略过。
-func FetchRemoteHead() (string, error) {
+func FetchRemoteHead(branch string) (string, error) {
 	connected, err := repo.IsBareConnected()
 	if err != nil {
 		return "", err
@@ -85,22 +100,13 @@ func FetchRemoteHead() (string, error) {
 		return "", err
 	}
 
-	branch, err := repo.GetDefaultBranch()
-	if err != nil {
-		return "", err
-	}
-
-	bareDir, err := repo.GetBareRepoDir()
-	if err != nil {
-		return "", err
-	}
-
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "rev-parse", fmt.Sprintf("refs/heads/%s", branch))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("读取 refs/heads/%s 失败: %s", branch, strings.TrimSpace(string(output)))
+	if strings.TrimSpace(branch) == "" {
+		branch, err = repo.GetDefaultBranch()
+		if err != nil {
+			return "", err
+		}
 	}
-	return strings.TrimSpace(string(output)), nil
+	return repo.BareBranchHead(branch)
 }
 
 // StateFilePath 为给定项目根目录返回其专属节流状态文件路径。
@@ -45,6 +45,24 @@ func TestLoadVersionMeta(t *testing.T) {
 		}
 	})
 
+	t.Run("parses held flag", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, ".dec"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "commit: abcdef1234567890\npulled_at: \"2026-04-30T09:49:49+08:00\"\nheld: true\n"
+		if err := os.WriteFile(filepath.Join(dir, ".dec", ".version"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		meta, err := LoadVersionMeta(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if meta == nil || !meta.Held || !IsHeld(dir) {
+			t.Fatalf("expected held meta, got %+v", meta)
+		}
+	})
+
 	t.Run("malformed file gives empty commit but no error", func(t *testing.T) {
 		dir := t.TempDir()
 		if err := os.MkdirAll(filepath.Join(dir, ".dec"), 0755); err != nil {
@@ -0,0 +1,253 @@
+package ide
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// vault MCP 片段里的环境变量引用采用统一写法（规范语法）：
+//
+//	${VAR}            引用 IDE 启动 MCP 进程时的环境变量
+//	${VAR:-default}   变量为空时取默认值
+//	${input:id}       VS Code 的交互输入（只有 VS Code 能表达）
+//
+// ${env:VAR} 作为 ${VAR} 的别名接受；${workspaceFolder} 不是环境变量引用，原样保留。
+// 写入 IDE 前由 TranslateEnvRefs 转成该 IDE 的原生写法，无法表达的引用原样保留并报告。
+var envRefRe = regexp.MustCompile(`\$\{([^{}]*)\}`)
+
+var envRefNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envRefStyle 区分各 IDE 对 MCP 配置中环境变量引用的展开方式。
+type envRefStyle int
+
+const (
+	// envRefShell：${VAR} 与 ${VAR:-default}（Claude、CodeBuddy）。
+	envRefShell envRefStyle = iota
+	// envRefEnvPrefix：${env:VAR}，不支持默认值与 ${input:...}（Cursor）。
+	envRefEnvPrefix
+	// envRefEnvPrefixInputs：${env:VAR} 与 ${input:id}，不支持默认值（VS Code）。
+	envRefEnvPrefixInputs
+)
+
+// EnvRefIssue 描述一个目标 IDE 无法表达的环境变量引用。
+type EnvRefIssue struct {
+	// Field 是引用所在字段，如 args[1]、env.DATABASE_URL、url。
+	Field  string
+	Ref    string
+	Reason string
+}
+
+func (i EnvRefIssue) String() string {
+	return fmt.Sprintf("%s 中的 %s %s", i.Field, i.Ref, i.Reason)
+}
+
+// envRef 是一次解析出的规范引用。
+type envRef struct {
+	raw        string
+	name       string
+	input      bool
+	defaultVal string
+	hasDefault bool
+}
+
+// parseEnvRef 解析 ${...} 内部；workspaceFolder 与无法识别的内容返回 false，调用方原样保留。
+func parseEnvRef(raw, inner string) (envRef, bool) {
+	ref := envRef{raw: raw}
+	switch {
+	case inner == "workspaceFolder":
+		return ref, false
+	case strings.HasPrefix(inner, "input:"):
+		ref.name = strings.TrimPrefix(inner, "input:")
+		ref.input = true
+		return ref, ref.name != ""
+	case strings.HasPrefix(inner, "env:"):
+		inner = strings.TrimPrefix(inner, "env:")
+	}
+	if name, def, ok := strings.Cut(inner, ":-"); ok {
+		ref.name, ref.defaultVal, ref.hasDefault = name, def, true
+	} else {
+		ref.name = inner
+	}
+	return ref, envRefNameRe.MatchString(ref.name)
+}
+
+// HasEnvRefs 判断字符串里是否含有规范环境变量引用（不含 ${workspaceFolder}）。
+func HasEnvRefs(value string) bool {
+	for _, match := range envRefRe.FindAllStringSubmatch(value, -1) {
+		if _, ok := parseEnvRef(match[0], match[1]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInheritedEnvRef 判断 env 条目是否只是透传同名变量（K = "${K}" 或 "${env:K}"）。
+func IsInheritedEnvRef(key, value string) bool {
+	value = strings.TrimSpace(value)
+	match := envRefRe.FindStringSubmatch(value)
+	if match == nil || match[0] != value {
+		return false
+	}
+	ref, ok := parseEnvRef(match[0], match[1])
+	return ok && !ref.input && !ref.hasDefault && ref.name == key
+}
+
+// HasInputRefs 判断字符串里是否含有 ${input:id}。
+func HasInputRefs(value string) bool {
+	for _, match := range envRefRe.FindAllStringSubmatch(value, -1) {
+		if ref, ok := parseEnvRef(match[0], match[1]); ok && ref.input {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandEnvRefs 按 lookup 展开规范引用：${VAR} / ${env:VAR} 取变量值，${VAR:-default} 在变量为空时取默认值；
+// ${input:id}、${workspaceFolder} 与无法识别的内容原样保留。供 dec-exec 在注入 secrets 后展开 env。
+func ExpandEnvRefs(value string, lookup func(name string) (string, bool)) string {
+	return envRefRe.ReplaceAllStringFunc(value, func(raw string) string {
+		ref, ok := parseEnvRef(raw, raw[2:len(raw)-1])
+		if !ok || ref.input {
+			return raw
+		}
+		v, _ := lookup(ref.name)
+		if v == "" && ref.hasDefault {
+			return ref.defaultVal
+		}
+		return v
+	})
+}
+
+// TranslateEnvRefs 把 server 中的规范引用转成该 IDE 的原生写法。
+func (b *baseIDE) TranslateEnvRefs(server types.MCPServer) (types.MCPServer, []EnvRefIssue) {
+	var issues []EnvRefIssue
+	translate := func(field, value string) string {
+		out, fieldIssues := translateEnvRefString(field, value, b.envRefStyle)
+		issues = append(issues, fieldIssues...)
+		return out
+	}
+	server = translateMCPServerStrings(server, translate, true)
+	return server, issues
+}
+
+// TranslateEnvRefs 对 Codex：config.toml 不做变量展开，只能通过 env_vars 把同名变量从 Codex 环境透传给 MCP 进程。
+// 因此只有 env.K = "${K}" 可以表达（转成 env_vars），其它位置的引用原样保留并报告。
+func (c *codexIDE) TranslateEnvRefs(server types.MCPServer) (types.MCPServer, []EnvRefIssue) {
+	var issues []EnvRefIssue
+	report := func(field, value string) string {
+		for _, match := range envRefRe.FindAllStringSubmatch(value, -1) {
+			if _, ok := parseEnvRef(match[0], match[1]); ok {
+				issues = append(issues, EnvRefIssue{Field: field, Ref: match[0], Reason: "无法在 Codex 中表达（Codex 只支持通过 env_vars 透传同名变量）"})
+			}
+		}
+		return value
+	}
+	server = translateMCPServerStrings(server, report, false)
+
+	if len(server.Env) > 0 {
+		env := make(map[string]string, len(server.Env))
+		forwarded := append([]string(nil), server.EnvVars...)
+		for _, key := range sortedMapKeys(server.Env) {
+			value := server.Env[key]
+			matches := envRefRe.FindAllStringSubmatch(value, -1)
+			if len(matches) == 1 && matches[0][0] == strings.TrimSpace(value) {
+				if ref, ok := parseEnvRef(matches[0][0], matches[0][1]); ok && !ref.input && !ref.hasDefault && ref.name == key {
+					if !containsName(forwarded, key) {
+						forwarded = append(forwarded, key)
+					}
+					continue
+				}
+			}
+			env[key] = report("env."+key, value)
+		}
+		server.Env = env
+		if len(server.Env) == 0 {
+			server.Env = nil
+		}
+		server.EnvVars = forwarded
+	}
+	return server, issues
+}
+
+// translateMCPServerStrings 对 server 中可能出现引用的字符串字段逐个调用 fn。
+// includeEnv 为 false 时跳过 env，由调用方自行处理。
+func translateMCPServerStrings(server types.MCPServer, fn func(field, value string) string, includeEnv bool) types.MCPServer {
+	server.Command = fn("command", server.Command)
+	if len(server.Args) > 0 {
+		args := make([]string, len(server.Args))
+		for i, arg := range server.Args {
+			args[i] = fn(fmt.Sprintf("args[%d]", i), arg)
+		}
+		server.Args = args
+	}
+	server.Cwd = fn("cwd", server.Cwd)
+	server.URL = fn("url", server.URL)
+	translateMap := func(prefix string, values map[string]string) map[string]string {
+		if len(values) == 0 {
+			return values
+		}
+		out := make(map[string]string, len(values))
+		for _, key := range sortedMapKeys(values) {
+			out[key] = fn(prefix+key, values[key])
+		}
+		return out
+	}
+	if includeEnv {
+		server.Env = translateMap("env.", server.Env)
+	}
+	server.Headers = translateMap("headers.", server.Headers)
+	server.HTTPHeaders = translateMap("http_headers.", server.HTTPHeaders)
+	return server
+}
+
+func translateEnvRefString(field, value string, style envRefStyle) (string, []EnvRefIssue) {
+	var issues []EnvRefIssue
+	out := envRefRe.ReplaceAllStringFunc(value, func(raw string) string {
+		ref, ok := parseEnvRef(raw, raw[2:len(raw)-1])
+		if !ok {
+			return raw
+		}
+		if ref.input {
+			if style == envRefEnvPrefixInputs {
+				return "${input:" + ref.name + "}"
+			}
+			issues = append(issues, EnvRefIssue{Field: field, Ref: raw, Reason: "是 VS Code 专有的交互输入，该 IDE 无法表达"})
+			return raw
+		}
+		switch style {
+		case envRefEnvPrefix, envRefEnvPrefixInputs:
+			if ref.hasDefault {
+				issues = append(issues, EnvRefIssue{Field: field, Ref: raw, Reason: "带默认值，该 IDE 不支持默认值，已按无默认值写入"})
+			}
+			return "${env:" + ref.name + "}"
+		default:
+			if ref.hasDefault {
+				return "${" + ref.name + ":-" + ref.defaultVal + "}"
+			}
+			return "${" + ref.name + "}"
+		}
+	})
+	return out, issues
+}
+
+func sortedMapKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsName(names []string, name string) bool {
+	for _, item := range names {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package ide
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestTranslateEnvRefsPerIDE(t *testing.T) {
+	server := types.MCPServer{
+		Command: "db-mcp",
+		Args:    []string{"--dsn", "${DATABASE_URL}", "--schema=${DB_SCHEMA:-public}", "--root", "${workspaceFolder}", "--key", "${input:apiKey}"},
+	}
+	tests := []struct {
+		ide    string
+		args   []string
+		issues int
+	}{
+		{"claude", []string{"--dsn", "${DATABASE_URL}", "--schema=${DB_SCHEMA:-public}", "--root", "${workspaceFolder}", "--key", "${input:apiKey}"}, 1},
+		{"cursor", []string{"--dsn", "${env:DATABASE_URL}", "--schema=${env:DB_SCHEMA}", "--root", "${workspaceFolder}", "--key", "${input:apiKey}"}, 2},
+		{"vscode", []string{"--dsn", "${env:DATABASE_URL}", "--schema=${env:DB_SCHEMA}", "--root", "${workspaceFolder}", "--key", "${input:apiKey}"}, 1},
+		{"codex", server.Args, 3},
+	}
+	for _, tt := range tests {
+		got, issues := Get(tt.ide).TranslateEnvRefs(server)
+		if !reflect.DeepEqual(got.Args, tt.args) {
+			t.Fatalf("%s args = %#v, 期望 %#v", tt.ide, got.Args, tt.args)
+		}
+		if len(issues) != tt.issues {
+			t.Fatalf("%s 应报告 %d 个无法表达的引用, got %#v", tt.ide, tt.issues, issues)
+		}
+	}
+
+	// ${env:VAR} 是 ${VAR} 的别名。
+	got, _ := Get("claude").TranslateEnvRefs(types.MCPServer{URL: "https://h/${env:TENANT}"})
+	if got.URL != "https://h/${TENANT}" {
+		t.Fatalf("claude url = %q", got.URL)
+	}
+}
+
+func TestCodexTranslateEnvRefsForwardsSameNameEnv(t *testing.T) {
+	got, issues := Get("codex").TranslateEnvRefs(types.MCPServer{
+		Command: "db-mcp",
+		Env: map[string]string{
+			"DATABASE_URL": "${DATABASE_URL}",
+			"DB_ALIAS":     "${DATABASE_URL}",
+			"LOG_LEVEL":    "debug",
+		},
+	})
+	if !reflect.DeepEqual(got.EnvVars, []string{"DATABASE_URL"}) {
+		t.Fatalf("同名引用应转成 env_vars: %#v", got.EnvVars)
+	}
+	if _, ok := got.Env["DATABASE_URL"]; ok || got.Env["LOG_LEVEL"] != "debug" || got.Env["DB_ALIAS"] != "${DATABASE_URL}" {
+		t.Fatalf("env = %#v", got.Env)
+	}
+	if len(issues) != 1 || issues[0].Field != "env.DB_ALIAS" || !strings.Contains(issues[0].Reason, "Codex") {
+		t.Fatalf("改名引用应报告为无法表达: %#v", issues)
+	}
+}
@@ -0,0 +1,105 @@
+package ide
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// ExternalMCPSource 是一个可供导入的用户级 MCP 配置文件。
+//
+// 包括已注册 IDE 的用户平面配置（由各自的 LoadMCPConfigForPlane 读取），以及 Dec 不向其安装、
+// 只读取的客户端配置：Claude Desktop、Claude Code 的 ~/.claude.json。
+type ExternalMCPSource struct {
+	// Name 是来源标识，如 cursor、claude-desktop、vscode。
+	Name string
+	Path string
+	// IDE 非空时表示该来源对应一个已注册 IDE 的用户平面，应走该 IDE 的加载器。
+	IDE string
+}
+
+// ExternalMCPSources 列出 homeDir 下已知的用户级 MCP 配置来源（不检查文件是否存在），按路径去重。
+func ExternalMCPSources(homeDir string) []ExternalMCPSource {
+	var sources []ExternalMCPSource
+	seen := make(map[string]struct{})
+	add := func(source ExternalMCPSource) {
+		key := filepath.Clean(source.Path)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		sources = append(sources, source)
+	}
+
+	names := List()
+	sort.Strings(names)
+	for _, name := range names {
+		add(ExternalMCPSource{Name: name, Path: Get(name).MCPConfigPathForPlane(PlaneUser, "", homeDir), IDE: name})
+	}
+	configDir := userConfigDir(homeDir)
+	add(ExternalMCPSource{Name: "claude-desktop", Path: filepath.Join(configDir, "Claude", "claude_desktop_config.json")})
+	add(ExternalMCPSource{Name: "claude-code", Path: filepath.Join(homeDir, ".claude.json")})
+	return sources
+}
+
+// LoadExternalMCPSource 读取一个来源；文件不存在时返回空配置。
+func LoadExternalMCPSource(source ExternalMCPSource, homeDir string) (*types.MCPConfig, error) {
+	if source.IDE != "" {
+		return Get(source.IDE).LoadMCPConfigForPlane(PlaneUser, "", homeDir)
+	}
+	data, err := os.ReadFile(source.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &types.MCPConfig{MCPServers: make(map[string]types.MCPServer)}, nil
+		}
+		return nil, err
+	}
+	return ParseExternalMCPConfig(source.Path, data)
+}
+
+// ParseExternalMCPConfig 解析外部客户端的 MCP 配置。
+// .toml 按 Codex 格式解析；JSON 同时接受 mcpServers（Claude / Cursor）与 servers（VS Code）两种顶层键。
+func ParseExternalMCPConfig(path string, data []byte) (*types.MCPConfig, error) {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return parseCodexMCPConfig(data)
+	}
+	var raw struct {
+		MCPServers map[string]types.MCPServer `json:"mcpServers"`
+		Servers    map[string]types.MCPServer `json:"servers"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析 MCP 配置失败 (%s): %w", path, err)
+	}
+	config := &types.MCPConfig{MCPServers: make(map[string]types.MCPServer, len(raw.MCPServers)+len(raw.Servers))}
+	for name, server := range raw.Servers {
+		config.MCPServers[name] = server
+	}
+	for name, server := range raw.MCPServers {
+		config.MCPServers[name] = server
+	}
+	return config, nil
+}
+
+// userConfigDir 返回各平台的用户配置根目录（与 os.UserConfigDir 约定一致，但以 homeDir 为基准便于测试）。
+func userConfigDir(homeDir string) string {
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(homeDir, "Library", "Application Support")
+	case "windows":
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return appData
+		}
+		return filepath.Join(homeDir, "AppData", "Roaming")
+	default:
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			return xdg
+		}
+		return filepath.Join(homeDir, ".config")
+	}
+}
@@ -0,0 +1,48 @@
+package ide
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseExternalMCPConfig_VSCodeServersAndHeaders(t *testing.T) {
+	config, err := ParseExternalMCPConfig("mcp.json", []byte(`{
+  "servers": {"remote": {"type": "http", "url": "https://example.com/mcp", "headers": {"Authorization": "Bearer x"}}},
+  "inputs": []
+}`))
+	if err != nil {
+		t.Fatalf("ParseExternalMCPConfig() 失败: %v", err)
+	}
+	server, ok := config.MCPServers["remote"]
+	if !ok || server.Type != "http" || server.Headers["Authorization"] != "Bearer x" {
+		t.Fatalf("VS Code servers 应被识别并保留 type/headers: %#v", config.MCPServers)
+	}
+
+	toml, err := ParseExternalMCPConfig("config.toml", []byte("[mcp_servers.fs]\ncommand = \"npx\"\n"))
+	if err != nil || toml.MCPServers["fs"].Command != "npx" {
+		t.Fatalf("toml 应按 Codex 格式解析: %#v err=%v", toml, err)
+	}
+}
+
+func TestExternalMCPSources_IncludesRegisteredIDEsAndDedupesPaths(t *testing.T) {
+	home := t.TempDir()
+	sources := ExternalMCPSources(home)
+	seen := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, source := range sources {
+		key := filepath.Clean(source.Path)
+		if seen[key] {
+			t.Fatalf("路径重复: %s", source.Path)
+		}
+		seen[key] = true
+		names[source.Name] = true
+	}
+	for _, want := range []string{"cursor", "codex", "claude-desktop", "vscode"} {
+		if !names[want] {
+			t.Fatalf("缺少来源 %s: %#v", want, sources)
+		}
+	}
+	if !seen[filepath.Join(home, ".cursor", "mcp.json")] {
+		t.Fatalf("应包含 ~/.cursor/mcp.json: %#v", sources)
+	}
+}
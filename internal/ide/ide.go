@@ -61,6 +61,12 @@ type IDE interface {
 	// LoadMCPConfig 加载现有的 MCP 配置
 	LoadMCPConfig(projectRoot string) (*types.MCPConfig, error)
 	LoadMCPConfigForPlane(plane Plane, projectRoot, homeDir string) (*types.MCPConfig, error)
+
+	// RemoteMCPServer 把远端（HTTP / SSE）MCP server 翻译成该 IDE 配置里的写法
+	RemoteMCPServer(remote RemoteMCP) types.MCPServer
+	// TranslateEnvRefs 把 MCP 配置里的规范环境变量引用（${VAR}、${VAR:-default}）转成该 IDE 的原生写法，
+	// 返回无法表达的引用
+	TranslateEnvRefs(server types.MCPServer) (types.MCPServer, []EnvRefIssue)
 }
 
 // SkillFile 表示 Skill 中的一个文件
@@ -82,12 +88,37 @@ type baseIDE struct {
 	userDirKey    string // 用户级目录名；为空时复用 dirKey
 	mcpConfigPath string // MCP 配置文件路径（可选，为空则使用默认 {dirKey}/mcp.json）
 	userMCPPath   string // 用户级 MCP 路径（相对 homeDir）；为空则使用 {userDirKey}/mcp.json
+	remoteStyle   remoteMCPStyle
+	envRefStyle   envRefStyle
+	assetTypes    []string // IDE 读取的资产类型（skill | command | rule | mcp）；为空表示全部
 }
 
 func (b *baseIDE) Name() string {
 	return b.name
 }
 
+// SupportsAssetType 判断 IDE 是否读取某类资产。
+func (b *baseIDE) SupportsAssetType(itemType string) bool {
+	if len(b.assetTypes) == 0 {
+		return true
+	}
+	for _, t := range b.assetTypes {
+		if t == itemType {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsAssetType 判断 IDE 是否读取某类资产（skill | command | rule | mcp）。
+// 只有声明了限制的 IDE（如 VS Code 只读 MCP 配置）会返回 false；安装、扫描都应跳过这类组合。
+func SupportsAssetType(ideImpl IDE, itemType string) bool {
+	if filter, ok := ideImpl.(interface{ SupportsAssetType(string) bool }); ok {
+		return filter.SupportsAssetType(itemType)
+	}
+	return true
+}
+
 func (b *baseIDE) UserRootDir(homeDir string) string {
 	return filepath.Join(homeDir, b.userDirKeyOrDefault())
 }
@@ -54,13 +54,14 @@ func List() []string {
 
 // 初始化时注册内置的 IDE 实现
 func init() {
-	Register(&baseIDE{name: "cursor", dirKey: ".cursor"})
+	Register(&baseIDE{name: "cursor", dirKey: ".cursor", envRefStyle: envRefEnvPrefix})
 	// CodeBuddy 的 MCP 配置在根目录 .mcp.json
 	Register(&baseIDE{name: "codebuddy", dirKey: ".codebuddy", mcpConfigPath: ".mcp.json", userMCPPath: ".mcp.json"})
-	Register(&baseIDE{name: "claude", dirKey: ".claude"})
+	Register(&baseIDE{name: "claude", dirKey: ".claude", remoteStyle: remoteStyleTyped})
 	// claude-internal 在用户目录使用 ~/.claude-internal，
 	// 但项目级配置仍然落在 .claude/ 下。
-	Register(&baseIDE{name: "claude-internal", dirKey: ".claude", userDirKey: ".claude-internal"})
+	Register(&baseIDE{name: "claude-internal", dirKey: ".claude", userDirKey: ".claude-internal", remoteStyle: remoteStyleTyped})
+	Register(newVSCodeIDE())
 	Register(newCodexIDE("codex"))
 	// codex-internal 在用户目录使用 ~/.codex-internal，
 	// 但项目级配置仍然落在 .codex/ 下。
@@ -26,7 +26,7 @@ func TestGetUnregisteredIDEReturnsFallback(t *testing.T) {
 }
 
 func TestIsValidRegistered(t *testing.T) {
-	for _, name := range []string{"cursor", "codebuddy", "claude", "claude-internal", "codex", "codex-internal"} {
+	for _, name := range []string{"cursor", "codebuddy", "claude", "claude-internal", "codex", "codex-internal", "vscode"} {
 		if !IsValid(name) {
 			t.Fatalf("已注册 IDE %s 应返回 IsValid=true", name)
 		}
@@ -52,7 +52,7 @@ func TestListContainsAllRegistered(t *testing.T) {
 	names := List()
 	sort.Strings(names)
 
-	expected := []string{"claude", "claude-internal", "codebuddy", "codex", "codex-internal", "cursor"}
+	expected := []string{"claude", "claude-internal", "codebuddy", "codex", "codex-internal", "cursor", "vscode"}
 	if len(names) != len(expected) {
 		t.Fatalf("期望 %d 个 IDE，得到 %d 个: %v", len(expected), len(names), names)
 	}
@@ -0,0 +1,70 @@
+package ide
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// 远端 MCP 的传输方式。
+const (
+	RemoteTransportHTTP = "http"
+	RemoteTransportSSE  = "sse"
+)
+
+// RemoteMCP 是远端（Streamable HTTP / SSE）MCP server 的 IDE 无关描述。
+// Headers 只放可以明文写进 IDE 配置的请求头；需要从 .env 取值的请求头不在这里，
+// 由调用方改走 dec-exec 桥接。
+type RemoteMCP struct {
+	URL       string
+	Transport string
+	Headers   map[string]string
+}
+
+// remoteMCPStyle 区分 JSON 配置里远端 server 的写法。
+type remoteMCPStyle int
+
+const (
+	// remoteStyleURL：只写 url + headers，传输方式由客户端自动探测（Cursor、CodeBuddy）。
+	remoteStyleURL remoteMCPStyle = iota
+	// remoteStyleTyped：显式写 type: http|sse（Claude、VS Code）。
+	remoteStyleTyped
+)
+
+// RemoteMCPServer 把远端 server 翻译成该 IDE 的配置条目。
+func (b *baseIDE) RemoteMCPServer(remote RemoteMCP) types.MCPServer {
+	server := types.MCPServer{URL: remote.URL, Headers: cloneHeaderMap(remote.Headers)}
+	if b.remoteStyle == remoteStyleTyped {
+		server.Type = normalizeRemoteTransport(remote.Transport)
+	}
+	return server
+}
+
+// RemoteMCPServer 对 Codex 写 url + http_headers；Codex 自行协商传输方式，不区分 SSE。
+func (c *codexIDE) RemoteMCPServer(remote RemoteMCP) types.MCPServer {
+	return types.MCPServer{URL: remote.URL, HTTPHeaders: cloneHeaderMap(remote.Headers)}
+}
+
+func normalizeRemoteTransport(transport string) string {
+	if strings.EqualFold(strings.TrimSpace(transport), RemoteTransportSSE) {
+		return RemoteTransportSSE
+	}
+	return RemoteTransportHTTP
+}
+
+func cloneHeaderMap(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		out[key] = headers[key]
+	}
+	return out
+}
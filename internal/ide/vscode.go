@@ -0,0 +1,104 @@
+package ide
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shichao402/Dec/internal/types"
+)
+
+// vscodeIDE 是 VS Code（Copilot agent mode）。
+//
+// 只安装 MCP：VS Code 不读 .vscode 下的 skills / commands / rules。配置文件顶层键是 servers 而不是 mcpServers，
+// 同文件里还有 inputs 等键需要原样保留；用户级 mcp.json 位于 VS Code 的 User 配置目录。
+type vscodeIDE struct {
+	baseIDE
+}
+
+func newVSCodeIDE() IDE {
+	return &vscodeIDE{baseIDE: baseIDE{
+		name:          "vscode",
+		dirKey:        ".vscode",
+		mcpConfigPath: filepath.Join(".vscode", "mcp.json"),
+		remoteStyle:   remoteStyleTyped,
+		envRefStyle:   envRefEnvPrefixInputs,
+		assetTypes:    []string{"mcp"},
+	}}
+}
+
+func (v *vscodeIDE) MCPConfigPath(projectRoot string) string {
+	return v.MCPConfigPathForPlane(PlaneProject, projectRoot, "")
+}
+
+func (v *vscodeIDE) MCPConfigPathForPlane(plane Plane, projectRoot, homeDir string) string {
+	if plane == PlaneUser {
+		return filepath.Join(userConfigDir(homeDir), "Code", "User", "mcp.json")
+	}
+	return v.baseIDE.MCPConfigPathForPlane(plane, projectRoot, homeDir)
+}
+
+func (v *vscodeIDE) LoadMCPConfig(projectRoot string) (*types.MCPConfig, error) {
+	return v.LoadMCPConfigForPlane(PlaneProject, projectRoot, "")
+}
+
+func (v *vscodeIDE) LoadMCPConfigForPlane(plane Plane, projectRoot, homeDir string) (*types.MCPConfig, error) {
+	configPath := v.MCPConfigPathForPlane(plane, projectRoot, homeDir)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &types.MCPConfig{MCPServers: make(map[string]types.MCPServer)}, nil
+		}
+		return nil, err
+	}
+
+	var raw struct {
+		Servers map[string]types.MCPServer `json:"servers"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析 MCP 配置失败 (%s): %w", configPath, err)
+	}
+	config := &types.MCPConfig{MCPServers: raw.Servers}
+	if config.MCPServers == nil {
+		config.MCPServers = make(map[string]types.MCPServer)
+	}
+	return config, nil
+}
+
+func (v *vscodeIDE) WriteMCPConfig(projectRoot string, config *types.MCPConfig) error {
+	return v.WriteMCPConfigForPlane(PlaneProject, projectRoot, "", config)
+}
+
+// WriteMCPConfigForPlane 只替换 servers，保留 inputs 等其它顶层键。
+func (v *vscodeIDE) WriteMCPConfigForPlane(plane Plane, projectRoot, homeDir string, config *types.MCPConfig) error {
+	configPath := v.MCPConfigPathForPlane(plane, projectRoot, homeDir)
+
+	doc := make(map[string]json.RawMessage)
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("解析 MCP 配置失败 (%s): %w", configPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	servers := config.MCPServers
+	if servers == nil {
+		servers = make(map[string]types.MCPServer)
+	}
+	encoded, err := json.Marshal(servers)
+	if err != nil {
+		return err
+	}
+	doc["servers"] = encoded
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, data, 0644)
+}
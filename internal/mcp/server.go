@@ -54,16 +54,24 @@ func (s *Server) Register(mcpServer *mcp.Server) {
 	}, s.handleSetAssets)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "dec_pull",
-		Description: "拉取并安装某平面已启用的 Dec bundle 与 secrets（plane=project|user|both）。project 装进 <project> 内 IDE 目录，user 装进 ~ 用户级 IDE 目录。secrets 失败不阻断公开资产，走部分成功 + 警告。",
+		Description: "拉取并安装某平面已启用的 Dec bundle 与 secrets（plane=project|user|both）。project 装进 <project> 内 IDE 目录，user 装进 ~ 用户级 IDE 目录。secrets 失败不阻断公开资产，走部分成功 + 警告。dry_run=true 只返回每个 IDE 文件将产生的 diff，不写任何文件。preview_branch 指定 dec/ 开头的评审分支时按该分支内容安装，用于合并前试用。",
 	}, s.handlePull)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "dec_push",
-		Description: "把某平面的本地改动推回远端（plane=project|user|both）：Dec 资产推 Git，secrets 推 Bitwarden。改了项目内 token 用 plane=project；改了个人凭据/SSH 用 plane=user；两边都改过用 both。",
+		Description: "把某平面的本地改动推回远端（plane=project|user|both）：Dec 资产推 Git，secrets 推 Bitwarden。改了项目内 token 用 plane=project；改了个人凭据/SSH 用 plane=user；两边都改过用 both。bundles / files 只提交选中的 Dec 改动（files 取自 dec_preview_push 的 DecChanges[].Path），此时不推 secrets。message 是可选的提交说明，会与涉及的 bundle / 资产、项目名、主机名与 Dec 版本一起写进 vault 提交。",
 	}, s.handlePush)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "dec_preview_push",
-		Description: "预览某平面 push 将涉及的 Dec 与 secrets 变更（plane=project|user|both，不写远端）。推之前先 preview 确认范围。",
+		Description: "预览某平面 push 将涉及的 Dec 与 secrets 变更（plane=project|user|both，不写远端）。DecChanges 逐文件列出新增/修改/删除及 unified diff。推之前先 preview 确认范围。",
 	}, s.handlePreviewPush)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "dec_asset_history",
+		Description: "查看 vault 中某个资产（bundle+type+name）或整个 bundle（只给 bundle）的 Git 历史：默认列出提交（作者、时间、说明）；revision 返回该提交相对父提交的逐文件 diff；from/to 比较任意两个版本（to 留空为分支最新）。想解释某个 skill 改了什么、何时由谁改的先调它。restore=true 时把 revision 版本写回 .dec/cache，再 dec_push 才会进入 vault。plane=project|user，不支持 both。",
+	}, s.handleAssetHistory)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "dec_migrate_vault",
+		Description: "把某平面跟随的 vault 分支迁移到本版本 Dec 支持的格式（根目录 .dec-vault.yaml 的 format）：依次执行缺少的迁移并写入格式声明，作为一个提交推送。dry_run=true 只返回逐文件 diff，不提交。格式更高的 vault 只能由新版本 Dec 推送。plane=project|user，不支持 both。",
+	}, s.handleMigrateVault)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "dec_list_secrets",
 		Description: "列出某平面私密资产元数据（路径、本地/远端存在性；plane=project|user|both）。绝不返回 token/密钥/正文。",
@@ -76,6 +84,26 @@ func (s *Server) Register(mcpServer *mcp.Server) {
 		Name:        "dec_delete",
 		Description: "删除选中的 Dec 资产、secrets 或 bundle（需 confirmed=true）。plane=project|user，一次只作用一个平面，不支持 both。",
 	}, s.handleDelete)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "dec_scan_unmanaged",
+		Description: "扫描某平面各 IDE 目录与 MCP 配置中非 Dec 托管（非 dec-*）的 skill / command / rule / mcp（plane=project|user）。收编前先用它拿候选；mcp 条目会标出疑似凭据的 env 键与请求头。",
+	}, s.handleScanUnmanaged)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "dec_import_unmanaged",
+		Description: "把选中的非托管资产收编进 bundle：写入 vault 与 .dec/cache、追加 bundle.yaml members 并启用该 bundle；mcp env 与请求头（headers / http_headers）中的字面量凭据移入 bundle 的 .env，vault 里改为 ${VAR} 引用，不进 Git。replace_originals=true 时下次 pull 装好 dec-* 版本后删除原件。plane=project|user，不支持 both。",
+	}, s.handleImportUnmanaged)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "dec_scan_global_mcp",
+		Description: "扫描本机用户级 MCP 配置（Cursor / Claude / Claude Desktop / VS Code / Codex 等）中可导入的 server，同名跨配置合并；只列出疑似凭据的键名，不返回值。",
+	}, s.handleScanGlobalMCP)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "dec_import_global_mcp",
+		Description: "把选中的用户级 MCP server 规范化为 bundle 的 mcp/<name>.json 并推送 vault。含字面量凭据时须 extract_secrets=true：env 凭据移入 bundle .env 由 dec-exec 注入，请求头凭据改写为 ${VAR}。plane=project|user，不支持 both。",
+	}, s.handleImportGlobalMCP)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "dec_explain_vars",
+		Description: "解释项目内某个已 pull 资产的 {{VAR}} 占位符：每个变量的生效值、来源层与文件（asset / project / vars.d / global / vault / builtin / default），以及被遮蔽的定义。渲染结果不符合预期时先调它。",
+	}, s.handleExplainVars)
 }
 
 // Run 启动 stdio MCP Server。
@@ -217,22 +245,39 @@ func (s *Server) handleSetAssets(ctx context.Context, _ *mcp.CallToolRequest, in
 }
 
 type pullParams struct {
-	Plane string `json:"plane,omitempty" jsonschema:"作用平面：project|user|both。留空默认 project。"`
+	Plane         string `json:"plane,omitempty" jsonschema:"作用平面：project|user|both。留空默认 project。"`
+	DryRun        bool   `json:"dry_run,omitempty" jsonschema:"为 true 时只渲染并返回每个 IDE 文件的 unified diff（含将被清理的孤儿），不安装、不写 cache、不同步 secrets。"`
+	PreviewBranch string `json:"preview_branch,omitempty" jsonschema:"评审分支名（dec/<user>/<project>/<timestamp>，见 dec_status 的 PendingBranches）；非空时按该分支内容拉取安装，与 dry_run 互斥。"`
 }
 
 func (s *Server) handlePull(ctx context.Context, _ *mcp.CallToolRequest, in pullParams) (*mcp.CallToolResult, any, error) {
 	return s.dispatchPlanes(ctx, in.Plane, func(ctx context.Context, ws app.Workspace, reporter app.Reporter) (any, error) {
+		if in.DryRun {
+			if in.PreviewBranch != "" {
+				return nil, fmt.Errorf("dry_run 与 preview_branch 不能同时使用")
+			}
+			return serviceapi.PreviewPullWorkspaceAssets(ctx, ws, reporter)
+		}
+		if in.PreviewBranch != "" {
+			return serviceapi.PullWorkspaceBranchPreview(ctx, ws, in.PreviewBranch, reporter)
+		}
 		return serviceapi.PullWorkspaceAssets(ctx, ws, reporter)
 	})
 }
 
 type pushParams struct {
-	Plane string `json:"plane,omitempty" jsonschema:"作用平面：project|user|both。留空默认 project。"`
+	Plane   string   `json:"plane,omitempty" jsonschema:"作用平面：project|user|both。留空默认 project。"`
+	Bundles []string `json:"bundles,omitempty" jsonschema:"只提交这些 bundle 的 Dec 改动（整包）。与 files 取并集；都留空推送全部。"`
+	Files   []string `json:"files,omitempty" jsonschema:"只提交这些 vault 相对路径（如 bundles/combo/rules/a.mdc，取自 dec_preview_push 的 DecChanges）。"`
+	Message string   `json:"message,omitempty" jsonschema:"提交说明：首行进入提交标题，其余作为正文；留空按改动自动生成。"`
 }
 
 func (s *Server) handlePush(ctx context.Context, _ *mcp.CallToolRequest, in pushParams) (*mcp.CallToolResult, any, error) {
 	return s.dispatchPlanes(ctx, in.Plane, func(ctx context.Context, ws app.Workspace, reporter app.Reporter) (any, error) {
-		return serviceapi.PushWorkspaceAssets(ctx, ws, reporter)
+		return serviceapi.PushWorkspaceWith(ctx, ws, app.PushOptions{
+			Selection: app.PushSelection{Bundles: in.Bundles, Files: in.Files},
+			Message:   in.Message,
+		}, reporter)
 	})
 }
 
@@ -246,6 +291,64 @@ func (s *Server) handlePreviewPush(ctx context.Context, _ *mcp.CallToolRequest,
 	})
 }
 
+type assetHistoryParams struct {
+	Bundle   string `json:"bundle" jsonschema:"bundle 短名"`
+	Type     string `json:"type,omitempty" jsonschema:"skill | command | rule | mcp；与 name 同时留空时查看整个 bundle"`
+	Name     string `json:"name,omitempty" jsonschema:"资产短名（不含 dec- 前缀）"`
+	Limit    int    `json:"limit,omitempty" jsonschema:"列出提交的上限，默认 30"`
+	Revision string `json:"revision,omitempty" jsonschema:"提交 hash（可缩写）；非空时返回该提交的详情与 diff"`
+	From     string `json:"from,omitempty" jsonschema:"比较的起始版本；非空时返回 from 到 to 的 diff"`
+	To       string `json:"to,omitempty" jsonschema:"比较的目标版本，留空为分支最新"`
+	Restore  bool   `json:"restore,omitempty" jsonschema:"为 true 时把 revision 版本恢复进 .dec/cache（需 revision），之后 dec_push 生效"`
+	Plane    string `json:"plane,omitempty" jsonschema:"作用平面：project|user。决定读取的 vault 分支与恢复写入的 cache；留空默认 project。"`
+}
+
+func (s *Server) handleAssetHistory(ctx context.Context, _ *mcp.CallToolRequest, in assetHistoryParams) (*mcp.CallToolResult, any, error) {
+	plane, err := parseSinglePlane(in.Plane)
+	if err != nil {
+		return toolFail(err, nil)
+	}
+	query := app.AssetHistoryQuery{
+		Bundle:   in.Bundle,
+		Type:     in.Type,
+		Name:     in.Name,
+		Limit:    in.Limit,
+		Revision: in.Revision,
+		From:     in.From,
+		To:       in.To,
+	}
+	workspace := app.NewWorkspace(plane, s.projectRoot())
+	reporter, logs := newCollector()
+	var result any
+	if in.Restore {
+		result, err = serviceapi.RestoreAssetRevision(ctx, workspace, query, reporter)
+	} else {
+		result, err = serviceapi.LoadAssetHistory(ctx, workspace, query, reporter)
+	}
+	if err != nil {
+		return toolFail(err, logs())
+	}
+	return toolOK(result, logs())
+}
+
+type migrateVaultParams struct {
+	DryRun bool   `json:"dry_run,omitempty" jsonschema:"为 true 时只返回迁移将产生的逐文件 diff，不提交"`
+	Plane  string `json:"plane,omitempty" jsonschema:"作用平面：project|user。决定迁移的 vault 分支；留空默认 project。"`
+}
+
+func (s *Server) handleMigrateVault(ctx context.Context, _ *mcp.CallToolRequest, in migrateVaultParams) (*mcp.CallToolResult, any, error) {
+	plane, err := parseSinglePlane(in.Plane)
+	if err != nil {
+		return toolFail(err, nil)
+	}
+	reporter, logs := newCollector()
+	result, err := serviceapi.MigrateVault(ctx, app.NewWorkspace(plane, s.projectRoot()), app.MigrateVaultInput{DryRun: in.DryRun}, reporter)
+	if err != nil {
+		return toolFail(err, logs())
+	}
+	return toolOK(result, logs())
+}
+
 type listSecretsParams struct {
 	IncludeRemote *bool  `json:"include_remote,omitempty" jsonschema:"是否检查 Bitwarden 远端存在性（默认 true，可能触发 web unlock）"`
 	Plane         string `json:"plane,omitempty" jsonschema:"作用平面：project|user|both。留空默认 project。"`
@@ -316,3 +419,111 @@ func (s *Server) handleDelete(ctx context.Context, _ *mcp.CallToolRequest, in de
 	}
 	return toolOK(result, logs())
 }
+
+type scanUnmanagedParams struct {
+	Plane string `json:"plane,omitempty" jsonschema:"作用平面：project|user。留空默认 project；不支持 both。"`
+}
+
+func (s *Server) handleScanUnmanaged(ctx context.Context, _ *mcp.CallToolRequest, in scanUnmanagedParams) (*mcp.CallToolResult, any, error) {
+	plane, err := parseSinglePlane(in.Plane)
+	if err != nil {
+		return toolFail(err, nil)
+	}
+	reporter, logs := newCollector()
+	result, err := serviceapi.ScanUnmanagedAssets(ctx, app.NewWorkspace(plane, s.projectRoot()), reporter)
+	if err != nil {
+		return toolFail(err, logs())
+	}
+	return toolOK(result, logs())
+}
+
+type importUnmanagedItem struct {
+	Type string `json:"type" jsonschema:"skill | command | rule | mcp"`
+	Name string `json:"name" jsonschema:"dec_scan_unmanaged 返回的短名"`
+}
+
+type importUnmanagedParams struct {
+	Bundle           string                `json:"bundle" jsonschema:"目标 bundle 短名；不存在时按当前平面 scope 新建"`
+	Items            []importUnmanagedItem `json:"items" jsonschema:"要收编的条目，须来自同平面的 dec_scan_unmanaged"`
+	ReplaceOriginals bool                  `json:"replace_originals,omitempty" jsonschema:"下次 pull 装好 dec-* 版本后删除原始条目"`
+	Plane            string                `json:"plane,omitempty" jsonschema:"作用平面：project|user。留空默认 project；不支持 both。"`
+}
+
+func (s *Server) handleImportUnmanaged(ctx context.Context, _ *mcp.CallToolRequest, in importUnmanagedParams) (*mcp.CallToolResult, any, error) {
+	plane, err := parseSinglePlane(in.Plane)
+	if err != nil {
+		return toolFail(err, nil)
+	}
+	reporter, logs := newCollector()
+	items := make([]app.ImportUnmanagedRef, 0, len(in.Items))
+	for _, item := range in.Items {
+		items = append(items, app.ImportUnmanagedRef{Type: item.Type, Name: item.Name})
+	}
+	result, err := serviceapi.ImportUnmanagedAssets(ctx, app.ImportUnmanagedInput{
+		ProjectRoot:      s.projectRoot(),
+		Plane:            plane,
+		Bundle:           in.Bundle,
+		Items:            items,
+		ReplaceOriginals: in.ReplaceOriginals,
+	}, reporter)
+	if err != nil {
+		return toolFail(err, logs())
+	}
+	return toolOK(result, logs())
+}
+
+type scanGlobalMCPParams struct{}
+
+func (s *Server) handleScanGlobalMCP(ctx context.Context, _ *mcp.CallToolRequest, _ scanGlobalMCPParams) (*mcp.CallToolResult, any, error) {
+	reporter, logs := newCollector()
+	result, err := serviceapi.ScanGlobalMCPServers(ctx, app.NewWorkspace(app.WorkspaceUser, s.projectRoot()), reporter)
+	if err != nil {
+		return toolFail(err, logs())
+	}
+	return toolOK(result, logs())
+}
+
+type importGlobalMCPParams struct {
+	Bundle         string   `json:"bundle" jsonschema:"目标 bundle 短名；不存在时按当前平面 scope 新建"`
+	Names          []string `json:"names" jsonschema:"要导入的 server 名，须来自 dec_scan_global_mcp"`
+	ExtractSecrets bool     `json:"extract_secrets,omitempty" jsonschema:"把字面量凭据移入 bundle 的 .env（不进 Git）；含凭据的 server 必须为 true"`
+	Plane          string   `json:"plane,omitempty" jsonschema:"作用平面：project|user。留空默认 project；不支持 both。"`
+}
+
+func (s *Server) handleImportGlobalMCP(ctx context.Context, _ *mcp.CallToolRequest, in importGlobalMCPParams) (*mcp.CallToolResult, any, error) {
+	plane, err := parseSinglePlane(in.Plane)
+	if err != nil {
+		return toolFail(err, nil)
+	}
+	reporter, logs := newCollector()
+	result, err := serviceapi.ImportGlobalMCPServers(ctx, app.ImportGlobalMCPInput{
+		ProjectRoot:    s.projectRoot(),
+		Plane:          plane,
+		Bundle:         in.Bundle,
+		Names:          in.Names,
+		ExtractSecrets: in.ExtractSecrets,
+	}, reporter)
+	if err != nil {
+		return toolFail(err, logs())
+	}
+	return toolOK(result, logs())
+}
+
+type explainVarsParams struct {
+	Type   string `json:"type" jsonschema:"skill | command | rule | mcp"`
+	Name   string `json:"name" jsonschema:"资产短名（不含 dec- 前缀）"`
+	Bundle string `json:"bundle,omitempty" jsonschema:"资产所属 bundle；留空时在 .dec/cache 中按名查找"`
+}
+
+func (s *Server) handleExplainVars(_ context.Context, _ *mcp.CallToolRequest, in explainVarsParams) (*mcp.CallToolResult, any, error) {
+	result, err := serviceapi.ExplainVars(app.ExplainVarsInput{
+		ProjectRoot: s.projectRoot(),
+		AssetType:   in.Type,
+		AssetName:   in.Name,
+		Bundle:      in.Bundle,
+	})
+	if err != nil {
+		return toolFail(err, nil)
+	}
+	return toolOK(result, nil)
+}
@@ -0,0 +1,135 @@
+// Package mcpbridge 把本地 stdio MCP 连接转发到远端 Streamable HTTP / SSE MCP server。
+//
+// dec-exec 用它承接带凭据请求头的远端 server：IDE 配置里只写一条 stdio 命令，
+// 请求头的值在 dec-exec 进程内从 bundle .env 解析后附加到每个 HTTP 请求上，不落进 IDE 文件。
+//
+// 桥只做 JSON-RPC 消息的原样转发，不参与 initialize 协商；因此不会开启 standalone SSE 流，
+// 服务端主动推送只能随请求的响应流到达。
+package mcpbridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Options 描述一次桥接。
+type Options struct {
+	URL string
+	// Transport 为 http（默认）或 sse。
+	Transport string
+	// Header 附加到每个发往远端的请求上。
+	Header http.Header
+	// HTTPClient 为空时使用 http.DefaultTransport。
+	HTTPClient *http.Client
+	Stdin      io.Reader
+	Stdout     io.Writer
+}
+
+// Run 在本地 stdin/stdout 与远端之间双向转发，直到本地输入结束、远端断开或 ctx 取消。
+// 本地输入正常结束（IDE 关闭连接）时返回 nil。
+func Run(ctx context.Context, opts Options) error {
+	if strings.TrimSpace(opts.URL) == "" {
+		return fmt.Errorf("远端 MCP URL 不能为空")
+	}
+	if opts.Stdin == nil || opts.Stdout == nil {
+		return fmt.Errorf("必须提供 stdin / stdout")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client := withHeaders(opts.HTTPClient, opts.Header)
+	var remoteTransport mcp.Transport
+	switch strings.ToLower(strings.TrimSpace(opts.Transport)) {
+	case "", "http":
+		remoteTransport = &mcp.StreamableClientTransport{Endpoint: opts.URL, HTTPClient: client}
+	case "sse":
+		remoteTransport = &mcp.SSEClientTransport{Endpoint: opts.URL, HTTPClient: client}
+	default:
+		return fmt.Errorf("不支持的远端 MCP 传输方式: %s", opts.Transport)
+	}
+
+	remote, err := remoteTransport.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("连接远端 MCP 失败: %w", err)
+	}
+	defer remote.Close()
+	local, err := (&mcp.IOTransport{Reader: io.NopCloser(opts.Stdin), Writer: nopWriteCloser{opts.Stdout}}).Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer local.Close()
+
+	errc := make(chan error, 2)
+	go func() { errc <- pump(ctx, local, remote, "本地") }()
+	go func() { errc <- pump(ctx, remote, local, "远端") }()
+	err = <-errc
+	cancel()
+	if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// pump 把 from 读到的消息逐条写到 to；返回值中 io.EOF 只来自本地输入结束。
+func pump(ctx context.Context, from, to mcp.Connection, fromName string) error {
+	for {
+		msg, err := from.Read(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) && fromName == "本地" {
+				return io.EOF
+			}
+			return fmt.Errorf("读取%s MCP 消息失败: %w", fromName, err)
+		}
+		if err := to.Write(ctx, msg); err != nil {
+			return fmt.Errorf("转发%s MCP 消息失败: %w", fromName, err)
+		}
+	}
+}
+
+func withHeaders(base *http.Client, header http.Header) *http.Client {
+	client := &http.Client{}
+	if base != nil {
+		*client = *base
+	}
+	if len(header) == 0 {
+		return client
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = headerRoundTripper{next: next, header: header.Clone()}
+	return client
+}
+
+// headerRoundTripper 给每个请求附加固定请求头（不覆盖 SDK 自己设置的 MCP 协议头）。
+type headerRoundTripper struct {
+	next   http.RoundTripper
+	header http.Header
+}
+
+func (h headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range h.header {
+		if req.Header.Get(name) != "" {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	return h.next.RoundTrip(req)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,74 @@
+package mcpbridge
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRunForwardsStdioToStreamableHTTPWithHeaders(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "remote-test", Version: "v0"}, nil)
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	var unauthorized int
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" || r.Header.Get("X-Team") != "dec" {
+			unauthorized++
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret-token")
+	header.Set("X-Team", "dec")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, Options{URL: httpServer.URL, Header: header, Stdin: stdinReader, Stdout: stdoutWriter})
+		stdoutWriter.Close()
+	}()
+
+	lines := bufio.NewScanner(stdoutReader)
+	send := func(line string) {
+		t.Helper()
+		if _, err := io.WriteString(stdinWriter, line+"\n"); err != nil {
+			t.Fatalf("写入 stdin 失败: %v", err)
+		}
+	}
+	expect := func(want string) {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("未收到响应: %v", lines.Err())
+		}
+		if !strings.Contains(lines.Text(), want) {
+			t.Fatalf("响应应包含 %q, got %s", want, lines.Text())
+		}
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"ide","version":"1"}}}`)
+	expect(`"remote-test"`)
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	expect(`"id":2`)
+
+	stdinWriter.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("本地输入结束时 Run 应返回 nil, got %v", err)
+	}
+	if unauthorized != 0 {
+		t.Fatalf("所有请求都应带上请求头, 未授权次数 %d", unauthorized)
+	}
+}
@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Git 后端取值：global config 的 git_backend 与环境变量 DEC_GIT_BACKEND 共用。
+const (
+	// BackendAuto 优先使用系统 git，找不到 git 可执行文件时回落到纯 Go 实现（默认）。
+	BackendAuto = "auto"
+	// BackendGit 强制调用系统 git。
+	BackendGit = "git"
+	// BackendGoGit 强制使用纯 Go 实现（go-git），不依赖任何外部进程。
+	BackendGoGit = "go-git"
+)
+
+// backendEnv 覆盖 global config 中的 git_backend，主要用于排障与测试。
+const backendEnv = "DEC_GIT_BACKEND"
+
+// gitBackend 是 Dec 对仓库的全部操作面：bare 仓库维护、事务工作区、提交与推送。
+//
+// 两个实现共用同一套磁盘布局（bare repo + worktrees/<name> 元数据 + 工作区 .git 指针文件），
+// 切换后端不需要迁移，任一后端创建的事务工作区都能被另一个清理。
+type gitBackend interface {
+	name() string
+
+	// bare 仓库
+	isBareRepo(dir string) (bool, error)
+	cloneBare(url, dir string) error
+	// fetchBare 以 +refs/heads/*:refs/heads/* 镜像远端分支并清理已删除的分支
+	fetchBare(bareDir string) error
+	remoteHeadBranch(bareDir string) (string, error)
+	headBranch(bareDir string) (string, error)
+	setHeadBranch(bareDir, branch string) error
+	resolveRef(bareDir, ref string) (string, error)
+	updateRef(bareDir, branch, hash string) error
+	// listBranches 按短名前缀列出本地分支（前缀可含 /），结果已排序
+	listBranches(bareDir, prefix string) ([]string, error)
+	// isAncestor 判断 ancestor 是否已包含在 descendant 的历史中（相同提交也算）
+	isAncestor(bareDir, ancestor, descendant string) (bool, error)
+	deleteBranch(bareDir, branch string) error
+	// setRef / deleteRef / listRefs 操作 refs/heads 以外的完整引用名（如离线 push 队列）
+	setRef(bareDir, ref, hash string) error
+	deleteRef(bareDir, ref string) error
+	listRefs(bareDir, prefix string) ([]namedRef, error)
+	remoteNames(gitDir string) ([]string, error)
+	remoteURL(gitDir, remote string) (string, error)
+	addRemote(gitDir, remote, url string) error
+	setRemoteURL(gitDir, remote, url string) error
+	// listRemoteHeads 只读探测远端，不落盘
+	listRemoteHeads(url string) error
+	// listTree 列出提交内的全部文件（递归，跳过子模块）
+	listTree(bareDir, commit string) ([]treeEntry, error)
+	// checkoutFiles 只把给定的 blob 写到 dir 下，不建立 worktree 元数据
+	checkoutFiles(bareDir, dir string, entries []treeEntry) error
+	// logPaths 从 commit 起按提交时间倒序列出改动过 paths（文件或目录前缀）的提交，limit<=0 不限
+	logPaths(bareDir, commit string, paths []string, limit int) ([]CommitInfo, error)
+	// logRange 列出从 head 可达、从 exclude 不可达（exclude 为空时不排除）且改动过 paths 的提交，
+	// 即 git log --full-history exclude..head -- paths：与任一父提交在 paths 上不同即算改动，根提交含 paths 即算。
+	// 结果集合与提交时间无关，limit<=0 不限
+	logRange(bareDir, head, exclude string, paths []string, limit int) ([]CommitInfo, error)
+	readCommit(bareDir, commit string) (CommitInfo, error)
+	// readRawCommit 返回未解析的提交对象（含 gpgsig 头），用于校验签名
+	readRawCommit(bareDir, commit string) ([]byte, error)
+
+	// 事务工作区
+	addWorktree(bareDir, worktreeDir, startPoint string) error
+	removeWorktree(bareDir, worktreeDir string) error
+	pruneWorktrees(bareDir string)
+	createBranch(worktreeDir, branch string) error
+	checkout(worktreeDir, ref string) error
+	headCommit(worktreeDir string) (string, error)
+	isClean(worktreeDir string) (bool, error)
+	// commitAll 暂存全部改动并提交；没有实质差异时 committed=false
+	commitAll(worktreeDir, message string) (committed bool, err error)
+	// push 把 HEAD 推到 origin 的 branch；非快进时返回的错误满足 isNonFastForwardPushError
+	push(worktreeDir, branch string) error
+	// integrateRemote 把 origin/branch 的新提交并入当前 HEAD；冲突时保持工作区可清理并返回错误
+	integrateRemote(worktreeDir, branch string) error
+}
+
+// namedRef 是一个完整引用名及其指向的提交。
+type namedRef struct {
+	name string
+	hash string
+}
+
+var (
+	backendMu         sync.Mutex
+	backendPreference = BackendAuto
+	gitLookPath       = exec.LookPath
+)
+
+// NormalizeBackend 规范化 git_backend 取值，无法识别时 ok=false。
+func NormalizeBackend(raw string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", BackendAuto:
+		return BackendAuto, true
+	case BackendGit, "exec", "system":
+		return BackendGit, true
+	case BackendGoGit, "gogit":
+		return BackendGoGit, true
+	default:
+		return "", false
+	}
+}
+
+// SetBackendPreference 设置 global config 中的 git_backend；环境变量 DEC_GIT_BACKEND 优先。
+func SetBackendPreference(raw string) error {
+	pref, ok := NormalizeBackend(raw)
+	if !ok {
+		return fmt.Errorf("git_backend %q 无法识别（可选 auto | git | go-git）", raw)
+	}
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	backendPreference = pref
+	return nil
+}
+
+// ActiveBackend 返回当前生效的后端名称（git 或 go-git）。
+func ActiveBackend() string {
+	return currentBackend().name()
+}
+
+func currentBackend() gitBackend {
+	backendMu.Lock()
+	pref := backendPreference
+	backendMu.Unlock()
+	if env, ok := NormalizeBackend(os.Getenv(backendEnv)); ok && strings.TrimSpace(os.Getenv(backendEnv)) != "" {
+		pref = env
+	}
+
+	switch pref {
+	case BackendGit:
+		return execBackend{}
+	case BackendGoGit:
+		return goGitBackend{}
+	}
+	if _, err := gitLookPath("git"); err != nil {
+		return goGitBackend{}
+	}
+	return execBackend{}
+}
@@ -0,0 +1,951 @@
+package repo
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// 后端一致性用例：同一组断言分别跑在系统 git 与 go-git 上。
+// 夹具全部用 go-git 构造、经 file:// 访问，没有 git 可执行文件的环境仍能验证 go-git 后端。
+
+func forEachBackend(t *testing.T, fn func(t *testing.T)) {
+	t.Helper()
+	for _, name := range []string{BackendGit, BackendGoGit} {
+		t.Run(name, func(t *testing.T) {
+			if name == BackendGit {
+				if _, err := exec.LookPath("git"); err != nil {
+					t.Skip("未安装 git，跳过系统 git 后端")
+				}
+			}
+			setEnvForTest(t, backendEnv, name)
+			if got := ActiveBackend(); got != name {
+				t.Fatalf("ActiveBackend() = %q, want %q", got, name)
+			}
+			fn(t)
+		})
+	}
+}
+
+// conformanceRemote 是一个 file:// 远端，seed 是向它推送提交的独立克隆。
+type conformanceRemote struct {
+	dir  string
+	url  string
+	seed *git.Repository
+}
+
+var conformanceSig = object.Signature{Name: "Dec Conformance", Email: "conformance@example.com"}
+
+func newConformanceRemote(t testing.TB, files map[string]string) *conformanceRemote {
+	t.Helper()
+	useInProcessFileTransport()
+	root := t.TempDir()
+	dir := filepath.Join(root, "remote.git")
+	bare, err := git.PlainInit(dir, true)
+	if err != nil {
+		t.Fatalf("初始化远端失败: %v", err)
+	}
+	main := plumbing.NewBranchReferenceName("main")
+	if err := bare.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, main)); err != nil {
+		t.Fatal(err)
+	}
+
+	seed, err := git.PlainInit(filepath.Join(root, "seed"), false)
+	if err != nil {
+		t.Fatalf("初始化 seed 失败: %v", err)
+	}
+	if err := seed.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, main)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seed.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{dir}}); err != nil {
+		t.Fatal(err)
+	}
+	remote := &conformanceRemote{dir: dir, url: "file://" + filepath.ToSlash(dir), seed: seed}
+	remote.commit(t, files, "initial commit")
+	return remote
+}
+
+// commit 在远端 main 最新提交上写入 files（值为空串表示删除）并推送。
+func (r *conformanceRemote) commit(t testing.TB, files map[string]string, message string) string {
+	t.Helper()
+	wt, err := r.seed.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.seed.Fetch(&git.FetchOptions{RefSpecs: []gitconfig.RefSpec{"+refs/heads/main:refs/remotes/origin/main"}})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) && !strings.Contains(err.Error(), "remote repository is empty") {
+		t.Fatalf("seed fetch 失败: %v", err)
+	}
+	if ref, err := r.seed.Reference("refs/remotes/origin/main", true); err == nil {
+		if err := wt.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.HardReset}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, content := range files {
+		full := filepath.Join(wt.Filesystem.Root(), filepath.FromSlash(path))
+		if content == "" {
+			_ = os.Remove(full)
+			continue
+		}
+		writeFile(t, full, content)
+	}
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		t.Fatal(err)
+	}
+	sig := conformanceSig
+	sig.When = time.Now()
+	hash, err := wt.Commit(message, &git.CommitOptions{All: true, Author: &sig})
+	if err != nil {
+		t.Fatalf("seed commit 失败: %v", err)
+	}
+	if err := r.seed.Push(&git.PushOptions{RefSpecs: []gitconfig.RefSpec{"refs/heads/main:refs/heads/main"}}); err != nil {
+		t.Fatalf("seed push 失败: %v", err)
+	}
+	return hash.String()
+}
+
+// branchFromMain 在远端把 name 分支指到 main 当前的提交。
+func (r *conformanceRemote) branchFromMain(t *testing.T, name string) {
+	t.Helper()
+	repo, err := git.PlainOpen(r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), ref.Hash())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (r *conformanceRemote) head(t *testing.T) *object.Commit {
+	t.Helper()
+	return r.branchHead(t, "main")
+}
+
+func (r *conformanceRemote) branchHead(t *testing.T, branch string) *object.Commit {
+	t.Helper()
+	repo, err := git.PlainOpen(r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatalf("读取远端 %s 失败: %v", branch, err)
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
+
+// file 读取远端 main 上的文件，不存在时 ok=false。
+func (r *conformanceRemote) file(t *testing.T, path string) (string, bool) {
+	t.Helper()
+	file, err := r.head(t).File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content, true
+}
+
+// connectConformance 在独立 DEC_HOME 下连接远端，并给 bare 配置提交身份。
+func connectConformance(t testing.TB, remote *conformanceRemote) string {
+	t.Helper()
+	setEnvForTest(t, "DEC_HOME", t.TempDir())
+	if err := Connect(remote.url); err != nil {
+		t.Fatalf("Connect(%s) 失败: %v", remote.url, err)
+	}
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bare, err := git.PlainOpen(bareDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := bare.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.User.Name = conformanceSig.Name
+	cfg.User.Email = conformanceSig.Email
+	if err := bare.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return bareDir
+}
+
+func TestBackendConformance_ConnectFetchAndProbe(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "init\n"})
+		connectConformance(t, remote)
+
+		if ok, err := IsBareConnected(); err != nil || !ok {
+			t.Fatalf("IsBareConnected() = %v, %v", ok, err)
+		}
+		if url, err := GetBareRemoteURL(); err != nil || url != remote.url {
+			t.Fatalf("GetBareRemoteURL() = %q, %v", url, err)
+		}
+		if branch, err := GetDefaultBranch(); err != nil || branch != "main" {
+			t.Fatalf("GetDefaultBranch() = %q, %v", branch, err)
+		}
+		if head, err := BareBranchHead("main"); err != nil || head != remote.head(t).Hash.String() {
+			t.Fatalf("BareBranchHead(main) = %q, %v", head, err)
+		}
+
+		next := remote.commit(t, map[string]string{"remote.txt": "from remote\n"}, "remote update")
+		if err := FetchBare(); err != nil {
+			t.Fatalf("FetchBare() 失败: %v", err)
+		}
+		if head, _ := BareBranchHead("main"); head != next {
+			t.Fatalf("FetchBare 后 main = %s, want %s", head, next)
+		}
+
+		if err := Probe(remote.url); err != nil {
+			t.Fatalf("Probe(remote) 失败: %v", err)
+		}
+		if err := Probe("file://" + filepath.ToSlash(filepath.Join(t.TempDir(), "missing.git"))); err == nil {
+			t.Fatalf("Probe 不存在的仓库应失败")
+		}
+	})
+}
+
+func TestBackendConformance_ReadTransactions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "v1\n"})
+		first := remote.head(t).Hash.String()
+		remote.commit(t, map[string]string{"README.md": "v2\n"}, "second")
+		connectConformance(t, remote)
+
+		tx, err := NewReadTransaction()
+		if err != nil {
+			t.Fatalf("NewReadTransaction() 失败: %v", err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(tx.WorkDir(), "README.md")); string(data) != "v2\n" {
+			t.Fatalf("只读事务内容 = %q", data)
+		}
+		if got := tx.CommitHash(); got != remote.head(t).Hash.String() {
+			t.Fatalf("CommitHash() = %q", got)
+		}
+		if clean, err := tx.IsClean(); err != nil || !clean {
+			t.Fatalf("新建事务应干净, clean=%v err=%v", clean, err)
+		}
+		writeFile(t, filepath.Join(tx.WorkDir(), "scratch.txt"), "x\n")
+		if clean, err := tx.IsClean(); err != nil || clean {
+			t.Fatalf("写入新文件后应判脏, clean=%v err=%v", clean, err)
+		}
+		workDir := tx.WorkDir()
+		tx.Close()
+		if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+			t.Fatalf("Close 后工作区应删除: %v", err)
+		}
+
+		at, err := NewReadTransactionAt(first)
+		if err != nil {
+			t.Fatalf("NewReadTransactionAt() 失败: %v", err)
+		}
+		defer at.Close()
+		if data, _ := os.ReadFile(filepath.Join(at.WorkDir(), "README.md")); string(data) != "v1\n" {
+			t.Fatalf("历史版本内容 = %q", data)
+		}
+		if got := at.CommitHash(); got != first {
+			t.Fatalf("CommitHash() = %q, want %q", got, first)
+		}
+	})
+}
+
+func TestBackendConformance_CommitAndPush(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "init\n", "old.txt": "old\n"})
+		connectConformance(t, remote)
+
+		tx, err := NewWriteTransaction()
+		if err != nil {
+			t.Fatalf("NewWriteTransaction() 失败: %v", err)
+		}
+		defer tx.Close()
+		if committed, err := tx.CommitAndPush("noop"); err != nil || committed {
+			t.Fatalf("无改动时 CommitAndPush() = %v, %v", committed, err)
+		}
+
+		writeFile(t, filepath.Join(tx.WorkDir(), "bundles", "team", "rules", "a.mdc"), "rule\n")
+		if err := os.Remove(filepath.Join(tx.WorkDir(), "old.txt")); err != nil {
+			t.Fatal(err)
+		}
+		committed, err := tx.CommitAndPush("add rule")
+		if err != nil || !committed {
+			t.Fatalf("CommitAndPush() = %v, %v", committed, err)
+		}
+
+		head := remote.head(t)
+		if head.Message != "add rule\n" {
+			t.Fatalf("远端提交说明 = %q", head.Message)
+		}
+		if content, ok := remote.file(t, "bundles/team/rules/a.mdc"); !ok || content != "rule\n" {
+			t.Fatalf("远端应包含新增文件, got %q %v", content, ok)
+		}
+		if _, ok := remote.file(t, "old.txt"); ok {
+			t.Fatalf("远端应删除 old.txt")
+		}
+		if bareHead, _ := BareBranchHead("main"); bareHead != head.Hash.String() {
+			t.Fatalf("bare main 应同步到推送结果, bare=%s remote=%s", bareHead, head.Hash)
+		}
+	})
+}
+
+func TestBackendConformance_TransactionsFollowVaultBranch(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "v1\n"})
+		remote.branchFromMain(t, "stable")
+		remote.commit(t, map[string]string{"README.md": "v2\n"}, "main only")
+		connectConformance(t, remote)
+
+		readOn := func(branch string) string {
+			t.Helper()
+			tx, err := NewReadTransactionOn(branch)
+			if err != nil {
+				t.Fatalf("NewReadTransactionOn(%q) 失败: %v", branch, err)
+			}
+			defer tx.Close()
+			data, err := os.ReadFile(filepath.Join(tx.WorkDir(), "README.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tx.Branch() + ":" + string(data)
+		}
+		if got := readOn("stable"); got != "stable:v1\n" {
+			t.Fatalf("stable 读事务 = %q", got)
+		}
+		if got := readOn(""); got != "main:v2\n" {
+			t.Fatalf("默认读事务 = %q", got)
+		}
+		if _, err := NewLocalReadTransactionOn("missing"); err == nil || !strings.Contains(err.Error(), "不存在") {
+			t.Fatalf("不存在的分支应报错, got %v", err)
+		}
+
+		tx, err := NewWriteTransactionOn("stable")
+		if err != nil {
+			t.Fatalf("NewWriteTransactionOn(stable) 失败: %v", err)
+		}
+		defer tx.Close()
+		writeFile(t, filepath.Join(tx.WorkDir(), "stable.txt"), "pinned\n")
+		if committed, err := tx.CommitAndPush("stable only"); err != nil || !committed {
+			t.Fatalf("CommitAndPush() = %v, %v", committed, err)
+		}
+		stable := remote.branchHead(t, "stable")
+		if _, err := stable.File("stable.txt"); err != nil {
+			t.Fatalf("远端 stable 应包含新文件: %v", err)
+		}
+		if _, ok := remote.file(t, "stable.txt"); ok {
+			t.Fatalf("远端 main 不应被写入")
+		}
+		if bareHead, _ := BareBranchHead("stable"); bareHead != stable.Hash.String() {
+			t.Fatalf("bare stable 应同步到推送结果, bare=%s remote=%s", bareHead, stable.Hash)
+		}
+	})
+}
+
+func TestBackendConformance_PushToReviewBranch(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "init\n"})
+		remote.branchFromMain(t, "dec/bob/other/20260101-000000")
+		connectConformance(t, remote)
+		const review = "dec/alice/demo/20260102-030405"
+
+		tx, err := NewWriteTransaction()
+		if err != nil {
+			t.Fatalf("NewWriteTransaction() 失败: %v", err)
+		}
+		defer tx.Close()
+		writeFile(t, filepath.Join(tx.WorkDir(), "review.txt"), "please review\n")
+		if committed, err := tx.CommitAndPushToBranch("review me", review); err != nil || !committed {
+			t.Fatalf("CommitAndPushToBranch() = %v, %v", committed, err)
+		}
+		pushed := remote.branchHead(t, review)
+		if _, err := pushed.File("review.txt"); err != nil {
+			t.Fatalf("评审分支应包含新文件: %v", err)
+		}
+		if _, ok := remote.file(t, "review.txt"); ok {
+			t.Fatalf("默认分支不应被写入")
+		}
+
+		// 已合入默认分支的 dec/ 分支不算待合并。
+		pending, err := UnmergedBranches("dec/", "")
+		if err != nil || len(pending) != 1 || pending[0] != review {
+			t.Fatalf("UnmergedBranches() = %v, %v", pending, err)
+		}
+		preview, err := NewReadTransactionAt("refs/heads/" + review)
+		if err != nil {
+			t.Fatalf("NewReadTransactionAt() 失败: %v", err)
+		}
+		defer preview.Close()
+		if data, err := os.ReadFile(filepath.Join(preview.WorkDir(), "review.txt")); err != nil || string(data) != "please review\n" {
+			t.Fatalf("按评审分支读取 = %q, %v", data, err)
+		}
+
+		// 评审通过后快进合入 main，分支不再待合并。
+		remoteRepo, err := git.PlainOpen(remote.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := remoteRepo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), pushed.Hash)); err != nil {
+			t.Fatal(err)
+		}
+		if err := FetchBare(); err != nil {
+			t.Fatalf("FetchBare() 失败: %v", err)
+		}
+		if pending, err := UnmergedBranches("dec/", "main"); err != nil || len(pending) != 0 {
+			t.Fatalf("合并后 UnmergedBranches() = %v, %v", pending, err)
+		}
+	})
+}
+
+func TestBackendConformance_PathHistoryAndCommitDetails(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{
+			"README.md":                   "init\n",
+			"bundles/a/rules/x.mdc":       "x v1\n",
+			"bundles/a/skills/s/SKILL.md": "s v1\n",
+		})
+		tweak := remote.commit(t, map[string]string{"bundles/a/rules/x.mdc": "x v2\n"}, "tweak x\n\nwhy: clearer")
+		remote.commit(t, map[string]string{"bundles/b/rules/y.mdc": "y\n"}, "add b")
+		drop := remote.commit(t, map[string]string{"bundles/a/rules/x.mdc": "", "bundles/a/skills/s/SKILL.md": "s v2\n"}, "drop x")
+		connectConformance(t, remote)
+
+		history, err := History("", []string{"bundles/a"}, 0)
+		if err != nil {
+			t.Fatalf("History() 失败: %v", err)
+		}
+		if len(history) != 3 || history[0].Hash != drop || history[1].Hash != tweak || history[2].Subject != "initial commit" {
+			t.Fatalf("History(bundles/a) = %+v", history)
+		}
+		if history[1].Subject != "tweak x" || history[1].Message != "tweak x\n\nwhy: clearer" || history[1].Author != conformanceSig.Name {
+			t.Fatalf("提交元数据 = %+v", history[1])
+		}
+		if limited, err := History("refs/heads/main", []string{"bundles/a/rules/x.mdc"}, 1); err != nil || len(limited) != 1 || limited[0].Hash != drop {
+			t.Fatalf("History(x, 1) = %+v, %v", limited, err)
+		}
+
+		info, changes, err := CommitDetails(drop)
+		if err != nil {
+			t.Fatalf("CommitDetails() 失败: %v", err)
+		}
+		if info.Subject != "drop x" || len(info.Parents) != 1 || info.Email != conformanceSig.Email {
+			t.Fatalf("CommitDetails() info = %+v", info)
+		}
+		want := []CommitFileChange{
+			{Path: "bundles/a/rules/x.mdc", Status: FileDeleted},
+			{Path: "bundles/a/skills/s/SKILL.md", Status: FileModified},
+		}
+		if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+			t.Fatalf("CommitDetails() changes = %+v", changes)
+		}
+	})
+}
+
+func TestBackendConformance_SparseReadTransaction(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{
+			"projects/demo.yaml":          "name: demo\n",
+			"bundles/a/bundle.yaml":       "name: a\n",
+			"bundles/a/skills/x/SKILL.md": "a v1\n",
+			"bundles/b/bundle.yaml":       "name: b\n",
+			"bundles/b/rules/r.mdc":       "b\n",
+		})
+		first := remote.head(t).Hash.String()
+		remote.commit(t, map[string]string{"bundles/a/skills/x/SKILL.md": "a v2\n"}, "bump a")
+		connectConformance(t, remote)
+
+		selectA := func(files []string) []string {
+			var picked []string
+			for _, f := range files {
+				if strings.HasPrefix(f, "projects/") || strings.HasSuffix(f, "/bundle.yaml") || strings.HasPrefix(f, "bundles/a/") {
+					picked = append(picked, f)
+				}
+			}
+			return picked
+		}
+		tx, err := NewReadTransactionWith(ReadOptions{Select: selectA})
+		if err != nil {
+			t.Fatalf("NewReadTransactionWith() 失败: %v", err)
+		}
+		dir := tx.WorkDir()
+		if data, err := os.ReadFile(filepath.Join(dir, "bundles/a/skills/x/SKILL.md")); err != nil || string(data) != "a v2\n" {
+			t.Fatalf("选中的文件应物化为最新内容: %q, %v", data, err)
+		}
+		for _, rel := range []string{"projects/demo.yaml", "bundles/b/bundle.yaml"} {
+			if _, err := os.Stat(filepath.Join(dir, rel)); err != nil {
+				t.Fatalf("%s 应物化: %v", rel, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "bundles/b/rules/r.mdc")); !os.IsNotExist(err) {
+			t.Fatalf("未选中的文件不应物化, err=%v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); !os.IsNotExist(err) {
+			t.Fatalf("稀疏事务不应创建 worktree, err=%v", err)
+		}
+		for rel, want := range map[string]bool{
+			"bundles/b/rules/r.mdc":   true,
+			"bundles/b/rules":         true,
+			"bundles/b/rules/nope.md": false,
+			"bundles/c":               false,
+		} {
+			if got := PathExists(filepath.Join(dir, filepath.FromSlash(rel))); got != want {
+				t.Fatalf("PathExists(%s) = %v, want %v", rel, got, want)
+			}
+		}
+		if got, want := tx.CommitHash(), remote.head(t).Hash.String(); got != want {
+			t.Fatalf("CommitHash() = %s, want %s", got, want)
+		}
+		tx.Close()
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Fatalf("关闭后应删除工作区, err=%v", err)
+		}
+		if PathExists(filepath.Join(dir, "bundles/b/rules/r.mdc")) {
+			t.Fatalf("关闭后不应再按提交索引作答")
+		}
+
+		at, err := NewReadTransactionWith(ReadOptions{Ref: first, Local: true, Select: selectA})
+		if err != nil {
+			t.Fatalf("按版本创建稀疏事务失败: %v", err)
+		}
+		defer at.Close()
+		if data, err := os.ReadFile(filepath.Join(at.WorkDir(), "bundles/a/skills/x/SKILL.md")); err != nil || string(data) != "a v1\n" {
+			t.Fatalf("应物化指定版本的内容: %q, %v", data, err)
+		}
+		if at.CommitHash() != first {
+			t.Fatalf("CommitHash() = %s, want %s", at.CommitHash(), first)
+		}
+	})
+}
+
+func TestBackendConformance_IntegratesRemoteAdvance(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "init\n"})
+		connectConformance(t, remote)
+
+		tx, err := NewWriteTransaction()
+		if err != nil {
+			t.Fatalf("NewWriteTransaction() 失败: %v", err)
+		}
+		defer tx.Close()
+		remoteCommit := remote.commit(t, map[string]string{"remote.txt": "from remote\n"}, "remote update")
+		writeFile(t, filepath.Join(tx.WorkDir(), "local.txt"), "from local\n")
+
+		if _, err := tx.CommitAndPush("local update"); err != nil {
+			t.Fatalf("远端前进后 CommitAndPush() 应自动整合: %v", err)
+		}
+		for _, path := range []string{"remote.txt", "local.txt"} {
+			if _, ok := remote.file(t, path); !ok {
+				t.Fatalf("整合后远端应包含 %s", path)
+			}
+		}
+		// 推送必须是快进：远端原先的提交仍在新 HEAD 的祖先链上
+		found := false
+		iter := object.NewCommitPreorderIter(remote.head(t), nil, nil)
+		_ = iter.ForEach(func(c *object.Commit) error {
+			if c.Hash.String() == remoteCommit {
+				found = true
+			}
+			return nil
+		})
+		if !found {
+			t.Fatalf("远端提交 %s 不在推送结果的历史中", remoteCommit)
+		}
+	})
+}
+
+func TestBackendConformance_ConflictLeavesRemoteUntouched(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"shared.txt": "base\n"})
+		connectConformance(t, remote)
+
+		tx, err := NewWriteTransaction()
+		if err != nil {
+			t.Fatalf("NewWriteTransaction() 失败: %v", err)
+		}
+		defer tx.Close()
+		remoteCommit := remote.commit(t, map[string]string{"shared.txt": "remote\n"}, "remote change")
+		writeFile(t, filepath.Join(tx.WorkDir(), "shared.txt"), "local\n")
+
+		_, err = tx.CommitAndPush("local change")
+		if err == nil || !strings.Contains(err.Error(), "与远端存在冲突") {
+			t.Fatalf("同一文件两边修改应报冲突, got %v", err)
+		}
+		if head := remote.head(t).Hash.String(); head != remoteCommit {
+			t.Fatalf("冲突时远端不应被改动, head=%s want %s", head, remoteCommit)
+		}
+	})
+}
+
+func TestBackendConformance_PruneOrphanWorktrees(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "init\n"})
+		bareDir := connectConformance(t, remote)
+
+		// 模拟进程崩溃：事务既不 Close 也不 Rollback
+		tx, err := NewWriteTransaction()
+		if err != nil {
+			t.Fatalf("NewWriteTransaction() 失败: %v", err)
+		}
+		removed, err := PruneOrphanWorktrees()
+		if err != nil || removed != 1 {
+			t.Fatalf("PruneOrphanWorktrees() = %d, %v", removed, err)
+		}
+		if _, err := os.Stat(tx.WorkDir()); !os.IsNotExist(err) {
+			t.Fatalf("孤儿工作区应删除: %v", err)
+		}
+		if entries, _ := os.ReadDir(filepath.Join(bareDir, "worktrees")); len(entries) != 0 {
+			t.Fatalf("worktree 元数据应清理, 剩余 %d 项", len(entries))
+		}
+		branches, err := currentBackend().listBranches(bareDir, txBranchPrefix)
+		if err != nil || len(branches) != 0 {
+			t.Fatalf("残留事务分支 = %v, err = %v", branches, err)
+		}
+		if _, err := NewReadTransaction(); err != nil {
+			t.Fatalf("清理后应能继续创建事务: %v", err)
+		}
+	})
+}
+
+func TestCurrentBackend_SelectsByPreferenceAndGitAvailability(t *testing.T) {
+	setEnvForTest(t, backendEnv, "")
+	origLookPath := gitLookPath
+	t.Cleanup(func() {
+		gitLookPath = origLookPath
+		_ = SetBackendPreference(BackendAuto)
+	})
+	gitLookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+
+	if got := ActiveBackend(); got != BackendGoGit {
+		t.Fatalf("找不到 git 时 auto 应回落 go-git, got %q", got)
+	}
+	if err := SetBackendPreference("git"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ActiveBackend(); got != BackendGit {
+		t.Fatalf("git_backend=git 应强制系统 git, got %q", got)
+	}
+	setEnvForTest(t, backendEnv, "go-git")
+	if got := ActiveBackend(); got != BackendGoGit {
+		t.Fatalf("DEC_GIT_BACKEND 应覆盖配置, got %q", got)
+	}
+	if err := SetBackendPreference("libgit2"); err == nil {
+		t.Fatalf("无法识别的 git_backend 应报错")
+	}
+}
+
+func TestBackendConformance_SignedCommits(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		if name := ActiveBackend(); name == BackendGit {
+			if _, err := exec.LookPath("ssh-keygen"); err != nil {
+				t.Skip("未安装 ssh-keygen，系统 git 无法做 SSH 签名")
+			}
+		}
+		remote := newConformanceRemote(t, map[string]string{"README.md": "init\n"})
+		connectConformance(t, remote)
+		initial := remote.head(t).Hash.String()
+
+		keyPath, pubLine := writeTestSigningKey(t, "dec-signer")
+		_, otherPub := writeTestSigningKey(t, "someone-else")
+		if err := SetCommitSigning(SigningOptions{Mode: SigningSSH, Key: keyPath}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = SetCommitSigning(SigningOptions{Mode: SigningOff}) })
+
+		tx, err := NewWriteTransaction()
+		if err != nil {
+			t.Fatalf("NewWriteTransaction() 失败: %v", err)
+		}
+		defer tx.Close()
+		writeFile(t, filepath.Join(tx.WorkDir(), "bundles", "a", "rules", "x.mdc"), "x\n")
+		if committed, err := tx.CommitAndPush("signed"); err != nil || !committed {
+			t.Fatalf("CommitAndPush() = %v, %v", committed, err)
+		}
+		signed := remote.head(t)
+		if signed.PGPSignature == "" {
+			t.Fatal("开启签名后远端提交应带签名")
+		}
+
+		trusted, warnings := ParseTrustedSigners([]string{pubLine})
+		if len(warnings) != 0 || trusted.Empty() {
+			t.Fatalf("ParseTrustedSigners() warnings = %v", warnings)
+		}
+		got, err := VerifyCommitSignature(signed.Hash.String(), trusted)
+		if err != nil {
+			t.Fatalf("VerifyCommitSignature() 失败: %v", err)
+		}
+		if got.Status != SignatureTrusted || got.Format != "ssh" || !strings.Contains(got.Signer, "dec-signer") {
+			t.Fatalf("受信签名 = %+v", got)
+		}
+
+		others, _ := ParseTrustedSigners([]string{otherPub})
+		if got, err := VerifyCommitSignature(signed.Hash.String(), others); err != nil || got.Status != SignatureUntrusted {
+			t.Fatalf("非受信签名 = %+v, %v", got, err)
+		}
+		if got, err := VerifyCommitSignature(initial, trusted); err != nil || got.Status != SignatureUnsigned {
+			t.Fatalf("未签名提交 = %+v, %v", got, err)
+		}
+
+		commits, truncated, err := CommitsTouching("refs/heads/main", initial, []string{"bundles/a"}, 10)
+		if err != nil || truncated || len(commits) != 1 || commits[0].Hash != signed.Hash.String() {
+			t.Fatalf("CommitsTouching() = %+v, %v, %v", commits, truncated, err)
+		}
+		// 没有 base 时列出全部历史；超过 limit 时标记截断而不是静默只返回前几条。
+		commits, truncated, err = CommitsTouching("refs/heads/main", "", []string{"bundles/a", "README.md"}, 10)
+		if err != nil || truncated || len(commits) != 2 || commits[1].Hash != initial {
+			t.Fatalf("无 base 的 CommitsTouching() = %+v, %v, %v", commits, truncated, err)
+		}
+		if commits, truncated, err = CommitsTouching("refs/heads/main", "", []string{"bundles/a", "README.md"}, 1); err != nil || !truncated || len(commits) != 1 {
+			t.Fatalf("超过 limit 的 CommitsTouching() = %+v, %v, %v", commits, truncated, err)
+		}
+		if _, _, err := CommitsTouching("refs/heads/main", strings.Repeat("0", 40), []string{"bundles/a"}, 10); err == nil {
+			t.Fatal("base 不在本地仓库时应报错")
+		}
+	})
+}
+
+func TestBackendConformance_CommitsTouchingIgnoresCommitDates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"bundles/a/x.mdc": "x\n", "README.md": "init\n"})
+		connectConformance(t, remote)
+		initial := remote.head(t).Hash.String()
+		remote.commit(t, map[string]string{"bundles/a/x.mdc": "x1\n"}, "c1")
+		base := remote.commit(t, map[string]string{"bundles/a/x.mdc": "x2\n"}, "c2")
+
+		// 侧分支从初始提交分出，提交时间伪造成很早，再合入 main：按时间排序会把它排到窗口之外。
+		work := filepath.Join(t.TempDir(), "work")
+		runGitNoDir(t, "clone", "-q", remote.dir, work)
+		configureGitUser(t, work)
+		runGit(t, work, "checkout", "-q", "-b", "side", initial)
+		writeFile(t, filepath.Join(work, "bundles", "a", "y.mdc"), "backdated\n")
+		runGit(t, work, "add", "-A")
+		backdated := exec.Command("git", "commit", "-q", "-m", "backdated")
+		backdated.Dir = work
+		backdated.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2001-01-01T00:00:00Z", "GIT_COMMITTER_DATE=2001-01-01T00:00:00Z")
+		if output, err := backdated.CombinedOutput(); err != nil {
+			t.Fatalf("git commit 失败: %v\n%s", err, output)
+		}
+		side := runGit(t, work, "rev-parse", "HEAD")
+		runGit(t, work, "checkout", "-q", "main")
+		runGit(t, work, "merge", "-q", "--no-ff", "-m", "merge side", "side")
+		runGit(t, work, "push", "-q", "origin", "main")
+		if err := FetchBare(); err != nil {
+			t.Fatalf("FetchBare() 失败: %v", err)
+		}
+
+		commits, truncated, err := CommitsTouching("refs/heads/main", base, []string{"bundles/a"}, 2)
+		if err != nil || truncated {
+			t.Fatalf("CommitsTouching() = %+v, %v, %v", commits, truncated, err)
+		}
+		found := false
+		for _, commit := range commits {
+			found = found || commit.Hash == side
+		}
+		if len(commits) != 2 || !found {
+			t.Fatalf("范围内应有合并提交与伪造时间的侧分支提交: %+v", commits)
+		}
+		if _, truncated, err := CommitsTouching("refs/heads/main", base, []string{"bundles/a"}, 1); err != nil || !truncated {
+			t.Fatalf("超过 limit 应标记截断: %v, %v", truncated, err)
+		}
+	})
+}
+
+// writeTestSigningKey 生成 ed25519 私钥文件，返回路径与 authorized_keys 格式的公钥行。
+func writeTestSigningKey(t *testing.T, comment string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " " + comment
+}
+
+func TestBackendConformance_OfflineReadAndQueuedPush(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "init\n"})
+		connectConformance(t, remote)
+		if fetched, err := LastFetchTime(); err != nil || fetched.IsZero() {
+			t.Fatalf("连接后应记录同步时间: %v, %v", fetched, err)
+		}
+
+		// 远端不可达：auto 回落到本地 bare repo
+		hidden := remote.dir + ".away"
+		if err := os.Rename(remote.dir, hidden); err != nil {
+			t.Fatal(err)
+		}
+		read, err := NewReadTransaction()
+		if err != nil {
+			t.Fatalf("远端不可达时 NewReadTransaction() 应回落本地: %v", err)
+		}
+		if !read.Offline() || !strings.Contains(read.OfflineReason(), "远端不可达") {
+			t.Fatalf("只读事务应标记离线, reason=%q", read.OfflineReason())
+		}
+		read.Close()
+
+		for i, name := range []string{"a.txt", "b.txt"} {
+			tx, err := NewWriteTransaction()
+			if err != nil {
+				t.Fatalf("离线 NewWriteTransaction() 失败: %v", err)
+			}
+			writeFile(t, filepath.Join(tx.WorkDir(), name), "offline\n")
+			committed, err := tx.CommitAndPush("offline " + name)
+			if err != nil || !committed || !tx.Queued() {
+				t.Fatalf("离线 CommitAndPush() = %v, %v, queued=%v", committed, err, tx.Queued())
+			}
+			tx.Close()
+			queue, err := PushQueue()
+			if err != nil || len(queue) != 1 || queue[0].Target != "main" || len(queue[0].Commits) != i+1 {
+				t.Fatalf("PushQueue() = %+v, %v", queue, err)
+			}
+		}
+
+		// 仍离线时不发送
+		if sent, err := FlushPushQueue(nil); err != nil || len(sent) != 0 {
+			t.Fatalf("离线时 FlushPushQueue(nil) = %+v, %v", sent, err)
+		}
+
+		if err := os.Rename(hidden, remote.dir); err != nil {
+			t.Fatal(err)
+		}
+		remote.commit(t, map[string]string{"remote.txt": "remote\n"}, "remote advance")
+		sent, err := FlushPushQueue(nil)
+		if err != nil || len(sent) != 1 || len(sent[0].Commits) != 2 {
+			t.Fatalf("FlushPushQueue(nil) = %+v, %v", sent, err)
+		}
+		for _, path := range []string{"a.txt", "b.txt", "remote.txt"} {
+			if _, ok := remote.file(t, path); !ok {
+				t.Fatalf("发送排队的提交后远端应包含 %s", path)
+			}
+		}
+		if queue, err := PushQueue(); err != nil || len(queue) != 0 {
+			t.Fatalf("发送后队列应清空: %+v, %v", queue, err)
+		}
+
+		// 强制离线：不访问远端
+		setEnvForTest(t, offlineEnv, OfflineOn)
+		if err := FetchBare(); !errors.Is(err, ErrOffline) {
+			t.Fatalf("强制离线时 FetchBare() = %v", err)
+		}
+		forced, err := NewReadTransaction()
+		if err != nil || !forced.Offline() {
+			t.Fatalf("强制离线的只读事务 = %v, %v", forced, err)
+		}
+		forced.Close()
+	})
+}
+
+func TestBackendConformance_ReadSnapshotPool(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		remote := newConformanceRemote(t, map[string]string{"README.md": "init\n", "docs/a.md": "a\n"})
+		connectConformance(t, remote)
+		SetReadPool(2, time.Minute)
+		t.Cleanup(func() { SetReadPool(0, 0) })
+
+		open := func(opts ReadOptions) *Transaction {
+			t.Helper()
+			opts.Local = true
+			tx, err := NewReadTransactionWith(opts)
+			if err != nil {
+				t.Fatalf("NewReadTransactionWith() 失败: %v", err)
+			}
+			return tx
+		}
+		selectDocs := func(files []string) []string { return []string{"docs/a.md"} }
+
+		first, second := open(ReadOptions{}), open(ReadOptions{})
+		if first.WorkDir() != second.WorkDir() {
+			t.Fatalf("同一提交的只读事务应共享检出: %s vs %s", first.WorkDir(), second.WorkDir())
+		}
+		first.Close()
+		if _, err := os.Stat(filepath.Join(second.WorkDir(), "README.md")); err != nil {
+			t.Fatalf("仍在使用的快照不应被删除: %v", err)
+		}
+		second.Close()
+		reused := open(ReadOptions{})
+		if reused.WorkDir() != first.WorkDir() || reused.CommitHash() != remote.head(t).Hash.String() {
+			t.Fatalf("空闲快照应被复用: %s", reused.WorkDir())
+		}
+
+		sparse := open(ReadOptions{Select: selectDocs})
+		if sparse.WorkDir() == reused.WorkDir() || !PathExists(filepath.Join(sparse.WorkDir(), "README.md")) {
+			t.Fatalf("不同物化范围应各自检出，且保留完整文件索引: %s", sparse.WorkDir())
+		}
+		if again := open(ReadOptions{Select: selectDocs}); again.WorkDir() != sparse.WorkDir() {
+			t.Fatalf("相同物化范围的稀疏事务应共享检出")
+		} else {
+			again.Close()
+		}
+
+		write, err := NewWriteTransaction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if write.WorkDir() == reused.WorkDir() {
+			t.Fatal("可写事务不应使用快照池")
+		}
+		writeFile(t, filepath.Join(write.WorkDir(), "new.txt"), "new\n")
+		if _, err := write.CommitAndPush("add new"); err != nil {
+			t.Fatal(err)
+		}
+		write.Close()
+		if _, err := os.Stat(filepath.Join(reused.WorkDir(), "new.txt")); err == nil {
+			t.Fatal("已有快照不应看到新提交")
+		}
+		latest := open(ReadOptions{})
+		if latest.WorkDir() == reused.WorkDir() {
+			t.Fatal("新提交应使用新的快照")
+		}
+		old := reused.WorkDir()
+		reused.Close()
+		sparse.Close()
+		latest.Close()
+
+		// 空闲上限为 2：最早释放的快照被回收
+		if _, err := os.Stat(old); !os.IsNotExist(err) {
+			t.Fatalf("超出空闲上限的快照应被回收: %v", err)
+		}
+		SetReadPool(0, 0)
+		for _, dir := range []string{sparse.WorkDir(), latest.WorkDir()} {
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Fatalf("关闭快照池应回收空闲快照 %s: %v", dir, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,496 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shichao402/Dec/internal/sysproc"
+)
+
+// execBackend 通过系统 git 可执行文件完成全部操作。
+type execBackend struct{}
+
+func (execBackend) name() string { return BackendGit }
+
+// runGitDir 在指定 git 目录上执行 git 命令，失败时把输出带进错误。
+func runGitDir(gitDir string, args ...string) (string, error) {
+	full := append([]string{"--git-dir", gitDir}, args...)
+	output, err := sysproc.Command("git", full...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (execBackend) isBareRepo(dir string) (bool, error) {
+	cmd := sysproc.Command("git", "--git-dir", dir, "rev-parse", "--is-bare-repository")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("git rev-parse --is-bare-repository: %s", strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
+func (execBackend) cloneBare(url, dir string) error {
+	cmd := sysproc.Command("git", "clone", "--bare", url, dir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git clone --bare 失败: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (execBackend) fetchBare(bareDir string) error {
+	cmd := sysproc.Command("git", "--git-dir", bareDir, "fetch", "--prune", "origin", "+refs/heads/*:refs/heads/*")
+	// 禁止交互：凭证过期时不能让 git/GCM 弹窗阻塞 dec-server，失败后由门面显式确认走 bootstrap。
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=Never")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		message := strings.TrimSpace(string(output))
+		if message == "" {
+			message = err.Error()
+		}
+		return fmt.Errorf("git fetch --prune origin: %s", message)
+	}
+	return nil
+}
+
+func (execBackend) remoteHeadBranch(bareDir string) (string, error) {
+	cmd := sysproc.Command("git", "--git-dir", bareDir, "ls-remote", "--symref", "origin", "HEAD")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("读取远端默认分支失败: %s", strings.TrimSpace(string(output)))
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "ref: ") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		ref := fields[1]
+		if !strings.HasPrefix(ref, "refs/heads/") {
+			continue
+		}
+		return strings.TrimPrefix(ref, "refs/heads/"), nil
+	}
+
+	return "", nil
+}
+
+func (execBackend) headBranch(bareDir string) (string, error) {
+	return runGitDir(bareDir, "symbolic-ref", "--short", "HEAD")
+}
+
+func (execBackend) setHeadBranch(bareDir, branch string) error {
+	if _, err := runGitDir(bareDir, "symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+		return fmt.Errorf("同步默认分支失败: %w", err)
+	}
+	return nil
+}
+
+func (execBackend) resolveRef(bareDir, ref string) (string, error) {
+	return runGitDir(bareDir, "rev-parse", "--verify", ref+"^{commit}")
+}
+
+func (execBackend) updateRef(bareDir, branch, hash string) error {
+	_, err := runGitDir(bareDir, "update-ref", "refs/heads/"+branch, hash)
+	return err
+}
+
+func (execBackend) listBranches(bareDir, prefix string) ([]string, error) {
+	// for-each-ref 的通配符不跨越 /，列出全部分支后再按前缀过滤，与 go-git 实现保持一致。
+	output, err := runGitDir(bareDir, "for-each-ref", "--format=%(refname)", "refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, ref := range strings.Fields(output) {
+		if name := strings.TrimPrefix(ref, "refs/heads/"); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (execBackend) isAncestor(bareDir, ancestor, descendant string) (bool, error) {
+	output, err := sysproc.Command("git", "--git-dir", bareDir, "merge-base", "--is-ancestor", ancestor, descendant).CombinedOutput()
+	if err == nil {
+		return true, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("git merge-base --is-ancestor: %s", strings.TrimSpace(string(output)))
+}
+
+func (execBackend) deleteBranch(bareDir, branch string) error {
+	_, err := runGitDir(bareDir, "branch", "-D", branch)
+	return err
+}
+
+func (execBackend) setRef(bareDir, ref, hash string) error {
+	_, err := runGitDir(bareDir, "update-ref", ref, hash)
+	return err
+}
+
+func (execBackend) deleteRef(bareDir, ref string) error {
+	_, err := runGitDir(bareDir, "update-ref", "-d", ref)
+	return err
+}
+
+func (execBackend) listRefs(bareDir, prefix string) ([]namedRef, error) {
+	output, err := runGitDir(bareDir, "for-each-ref", "--format=%(refname) %(objectname)", prefix)
+	if err != nil {
+		return nil, err
+	}
+	var refs []namedRef
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.HasPrefix(fields[0], prefix) {
+			refs = append(refs, namedRef{name: fields[0], hash: fields[1]})
+		}
+	}
+	return refs, nil
+}
+
+func (execBackend) remoteNames(gitDir string) ([]string, error) {
+	output, err := runGitDir(gitDir, "remote")
+	if err != nil {
+		return nil, fmt.Errorf("git remote 失败: %w", err)
+	}
+	return strings.Fields(output), nil
+}
+
+func (execBackend) remoteURL(gitDir, remote string) (string, error) {
+	cmd := sysproc.Command("git", "--git-dir", gitDir, "config", "--get", fmt.Sprintf("remote.%s.url", remote))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("读取 remote %s URL 失败: %s", remote, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (execBackend) addRemote(gitDir, remote, url string) error {
+	if _, err := runGitDir(gitDir, "remote", "add", remote, url); err != nil {
+		return fmt.Errorf("git remote add 失败: %w", err)
+	}
+	return nil
+}
+
+func (execBackend) setRemoteURL(gitDir, remote, url string) error {
+	if _, err := runGitDir(gitDir, "remote", "set-url", remote, url); err != nil {
+		return fmt.Errorf("git remote set-url 失败: %w", err)
+	}
+	return nil
+}
+
+func (execBackend) listRemoteHeads(url string) error {
+	cmd := sysproc.Command("git", "ls-remote", "--heads", url)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=Never")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	message := strings.TrimSpace(string(output))
+	if message == "" {
+		message = err.Error()
+	}
+	return fmt.Errorf("git ls-remote: %s", message)
+}
+
+func (execBackend) addWorktree(bareDir, worktreeDir, startPoint string) error {
+	cmd := sysproc.Command("git", "--git-dir", bareDir, "worktree", "add", "--detach", worktreeDir, startPoint)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree add 失败: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (execBackend) removeWorktree(bareDir, worktreeDir string) error {
+	cmd := sysproc.Command("git", "--git-dir", bareDir, "worktree", "remove", "--force", worktreeDir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree remove 失败: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (execBackend) pruneWorktrees(bareDir string) {
+	_ = sysproc.Command("git", "--git-dir", bareDir, "worktree", "prune").Run()
+}
+
+func (execBackend) createBranch(worktreeDir, branch string) error {
+	_, err := NewGitOps(worktreeDir).run("switch", "-c", branch)
+	return err
+}
+
+func (execBackend) checkout(worktreeDir, ref string) error {
+	_, err := NewGitOps(worktreeDir).run("checkout", ref)
+	return err
+}
+
+func (execBackend) headCommit(worktreeDir string) (string, error) {
+	return NewGitOps(worktreeDir).run("rev-parse", "HEAD")
+}
+
+func (execBackend) isClean(worktreeDir string) (bool, error) {
+	return NewGitOps(worktreeDir).IsClean()
+}
+
+func (execBackend) commitAll(worktreeDir, message string) (bool, error) {
+	git := NewGitOps(worktreeDir)
+	if err := git.Add("."); err != nil {
+		return false, fmt.Errorf("git add 失败: %w", err)
+	}
+	// status 可能因换行等判脏，add 后用 cached diff 判定是否真有可提交内容。
+	if has, err := git.HasCachedDiff(); err != nil {
+		return false, err
+	} else if !has {
+		return false, nil
+	}
+	if _, err := git.run(append(gitSigningArgs(CommitSigning()), "commit", "-m", message)...); err != nil {
+		if isNothingToCommitError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("git commit 失败: %w", err)
+	}
+	return true, nil
+}
+
+func (execBackend) push(worktreeDir, branch string) error {
+	_, err := NewGitOps(worktreeDir).run("push", "origin", fmt.Sprintf("HEAD:%s", branch))
+	return err
+}
+
+func (execBackend) integrateRemote(worktreeDir, branch string) error {
+	git := NewGitOps(worktreeDir)
+	if err := git.ensureNoSyncInProgress(); err != nil {
+		return err
+	}
+	if _, err := git.run("fetch", "origin", branch); err != nil {
+		return fmt.Errorf("拉取远端引用失败: %w", err)
+	}
+	if _, err := git.run(append(gitSigningArgs(CommitSigning()), "merge", "--no-edit", "FETCH_HEAD")...); err != nil {
+		_ = git.abortMerge()
+		return fmt.Errorf("与远端存在冲突，请稍后重试: %w", err)
+	}
+	return nil
+}
+
+func (execBackend) listTree(bareDir, commit string) ([]treeEntry, error) {
+	cmd := sysproc.Command("git", "--git-dir", bareDir, "ls-tree", "-r", "-z", "--full-tree", commit)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree 失败: %s", strings.TrimSpace(stderr.String()))
+	}
+	var entries []treeEntry
+	for _, record := range strings.Split(string(output), "\x00") {
+		// <mode> SP <type> SP <object> TAB <file>
+		meta, file, ok := strings.Cut(record, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		mode, err := strconv.ParseUint(fields[0], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("git ls-tree 输出无法解析: %q", record)
+		}
+		entries = append(entries, treeEntry{path: file, mode: uint32(mode), hash: fields[2]})
+	}
+	return entries, nil
+}
+
+// commitLogFormat 以 NUL 分隔字段、RS 分隔提交，说明里的换行原样保留。
+const commitLogFormat = "--format=%H%x00%P%x00%an%x00%ae%x00%at%x00%B%x1e"
+
+func (execBackend) logPaths(bareDir, commit string, paths []string, limit int) ([]CommitInfo, error) {
+	args := []string{"log", commitLogFormat, "--date-order"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", limit))
+	}
+	args = append(args, commit, "--")
+	for _, p := range paths {
+		args = append(args, strings.TrimSuffix(p, "/"))
+	}
+	output, err := gitStdout(bareDir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommitLog(output)
+}
+
+func (execBackend) logRange(bareDir, head, exclude string, paths []string, limit int) ([]CommitInfo, error) {
+	args := []string{"log", commitLogFormat, "--full-history"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", limit))
+	}
+	args = append(args, head)
+	if exclude != "" {
+		args = append(args, "^"+exclude)
+	}
+	args = append(args, "--")
+	for _, p := range paths {
+		args = append(args, strings.TrimSuffix(p, "/"))
+	}
+	output, err := gitStdout(bareDir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommitLog(output)
+}
+
+func (execBackend) readCommit(bareDir, commit string) (CommitInfo, error) {
+	output, err := gitStdout(bareDir, "log", "-1", commitLogFormat, commit, "--")
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	commits, err := parseCommitLog(output)
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	if len(commits) != 1 {
+		return CommitInfo{}, fmt.Errorf("读取提交 %s 失败", commit)
+	}
+	return commits[0], nil
+}
+
+func (execBackend) readRawCommit(bareDir, commit string) ([]byte, error) {
+	output, err := gitStdout(bareDir, "cat-file", "commit", commit)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(output), nil
+}
+
+// gitStdout 只取标准输出，避免 stderr 的提示混进需要解析的内容。
+func gitStdout(gitDir string, args ...string) (string, error) {
+	cmd := sysproc.Command("git", append([]string{"--git-dir", gitDir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// parseCommitLog 解析 commitLogFormat 的输出。
+func parseCommitLog(output string) ([]CommitInfo, error) {
+	var commits []CommitInfo
+	for _, record := range strings.Split(output, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x00", 6)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("git log 输出无法解析: %q", record)
+		}
+		unix, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("git log 输出无法解析: %q", record)
+		}
+		info := CommitInfo{
+			Hash:    fields[0],
+			Parents: strings.Fields(fields[1]),
+			Author:  fields[2],
+			Email:   fields[3],
+			Time:    time.Unix(unix, 0),
+		}
+		info.Subject, info.Message = splitCommitMessage(fields[5])
+		commits = append(commits, info)
+	}
+	return commits, nil
+}
+
+func (execBackend) checkoutFiles(bareDir, dir string, entries []treeEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	cmd := sysproc.Command("git", "--git-dir", bareDir, "cat-file", "--batch")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git cat-file 启动失败: %w", err)
+	}
+	go func() {
+		w := bufio.NewWriter(stdin)
+		for _, entry := range entries {
+			_, _ = w.WriteString(entry.hash + "\n")
+		}
+		_ = w.Flush()
+		_ = stdin.Close()
+	}()
+
+	readErr := readCatFileBatch(bufio.NewReader(stdout), dir, entries)
+	if readErr != nil {
+		_ = cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+	if readErr != nil {
+		return readErr
+	}
+	if waitErr != nil {
+		return fmt.Errorf("git cat-file 失败: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// readCatFileBatch 按请求顺序读取 `git cat-file --batch` 的输出并写出文件。
+func readCatFileBatch(r *bufio.Reader, dir string, entries []treeEntry) error {
+	for _, entry := range entries {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", entry.path, err)
+		}
+		// <object> SP <type> SP <size> LF；对象缺失时为 <object> SP missing LF
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return fmt.Errorf("读取 %s 失败: %s", entry.path, strings.TrimSpace(header))
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %s", entry.path, strings.TrimSpace(header))
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", entry.path, err)
+		}
+		if _, err := r.Discard(1); err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", entry.path, err)
+		}
+		if err := writeTreeFile(dir, entry, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
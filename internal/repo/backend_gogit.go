@@ -0,0 +1,978 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// goGitBackend 基于 go-git 的纯 Go 实现，用于没有 git 可执行文件的环境。
+//
+// 事务工作区沿用 git worktree 的磁盘布局（<bare>/worktrees/<name> 存 HEAD 与 index，
+// 工作区 .git 是指向它的 gitdir 文件），对象与分支直接写进 bare，推送后无需再搬运对象。
+// 远端前进时不做真正的 merge，而是在远端最新提交上重放本事务的改动；
+// 同一路径两边都改且结果不同即视为冲突。
+type goGitBackend struct{}
+
+func (goGitBackend) name() string { return BackendGoGit }
+
+func openGoGitRepo(dir string) (*git.Repository, error) {
+	useInProcessFileTransport()
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("打开仓库 %s 失败: %w", dir, err)
+	}
+	return r, nil
+}
+
+func (goGitBackend) isBareRepo(dir string) (bool, error) {
+	r, err := openGoGitRepo(dir)
+	if err != nil {
+		return false, err
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return false, fmt.Errorf("读取仓库配置失败: %w", err)
+	}
+	return cfg.Core.IsBare, nil
+}
+
+func (b goGitBackend) cloneBare(url, dir string) error {
+	useInProcessFileTransport()
+	r, err := git.PlainInit(dir, true)
+	if err != nil {
+		return fmt.Errorf("git clone --bare 失败: %w", err)
+	}
+	if _, err := r.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{url}}); err != nil {
+		_ = os.RemoveAll(dir)
+		return fmt.Errorf("git clone --bare 失败: %w", err)
+	}
+	if err := b.fetchBare(dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return fmt.Errorf("git clone --bare 失败: %w", err)
+	}
+	branch, err := b.remoteHeadBranch(dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return fmt.Errorf("git clone --bare 失败: %w", err)
+	}
+	if branch != "" {
+		return b.setHeadBranch(dir, branch)
+	}
+	return nil
+}
+
+func (b goGitBackend) fetchBare(bareDir string) error {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return err
+	}
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return fmt.Errorf("git fetch --prune origin: %w", err)
+	}
+	var advertised []*plumbing.Reference
+	err = withGoGitAuth(remoteConfigURL(remote.Config()), func(auth transport.AuthMethod) error {
+		var listErr error
+		advertised, listErr = remote.List(&git.ListOptions{Auth: auth})
+		if listErr != nil {
+			return listErr
+		}
+		return remote.Fetch(&git.FetchOptions{
+			RefSpecs: []gitconfig.RefSpec{"+refs/heads/*:refs/heads/*"},
+			Force:    true,
+			Auth:     auth,
+		})
+	})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("git fetch --prune origin: %w", err)
+	}
+
+	// 手动 prune：事务临时分支只存在于本地，不能随远端一起清掉
+	remoteHeads := make(map[plumbing.ReferenceName]bool, len(advertised))
+	for _, ref := range advertised {
+		remoteHeads[ref.Name()] = true
+	}
+	locals, err := b.listBranches(bareDir, "")
+	if err != nil {
+		return err
+	}
+	for _, name := range locals {
+		if strings.HasPrefix(name, txBranchPrefix) || remoteHeads[plumbing.NewBranchReferenceName(name)] {
+			continue
+		}
+		if err := b.deleteBranch(bareDir, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listRemoteRefs(url string) ([]*plumbing.Reference, error) {
+	useInProcessFileTransport()
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{Name: "origin", URLs: []string{url}})
+	var refs []*plumbing.Reference
+	err := withGoGitAuth(url, func(auth transport.AuthMethod) error {
+		var listErr error
+		refs, listErr = remote.List(&git.ListOptions{Auth: auth})
+		return listErr
+	})
+	return refs, err
+}
+
+// remoteConfigURL 返回 remote 的第一个 URL，用于查找凭证。
+func remoteConfigURL(cfg *gitconfig.RemoteConfig) string {
+	if cfg == nil || len(cfg.URLs) == 0 {
+		return ""
+	}
+	return cfg.URLs[0]
+}
+
+// originURL 返回仓库 origin 的 URL；没有 origin 时返回空串。
+func originURL(r *git.Repository) string {
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return ""
+	}
+	return remoteConfigURL(remote.Config())
+}
+
+// localRepoPath 识别本地路径与 file:// 地址；进程内传输不通告 symref，远端 HEAD 需直接读取。
+func localRepoPath(url string) (string, bool) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil || endpoint.Protocol != "file" {
+		return "", false
+	}
+	return localEndpointPath(endpoint), true
+}
+
+func (b goGitBackend) remoteHeadBranch(bareDir string) (string, error) {
+	url, err := b.remoteURL(bareDir, "origin")
+	if err != nil {
+		return "", err
+	}
+	if path, ok := localRepoPath(url); ok {
+		return b.headBranch(path)
+	}
+	refs, err := listRemoteRefs(url)
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取远端默认分支失败: %w", err)
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference && ref.Target().IsBranch() {
+			return ref.Target().Short(), nil
+		}
+	}
+	return "", nil
+}
+
+func (goGitBackend) headBranch(gitDir string) (string, error) {
+	r, err := openGoGitRepo(gitDir)
+	if err != nil {
+		return "", err
+	}
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", fmt.Errorf("读取 HEAD 失败: %w", err)
+	}
+	if head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+		return "", fmt.Errorf("HEAD 未指向分支")
+	}
+	return head.Target().Short(), nil
+}
+
+func (goGitBackend) setHeadBranch(bareDir, branch string) error {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return err
+	}
+	ref := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch))
+	if err := r.Storer.SetReference(ref); err != nil {
+		return fmt.Errorf("同步默认分支失败: %w", err)
+	}
+	return nil
+}
+
+func (goGitBackend) resolveRef(gitDir, ref string) (string, error) {
+	r, err := openGoGitRepo(gitDir)
+	if err != nil {
+		return "", err
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", fmt.Errorf("解析 %s 失败: %w", ref, err)
+	}
+	return hash.String(), nil
+}
+
+func (goGitBackend) updateRef(bareDir, branch, hash string) error {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return err
+	}
+	return r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), plumbing.NewHash(hash)))
+}
+
+func (goGitBackend) listBranches(bareDir, prefix string) ([]string, error) {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := r.Branches()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if name := ref.Name().Short(); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (goGitBackend) isAncestor(bareDir, ancestor, descendant string) (bool, error) {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return false, err
+	}
+	from, err := r.CommitObject(plumbing.NewHash(ancestor))
+	if err != nil {
+		return false, fmt.Errorf("读取提交 %s 失败: %w", ancestor, err)
+	}
+	to, err := r.CommitObject(plumbing.NewHash(descendant))
+	if err != nil {
+		return false, fmt.Errorf("读取提交 %s 失败: %w", descendant, err)
+	}
+	return from.IsAncestor(to)
+}
+
+func (goGitBackend) deleteBranch(bareDir, branch string) error {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return err
+	}
+	return r.Storer.RemoveReference(plumbing.NewBranchReferenceName(branch))
+}
+
+func (goGitBackend) setRef(bareDir, ref, hash string) error {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return err
+	}
+	return r.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(ref), plumbing.NewHash(hash)))
+}
+
+func (goGitBackend) deleteRef(bareDir, ref string) error {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return err
+	}
+	return r.Storer.RemoveReference(plumbing.ReferenceName(ref))
+}
+
+func (goGitBackend) listRefs(bareDir, prefix string) ([]namedRef, error) {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+	var refs []namedRef
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && strings.HasPrefix(ref.Name().String(), prefix) {
+			refs = append(refs, namedRef{name: ref.Name().String(), hash: ref.Hash().String()})
+		}
+		return nil
+	})
+	sort.Slice(refs, func(i, j int) bool { return refs[i].name < refs[j].name })
+	return refs, err
+}
+
+func (goGitBackend) remoteNames(gitDir string) ([]string, error) {
+	r, err := openGoGitRepo(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, fmt.Errorf("读取仓库配置失败: %w", err)
+	}
+	names := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (goGitBackend) remoteURL(gitDir, remote string) (string, error) {
+	r, err := openGoGitRepo(gitDir)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return "", fmt.Errorf("读取 remote %s URL 失败: %w", remote, err)
+	}
+	rc, ok := cfg.Remotes[remote]
+	if !ok || len(rc.URLs) == 0 {
+		return "", fmt.Errorf("读取 remote %s URL 失败: 未配置", remote)
+	}
+	return rc.URLs[0], nil
+}
+
+func (goGitBackend) addRemote(gitDir, remote, url string) error {
+	r, err := openGoGitRepo(gitDir)
+	if err != nil {
+		return err
+	}
+	if _, err := r.CreateRemote(&gitconfig.RemoteConfig{Name: remote, URLs: []string{url}}); err != nil {
+		return fmt.Errorf("git remote add 失败: %w", err)
+	}
+	return nil
+}
+
+func (goGitBackend) setRemoteURL(gitDir, remote, url string) error {
+	r, err := openGoGitRepo(gitDir)
+	if err != nil {
+		return err
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return fmt.Errorf("git remote set-url 失败: %w", err)
+	}
+	rc, ok := cfg.Remotes[remote]
+	if !ok {
+		return fmt.Errorf("git remote set-url 失败: remote %s 不存在", remote)
+	}
+	rc.URLs = []string{url}
+	if err := r.SetConfig(cfg); err != nil {
+		return fmt.Errorf("git remote set-url 失败: %w", err)
+	}
+	return nil
+}
+
+func (goGitBackend) listRemoteHeads(url string) error {
+	_, err := listRemoteRefs(url)
+	if err == nil || errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return nil
+	}
+	return fmt.Errorf("git ls-remote: %v", err)
+}
+
+func (goGitBackend) listTree(bareDir, commit string) ([]treeEntry, error) {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 失败: %w", commit, err)
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 的目录树失败: %w", commit, err)
+	}
+	var entries []treeEntry
+	err = tree.Files().ForEach(func(f *object.File) error {
+		entries = append(entries, treeEntry{path: f.Name, mode: uint32(f.Mode), hash: f.Hash.String()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("遍历提交 %s 的目录树失败: %w", commit, err)
+	}
+	return entries, nil
+}
+
+func (goGitBackend) logPaths(bareDir, commit string, paths []string, limit int) ([]CommitInfo, error) {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return nil, err
+	}
+	opts := &git.LogOptions{From: plumbing.NewHash(commit), Order: git.LogOrderCommitterTime}
+	if len(paths) > 0 {
+		opts.PathFilter = func(file string) bool { return PathWithin(file, paths) }
+	}
+	iter, err := r.Log(opts)
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 的历史失败: %w", commit, err)
+	}
+	defer iter.Close()
+	var commits []CommitInfo
+	err = iter.ForEach(func(c *object.Commit) error {
+		commits = append(commits, goGitCommitInfo(c))
+		if limit > 0 && len(commits) >= limit {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 的历史失败: %w", commit, err)
+	}
+	return commits, nil
+}
+
+func (goGitBackend) logRange(bareDir, head, exclude string, paths []string, limit int) ([]CommitInfo, error) {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return nil, err
+	}
+	// 先收集 exclude 可达的全部提交，再从 head 遍历时跳过它们（不按提交时间截断）。
+	excluded := make(map[plumbing.Hash]bool)
+	if exclude != "" {
+		base, err := r.CommitObject(plumbing.NewHash(exclude))
+		if err != nil {
+			return nil, fmt.Errorf("读取提交 %s 失败: %w", exclude, err)
+		}
+		if err := object.NewCommitPreorderIter(base, nil, nil).ForEach(func(c *object.Commit) error {
+			excluded[c.Hash] = true
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("读取提交 %s 的历史失败: %w", exclude, err)
+		}
+	}
+	start, err := r.CommitObject(plumbing.NewHash(head))
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 失败: %w", head, err)
+	}
+	var commits []CommitInfo
+	err = object.NewCommitPreorderIter(start, excluded, nil).ForEach(func(c *object.Commit) error {
+		touched, err := goGitCommitTouches(c, paths)
+		if err != nil {
+			return err
+		}
+		if !touched {
+			return nil
+		}
+		commits = append(commits, goGitCommitInfo(c))
+		if limit > 0 && len(commits) >= limit {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 的历史失败: %w", head, err)
+	}
+	return commits, nil
+}
+
+// goGitCommitTouches 判断提交是否改动了 paths：与任一父提交在某个 path 上的条目不同即算；根提交含任一 path 即算。
+func goGitCommitTouches(c *object.Commit, paths []string) (bool, error) {
+	if len(paths) == 0 {
+		return true, nil
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return false, err
+	}
+	own := goGitPathEntries(tree, paths)
+	if c.NumParents() == 0 {
+		for _, entry := range own {
+			if !entry.Hash.IsZero() {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	touched := false
+	err = c.Parents().ForEach(func(parent *object.Commit) error {
+		parentTree, err := parent.Tree()
+		if err != nil {
+			return err
+		}
+		for i, entry := range goGitPathEntries(parentTree, paths) {
+			if entry.Hash != own[i].Hash || entry.Mode != own[i].Mode {
+				touched = true
+				return storer.ErrStop
+			}
+		}
+		return nil
+	})
+	return touched, err
+}
+
+// goGitPathEntries 返回每个 path 在树中的条目（文件或目录），不存在时为零值。
+func goGitPathEntries(tree *object.Tree, paths []string) []object.TreeEntry {
+	entries := make([]object.TreeEntry, len(paths))
+	for i, p := range paths {
+		if entry, err := tree.FindEntry(strings.TrimSuffix(p, "/")); err == nil {
+			entries[i] = *entry
+		}
+	}
+	return entries
+}
+
+func (goGitBackend) readCommit(bareDir, commit string) (CommitInfo, error) {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	c, err := r.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return CommitInfo{}, fmt.Errorf("读取提交 %s 失败: %w", commit, err)
+	}
+	return goGitCommitInfo(c), nil
+}
+
+func (goGitBackend) readRawCommit(bareDir, commit string) ([]byte, error) {
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := r.Storer.EncodedObject(plumbing.CommitObject, plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 失败: %w", commit, err)
+	}
+	reader, err := obj.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 失败: %w", commit, err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func goGitCommitInfo(c *object.Commit) CommitInfo {
+	info := CommitInfo{
+		Hash:   c.Hash.String(),
+		Author: c.Author.Name,
+		Email:  c.Author.Email,
+		Time:   c.Author.When,
+	}
+	for _, parent := range c.ParentHashes {
+		info.Parents = append(info.Parents, parent.String())
+	}
+	info.Subject, info.Message = splitCommitMessage(c.Message)
+	return info
+}
+
+func (goGitBackend) checkoutFiles(bareDir, dir string, entries []treeEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	r, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		blob, err := r.BlobObject(plumbing.NewHash(entry.hash))
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", entry.path, err)
+		}
+		reader, err := blob.Reader()
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", entry.path, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", entry.path, err)
+		}
+		if err := writeTreeFile(dir, entry, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// worktreeAdminDir 返回工作区在 bare 中的元数据目录；优先读工作区 .git 指针，兼容 git 自动加序号的情况。
+func worktreeAdminDir(bareDir, worktreeDir string) string {
+	if data, err := os.ReadFile(filepath.Join(worktreeDir, ".git")); err == nil {
+		if dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:"); ok {
+			return strings.TrimSpace(dir)
+		}
+	}
+	return filepath.Join(bareDir, "worktrees", filepath.Base(worktreeDir))
+}
+
+func (goGitBackend) addWorktree(bareDir, worktreeDir, startPoint string) error {
+	bare, err := openGoGitRepo(bareDir)
+	if err != nil {
+		return err
+	}
+	hash, err := bare.ResolveRevision(plumbing.Revision(startPoint))
+	if err != nil {
+		return fmt.Errorf("git worktree add 失败: 解析 %s: %w", startPoint, err)
+	}
+
+	adminDir := filepath.Join(bareDir, "worktrees", filepath.Base(worktreeDir))
+	files := map[string]string{
+		filepath.Join(adminDir, "HEAD"):      hash.String() + "\n",
+		filepath.Join(adminDir, "commondir"): filepath.Join("..", "..") + "\n",
+		filepath.Join(adminDir, "gitdir"):    filepath.Join(worktreeDir, ".git") + "\n",
+		filepath.Join(worktreeDir, ".git"):   "gitdir: " + adminDir + "\n",
+	}
+	fail := func(err error) error {
+		_ = os.RemoveAll(worktreeDir)
+		_ = os.RemoveAll(adminDir)
+		return fmt.Errorf("git worktree add 失败: %w", err)
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fail(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fail(err)
+		}
+	}
+
+	r, err := openGoGitRepo(worktreeDir)
+	if err != nil {
+		return fail(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return fail(err)
+	}
+	if err := wt.Reset(&git.ResetOptions{Commit: *hash, Mode: git.HardReset}); err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+func (goGitBackend) removeWorktree(bareDir, worktreeDir string) error {
+	adminDir := worktreeAdminDir(bareDir, worktreeDir)
+	if err := os.RemoveAll(worktreeDir); err != nil {
+		return fmt.Errorf("git worktree remove 失败: %w", err)
+	}
+	if err := os.RemoveAll(adminDir); err != nil {
+		return fmt.Errorf("git worktree remove 失败: %w", err)
+	}
+	return nil
+}
+
+func (goGitBackend) pruneWorktrees(bareDir string) {
+	root := filepath.Join(bareDir, "worktrees")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		adminDir := filepath.Join(root, entry.Name())
+		data, err := os.ReadFile(filepath.Join(adminDir, "gitdir"))
+		if err == nil {
+			if _, statErr := os.Stat(strings.TrimSpace(string(data))); statErr == nil {
+				continue
+			}
+		}
+		_ = os.RemoveAll(adminDir)
+	}
+}
+
+func openGoGitWorktree(worktreeDir string) (*git.Repository, *git.Worktree, error) {
+	r, err := openGoGitRepo(worktreeDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开工作区 %s 失败: %w", worktreeDir, err)
+	}
+	return r, wt, nil
+}
+
+func (goGitBackend) createBranch(worktreeDir, branch string) error {
+	r, err := openGoGitRepo(worktreeDir)
+	if err != nil {
+		return err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return fmt.Errorf("读取 HEAD 失败: %w", err)
+	}
+	name := plumbing.NewBranchReferenceName(branch)
+	if err := r.Storer.SetReference(plumbing.NewHashReference(name, head.Hash())); err != nil {
+		return err
+	}
+	return r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, name))
+}
+
+func (goGitBackend) checkout(worktreeDir, ref string) error {
+	r, wt, err := openGoGitWorktree(worktreeDir)
+	if err != nil {
+		return err
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return fmt.Errorf("解析 %s 失败: %w", ref, err)
+	}
+	return wt.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true})
+}
+
+func (goGitBackend) headCommit(worktreeDir string) (string, error) {
+	r, err := openGoGitRepo(worktreeDir)
+	if err != nil {
+		return "", err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return "", fmt.Errorf("读取 HEAD 失败: %w", err)
+	}
+	return head.Hash().String(), nil
+}
+
+func (goGitBackend) isClean(worktreeDir string) (bool, error) {
+	_, wt, err := openGoGitWorktree(worktreeDir)
+	if err != nil {
+		return false, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return false, fmt.Errorf("读取工作区状态失败: %w", err)
+	}
+	return status.IsClean(), nil
+}
+
+// commitSignature 依次取仓库与全局 git 配置里的 user.name / user.email，都没有时用占位身份。
+func commitSignature(r *git.Repository) *object.Signature {
+	sig := &object.Signature{Name: "Dec", Email: "dec@localhost", When: time.Now()}
+	cfg, err := r.ConfigScoped(gitconfig.GlobalScope)
+	if err != nil {
+		return sig
+	}
+	if cfg.User.Name != "" {
+		sig.Name = cfg.User.Name
+	}
+	if cfg.User.Email != "" {
+		sig.Email = cfg.User.Email
+	}
+	return sig
+}
+
+func (goGitBackend) commitAll(worktreeDir, message string) (bool, error) {
+	r, wt, err := openGoGitWorktree(worktreeDir)
+	if err != nil {
+		return false, err
+	}
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return false, fmt.Errorf("git add 失败: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return false, fmt.Errorf("读取工作区状态失败: %w", err)
+	}
+	if status.IsClean() {
+		return false, nil
+	}
+	signer, err := goGitSigner(CommitSigning())
+	if err != nil {
+		return false, err
+	}
+	sig := commitSignature(r)
+	// 与 git commit 默认的 cleanup 一致：去掉首尾空白并以换行结尾
+	message = strings.TrimSpace(message) + "\n"
+	if _, err := wt.Commit(message, &git.CommitOptions{All: true, Author: sig, Committer: sig, Signer: signer}); err != nil {
+		if errors.Is(err, git.ErrEmptyCommit) {
+			return false, nil
+		}
+		return false, fmt.Errorf("git commit 失败: %w", err)
+	}
+	return true, nil
+}
+
+func (goGitBackend) push(worktreeDir, branch string) error {
+	r, err := openGoGitRepo(worktreeDir)
+	if err != nil {
+		return err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return fmt.Errorf("读取 HEAD 失败: %w", err)
+	}
+	spec := gitconfig.RefSpec(fmt.Sprintf("%s:%s", head.Hash(), plumbing.NewBranchReferenceName(branch)))
+	err = withGoGitAuth(originURL(r), func(auth transport.AuthMethod) error {
+		return r.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []gitconfig.RefSpec{spec}, Auth: auth})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("git push origin HEAD:%s: %w", branch, err)
+	}
+	return nil
+}
+
+// fileState 是某路径在一棵树里的内容；deleted 表示该路径在这一侧被删除。
+type fileState struct {
+	hash    plumbing.Hash
+	mode    filemode.FileMode
+	deleted bool
+}
+
+// treeDelta 列出 from → to 之间每个变动路径的最终状态。
+func treeDelta(from, to *object.Tree) (map[string]fileState, error) {
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, err
+	}
+	delta := make(map[string]fileState, len(changes))
+	for _, change := range changes {
+		if change.To.Name == "" {
+			delta[change.From.Name] = fileState{deleted: true}
+			continue
+		}
+		delta[change.To.Name] = fileState{hash: change.To.TreeEntry.Hash, mode: change.To.TreeEntry.Mode}
+		if change.From.Name != "" && change.From.Name != change.To.Name {
+			delta[change.From.Name] = fileState{deleted: true}
+		}
+	}
+	return delta, nil
+}
+
+func (goGitBackend) integrateRemote(worktreeDir, branch string) error {
+	r, wt, err := openGoGitWorktree(worktreeDir)
+	if err != nil {
+		return err
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	err = withGoGitAuth(originURL(r), func(auth transport.AuthMethod) error {
+		return r.Fetch(&git.FetchOptions{
+			RemoteName: "origin",
+			RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("+%s:%s", ref, ref))},
+			Auth:       auth,
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("拉取远端引用失败: %w", err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return fmt.Errorf("读取 HEAD 失败: %w", err)
+	}
+	remoteRef, err := r.Reference(ref, true)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", ref, err)
+	}
+	ours, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	theirs, err := r.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return err
+	}
+	bases, err := ours.MergeBase(theirs)
+	if err != nil {
+		return err
+	}
+	if len(bases) == 0 {
+		return fmt.Errorf("与远端存在冲突，请稍后重试: 本地与远端没有共同祖先")
+	}
+	base := bases[0]
+	switch base.Hash {
+	case theirs.Hash:
+		return nil
+	case ours.Hash:
+		return wt.Reset(&git.ResetOptions{Commit: theirs.Hash, Mode: git.HardReset})
+	}
+
+	baseTree, err := base.Tree()
+	if err != nil {
+		return err
+	}
+	oursTree, err := ours.Tree()
+	if err != nil {
+		return err
+	}
+	theirsTree, err := theirs.Tree()
+	if err != nil {
+		return err
+	}
+	oursDelta, err := treeDelta(baseTree, oursTree)
+	if err != nil {
+		return err
+	}
+	theirsDelta, err := treeDelta(baseTree, theirsTree)
+	if err != nil {
+		return err
+	}
+	var conflicts []string
+	for path, state := range oursDelta {
+		if other, ok := theirsDelta[path]; ok && other != state {
+			conflicts = append(conflicts, path)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("与远端存在冲突，请稍后重试: %s", strings.Join(conflicts, ", "))
+	}
+
+	// 在远端最新提交上重放本地改动，保留原提交说明与作者
+	if err := wt.Reset(&git.ResetOptions{Commit: theirs.Hash, Mode: git.HardReset}); err != nil {
+		return err
+	}
+	for path, state := range oursDelta {
+		if err := writeFileState(r, filepath.Join(worktreeDir, filepath.FromSlash(path)), state); err != nil {
+			return err
+		}
+	}
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return fmt.Errorf("git add 失败: %w", err)
+	}
+	signer, err := goGitSigner(CommitSigning())
+	if err != nil {
+		return err
+	}
+	committer := commitSignature(r)
+	_, err = wt.Commit(ours.Message, &git.CommitOptions{All: true, Author: &ours.Author, Committer: committer, AllowEmptyCommits: true, Signer: signer})
+	if err != nil {
+		return fmt.Errorf("git commit 失败: %w", err)
+	}
+	return nil
+}
+
+func writeFileState(r *git.Repository, path string, state fileState) error {
+	if state.deleted {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	blob, err := r.BlobObject(state.hash)
+	if err != nil {
+		return err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	_ = os.Remove(path)
+	if state.mode == filemode.Symlink {
+		return os.Symlink(string(data), path)
+	}
+	perm := os.FileMode(0644)
+	if state.mode == filemode.Executable {
+		perm = 0755
+	}
+	return os.WriteFile(path, data, perm)
+}
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"bufio"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/shichao402/Dec/internal/sysproc"
+)
+
+// withGoGitAuth 先按 URL 自带的信息访问远端（go-git 会用 URL 里的 user:password，SSH 走 ssh-agent）；
+// HTTP(S) 远端要求认证时，向 git credential helper 取凭证重试一次。
+// 这样 go-git 后端与系统 git 共用 GCM 等已配置的凭证，私有 HTTPS 仓库不会因换后端而失效。
+func withGoGitAuth(remoteURL string, fn func(auth transport.AuthMethod) error) error {
+	err := fn(nil)
+	if !isGoGitAuthError(err) {
+		return err
+	}
+	auth := gitCredentialAuth(remoteURL)
+	if auth == nil {
+		return err
+	}
+	return fn(auth)
+}
+
+func isGoGitAuthError(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		errors.Is(err, transport.ErrRepositoryNotFound)
+}
+
+// gitCredentialFill 便于测试替换；返回 git credential fill 的输出。
+var gitCredentialFill = func(input string) (string, error) {
+	if _, err := gitLookPath("git"); err != nil {
+		return "", err
+	}
+	cmd := sysproc.Command("git", "credential", "fill")
+	// 与 execBackend 一致：禁止交互，凭证失效时由门面走 bootstrap。
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=Never")
+	cmd.Stdin = strings.NewReader(input)
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// gitCredentialAuth 通过 git credential fill 取 HTTP(S) 远端的用户名与密码；取不到时返回 nil。
+func gitCredentialAuth(remoteURL string) transport.AuthMethod {
+	parsed, err := url.Parse(strings.TrimSpace(remoteURL))
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil
+	}
+	var input strings.Builder
+	input.WriteString("protocol=" + parsed.Scheme + "\n")
+	input.WriteString("host=" + parsed.Host + "\n")
+	if path := strings.TrimPrefix(parsed.Path, "/"); path != "" {
+		input.WriteString("path=" + path + "\n")
+	}
+	if parsed.User != nil && parsed.User.Username() != "" {
+		input.WriteString("username=" + parsed.User.Username() + "\n")
+	}
+	input.WriteString("\n")
+
+	output, err := gitCredentialFill(input.String())
+	if err != nil {
+		return nil
+	}
+	auth := &githttp.BasicAuth{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "username":
+			auth.Username = value
+		case "password":
+			auth.Password = value
+		}
+	}
+	if auth.Password == "" {
+		return nil
+	}
+	return auth
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+)
+
+var fileTransportOnce sync.Once
+
+// useInProcessFileTransport 把 file:// 与本地路径的传输换成进程内实现；
+// go-git 默认的 file 传输仍会 exec git-upload-pack / git-receive-pack。
+func useInProcessFileTransport() {
+	fileTransportOnce.Do(func() {
+		client.InstallProtocol("file", localTransport{server.NewClient(localRepoLoader{})})
+	})
+}
+
+// localRepoLoader 按 git 的规则打开本地仓库（bare、普通工作区、linked worktree 均可）。
+// go-git 自带的 FilesystemLoader 把普通仓库的工作区根当成 git 目录，读不到任何引用。
+type localRepoLoader struct{}
+
+func (localRepoLoader) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	r, err := git.PlainOpenWithOptions(localEndpointPath(ep), &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, transport.ErrRepositoryNotFound
+	}
+	return r.Storer, nil
+}
+
+func localEndpointPath(ep *transport.Endpoint) string {
+	path := ep.Path
+	// file:///C:/x 解析后带前导斜杠
+	if runtime.GOOS == "windows" && len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// localTransport 在进程内服务端之上过滤客户端声明的 have：
+// 服务端会沿 have 遍历历史，遇到只存在于客户端的提交（例如事务里尚未推送的提交）会直接报 object not found。
+type localTransport struct {
+	transport.Transport
+}
+
+func (t localTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	session, err := t.Transport.NewUploadPackSession(ep, auth)
+	if err != nil {
+		return nil, err
+	}
+	sto, err := localRepoLoader{}.Load(ep)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return &haveFilteringSession{UploadPackSession: session, storer: sto}, nil
+}
+
+type haveFilteringSession struct {
+	transport.UploadPackSession
+	storer storer.Storer
+}
+
+func (s *haveFilteringSession) UploadPack(ctx context.Context, req *packp.UploadPackRequest) (*packp.UploadPackResponse, error) {
+	known := make([]plumbing.Hash, 0, len(req.Haves))
+	for _, have := range req.Haves {
+		if s.storer.HasEncodedObject(have) == nil {
+			known = append(known, have)
+		}
+	}
+	req.Haves = known
+	return s.UploadPackSession.UploadPack(ctx, req)
+}
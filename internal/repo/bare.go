@@ -5,8 +5,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/shichao402/Dec/internal/sysproc"
 )
 
 const (
@@ -48,12 +46,7 @@ func isBareRepo(dir string) (bool, error) {
 		return false, err
 	}
 
-	cmd := sysproc.Command("git", "--git-dir", dir, "rev-parse", "--is-bare-repository")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false, fmt.Errorf("git rev-parse --is-bare-repository: %s", strings.TrimSpace(string(output)))
-	}
-	return strings.TrimSpace(string(output)) == "true", nil
+	return currentBackend().isBareRepo(dir)
 }
 
 // ConnectBare 连接用户仓库到本地 bare repo
@@ -81,12 +74,16 @@ func ConnectBare(repoURL string) error {
 	if err := gitCloneBare(repoURL, bareDir); err != nil {
 		return fmt.Errorf("克隆仓库失败: %w", err)
 	}
+	recordFetchTime(bareDir)
 
 	return nil
 }
 
-// FetchBare 拉取 bare repo 远端引用
+// FetchBare 拉取 bare repo 远端引用；强制离线时直接返回 ErrOffline。
 func FetchBare() error {
+	if OfflineMode() == OfflineOn {
+		return ErrOffline
+	}
 	bareDir, err := GetBareRepoDir()
 	if err != nil {
 		return err
@@ -99,26 +96,18 @@ func FetchBare() error {
 		return fmt.Errorf("仓库未连接\n\n请先到 Settings 页配置 Repo URL")
 	}
 
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "fetch", "--prune", "origin", "+refs/heads/*:refs/heads/*")
-	// 禁止交互：凭证过期时不能让 git/GCM 弹窗阻塞 dec-server，失败后由门面显式确认走 bootstrap。
-	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=Never")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		message := strings.TrimSpace(string(output))
-		if message == "" {
-			message = err.Error()
-		}
-		fetchErr := fmt.Errorf("git fetch --prune origin: %s", message)
+	if fetchErr := currentBackend().fetchBare(bareDir); fetchErr != nil {
 		// origin URL 读不到就退化成普通错误：分类只是为了给出 bootstrap 提示，不该反过来掩盖 fetch 失败。
 		originURL, urlErr := getBareRemoteURL(bareDir, "origin")
 		if urlErr != nil {
 			return fetchErr
 		}
-		return classifyRemoteAuthError(originURL, message, fetchErr)
+		return classifyRemoteAuthError(originURL, fetchErr.Error(), fetchErr)
 	}
 	if err := syncBareHeadToRemote(bareDir); err != nil {
 		return err
 	}
+	recordFetchTime(bareDir)
 	return nil
 }
 
@@ -145,13 +134,8 @@ func GetDefaultBranch() (string, error) {
 		return "", err
 	}
 
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "symbolic-ref", "--short", "HEAD")
-	output, err := cmd.CombinedOutput()
-	if err == nil {
-		branch := strings.TrimSpace(string(output))
-		if branch != "" {
-			return branch, nil
-		}
+	if branch, err := currentBackend().headBranch(bareDir); err == nil && branch != "" {
+		return branch, nil
 	}
 
 	for _, branch := range []string{"main", "master"} {
@@ -164,6 +148,92 @@ func GetDefaultBranch() (string, error) {
 	return "", fmt.Errorf("无法确定默认分支")
 }
 
+// BareBranchHead 返回本地 bare repo 中 refs/heads/<branch> 指向的 commit hash。
+func BareBranchHead(branch string) (string, error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return "", err
+	}
+	hash, err := currentBackend().resolveRef(bareDir, "refs/heads/"+branch)
+	if err != nil {
+		return "", fmt.Errorf("读取 refs/heads/%s 失败: %w", branch, err)
+	}
+	return hash, nil
+}
+
+// UnmergedBranches 列出短名以 prefix 开头、且尚未合入 base 分支的本地分支（base 为空时取默认分支）。
+// 分支是 base 的祖先，或它相对分叉点改动的文件在 base 中内容一致（squash / rebase 合入）都算已合入。
+// 结果来自最近一次同步的 bare repo，不访问网络。
+func UnmergedBranches(prefix, base string) ([]string, error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return nil, err
+	}
+	if base == "" {
+		if base, err = GetDefaultBranch(); err != nil {
+			return nil, err
+		}
+	}
+	backend := currentBackend()
+	baseHash, err := backend.resolveRef(bareDir, "refs/heads/"+base)
+	if err != nil {
+		return nil, fmt.Errorf("读取 refs/heads/%s 失败: %w", base, err)
+	}
+	names, err := backend.listBranches(bareDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, name := range names {
+		if name == base {
+			continue
+		}
+		hash, err := backend.resolveRef(bareDir, "refs/heads/"+name)
+		if err != nil {
+			return nil, fmt.Errorf("读取 refs/heads/%s 失败: %w", name, err)
+		}
+		merged, err := backend.isAncestor(bareDir, hash, baseHash)
+		if err != nil {
+			return nil, err
+		}
+		if !merged {
+			// squash / rebase 合入不保留祖先关系：分支相对分叉点的改动都已出现在 base 中也算合入。
+			fork, err := forkPoint(backend, bareDir, hash, baseHash)
+			if err != nil {
+				return nil, err
+			}
+			if merged, err = changesContainedIn(backend, bareDir, fork, hash, baseHash); err != nil {
+				return nil, err
+			}
+		}
+		if !merged {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
+
+// maxForkPointCommits 是寻找分支分叉点时最多回看的提交数；评审分支通常只有几个提交。
+const maxForkPointCommits = 200
+
+// forkPoint 返回 tip 历史中最近一个已包含在 base 里的提交；回看范围内找不到时返回空串。
+func forkPoint(backend gitBackend, bareDir, tip, base string) (string, error) {
+	commits, err := backend.logPaths(bareDir, tip, nil, maxForkPointCommits)
+	if err != nil {
+		return "", err
+	}
+	for _, commit := range commits {
+		contained, err := backend.isAncestor(bareDir, commit.Hash, base)
+		if err != nil {
+			return "", err
+		}
+		if contained {
+			return commit.Hash, nil
+		}
+	}
+	return "", nil
+}
+
 // MigrateToBare 将旧的工作区仓库迁移为 bare repo
 func MigrateToBare() error {
 	legacyDir, err := getLegacyRepoDir()
@@ -189,11 +259,12 @@ func MigrateToBare() error {
 		return nil
 	}
 
-	legacyGit := NewGitOps(legacyDir)
-	if err := legacyGit.ensureNoSyncInProgress(); err != nil {
+	backend := currentBackend()
+	legacyGitDir := filepath.Join(legacyDir, ".git")
+	if err := ensureNoSyncInProgressAt(legacyGitDir); err != nil {
 		return fmt.Errorf("旧仓库存在未完成的同步状态，请先处理后再迁移: %w", err)
 	}
-	clean, err := legacyGit.IsClean()
+	clean, err := backend.isClean(legacyDir)
 	if err != nil {
 		return fmt.Errorf("检查旧仓库状态失败: %w", err)
 	}
@@ -205,7 +276,7 @@ func MigrateToBare() error {
 		return fmt.Errorf("创建 bare repo 父目录失败: %w", err)
 	}
 	// 迁移前先读取旧仓库的真正远程 URL
-	originURL, err := legacyGit.getRemoteURL("origin")
+	originURL, err := backend.remoteURL(legacyGitDir, "origin")
 	if err != nil {
 		return fmt.Errorf("读取旧仓库远程 URL 失败: %w", err)
 	}
@@ -229,30 +300,19 @@ func MigrateToBare() error {
 }
 
 func setBareRemoteURL(bareDir, remote, url string) error {
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "remote", "set-url", remote, url)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git remote set-url 失败: %s", strings.TrimSpace(string(output)))
-	}
-	return nil
+	return currentBackend().setRemoteURL(bareDir, remote, url)
 }
 
 func addBareRemoteURL(bareDir, remote, url string) error {
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "remote", "add", remote, url)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git remote add 失败: %s", strings.TrimSpace(string(output)))
-	}
-	return nil
+	return currentBackend().addRemote(bareDir, remote, url)
 }
 
 func hasBareRemote(bareDir, remote string) (bool, error) {
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "remote")
-	output, err := cmd.CombinedOutput()
+	names, err := currentBackend().remoteNames(bareDir)
 	if err != nil {
-		return false, fmt.Errorf("git remote 失败: %s", strings.TrimSpace(string(output)))
+		return false, err
 	}
-	for _, name := range strings.Fields(string(output)) {
+	for _, name := range names {
 		if name == remote {
 			return true, nil
 		}
@@ -261,12 +321,7 @@ func hasBareRemote(bareDir, remote string) (bool, error) {
 }
 
 func getBareRemoteURL(bareDir, remote string) (string, error) {
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "config", "--get", fmt.Sprintf("remote.%s.url", remote))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("读取 remote %s URL 失败: %s", remote, strings.TrimSpace(string(output)))
-	}
-	return strings.TrimSpace(string(output)), nil
+	return currentBackend().remoteURL(bareDir, remote)
 }
 
 func upsertBareRemoteURL(bareDir, remote, url string) error {
@@ -287,53 +342,17 @@ func upsertBareRemoteURL(bareDir, remote, url string) error {
 }
 
 func syncBareHeadToRemote(bareDir string) error {
-	branch, err := getRemoteHeadBranch(bareDir)
+	backend := currentBackend()
+	branch, err := backend.remoteHeadBranch(bareDir)
 	if err != nil {
 		return err
 	}
 	if branch == "" {
 		return nil
 	}
-
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "symbolic-ref", "HEAD", fmt.Sprintf("refs/heads/%s", branch))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("同步默认分支失败: %s", strings.TrimSpace(string(output)))
-	}
-	return nil
-}
-
-func getRemoteHeadBranch(bareDir string) (string, error) {
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "ls-remote", "--symref", "origin", "HEAD")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("读取远端默认分支失败: %s", strings.TrimSpace(string(output)))
-	}
-
-	for _, line := range strings.Split(string(output), "\n") {
-		line = strings.TrimSpace(line)
-		if !strings.HasPrefix(line, "ref: ") {
-			continue
-		}
-		fields := strings.Fields(line)
-		if len(fields) < 3 {
-			continue
-		}
-		ref := fields[1]
-		if !strings.HasPrefix(ref, "refs/heads/") {
-			continue
-		}
-		return strings.TrimPrefix(ref, "refs/heads/"), nil
-	}
-
-	return "", nil
+	return backend.setHeadBranch(bareDir, branch)
 }
 
 func gitCloneBare(url, targetDir string) error {
-	cmd := sysproc.Command("git", "clone", "--bare", url, targetDir)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git clone --bare 失败: %s", strings.TrimSpace(string(output)))
-	}
-	return nil
+	return currentBackend().cloneBare(url, targetDir)
 }
@@ -0,0 +1,179 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CommitInfo 是 vault 历史里的一次提交。
+type CommitInfo struct {
+	Hash    string
+	Parents []string
+	Author  string
+	Email   string
+	Time    time.Time
+	// Subject 是提交说明首行，Message 是完整说明。
+	Subject string
+	Message string
+}
+
+// CommitFileChange 是一次提交相对第一个父提交改动的文件。
+type CommitFileChange struct {
+	// Path 是仓库相对路径（/ 分隔）
+	Path string
+	// Status 取值 added | modified | deleted
+	Status string
+}
+
+// 提交内文件改动的状态取值，与 app 层 RenderedFile* 一致。
+const (
+	FileAdded    = "added"
+	FileModified = "modified"
+	FileDeleted  = "deleted"
+)
+
+// PathWithin 判断文件是否落在 paths 中某个文件或目录之下（/ 分隔）；paths 为空时匹配一切。
+func PathWithin(file string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = strings.TrimSuffix(p, "/")
+		if file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCommitMessage 取提交说明首行作为 Subject。
+func splitCommitMessage(message string) (subject, full string) {
+	full = strings.TrimRight(message, "\n")
+	subject, _, _ = strings.Cut(full, "\n")
+	return strings.TrimSpace(subject), full
+}
+
+// History 列出 ref 上改动过 paths（文件或目录，/ 分隔）的提交，新的在前，最多 limit 条（<=0 不限）。
+// ref 为空时取默认分支；只读本地 bare repo，新旧取决于上次 fetch。
+func History(ref string, paths []string, limit int) ([]CommitInfo, error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		branch, err := GetDefaultBranch()
+		if err != nil {
+			return nil, err
+		}
+		ref = "refs/heads/" + branch
+	}
+	backend := currentBackend()
+	hash, err := backend.resolveRef(bareDir, ref)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", ref, err)
+	}
+	return backend.logPaths(bareDir, hash, paths, limit)
+}
+
+// ResolveCommit 在本地 bare repo 中把 ref（hash、短 hash、分支或 tag）解析为完整 commit hash。
+func ResolveCommit(ref string) (string, error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return "", err
+	}
+	hash, err := currentBackend().resolveRef(bareDir, ref)
+	if err != nil {
+		return "", fmt.Errorf("解析版本 %s 失败: %w", ref, err)
+	}
+	return hash, nil
+}
+
+// CommitDetails 读取提交元数据，以及它相对第一个父提交改动的文件（根提交视为全部新增）。
+func CommitDetails(ref string) (*CommitInfo, []CommitFileChange, error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	backend := currentBackend()
+	hash, err := backend.resolveRef(bareDir, ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取提交 %s 失败: %w", ref, err)
+	}
+	info, err := backend.readCommit(bareDir, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := backend.listTree(bareDir, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	var before []treeEntry
+	if len(info.Parents) > 0 {
+		if before, err = backend.listTree(bareDir, info.Parents[0]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return &info, diffTreeEntries(before, after), nil
+}
+
+// diffTreeEntries 按路径比较两棵树的 blob 与模式，结果按路径排序。
+func diffTreeEntries(before, after []treeEntry) []CommitFileChange {
+	old := make(map[string]treeEntry, len(before))
+	for _, entry := range before {
+		old[entry.path] = entry
+	}
+	var changes []CommitFileChange
+	for _, entry := range after {
+		prev, ok := old[entry.path]
+		switch {
+		case !ok:
+			changes = append(changes, CommitFileChange{Path: entry.path, Status: FileAdded})
+		case prev.hash != entry.hash || prev.mode != entry.mode:
+			changes = append(changes, CommitFileChange{Path: entry.path, Status: FileModified})
+		}
+		delete(old, entry.path)
+	}
+	for path := range old {
+		changes = append(changes, CommitFileChange{Path: path, Status: FileDeleted})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// changesContainedIn 判断 from→to 改动过的每个文件在 head 中是否都与 to 一致（内容与模式），
+// from 为空时视为从空树开始。用于识别 squash / rebase 合入等不保留祖先关系的情况。
+func changesContainedIn(backend gitBackend, bareDir, from, to, head string) (bool, error) {
+	var before []treeEntry
+	if from != "" {
+		var err error
+		if before, err = backend.listTree(bareDir, from); err != nil {
+			return false, err
+		}
+	}
+	after, err := backend.listTree(bareDir, to)
+	if err != nil {
+		return false, err
+	}
+	current, err := backend.listTree(bareDir, head)
+	if err != nil {
+		return false, err
+	}
+	afterByPath := make(map[string]treeEntry, len(after))
+	for _, entry := range after {
+		afterByPath[entry.path] = entry
+	}
+	currentByPath := make(map[string]treeEntry, len(current))
+	for _, entry := range current {
+		currentByPath[entry.path] = entry
+	}
+	for _, change := range diffTreeEntries(before, after) {
+		want, wantOK := afterByPath[change.Path]
+		got, gotOK := currentByPath[change.Path]
+		if wantOK != gotOK || want.hash != got.hash || want.mode != got.mode {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,242 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 离线模式取值：global config 的 offline 与环境变量 DEC_OFFLINE 共用。
+const (
+	// OfflineAuto 平时访问远端，fetch 失败（凭证错误除外）时回落到本地 bare repo（默认）。
+	OfflineAuto = "auto"
+	// OfflineOn 强制离线：不访问远端，读本地 bare repo，push 只排队。
+	OfflineOn = "on"
+	// OfflineOff 关闭回落，远端不可达时照旧报错。
+	OfflineOff = "off"
+)
+
+// offlineEnv 覆盖 global config 中的 offline。
+const offlineEnv = "DEC_OFFLINE"
+
+// pushQueueRefPrefix 是离线 push 排队提交的引用前缀：refs/dec-queue/<目标分支>。
+// 不放在 refs/heads 下，fetch --prune 不会把它当作远端已删除的分支清掉。
+const pushQueueRefPrefix = "refs/dec-queue/"
+
+// fetchStampFile 记录最近一次成功同步远端的时间（bare repo 内）。
+const fetchStampFile = "dec-fetched-at"
+
+// maxQueuedCommits 是列出排队提交时最多回看的提交数。
+const maxQueuedCommits = 100
+
+// ErrOffline 表示离线模式下跳过了远端访问。
+var ErrOffline = errors.New("离线模式：不访问远端")
+
+var (
+	offlineMu   sync.Mutex
+	offlineMode = OfflineAuto
+)
+
+// NormalizeOfflineMode 规范化 offline 取值，无法识别时 ok=false。
+func NormalizeOfflineMode(raw string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", OfflineAuto:
+		return OfflineAuto, true
+	case OfflineOn, "true", "1", "yes":
+		return OfflineOn, true
+	case OfflineOff, "false", "0", "no":
+		return OfflineOff, true
+	default:
+		return "", false
+	}
+}
+
+// SetOfflineMode 设置 global config 中的 offline；环境变量 DEC_OFFLINE 优先。
+func SetOfflineMode(raw string) error {
+	mode, ok := NormalizeOfflineMode(raw)
+	if !ok {
+		return fmt.Errorf("offline %q 无法识别（可选 auto | on | off）", raw)
+	}
+	offlineMu.Lock()
+	defer offlineMu.Unlock()
+	offlineMode = mode
+	return nil
+}
+
+// OfflineMode 返回当前生效的离线模式。
+func OfflineMode() string {
+	if raw := os.Getenv(offlineEnv); strings.TrimSpace(raw) != "" {
+		if mode, ok := NormalizeOfflineMode(raw); ok {
+			return mode
+		}
+	}
+	offlineMu.Lock()
+	defer offlineMu.Unlock()
+	return offlineMode
+}
+
+// fetchOrOffline 在事务开始前同步远端，返回离线原因；为空表示已在线同步。
+// 强制离线时不访问远端；auto 下 fetch 失败（凭证错误除外，需要走 bootstrap）回落到本地 bare repo。
+func fetchOrOffline() (string, error) {
+	switch OfflineMode() {
+	case OfflineOn:
+		return "已开启离线模式", nil
+	case OfflineOff:
+		return "", FetchBare()
+	}
+	err := FetchBare()
+	if err == nil {
+		return "", nil
+	}
+	var authErr *AuthenticationError
+	if errors.As(err, &authErr) {
+		return "", err
+	}
+	return fmt.Sprintf("远端不可达: %v", err), nil
+}
+
+// LastFetchTime 返回本地 bare repo 最近一次成功同步远端的时间；从未记录时为零值。
+func LastFetchTime() (time.Time, error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return time.Time{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(bareDir, fetchStampFile))
+	if err == nil {
+		if stamp, parseErr := time.Parse(time.RFC3339, strings.TrimSpace(string(data))); parseErr == nil {
+			return stamp, nil
+		}
+	}
+	// 旧版本没有写时间戳：系统 git fetch 会更新 FETCH_HEAD
+	if info, statErr := os.Stat(filepath.Join(bareDir, "FETCH_HEAD")); statErr == nil {
+		return info.ModTime(), nil
+	}
+	return time.Time{}, nil
+}
+
+func recordFetchTime(bareDir string) {
+	_ = os.WriteFile(filepath.Join(bareDir, fetchStampFile), []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// QueuedPush 是离线时排队、尚未送到远端的提交。
+type QueuedPush struct {
+	// Target 是要推送到的远端分支。
+	Target string
+	// Commits 是排队的提交，新的在前。
+	Commits []CommitInfo
+}
+
+func pushQueueRef(target string) string {
+	return pushQueueRefPrefix + target
+}
+
+// PushQueue 列出本地 bare repo 中排队的离线 push，按目标分支排序。
+func PushQueue() ([]QueuedPush, error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := isBareRepo(bareDir)
+	if err != nil || !ok {
+		return nil, err
+	}
+	backend := currentBackend()
+	refs, err := backend.listRefs(bareDir, pushQueueRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	queue := make([]QueuedPush, 0, len(refs))
+	for _, ref := range refs {
+		target := strings.TrimPrefix(ref.name, pushQueueRefPrefix)
+		item := QueuedPush{Target: target}
+		commits, err := backend.logPaths(bareDir, ref.hash, nil, maxQueuedCommits)
+		if err != nil {
+			return nil, err
+		}
+		// 评审分支在远端还不存在，以默认分支为界
+		remoteHead, err := backend.resolveRef(bareDir, "refs/heads/"+target)
+		if err != nil {
+			if branch, branchErr := GetDefaultBranch(); branchErr == nil {
+				remoteHead, _ = backend.resolveRef(bareDir, "refs/heads/"+branch)
+			}
+		}
+		for _, commit := range commits {
+			if commit.Hash == remoteHead {
+				break
+			}
+			if remoteHead != "" {
+				if seen, err := backend.isAncestor(bareDir, commit.Hash, remoteHead); err == nil && seen {
+					break
+				}
+			}
+			item.Commits = append(item.Commits, commit)
+		}
+		queue = append(queue, item)
+	}
+	return queue, nil
+}
+
+// FlushPushQueue 把排队的离线 push 依次送到远端；远端前进时与普通 push 一样先整合。
+// 仍处于离线状态时不做任何事，返回 (nil, nil)。成功送出的队列会被删除。
+// check 非空时在发送每个目标前调用，返回错误则停止发送并保留该目标及之后的队列。
+func FlushPushQueue(check func(target string) error) ([]QueuedPush, error) {
+	queue, err := PushQueue()
+	if err != nil || len(queue) == 0 {
+		return nil, err
+	}
+	var sent []QueuedPush
+	for _, item := range queue {
+		if check != nil {
+			if err := check(item.Target); err != nil {
+				return sent, fmt.Errorf("发送排队的提交到 %s 前检查失败: %w", item.Target, err)
+			}
+		}
+		tx, err := newTransaction(false, true, "", pushQueueRef(item.Target), nil)
+		if err != nil {
+			return sent, err
+		}
+		if tx.Offline() {
+			tx.Close()
+			return sent, nil
+		}
+		err = tx.pushHead(item.Target)
+		tx.Close()
+		if err != nil {
+			return sent, fmt.Errorf("发送排队的提交到 %s 失败: %w", item.Target, err)
+		}
+		_ = DiscardPushQueue(item.Target)
+		sent = append(sent, item)
+	}
+	return sent, nil
+}
+
+// DiscardPushQueue 丢弃 target 上排队的离线提交。
+func DiscardPushQueue(target string) error {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return err
+	}
+	return currentBackend().deleteRef(bareDir, pushQueueRef(target))
+}
+
+// PushQueueSupersededBy 判断排队的改动是否都已包含在 head 中：排队提交相对起点改动过的每个文件，
+// 在 head 中的内容与模式都与队列末端一致。队列按目标分支汇总，可能混有其他项目的提交，
+// 只有全部被 head 覆盖时才可以丢弃。
+func PushQueueSupersededBy(item QueuedPush, head string) (bool, error) {
+	if len(item.Commits) == 0 {
+		return true, nil
+	}
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return false, err
+	}
+	from := ""
+	if oldest := item.Commits[len(item.Commits)-1]; len(oldest.Parents) > 0 {
+		from = oldest.Parents[0]
+	}
+	return changesContainedIn(currentBackend(), bareDir, from, item.Commits[0].Hash, head)
+}
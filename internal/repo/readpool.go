@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// readSnapshot 是可被多个只读事务共享的检出：同一 bare repo、同一提交、同一物化范围只检出一次。
+type readSnapshot struct {
+	key     string
+	backend gitBackend
+	bareDir string
+	dir     string
+	commit  string
+	sparse  bool
+	// refs 是正在使用它的只读事务数；为 0 时快照空闲，等待复用或回收。
+	refs     int
+	lastUsed time.Time
+}
+
+// readPool 按提交缓存只读快照。所有字段由 bareOpMu 保护。
+type readPool struct {
+	// limit 是最多保留的空闲快照数；0 表示不启用，只读事务各自检出、关闭即删。
+	limit int
+	// idle 是空闲快照的保留时长，超过后在下次访问池时回收；0 表示不按时间回收。
+	idle    time.Duration
+	entries map[string]*readSnapshot
+}
+
+var snapshots = readPool{entries: make(map[string]*readSnapshot)}
+
+// SetReadPool 设置只读快照池：limit 为最多保留的空闲快照数，idle 为空闲快照的保留时长。
+//
+// 启用后，同一提交、同一物化范围的只读事务共享一份检出，关闭时只减引用计数；
+// 可写事务不受影响，始终使用独立 worktree。limit <= 0 关闭快照池并立即回收所有空闲快照，
+// 仍在使用的快照在最后一个事务关闭时删除。仅 dec-server 启用：CLI 与测试进程默认不缓存。
+func SetReadPool(limit int, idle time.Duration) {
+	bareOpMu.Lock()
+	defer bareOpMu.Unlock()
+	if limit < 0 {
+		limit = 0
+	}
+	snapshots.limit = limit
+	snapshots.idle = idle
+	snapshots.evictLocked(time.Now())
+}
+
+func readSnapshotKey(bareDir, commit, scope string) string {
+	return filepath.Clean(bareDir) + "\x00" + commit + "\x00" + scope
+}
+
+func (p *readPool) enabled() bool {
+	return p.limit > 0
+}
+
+// acquireLocked 取出 key 对应的快照并增加引用；池未启用、没有或快照目录已不在时返回 nil。
+func (p *readPool) acquireLocked(key string) *readSnapshot {
+	if !p.enabled() {
+		return nil
+	}
+	snap := p.entries[key]
+	if snap == nil {
+		return nil
+	}
+	if _, err := os.Stat(snap.dir); err != nil {
+		delete(p.entries, key)
+		if snap.refs == 0 {
+			snap.remove()
+		}
+		return nil
+	}
+	snap.refs++
+	snap.lastUsed = time.Now()
+	return snap
+}
+
+// addLocked 登记新检出的快照（引用计数为 1）；池未启用时返回 nil，由事务自行清理。
+func (p *readPool) addLocked(snap *readSnapshot) *readSnapshot {
+	if !p.enabled() {
+		return nil
+	}
+	if old := p.entries[snap.key]; old != nil && old.refs == 0 {
+		old.remove()
+	}
+	snap.refs = 1
+	snap.lastUsed = time.Now()
+	p.entries[snap.key] = snap
+	return snap
+}
+
+// releaseLocked 减少引用；空闲后按池的上限与保留时长回收。
+func (p *readPool) releaseLocked(snap *readSnapshot) {
+	snap.refs--
+	snap.lastUsed = time.Now()
+	if p.entries[snap.key] != snap {
+		// 已被池替换或池已关闭：最后一个使用者负责删除
+		if snap.refs <= 0 {
+			snap.remove()
+		}
+		return
+	}
+	p.evictLocked(snap.lastUsed)
+}
+
+// evictLocked 回收超过保留时长的空闲快照，再按最近使用时间只保留 limit 个空闲快照。
+func (p *readPool) evictLocked(now time.Time) {
+	var idle []*readSnapshot
+	for key, snap := range p.entries {
+		if snap.refs > 0 {
+			if !p.enabled() {
+				delete(p.entries, key)
+			}
+			continue
+		}
+		if !p.enabled() || (p.idle > 0 && now.Sub(snap.lastUsed) > p.idle) {
+			delete(p.entries, key)
+			snap.remove()
+			continue
+		}
+		idle = append(idle, snap)
+	}
+	if len(idle) <= p.limit {
+		return
+	}
+	sort.Slice(idle, func(i, j int) bool { return idle[i].lastUsed.Before(idle[j].lastUsed) })
+	for _, snap := range idle[:len(idle)-p.limit] {
+		delete(p.entries, snap.key)
+		snap.remove()
+	}
+}
+
+// ownsLocked 判断 dir 是否为池中快照的检出目录。
+func (p *readPool) ownsLocked(dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, snap := range p.entries {
+		if filepath.Clean(snap.dir) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// remove 删除快照的检出目录；调用方需持有 bareOpMu。
+func (s *readSnapshot) remove() {
+	if s.sparse {
+		sparseIndexes.Delete(filepath.Clean(s.dir))
+		_ = os.RemoveAll(s.dir)
+		return
+	}
+	if err := s.backend.removeWorktree(s.bareDir, s.dir); err != nil {
+		_ = os.RemoveAll(s.dir)
+	}
+	s.backend.pruneWorktrees(s.bareDir)
+}
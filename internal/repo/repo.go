@@ -84,17 +84,11 @@ func RepoHost(repoURL string) (string, error) {
 // 禁止终端/GCM 交互，认证失败交由 TUI 显式确认是否走 Bitwarden bootstrap。
 func Probe(repoURL string) error {
 	repoURL = strings.TrimSpace(repoURL)
-	cmd := sysproc.Command("git", "ls-remote", "--heads", repoURL)
-	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=Never")
-	output, err := cmd.CombinedOutput()
+	err := currentBackend().listRemoteHeads(repoURL)
 	if err == nil {
 		return nil
 	}
-	message := strings.TrimSpace(string(output))
-	if message == "" {
-		message = err.Error()
-	}
-	return classifyRemoteAuthError(repoURL, message, fmt.Errorf("git ls-remote: %s", message))
+	return classifyRemoteAuthError(repoURL, err.Error(), err)
 }
 
 func isHTTPSRepoURL(repoURL string) bool {
@@ -106,6 +100,7 @@ func looksLikeAuthenticationFailure(message string) bool {
 	lower := strings.ToLower(message)
 	for _, marker := range []string{
 		"authentication failed",
+		"authentication required",
 		"credentials have expired",
 		"credential has expired",
 		"could not read username",
@@ -403,6 +398,11 @@ func (g *GitOps) ensureNoSyncInProgress() error {
 	if err != nil {
 		return err
 	}
+	return ensureNoSyncInProgressAt(gitDir)
+}
+
+// ensureNoSyncInProgressAt 检查 git 目录下是否残留未完成的 merge / rebase 标记。
+func ensureNoSyncInProgressAt(gitDir string) error {
 	markers := []struct {
 		path    string
 		message string
@@ -423,15 +423,6 @@ func (g *GitOps) ensureNoSyncInProgress() error {
 	return nil
 }
 
-// getRemoteURL 获取指定 remote 的 URL
-func (g *GitOps) getRemoteURL(remote string) (string, error) {
-	url, err := g.run("config", "--get", fmt.Sprintf("remote.%s.url", remote))
-	if err != nil {
-		return "", err
-	}
-	return url, nil
-}
-
 // IsClean 检查工作区是否干净
 func (g *GitOps) IsClean() (bool, error) {
 	output, err := g.run("status", "--porcelain")
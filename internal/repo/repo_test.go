@@ -2,6 +2,9 @@ package repo
 
 import (
 	"errors"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -63,6 +66,71 @@ func TestAuthenticationErrorClassification(t *testing.T) {
 	}
 }
 
+func TestGoGitBackendUsesGitCredentialHelperForHTTPS(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("需要 git http-backend")
+	}
+	remoteBare := setupRemoteBareRepo(t)
+	runGitNoDir(t, "--git-dir", remoteBare, "config", "http.receivepack", "true")
+	backend := &cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + filepath.Dir(remoteBare), "GIT_HTTP_EXPORT_ALL=1", "REMOTE_USER=dec"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "dec" || pass != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	url := server.URL + "/" + filepath.Base(remoteBare)
+
+	var requests []string
+	credential := "username=dec\npassword=s3cret\n"
+	oldFill := gitCredentialFill
+	gitCredentialFill = func(input string) (string, error) {
+		requests = append(requests, input)
+		if credential == "" {
+			return "", errors.New("no credential")
+		}
+		return credential, nil
+	}
+	t.Cleanup(func() { gitCredentialFill = oldFill })
+
+	b := goGitBackend{}
+	bareDir := filepath.Join(t.TempDir(), "vault.git")
+	if err := b.cloneBare(url, bareDir); err != nil {
+		t.Fatalf("go-git 应通过 credential helper 克隆私有 HTTPS 仓库: %v", err)
+	}
+	if len(requests) == 0 || !strings.Contains(requests[0], "protocol=http\nhost="+strings.TrimPrefix(server.URL, "http://")+"\n") {
+		t.Fatalf("应向 git credential fill 查询该主机: %q", requests)
+	}
+
+	worktree := filepath.Join(t.TempDir(), "tx")
+	if err := b.addWorktree(bareDir, worktree, "main"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(worktree, "note.md"), "hello\n")
+	if _, err := b.commitAll(worktree, "add note"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.push(worktree, "main"); err != nil {
+		t.Fatalf("go-git 应带凭证推送: %v", err)
+	}
+	if got := runGitNoDir(t, "--git-dir", remoteBare, "show", "main:note.md"); got != "hello" {
+		t.Fatalf("远端应收到推送, got %q", got)
+	}
+
+	credential = ""
+	if err := b.listRemoteHeads(url); err == nil || !looksLikeAuthenticationFailure(err.Error()) {
+		t.Fatalf("取不到凭证时应报认证失败, got %v", err)
+	}
+}
+
 func TestGitOpsPull_FastForwardsWhenRemoteAdvances(t *testing.T) {
 	localDir, remoteWorkDir := setupGitOpsTestRepos(t)
 
@@ -245,7 +313,7 @@ func configureGitUser(t *testing.T, dir string) {
 	runGit(t, dir, "config", "user.email", "dec-test@example.com")
 }
 
-func setEnvForTest(t *testing.T, key, value string) {
+func setEnvForTest(t testing.TB, key, value string) {
 	t.Helper()
 	oldValue, existed := os.LookupEnv(key)
 	if err := os.Setenv(key, value); err != nil {
@@ -260,7 +328,7 @@ func setEnvForTest(t *testing.T, key, value string) {
 	})
 }
 
-func writeFile(t *testing.T, path, content string) {
+func writeFile(t testing.TB, path, content string) {
 	t.Helper()
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		t.Fatalf("创建目录失败: %v", err)
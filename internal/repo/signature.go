@@ -0,0 +1,270 @@
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// 提交签名的校验结论。
+const (
+	// SignatureUnsigned 提交没有签名。
+	SignatureUnsigned = "unsigned"
+	// SignatureTrusted 签名有效且签名者在受信列表中。
+	SignatureTrusted = "trusted"
+	// SignatureUntrusted 签名有效（或 GPG 签名者不在受信公钥中、无法核验），但签名者不受信任。
+	SignatureUntrusted = "untrusted"
+	// SignatureInvalid 签名与提交内容不符或无法解析。
+	SignatureInvalid = "invalid"
+)
+
+// CommitSignature 是一次提交的签名校验结果。
+type CommitSignature struct {
+	Commit string
+	// Status 取值见 Signature*。
+	Status string
+	// Format 取值 ssh | gpg，未签名时为空。
+	Format string
+	// Signer 是签名者：SSH 为公钥指纹（受信列表里有备注时附上），GPG 为身份或 key ID。
+	Signer string
+	// Reason 说明 invalid / untrusted 的原因。
+	Reason string
+}
+
+// TrustedSigners 是受信任的签名公钥集合。
+type TrustedSigners struct {
+	ssh map[string]string // 公钥 wire 格式 → 显示名
+	gpg openpgp.EntityList
+}
+
+// Empty 判断是否没有任何受信公钥。
+func (t *TrustedSigners) Empty() bool {
+	return t == nil || (len(t.ssh) == 0 && len(t.gpg) == 0)
+}
+
+// ParseTrustedSigners 解析受信签名者列表。每项可以是一行 SSH 公钥（ssh-ed25519 AAAA… 备注），
+// 也可以是公钥文件路径（.pub 或 armored GPG 公钥，支持 ~）。无法解析的项跳过并作为告警返回。
+func ParseTrustedSigners(entries []string) (*TrustedSigners, []string) {
+	trusted := &TrustedSigners{ssh: make(map[string]string)}
+	var warnings []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		data := []byte(entry)
+		if !looksLikeSSHPublicKey(entry) {
+			content, err := os.ReadFile(expandHomePath(entry))
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("受信签名者 %s 读取失败: %v", entry, err))
+				continue
+			}
+			data = content
+		}
+		if err := trusted.add(data); err != nil {
+			warnings = append(warnings, fmt.Sprintf("受信签名者 %s 无法解析: %v", entry, err))
+		}
+	}
+	return trusted, warnings
+}
+
+func (t *TrustedSigners) add(data []byte) error {
+	if bytes.Contains(data, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----")) {
+		keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		t.gpg = append(t.gpg, keys...)
+		return nil
+	}
+	added := 0
+	for len(bytes.TrimSpace(data)) > 0 {
+		pub, comment, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			if added > 0 {
+				return nil
+			}
+			return err
+		}
+		name := ssh.FingerprintSHA256(pub)
+		if comment != "" {
+			name = comment + " (" + name + ")"
+		}
+		t.ssh[string(pub.Marshal())] = name
+		added++
+		data = rest
+	}
+	return nil
+}
+
+func looksLikeSSHPublicKey(entry string) bool {
+	for _, prefix := range []string{"ssh-", "ecdsa-", "sk-"} {
+		if strings.HasPrefix(entry, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func expandHomePath(value string) string {
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			value = home + value[1:]
+		}
+	}
+	return filepath.Clean(filepath.FromSlash(value))
+}
+
+// VerifyCommitSignature 在本地 bare repo 中校验提交签名。trusted 为 nil 时只校验签名本身，有效签名一律记为 untrusted。
+func VerifyCommitSignature(ref string, trusted *TrustedSigners) (*CommitSignature, error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return nil, err
+	}
+	backend := currentBackend()
+	hash, err := backend.resolveRef(bareDir, ref)
+	if err != nil {
+		return nil, fmt.Errorf("解析版本 %s 失败: %w", ref, err)
+	}
+	raw, err := backend.readRawCommit(bareDir, hash)
+	if err != nil {
+		return nil, err
+	}
+	result := verifyRawCommit(raw, trusted)
+	result.Commit = hash
+	return result, nil
+}
+
+// verifyRawCommit 校验原始提交对象（git cat-file commit 的输出）里的 gpgsig 头。
+func verifyRawCommit(raw []byte, trusted *TrustedSigners) *CommitSignature {
+	payload, signature := splitCommitSignature(raw)
+	result := &CommitSignature{Status: SignatureUnsigned}
+	if signature == "" {
+		return result
+	}
+	if strings.HasPrefix(signature, sshsigArmorHead) {
+		result.Format = "ssh"
+		pub, err := verifySSHSig(signature, payload)
+		if pub != nil {
+			result.Signer = ssh.FingerprintSHA256(pub)
+		}
+		if err != nil {
+			result.Status, result.Reason = SignatureInvalid, err.Error()
+			return result
+		}
+		if trusted != nil {
+			if name, ok := trusted.ssh[string(pub.Marshal())]; ok {
+				result.Status, result.Signer = SignatureTrusted, name
+				return result
+			}
+		}
+		result.Status, result.Reason = SignatureUntrusted, "签名公钥不在受信列表中"
+		return result
+	}
+
+	result.Format = "gpg"
+	var keyring openpgp.EntityList
+	if trusted != nil {
+		keyring = trusted.gpg
+	}
+	entity, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(payload), strings.NewReader(signature), nil)
+	switch {
+	case err == nil:
+		result.Status, result.Signer = SignatureTrusted, gpgEntityName(entity)
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		result.Status, result.Reason = SignatureUntrusted, "GPG 签名者不在受信公钥中，无法核验"
+	default:
+		result.Status, result.Reason = SignatureInvalid, err.Error()
+	}
+	return result
+}
+
+func gpgEntityName(entity *openpgp.Entity) string {
+	if entity == nil || entity.PrimaryKey == nil {
+		return ""
+	}
+	names := make([]string, 0, len(entity.Identities))
+	for name := range entity.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keyID := entity.PrimaryKey.KeyIdString()
+	if len(names) == 0 {
+		return keyID
+	}
+	return names[0] + " (" + keyID + ")"
+}
+
+// splitCommitSignature 从原始提交对象中取出签名头，返回签名覆盖的内容（去掉签名头的对象）与签名。
+func splitCommitSignature(raw []byte) (payload []byte, signature string) {
+	var out bytes.Buffer
+	var sig []string
+	inHeader, inSig := true, false
+	for _, line := range strings.SplitAfter(string(raw), "\n") {
+		if inHeader {
+			bare := strings.TrimSuffix(line, "\n")
+			if inSig && strings.HasPrefix(bare, " ") {
+				sig = append(sig, bare[1:])
+				continue
+			}
+			inSig = false
+			if value, ok := strings.CutPrefix(bare, "gpgsig "); ok && sig == nil {
+				sig = append(sig, value)
+				inSig = true
+				continue
+			}
+			if strings.HasPrefix(bare, "gpgsig-sha256 ") {
+				// SHA-256 对象格式的签名头：SHA-1 仓库里只是附带，不参与校验
+				inSig = true
+				continue
+			}
+			if bare == "" {
+				inHeader = false
+			}
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes(), strings.Join(sig, "\n")
+}
+
+// CommitsTouching 列出 base..head 范围内改动过 paths 的提交（--full-history，按修订范围而非提交时间），最多 limit 条。
+// base 为空时列出 head 上全部改动 paths 的提交；base 不在本地仓库时返回错误，由调用方决定退回哪里。
+// truncated 表示范围超过 limit，结果不完整：校验签名时应按未通过处理，不能只看前 limit 条。
+func CommitsTouching(head, base string, paths []string, limit int) (commits []CommitInfo, truncated bool, err error) {
+	bareDir, err := GetBareRepoDir()
+	if err != nil {
+		return nil, false, err
+	}
+	backend := currentBackend()
+	headHash, err := backend.resolveRef(bareDir, head)
+	if err != nil {
+		return nil, false, fmt.Errorf("读取 %s 失败: %w", head, err)
+	}
+	baseHash := ""
+	if base != "" {
+		if baseHash, err = backend.resolveRef(bareDir, base); err != nil {
+			return nil, false, fmt.Errorf("读取 %s 失败: %w", base, err)
+		}
+	}
+	// 多取一条：拿到 limit+1 条说明范围被截断。
+	logLimit := 0
+	if limit > 0 {
+		logLimit = limit + 1
+	}
+	commits, err = backend.logRange(bareDir, headHash, baseHash, paths, logLimit)
+	if err != nil {
+		return nil, false, err
+	}
+	if limit > 0 && len(commits) > limit {
+		return commits[:limit], true, nil
+	}
+	return commits, false, nil
+}
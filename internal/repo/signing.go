@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	git "github.com/go-git/go-git/v5"
+	"golang.org/x/crypto/ssh"
+)
+
+// 提交签名方式：global config 的 commit_signing.mode。
+const (
+	// SigningOff 不签名（默认）。
+	SigningOff = "off"
+	// SigningGit 交给系统 git 按 git config（gpg.format / user.signingkey）签名，GPG 与 SSH 均可；go-git 后端不支持。
+	SigningGit = "git"
+	// SigningSSH 用指定的 SSH 私钥文件签名（例如 secrets bundle 落地到 ~/.ssh/ 的专用 key），两个后端都支持。
+	SigningSSH = "ssh"
+)
+
+// SigningOptions 是 Dec 自己产生的 vault 提交的签名方式。
+type SigningOptions struct {
+	Mode string
+	// Key 是 ssh 模式的私钥文件绝对路径（不能带口令：dec-server 无法交互输入）。
+	Key string
+}
+
+var (
+	signingMu      sync.Mutex
+	signingOptions = SigningOptions{Mode: SigningOff}
+)
+
+// NormalizeSigningMode 规范化 commit_signing.mode，无法识别时 ok=false。
+func NormalizeSigningMode(raw string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", SigningOff, "none":
+		return SigningOff, true
+	case SigningGit:
+		return SigningGit, true
+	case SigningSSH:
+		return SigningSSH, true
+	default:
+		return "", false
+	}
+}
+
+// SetCommitSigning 设置之后所有事务提交的签名方式。
+func SetCommitSigning(opts SigningOptions) error {
+	mode, ok := NormalizeSigningMode(opts.Mode)
+	if !ok {
+		return fmt.Errorf("commit_signing.mode %q 无法识别（可选 off | git | ssh）", opts.Mode)
+	}
+	opts.Mode = mode
+	if mode == SigningSSH && strings.TrimSpace(opts.Key) == "" {
+		return fmt.Errorf("commit_signing.mode 为 ssh 时必须配置 key（SSH 私钥文件）")
+	}
+	signingMu.Lock()
+	defer signingMu.Unlock()
+	signingOptions = opts
+	return nil
+}
+
+// CommitSigning 返回当前生效的签名方式。
+func CommitSigning() SigningOptions {
+	signingMu.Lock()
+	defer signingMu.Unlock()
+	return signingOptions
+}
+
+// gitSigningArgs 返回系统 git 在 commit / merge 前需要的 -c 参数；不签名时为空。
+func gitSigningArgs(opts SigningOptions) []string {
+	switch opts.Mode {
+	case SigningGit:
+		return []string{"-c", "commit.gpgsign=true"}
+	case SigningSSH:
+		return []string{"-c", "commit.gpgsign=true", "-c", "gpg.format=ssh", "-c", "user.signingkey=" + opts.Key}
+	default:
+		return nil
+	}
+}
+
+// goGitSigner 返回 go-git 提交用的签名器；不签名时为 nil。
+func goGitSigner(opts SigningOptions) (git.Signer, error) {
+	switch opts.Mode {
+	case SigningSSH:
+		data, err := os.ReadFile(opts.Key)
+		if err != nil {
+			return nil, fmt.Errorf("读取签名私钥失败: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析签名私钥 %s 失败（不支持带口令的 key）: %w", opts.Key, err)
+		}
+		return sshCommitSigner{signer: signer}, nil
+	case SigningGit:
+		return nil, fmt.Errorf("go-git 后端无法按 git config 签名，请把 commit_signing.mode 改为 ssh 并配置 key")
+	default:
+		return nil, nil
+	}
+}
+
+// sshCommitSigner 实现 go-git 的 Signer，输出与 git gpg.format=ssh 相同的签名。
+type sshCommitSigner struct {
+	signer ssh.Signer
+}
+
+func (s sshCommitSigner) Sign(message io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+	return signSSHSig(s.signer, data)
+}
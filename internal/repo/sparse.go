@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// treeEntry 是提交树里的一个文件（不含子模块）。
+type treeEntry struct {
+	path string // 仓库相对路径，/ 分隔
+	mode uint32 // git 文件模式，如 0100644、0100755、0120000
+	hash string
+}
+
+const (
+	gitModeExecutable = 0100755
+	gitModeSymlink    = 0120000
+)
+
+// sparseIndexes 记录进行中的稀疏只读事务：工作区目录 → 提交内全部文件与目录的集合。
+// 稀疏事务只物化了部分文件，PathExists 靠它回答未物化路径的存在性。
+var sparseIndexes sync.Map
+
+// newTreeIndex 把文件列表展开为文件与各级父目录的集合。
+func newTreeIndex(files []string) map[string]struct{} {
+	index := make(map[string]struct{}, len(files)*2)
+	for _, file := range files {
+		for p := file; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+			if _, ok := index[p]; ok && p != file {
+				break
+			}
+			index[p] = struct{}{}
+		}
+	}
+	return index
+}
+
+// PathExists 判断路径在其所属事务的提交中是否存在。
+//
+// 稀疏只读事务（ReadOptions.Select）只把选中的文件写到工作区，
+// 工作区内未物化的路径按提交内容作答；其余路径直接看磁盘。
+func PathExists(p string) bool {
+	clean := filepath.Clean(p)
+	found, exists := false, false
+	sparseIndexes.Range(func(key, value any) bool {
+		root := key.(string)
+		rel, err := filepath.Rel(root, clean)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+		found = true
+		if rel == "." {
+			exists = true
+			return false
+		}
+		_, exists = value.(map[string]struct{})[filepath.ToSlash(rel)]
+		return false
+	})
+	if found {
+		return exists
+	}
+	_, err := os.Stat(clean)
+	return err == nil
+}
+
+// sparsePlan 是稀疏物化的计划：ref 解析出的提交、提交内完整文件索引与选中的文件。
+type sparsePlan struct {
+	commit string
+	index  map[string]struct{}
+	picked []treeEntry
+}
+
+// planSparse 列出 ref 对应提交的文件并按 selectFn 选出要物化的部分，不写磁盘。
+func planSparse(backend gitBackend, bareDir, ref string, selectFn func([]string) []string) (*sparsePlan, error) {
+	commit, err := backend.resolveRef(bareDir, ref)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := backend.listTree(bareDir, commit)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, len(entries))
+	byPath := make(map[string]treeEntry, len(entries))
+	for i, entry := range entries {
+		files[i] = entry.path
+		byPath[entry.path] = entry
+	}
+	plan := &sparsePlan{commit: commit, index: newTreeIndex(files)}
+	for _, file := range selectFn(files) {
+		if entry, ok := byPath[file]; ok {
+			plan.picked = append(plan.picked, entry)
+			delete(byPath, file)
+		}
+	}
+	return plan, nil
+}
+
+// materialize 把选中的文件写入 dir。
+func (p *sparsePlan) materialize(backend gitBackend, bareDir, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return backend.checkoutFiles(bareDir, dir, p.picked)
+}
+
+// snapshotKey 返回只读快照池里区分物化范围的部分。
+func (p *sparsePlan) snapshotKey() string {
+	paths := make([]string, len(p.picked))
+	for i, entry := range p.picked {
+		paths[i] = entry.path
+	}
+	sort.Strings(paths)
+	sum := sha256.Sum256([]byte(strings.Join(paths, "\n")))
+	return "sparse:" + hex.EncodeToString(sum[:])
+}
+
+// writeTreeFile 按 git 文件模式把一个 blob 写到 dir 下。
+// 无法创建符号链接的平台退化为写入链接目标文本，与 core.symlinks=false 一致。
+func writeTreeFile(dir string, entry treeEntry, data []byte) error {
+	target := filepath.Join(dir, filepath.FromSlash(entry.path))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if entry.mode == gitModeSymlink {
+		if err := os.Symlink(string(data), target); err == nil {
+			return nil
+		}
+	}
+	perm := os.FileMode(0644)
+	if entry.mode == gitModeExecutable {
+		perm = 0755
+	}
+	if err := os.WriteFile(target, data, perm); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", entry.path, err)
+	}
+	return nil
+}
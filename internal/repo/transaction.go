@@ -11,57 +11,91 @@ import (
 	"time"
 
 	"github.com/shichao402/Dec/internal/diag"
-	"github.com/shichao402/Dec/internal/sysproc"
 )
 
+// txBranchPrefix 是可写事务临时分支的名字前缀。
+const txBranchPrefix = "dec-tx-"
+
 // bareOpMu 串行化 bare 上的 fetch / worktree 增删，避免 TUI 并联 refresh 在 Windows 上互卡。
 var bareOpMu sync.Mutex
 
 // Transaction 封装基于 bare repo 的短生命周期工作区
-// readOnly=true 表示只读事务，仅用于读取本地工作区文件。
+// readOnly=true 表示只读事务，仅用于读取本地工作区文件；启用快照池时工作区可能与其他只读事务共享，不得修改。
+// sparse=true 表示工作区只物化了部分文件且不是 git worktree，见 ReadOptions.Select。
 type Transaction struct {
+	backend     gitBackend
 	bareDir     string
 	worktreeDir string
 	branch      string
 	tempBranch  string
+	commit      string
 	readOnly    bool
+	sparse      bool
 	cleaned     bool
+	// offlineReason 非空表示事务建立时没有同步远端（离线模式或远端不可达），读的是本地 bare repo。
+	offlineReason string
+	// queued 表示离线时提交只进了本地 push 队列，见 QueuedPush。
+	queued bool
+	// snapshot 非 nil 表示工作区来自只读快照池，可能与其他只读事务共享，关闭时只释放引用。
+	snapshot *readSnapshot
+}
+
+// ReadOptions 描述只读事务读取的版本与物化范围。
+type ReadOptions struct {
+	// Branch 为跟随的 vault 分支，空串表示远端默认分支。
+	Branch string
+	// Ref 非空时读取该版本（commit hash、tag 或 branch 名称）。
+	Ref string
+	// Local 为 true 时不先 FetchBare。未设置时按 OfflineMode 决定远端不可达是否回落到本地，见 Transaction.Offline。
+	Local bool
+	// Select 非 nil 时只物化它从提交内全部文件（/ 分隔的仓库相对路径）中选出的文件，
+	// 不再创建 git worktree；未物化文件的存在性用 PathExists 判断。
+	Select func(files []string) []string
 }
 
 // NewReadTransaction 创建只读事务（会先 FetchBare）。
 func NewReadTransaction() (*Transaction, error) {
-	return newTransaction(true, true)
+	return NewReadTransactionWith(ReadOptions{})
+}
+
+// NewReadTransactionOn 在指定 vault 分支上创建只读事务；branch 为空时跟随远端默认分支。
+func NewReadTransactionOn(branch string) (*Transaction, error) {
+	return NewReadTransactionWith(ReadOptions{Branch: branch})
 }
 
 // NewLocalReadTransaction 创建只读事务但不 fetch，用于 TUI 概览/列表等可接受略旧 refs 的场景。
 func NewLocalReadTransaction() (*Transaction, error) {
-	return newTransaction(true, false)
+	return NewReadTransactionWith(ReadOptions{Local: true})
+}
+
+// NewLocalReadTransactionOn 是 NewLocalReadTransaction 的指定分支版本。
+func NewLocalReadTransactionOn(branch string) (*Transaction, error) {
+	return NewReadTransactionWith(ReadOptions{Branch: branch, Local: true})
 }
 
 // NewReadTransactionAt 创建指定版本的只读事务。
 // ref 可以是 commit hash、tag 或 branch 名称。
 func NewReadTransactionAt(ref string) (*Transaction, error) {
-	tx, err := newTransaction(true, true)
-	if err != nil {
-		return nil, err
-	}
-
-	git := NewGitOps(tx.worktreeDir)
-	if _, err := git.run("checkout", ref); err != nil {
-		tx.Close()
-		return nil, fmt.Errorf("切换到版本 %s 失败: %w", ref, err)
-	}
+	return NewReadTransactionWith(ReadOptions{Ref: ref})
+}
 
-	return tx, nil
+// NewReadTransactionWith 按 opts 创建只读事务。
+func NewReadTransactionWith(opts ReadOptions) (*Transaction, error) {
+	return newTransaction(true, !opts.Local, opts.Branch, opts.Ref, opts.Select)
 }
 
 // NewWriteTransaction 创建可写事务。
 func NewWriteTransaction() (*Transaction, error) {
-	return newTransaction(false, true)
+	return newTransaction(false, true, "", "", nil)
 }
 
-func newTransaction(readOnly, fetch bool) (*Transaction, error) {
-	label := fmt.Sprintf("bareTX readOnly=%v fetch=%v", readOnly, fetch)
+// NewWriteTransactionOn 创建推送到指定 vault 分支的可写事务；branch 为空时跟随远端默认分支。
+func NewWriteTransactionOn(branch string) (*Transaction, error) {
+	return newTransaction(false, true, branch, "", nil)
+}
+
+func newTransaction(readOnly, fetch bool, branch, ref string, selectFn func([]string) []string) (*Transaction, error) {
+	label := fmt.Sprintf("bareTX readOnly=%v fetch=%v branch=%s ref=%s sparse=%v", readOnly, fetch, branch, ref, selectFn != nil)
 	diag.StartupLog("bareOpMu waiting… (%s)", label)
 	waitStart := time.Now()
 	bareOpMu.Lock()
@@ -75,6 +109,7 @@ func newTransaction(readOnly, fetch bool) (*Transaction, error) {
 		return nil, err
 	}
 
+	backend := currentBackend()
 	bareDir, err := GetBareRepoDir()
 	if err != nil {
 		return nil, err
@@ -87,56 +122,154 @@ func newTransaction(readOnly, fetch bool) (*Transaction, error) {
 		return nil, fmt.Errorf("仓库未连接\n\n请先到 Settings 页配置 Repo URL")
 	}
 
+	offlineReason := ""
 	if fetch {
 		diag.StartupLog("FetchBare starting")
-		if err := FetchBare(); err != nil {
+		offlineReason, err = fetchOrOffline()
+		if err != nil {
 			diag.StartupLog("FetchBare failed: %v", err)
 			return nil, err
 		}
-		diag.StartupLog("FetchBare done")
+		if offlineReason != "" {
+			diag.StartupLog("FetchBare skipped, offline: %s", offlineReason)
+		} else {
+			diag.StartupLog("FetchBare done")
+		}
 	}
-	branch, err := GetDefaultBranch()
+	branch, err = resolveTxBranch(backend, bareDir, branch)
 	if err != nil {
 		return nil, err
 	}
 
+	if readOnly {
+		return newReadOnlyTransaction(backend, bareDir, branch, ref, selectFn, offlineReason)
+	}
+
 	worktreeDir, err := newWorktreePath()
 	if err != nil {
 		return nil, err
 	}
 
-	if readOnly {
-		diag.StartupLog("addDetachedWorktree starting branch=%s", branch)
-		if err := addDetachedWorktree(bareDir, worktreeDir, branch); err != nil {
-			diag.StartupLog("addDetachedWorktree failed: %v", err)
-			return nil, err
-		}
-		diag.StartupLog("addDetachedWorktree done")
-		return &Transaction{bareDir: bareDir, worktreeDir: worktreeDir, branch: branch, readOnly: true}, nil
-	}
-
 	tempBranch, err := randomBranchName("dec-tx")
 	if err != nil {
 		return nil, err
 	}
-	if err := addDetachedWorktree(bareDir, worktreeDir, branch); err != nil {
+	// 可写事务通常从跟随分支开始；ref 非空时从该版本开始（发送排队的提交）。
+	// 离线时若该分支已有排队的提交，接着它们往下提交，远端恢复后一并送出。
+	startPoint := branch
+	if ref != "" {
+		startPoint = ref
+	} else if offlineReason != "" {
+		if _, err := backend.resolveRef(bareDir, pushQueueRef(branch)); err == nil {
+			startPoint = pushQueueRef(branch)
+		}
+	}
+	if err := backend.addWorktree(bareDir, worktreeDir, startPoint); err != nil {
 		return nil, err
 	}
 
-	git := NewGitOps(worktreeDir)
-	if _, err := git.run("switch", "-c", tempBranch); err != nil {
-		_ = removeWorktree(bareDir, worktreeDir)
+	if err := backend.createBranch(worktreeDir, tempBranch); err != nil {
+		_ = backend.removeWorktree(bareDir, worktreeDir)
 		return nil, fmt.Errorf("创建事务分支失败: %w", err)
 	}
 
 	return &Transaction{
-		bareDir:     bareDir,
-		worktreeDir: worktreeDir,
-		branch:      branch,
-		tempBranch:  tempBranch,
+		backend:       backend,
+		bareDir:       bareDir,
+		worktreeDir:   worktreeDir,
+		branch:        branch,
+		tempBranch:    tempBranch,
+		offlineReason: offlineReason,
 	}, nil
 }
 
+// newReadOnlyTransaction 检出只读事务的工作区；调用方需持有 bareOpMu。
+// 启用快照池（SetReadPool）时，同一提交、同一物化范围的只读事务共享一份检出。
+func newReadOnlyTransaction(backend gitBackend, bareDir, branch, ref string, selectFn func([]string) []string, offlineReason string) (*Transaction, error) {
+	startPoint := "refs/heads/" + branch
+	if ref != "" {
+		startPoint = ref
+	}
+	wrapRefErr := func(err error) error {
+		if ref != "" {
+			return fmt.Errorf("切换到版本 %s 失败: %w", ref, err)
+		}
+		return err
+	}
+	tx := &Transaction{backend: backend, bareDir: bareDir, branch: branch, readOnly: true, sparse: selectFn != nil, offlineReason: offlineReason}
+
+	var plan *sparsePlan
+	scope := "full"
+	if selectFn != nil {
+		var err error
+		diag.StartupLog("planSparse starting ref=%s backend=%s", startPoint, backend.name())
+		if plan, err = planSparse(backend, bareDir, startPoint, selectFn); err != nil {
+			return nil, wrapRefErr(err)
+		}
+		tx.commit, scope = plan.commit, plan.snapshotKey()
+	} else {
+		commit, err := backend.resolveRef(bareDir, startPoint)
+		if err != nil {
+			return nil, wrapRefErr(err)
+		}
+		tx.commit = commit
+	}
+	key := readSnapshotKey(bareDir, tx.commit, scope)
+	if snap := snapshots.acquireLocked(key); snap != nil {
+		diag.StartupLog("read snapshot reused commit=%s sparse=%v", tx.commit, tx.sparse)
+		tx.worktreeDir, tx.snapshot = snap.dir, snap
+		return tx, nil
+	}
+
+	worktreeDir, err := newWorktreePath()
+	if err != nil {
+		return nil, err
+	}
+	tx.worktreeDir = worktreeDir
+	if plan != nil {
+		if err := plan.materialize(backend, bareDir, worktreeDir); err != nil {
+			_ = os.RemoveAll(worktreeDir)
+			return nil, wrapRefErr(err)
+		}
+		diag.StartupLog("planSparse materialized files=%d", len(plan.picked))
+		sparseIndexes.Store(filepath.Clean(worktreeDir), plan.index)
+	} else {
+		diag.StartupLog("addDetachedWorktree starting commit=%s backend=%s", tx.commit, backend.name())
+		if err := backend.addWorktree(bareDir, worktreeDir, tx.commit); err != nil {
+			diag.StartupLog("addDetachedWorktree failed: %v", err)
+			_ = os.RemoveAll(worktreeDir)
+			backend.pruneWorktrees(bareDir)
+			return nil, wrapRefErr(err)
+		}
+		diag.StartupLog("addDetachedWorktree done")
+	}
+	tx.snapshot = snapshots.addLocked(&readSnapshot{
+		key: key, backend: backend, bareDir: bareDir, dir: worktreeDir, commit: tx.commit, sparse: tx.sparse,
+	})
+	return tx, nil
+}
+
+// BranchNotFoundError 表示同步远端后 bare 中仍没有要跟随的分支。
+type BranchNotFoundError struct {
+	Branch string
+}
+
+func (e *BranchNotFoundError) Error() string {
+	return fmt.Sprintf("vault 分支 %s 不存在，请确认远端已创建该分支或修改 vault_branch", e.Branch)
+}
+
+// resolveTxBranch 返回事务要跟随的分支：显式指定时要求 bare 中已有该分支，否则取远端默认分支。
+func resolveTxBranch(backend gitBackend, bareDir, branch string) (string, error) {
+	branch = strings.TrimSpace(branch)
+	if branch == "" {
+		return GetDefaultBranch()
+	}
+	if _, err := backend.resolveRef(bareDir, "refs/heads/"+branch); err != nil {
+		return "", &BranchNotFoundError{Branch: branch}
+	}
+	return branch, nil
+}
+
 func newWorktreePath() (string, error) {
 	rootDir, err := GetRootDir()
 	if err != nil {
@@ -160,24 +293,6 @@ func randomBranchName(prefix string) (string, error) {
 	return fmt.Sprintf("%s-%s", prefix, hex.EncodeToString(buf)), nil
 }
 
-func addDetachedWorktree(bareDir, worktreeDir, startPoint string) error {
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "worktree", "add", "--detach", worktreeDir, startPoint)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git worktree add 失败: %s", strings.TrimSpace(string(output)))
-	}
-	return nil
-}
-
-func removeWorktree(bareDir, worktreeDir string) error {
-	cmd := sysproc.Command("git", "--git-dir", bareDir, "worktree", "remove", "--force", worktreeDir)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git worktree remove 失败: %s", strings.TrimSpace(string(output)))
-	}
-	return nil
-}
-
 // PruneOrphanWorktrees 清理上次进程异常退出残留的事务工作树与临时分支。
 //
 // 仅应在 dec-server 启动时调用：单例锁已持有、进程内无活跃事务，因此 rootDir 下所有
@@ -195,6 +310,7 @@ func PruneOrphanWorktrees() (removed int, err error) {
 	if err != nil {
 		return 0, err
 	}
+	backend := currentBackend()
 	// bare 未初始化 → 从未 worktree add 过，无需清理。
 	if ok, _ := isBareRepo(bareDir); !ok {
 		return 0, nil
@@ -212,30 +328,29 @@ func PruneOrphanWorktrees() (removed int, err error) {
 			continue
 		}
 		dir := filepath.Join(rootDir, e.Name())
-		if rmErr := removeWorktree(bareDir, dir); rmErr != nil {
+		if snapshots.ownsLocked(dir) {
+			continue
+		}
+		if rmErr := backend.removeWorktree(bareDir, dir); rmErr != nil {
 			_ = os.RemoveAll(dir)
 		}
 		removed++
 	}
 
 	// 清理 git worktree 元数据与残留事务分支。
-	_ = sysproc.Command("git", "--git-dir", bareDir, "worktree", "prune").Run()
-	pruneOrphanTxBranches(bareDir)
+	backend.pruneWorktrees(bareDir)
+	pruneOrphanTxBranches(backend, bareDir)
 	return removed, nil
 }
 
 // pruneOrphanTxBranches 删除 crash 残留的 dec-tx-* 临时分支。
-func pruneOrphanTxBranches(bareDir string) {
-	out, err := sysproc.Command("git", "--git-dir", bareDir, "branch", "--list", "dec-tx-*").CombinedOutput()
+func pruneOrphanTxBranches(backend gitBackend, bareDir string) {
+	names, err := backend.listBranches(bareDir, txBranchPrefix)
 	if err != nil {
 		return
 	}
-	for _, line := range strings.Split(string(out), "\n") {
-		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
-		if name == "" {
-			continue
-		}
-		_ = sysproc.Command("git", "--git-dir", bareDir, "branch", "-D", name).Run()
+	for _, name := range names {
+		_ = backend.deleteBranch(bareDir, name)
 	}
 }
 
@@ -261,16 +376,43 @@ func (t *Transaction) WorkDir() string {
 	return t.worktreeDir
 }
 
+// Branch 返回事务跟随（可写事务则推送）的 vault 分支名。
+func (t *Transaction) Branch() string {
+	return t.branch
+}
+
 // CommitHash 返回当前事务工作目录的 HEAD commit hash
 func (t *Transaction) CommitHash() string {
-	git := NewGitOps(t.worktreeDir)
-	hash, err := git.run("rev-parse", "HEAD")
+	if t.readOnly && t.commit != "" {
+		return t.commit
+	}
+	hash, err := t.backend.headCommit(t.worktreeDir)
 	if err != nil {
 		return ""
 	}
 	return hash
 }
 
+// Offline 判断事务是否读的是未同步远端的本地 bare repo（离线模式或远端不可达）。
+func (t *Transaction) Offline() bool {
+	return t.offlineReason != ""
+}
+
+// OfflineReason 返回离线原因；在线时为空。
+func (t *Transaction) OfflineReason() string {
+	return t.offlineReason
+}
+
+// Queued 判断 CommitAndPush 是否只把提交放进了本地 push 队列（离线），尚未送到远端。
+func (t *Transaction) Queued() bool {
+	return t.queued
+}
+
+// IsClean 检查事务工作区相对 HEAD 是否没有任何改动。
+func (t *Transaction) IsClean() (bool, error) {
+	return t.backend.isClean(t.worktreeDir)
+}
+
 // Rollback 终止事务并清理临时目录。
 func (t *Transaction) Rollback() error {
 	return t.cleanup()
@@ -291,19 +433,30 @@ func (t *Transaction) cleanup() error {
 		return nil
 	}
 	t.cleaned = true
+	return t.removeLocked()
+}
+
+// removeLocked 删除事务工作区与临时分支；调用方需持有 bareOpMu。
+func (t *Transaction) removeLocked() error {
+	if t.snapshot != nil {
+		snapshots.releaseLocked(t.snapshot)
+		return nil
+	}
+	if t.sparse {
+		sparseIndexes.Delete(filepath.Clean(t.worktreeDir))
+		return os.RemoveAll(t.worktreeDir)
+	}
 
 	var cleanupErr error
-	if err := removeWorktree(t.bareDir, t.worktreeDir); err != nil {
+	if err := t.backend.removeWorktree(t.bareDir, t.worktreeDir); err != nil {
 		cleanupErr = err
 		_ = os.RemoveAll(t.worktreeDir)
 	}
 
-	pruneCmd := sysproc.Command("git", "--git-dir", t.bareDir, "worktree", "prune")
-	_ = pruneCmd.Run()
+	t.backend.pruneWorktrees(t.bareDir)
 
 	if t.tempBranch != "" {
-		deleteCmd := sysproc.Command("git", "--git-dir", t.bareDir, "branch", "-D", t.tempBranch)
-		_ = deleteCmd.Run()
+		_ = t.backend.deleteBranch(t.bareDir, t.tempBranch)
 	}
 
 	return cleanupErr
@@ -311,6 +464,19 @@ func (t *Transaction) cleanup() error {
 
 // CommitAndPush 提交并推送；若工作区或暂存区最终无实质变更则 committed=false。
 func (t *Transaction) CommitAndPush(message string) (committed bool, err error) {
+	return t.commitAndPush(message, t.branch)
+}
+
+// CommitAndPushToBranch 提交并推送到 target 分支（通常是新建的评审分支），
+// 事务跟随的分支在本地与远端都保持不动。
+func (t *Transaction) CommitAndPushToBranch(message, target string) (committed bool, err error) {
+	if strings.TrimSpace(target) == "" {
+		return false, fmt.Errorf("目标分支不能为空")
+	}
+	return t.commitAndPush(message, target)
+}
+
+func (t *Transaction) commitAndPush(message, target string) (committed bool, err error) {
 	if t.readOnly {
 		return false, fmt.Errorf("只读事务不支持提交")
 	}
@@ -318,8 +484,7 @@ func (t *Transaction) CommitAndPush(message string) (committed bool, err error)
 		return false, fmt.Errorf("事务已关闭")
 	}
 
-	git := NewGitOps(t.worktreeDir)
-	clean, err := git.IsClean()
+	clean, err := t.backend.isClean(t.worktreeDir)
 	if err != nil {
 		return false, err
 	}
@@ -327,51 +492,51 @@ func (t *Transaction) CommitAndPush(message string) (committed bool, err error)
 		return false, nil
 	}
 
-	if err := git.Add("."); err != nil {
-		return false, fmt.Errorf("git add 失败: %w", err)
-	}
-	// status 可能因换行等判脏，add 后用 cached diff 判定是否真有可提交内容。
-	if has, derr := git.HasCachedDiff(); derr != nil {
-		return false, derr
-	} else if !has {
-		return false, nil
+	committed, err = t.backend.commitAll(t.worktreeDir, message)
+	if err != nil || !committed {
+		return false, err
 	}
-	if err := git.Commit(message); err != nil {
-		if isNothingToCommitError(err) {
-			return false, nil
+	if t.Offline() {
+		hash, err := t.backend.headCommit(t.worktreeDir)
+		if err != nil {
+			return false, err
 		}
-		return false, fmt.Errorf("git commit 失败: %w", err)
-	}
-	if _, err := git.run("push", "origin", fmt.Sprintf("HEAD:%s", t.branch)); err == nil {
-		t.syncBareRef(git)
+		if err := t.backend.setRef(t.bareDir, pushQueueRef(target), hash); err != nil {
+			return false, fmt.Errorf("提交加入离线队列失败: %w", err)
+		}
+		t.queued = true
 		return true, nil
-	} else if !isNonFastForwardPushError(err) {
-		return false, fmt.Errorf("git push 失败: %w", err)
 	}
-
-	if err := git.ensureNoSyncInProgress(); err != nil {
+	if err := t.pushHead(target); err != nil {
 		return false, err
 	}
-	if _, err := git.run("fetch", "origin", t.branch); err != nil {
-		return false, fmt.Errorf("拉取远端引用失败: %w", err)
+	return true, nil
+}
+
+// pushHead 把工作区 HEAD 推到 target；远端已前进时先整合再推。
+func (t *Transaction) pushHead(target string) error {
+	if err := t.backend.push(t.worktreeDir, target); err == nil {
+		t.syncBareRef(target)
+		return nil
+	} else if !isNonFastForwardPushError(err) {
+		return fmt.Errorf("git push 失败: %w", err)
 	}
-	if _, err := git.run("merge", "--no-edit", "FETCH_HEAD"); err != nil {
-		_ = git.abortMerge()
-		return false, fmt.Errorf("与远端存在冲突，请稍后重试: %w", err)
+
+	if err := t.backend.integrateRemote(t.worktreeDir, target); err != nil {
+		return err
 	}
-	if _, err := git.run("push", "origin", fmt.Sprintf("HEAD:%s", t.branch)); err != nil {
-		return false, fmt.Errorf("git push 失败: %w", err)
+	if err := t.backend.push(t.worktreeDir, target); err != nil {
+		return fmt.Errorf("git push 失败: %w", err)
 	}
-	t.syncBareRef(git)
-	return true, nil
+	t.syncBareRef(target)
+	return nil
 }
 
 // syncBareRef 将 worktree 的 HEAD 同步到 bare repo 的目标分支
-func (t *Transaction) syncBareRef(git *GitOps) {
-	hash, err := git.run("rev-parse", "HEAD")
+func (t *Transaction) syncBareRef(branch string) {
+	hash, err := t.backend.headCommit(t.worktreeDir)
 	if err != nil {
 		return
 	}
-	cmd := sysproc.Command("git", "--git-dir", t.bareDir, "update-ref", fmt.Sprintf("refs/heads/%s", t.branch), hash)
-	_ = cmd.Run()
+	_ = t.backend.updateRef(t.bareDir, branch, hash)
 }
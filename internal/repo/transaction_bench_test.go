@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 合成 vault：benchBundles 个 bundle，每个带 bundle.yaml、一个 skill 与一份较大的资产文件。
+const (
+	benchBundles   = 300
+	benchAssetSize = 64 << 10
+	benchEnabled   = 5
+)
+
+func newBenchVault(b *testing.B) *conformanceRemote {
+	b.Helper()
+	large := strings.Repeat("0123456789abcdef", benchAssetSize/16)
+	files := map[string]string{"projects/demo.yaml": "name: demo\n"}
+	for i := 0; i < benchBundles; i++ {
+		dir := fmt.Sprintf("bundles/bundle-%03d", i)
+		files[dir+"/bundle.yaml"] = fmt.Sprintf("name: bundle-%03d\nmembers:\n  - skill/skill-%03d\n", i, i)
+		files[fmt.Sprintf("%s/skills/skill-%03d/SKILL.md", dir, i)] = "---\nname: skill\n---\nbody\n"
+		files[fmt.Sprintf("%s/skills/skill-%03d/assets/model.bin", dir, i)] = large
+	}
+	return newConformanceRemote(b, files)
+}
+
+// benchSelect 选出 projects/、全部 bundle 声明，以及前 enabled 个 bundle 的整个目录。
+func benchSelect(enabled int) func([]string) []string {
+	return func(files []string) []string {
+		var picked []string
+		for _, f := range files {
+			keep := strings.HasPrefix(f, "projects/") || strings.HasSuffix(f, "/bundle.yaml")
+			for i := 0; i < enabled && !keep; i++ {
+				keep = strings.HasPrefix(f, fmt.Sprintf("bundles/bundle-%03d/", i))
+			}
+			if keep {
+				picked = append(picked, f)
+			}
+		}
+		return picked
+	}
+}
+
+// BenchmarkReadTransaction 对比完整 worktree 与稀疏物化（拉取 / 只读声明）的只读事务开销。
+//
+//	go test ./internal/repo -run '^$' -bench ReadTransaction -benchtime 20x
+func BenchmarkReadTransaction(b *testing.B) {
+	for _, backend := range []string{BackendGit, BackendGoGit} {
+		b.Run(backend, func(b *testing.B) {
+			if backend == BackendGit {
+				if _, err := exec.LookPath("git"); err != nil {
+					b.Skip("未安装 git，跳过系统 git 后端")
+				}
+			}
+			setEnvForTest(b, backendEnv, backend)
+			remote := newBenchVault(b)
+			connectConformance(b, remote)
+
+			cases := []struct {
+				name string
+				opts ReadOptions
+			}{
+				{"full", ReadOptions{Local: true}},
+				{"pull-enabled", ReadOptions{Local: true, Select: benchSelect(benchEnabled)}},
+				{"manifests", ReadOptions{Local: true, Select: benchSelect(0)}},
+			}
+			for _, c := range cases {
+				b.Run(c.name, func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						tx, err := NewReadTransactionWith(c.opts)
+						if err != nil {
+							b.Fatalf("NewReadTransactionWith() 失败: %v", err)
+						}
+						tx.Close()
+					}
+				})
+			}
+		})
+	}
+}
+
+// BenchmarkTUIStartupReads 模拟 TUI 启动时的一轮只读事务：Bundles 扫描与概览补全各读一次声明，
+// Settings、项目变量与 vault project 推断各开一次完整只读事务。每轮从空的快照池开始。
+//
+//	go test ./internal/repo -run '^$' -bench TUIStartupReads -benchtime 20x
+func BenchmarkTUIStartupReads(b *testing.B) {
+	sequence := []ReadOptions{
+		{Local: true, Select: benchSelect(0)},
+		{Local: true},
+		{Local: true},
+		{Local: true},
+		{Local: true, Select: benchSelect(0)},
+	}
+	for _, backend := range []string{BackendGit, BackendGoGit} {
+		b.Run(backend, func(b *testing.B) {
+			if backend == BackendGit {
+				if _, err := exec.LookPath("git"); err != nil {
+					b.Skip("未安装 git，跳过系统 git 后端")
+				}
+			}
+			setEnvForTest(b, backendEnv, backend)
+			remote := newBenchVault(b)
+			connectConformance(b, remote)
+
+			for _, pool := range []int{0, 8} {
+				b.Run(fmt.Sprintf("pool=%d", pool), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						b.StopTimer()
+						SetReadPool(0, 0)
+						SetReadPool(pool, time.Minute)
+						b.StartTimer()
+						for _, opts := range sequence {
+							tx, err := NewReadTransactionWith(opts)
+							if err != nil {
+								b.Fatalf("NewReadTransactionWith() 失败: %v", err)
+							}
+							tx.Close()
+						}
+					}
+					b.StopTimer()
+					SetReadPool(0, 0)
+				})
+			}
+		})
+	}
+}
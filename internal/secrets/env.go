@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"unicode"
 )
@@ -96,6 +97,71 @@ func LoadEnvForBundle(projectRoot, bundleName string, plane SyncPlane) (map[stri
 	return out, nil
 }
 
+// MergeEnvIntoTarget 把 vars 合并写入 SyncTarget 同步根下的 .env/<name>.env（0600，不进 Git）。
+//
+// 文件已存在时保留原有键；同名键值不同视为冲突并报错，避免静默覆盖已登记的凭据。
+// 返回同步根相对路径（如 .env/github.env），供后续 push 登记为 Bitwarden Note。
+func MergeEnvIntoTarget(projectRoot string, target SyncTarget, name string, vars map[string]string) (string, error) {
+	if err := RequireDeclared(target); err != nil {
+		return "", err
+	}
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".env")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("非法 .env 文件名 %q", name)
+	}
+	noteRel := path.Join(TypeDirEnv, name+".env")
+	if len(vars) == 0 {
+		return noteRel, nil
+	}
+	abs, err := ResolveAbsDir(projectRoot, target)
+	if err != nil {
+		return "", err
+	}
+	filePath := filepath.Join(abs, filepath.FromSlash(noteRel))
+
+	merged := make(map[string]string, len(vars))
+	if _, statErr := os.Stat(filePath); statErr == nil {
+		existing, err := parseDotEnvFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", noteRel, err)
+		}
+		for k, v := range existing {
+			merged[k] = v
+		}
+	} else if !os.IsNotExist(statErr) {
+		return "", statErr
+	}
+	for k, v := range vars {
+		if _, _, err := parseDotEnvLine(k + "=x"); err != nil {
+			return "", err
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return "", fmt.Errorf("环境变量 %s 的值含换行，.env 不支持多行值", k)
+		}
+		if prev, ok := merged[k]; ok && prev != v {
+			return "", fmt.Errorf("环境变量 %s 已在 %s 中以不同的值定义", k, noteRel)
+		}
+		merged[k] = v
+	}
+
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(merged[k])
+		b.WriteByte('\n')
+	}
+	if err := writeSecureFile(filePath, []byte(b.String()), 0600); err != nil {
+		return "", err
+	}
+	return noteRel, nil
+}
+
 func parseDotEnvFile(path string) (map[string]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
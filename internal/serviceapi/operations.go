@@ -176,6 +176,15 @@ func EnsureProjectVarsFile(projectRoot string) (*app.EnsureProjectVarsFileResult
 	return invoke[app.EnsureProjectVarsFileResult](context.Background(), "ensure_project_vars", projectRoot, nil, nil)
 }
 
+func ExplainVars(input app.ExplainVarsInput) (*app.VarsExplanation, error) {
+	return invoke[app.VarsExplanation](context.Background(), "explain_vars", input.ProjectRoot, input, nil)
+}
+
+func SwitchVarsProfile(projectRoot, profile string, reporter app.Reporter) (*app.SwitchVarsProfileResult, error) {
+	input := struct{ Profile string }{Profile: profile}
+	return invoke[app.SwitchVarsProfileResult](context.Background(), "switch_vars_profile", projectRoot, input, reporter)
+}
+
 func ListSecretSyncTargets(projectRoot string) ([]app.SecretTargetOption, error) {
 	return invokeSlice[app.SecretTargetOption](context.Background(), "list_secret_sync_targets", projectRoot, nil, nil)
 }
@@ -212,6 +221,28 @@ func PullWorkspaceAssets(ctx context.Context, workspace app.Workspace, reporter
 	return runWorkspace[app.PullProjectAssetsResult](ctx, "pull", workspace, nil, reporter)
 }
 
+func PullWorkspaceBranchPreview(ctx context.Context, workspace app.Workspace, branch string, reporter app.Reporter) (*app.PullProjectAssetsResult, error) {
+	return runWorkspace[app.PullProjectAssetsResult](ctx, "pull_branch_preview", workspace,
+		struct{ Branch string }{branch}, reporter)
+}
+
+func LoadPullHistory(workspace app.Workspace) (*app.PullHistory, error) {
+	return invokeWorkspace[app.PullHistory](context.Background(), "load_pull_history", workspace, nil, nil)
+}
+
+func RollbackWorkspacePull(ctx context.Context, workspace app.Workspace, commit string, reporter app.Reporter) (*app.PullProjectAssetsResult, error) {
+	return runWorkspace[app.PullProjectAssetsResult](ctx, "rollback_pull", workspace,
+		struct{ Commit string }{commit}, reporter)
+}
+
+func UnholdWorkspacePull(workspace app.Workspace) (*app.PullHistory, error) {
+	return invokeWorkspace[app.PullHistory](context.Background(), "unhold_pull", workspace, nil, nil)
+}
+
+func PreviewPullWorkspaceAssets(ctx context.Context, workspace app.Workspace, reporter app.Reporter) (*app.PullPreview, error) {
+	return runWorkspace[app.PullPreview](ctx, "preview_pull", workspace, nil, reporter)
+}
+
 func PushProjectAssets(ctx context.Context, projectRoot string, reporter app.Reporter) (*app.PushProjectAssetsResult, error) {
 	return run[app.PushProjectAssetsResult](ctx, "push", projectRoot, nil, reporter)
 }
@@ -220,6 +251,10 @@ func PushWorkspaceAssets(ctx context.Context, workspace app.Workspace, reporter
 	return runWorkspace[app.PushProjectAssetsResult](ctx, "push", workspace, nil, reporter)
 }
 
+func PushWorkspaceWith(ctx context.Context, workspace app.Workspace, opts app.PushOptions, reporter app.Reporter) (*app.PushProjectAssetsResult, error) {
+	return runWorkspace[app.PushProjectAssetsResult](ctx, "push", workspace, opts, reporter)
+}
+
 func PreviewPushProjectAssets(ctx context.Context, projectRoot string, reporter app.Reporter) (*app.PushProjectAssetsPreview, error) {
 	return run[app.PushProjectAssetsPreview](ctx, "preview_push", projectRoot, nil, reporter)
 }
@@ -228,6 +263,40 @@ func PreviewPushWorkspaceAssets(ctx context.Context, workspace app.Workspace, re
 	return runWorkspace[app.PushProjectAssetsPreview](ctx, "preview_push", workspace, nil, reporter)
 }
 
+func LoadAssetHistory(ctx context.Context, workspace app.Workspace, query app.AssetHistoryQuery, reporter app.Reporter) (*app.AssetHistoryResult, error) {
+	return runWorkspace[app.AssetHistoryResult](ctx, "asset_history", workspace, query, reporter)
+}
+
+func RestoreAssetRevision(ctx context.Context, workspace app.Workspace, query app.AssetHistoryQuery, reporter app.Reporter) (*app.AssetRestoreResult, error) {
+	return runWorkspace[app.AssetRestoreResult](ctx, "restore_asset_revision", workspace, query, reporter)
+}
+
+func LoadVaultFormat(workspace app.Workspace) (*app.VaultFormatStatus, error) {
+	return invokeWorkspace[app.VaultFormatStatus](context.Background(), "load_vault_format", workspace, nil, nil)
+}
+
+func MigrateVault(ctx context.Context, workspace app.Workspace, input app.MigrateVaultInput, reporter app.Reporter) (*app.MigrateVaultResult, error) {
+	return runWorkspace[app.MigrateVaultResult](ctx, "migrate_vault", workspace, input, reporter)
+}
+
+func ScanUnmanagedAssets(ctx context.Context, workspace app.Workspace, reporter app.Reporter) (*app.UnmanagedAssetScan, error) {
+	return invokeWorkspace[app.UnmanagedAssetScan](ctx, "scan_unmanaged_assets", workspace, nil, reporter)
+}
+
+func ImportUnmanagedAssets(ctx context.Context, input app.ImportUnmanagedInput, reporter app.Reporter) (*app.ImportUnmanagedResult, error) {
+	return runWorkspace[app.ImportUnmanagedResult](ctx, "import_unmanaged_assets",
+		app.NewWorkspace(input.Plane, input.ProjectRoot), input, reporter)
+}
+
+func ScanGlobalMCPServers(ctx context.Context, workspace app.Workspace, reporter app.Reporter) (*app.GlobalMCPScan, error) {
+	return invokeWorkspace[app.GlobalMCPScan](ctx, "scan_global_mcp_servers", workspace, nil, reporter)
+}
+
+func ImportGlobalMCPServers(ctx context.Context, input app.ImportGlobalMCPInput, reporter app.Reporter) (*app.ImportGlobalMCPResult, error) {
+	return runWorkspace[app.ImportGlobalMCPResult](ctx, "import_global_mcp_servers",
+		app.NewWorkspace(input.Plane, input.ProjectRoot), input, reporter)
+}
+
 func RemoveBundle(ctx context.Context, input app.RemoveBundleInput, reporter app.Reporter) (*app.RemoveBundleResult, error) {
 	return runWorkspace[app.RemoveBundleResult](ctx, "remove_bundle",
 		app.NewWorkspace(input.Plane, input.ProjectRoot), input, reporter)
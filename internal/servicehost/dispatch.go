@@ -45,6 +45,7 @@ func (s *Server) Invoke(ctx context.Context, req *servicev1.InvokeRequest) (*ser
 	}
 	if req.Method == "save_global_settings" {
 		s.presence.setTimeout(loadIdleTimeout())
+		applyRepoSettings()
 	}
 	data, err := json.Marshal(result)
 	if err != nil {
@@ -56,8 +57,8 @@ func (s *Server) Invoke(ctx context.Context, req *servicev1.InvokeRequest) (*ser
 func isProjectMutation(method string) bool {
 	switch method {
 	case "save_enabled_bundles", "prepare_project_config_init", "ensure_local_project_config",
-		"apply_vault_project", "save_project_settings", "ensure_project_vars",
-		"prepare_remote_note_edit", "prepare_remote_ssh_hosts_edit":
+		"apply_vault_project", "save_project_settings", "ensure_project_vars", "switch_vars_profile",
+		"prepare_remote_note_edit", "prepare_remote_ssh_hosts_edit", "unhold_pull":
 		return true
 	default:
 		return false
@@ -88,6 +89,12 @@ func dispatchInvokeWorkspace(ctx context.Context, method string, workspace app.W
 		return app.LoadWorkspaceOverviewOpts(workspace, app.OverviewLoadOpts{IncludeVaultBundles: in.IncludeVaultBundles})
 	case "load_asset_selection":
 		return app.LoadWorkspaceAssetSelection(workspace, reporter)
+	case "load_pull_history":
+		return app.LoadPullHistory(workspace)
+	case "unhold_pull":
+		return app.UnholdWorkspacePull(workspace)
+	case "load_vault_format":
+		return app.LoadVaultFormat(workspace, reporter)
 	case "save_enabled_bundles":
 		var in struct{ EnabledBundles []string }
 		if err := decode(payload, &in); err != nil {
@@ -139,6 +146,19 @@ func dispatchInvokeWorkspace(ctx context.Context, method string, workspace app.W
 		return app.LoadProjectVarsView(projectRoot)
 	case "ensure_project_vars":
 		return app.EnsureProjectVarsFile(projectRoot)
+	case "explain_vars":
+		var in app.ExplainVarsInput
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		in.ProjectRoot = projectRoot
+		return app.ExplainVars(in)
+	case "switch_vars_profile":
+		var in struct{ Profile string }
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		return app.SwitchVarsProfile(projectRoot, in.Profile, reporter)
 	case "list_secret_sync_targets":
 		return app.ListSecretSyncTargets(projectRoot)
 	case "suggest_secret_targets":
@@ -149,6 +169,10 @@ func dispatchInvokeWorkspace(ctx context.Context, method string, workspace app.W
 			return nil, err
 		}
 		return app.ListWorkspaceSecretsMetadata(ctx, workspace, in.IncludeRemote, reporter)
+	case "scan_unmanaged_assets":
+		return app.ScanUnmanagedAssets(workspace, reporter)
+	case "scan_global_mcp_servers":
+		return app.ScanGlobalMCPServers(reporter)
 	case "list_delete_candidates":
 		var in struct{ IncludeRemote bool }
 		if err := decode(payload, &in); err != nil {
@@ -284,10 +308,50 @@ func dispatchOperationWorkspace(ctx context.Context, operation string, workspace
 	switch operation {
 	case "pull":
 		return app.PullWorkspaceAssets(ctx, workspace, "", reporter)
+	case "preview_pull":
+		return app.PreviewPullWorkspaceAssets(ctx, workspace, reporter)
+	case "pull_branch_preview":
+		var in struct {
+			Branch string
+		}
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		return app.PullWorkspaceBranchPreview(ctx, workspace, in.Branch, reporter)
+	case "rollback_pull":
+		var in struct {
+			Commit string
+		}
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		return app.RollbackWorkspacePull(ctx, workspace, in.Commit, reporter)
 	case "push":
-		return app.PushWorkspaceAssets(ctx, workspace, reporter)
+		var in app.PushOptions
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		return app.PushWorkspaceWith(ctx, workspace, in, reporter)
 	case "preview_push":
 		return app.PreviewPushWorkspaceAssets(workspace)
+	case "asset_history":
+		var in app.AssetHistoryQuery
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		return app.LoadAssetHistory(ctx, workspace, in, reporter)
+	case "restore_asset_revision":
+		var in app.AssetHistoryQuery
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		return app.RestoreAssetRevision(ctx, workspace, in, reporter)
+	case "migrate_vault":
+		var in app.MigrateVaultInput
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		return app.MigrateVault(ctx, workspace, in, reporter)
 	case "prepare_repo_gcm_bootstrap":
 		var in struct {
 			RepoURL string
@@ -310,6 +374,22 @@ func dispatchOperationWorkspace(ctx context.Context, operation string, workspace
 		in.ProjectRoot = projectRoot
 		in.Plane = workspace.EffectivePlane()
 		return writer.RemoveBundle(in, reporter)
+	case "import_unmanaged_assets":
+		var in app.ImportUnmanagedInput
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		in.ProjectRoot = projectRoot
+		in.Plane = workspace.EffectivePlane()
+		return writer.ImportUnmanagedAssets(ctx, in, reporter)
+	case "import_global_mcp_servers":
+		var in app.ImportGlobalMCPInput
+		if err := decode(payload, &in); err != nil {
+			return nil, err
+		}
+		in.ProjectRoot = projectRoot
+		in.Plane = workspace.EffectivePlane()
+		return writer.ImportGlobalMCPServers(ctx, in, reporter)
 	case "delete":
 		var in app.DeleteProjectInput
 		if err := decode(payload, &in); err != nil {
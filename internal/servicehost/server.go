@@ -24,6 +24,12 @@ import (
 
 const defaultIdleTimeout = 30 * time.Minute
 
+// 只读快照池：TUI 一次刷新会在同一提交上连开多个只读事务，共享检出避免反复建删 worktree。
+const (
+	readPoolSize = 8
+	readPoolIdle = 5 * time.Minute
+)
+
 type Server struct {
 	servicev1.UnimplementedDecServiceServer
 
@@ -74,6 +80,10 @@ func Run(ctx context.Context, version string) error {
 		return err
 	}
 	idleTimeout := loadIdleTimeout()
+	applyRepoSettings()
+	repo.SetReadPool(readPoolSize, readPoolIdle)
+	defer repo.SetReadPool(0, 0)
+	app.SetDecVersion(version)
 	stopRequested := make(chan struct{}, 1)
 	host := &Server{
 		version:    version,
@@ -144,6 +154,15 @@ func pruneOrphanWorktreesAtStartup() {
 	}
 }
 
+// applyRepoSettings 把 global config 的 git_backend / offline / commit_signing 同步给 repo 包；
+// 取值有误的项回落到默认值并记日志。
+func applyRepoSettings() {
+	for _, warning := range config.ApplyRepoSettings() {
+		diag.StartupLog("%s", warning)
+	}
+	diag.StartupLog("git backend: %s", repo.ActiveBackend())
+}
+
 func loadIdleTimeout() time.Duration {
 	cfg, err := config.LoadGlobalConfig()
 	if err != nil || strings.TrimSpace(cfg.ServerIdleTimeout) == "" {
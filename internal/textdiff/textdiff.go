@@ -0,0 +1,331 @@
+// Package textdiff 生成行级 unified diff，供 pull dry-run、push 预览等只读展示使用；
+// Merge3 在同一套行级编辑脚本上做三方合并，供 push 以拉取版本为基准合入本地改动。
+//
+// 只追求「人能看懂、git apply 风格一致」，不追求最小编辑脚本：超大输入直接退化为整段替换。
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines 是每个 hunk 前后保留的上下文行数，与 git diff 默认一致
+const contextLines = 3
+
+// maxCells 限制 LCS 表的规模（行数乘积），超出时整段替换
+const maxCells = 4_000_000
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified 返回 oldText → newText 的 unified diff；两者相同时返回空串。
+// oldName / newName 写入 ---/+++ 头，新增文件的 oldName 惯例为 /dev/null。
+func Unified(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops) {
+		b.WriteString(h)
+	}
+	return b.String()
+}
+
+// Stats 统计 diff 中新增与删除的行数（不含 ---/+++ 头）
+func Stats(diff string) (added, removed int) {
+	for i, line := range strings.Split(diff, "\n") {
+		switch {
+		case i < 2:
+			// ---/+++ 头
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// splitLines 按行切分并保留换行信息：末行无换行时追加 git 风格的提示行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	last := lines[len(lines)-1]
+	if !strings.HasSuffix(last, "\n") {
+		lines[len(lines)-1] = last + "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
+
+func diffLines(a, b []string) []op {
+	// 去掉公共前后缀，LCS 只算中间部分
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []op
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{opEqual, line})
+	}
+	ops = append(ops, lcsOps(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{opEqual, line})
+	}
+	return ops
+}
+
+func lcsOps(a, b []string) []op {
+	n, m := len(a), len(b)
+	if n*m > maxCells {
+		ops := make([]op, 0, n+m)
+		for _, line := range a {
+			ops = append(ops, op{opDelete, line})
+		}
+		for _, line := range b {
+			ops = append(ops, op{opInsert, line})
+		}
+		return ops
+	}
+
+	// table[i][j] 为 a[i:] 与 b[j:] 的 LCS 长度
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = append(ops, op{opDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, op{opInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, op{opDelete, a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, op{opInsert, b[j]})
+	}
+	return ops
+}
+
+// hunks 把编辑脚本切成带上下文的 hunk 文本
+func hunks(ops []op) []string {
+	var result []string
+	for start := 0; start < len(ops); {
+		// 找下一处改动
+		first := start
+		for first < len(ops) && ops[first].kind == opEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		// 向后延伸，两处改动间隔不超过 2*contextLines 时并入同一 hunk
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].kind != opEqual {
+				last = k
+				continue
+			}
+			if k-last > 2*contextLines {
+				break
+			}
+		}
+		from := max(first-contextLines, 0)
+		to := min(last+contextLines+1, len(ops))
+
+		oldStart, newStart := 1, 1
+		for _, o := range ops[:from] {
+			if o.kind != opInsert {
+				oldStart++
+			}
+			if o.kind != opDelete {
+				newStart++
+			}
+		}
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for _, o := range ops[from:to] {
+			if o.kind != opInsert {
+				oldCount++
+			}
+			if o.kind != opDelete {
+				newCount++
+			}
+			body.WriteByte(byte(o.kind))
+			body.WriteString(o.line)
+		}
+		result = append(result, fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount), body.String()))
+		start = to
+	}
+	return result
+}
+
+// region 表示把 base[start:end) 替换为 lines 的一处改动
+type region struct {
+	start, end int
+	lines      []string
+}
+
+// Merge3 以 base 为共同祖先合并 ours 与 theirs 的行级改动。
+// 两边改动了重叠的行（或在同一位置插入）且结果不同时 ok=false；相邻但不重叠的改动可以合并。
+func Merge3(base, ours, theirs string) (merged string, ok bool) {
+	switch {
+	case ours == theirs, base == theirs:
+		return ours, true
+	case base == ours:
+		return theirs, true
+	}
+	baseLines := rawLines(base)
+	a := changeRegions(diffLines(baseLines, rawLines(ours)))
+	b := changeRegions(diffLines(baseLines, rawLines(theirs)))
+
+	var out strings.Builder
+	pos, i, j := 0, 0, 0
+	for i < len(a) || j < len(b) {
+		start := 0
+		if j >= len(b) || (i < len(a) && a[i].start <= b[j].start) {
+			start = a[i].start
+		} else {
+			start = b[j].start
+		}
+		// 把与当前区间重叠的改动并进来，直到两边都不再扩展
+		end, fromA, fromB := start, i, j
+		for grew := true; grew; {
+			grew = false
+			if i < len(a) && (a[i].start < end || a[i].start == start) {
+				end = max(end, a[i].end)
+				i++
+				grew = true
+			}
+			if j < len(b) && (b[j].start < end || b[j].start == start) {
+				end = max(end, b[j].end)
+				j++
+				grew = true
+			}
+		}
+
+		writeLines(&out, baseLines[pos:start])
+		oursPart := applyRegions(baseLines, a[fromA:i], start, end)
+		theirsPart := applyRegions(baseLines, b[fromB:j], start, end)
+		switch {
+		case fromB == j:
+			writeLines(&out, oursPart)
+		case fromA == i:
+			writeLines(&out, theirsPart)
+		case strings.Join(oursPart, "") == strings.Join(theirsPart, ""):
+			writeLines(&out, oursPart)
+		default:
+			return "", false
+		}
+		pos = end
+	}
+	writeLines(&out, baseLines[pos:])
+	return out.String(), true
+}
+
+// rawLines 按行切分且保留换行符，拼接后与原文一致
+func rawLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// changeRegions 把编辑脚本中连续的增删归并为以 base 行号表示的改动区间
+func changeRegions(ops []op) []region {
+	var regions []region
+	baseIdx := 0
+	for k := 0; k < len(ops); {
+		if ops[k].kind == opEqual {
+			baseIdx++
+			k++
+			continue
+		}
+		r := region{start: baseIdx}
+		for ; k < len(ops) && ops[k].kind != opEqual; k++ {
+			if ops[k].kind == opDelete {
+				baseIdx++
+			} else {
+				r.lines = append(r.lines, ops[k].line)
+			}
+		}
+		r.end = baseIdx
+		regions = append(regions, r)
+	}
+	return regions
+}
+
+// applyRegions 返回 base[start:end) 应用 regions 之后的内容
+func applyRegions(base []string, regions []region, start, end int) []string {
+	var out []string
+	cur := start
+	for _, r := range regions {
+		out = append(out, base[cur:r.start]...)
+		out = append(out, r.lines...)
+		cur = r.end
+	}
+	return append(out, base[cur:end]...)
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
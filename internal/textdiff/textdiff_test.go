@@ -0,0 +1,60 @@
+package textdiff
+
+import "testing"
+
+func TestUnifiedProducesHunksWithContext(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	newText := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	got := Unified("a/x.md", "b/x.md", oldText, newText)
+	want := "--- a/x.md\n+++ b/x.md\n" +
+		"@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n" +
+		"@@ -11,3 +11,4 @@\n k\n l\n m\n+n\n"
+	if got != want {
+		t.Fatalf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+	if added, removed := Stats(got); added != 2 || removed != 1 {
+		t.Fatalf("Stats() = +%d -%d", added, removed)
+	}
+}
+
+func TestUnifiedNewFileAndMissingNewline(t *testing.T) {
+	if got := Unified("x", "x", "same\n", "same\n"); got != "" {
+		t.Fatalf("相同内容应返回空串, got %q", got)
+	}
+	got := Unified("/dev/null", "b/x", "", "one\ntwo")
+	want := "--- /dev/null\n+++ b/x\n@@ -0,0 +1,2 @@\n+one\n+two\n\\ No newline at end of file\n"
+	if got != want {
+		t.Fatalf("Unified() = %q, want %q", got, want)
+	}
+}
+
+func TestMerge3CombinesNonOverlappingEdits(t *testing.T) {
+	base := "a\nb\nc\nd\ne\nf\n"
+	ours := "a\nB\nc\nd\ne\nf\n"
+	theirs := "a\nb\nc\nd\nE\nf\ng\n"
+	got, ok := Merge3(base, ours, theirs)
+	if !ok || got != "a\nB\nc\nd\nE\nf\ng\n" {
+		t.Fatalf("Merge3() = %q, %v", got, ok)
+	}
+	// 两边做了相同的修改
+	if got, ok := Merge3(base, ours, ours); !ok || got != ours {
+		t.Fatalf("相同修改应直接合并: %q, %v", got, ok)
+	}
+	// 紧邻但不重叠
+	if got, ok := Merge3("a\nb\n", "A\nb\n", "a\nB\n"); !ok || got != "A\nB\n" {
+		t.Fatalf("相邻修改应合并: %q, %v", got, ok)
+	}
+}
+
+func TestMerge3ReportsOverlappingEdits(t *testing.T) {
+	base := "a\nb\nc\n"
+	if _, ok := Merge3(base, "a\nours\nc\n", "a\ntheirs\nc\n"); ok {
+		t.Fatal("同一行两边改得不同应冲突")
+	}
+	if _, ok := Merge3(base, "a\nb\nc\nours\n", "a\nb\nc\ntheirs\n"); ok {
+		t.Fatal("同一位置插入不同内容应冲突")
+	}
+	if _, ok := Merge3(base, "a\nc\n", "a\nB\nc\n"); ok {
+		t.Fatal("一边删除一边修改同一行应冲突")
+	}
+}
@@ -88,6 +88,10 @@ func (m model) routeDeletePageKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
 	if m.focus == focusSidebar && msg.String() != "r" {
 		return m, nil, false
 	}
+	if m.remoteHistory.open {
+		model, cmd := m.handleRemoteHistoryKey(msg)
+		return model, cmd, true
+	}
 	if m.deleteFilterInput {
 		model, cmd := m.handleDeleteFilterInput(msg)
 		return model, cmd, true
@@ -226,6 +230,8 @@ func (m model) handleDeletePageKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "e":
 		cmd := m.startRemoteEditAtCursor()
 		return m, cmd
+	case "H":
+		return m, m.startRemoteHistoryAtCursor()
 	case "j", "down":
 		m.syncTreeViewports()
 		m.deleteTree.MoveCursor(1)
@@ -391,7 +397,7 @@ func (m model) remoteHeadLines() []string {
 	if m.deleteLoad.busy() {
 		lines = append(lines, shellWarnStyle.Render("刷新中…"))
 	} else {
-		lines = append(lines, shellMutedStyle.Render("a 全选 · A 全不选 · n 登记到光标 folder · N 登记到新 folder · / 筛选"))
+		lines = append(lines, shellMutedStyle.Render("a 全选 · A 全不选 · n 登记到光标 folder · N 登记到新 folder · H 历史 · / 筛选"))
 	}
 	if m.deleteFilterInput {
 		lines = append(lines, shellMutedStyle.Render("筛选输入中：Enter 应用 · Esc 退出"))
@@ -439,6 +445,9 @@ func (m model) renderDeletePage(width, height int) string {
 	if m.addSecretStage != "" {
 		return wrapLines(width, m.addSecretBlockLines())
 	}
+	if m.remoteHistory.open {
+		return m.renderRemoteHistory(width, height)
+	}
 	if m.deleteStage == "summary" || m.deleteStage == "confirm" || m.deleteStage == "typed" {
 		return m.renderDeleteConfirmPage(width)
 	}
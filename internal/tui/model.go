@@ -13,7 +13,9 @@ import (
 	"github.com/shichao402/Dec/internal/diag"
 	"github.com/shichao402/Dec/internal/editor"
 	"github.com/shichao402/Dec/internal/serviceapi"
+	"github.com/shichao402/Dec/internal/types"
 	"github.com/shichao402/Dec/internal/update"
+	"github.com/shichao402/Dec/internal/vars"
 )
 
 type overviewLoadedMsg struct {
@@ -33,6 +35,7 @@ type vaultInferenceLoadedMsg struct {
 type overviewVaultEnrichedMsg struct {
 	bundles              []app.BundleOverview
 	availableBundleCount int
+	pendingBranches      []string
 	err                  error
 }
 
@@ -109,6 +112,17 @@ type projectVarsLoadedMsg struct {
 	solo    bool // true: 独立 vars 重载；false: shell refresh 分片
 }
 
+type varsExplainedMsg struct {
+	result *app.VarsExplanation
+	err    error
+	pos    int
+}
+
+type varsProfileSwitchedMsg struct {
+	result *app.SwitchVarsProfileResult
+	err    error
+}
+
 type projectVarsEditedMsg struct {
 	err error
 }
@@ -200,8 +214,12 @@ var runPullOperation = func(ctx context.Context, projectRoot string, reporter ap
 	return serviceapi.PullProjectAssets(ctx, projectRoot, reporter)
 }
 
-var runPushOperation = func(ctx context.Context, workspace app.Workspace, reporter app.Reporter) (*app.PushProjectAssetsResult, error) {
-	return serviceapi.PushWorkspaceAssets(ctx, workspace, reporter)
+var runBranchPreviewOperation = func(ctx context.Context, workspace app.Workspace, branch string, reporter app.Reporter) (*app.PullProjectAssetsResult, error) {
+	return serviceapi.PullWorkspaceBranchPreview(ctx, workspace, branch, reporter)
+}
+
+var runPushOperation = func(ctx context.Context, workspace app.Workspace, opts app.PushOptions, reporter app.Reporter) (*app.PushProjectAssetsResult, error) {
+	return serviceapi.PushWorkspaceWith(ctx, workspace, opts, reporter)
 }
 
 var runRemoveOperation = func(input app.RemoveBundleInput, reporter app.Reporter) (*app.RemoveBundleResult, error) {
@@ -264,6 +282,14 @@ var loadProjectVarsViewOperation = func(projectRoot string) (*app.ProjectVarsVie
 	return serviceapi.LoadProjectVarsView(projectRoot)
 }
 
+var explainVarsOperation = func(input app.ExplainVarsInput) (*app.VarsExplanation, error) {
+	return serviceapi.ExplainVars(input)
+}
+
+var switchVarsProfileOperation = func(projectRoot, profile string, reporter app.Reporter) (*app.SwitchVarsProfileResult, error) {
+	return serviceapi.SwitchVarsProfile(projectRoot, profile, reporter)
+}
+
 var ensureProjectVarsFileOperation = func(projectRoot string) (*app.EnsureProjectVarsFileResult, error) {
 	return serviceapi.EnsureProjectVarsFile(projectRoot)
 }
@@ -339,12 +365,17 @@ type model struct {
 	lastInitErr                 error
 	projectVars                 *app.ProjectVarsView
 	projectVarsErr              error
+	varsExplainPos              int
+	varsExplain                 *app.VarsExplanation
+	varsExplainErr              error
+	switchingVarsProfile        bool
 	lastEditErr                 error
 	runningPull                 bool
 	runProgress                 *app.Progress
 	runEvents                   []string
 	runPinLine                  string
 	runShowHelp                 bool
+	previewBranchIdx            int // Run 页 b 预览的评审分支在 overview.PendingBranches 中的下标
 	runResult                   *app.PullProjectAssetsResult
 	pushResult                  *app.PushProjectAssetsResult
 	runErr                      error
@@ -366,6 +397,11 @@ type model struct {
 	pushStage                   string // "", "loading", "summary", "confirm", "running"
 	pushPreview                 *app.PushProjectAssetsPreview
 	pushPreviewErr              error
+	pushCursor                  int             // Push 摘要页逐文件列表的光标
+	pushExcluded                map[string]bool // Push 摘要页取消勾选的 vault 相对路径
+	pushDiffOpen                bool
+	pushMessage                 string // Push 确认页填写的提交说明，空串表示自动生成
+	pushMessageEditing          bool
 	pushPreviewLoad             asyncLoad
 	updateStage                 string // "", "checking", "result", "confirm", "running", "done"
 	updateResult                *update.CheckResult
@@ -389,14 +425,16 @@ type model struct {
 	remoteNoteEdit              *app.RemoteNoteEditSession
 	remoteSSHEdit               *app.RemoteSSHHostsEditSession
 	remoteRegisterSess          *app.RemoteRegisterSession
-	remoteRegisterPending       bool       // n 登记：Esc 可退出等待，迟到结果只记日志
-	shellRefresh                asyncBatch // overview/assets/settings/projectSettings/projectVars
-	projectVarsLoad             asyncLoad  // 独立重载 .dec/vars.yaml
-	globalVarsLoad              asyncLoad  // 独立重载 ~/.dec/local/vars.yaml
-	builtinAssetsLoad           asyncLoad  // 同步内置 IDE assets
-	localProjectLoad            asyncLoad  // 生成本地 project 配置
-	vaultApplyLoad              asyncLoad  // 应用推断的 vault project
-	projectInitLoad             asyncLoad  // Project 页扫描仓库
+	remoteRegisterPending       bool               // n 登记：Esc 可退出等待，迟到结果只记日志
+	remoteHistory               remoteHistoryState // H 打开的 vault 历史视图
+	pullHistory                 pullHistoryState   // Run 页 H 打开的拉取记录
+	shellRefresh                asyncBatch         // overview/assets/settings/projectSettings/projectVars
+	projectVarsLoad             asyncLoad          // 独立重载 .dec/vars.yaml
+	globalVarsLoad              asyncLoad          // 独立重载 ~/.dec/local/vars.yaml
+	builtinAssetsLoad           asyncLoad          // 同步内置 IDE assets
+	localProjectLoad            asyncLoad          // 生成本地 project 配置
+	vaultApplyLoad              asyncLoad          // 应用推断的 vault project
+	projectInitLoad             asyncLoad          // Project 页扫描仓库
 	// configInitMode 为 true 时表示由 dec config init 拉起：聚焦 Assets/bundle 视图，保存后退出。
 	configInitMode bool
 	// vaultInference Home 页待确认的 vault project 推断（来自目录名匹配）。
@@ -677,6 +715,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.overview.Bundles = msg.bundles
 		m.overview.AvailableBundleCount = msg.availableBundleCount
+		m.overview.PendingBranches = msg.pendingBranches
+		if m.previewBranchIdx >= len(msg.pendingBranches) {
+			m.previewBranchIdx = 0
+		}
 		diag.StartupLog("overviewVaultEnrichedMsg applied available=%d", msg.availableBundleCount)
 		m.pushLog(fmt.Sprintf("Overview vault bundles ready: %d available", msg.availableBundleCount))
 		return m, nil
@@ -953,6 +995,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.projectVars = msg.view
 		m.projectVarsErr = msg.err
+		if msg.view == nil || m.varsExplainPos > len(msg.view.PlaceholderAssets) {
+			m.varsExplainPos = 0
+			m.varsExplain = nil
+			m.varsExplainErr = nil
+		}
 		if msg.err != nil {
 			m.pushLog("Project vars load failed: " + msg.err.Error())
 			return m, nil
@@ -962,6 +1009,27 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pushLog(fmt.Sprintf("Project vars loaded: %d used / %d missing", len(msg.view.UsedPlaceholders), len(missing)))
 		}
 		return m, nil
+	case varsExplainedMsg:
+		if msg.pos != m.varsExplainPos {
+			return m, nil
+		}
+		m.varsExplain = msg.result
+		m.varsExplainErr = msg.err
+		return m, nil
+	case varsProfileSwitchedMsg:
+		m.switchingVarsProfile = false
+		if msg.err != nil {
+			m.pushLog("Vars profile switch failed: " + msg.err.Error())
+			return m, nil
+		}
+		if msg.result != nil {
+			m.pushLog(fmt.Sprintf("Vars profile: %s → %s · re-rendered %d assets", fallbackValue(msg.result.Previous, "<none>"), fallbackValue(msg.result.Active, "<none>"), len(msg.result.Rerendered)))
+			for _, w := range msg.result.Warnings {
+				m.pushLog("⚠️  " + w)
+			}
+		}
+		gen := m.projectVarsLoad.beginGen()
+		return m, loadProjectVarsCmd(m.projectRoot, gen, true)
 	case projectVarsEditedMsg:
 		m.lastEditErr = msg.err
 		if msg.err != nil {
@@ -997,6 +1065,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pushLog(fmt.Sprintf("Global vars loaded: %d keys, ready=%v", len(msg.view.Vars), msg.view.VarsFileReady))
 		}
 		return m, nil
+	case remoteHistoryLoadedMsg:
+		m.handleRemoteHistoryLoaded(msg)
+		return m, nil
+	case pullHistoryLoadedMsg:
+		m.handlePullHistoryLoaded(msg)
+		return m, nil
+	case pullUnheldMsg:
+		return m, m.handlePullUnheld(msg)
+	case remoteHistoryRestoredMsg:
+		return m, m.handleRemoteHistoryRestored(msg)
 	case remoteEditPreparedMsg:
 		return m, m.handleRemoteEditPrepared(msg)
 	case remoteEditEditorClosedMsg:
@@ -1018,6 +1096,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.pushPreview = msg.preview
 		m.pushPreviewErr = msg.err
+		m.resetPushSelection()
 		m.pushStage = "summary"
 		m.pushResult = nil
 		m.runErr = nil
@@ -1197,6 +1276,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.isRunPage() && m.pushStage != "" && !m.runningPull {
 			return m.handlePushStageKey(msg)
 		}
+		if m.isRunPage() && m.pullHistory.open && !m.runningPull {
+			return m.handlePullHistoryKey(msg)
+		}
 		if m.isRunPage() && m.removeStage != "" && !m.runningRemove {
 			return m.handleRemoveStageKey(msg)
 		}
@@ -1382,6 +1464,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, m.beginAddSecret()
 			}
 			return m, nil
+		case "x":
+			if m.isProjectPage() && m.projectVars != nil && len(m.projectVars.PlaceholderAssets) > 0 {
+				return m, m.cycleVarsExplain()
+			}
+			return m, nil
+		case "v":
+			if m.isProjectPage() && m.projectVars != nil && len(m.projectVars.Profiles) > 0 && !m.switchingVarsProfile {
+				return m, m.cycleVarsProfile()
+			}
+			return m, nil
 		case "n":
 			if m.isRemotePage() {
 				return m, m.beginRemoteRegisterAtCursor()
@@ -1408,11 +1500,34 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, m.startPullRun()
 			}
 			return m, nil
+		case "b":
+			if m.isRunPage() && !m.runningPull && !m.runningRemove && m.pushStage == "" && !m.updatingBinary && m.updateStage == "" {
+				if branch := m.selectedPreviewBranch(); branch != "" {
+					return m, m.startBranchPreviewRun(branch)
+				}
+				m.pushLog("没有待合并的评审分支可预览")
+			}
+			return m, nil
+		case "B":
+			if m.isRunPage() && m.overview != nil && len(m.overview.PendingBranches) > 1 {
+				m.previewBranchIdx = (m.previewBranchIdx + 1) % len(m.overview.PendingBranches)
+			}
+			return m, nil
 		case "P":
 			if m.isRunPage() && !m.runningPull && !m.runningRemove && m.pushStage == "" && !m.updatingBinary && m.updateStage == "" {
 				return m, m.beginPushConfirmation()
 			}
 			return m, nil
+		case "H":
+			if m.isRunPage() && !m.runningPull && !m.runningRemove && m.pushStage == "" && !m.updatingBinary && m.updateStage == "" {
+				return m, m.startPullHistory()
+			}
+			return m, nil
+		case "U":
+			if m.isRunPage() && !m.runningPull && m.pullHeld() {
+				return m, unholdPullCmd(m.workspace())
+			}
+			return m, nil
 		case "u":
 			if m.isRunPage() && !m.runningPull && !m.runningRemove && m.pushStage == "" && !m.updatingBinary && m.removeStage == "" {
 				return m, m.startUpdateCheck()
@@ -1602,6 +1717,7 @@ func enrichWorkspaceOverviewVaultCmd(workspace app.Workspace) tea.Cmd {
 		return overviewVaultEnrichedMsg{
 			bundles:              overview.Bundles,
 			availableBundleCount: overview.AvailableBundleCount,
+			pendingBranches:      overview.PendingBranches,
 		}
 	}
 }
@@ -1752,6 +1868,55 @@ func loadProjectVarsCmd(projectRoot string, loadGen uint64, solo bool) tea.Cmd {
 	}
 }
 
+// cycleVarsExplain 把变量溯源切到下一个含占位符的资产；转过最后一个后收起。
+// varsExplainPos 为 0 表示未展开，n 表示 projectVars.PlaceholderAssets[n-1]。
+func (m *model) cycleVarsExplain() tea.Cmd {
+	assets := m.projectVars.PlaceholderAssets
+	m.varsExplainPos = (m.varsExplainPos + 1) % (len(assets) + 1)
+	m.varsExplain = nil
+	m.varsExplainErr = nil
+	if m.varsExplainPos == 0 {
+		return nil
+	}
+	asset := assets[m.varsExplainPos-1]
+	return explainVarsCmd(app.ExplainVarsInput{
+		ProjectRoot: m.projectRoot,
+		AssetType:   asset.Type,
+		AssetName:   asset.Name,
+		Bundle:      asset.Vault,
+	}, m.varsExplainPos)
+}
+
+// cycleVarsProfile 按 "无 → profiles 字典序 → 无" 切换 active_profile。
+// 切换只从本地 .dec/cache 重新渲染受影响的资产，不触发 pull。
+func (m *model) cycleVarsProfile() tea.Cmd {
+	profiles := m.projectVars.Profiles
+	next := profiles[0]
+	for i, name := range profiles {
+		if name == m.projectVars.ActiveProfile {
+			next = ""
+			if i+1 < len(profiles) {
+				next = profiles[i+1]
+			}
+			break
+		}
+	}
+	m.switchingVarsProfile = true
+	m.pushLog("Switching vars profile: " + fallbackValue(next, "<none>"))
+	projectRoot := m.projectRoot
+	return func() tea.Msg {
+		result, err := switchVarsProfileOperation(projectRoot, next, nil)
+		return varsProfileSwitchedMsg{result: result, err: err}
+	}
+}
+
+func explainVarsCmd(input app.ExplainVarsInput, pos int) tea.Cmd {
+	return func() tea.Msg {
+		result, err := explainVarsOperation(input)
+		return varsExplainedMsg{result: result, err: err, pos: pos}
+	}
+}
+
 // openProjectVarsEditorCmd 挂起 TUI 用 tea.ExecProcess 拉起外部编辑器编辑 .dec/vars.yaml。
 // 文件不存在时先落模板（不覆盖已有内容）。编辑器退出后推送 projectVarsEditedMsg 触发刷新。
 func openProjectVarsEditorCmd(projectRoot, editorCmd string) tea.Cmd {
@@ -1831,10 +1996,23 @@ func startWorkspacePullRunCmd(ctx context.Context, workspace app.Workspace, stre
 	}
 }
 
-func startPushRunCmd(ctx context.Context, workspace app.Workspace, stream chan<- tea.Msg) tea.Cmd {
+func startBranchPreviewRunCmd(ctx context.Context, workspace app.Workspace, branch string, stream chan<- tea.Msg) tea.Cmd {
 	return func() tea.Msg {
 		go func() {
-			result, err := runPushOperation(ctx, workspace, app.ReporterFunc(func(event app.OperationEvent) {
+			result, err := runBranchPreviewOperation(ctx, workspace, branch, app.ReporterFunc(func(event app.OperationEvent) {
+				stream <- runEventMsg{event: event}
+			}))
+			stream <- runCompletedMsg{result: result, err: err}
+			close(stream)
+		}()
+		return nil
+	}
+}
+
+func startPushRunCmd(ctx context.Context, workspace app.Workspace, opts app.PushOptions, stream chan<- tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		go func() {
+			result, err := runPushOperation(ctx, workspace, opts, app.ReporterFunc(func(event app.OperationEvent) {
 				stream <- runEventMsg{event: event}
 			}))
 			stream <- runCompletedMsg{pushResult: result, err: err}
@@ -2108,6 +2286,36 @@ func (m model) handleSettingsIdleTimeoutInput(msg tea.KeyMsg) (tea.Model, tea.Cm
 }
 
 func (m *model) startPullRun() tea.Cmd {
+	stream := m.beginPullRun()
+	if stream == nil {
+		return nil
+	}
+	m.pushLog("Run page started pull")
+	return tea.Batch(startWorkspacePullRunCmd(m.runCtx, m.workspace(), stream), waitRunMsg(stream))
+}
+
+// startBranchPreviewRun 按评审分支的内容拉取安装，合并前试用；再按 p 回到主线版本。
+func (m *model) startBranchPreviewRun(branch string) tea.Cmd {
+	stream := m.beginPullRun()
+	if stream == nil {
+		return nil
+	}
+	m.pushLog("Run page started pull preview of " + branch)
+	return tea.Batch(startBranchPreviewRunCmd(m.runCtx, m.workspace(), branch, stream), waitRunMsg(stream))
+}
+
+// selectedPreviewBranch 返回 Run 页选中的待合并评审分支，没有时为空串。
+func (m model) selectedPreviewBranch() string {
+	if m.overview == nil || len(m.overview.PendingBranches) == 0 {
+		return ""
+	}
+	if m.previewBranchIdx < 0 || m.previewBranchIdx >= len(m.overview.PendingBranches) {
+		return m.overview.PendingBranches[0]
+	}
+	return m.overview.PendingBranches[m.previewBranchIdx]
+}
+
+func (m *model) beginPullRun() chan tea.Msg {
 	if m.observedOperationID != "" {
 		m.pushLog("当前 project 已有操作进行中，不能重复 pull/push")
 		return nil
@@ -2125,8 +2333,7 @@ func (m *model) startPullRun() tea.Cmd {
 	m.runStream = stream
 	m.runCtx = ctx
 	m.runCancel = cancel
-	m.pushLog("Run page started pull")
-	return tea.Batch(startWorkspacePullRunCmd(ctx, m.workspace(), stream), waitRunMsg(stream))
+	return stream
 }
 
 func (m *model) beginPushConfirmation() tea.Cmd {
@@ -2168,17 +2375,27 @@ func (m model) handlePushStageKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "summary":
 		return m.handlePushSummaryKey(msg)
 	case "confirm":
+		if m.pushMessageEditing {
+			return m.handlePushMessageInput(msg)
+		}
 		return m.handlePushConfirmKey(msg)
 	}
 	return m, nil
 }
 
 func (m model) handlePushSummaryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if updated, handled := m.handlePushSelectKey(msg); handled {
+		return updated, nil
+	}
 	switch msg.String() {
 	case "y", "enter":
 		if m.pushPreviewErr != nil {
 			return m, nil
 		}
+		if len(m.pushExcluded) > 0 && m.pushSelectedCount() == 0 {
+			m.pushLog("未勾选任何 Dec 文件，按空格勾选后再继续")
+			return m, nil
+		}
 		m.pushStage = "confirm"
 		m.pushLog("Push 进入最终确认")
 		return m, nil
@@ -2195,10 +2412,15 @@ func (m model) handlePushSummaryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m model) handlePushConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "y":
+		opts := app.PushOptions{Selection: m.pushSelection(), Message: strings.TrimSpace(m.pushMessage)}
 		m.pushStage = "running"
 		m.pushPreview = nil
 		m.pushPreviewErr = nil
-		return m, m.startPushRun()
+		m.resetPushSelection()
+		return m, m.startPushRun(opts)
+	case "m":
+		m.pushMessageEditing = true
+		return m, nil
 	case "n", "esc":
 		m.pushStage = "summary"
 		m.pushLog("Push 最终确认已取消，返回摘要")
@@ -2207,7 +2429,7 @@ func (m model) handlePushConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m *model) startPushRun() tea.Cmd {
+func (m *model) startPushRun(opts app.PushOptions) tea.Cmd {
 	stream := make(chan tea.Msg, 64)
 	ctx, cancel := context.WithCancel(context.Background())
 	m.runningPull = true
@@ -2222,7 +2444,7 @@ func (m *model) startPushRun() tea.Cmd {
 	m.runCtx = ctx
 	m.runCancel = cancel
 	m.pushLog("Run page started push")
-	return tea.Batch(startPushRunCmd(ctx, m.workspace(), stream), waitRunMsg(stream))
+	return tea.Batch(startPushRunCmd(ctx, m.workspace(), opts, stream), waitRunMsg(stream))
 }
 
 func (m *model) beginRemoveSelection() {
@@ -2607,12 +2829,44 @@ func (m model) renderHomePage(width int) string {
 		fmt.Sprintf("Bundle: 可选 %d 个 · 已启用 %d 个", countOverviewAvailableBundles(m.overview), countOverviewEnabledBundles(m.overview)),
 		fmt.Sprintf("IDE: %s · 编辑器: %s", fallbackValue(strings.Join(m.overview.IDEs, ", "), "<none>"), fallbackValue(m.overview.Editor, "未配置")),
 	)
+	if branch := formatVaultBranchDisplay(m.overview); branch != "" {
+		lines = append(lines, "Vault 分支: "+branch)
+		if pulled := m.overview.PulledBranch; pulled != "" && pulled != m.overview.VaultBranch {
+			lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("上次拉取自 %s，到 Run 页重新拉取后切换到 %s", pulled, m.overview.VaultBranch)))
+		}
+	}
+	lines = append(lines, formatOfflineStatus(m.overview)...)
+	lines = append(lines, formatVaultFormat(m.overview)...)
+	if line, warn := formatPulledSignature(m.overview); line != "" {
+		if warn {
+			line = shellWarnStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if m.overview.PullHeld {
+		lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("已固定在 %s（回滚），不提示远端更新；Run 页 U 解除固定", shortCommitLabel(m.overview.PulledCommit))))
+	}
+	if pending := m.overview.PendingBranches; len(pending) > 0 {
+		lines = append(lines, fmt.Sprintf("待合并评审分支: %d 个（Run 页 b 预览拉取）", len(pending)))
+		for i, branch := range pending {
+			if i == homePendingBranchLimit {
+				lines = append(lines, shellMutedStyle.Render(fmt.Sprintf("  … 另有 %d 个", len(pending)-i)))
+				break
+			}
+			lines = append(lines, shellMutedStyle.Render("  · "+branch))
+		}
+	} else if m.overview.PushPolicy == types.PushPolicyBranch {
+		lines = append(lines, shellMutedStyle.Render("推送方式: 评审分支（无待合并分支）"))
+	}
 	if warn := formatWarnings(m.overview.IDEWarnings); !strings.HasSuffix(warn, "无") {
 		lines = append(lines, warn)
 	}
 	return wrapLines(width, lines)
 }
 
+// homePendingBranchLimit 是 Home 页最多逐条列出的评审分支数。
+const homePendingBranchLimit = 5
+
 func (m model) renderBundlesPage(width, height int) string {
 	if m.assetsErr != nil {
 		return shellWarnStyle.Render("无法加载 bundle 选择") + "\n\n" + m.assetsErr.Error()
@@ -2833,6 +3087,9 @@ func (m model) renderProjectVarsBlock() string {
 	lines = append(lines, fileLine)
 	lines = append(lines, shellMutedStyle.Render(fmt.Sprintf("编辑器: %s · e 打开外部编辑器", fallbackValue(view.EditorCommand, "vim"))))
 	lines = append(lines, shellMutedStyle.Render("A 登记 secret（相对 .secrets 同步根）"))
+	if len(view.Profiles) > 0 {
+		lines = append(lines, fmt.Sprintf("Profile: %s", fallbackValue(view.ActiveProfile, "<无>"))+shellMutedStyle.Render(fmt.Sprintf(" · v 切换（%s）", strings.Join(view.Profiles, " / "))))
+	}
 
 	for _, w := range view.Warnings {
 		lines = append(lines, shellWarnStyle.Render(w))
@@ -2845,6 +3102,7 @@ func (m model) renderProjectVarsBlock() string {
 		if view.CacheExists {
 			lines = append(lines, shellMutedStyle.Render("当前资产中未检测到 {{VAR_NAME}} 占位符。"))
 		}
+		lines = append(lines, projectBuiltinVarLines(view)...)
 		return strings.Join(lines, "\n")
 	}
 
@@ -2858,12 +3116,27 @@ func (m model) renderProjectVarsBlock() string {
 		status := view.ResolvedVars[name]
 		var row string
 		switch status.Source {
+		case app.PlaceholderSourceProfile:
+			row = fmt.Sprintf("  %s = %s  (profile: %s)", name, truncateVarValue(status.Value), status.Origin)
+			row = shellEnabledRow.Render(row)
 		case app.PlaceholderSourceProject:
 			row = fmt.Sprintf("  %s = %s  (project)", name, truncateVarValue(status.Value))
 			row = shellEnabledRow.Render(row)
 		case app.PlaceholderSourceGlobal:
 			row = fmt.Sprintf("  %s = %s  (global)", name, truncateVarValue(status.Value))
 			row = shellMutedStyle.Render(row)
+		case app.PlaceholderSourceVaultProject:
+			row = fmt.Sprintf("  %s = %s  (vault project)", name, truncateVarValue(status.Value))
+			row = shellMutedStyle.Render(row)
+		case app.PlaceholderSourceVaultBundle:
+			row = fmt.Sprintf("  %s = %s  (vault bundle: %s)", name, truncateVarValue(status.Value), status.Origin)
+			row = shellMutedStyle.Render(row)
+		case app.PlaceholderSourceDefault:
+			row = fmt.Sprintf("  %s = %s  (default)", name, truncateVarValue(status.Value))
+			row = shellMutedStyle.Render(row)
+		case app.PlaceholderSourceBuiltin:
+			row = fmt.Sprintf("  %s = %s  (builtin)", name, truncateVarValue(fallbackValue(status.Value, "按输出计算")))
+			row = shellMutedStyle.Render(row)
 		default:
 			row = shellWarnStyle.Render(fmt.Sprintf("  %s = <缺失>  (missing)", name))
 		}
@@ -2872,10 +3145,65 @@ func (m model) renderProjectVarsBlock() string {
 	if len(view.UsedPlaceholders) > maxPlaceholders {
 		lines = append(lines, shellMutedStyle.Render(fmt.Sprintf("  … 另有 %d 个占位符", len(view.UsedPlaceholders)-maxPlaceholders)))
 	}
+	lines = append(lines, m.varsExplainLines()...)
+	lines = append(lines, projectBuiltinVarLines(view)...)
 
 	return strings.Join(lines, "\n")
 }
 
+// varsExplainLines 渲染当前选中资产的变量溯源：生效值、来源层与文件，以及被遮蔽的定义。
+func (m model) varsExplainLines() []string {
+	assets := m.projectVars.PlaceholderAssets
+	if len(assets) == 0 {
+		return nil
+	}
+	if m.varsExplainPos == 0 || m.varsExplainPos > len(assets) {
+		return []string{shellMutedStyle.Render(fmt.Sprintf("x 逐个资产查看变量来源（%d 个资产含占位符）", len(assets)))}
+	}
+	asset := assets[m.varsExplainPos-1]
+	lines := []string{shellTitleStyle.Render(fmt.Sprintf("变量来源 %d/%d · %s/%s (bundle: %s)", m.varsExplainPos, len(assets), asset.Type, asset.Name, asset.Vault))}
+	switch {
+	case m.varsExplainErr != nil:
+		return append(lines, shellWarnStyle.Render("解释失败: "+m.varsExplainErr.Error()))
+	case m.varsExplain == nil:
+		return append(lines, shellMutedStyle.Render("Loading..."))
+	}
+	for _, w := range m.varsExplain.Warnings {
+		lines = append(lines, shellWarnStyle.Render(w))
+	}
+	for _, explanation := range m.varsExplain.Vars {
+		if explanation.Winner == nil {
+			lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("  %s = <缺失>", explanation.Name)))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s = %s  ← %s", explanation.Name, truncateVarValue(explanation.Winner.Value), varsCandidateOrigin(*explanation.Winner)))
+		for _, shadowed := range explanation.Shadowed {
+			lines = append(lines, shellMutedStyle.Render(fmt.Sprintf("      遮蔽 %s = %s", varsCandidateOrigin(shadowed), truncateVarValue(shadowed.Value))))
+		}
+	}
+	return append(lines, shellMutedStyle.Render("x 下一个资产"))
+}
+
+// varsCandidateOrigin 把来源层与文件拼成一段简短说明。
+func varsCandidateOrigin(candidate vars.Candidate) string {
+	if candidate.File == "" {
+		return candidate.Source
+	}
+	return fmt.Sprintf("%s (%s)", candidate.Source, compactPath(candidate.File, 40))
+}
+
+// projectBuiltinVarLines 列出全部内置变量；用户定义同名变量时会被覆盖，这里只展示计算值。
+func projectBuiltinVarLines(view *app.ProjectVarsView) []string {
+	if len(view.BuiltinVars) == 0 {
+		return nil
+	}
+	lines := []string{shellMutedStyle.Render("内置变量（最低优先级，可在 vars.yaml 覆盖）:")}
+	for _, status := range view.BuiltinVars {
+		lines = append(lines, shellMutedStyle.Render(fmt.Sprintf("  %s = %s", status.Name, truncateVarValue(fallbackValue(status.Value, "<不可用>")))))
+	}
+	return lines
+}
+
 // truncateVarValue 把过长的变量值截断显示，避免一行撑破区块。
 func truncateVarValue(v string) string {
 	const maxW = 40
@@ -2906,6 +3234,9 @@ func (m model) renderRunPage(width int) string {
 	if m.removeStage == "select" || m.removeStage == "confirm" {
 		return m.renderRunRemovePage(width)
 	}
+	if m.pullHistory.open {
+		return m.renderRunPullHistory(width)
+	}
 
 	sections := []string{
 		m.renderRunHeader(),
@@ -2976,8 +3307,10 @@ func (m model) renderRunActionBar() string {
 		return shellMutedStyle.Render("Esc 取消 pull  ·  ? 帮助")
 	case m.runningRemove, m.updatingBinary:
 		return shellMutedStyle.Render("? 帮助")
+	case m.pullHeld():
+		return shellMutedStyle.Render("p Pull（固定版本）  ·  P Push  ·  H 历史  ·  U 解除固定  ·  u Update  ·  ? 帮助")
 	default:
-		return shellMutedStyle.Render("p Pull  ·  P Push  ·  u Update  ·  ? 帮助")
+		return shellMutedStyle.Render("p Pull  ·  P Push  ·  H 历史  ·  u Update  ·  ? 帮助")
 	}
 }
 
@@ -3024,6 +3357,13 @@ func (m model) renderPullPlanLines() []string {
 	if pending := m.pendingBundleChanges(); pending != "" {
 		lines = append(lines, shellWarnStyle.Render("⚠ Bundles 页有未保存的勾选（"+pending+"），按 s 保存后才会生效"))
 	}
+	if branch := m.selectedPreviewBranch(); branch != "" {
+		line := fmt.Sprintf("评审  %d 个待合并分支 · 选中 %s · b 预览拉取", len(m.overview.PendingBranches), branch)
+		if len(m.overview.PendingBranches) > 1 {
+			line += " · B 切换"
+		}
+		lines = append(lines, shellMutedStyle.Render(line))
+	}
 	return lines
 }
 
@@ -3128,9 +3468,37 @@ func (m model) renderRunLastResult() []string {
 		if grouped := formatRunEventsBySyncTarget(m.runEvents); len(grouped) > 0 {
 			lines = append(lines, grouped...)
 		}
-		lines = append(lines, fmt.Sprintf("IDE   %s", fallbackValue(strings.Join(m.runResult.EffectiveIDEs, ", "), "<none>")))
+		ideLine := fmt.Sprintf("IDE   %s", fallbackValue(strings.Join(m.runResult.EffectiveIDEs, ", "), "<none>"))
+		if m.runResult.InstallMode == types.InstallModeSymlink {
+			ideLine += " · symlink → .dec/rendered/"
+		}
+		lines = append(lines, ideLine)
+		for _, fallback := range m.runResult.CopyFallbacks {
+			lines = append(lines, shellMutedStyle.Render("  ↩ 副本安装 "+fallback))
+		}
+		if m.runResult.PreviewBranch != "" {
+			lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("预览  已安装评审分支 %s 的内容（未合并），按 p 回到 %s",
+				m.runResult.PreviewBranch, fallbackValue(m.runResult.VaultBranch, "主线"))))
+		}
 		if strings.TrimSpace(m.runResult.VersionCommit) != "" {
-			lines = append(lines, fmt.Sprintf("Commit %s", m.runResult.VersionCommit))
+			commitLine := fmt.Sprintf("Commit %s", m.runResult.VersionCommit)
+			if m.runResult.VaultBranch != "" {
+				commitLine += " · 分支 " + m.runResult.VaultBranch
+			}
+			lines = append(lines, commitLine)
+		}
+		if m.runResult.SignatureStatus != "" && (m.runResult.Signer != "" || len(m.runResult.SignatureIssues) > 0) {
+			signLine := "签名  " + signatureStatusLabel(m.runResult.SignatureStatus)
+			if m.runResult.Signer != "" {
+				signLine += " · " + m.runResult.Signer
+			}
+			lines = append(lines, signLine)
+		}
+		if m.runResult.Held {
+			lines = append(lines, shellWarnStyle.Render("固定  项目固定在该版本，Pull 不跟随远端；按 U 解除固定"))
+		}
+		for _, mismatch := range m.runResult.LockMismatches {
+			lines = append(lines, shellWarnStyle.Render("⚠ 与当时拉取的内容不一致："+mismatch))
 		}
 		for _, warning := range m.runResult.NonFatalWarnings {
 			lines = append(lines, shellWarnStyle.Render("⚠ "+warning))
@@ -3139,11 +3507,32 @@ func (m model) renderRunLastResult() []string {
 	if m.pushResult != nil {
 		if m.pushResult.DecPushedCount > 0 || m.pushResult.DecSkippedReason != "" {
 			decLine := fmt.Sprintf("Dec   推送 %d 项", m.pushResult.DecPushedCount)
+			if m.pushResult.DecSelected {
+				decLine = fmt.Sprintf("Dec   按选择推送 %d 个文件", m.pushResult.DecPushedCount)
+			}
 			if m.pushResult.DecSkippedReason != "" && m.pushResult.DecPushedCount == 0 {
 				decLine = "Dec   " + m.pushResult.DecSkippedReason
 			}
 			lines = append(lines, decLine)
 		}
+		if title, _, _ := strings.Cut(m.pushResult.DecCommitMessage, "\n"); title != "" {
+			lines = append(lines, shellMutedStyle.Render("提交  "+title))
+		}
+		if m.pushResult.DecReviewBranch != "" {
+			lines = append(lines, fmt.Sprintf("评审  已推到分支 %s，合并后对他人生效", m.pushResult.DecReviewBranch))
+		}
+		if m.pushResult.DecQueueSent > 0 {
+			lines = append(lines, fmt.Sprintf("发送  此前离线排队的 %d 个提交已送到远端", m.pushResult.DecQueueSent))
+		}
+		if m.pushResult.DecQueued {
+			lines = append(lines, shellWarnStyle.Render("排队  离线，提交已加入本地队列，下次在线 Push 时发送"))
+		}
+		if len(m.pushResult.DecConflicts) > 0 {
+			lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("冲突 %d 个文件（相对拉取版本 %s，本地与远端都改过）：",
+				len(m.pushResult.DecConflicts), shortCommitLabel(m.pushResult.DecMergeBase))))
+			lines = append(lines, formatPushConflicts(m.pushResult.DecConflicts)...)
+			lines = append(lines, shellMutedStyle.Render("  先 Pull 合入远端改动，再处理这些文件后重新 Push"))
+		}
 		secretsLine := fmt.Sprintf("Secrets  新建 %d · 更新 %d", m.pushResult.SecretsCreatedCount, m.pushResult.SecretsUpdatedCount)
 		if m.pushResult.SecretsSkippedReason != "" && m.pushResult.SecretsCreatedCount+m.pushResult.SecretsUpdatedCount == 0 {
 			secretsLine = "Secrets  " + m.pushResult.SecretsSkippedReason
@@ -3183,6 +3572,9 @@ func (m model) renderRunHelpPanel() []string {
 		shellTitleStyle.Render("快捷键"),
 		shellMutedStyle.Render("p / s  执行 pull"),
 		shellMutedStyle.Render("P      推送到远端（两次确认）"),
+		shellMutedStyle.Render("b / B  按待合并评审分支预览拉取 / 切换选中分支"),
+		shellMutedStyle.Render("H      拉取记录：回滚到更早的一次拉取并固定"),
+		shellMutedStyle.Render("U      解除固定，之后 pull 跟随远端最新"),
 		shellMutedStyle.Render("删除 / 编辑远端请切到 Remote 页（侧栏 Run 之后）"),
 		shellMutedStyle.Render("u      检查并自更新 dec"),
 		shellMutedStyle.Render("r      刷新项目概览"),
@@ -3235,23 +3627,66 @@ func (m model) renderPushSummary() []string {
 		secretsLine += "（Bitwarden 未配置，将跳过）"
 	}
 	lines = append(lines, secretsLine)
-	if p.DecHasChanges {
+	if p.VaultBranch != "" {
+		lines = append(lines, fmt.Sprintf("Vault 分支: %s", p.VaultBranch))
+	}
+	if p.PushPolicy == types.PushPolicyBranch {
+		lines = append(lines, "推送方式: 新建评审分支 dec/<user>/<project>/<时间戳>，合并后生效")
+	}
+	if len(p.DecConflicts) > 0 {
+		lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("Dec cache  %d 个文件与远端冲突，推送会放弃：", len(p.DecConflicts))))
+		lines = append(lines, formatPushConflicts(p.DecConflicts)...)
+		if len(p.DecChanges) > 0 {
+			lines = append(lines, shellMutedStyle.Render("  取消勾选任一文件即按选择推送，冲突文件不会提交"))
+		}
+	} else if p.DecHasChanges {
 		lines = append(lines, fmt.Sprintf("Dec cache  有变更（约 %d 项待推送）", p.DecCandidateCount))
 	} else if p.DecSkippedReason != "" {
 		lines = append(lines, fmt.Sprintf("Dec cache  %s", p.DecSkippedReason))
 	} else {
 		lines = append(lines, "Dec cache  无本地变更")
 	}
+	lines = append(lines, m.renderPushChanges()...)
+	return lines
+}
+
+// formatPushConflicts 逐文件列出推送冲突。
+func formatPushConflicts(conflicts []app.PushConflict) []string {
+	lines := make([]string, 0, len(conflicts))
+	for _, c := range conflicts {
+		lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("  ✗ %s", c.Path))+shellMutedStyle.Render(" · "+c.Reason))
+	}
 	return lines
 }
 
+func shortCommitLabel(commit string) string {
+	if commit == "" {
+		return "<未知>"
+	}
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
 func (m model) renderPushConfirm() []string {
 	lines := []string{shellTitleStyle.Render("Push 最终确认")}
-	lines = append(lines,
-		shellWarnStyle.Render("将更新 Dec Git vault 与 Bitwarden。"),
-		shellMutedStyle.Render("Dec 变更将提交并推送；Secrets 只新建或更新，不删除。"),
-		shellMutedStyle.Render("y 确认 · n/Esc 返回"),
-	)
+	if len(m.pushExcluded) > 0 {
+		lines = append(lines,
+			shellWarnStyle.Render(fmt.Sprintf("按选择推送：只提交勾选的 %d/%d 个 Dec 文件，本次不推 secrets。", m.pushSelectedCount(), len(m.pushChanges()))),
+		)
+	} else {
+		lines = append(lines,
+			shellWarnStyle.Render("将更新 Dec Git vault 与 Bitwarden。"),
+			shellMutedStyle.Render("Dec 变更将提交并推送；Secrets 只新建或更新，不删除。"),
+		)
+	}
+	lines = append(lines, m.renderPushMessageLine())
+	if m.pushMessageEditing {
+		lines = append(lines, shellMutedStyle.Render("Enter 完成 · Esc 放弃说明"))
+	} else {
+		lines = append(lines, shellMutedStyle.Render("y 确认 · m 填写提交说明 · n/Esc 返回"))
+	}
 	return lines
 }
 
@@ -3300,6 +3735,8 @@ func runPhaseLabel(phase string) string {
 		return "Dec 推送"
 	case "secrets":
 		return "Secrets"
+	case "render":
+		return "渲染校验"
 	case "install":
 		return "IDE 安装"
 	case "done":
@@ -4557,6 +4994,102 @@ func formatProjectNameDisplay(overview *app.ProjectOverview) string {
 	return name
 }
 
+// formatOfflineStatus 返回 Home 页的离线模式与离线 push 队列；在线且队列为空时不显示。
+func formatOfflineStatus(overview *app.ProjectOverview) []string {
+	if overview == nil {
+		return nil
+	}
+	var lines []string
+	if overview.OfflineMode == app.OfflineOn {
+		lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("离线模式: 已开启 · 本地缓存%s", app.CacheAgeLabel(overview.CacheSyncedAt, time.Now()))))
+	}
+	total := 0
+	for _, entry := range overview.PushQueue {
+		total += len(entry.Subjects)
+	}
+	if total == 0 {
+		return lines
+	}
+	lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("待发送的离线提交: %d 个（下次在线 Push 时发送）", total)))
+	shown := 0
+	for _, entry := range overview.PushQueue {
+		for _, subject := range entry.Subjects {
+			if shown == homePendingBranchLimit {
+				lines = append(lines, shellMutedStyle.Render(fmt.Sprintf("  … 另有 %d 个", total-shown)))
+				return lines
+			}
+			lines = append(lines, shellMutedStyle.Render(fmt.Sprintf("  · %s → %s", subject, entry.Target)))
+			shown++
+		}
+	}
+	return lines
+}
+
+// formatVaultFormat 返回 Home 页的 vault 格式提示；格式与本版本一致或未读取时不显示。
+func formatVaultFormat(overview *app.ProjectOverview) []string {
+	if overview == nil || overview.VaultFormat == nil {
+		return nil
+	}
+	status := overview.VaultFormat
+	if status.Newer() {
+		return []string{shellWarnStyle.Render(fmt.Sprintf("vault 格式 v%d 高于本版本支持的 v%d：可以 Pull，Push 前请先升级 Dec", status.Format, status.Supported))}
+	}
+	if len(status.Pending) == 0 {
+		return nil
+	}
+	return []string{shellMutedStyle.Render(fmt.Sprintf("vault 格式 v%d，可迁移到 v%d（dec_migrate_vault，%d 步）", status.Format, status.Supported, len(status.Pending)))}
+}
+
+// formatPulledSignature 返回 Home 页「签名」行；未开启 commit_trust 且上次拉取的版本未签名时不显示。
+// warn 表示开启了校验但该版本不是受信签名。
+func formatPulledSignature(overview *app.ProjectOverview) (string, bool) {
+	if overview == nil || overview.PulledSignatureStatus == "" {
+		return "", false
+	}
+	checking := overview.CommitTrustPolicy != "" && overview.CommitTrustPolicy != types.CommitTrustOff
+	if !checking && overview.PulledSignatureStatus == app.SignatureUnsigned {
+		return "", false
+	}
+	line := fmt.Sprintf("签名: %s · %s", shortCommitLabel(overview.PulledCommit), signatureStatusLabel(overview.PulledSignatureStatus))
+	if overview.PulledSigner != "" {
+		line += " · " + overview.PulledSigner
+	}
+	if checking {
+		line += fmt.Sprintf("（commit_trust: %s）", overview.CommitTrustPolicy)
+	}
+	return line, checking && overview.PulledSignatureStatus != app.SignatureTrusted
+}
+
+func signatureStatusLabel(status string) string {
+	switch status {
+	case app.SignatureTrusted:
+		return "受信签名"
+	case app.SignatureUntrusted:
+		return "签名者不受信任"
+	case app.SignatureInvalid:
+		return "签名无效"
+	case app.SignatureUnsigned:
+		return "未签名"
+	default:
+		return status
+	}
+}
+
+// formatVaultBranchDisplay 展示项目跟随的 vault 分支及其来源；分支未知（仓库未连接）时返回空串。
+func formatVaultBranchDisplay(overview *app.ProjectOverview) string {
+	if overview == nil || strings.TrimSpace(overview.VaultBranch) == "" {
+		return ""
+	}
+	switch overview.VaultBranchSource {
+	case types.VaultBranchSourceProject:
+		return overview.VaultBranch + "（项目配置）"
+	case types.VaultBranchSourceGlobal:
+		return overview.VaultBranch + "（全局配置）"
+	default:
+		return overview.VaultBranch + "（远端默认）"
+	}
+}
+
 // countOverviewAvailableBundles 优先按扫描到的 bundle 列表计数；仓库未连接或扫描失败时
 // Bundles 为空，退回 config 层记录的计数。
 func countOverviewAvailableBundles(overview *app.ProjectOverview) int {
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/shichao402/Dec/internal/app"
 	"github.com/shichao402/Dec/internal/types"
 	"github.com/shichao402/Dec/internal/update"
+	"github.com/shichao402/Dec/internal/vars"
 )
 
 func TestModelViewRendersHomeOverview(t *testing.T) {
@@ -56,6 +58,140 @@ func TestModelViewRendersHomeOverview(t *testing.T) {
 	}
 }
 
+func TestModelHomeShowsVaultBranch(t *testing.T) {
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.width = 120
+	m.height = 36
+
+	updated, _ := m.Update(overviewLoadedMsg{overview: &app.ProjectOverview{
+		ProjectRoot:       "/tmp/dec-project",
+		RepoConnected:     true,
+		VaultBranch:       "next",
+		VaultBranchSource: types.VaultBranchSourceProject,
+		PulledBranch:      "stable",
+	}})
+	m = updated.(model)
+
+	view := m.View()
+	for _, check := range []string{"Vault 分支: next（项目配置）", "上次拉取自 stable"} {
+		if !strings.Contains(view, check) {
+			t.Fatalf("View() 缺少 %q:\n%s", check, view)
+		}
+	}
+}
+
+func TestModelHomeShowsPulledSigner(t *testing.T) {
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.width = 140
+	m.height = 40
+	m.overview = &app.ProjectOverview{ProjectRoot: "/tmp/dec-project", RepoConnected: true, VaultBranch: "main",
+		PulledCommit: "0123456789abcdef", PulledSignatureStatus: app.SignatureTrusted,
+		PulledSigner: "vault-bot (SHA256:abc)", CommitTrustPolicy: types.CommitTrustEnforce}
+	if view := m.View(); !strings.Contains(view, "签名: 0123456789ab · 受信签名 · vault-bot (SHA256:abc)（commit_trust: enforce）") {
+		t.Fatalf("Home 应显示拉取版本的签名者:\n%s", view)
+	}
+
+	m.overview.PulledSignatureStatus, m.overview.PulledSigner, m.overview.CommitTrustPolicy = app.SignatureUnsigned, "", types.CommitTrustOff
+	if view := m.View(); strings.Contains(view, "签名:") {
+		t.Fatalf("未开启校验且未签名时不应显示签名行:\n%s", view)
+	}
+}
+
+func TestModelHomeShowsOfflineQueue(t *testing.T) {
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.width = 140
+	m.height = 40
+	m.overview = &app.ProjectOverview{ProjectRoot: "/tmp/dec-project", RepoConnected: true, VaultBranch: "main",
+		OfflineMode: app.OfflineOn, CacheSyncedAt: time.Now().Add(-3 * time.Hour),
+		PushQueue: []app.PushQueueEntry{{Target: "main", Subjects: []string{"dec: update combo", "dec: add rule"}}}}
+	view := m.View()
+	for _, want := range []string{"离线模式: 已开启 · 本地缓存3 小时前同步", "待发送的离线提交: 2 个", "· dec: update combo → main"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("Home 应显示 %q:\n%s", want, view)
+		}
+	}
+
+	m.overview.OfflineMode, m.overview.PushQueue = app.OfflineAuto, nil
+	if view := m.View(); strings.Contains(view, "离线") {
+		t.Fatalf("在线且队列为空时不应显示离线信息:\n%s", view)
+	}
+}
+
+func TestModelHomeShowsVaultFormat(t *testing.T) {
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.width = 140
+	m.height = 40
+	m.overview = &app.ProjectOverview{ProjectRoot: "/tmp/dec-project", RepoConnected: true, VaultBranch: "main",
+		VaultFormat: &app.VaultFormatStatus{Branch: "main", Format: 0, Supported: 2, Pending: []string{"a", "b"}}}
+	if view := m.View(); !strings.Contains(view, "vault 格式 v0，可迁移到 v2") {
+		t.Fatalf("Home 应提示可迁移:\n%s", view)
+	}
+
+	m.overview.VaultFormat = &app.VaultFormatStatus{Branch: "main", Format: 3, Supported: 2}
+	if view := m.View(); !strings.Contains(view, "Push 前请先升级 Dec") {
+		t.Fatalf("Home 应提示 vault 格式更高:\n%s", view)
+	}
+
+	m.overview.VaultFormat = &app.VaultFormatStatus{Branch: "main", Format: 2, Supported: 2}
+	if view := m.View(); strings.Contains(view, "vault 格式") {
+		t.Fatalf("格式一致时不应显示:\n%s", view)
+	}
+}
+
+func TestModelPendingReviewBranchesOnHomeAndRunPreview(t *testing.T) {
+	oldPreview := runBranchPreviewOperation
+	defer func() { runBranchPreviewOperation = oldPreview }()
+	var previewed string
+	runBranchPreviewOperation = func(ctx context.Context, workspace app.Workspace, branch string, reporter app.Reporter) (*app.PullProjectAssetsResult, error) {
+		previewed = branch
+		return &app.PullProjectAssetsResult{PreviewBranch: branch, VaultBranch: "main", PulledCount: 1, RequestedCount: 1}, nil
+	}
+
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.width = 140
+	m.height = 40
+	pending := []string{"dec/alice/demo/20260102-030405", "dec/bob/demo/20260103-000000"}
+	m.overview = &app.ProjectOverview{ProjectRoot: "/tmp/dec-project", RepoConnected: true, VaultBranch: "main", PendingBranches: pending}
+
+	view := m.View()
+	for _, check := range []string{"待合并评审分支: 2 个", pending[0], pending[1]} {
+		if !strings.Contains(view, check) {
+			t.Fatalf("Home View() 缺少 %q:\n%s", check, view)
+		}
+	}
+
+	m.pageIndex = 3
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'B'}})
+	m = updated.(model)
+	if got := m.selectedPreviewBranch(); got != pending[1] {
+		t.Fatalf("B 后选中 %q, 期望 %q", got, pending[1])
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}})
+	m = updated.(model)
+	if cmd == nil || !m.runningPull || m.runMode != "pull" {
+		t.Fatalf("b 后应进入预览拉取: cmd=%v running=%v mode=%q", cmd, m.runningPull, m.runMode)
+	}
+	batchMsg, ok := cmd().(tea.BatchMsg)
+	if !ok {
+		t.Fatalf("cmd() 类型 = %T, 期望 tea.BatchMsg", cmd())
+	}
+	for _, sub := range batchMsg {
+		if sub == nil {
+			continue
+		}
+		if completed, ok := sub().(runCompletedMsg); ok {
+			updated, _ = m.Update(completed)
+			m = updated.(model)
+		}
+	}
+	if previewed != pending[1] {
+		t.Fatalf("应按选中分支预览, got %q", previewed)
+	}
+	if view := m.View(); !strings.Contains(view, "已安装评审分支 "+pending[1]) {
+		t.Fatalf("Run View() 应标明预览分支:\n%s", view)
+	}
+}
+
 func TestInitRefreshKickKeepsShellRefreshGen(t *testing.T) {
 	m := newModel("/tmp/dec-project", "v1.0.0")
 	initCmd := m.Init()
@@ -317,6 +453,33 @@ func TestModelAssetsPageRendersSelectionState(t *testing.T) {
 	}
 }
 
+func TestModelRunPageListsPushConflicts(t *testing.T) {
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.pageIndex = 3
+	m.width = 140
+	m.height = 40
+	m.runMode = "push"
+	m.pushResult = &app.PushProjectAssetsResult{
+		DecSkippedReason: "1 个文件与远端冲突，未推送",
+		DecMergeBase:     "0123456789abcdef",
+		DecConflicts: []app.PushConflict{
+			{Path: "bundles/combo/rules/a.mdc", Reason: "本地与远端修改了相同的行"},
+		},
+	}
+
+	view := m.View()
+	for _, check := range []string{
+		"Dec   1 个文件与远端冲突，未推送",
+		"冲突 1 个文件（相对拉取版本 0123456789ab",
+		"✗ bundles/combo/rules/a.mdc",
+		"本地与远端修改了相同的行",
+	} {
+		if !strings.Contains(view, check) {
+			t.Fatalf("Run View() 缺少 %q:\n%s", check, view)
+		}
+	}
+}
+
 func TestModelRunPageRendersExecutionState(t *testing.T) {
 	m := newModel("/tmp/dec-project", "v1.0.0")
 	m.pageIndex = 3
@@ -565,7 +728,7 @@ func TestModelRunPagePushConfirmTriggersRunPushOperation(t *testing.T) {
 		return &app.PushProjectAssetsPreview{EnabledBundleCount: 1}, nil
 	}
 	called := false
-	runPushOperation = func(ctx context.Context, workspace app.Workspace, reporter app.Reporter) (*app.PushProjectAssetsResult, error) {
+	runPushOperation = func(ctx context.Context, workspace app.Workspace, opts app.PushOptions, reporter app.Reporter) (*app.PushProjectAssetsResult, error) {
 		called = true
 		return &app.PushProjectAssetsResult{DecPushedCount: 1}, nil
 	}
@@ -2336,6 +2499,58 @@ func TestModelProjectEditKeyInvokesCmd(t *testing.T) {
 	}
 }
 
+func TestModelProjectVarsExplainCyclesAssets(t *testing.T) {
+	oldExplain := explainVarsOperation
+	defer func() { explainVarsOperation = oldExplain }()
+
+	var requested app.ExplainVarsInput
+	explainVarsOperation = func(input app.ExplainVarsInput) (*app.VarsExplanation, error) {
+		requested = input
+		return &app.VarsExplanation{Vars: []vars.Explanation{{
+			Name:     "FOO",
+			Winner:   &vars.Candidate{Source: vars.SourceProject, File: "/tmp/dec-project/.dec/vars.d/20-foo.yaml", Value: "foo-val"},
+			Shadowed: []vars.Candidate{{Source: vars.SourceGlobal, File: "/tmp/home/.dec/local/vars.yaml", Value: "foo-global"}},
+		}}}, nil
+	}
+
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.pageIndex = 2
+	m.width = 120
+	m.height = 40
+	m.overview = &app.ProjectOverview{RepoConnected: true}
+	m.projectSettings = &app.ProjectSettingsState{ProjectRoot: "/tmp/dec-project", AvailableIDEs: []string{"cursor"}, ProjectConfigReady: true}
+	m.projectVars = &app.ProjectVarsView{
+		VarsPath:          "/tmp/dec-project/.dec/vars.yaml",
+		CacheExists:       true,
+		UsedPlaceholders:  []string{"FOO"},
+		ResolvedVars:      map[string]app.PlaceholderStatus{"FOO": {Name: "FOO", Value: "foo-val", Source: app.PlaceholderSourceProject}},
+		PlaceholderAssets: []types.TypedAssetRef{{Type: "skill", AssetRef: types.AssetRef{Name: "demo", Vault: "team"}}},
+	}
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	m = updated.(model)
+	if cmd == nil {
+		t.Fatal("按 x 应返回 explain tea.Cmd")
+	}
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+	if requested.AssetType != "skill" || requested.AssetName != "demo" || requested.Bundle != "team" {
+		t.Fatalf("explain 请求 = %+v", requested)
+	}
+	view := m.View()
+	for _, check := range []string{"变量来源 1/1", "skill/demo", "20-foo.yaml", "遮蔽 global", "foo-global"} {
+		if !strings.Contains(view, check) {
+			t.Fatalf("Project 页未包含 %q:\n%s", check, view)
+		}
+	}
+
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	m = updated.(model)
+	if cmd != nil || m.varsExplainPos != 0 || strings.Contains(m.View(), "变量来源 1/1") {
+		t.Fatal("转过最后一个资产后应收起溯源")
+	}
+}
+
 func TestModelProjectVarsEditedMsgRefreshesView(t *testing.T) {
 	oldLoad := loadProjectVarsViewOperation
 	defer func() { loadProjectVarsViewOperation = oldLoad }()
@@ -2753,3 +2968,69 @@ func TestModelVersionMismatchTriggersConfirm(t *testing.T) {
 		t.Fatalf("取消后应 dismiss: stage=%q dismissed=%v", m.serverRestartStage, m.serverVersionMismatchDismissed)
 	}
 }
+
+func TestModelProjectVarsProfileCyclesAndReloads(t *testing.T) {
+	oldSwitch := switchVarsProfileOperation
+	oldLoad := loadProjectVarsViewOperation
+	defer func() {
+		switchVarsProfileOperation = oldSwitch
+		loadProjectVarsViewOperation = oldLoad
+	}()
+
+	var requested []string
+	switchVarsProfileOperation = func(projectRoot, profile string, reporter app.Reporter) (*app.SwitchVarsProfileResult, error) {
+		requested = append(requested, profile)
+		return &app.SwitchVarsProfileResult{ProjectRoot: projectRoot, Active: profile, Rerendered: []string{"[rule ] api (team)"}}, nil
+	}
+	reloaded := false
+	loadProjectVarsViewOperation = func(projectRoot string) (*app.ProjectVarsView, error) {
+		reloaded = true
+		return &app.ProjectVarsView{VarsPath: "/tmp/dec-project/.dec/vars.yaml", Profiles: []string{"prod", "staging"}, ActiveProfile: "prod"}, nil
+	}
+
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.pageIndex = 2
+	m.width = 120
+	m.height = 40
+	m.overview = &app.ProjectOverview{RepoConnected: true}
+	m.projectSettings = &app.ProjectSettingsState{ProjectRoot: "/tmp/dec-project", AvailableIDEs: []string{"cursor"}, ProjectConfigReady: true}
+	m.projectVars = &app.ProjectVarsView{
+		VarsPath:         "/tmp/dec-project/.dec/vars.yaml",
+		CacheExists:      true,
+		Profiles:         []string{"prod", "staging"},
+		UsedPlaceholders: []string{"API_URL"},
+		ResolvedVars:     map[string]app.PlaceholderStatus{"API_URL": {Name: "API_URL", Value: "https://prod", Source: app.PlaceholderSourceProfile, Origin: "prod"}},
+	}
+	for _, check := range []string{"Profile: <无>", "v 切换（prod / staging）", "(profile: prod)"} {
+		if !strings.Contains(m.View(), check) {
+			t.Fatalf("Project 页未包含 %q:\n%s", check, m.View())
+		}
+	}
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}})
+	m = updated.(model)
+	if cmd == nil || !m.switchingVarsProfile {
+		t.Fatal("按 v 应开始切换 profile")
+	}
+	updated, cmd = m.Update(cmd())
+	m = updated.(model)
+	if len(requested) != 1 || requested[0] != "prod" {
+		t.Fatalf("未设置 profile 时应切到第一个, got %v", requested)
+	}
+	if cmd == nil {
+		t.Fatal("切换完成后应重载项目变量")
+	}
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+	if !reloaded || m.switchingVarsProfile || m.projectVars.ActiveProfile != "prod" {
+		t.Fatalf("重载后状态不对: reloaded=%v switching=%v view=%+v", reloaded, m.switchingVarsProfile, m.projectVars)
+	}
+
+	m.projectVars.ActiveProfile = "staging"
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}})
+	m = updated.(model)
+	m.Update(cmd())
+	if requested[len(requested)-1] != "" {
+		t.Fatalf("最后一个 profile 之后应关闭 profile, got %v", requested)
+	}
+}
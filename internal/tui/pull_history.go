@@ -0,0 +1,241 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shichao402/Dec/internal/app"
+	"github.com/shichao402/Dec/internal/serviceapi"
+)
+
+// pullHistoryState 是 Run 页拉取记录视图的状态；open 为 false 时不接管按键。
+type pullHistoryState struct {
+	open       bool
+	history    *app.PullHistory
+	cursor     int
+	confirming bool // Enter 后等待 y 确认回滚
+	load       asyncLoad
+	err        error
+}
+
+type pullHistoryLoadedMsg struct {
+	gen     uint64
+	history *app.PullHistory
+	err     error
+}
+
+type pullUnheldMsg struct {
+	history *app.PullHistory
+	err     error
+}
+
+var loadPullHistoryOperation = func(workspace app.Workspace) (*app.PullHistory, error) {
+	return serviceapi.LoadPullHistory(workspace)
+}
+
+var runRollbackOperation = func(ctx context.Context, workspace app.Workspace, commit string, reporter app.Reporter) (*app.PullProjectAssetsResult, error) {
+	return serviceapi.RollbackWorkspacePull(ctx, workspace, commit, reporter)
+}
+
+var unholdPullOperation = func(workspace app.Workspace) (*app.PullHistory, error) {
+	return serviceapi.UnholdWorkspacePull(workspace)
+}
+
+func loadPullHistoryCmd(workspace app.Workspace, gen uint64) tea.Cmd {
+	return func() tea.Msg {
+		history, err := loadPullHistoryOperation(workspace)
+		return pullHistoryLoadedMsg{gen: gen, history: history, err: err}
+	}
+}
+
+func unholdPullCmd(workspace app.Workspace) tea.Cmd {
+	return func() tea.Msg {
+		history, err := unholdPullOperation(workspace)
+		return pullUnheldMsg{history: history, err: err}
+	}
+}
+
+func startRollbackRunCmd(ctx context.Context, workspace app.Workspace, commit string, stream chan<- tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		go func() {
+			result, err := runRollbackOperation(ctx, workspace, commit, app.ReporterFunc(func(event app.OperationEvent) {
+				stream <- runEventMsg{event: event}
+			}))
+			stream <- runCompletedMsg{result: result, err: err}
+			close(stream)
+		}()
+		return nil
+	}
+}
+
+// startPullHistory 打开 Run 页拉取记录（H）。
+func (m *model) startPullHistory() tea.Cmd {
+	m.pullHistory = pullHistoryState{open: true}
+	m.pushLog("Run 拉取记录")
+	gen := m.pullHistory.load.beginGen()
+	return loadPullHistoryCmd(m.workspace(), gen)
+}
+
+func (m *model) handlePullHistoryLoaded(msg pullHistoryLoadedMsg) {
+	h := &m.pullHistory
+	if !h.open || !h.load.finish(msg.gen) {
+		return
+	}
+	if msg.err != nil {
+		h.err = msg.err
+		m.pushLog("拉取记录加载失败: " + msg.err.Error())
+		return
+	}
+	h.history = msg.history
+	if h.cursor >= len(msg.history.Entries) {
+		h.cursor = 0
+	}
+}
+
+// startRollbackRun 回滚到选中的拉取记录，走与 pull 相同的进度流；完成后项目固定在该版本。
+func (m *model) startRollbackRun(commit string) tea.Cmd {
+	stream := m.beginPullRun()
+	if stream == nil {
+		return nil
+	}
+	m.pushLog("Run page started rollback to " + shortCommitLabel(commit))
+	return tea.Batch(startRollbackRunCmd(m.runCtx, m.workspace(), commit, stream), waitRunMsg(stream))
+}
+
+func (m *model) handlePullUnheld(msg pullUnheldMsg) tea.Cmd {
+	if msg.err != nil {
+		m.pushLog("解除固定失败: " + msg.err.Error())
+		return nil
+	}
+	if m.pullHistory.open {
+		m.pullHistory.history = msg.history
+	}
+	if m.runResult != nil {
+		m.runResult.Held = false
+	}
+	m.pushLog("已解除固定，下次 Pull 跟随远端最新")
+	return m.refreshCmd()
+}
+
+// pullHeld 判断当前工作空间是否固定在某次拉取（回滚后）。
+func (m model) pullHeld() bool {
+	if m.pullHistory.history != nil {
+		return m.pullHistory.history.Held
+	}
+	return m.overview != nil && m.overview.PullHeld
+}
+
+func (m model) cursorPullHistoryEntry() (app.PullHistoryEntry, bool) {
+	h := m.pullHistory
+	if h.history == nil || h.cursor < 0 || h.cursor >= len(h.history.Entries) {
+		return app.PullHistoryEntry{}, false
+	}
+	return h.history.Entries[h.cursor], true
+}
+
+func (m model) handlePullHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	h := &m.pullHistory
+	if h.confirming {
+		switch msg.String() {
+		case "y":
+			entry, ok := m.cursorPullHistoryEntry()
+			h.confirming = false
+			if !ok {
+				return m, nil
+			}
+			h.load.clear()
+			m.pullHistory = pullHistoryState{}
+			return m, m.startRollbackRun(entry.Commit)
+		case "n", "esc":
+			h.confirming = false
+			m.pushLog("已取消回滚")
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "esc", "h", "left", "q", "H":
+		h.load.clear()
+		m.pullHistory = pullHistoryState{}
+		return m, nil
+	case "U":
+		if m.pullHeld() {
+			return m, unholdPullCmd(m.workspace())
+		}
+		return m, nil
+	}
+	if h.history == nil || len(h.history.Entries) == 0 {
+		return m, nil
+	}
+	switch msg.String() {
+	case "j", "down":
+		if h.cursor < len(h.history.Entries)-1 {
+			h.cursor++
+		}
+	case "k", "up":
+		if h.cursor > 0 {
+			h.cursor--
+		}
+	case "enter":
+		h.confirming = true
+	}
+	return m, nil
+}
+
+func pullSourceLabel(source string) string {
+	switch source {
+	case app.PullSourcePreview:
+		return "预览"
+	case app.PullSourceRollback:
+		return "回滚"
+	default:
+		return "拉取"
+	}
+}
+
+func (m model) renderRunPullHistory(width int) string {
+	h := m.pullHistory
+	lines := []string{shellTitleStyle.Render("Run · 拉取记录")}
+	hint := "j/k 选择 · Enter 回滚到该次拉取 · Esc 返回"
+	if m.pullHeld() {
+		hint = "j/k 选择 · Enter 回滚到该次拉取 · U 解除固定 · Esc 返回"
+	}
+	lines = append(lines, shellMutedStyle.Render(hint))
+	if h.err != nil {
+		lines = append(lines, shellWarnStyle.Render(h.err.Error()))
+	}
+	if h.load.busy() {
+		lines = append(lines, shellWarnStyle.Render("读取拉取记录…"))
+	}
+	if h.history == nil {
+		return wrapLines(width, lines)
+	}
+	if h.history.Held {
+		lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("📌 已固定在 %s，Pull 不跟随远端、不再提示更新", shortCommitLabel(h.history.CurrentCommit))))
+	}
+	if len(h.history.Entries) == 0 {
+		lines = append(lines, shellMutedStyle.Render("还没有拉取记录；Pull 成功后会记下版本与启用的 bundle"))
+		return wrapLines(width, lines)
+	}
+	if h.confirming {
+		entry, _ := m.cursorPullHistoryEntry()
+		lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("重新拉取 %s 并固定在该版本？启用的 bundle 会恢复为当时的选择。y 确认 · n 取消", shortCommitLabel(entry.Commit))))
+	}
+	lines = append(lines, "")
+	for i, entry := range h.history.Entries {
+		current := "  "
+		if entry.Commit == h.history.CurrentCommit {
+			current = "● "
+		}
+		line := fitLine(fmt.Sprintf("%s%s  %s  %s  %s", current, shortCommitLabel(entry.Commit),
+			entry.PulledAt.Local().Format("2006-01-02 15:04"), pullSourceLabel(entry.Source),
+			fallbackValue(strings.Join(entry.EnabledBundles, ", "), "<无 bundle>")), width)
+		if i == h.cursor {
+			lines = append(lines, shellSelectedRow.Render(line))
+		} else {
+			lines = append(lines, shellLogStyle.Render(line))
+		}
+	}
+	return wrapLines(width, lines)
+}
@@ -0,0 +1,106 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shichao402/Dec/internal/app"
+)
+
+func TestRunPullHistory_RollbackAndUnhold(t *testing.T) {
+	oldLoad := loadPullHistoryOperation
+	oldRollback := runRollbackOperation
+	oldUnhold := unholdPullOperation
+	defer func() {
+		loadPullHistoryOperation = oldLoad
+		runRollbackOperation = oldRollback
+		unholdPullOperation = oldUnhold
+	}()
+	history := &app.PullHistory{
+		CurrentCommit: "bbbbbbbbbbbbbbbb",
+		Entries: []app.PullHistoryEntry{
+			{Commit: "bbbbbbbbbbbbbbbb", PulledAt: time.Unix(1700000100, 0), Source: app.PullSourcePull, EnabledBundles: []string{"combo", "extra"}},
+			{Commit: "aaaaaaaaaaaaaaaa", PulledAt: time.Unix(1700000000, 0), Source: app.PullSourcePull, EnabledBundles: []string{"combo"}},
+		},
+	}
+	loadPullHistoryOperation = func(workspace app.Workspace) (*app.PullHistory, error) {
+		return history, nil
+	}
+	var rolledBack string
+	runRollbackOperation = func(ctx context.Context, workspace app.Workspace, commit string, reporter app.Reporter) (*app.PullProjectAssetsResult, error) {
+		rolledBack = commit
+		return &app.PullProjectAssetsResult{VersionCommit: commit, Held: true, PulledCount: 1, RequestedCount: 1}, nil
+	}
+	unheld := false
+	unholdPullOperation = func(workspace app.Workspace) (*app.PullHistory, error) {
+		unheld = true
+		return &app.PullHistory{CurrentCommit: "aaaaaaaaaaaaaaaa", Entries: history.Entries}, nil
+	}
+
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.width, m.height = 140, 40
+	m.overview = &app.ProjectOverview{ProjectRoot: "/tmp/dec-project", RepoConnected: true}
+	m.pageIndex = 3
+	runes := func(r rune) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}} }
+	press := func(key tea.KeyMsg) tea.Cmd {
+		t.Helper()
+		updated, cmd := m.Update(key)
+		m = updated.(model)
+		return cmd
+	}
+
+	cmd := press(runes('H'))
+	if !m.pullHistory.open || cmd == nil {
+		t.Fatal("Run 页 H 应打开拉取记录")
+	}
+	updated, _ := m.Update(cmd())
+	m = updated.(model)
+	view := m.View()
+	for _, check := range []string{"Run · 拉取记录", "aaaaaaaaaaaa", "combo, extra"} {
+		if !strings.Contains(view, check) {
+			t.Fatalf("拉取记录视图缺少 %q:\n%s", check, view)
+		}
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.View(), "y 确认") {
+		t.Fatal("Enter 应先要求确认回滚")
+	}
+	cmd = press(runes('y'))
+	if cmd == nil || !m.runningPull || m.pullHistory.open {
+		t.Fatalf("y 后应关闭记录并开始回滚: running=%v open=%v", m.runningPull, m.pullHistory.open)
+	}
+	batchMsg, ok := cmd().(tea.BatchMsg)
+	if !ok {
+		t.Fatalf("cmd() 类型 = %T, 期望 tea.BatchMsg", cmd())
+	}
+	for _, sub := range batchMsg {
+		if sub == nil {
+			continue
+		}
+		if completed, ok := sub().(runCompletedMsg); ok {
+			updated, _ = m.Update(completed)
+			m = updated.(model)
+		}
+	}
+	if rolledBack != "aaaaaaaaaaaaaaaa" {
+		t.Fatalf("应回滚到选中的记录, got %q", rolledBack)
+	}
+	m.overview.PullHeld = true
+	m.overview.PulledCommit = rolledBack
+	if view := m.View(); !strings.Contains(view, "按 U 解除固定") || !strings.Contains(view, "U 解除固定") {
+		t.Fatalf("回滚后 Run 页应提示固定状态:\n%s", view)
+	}
+
+	cmd = press(runes('U'))
+	if cmd == nil {
+		t.Fatal("固定时 U 应解除固定")
+	}
+	if _, ok := cmd().(pullUnheldMsg); !ok || !unheld {
+		t.Fatal("U 应调用解除固定")
+	}
+}
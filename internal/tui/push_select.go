@@ -0,0 +1,210 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shichao402/Dec/internal/app"
+	"github.com/shichao402/Dec/internal/textdiff"
+)
+
+// pushDiffMaxLines 是 Push 摘要页展开单个文件 diff 时最多显示的行数。
+const pushDiffMaxLines = 40
+
+// pushChanges 是 Push 预览中的逐文件改动，预览未加载时为空。
+func (m model) pushChanges() []app.VaultFileChange {
+	if m.pushPreview == nil {
+		return nil
+	}
+	return m.pushPreview.DecChanges
+}
+
+// resetPushSelection 在新预览到达时恢复默认：全部勾选、光标回到第一项、收起 diff、清空提交说明。
+func (m *model) resetPushSelection() {
+	m.pushCursor = 0
+	m.pushExcluded = nil
+	m.pushDiffOpen = false
+	m.pushMessage = ""
+	m.pushMessageEditing = false
+}
+
+// handlePushMessageInput 处理确认页的提交说明输入；Esc 放弃本次输入的说明。
+func (m model) handlePushMessageInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.pushMessageEditing = false
+		m.pushMessage = ""
+		return m, nil
+	case tea.KeyEnter:
+		m.pushMessageEditing = false
+		m.pushMessage = strings.TrimSpace(m.pushMessage)
+		return m, nil
+	case tea.KeyBackspace, tea.KeyCtrlH:
+		m.pushMessage = trimLastRune(m.pushMessage)
+		return m, nil
+	}
+	if len(msg.Runes) > 0 && !msg.Alt {
+		m.pushMessage += string(msg.Runes)
+	}
+	return m, nil
+}
+
+// renderPushMessageLine 展示确认页的提交说明；未填写时提示将按改动自动生成。
+func (m model) renderPushMessageLine() string {
+	switch {
+	case m.pushMessageEditing:
+		return "说明  " + m.pushMessage + "▏"
+	case m.pushMessage != "":
+		return "说明  " + m.pushMessage
+	default:
+		return shellMutedStyle.Render("说明  （未填写，按涉及的 bundle / 资产自动生成）")
+	}
+}
+
+// pushSelection 把勾选状态换成 app.PushSelection：全选时返回空选择（推送全部，含 secrets），
+// 否则逐文件列出，与预览看到的内容一一对应。
+func (m model) pushSelection() app.PushSelection {
+	if len(m.pushExcluded) == 0 {
+		return app.PushSelection{}
+	}
+	var selection app.PushSelection
+	for _, change := range m.pushChanges() {
+		if !m.pushExcluded[change.Path] {
+			selection.Files = append(selection.Files, change.Path)
+		}
+	}
+	return selection
+}
+
+// pushSelectedCount 返回勾选的文件数。
+func (m model) pushSelectedCount() int {
+	count := 0
+	for _, change := range m.pushChanges() {
+		if !m.pushExcluded[change.Path] {
+			count++
+		}
+	}
+	return count
+}
+
+// handlePushSelectKey 处理摘要页的勾选按键，handled=false 时交回摘要页的确认 / 取消逻辑。
+func (m model) handlePushSelectKey(msg tea.KeyMsg) (model, bool) {
+	changes := m.pushChanges()
+	if len(changes) == 0 {
+		return m, false
+	}
+	if m.pushCursor < 0 || m.pushCursor >= len(changes) {
+		m.pushCursor = 0
+	}
+	current := changes[m.pushCursor]
+	switch msg.String() {
+	case "j", "down":
+		if m.pushCursor < len(changes)-1 {
+			m.pushCursor++
+		}
+	case "k", "up":
+		if m.pushCursor > 0 {
+			m.pushCursor--
+		}
+	case " ":
+		m.setPushExcluded(current.Path, !m.pushExcluded[current.Path])
+	case "a":
+		// 整个 bundle 只要还有勾选的文件就全部取消，否则全部勾选。
+		exclude := false
+		for _, change := range changes {
+			if change.Bundle == current.Bundle && !m.pushExcluded[change.Path] {
+				exclude = true
+				break
+			}
+		}
+		for _, change := range changes {
+			if change.Bundle == current.Bundle {
+				m.setPushExcluded(change.Path, exclude)
+			}
+		}
+	case "d":
+		m.pushDiffOpen = !m.pushDiffOpen
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+func (m *model) setPushExcluded(path string, excluded bool) {
+	if !excluded {
+		delete(m.pushExcluded, path)
+		return
+	}
+	if m.pushExcluded == nil {
+		m.pushExcluded = make(map[string]bool)
+	}
+	m.pushExcluded[path] = true
+}
+
+// renderPushChanges 列出逐文件改动与勾选状态，光标所在文件可展开 diff。
+func (m model) renderPushChanges() []string {
+	changes := m.pushChanges()
+	if len(changes) == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("Dec 文件  已选 %d/%d · j/k 移动 · 空格 勾选 · a 整个 bundle · d 展开 diff", m.pushSelectedCount(), len(changes))}
+	for i, change := range changes {
+		mark := "[x]"
+		if m.pushExcluded[change.Path] {
+			mark = "[ ]"
+		}
+		line := fmt.Sprintf("%s %s %s", mark, pushChangeSymbol(change.Status), change.Path)
+		if change.Binary {
+			line += " · 二进制"
+		} else if added, removed := textdiff.Stats(change.Diff); added+removed > 0 {
+			line += fmt.Sprintf(" · +%d -%d", added, removed)
+		}
+		if i == m.pushCursor {
+			lines = append(lines, shellSelectedRow.Render("▸ "+line))
+		} else {
+			lines = append(lines, "  "+line)
+		}
+	}
+	if m.pushDiffOpen && m.pushCursor >= 0 && m.pushCursor < len(changes) {
+		lines = append(lines, renderPushDiff(changes[m.pushCursor])...)
+	}
+	if len(m.pushExcluded) > 0 {
+		lines = append(lines, shellWarnStyle.Render("按选择推送：只提交勾选的文件，本次不推 secrets"))
+	}
+	return lines
+}
+
+func renderPushDiff(change app.VaultFileChange) []string {
+	if change.Binary {
+		return []string{shellMutedStyle.Render("    二进制文件，不展示 diff")}
+	}
+	diffLines := strings.Split(strings.TrimRight(change.Diff, "\n"), "\n")
+	lines := make([]string, 0, len(diffLines)+1)
+	for i, line := range diffLines {
+		if i == pushDiffMaxLines {
+			lines = append(lines, shellMutedStyle.Render(fmt.Sprintf("    … 另有 %d 行", len(diffLines)-i)))
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+			lines = append(lines, shellGoodStyle.Render("    "+line))
+		case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+			lines = append(lines, shellWarnStyle.Render("    "+line))
+		default:
+			lines = append(lines, shellMutedStyle.Render("    "+line))
+		}
+	}
+	return lines
+}
+
+func pushChangeSymbol(status string) string {
+	switch status {
+	case app.RenderedFileAdded:
+		return "+"
+	case app.RenderedFileDeleted:
+		return "−"
+	default:
+		return "~"
+	}
+}
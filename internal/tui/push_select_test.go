@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shichao402/Dec/internal/app"
+)
+
+func TestModelRunPagePushSelectionSendsTickedFiles(t *testing.T) {
+	oldPreview := previewPushOperation
+	oldPush := runPushOperation
+	defer func() {
+		previewPushOperation = oldPreview
+		runPushOperation = oldPush
+	}()
+
+	previewPushOperation = func(workspace app.Workspace) (*app.PushProjectAssetsPreview, error) {
+		return &app.PushProjectAssetsPreview{
+			EnabledBundleCount: 2,
+			DecHasChanges:      true,
+			DecChanges: []app.VaultFileChange{
+				{Bundle: "alpha", Path: "bundles/alpha/rules/a.mdc", Status: app.RenderedFileModified, Diff: "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-old\n+new\n"},
+				{Bundle: "alpha", Path: "bundles/alpha/rules/b.mdc", Status: app.RenderedFileAdded, Diff: "+b\n"},
+				{Bundle: "beta", Path: "bundles/beta/rules/c.mdc", Status: app.RenderedFileDeleted, Diff: "-c\n"},
+			},
+		}, nil
+	}
+	var got app.PushOptions
+	runPushOperation = func(ctx context.Context, workspace app.Workspace, opts app.PushOptions, reporter app.Reporter) (*app.PushProjectAssetsResult, error) {
+		got = opts
+		return &app.PushProjectAssetsResult{DecPushedCount: len(opts.Selection.Files), DecSelected: true}, nil
+	}
+
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.pageIndex = 3
+	press := func(key tea.KeyMsg) tea.Cmd {
+		t.Helper()
+		updated, cmd := m.Update(key)
+		m = updated.(model)
+		return cmd
+	}
+	runes := func(r rune) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}} }
+
+	cmd := press(runes('P'))
+	updated, _ := m.Update(cmd())
+	m = updated.(model)
+	if m.pushStage != "summary" || m.pushSelectedCount() != 3 {
+		t.Fatalf("预览后应默认全选，stage=%q selected=%d", m.pushStage, m.pushSelectedCount())
+	}
+	if !m.pushSelection().IsEmpty() {
+		t.Fatalf("全选时应发送空选择（推送全部），得到 %+v", m.pushSelection())
+	}
+
+	// d 展开光标所在文件的 diff。
+	press(runes('d'))
+	if view := strings.Join(m.renderPushSummary(), "\n"); !strings.Contains(view, "+new") || !strings.Contains(view, "已选 3/3") {
+		t.Fatalf("摘要应列出文件并展开 diff:\n%s", view)
+	}
+
+	// a 取消整个 alpha bundle，再用空格勾回 b.mdc。
+	press(runes('a'))
+	if m.pushSelectedCount() != 1 {
+		t.Fatalf("a 后应只剩 beta，selected=%d", m.pushSelectedCount())
+	}
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	want := []string{"bundles/alpha/rules/b.mdc", "bundles/beta/rules/c.mdc"}
+	if files := m.pushSelection().Files; strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Fatalf("选择 = %v, 期望 %v", files, want)
+	}
+
+	press(runes('y'))
+	if m.pushStage != "confirm" || !strings.Contains(strings.Join(m.renderPushConfirm(), "\n"), "2/3") {
+		t.Fatalf("确认页应说明按选择推送，stage=%q", m.pushStage)
+	}
+	// m 填写提交说明，Enter 结束输入。
+	press(runes('m'))
+	for _, r := range "fix rule" {
+		press(runes(r))
+	}
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.pushMessageEditing || !strings.Contains(m.renderPushMessageLine(), "fix rule") {
+		t.Fatalf("提交说明输入未生效: %q", m.pushMessage)
+	}
+	cmd = press(runes('y'))
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok {
+		t.Fatalf("cmd() 类型 = %T, 期望 tea.BatchMsg", cmd())
+	}
+	for _, sub := range batch {
+		if sub != nil {
+			sub()
+		}
+	}
+	if strings.Join(got.Selection.Files, ",") != strings.Join(want, ",") || got.Message != "fix rule" {
+		t.Fatalf("runPushOperation 收到的参数 = %+v", got)
+	}
+}
+
+func TestModelRunPagePushSelectionBlocksEmptySelection(t *testing.T) {
+	m := newModel("/tmp/dec-project", "v1.0.0")
+	m.pageIndex = 3
+	m.pushStage = "summary"
+	m.pushPreview = &app.PushProjectAssetsPreview{
+		DecChanges: []app.VaultFileChange{{Bundle: "alpha", Path: "bundles/alpha/rules/a.mdc", Status: app.RenderedFileModified}},
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	m = updated.(model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	m = updated.(model)
+	if m.pushStage != "summary" {
+		t.Fatalf("全部取消勾选时不应进入确认，stage=%q", m.pushStage)
+	}
+}
@@ -0,0 +1,361 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shichao402/Dec/internal/app"
+	"github.com/shichao402/Dec/internal/serviceapi"
+)
+
+// remoteHistoryState 是 Remote 页资产 / bundle 历史视图的状态；open 为 false 时不接管按键。
+type remoteHistoryState struct {
+	open       bool
+	query      app.AssetHistoryQuery // 目标资产或 bundle（不含 revision / from / to）
+	log        *app.AssetHistoryResult
+	detail     *app.AssetHistoryResult // 提交详情或两版本比较，非 nil 时展示 diff
+	cursor     int
+	mark       string // m 标记的比较基准提交
+	offset     int    // diff 视图的滚动行
+	confirming bool   // R 后等待 y 确认恢复
+	restoring  bool
+	load       asyncLoad
+	err        error
+}
+
+type remoteHistoryLoadedMsg struct {
+	gen    uint64
+	detail bool
+	result *app.AssetHistoryResult
+	err    error
+	logs   []string
+}
+
+type remoteHistoryRestoredMsg struct {
+	result *app.AssetRestoreResult
+	err    error
+	logs   []string
+}
+
+var loadAssetHistoryOperation = func(ctx context.Context, workspace app.Workspace, query app.AssetHistoryQuery, reporter app.Reporter) (*app.AssetHistoryResult, error) {
+	return serviceapi.LoadAssetHistory(ctx, workspace, query, reporter)
+}
+
+var restoreAssetRevisionOperation = func(ctx context.Context, workspace app.Workspace, query app.AssetHistoryQuery, reporter app.Reporter) (*app.AssetRestoreResult, error) {
+	return serviceapi.RestoreAssetRevision(ctx, workspace, query, reporter)
+}
+
+func loadRemoteHistoryCmd(ctx context.Context, workspace app.Workspace, query app.AssetHistoryQuery, detail bool, gen uint64) tea.Cmd {
+	return func() tea.Msg {
+		var logs []string
+		reporter := app.ReporterFunc(func(event app.OperationEvent) {
+			if event.Level == app.EventWarn {
+				logs = append(logs, strings.TrimSpace(event.Message))
+			}
+		})
+		result, err := loadAssetHistoryOperation(ctx, workspace, query, reporter)
+		return remoteHistoryLoadedMsg{gen: gen, detail: detail, result: result, err: err, logs: logs}
+	}
+}
+
+func restoreRemoteHistoryCmd(workspace app.Workspace, query app.AssetHistoryQuery) tea.Cmd {
+	return func() tea.Msg {
+		var logs []string
+		reporter := app.ReporterFunc(func(event app.OperationEvent) {
+			if msg := strings.TrimSpace(event.Message); msg != "" {
+				logs = append(logs, msg)
+			}
+		})
+		result, err := restoreAssetRevisionOperation(context.Background(), workspace, query, reporter)
+		return remoteHistoryRestoredMsg{result: result, err: err, logs: logs}
+	}
+}
+
+// startRemoteHistoryAtCursor 打开光标所在 Dec 资产或 bundle 的 vault 历史。
+func (m *model) startRemoteHistoryAtCursor() tea.Cmd {
+	cand, ok := m.cursorRemoteCandidate()
+	if !ok {
+		m.pushLog("Remote：请先把光标停在 Dec 资产或 bundle 上")
+		return nil
+	}
+	var query app.AssetHistoryQuery
+	switch cand.Kind {
+	case app.DeleteKindDecAsset:
+		query = app.AssetHistoryQuery{Bundle: cand.Vault, Type: cand.Type, Name: cand.Name}
+	case app.DeleteKindBundle:
+		query = app.AssetHistoryQuery{Bundle: cand.BundleName}
+	default:
+		m.pushLog("Remote：只有 Dec 资产与 bundle 有 vault 历史（H）")
+		return nil
+	}
+	m.remoteHistory = remoteHistoryState{open: true, query: query}
+	m.pushLog("Remote 历史：" + remoteHistoryTitle(query))
+	return m.reloadRemoteHistory()
+}
+
+func (m *model) reloadRemoteHistory() tea.Cmd {
+	ctx, gen := m.remoteHistory.load.begin()
+	m.remoteHistory.err = nil
+	return loadRemoteHistoryCmd(ctx, m.workspace(), m.remoteHistory.query, false, gen)
+}
+
+func (m *model) loadRemoteHistoryDetail(query app.AssetHistoryQuery) tea.Cmd {
+	ctx, gen := m.remoteHistory.load.begin()
+	m.remoteHistory.err = nil
+	return loadRemoteHistoryCmd(ctx, m.workspace(), query, true, gen)
+}
+
+func (m *model) handleRemoteHistoryLoaded(msg remoteHistoryLoadedMsg) {
+	h := &m.remoteHistory
+	if !h.open || !h.load.finish(msg.gen) {
+		return
+	}
+	for _, line := range msg.logs {
+		m.pushLog(line)
+	}
+	if msg.err != nil {
+		h.err = msg.err
+		m.pushLog("Remote 历史加载失败: " + msg.err.Error())
+		return
+	}
+	if msg.detail {
+		h.detail = msg.result
+		h.offset = 0
+		return
+	}
+	h.log = msg.result
+	h.detail = nil
+	if h.cursor >= len(msg.result.Commits) {
+		h.cursor = 0
+	}
+}
+
+func (m *model) handleRemoteHistoryRestored(msg remoteHistoryRestoredMsg) tea.Cmd {
+	m.remoteHistory.restoring = false
+	for _, line := range msg.logs {
+		m.pushLog(line)
+	}
+	if msg.err != nil {
+		m.remoteHistory.err = msg.err
+		m.pushLog("Remote 恢复失败: " + msg.err.Error())
+		return nil
+	}
+	// 缓存已改，刷新候选列表让「仅远端」等标记跟上。
+	return m.startDeleteCandidatesLoad(true, true)
+}
+
+func (m model) cursorHistoryCommit() (app.VaultCommit, bool) {
+	h := m.remoteHistory
+	if h.log == nil || h.cursor < 0 || h.cursor >= len(h.log.Commits) {
+		return app.VaultCommit{}, false
+	}
+	return h.log.Commits[h.cursor], true
+}
+
+func (m model) handleRemoteHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	h := &m.remoteHistory
+	if h.restoring {
+		return m, nil
+	}
+	if h.confirming {
+		switch msg.String() {
+		case "y":
+			commit, ok := m.cursorHistoryCommit()
+			h.confirming = false
+			if !ok {
+				return m, nil
+			}
+			h.restoring = true
+			query := h.query
+			query.Revision = commit.Hash
+			m.pushLog("Remote 恢复 " + shortCommitLabel(commit.Hash) + " 到本地缓存…")
+			return m, restoreRemoteHistoryCmd(m.workspace(), query)
+		case "n", "esc":
+			h.confirming = false
+			m.pushLog("Remote 已取消恢复")
+		}
+		return m, nil
+	}
+	if h.detail != nil {
+		switch msg.String() {
+		case "esc", "h", "left", "q":
+			h.detail = nil
+			h.load.clear()
+		case "j", "down":
+			h.offset++
+		case "k", "up":
+			if h.offset > 0 {
+				h.offset--
+			}
+		case "pgdown", "ctrl+d":
+			h.offset += 10
+		case "pgup", "ctrl+u":
+			h.offset -= 10
+			if h.offset < 0 {
+				h.offset = 0
+			}
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc", "h", "left", "q":
+		h.load.clear()
+		m.remoteHistory = remoteHistoryState{}
+		m.pushLog("Remote 关闭历史")
+		return m, nil
+	case "r":
+		return m, m.reloadRemoteHistory()
+	}
+	if h.log == nil || len(h.log.Commits) == 0 {
+		return m, nil
+	}
+	switch msg.String() {
+	case "j", "down":
+		if h.cursor < len(h.log.Commits)-1 {
+			h.cursor++
+		}
+	case "k", "up":
+		if h.cursor > 0 {
+			h.cursor--
+		}
+	case "enter", "l", "right":
+		commit, _ := m.cursorHistoryCommit()
+		query := h.query
+		query.Revision = commit.Hash
+		return m, m.loadRemoteHistoryDetail(query)
+	case "m":
+		commit, _ := m.cursorHistoryCommit()
+		if h.mark == commit.Hash {
+			h.mark = ""
+			m.pushLog("Remote 取消比较基准")
+		} else {
+			h.mark = commit.Hash
+			m.pushLog("Remote 比较基准：" + shortCommitLabel(commit.Hash))
+		}
+	case "D":
+		// 有标记时比较标记与光标；否则比较光标与分支最新。
+		commit, _ := m.cursorHistoryCommit()
+		query := h.query
+		if h.mark != "" && h.mark != commit.Hash {
+			query.From, query.To = h.mark, commit.Hash
+		} else {
+			query.From = commit.Hash
+		}
+		return m, m.loadRemoteHistoryDetail(query)
+	case "R":
+		h.confirming = true
+	}
+	return m, nil
+}
+
+func remoteHistoryTitle(query app.AssetHistoryQuery) string {
+	if query.Type == "" {
+		return "bundle " + query.Bundle
+	}
+	return fmt.Sprintf("[%s] %s / %s", query.Type, query.Name, query.Bundle)
+}
+
+func (m model) renderRemoteHistory(width, height int) string {
+	h := m.remoteHistory
+	lines := []string{shellTitleStyle.Render("Remote · 历史 · " + remoteHistoryTitle(h.query))}
+	if h.err != nil {
+		lines = append(lines, shellWarnStyle.Render(h.err.Error()))
+	}
+	if h.detail != nil {
+		lines = append(lines, shellMutedStyle.Render("j/k 滚动 · Esc 返回提交列表"))
+		body := renderRemoteHistoryDetail(h.detail)
+		budget := height - wrappedHeight(width, lines)
+		if budget < 1 {
+			budget = 1
+		}
+		offset := h.offset
+		if offset > len(body)-budget {
+			offset = len(body) - budget
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		end := offset + budget
+		if end > len(body) {
+			end = len(body)
+		}
+		for _, line := range body[offset:end] {
+			lines = append(lines, fitLine(line, width))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	lines = append(lines, shellMutedStyle.Render("Enter 提交详情 · m 标记比较基准 · D 比较 · R 恢复到本地缓存 · r 刷新 · Esc 返回"))
+	if h.load.busy() {
+		lines = append(lines, shellWarnStyle.Render("读取历史…"))
+	}
+	if h.log == nil {
+		return wrapLines(width, lines)
+	}
+	if len(h.log.Commits) == 0 {
+		lines = append(lines, shellMutedStyle.Render("分支 "+fallbackValue(h.log.Branch, "<默认>")+" 上没有改动过它的提交"))
+		return wrapLines(width, lines)
+	}
+	if h.confirming {
+		commit, _ := m.cursorHistoryCommit()
+		lines = append(lines, shellWarnStyle.Render(fmt.Sprintf("把 %s 版本写回本地缓存？之后 push 才会进入 vault。y 确认 · n 取消", shortCommitLabel(commit.Hash))))
+	}
+	if h.restoring {
+		lines = append(lines, shellWarnStyle.Render("正在恢复…"))
+	}
+	// 列表超出高度时按光标滚动，保证光标行可见。
+	budget := height - wrappedHeight(width, lines)
+	if budget < 1 {
+		budget = 1
+	}
+	start := 0
+	if h.cursor >= budget {
+		start = h.cursor - budget + 1
+	}
+	end := start + budget
+	if end > len(h.log.Commits) {
+		end = len(h.log.Commits)
+	}
+	for i := start; i < end; i++ {
+		commit := h.log.Commits[i]
+		mark := "  "
+		if commit.Hash == h.mark {
+			mark = "◆ "
+		}
+		line := fitLine(fmt.Sprintf("%s%s  %s  %-12s  %s", mark, shortCommitLabel(commit.Hash),
+			commit.Time.Local().Format("2006-01-02 15:04"), commit.Author, commit.Subject), width)
+		if i == h.cursor {
+			lines = append(lines, shellSelectedRow.Render(line))
+		} else {
+			lines = append(lines, shellLogStyle.Render(line))
+		}
+	}
+	return wrapLines(width, lines)
+}
+
+// renderRemoteHistoryDetail 展开提交详情或版本比较：说明、逐文件状态与 diff。
+func renderRemoteHistoryDetail(result *app.AssetHistoryResult) []string {
+	var lines []string
+	if commit := result.Commit; commit != nil {
+		lines = append(lines,
+			shellTitleStyle.Render("提交 "+shortCommitLabel(commit.Hash)),
+			fmt.Sprintf("作者  %s <%s> · %s", commit.Author, commit.Email, commit.Time.Local().Format("2006-01-02 15:04")),
+		)
+		for _, line := range strings.Split(commit.Message, "\n") {
+			lines = append(lines, "  "+line)
+		}
+	} else {
+		lines = append(lines, shellTitleStyle.Render(fmt.Sprintf("比较 %s → %s", shortCommitLabel(result.From), shortCommitLabel(result.To))))
+	}
+	if len(result.Changes) == 0 {
+		return append(lines, shellMutedStyle.Render("没有文件改动"))
+	}
+	for _, change := range result.Changes {
+		lines = append(lines, fmt.Sprintf("%s %s", pushChangeSymbol(change.Status), change.Path))
+		lines = append(lines, renderPushDiff(change)...)
+	}
+	return lines
+}
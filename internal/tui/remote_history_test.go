@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shichao402/Dec/internal/app"
+)
+
+func TestRemoteHistory_BrowseDiffAndRestore(t *testing.T) {
+	oldLoad := loadAssetHistoryOperation
+	oldRestore := restoreAssetRevisionOperation
+	defer func() {
+		loadAssetHistoryOperation = oldLoad
+		restoreAssetRevisionOperation = oldRestore
+	}()
+	commits := []app.VaultCommit{
+		{Hash: "cccccccccccccccc", Author: "bob", Time: time.Unix(1700000200, 0), Subject: "push(demo): alpha v3"},
+		{Hash: "bbbbbbbbbbbbbbbb", Author: "amy", Time: time.Unix(1700000100, 0), Subject: "push(demo): alpha v2"},
+		{Hash: "aaaaaaaaaaaaaaaa", Author: "amy", Time: time.Unix(1700000000, 0), Subject: "initial"},
+	}
+	var queries []app.AssetHistoryQuery
+	loadAssetHistoryOperation = func(ctx context.Context, workspace app.Workspace, query app.AssetHistoryQuery, reporter app.Reporter) (*app.AssetHistoryResult, error) {
+		queries = append(queries, query)
+		if query.Revision != "" || query.From != "" {
+			return &app.AssetHistoryResult{From: query.From, To: query.Revision, Changes: []app.VaultFileChange{
+				{Bundle: "combo", Path: "bundles/combo/rules/alpha.mdc", Status: app.RenderedFileModified, Diff: "@@ -1 +1 @@\n-alpha v1\n+alpha v2\n"},
+			}}, nil
+		}
+		return &app.AssetHistoryResult{Bundle: query.Bundle, Type: query.Type, Name: query.Name, Commits: commits}, nil
+	}
+	var restored app.AssetHistoryQuery
+	restoreAssetRevisionOperation = func(ctx context.Context, workspace app.Workspace, query app.AssetHistoryQuery, reporter app.Reporter) (*app.AssetRestoreResult, error) {
+		restored = query
+		return &app.AssetRestoreResult{Restored: []string{"combo/rules/alpha.mdc"}}, nil
+	}
+
+	m := newModel(t.TempDir(), "v1")
+	m.pages = []string{"Remote"}
+	m.pageIndex = 0
+	m.focus = focusContent
+	m.width, m.height = 120, 40
+	m.deleteCandidates = []app.DeleteCandidate{
+		{Kind: app.DeleteKindDecAsset, Type: "rule", Name: "alpha", Vault: "combo", Label: "[rule] alpha / combo",
+			Partition: app.PartitionRemote, TreeRoot: ".dec", TreeBranch: "combo"},
+	}
+	m.deleteCandidatesLoaded = true
+	m.rebuildDeleteTree()
+	// 逐层展开到资产叶子并把光标停在上面。
+	for i := 0; i < len(m.deleteTree.VisibleRows()); i++ {
+		m.deleteTree.Cursor = i
+		if _, ok := m.deleteTree.VisibleRows()[i].Node.Payload.(int); ok {
+			break
+		}
+		m.deleteTree.ExpandAtCursor()
+	}
+	press := func(key tea.KeyMsg) {
+		t.Helper()
+		updated, cmd := m.Update(key)
+		m = updated.(model)
+		for cmd != nil {
+			msg := cmd()
+			if msg == nil {
+				return
+			}
+			updated, cmd = m.Update(msg)
+			m = updated.(model)
+		}
+	}
+	runes := func(r rune) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}} }
+
+	press(runes('H'))
+	if !m.remoteHistory.open || len(queries) != 1 || queries[0] != (app.AssetHistoryQuery{Bundle: "combo", Type: "rule", Name: "alpha"}) {
+		t.Fatalf("H 应按光标资产查询历史: open=%v queries=%+v", m.remoteHistory.open, queries)
+	}
+	view := m.renderRemoteHistory(120, 30)
+	if !strings.Contains(view, "[rule] alpha / combo") || !strings.Contains(view, "alpha v3") || !strings.Contains(view, "initial") {
+		t.Fatalf("历史视图应列出提交:\n%s", view)
+	}
+
+	// Enter 展开光标提交的 diff，Esc 回到列表。
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if queries[len(queries)-1].Revision != "bbbbbbbbbbbbbbbb" || m.remoteHistory.detail == nil {
+		t.Fatalf("Enter 应加载光标提交详情: %+v", queries[len(queries)-1])
+	}
+	if view := m.renderRemoteHistory(120, 30); !strings.Contains(view, "+alpha v2") {
+		t.Fatalf("详情应展示 diff:\n%s", view)
+	}
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.remoteHistory.detail != nil || !m.remoteHistory.open {
+		t.Fatal("Esc 应从详情回到提交列表")
+	}
+
+	// m 标记基准，移到另一提交后 D 比较两者。
+	press(runes('m'))
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(runes('D'))
+	if got := queries[len(queries)-1]; got.From != "bbbbbbbbbbbbbbbb" || got.To != "aaaaaaaaaaaaaaaa" {
+		t.Fatalf("D 应比较标记与光标: %+v", got)
+	}
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+
+	// R 需要 y 确认才写本地缓存。
+	press(runes('R'))
+	if restored.Revision != "" || !strings.Contains(m.renderRemoteHistory(120, 30), "y 确认") {
+		t.Fatal("R 应先要求确认")
+	}
+	press(runes('y'))
+	if restored.Revision != "aaaaaaaaaaaaaaaa" || restored.Name != "alpha" {
+		t.Fatalf("恢复参数 = %+v", restored)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.remoteHistory.open {
+		t.Fatal("列表 Esc 应关闭历史视图")
+	}
+}
@@ -43,6 +43,8 @@ func (m model) ioBusyLabel() string {
 			return "Refreshing remote list…"
 		}
 		return "Loading remote list…"
+	case m.switchingVarsProfile:
+		return "Switching vars profile…"
 	case m.projectVarsLoad.busy():
 		return "Reloading project vars…"
 	case m.globalVarsLoad.busy():
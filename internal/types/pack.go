@@ -1,5 +1,7 @@
 package types
 
+import "sort"
+
 // IDEsConfig 表示 IDE 配置
 type IDEsConfig struct {
 	IDEs []string `yaml:"ides,omitempty" json:"ides,omitempty"`
@@ -15,6 +17,9 @@ type MCPConfig struct {
 }
 
 // MCPServer 表示单个 MCP Server 配置
+//
+// Type（stdio | http | sse）与 Headers 是 Cursor / Claude / VS Code JSON 配置里远端 server 的写法，
+// Codex 对应的是 URL + HTTPHeaders / BearerTokenEnvVar。
 type MCPServer struct {
 	Command           string            `json:"command,omitempty"`
 	Args              []string          `json:"args,omitempty"`
@@ -32,6 +37,8 @@ type MCPServer struct {
 	EnabledTools      []string          `json:"enabled_tools,omitempty"`
 	DisabledTools     []string          `json:"disabled_tools,omitempty"`
 	Scopes            []string          `json:"scopes,omitempty"`
+	Type              string            `json:"type,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
 	Extra             map[string]any    `json:"-"`
 }
 
@@ -44,8 +51,83 @@ type GlobalConfig struct {
 	// EnabledBundles 是用户平面启用的 bundle 短名列表（ADR 0009）。
 	// 仅应包含 scope: user 的包；与 ProjectConfig.EnabledBundles 字段同名同语义。
 	EnabledBundles []string `yaml:"enabled_bundles,omitempty"`
+	// InstallMode 是用户平面的默认安装方式（copy | symlink）；项目配置可覆盖。
+	InstallMode string `yaml:"install_mode,omitempty"`
+	// GitBackend 选择仓库操作的实现（auto | git | go-git），空串等同 auto：
+	// 有系统 git 时用 git，否则回落纯 Go 实现。环境变量 DEC_GIT_BACKEND 优先。
+	GitBackend string `yaml:"git_backend,omitempty"`
+	// Offline 是离线模式（auto | on | off），空串等同 auto：远端不可达时读本地 bare repo、push 排队。
+	// 环境变量 DEC_OFFLINE 优先。
+	Offline string `yaml:"offline,omitempty"`
+	// VaultBranch 是默认跟随的 vault 分支（频道，如 stable / next）；空串表示跟随远端默认分支。
+	// 项目配置可覆盖。
+	VaultBranch string `yaml:"vault_branch,omitempty"`
+	// PushPolicy 是推送到 repo_url 这个 vault 的方式（direct | branch），空串等同 direct。
+	PushPolicy string `yaml:"push_policy,omitempty"`
+	// CommitTemplate 是 push 提交说明的 text/template 模板，空串使用内置默认模板。
+	CommitTemplate string `yaml:"commit_template,omitempty"`
+	// CommitSigning 是 Dec 自己产生的 vault 提交的签名方式。
+	CommitSigning CommitSigningConfig `yaml:"commit_signing,omitempty"`
+	// CommitTrust 是 repo_url 这个 vault 的签名信任策略。只放在本机配置里：
+	// 写进 vault 的信任列表会被攻破的远端一并改掉。
+	CommitTrust CommitTrustConfig `yaml:"commit_trust,omitempty"`
 }
 
+// CommitSigningConfig 是 push 提交的签名配置。
+type CommitSigningConfig struct {
+	// Mode 取值 off | git | ssh，空串等同 off。
+	// git 交给系统 git 按 git config 签名（GPG 或 SSH）；ssh 用 Key 指定的私钥签名，两个 git 后端都支持。
+	Mode string `yaml:"mode,omitempty"`
+	// Key 是 ssh 模式的私钥文件（支持 ~），可以是 secrets bundle 落地的专用 key；不能带口令。
+	Key string `yaml:"key,omitempty"`
+}
+
+// CommitTrustConfig 是 pull 时对 vault 提交签名的校验策略。
+type CommitTrustConfig struct {
+	// Policy 取值 off | warn | enforce；空串时配置了 Signers 即为 warn，否则 off。
+	Policy string `yaml:"policy,omitempty"`
+	// Signers 是允许的签名者：一行 SSH 公钥，或公钥文件路径（.pub / armored GPG 公钥）。
+	Signers []string `yaml:"signers,omitempty"`
+	// Anchor 是人工审计过的提交：没有上次拉取记录时只校验它之后的提交，早于签名启用的历史不必重签。
+	Anchor string `yaml:"anchor,omitempty"`
+}
+
+// CommitTrust.Policy 取值：pull 遇到未签名或签名者不受信任的提交时怎么办。
+const (
+	// CommitTrustOff 不校验签名（默认）。
+	CommitTrustOff = "off"
+	// CommitTrustWarn 照常拉取，但给出告警。
+	CommitTrustWarn = "warn"
+	// CommitTrustEnforce 拒绝拉取。
+	CommitTrustEnforce = "enforce"
+)
+
+// PushPolicy 取值：push 把提交送到 vault 的哪里。
+const (
+	// PushPolicyDirect 直接推到跟随的 vault 分支（默认）。
+	PushPolicyDirect = "direct"
+	// PushPolicyBranch 推到新的评审分支 dec/<user>/<project>/<timestamp>，合并后才对他人可见。
+	PushPolicyBranch = "branch"
+	// ReviewBranchPrefix 是 branch 策略下评审分支的公共前缀。
+	ReviewBranchPrefix = "dec/"
+)
+
+// InstallMode 取值：IDE 目录里的 skill / command / rule 以何种方式落地。
+const (
+	// InstallModeCopy 为每个 IDE 各写一份渲染副本（默认）。
+	InstallModeCopy = "copy"
+	// InstallModeSymlink 只在 .dec/rendered/ 下保留一份渲染结果，IDE 目录放相对符号链接。
+	InstallModeSymlink = "symlink"
+)
+
+// VaultBranchSource 取值：解析后的 vault_branch 来自哪一层配置。
+const (
+	VaultBranchSourceProject = "project"
+	VaultBranchSourceGlobal  = "global"
+	// VaultBranchSourceDefault 表示未配置，跟随远端默认分支。
+	VaultBranchSourceDefault = "default"
+)
+
 const ProjectConfigVersionV2 = "v2"
 
 // VaultProjectsDir 是 Git Vault 中 project 声明目录。
@@ -57,9 +139,27 @@ const VaultBundlesDir = "bundles"
 // BundleManifestFileName 是每个 bundle 目录内的声明文件名。
 const BundleManifestFileName = "bundle.yaml"
 
+// BundleVarsFileName 是 bundle 目录内的共享变量默认值文件名（格式同 .dec/vars.yaml 的 vars 段）。
+const BundleVarsFileName = "vars.yaml"
+
 // VaultProjectFileExt 是 project 声明文件扩展名。
 const VaultProjectFileExt = ".yaml"
 
+// VaultMetaFileName 是 vault 根目录的格式声明文件。
+const VaultMetaFileName = ".dec-vault.yaml"
+
+// VaultFormatCurrent 是本版本 Dec 读写的 vault 格式版本。
+// 没有 .dec-vault.yaml 的 vault 视为格式 0；格式更高的 vault 本版本只读不写。
+const VaultFormatCurrent = 2
+
+// VaultMeta 描述 vault 根目录 .dec-vault.yaml：
+//
+//	format: 2
+type VaultMeta struct {
+	// Format 是 vault 布局的格式版本，由 vault 迁移写入。
+	Format int `yaml:"format"`
+}
+
 // Project 描述 vault 中 projects/<name>.yaml 的项目声明。
 //
 // Wire format 示例（projects/my-app.yaml）：
@@ -71,6 +171,9 @@ const VaultProjectFileExt = ".yaml"
 //	  - helloworld
 //	ides:
 //	  - cursor
+//	vault_branch: stable
+//	vars:
+//	  JIRA_BASE_URL: https://jira.example.com
 type Project struct {
 	// Name 为 project 短名，与文件名 projects/<name>.yaml 一致。
 	Name string `yaml:"name"`
@@ -82,6 +185,10 @@ type Project struct {
 	IDEs []string `yaml:"ides,omitempty"`
 	// Editor 为该项目默认交互式编辑器；本地可覆盖。
 	Editor string `yaml:"editor,omitempty"`
+	// VaultBranch 为该项目默认跟随的 vault 分支；应用 project 时写入本地配置，本地可覆盖。
+	VaultBranch string `yaml:"vault_branch,omitempty"`
+	// Vars 是随 vault 共享的项目级变量默认值，优先级低于一切本地 vars 文件。
+	Vars map[string]string `yaml:"vars,omitempty"`
 }
 
 // VaultProjectPath 返回 vault 内 project 声明的相对路径。
@@ -99,6 +206,11 @@ func VaultBundleManifestPath(name string) string {
 	return VaultBundleDir(name) + "/" + BundleManifestFileName
 }
 
+// VaultBundleVarsPath 返回 bundle 共享变量默认值文件的相对路径。
+func VaultBundleVarsPath(name string) string {
+	return VaultBundleDir(name) + "/" + BundleVarsFileName
+}
+
 // ProjectConfig 项目配置 (<project>/.dec/config.yaml)
 type ProjectConfig struct {
 	Version string `yaml:"version,omitempty"`
@@ -110,6 +222,12 @@ type ProjectConfig struct {
 	// EnabledBundles 是本项目启用的 bundle 短名列表，也是唯一的资产启用入口。
 	// 早期版本支持的单资产粒度（available / enabled）已移除，加载旧配置时会折叠成 bundle 引用。
 	EnabledBundles []string `yaml:"enabled_bundles,omitempty"`
+	// InstallMode 覆盖全局配置的 install_mode（copy | symlink），空串表示沿用全局。
+	InstallMode string `yaml:"install_mode,omitempty"`
+	// VaultBranch 覆盖全局配置的 vault_branch：pull / push / 陈旧度检查都跟随该分支，空串表示沿用全局。
+	VaultBranch string `yaml:"vault_branch,omitempty"`
+	// ActiveProfile 选中 .dec/vars.yaml 中 profiles 下的一组变量，空串表示不启用 profile。
+	ActiveProfile string `yaml:"active_profile,omitempty"`
 }
 
 // BundleScope 是 bundle 的二元作用域（ADR 0009）。
@@ -131,6 +249,8 @@ const (
 //	  - mcp/vikunja-mcp
 //	  - rules/vikunja-integration
 //	  - skills/vikunja-workflow
+//	templates:
+//	  - rules/vikunja-integration
 //
 // 成员资产须位于同一 bundles/<name>/ 目录内；成员只能是 skill/command/rule/mcp（不能是 bundle）。
 type Bundle struct {
@@ -142,6 +262,9 @@ type Bundle struct {
 	Description string `yaml:"description,omitempty"`
 	// Members 列出 bundle 的成员资产，格式为 <type>/<asset-name>。
 	Members []string `yaml:"members"`
+	// Templates 列出改用 text/template 渲染的资产（格式同 members，解析后规范为 <type>/<name>）。
+	// 单个 Markdown 资产也可在 frontmatter 写 dec_template: true 自行声明。
+	Templates []string `yaml:"templates,omitempty"`
 }
 
 // BundleMember 是解析后的 bundle 成员引用。
@@ -168,6 +291,35 @@ type TypedAssetRef struct {
 type VarsConfig struct {
 	Vars   map[string]string `yaml:"vars,omitempty"`
 	Assets *AssetVars        `yaml:"assets,omitempty"`
+	// Profiles 是命名变量组（如 dev / staging / prod），由 .dec/config.yaml 的 active_profile 选中一个
+	Profiles map[string]VarsProfile `yaml:"profiles,omitempty"`
+}
+
+// VarsProfile 是单个命名 profile 的变量
+type VarsProfile struct {
+	Vars map[string]string `yaml:"vars,omitempty"`
+}
+
+// ProfileVars 返回指定 profile 的变量；name 为空或未定义时 ok=false
+func (c *VarsConfig) ProfileVars(name string) (map[string]string, bool) {
+	if c == nil || name == "" {
+		return nil, false
+	}
+	profile, ok := c.Profiles[name]
+	return profile.Vars, ok
+}
+
+// ProfileNames 按字典序返回已定义的 profile 名
+func (c *VarsConfig) ProfileNames() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // AssetVars 按资产类型和名称限定的变量
@@ -0,0 +1,113 @@
+package vars
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/shichao402/Dec/internal/sysproc"
+)
+
+// 内置计算变量：由 Dec 根据当前上下文算出，优先级低于一切用户定义（同名定义会覆盖）。
+const (
+	BuiltinProjectRoot = "DEC_PROJECT_ROOT"
+	BuiltinProjectName = "DEC_PROJECT_NAME"
+	BuiltinBundle      = "DEC_BUNDLE"
+	BuiltinIDE         = "DEC_IDE"
+	BuiltinOS          = "DEC_OS"
+	BuiltinArch        = "DEC_ARCH"
+	BuiltinHome        = "DEC_HOME"
+	BuiltinGitRemote   = "DEC_GIT_REMOTE"
+	BuiltinGitBranch   = "DEC_GIT_BRANCH"
+)
+
+// BuiltinNames 按展示顺序列出全部内置变量名
+func BuiltinNames() []string {
+	return []string{
+		BuiltinProjectRoot,
+		BuiltinProjectName,
+		BuiltinBundle,
+		BuiltinIDE,
+		BuiltinOS,
+		BuiltinArch,
+		BuiltinHome,
+		BuiltinGitRemote,
+		BuiltinGitBranch,
+	}
+}
+
+// IsBuiltin 判断变量名是否为保留的内置变量
+func IsBuiltin(name string) bool {
+	for _, builtin := range BuiltinNames() {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPerOutputBuiltin 判断内置变量是否随每份输出（bundle / IDE）变化，而不是整个项目一个值
+func IsPerOutputBuiltin(name string) bool {
+	return name == BuiltinBundle || name == BuiltinIDE
+}
+
+// BuiltinContext 是计算内置变量所需的上下文
+type BuiltinContext struct {
+	ProjectRoot string
+	// DecHome 是 Dec 根目录（~/.dec 或 $DEC_HOME），由调用方解析后传入
+	DecHome string
+}
+
+// BuiltinVars 计算项目级内置变量（不含 DEC_BUNDLE / DEC_IDE，见 WithBuiltinScope）。
+// git 信息取不到（非 git 仓库、无 origin、detached HEAD）时不设置对应变量，模板可用 default 兜底。
+func BuiltinVars(ctx BuiltinContext) map[string]string {
+	result := map[string]string{
+		BuiltinOS:   runtime.GOOS,
+		BuiltinArch: runtime.GOARCH,
+	}
+	if root := strings.TrimSpace(ctx.ProjectRoot); root != "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		result[BuiltinProjectRoot] = root
+		result[BuiltinProjectName] = filepath.Base(root)
+		if remote := gitOutput(root, "config", "--get", "remote.origin.url"); remote != "" {
+			result[BuiltinGitRemote] = remote
+		}
+		if branch := gitOutput(root, "symbolic-ref", "--short", "-q", "HEAD"); branch != "" {
+			result[BuiltinGitBranch] = branch
+		}
+	}
+	if home := strings.TrimSpace(ctx.DecHome); home != "" {
+		result[BuiltinHome] = home
+	}
+	return result
+}
+
+// WithBuiltinScope 在项目级内置变量上补上当前输出对应的 DEC_BUNDLE / DEC_IDE，返回新映射
+func WithBuiltinScope(builtins map[string]string, bundle, ide string) map[string]string {
+	result := make(map[string]string, len(builtins)+2)
+	for k, v := range builtins {
+		result[k] = v
+	}
+	if bundle != "" {
+		result[BuiltinBundle] = bundle
+	}
+	if ide != "" {
+		result[BuiltinIDE] = ide
+	}
+	return result
+}
+
+func gitOutput(dir string, args ...string) string {
+	if _, err := os.Stat(dir); err != nil {
+		return ""
+	}
+	cmd := sysproc.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
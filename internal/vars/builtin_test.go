@@ -0,0 +1,72 @@
+package vars
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/shichao402/Dec/internal/types"
+)
+
+func TestBuiltinVarsFromGitProject(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git 不可用")
+	}
+	root := filepath.Join(t.TempDir(), "demo-project")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", root},
+		{"-C", root, "remote", "add", "origin", "git@example.com:team/demo.git"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v 失败: %v\n%s", args, err, out)
+		}
+	}
+
+	got := BuiltinVars(BuiltinContext{ProjectRoot: root, DecHome: "/home/u/.dec"})
+	want := map[string]string{
+		BuiltinProjectRoot: root,
+		BuiltinProjectName: "demo-project",
+		BuiltinOS:          runtime.GOOS,
+		BuiltinArch:        runtime.GOARCH,
+		BuiltinHome:        "/home/u/.dec",
+		BuiltinGitRemote:   "git@example.com:team/demo.git",
+		BuiltinGitBranch:   "main",
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+	if _, ok := got[BuiltinIDE]; ok {
+		t.Errorf("项目级内置变量不应包含 %s", BuiltinIDE)
+	}
+
+	scoped := WithBuiltinScope(got, "team", "cursor")
+	if scoped[BuiltinBundle] != "team" || scoped[BuiltinIDE] != "cursor" {
+		t.Errorf("WithBuiltinScope() = %v", scoped)
+	}
+	if _, ok := got[BuiltinIDE]; ok {
+		t.Errorf("WithBuiltinScope 不应修改原映射")
+	}
+}
+
+func TestBuiltinVarsOutsideGitOmitsGitVars(t *testing.T) {
+	got := BuiltinVars(BuiltinContext{ProjectRoot: t.TempDir()})
+	if _, ok := got[BuiltinGitRemote]; ok {
+		t.Errorf("非 git 目录不应有 %s: %v", BuiltinGitRemote, got)
+	}
+	if _, ok := got[BuiltinGitBranch]; ok {
+		t.Errorf("非 git 目录不应有 %s: %v", BuiltinGitBranch, got)
+	}
+}
+
+func TestResolveLayeredVarsBuiltinsLowestPriority(t *testing.T) {
+	global := &types.VarsConfig{Vars: map[string]string{"DEC_OS": "from-global"}}
+	builtins := map[string]string{"DEC_OS": "linux", "DEC_ARCH": "amd64"}
+	got := ResolveLayeredVars(Layers{Global: global, Builtins: builtins}, "rule", "x", []string{"DEC_OS", "DEC_ARCH", "OTHER"})
+	want := map[string]string{"DEC_OS": "from-global", "DEC_ARCH": "amd64"}
+	if len(got) != len(want) || got["DEC_OS"] != want["DEC_OS"] || got["DEC_ARCH"] != want["DEC_ARCH"] {
+		t.Errorf("ResolveLayeredVars() = %v, want %v", got, want)
+	}
+}
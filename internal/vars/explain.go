@@ -0,0 +1,58 @@
+package vars
+
+// Layer 是参与解释的一层变量定义。同一来源可拆成多层（如 vars.yaml 与各 vars.d 片段），
+// 以便指出具体文件；File 为空表示该层不来自文件（内置变量）。
+type Layer struct {
+	Source string
+	File   string
+	Vars   map[string]string
+}
+
+// Candidate 是某个变量在一层中的定义
+type Candidate struct {
+	Source string
+	File   string
+	Value  string
+}
+
+// Explanation 描述单个变量的解析过程：Winner 为生效的定义（nil 表示缺失），
+// Shadowed 按优先级列出被它遮蔽的其余定义。
+type Explanation struct {
+	Name     string
+	Winner   *Candidate
+	Shadowed []Candidate
+}
+
+// Explain 按 ordered（优先级从高到低）逐层查找 names 中的每个变量，返回顺序与 names 一致。
+// 层的排列须与 ResolveLayers 一致，否则解释结果会与实际渲染不符。
+// 与 Substitute 相同，最高层的值为空而模板给了 default（SourceDefault 层）时由 default 生效，空值列入 Shadowed。
+func Explain(ordered []Layer, names []string) []Explanation {
+	result := make([]Explanation, 0, len(names))
+	for _, name := range names {
+		explanation := Explanation{Name: name}
+		var candidates []Candidate
+		for _, layer := range ordered {
+			if value, ok := layer.Vars[name]; ok {
+				candidates = append(candidates, Candidate{Source: layer.Source, File: layer.File, Value: value})
+			}
+		}
+		winner := 0
+		if len(candidates) > 0 && candidates[0].Value == "" {
+			for i, candidate := range candidates {
+				if candidate.Source == SourceDefault {
+					winner = i
+					break
+				}
+			}
+		}
+		for i := range candidates {
+			if i == winner {
+				explanation.Winner = &candidates[i]
+				continue
+			}
+			explanation.Shadowed = append(explanation.Shadowed, candidates[i])
+		}
+		result = append(result, explanation)
+	}
+	return result
+}
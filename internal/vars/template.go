@@ -0,0 +1,137 @@
+package vars
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateFrontmatterKey 是资产在 frontmatter 中声明走 text/template 渲染的键：
+//
+//	---
+//	name: deploy
+//	dec_template: true
+//	---
+//
+// 未声明（且 bundle.yaml 的 templates 未列出）的资产继续使用 {{VAR}} 占位符语法。
+const TemplateFrontmatterKey = "dec_template"
+
+// maxTemplateOutput 限制单个文件的渲染结果，防止 range 之类的构造生成失控的输出
+const maxTemplateOutput = 4 << 20
+
+// TemplateData 是 text/template 渲染时的数据上下文（模板中以 .Vars / .IDE 等访问）
+type TemplateData struct {
+	// Vars 是该资产可见的全部变量（各层按优先级合并后的结果，含内置变量）
+	Vars map[string]string
+	// IDE 是本份输出对应的 IDE
+	IDE string
+	// Bundle 是资产所属 bundle；Bundles 是当前平面启用的全部 bundle
+	Bundle  string
+	Bundles []string
+	// Plane 为 project 或 user
+	Plane string
+}
+
+// templateFuncs 是沙箱函数表：只含纯字符串 / 列表函数，不能读文件、环境变量或执行命令。
+// 数据上下文也只有字符串与字符串列表，text/template 内置的 call 没有可调用的对象。
+func templateFuncs() template.FuncMap {
+	filter := func(name string) func(string) string {
+		return func(value string) string { return applyFilters(value, []string{name}) }
+	}
+	return template.FuncMap{
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"json":      filter("json"),
+		"shell":     filter("shell"),
+		"path":      filter("path"),
+		"trim":      strings.TrimSpace,
+		"replace":   strings.ReplaceAll,
+		"split":     strings.Split,
+		"join":      func(sep string, items []string) string { return strings.Join(items, sep) },
+		"contains":  strings.Contains,
+		"hasPrefix": strings.HasPrefix,
+		"hasSuffix": strings.HasSuffix,
+		"has": func(items []string, item string) bool {
+			for _, candidate := range items {
+				if candidate == item {
+					return true
+				}
+			}
+			return false
+		},
+		"default": func(def, value string) string {
+			if value == "" {
+				return def
+			}
+			return value
+		},
+	}
+}
+
+// RenderTemplate 以 text/template 渲染 content。name 用于错误信息，
+// 解析与执行错误都带 "name:行号"，调用方应传入用户能找到的模板路径。
+// 引用未定义的 .Vars.NAME 会报错；可选变量写成 {{ index .Vars "NAME" | default "x" }}。
+func RenderTemplate(name, content string, data TemplateData) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs()).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", err
+	}
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+	out := &limitedBuffer{limit: maxTemplateOutput}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// IsTemplateOptIn 判断内容的 YAML frontmatter 是否声明了 dec_template: true
+func IsTemplateOptIn(content string) bool {
+	block, ok := Frontmatter(content)
+	if !ok {
+		return false
+	}
+	var frontmatter map[string]any
+	if err := yaml.Unmarshal([]byte(block), &frontmatter); err != nil {
+		return false
+	}
+	switch v := frontmatter[TemplateFrontmatterKey].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(strings.TrimSpace(v), "true")
+	}
+	return false
+}
+
+// Frontmatter 取出内容开头 --- 包围的 YAML 块（不含分隔行）；没有 frontmatter 时 ok 为 false
+func Frontmatter(content string) (string, bool) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return "", false
+	}
+	rest := content[strings.Index(content, "\n")+1:]
+	end := strings.Index(rest, "\n---")
+	if end < 0 || strings.HasPrefix(rest, "---") {
+		return "", false
+	}
+	return rest[:end], true
+}
+
+var errTemplateOutputTooLarge = errors.New("模板渲染结果超过 4 MiB 上限")
+
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errTemplateOutputTooLarge
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,62 @@
+package vars
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplateConditionalsAndLoops(t *testing.T) {
+	content := `{{if eq .IDE "codex"}}codex only
+{{end}}{{range .Bundles}}- {{.}}
+{{end}}url={{.Vars.API_URL | lower}} opt={{index .Vars "MISSING" | default "none"}} plane={{.Plane}}{{if has .Bundles "team"}} team{{end}}`
+	data := TemplateData{
+		Vars:    map[string]string{"API_URL": "HTTPS://API"},
+		IDE:     "codex",
+		Bundles: []string{"team", "ops"},
+		Plane:   "project",
+	}
+	got, err := RenderTemplate("rules/deploy.mdc", content, data)
+	if err != nil {
+		t.Fatalf("RenderTemplate() 失败: %v", err)
+	}
+	want := "codex only\n- team\n- ops\nurl=https://api opt=none plane=project team"
+	if got != want {
+		t.Fatalf("RenderTemplate() = %q, want %q", got, want)
+	}
+
+	data.IDE = "cursor"
+	if got, _ := RenderTemplate("rules/deploy.mdc", content, data); strings.Contains(got, "codex only") {
+		t.Fatalf("非 codex 不应包含条件段: %q", got)
+	}
+}
+
+func TestRenderTemplateErrorsReportFileAndLine(t *testing.T) {
+	_, err := RenderTemplate(".dec/cache/team/rules/deploy.mdc", "line1\nline2 {{.Vars.NOPE}}\n", TemplateData{})
+	if err == nil || !strings.Contains(err.Error(), ".dec/cache/team/rules/deploy.mdc:2") {
+		t.Fatalf("未定义变量应带文件与行号报错, got %v", err)
+	}
+	_, err = RenderTemplate("skills/x/SKILL.md", "a\nb\n{{env \"HOME\"}}\n", TemplateData{})
+	if err == nil || !strings.Contains(err.Error(), "skills/x/SKILL.md:3") {
+		t.Fatalf("沙箱外函数应在解析时报错并带行号, got %v", err)
+	}
+	_, err = RenderTemplate("big", "{{range 100000000}}xxxxxxxx{{end}}", TemplateData{})
+	if err == nil {
+		t.Fatal("超大输出应报错")
+	}
+}
+
+func TestIsTemplateOptIn(t *testing.T) {
+	cases := map[string]bool{
+		"---\nname: x\ndec_template: true\n---\nbody": true,
+		"---\ndec_template: \"true\"\n---\n":          true,
+		"---\nname: x\n---\ndec_template: true\n":     false,
+		"---\ndec_template: false\n---\n":             false,
+		"dec_template: true\n":                        false,
+		"---\n---\n---\ndec_template: true\n---\n":    false,
+	}
+	for content, want := range cases {
+		if got := IsTemplateOptIn(content); got != want {
+			t.Fatalf("IsTemplateOptIn(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
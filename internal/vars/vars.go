@@ -1,21 +1,110 @@
 // Package vars 提供占位符替换逻辑
 // Vault 模板中使用 {{VAR_NAME}} 占位符，pull 时替换为实际值
+//
+// 完整语法：
+//
+//	{{NAME}}                      变量值
+//	{{NAME|default:"x"}}          变量未定义或为空时取 x（不再报告缺失）
+//	{{NAME|lower|json}}           依次应用过滤器：lower、upper、json、shell、path（未知过滤器由 CheckFilters 报错）
+//	\{{NAME}}                     转义：原样输出 {{NAME}}（去掉反斜杠），用于在资产里描述模板本身
 package vars
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/shichao402/Dec/internal/types"
 	"gopkg.in/yaml.v3"
 )
 
-// placeholderRe 匹配 {{VAR_NAME}} 占位符
+// placeholderRe 匹配 {{VAR_NAME}} 占位符（可带过滤器，可被反斜杠转义）
 // 变量名规则：大写字母开头，由大写字母、数字、下划线组成
-var placeholderRe = regexp.MustCompile(`\{\{([A-Z][A-Z0-9_]*)\}\}`)
+// 未知过滤器不匹配，整段按普通文本保留，由 CheckFilters 报告
+var placeholderRe = regexp.MustCompile(`(\\?)\{\{([A-Z][A-Z0-9_]*)((?:\s*\|\s*(?:lower|upper|json|shell|path|default:"(?:[^"\\]|\\.)*"))*)\}\}`)
+
+// filterRe 从占位符的过滤器部分逐个取出过滤器
+var filterRe = regexp.MustCompile(`\|\s*(lower|upper|json|shell|path|default:("(?:[^"\\]|\\.)*"))\s*`)
+
+// filteredPlaceholderRe 匹配任意带过滤器的 {{NAME|...}}，用来找出 placeholderRe 不认的写法
+var filteredPlaceholderRe = regexp.MustCompile(`(\\?)\{\{[A-Z][A-Z0-9_]*\s*\|[^{}]*\}\}`)
+
+// Placeholder 是解析后的单个占位符
+type Placeholder struct {
+	Name       string
+	Filters    []string // 不含 default，按书写顺序
+	Default    string
+	HasDefault bool
+}
+
+// parsePlaceholder 解析 placeholderRe 的一次匹配；escaped 为 true 表示是转义形式
+func parsePlaceholder(sub []string) (p Placeholder, escaped bool) {
+	p.Name = sub[2]
+	for _, f := range filterRe.FindAllStringSubmatch(sub[3], -1) {
+		if f[2] != "" {
+			if !p.HasDefault {
+				if def, err := strconv.Unquote(f[2]); err == nil {
+					p.Default = def
+				} else {
+					p.Default = f[2][1 : len(f[2])-1]
+				}
+				p.HasDefault = true
+			}
+			continue
+		}
+		p.Filters = append(p.Filters, f[1])
+	}
+	return p, sub[1] != ""
+}
+
+// ParsePlaceholders 按出现顺序返回文本中的占位符（不去重，不含转义形式）
+func ParsePlaceholders(content string) []Placeholder {
+	var result []Placeholder
+	for _, sub := range placeholderRe.FindAllStringSubmatch(content, -1) {
+		if p, escaped := parsePlaceholder(sub); !escaped {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// applyFilters 依次应用过滤器
+func applyFilters(value string, filters []string) string {
+	for _, filter := range filters {
+		switch filter {
+		case "lower":
+			value = strings.ToLower(value)
+		case "upper":
+			value = strings.ToUpper(value)
+		case "json":
+			data, _ := json.Marshal(value)
+			value = string(data)
+		case "shell":
+			value = "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+		case "path":
+			value = expandPath(value)
+		}
+	}
+	return value
+}
+
+// expandPath 展开开头的 ~，并按当前系统规范化分隔符
+func expandPath(value string) string {
+	if value == "" {
+		return value
+	}
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			value = home + value[1:]
+		}
+	}
+	return filepath.Clean(filepath.FromSlash(value))
+}
 
 // LoadVarsFile 从指定路径加载变量定义文件
 // 文件不存在时返回空配置而非错误
@@ -43,38 +132,140 @@ func ResolveVars(
 	assetName string,
 	placeholders []string,
 ) map[string]string {
-	result := make(map[string]string)
+	return ResolveLayeredVars(Layers{Project: projectVars, Global: globalVars}, assetType, assetName, placeholders)
+}
+
+// 变量来源层名，按优先级从高到低
+const (
+	SourceAsset        = "asset"
+	SourceProfile      = "profile"
+	SourceProject      = "project"
+	SourceGlobal       = "global"
+	SourceVaultProject = "vault-project"
+	SourceVaultBundle  = "vault-bundle"
+	SourceBuiltin      = "builtin"
+	SourceDefault      = "default"
+)
+
+// Layers 汇总一次解析可用的全部变量来源，字段按优先级从高到低排列；nil 表示该层缺席
+type Layers struct {
+	// Project 是 .dec/vars.yaml（已合并 vars.d），assets 段按资产限定，优先于 Profile 与 vars 段
+	Project *types.VarsConfig
+	// Profile 是 active_profile 选中的 profiles.<name>.vars，位于 vars 段之上
+	Profile map[string]string
+	// Global 是本机 ~/.dec/local/vars.yaml
+	Global *types.VarsConfig
+	// VaultProject 是 vault projects/<name>.yaml 的 vars，随 vault 共享
+	VaultProject map[string]string
+	// VaultBundle 是资产所属 bundle 的 vault bundles/<name>/vars.yaml，随 vault 共享
+	VaultBundle map[string]string
+	// Builtins 是内置计算变量（见 BuiltinVars / WithBuiltinScope）
+	Builtins map[string]string
+}
+
+// Resolved 是单个变量的解析结果及其来源层（Source* 常量）
+type Resolved struct {
+	Value  string
+	Source string
+}
+
+// ResolveLayers 按 Layers 的优先级解析占位符，返回值及来源；未找到的占位符不放入结果
+func ResolveLayers(layers Layers, assetType, assetName string, placeholders []string) map[string]Resolved {
+	result := make(map[string]Resolved)
 
 	for _, key := range placeholders {
 		// 1. 项目级按资产限定
-		if v, ok := getAssetSpecificVar(projectVars, assetType, assetName, key); ok {
-			result[key] = v
+		if v, ok := getAssetSpecificVar(layers.Project, assetType, assetName, key); ok {
+			result[key] = Resolved{Value: v, Source: SourceAsset}
+			continue
+		}
+		// 2. 当前 profile
+		if v, ok := layers.Profile[key]; ok {
+			result[key] = Resolved{Value: v, Source: SourceProfile}
 			continue
 		}
-		// 2. 项目级全局
-		if projectVars != nil && projectVars.Vars != nil {
-			if v, ok := projectVars.Vars[key]; ok {
-				result[key] = v
+		// 3. 项目级全局
+		if layers.Project != nil && layers.Project.Vars != nil {
+			if v, ok := layers.Project.Vars[key]; ok {
+				result[key] = Resolved{Value: v, Source: SourceProject}
 				continue
 			}
 		}
-		// 3. 机器级全局
-		if globalVars != nil && globalVars.Vars != nil {
-			if v, ok := globalVars.Vars[key]; ok {
-				result[key] = v
+		// 4. 机器级全局
+		if layers.Global != nil && layers.Global.Vars != nil {
+			if v, ok := layers.Global.Vars[key]; ok {
+				result[key] = Resolved{Value: v, Source: SourceGlobal}
 				continue
 			}
 		}
+		// 5. vault 共享：project 声明比 bundle 默认值更具体
+		if v, ok := layers.VaultProject[key]; ok {
+			result[key] = Resolved{Value: v, Source: SourceVaultProject}
+			continue
+		}
+		if v, ok := layers.VaultBundle[key]; ok {
+			result[key] = Resolved{Value: v, Source: SourceVaultBundle}
+			continue
+		}
+		// 6. 内置计算变量
+		if v, ok := layers.Builtins[key]; ok {
+			result[key] = Resolved{Value: v, Source: SourceBuiltin}
+			continue
+		}
 		// 未找到：不放入 result，由调用方决定如何处理
 	}
 
 	return result
 }
 
+// ResolveLayeredVars 同 ResolveLayers，只返回变量值
+func ResolveLayeredVars(layers Layers, assetType, assetName string, placeholders []string) map[string]string {
+	resolved := ResolveLayers(layers, assetType, assetName, placeholders)
+	result := make(map[string]string, len(resolved))
+	for key, r := range resolved {
+		result[key] = r.Value
+	}
+	return result
+}
+
+// LayerNames 返回某资产在各层可见的全部变量名（去重、排序），供 text/template 渲染取完整变量表
+func LayerNames(layers Layers, assetType, assetName string) []string {
+	seen := make(map[string]bool)
+	add := func(values map[string]string) {
+		for name := range values {
+			seen[name] = true
+		}
+	}
+	add(AssetSpecificVars(layers.Project, assetType, assetName))
+	add(layers.Profile)
+	if layers.Project != nil {
+		add(layers.Project.Vars)
+	}
+	if layers.Global != nil {
+		add(layers.Global.Vars)
+	}
+	add(layers.VaultProject)
+	add(layers.VaultBundle)
+	add(layers.Builtins)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // getAssetSpecificVar 从资产特定配置中获取变量
 func getAssetSpecificVar(cfg *types.VarsConfig, assetType, assetName, key string) (string, bool) {
+	v, ok := AssetSpecificVars(cfg, assetType, assetName)[key]
+	return v, ok
+}
+
+// AssetSpecificVars 返回 assets.<type>.<name>.vars；未定义时返回 nil
+func AssetSpecificVars(cfg *types.VarsConfig, assetType, assetName string) map[string]string {
 	if cfg == nil || cfg.Assets == nil {
-		return "", false
+		return nil
 	}
 	var entries map[string]types.AssetVarEntry
 	switch assetType {
@@ -87,51 +278,68 @@ func getAssetSpecificVar(cfg *types.VarsConfig, assetType, assetName, key string
 	case "command":
 		entries = cfg.Assets.Commands
 	}
-	if entries == nil {
-		return "", false
-	}
-	entry, ok := entries[assetName]
-	if !ok || entry.Vars == nil {
-		return "", false
-	}
-	v, ok := entry.Vars[key]
-	return v, ok
+	return entries[assetName].Vars
 }
 
-// ExtractPlaceholders 从文本中提取所有占位符变量名（去重）
+// ExtractPlaceholders 从文本中提取所有占位符变量名（去重，不含转义形式）
 func ExtractPlaceholders(content string) []string {
-	matches := placeholderRe.FindAllStringSubmatch(content, -1)
 	seen := make(map[string]bool)
 	var result []string
-	for _, m := range matches {
-		name := m[1]
-		if !seen[name] {
-			seen[name] = true
-			result = append(result, name)
+	for _, p := range ParsePlaceholders(content) {
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			result = append(result, p.Name)
 		}
 	}
 	return result
 }
 
-// HasPlaceholders 检查文本中是否包含占位符
+// CheckFilters 检查文本中使用了未知过滤器的占位符（转义形式除外）。
+// 这类占位符不会被替换，原样留在输出里，调用方应作为错误报告。
+func CheckFilters(content string) error {
+	var invalid []string
+	seen := make(map[string]bool)
+	for _, sub := range filteredPlaceholderRe.FindAllStringSubmatch(content, -1) {
+		if sub[1] != "" || placeholderRe.MatchString(sub[0]) || seen[sub[0]] {
+			continue
+		}
+		seen[sub[0]] = true
+		invalid = append(invalid, sub[0])
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("占位符使用了未知过滤器: %s（可用 lower、upper、json、shell、path、default:\"x\"）", strings.Join(invalid, ", "))
+}
+
+// HasPlaceholders 检查文本中是否包含占位符（含转义形式，转义同样需要改写）
 func HasPlaceholders(content string) bool {
 	return placeholderRe.MatchString(content)
 }
 
 // Substitute 将文本中的占位符替换为实际值
+// 变量未定义时使用 default；没有 default 的才算缺失并保持原样。转义形式去掉反斜杠原样输出。
 // 返回：替换后的文本、实际使用的变量映射、缺失的变量列表
 func Substitute(content string, vars map[string]string) (string, map[string]string, []string) {
 	used := make(map[string]string)
 	missingSet := make(map[string]bool)
 
 	result := placeholderRe.ReplaceAllStringFunc(content, func(match string) string {
-		name := placeholderRe.FindStringSubmatch(match)[1]
-		if val, ok := vars[name]; ok {
-			used[name] = val
-			return val
+		p, escaped := parsePlaceholder(placeholderRe.FindStringSubmatch(match))
+		if escaped {
+			return match[1:]
 		}
-		missingSet[name] = true
-		return match // 保持原样
+		val, ok := vars[p.Name]
+		switch {
+		case ok && (val != "" || !p.HasDefault):
+			used[p.Name] = val
+		case p.HasDefault:
+			val = p.Default
+		default:
+			missingSet[p.Name] = true
+			return match // 保持原样
+		}
+		return applyFilters(val, p.Filters)
 	})
 
 	var missing []string
@@ -158,7 +366,10 @@ func SubstituteFile(filePath string, vars map[string]string) (map[string]string,
 
 	result, used, missing := Substitute(content, vars)
 	if len(used) == 0 {
-		return nil, missing, nil
+		used = nil
+	}
+	if result == content {
+		return used, missing, nil
 	}
 
 	if err := os.WriteFile(filePath, []byte(result), 0644); err != nil {
@@ -221,6 +432,57 @@ func ExtractPlaceholdersFromDir(dirPath string) []string {
 	return result
 }
 
+// ExtractPlaceholderDefaults 返回每处出现都带 default 的变量及其默认值（取第一次出现的值）
+// 只要有一处没写 default，该变量未定义时仍会被报告缺失，因此不计入
+func ExtractPlaceholderDefaults(content string) map[string]string {
+	defaults := make(map[string]string)
+	bare := make(map[string]bool)
+	collectPlaceholderDefaults(content, defaults, bare)
+	return finishPlaceholderDefaults(defaults, bare)
+}
+
+// ExtractPlaceholderDefaultsFromDir 从目录中递归提取 ExtractPlaceholderDefaults
+func ExtractPlaceholderDefaultsFromDir(dirPath string) map[string]string {
+	defaults := make(map[string]string)
+	bare := make(map[string]bool)
+
+	filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		collectPlaceholderDefaults(string(data), defaults, bare)
+		return nil
+	})
+
+	return finishPlaceholderDefaults(defaults, bare)
+}
+
+func collectPlaceholderDefaults(content string, defaults map[string]string, bare map[string]bool) {
+	for _, p := range ParsePlaceholders(content) {
+		if !p.HasDefault {
+			bare[p.Name] = true
+			continue
+		}
+		if _, ok := defaults[p.Name]; !ok {
+			defaults[p.Name] = p.Default
+		}
+	}
+}
+
+func finishPlaceholderDefaults(defaults map[string]string, bare map[string]bool) map[string]string {
+	for name := range bare {
+		delete(defaults, name)
+	}
+	if len(defaults) == 0 {
+		return nil
+	}
+	return defaults
+}
+
 // ExtractPlaceholderLocationsFromFile 从文件中提取占位符及其所在文件路径
 func ExtractPlaceholderLocationsFromFile(filePath string) map[string][]string {
 	data, err := os.ReadFile(filePath)
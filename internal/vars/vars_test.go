@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/shichao402/Dec/internal/types"
@@ -425,3 +426,175 @@ func TestExtractPlaceholderLocationsFromDir(t *testing.T) {
 		t.Fatalf("COMMON_VAR 位置不正确: %#v", got["COMMON_VAR"])
 	}
 }
+
+func TestSubstituteDefaultsFiltersAndEscape(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		vars        map[string]string
+		want        string
+		wantMissing []string
+	}{
+		{
+			name:    "default used when undefined",
+			content: `region: {{REGION|default:"us-east-1"}}`,
+			want:    "region: us-east-1",
+		},
+		{
+			name:    "default used when empty",
+			content: `region: {{REGION|default:"us-east-1"}}`,
+			vars:    map[string]string{"REGION": ""},
+			want:    "region: us-east-1",
+		},
+		{
+			name:    "defined value wins over default",
+			content: `region: {{REGION|default:"us-east-1"}}`,
+			vars:    map[string]string{"REGION": "eu-west-1"},
+			want:    "region: eu-west-1",
+		},
+		{
+			name:    "default with escaped quote",
+			content: `{{GREETING|default:"say \"hi\""}}`,
+			want:    `say "hi"`,
+		},
+		{
+			name:    "filters chain left to right",
+			content: "{{TEAM|lower}} {{TEAM|upper}} {{TEAM | lower | json}}",
+			vars:    map[string]string{"TEAM": "Core"},
+			want:    `core CORE "core"`,
+		},
+		{
+			name:    "shell quoting",
+			content: "echo {{MSG|shell}}",
+			vars:    map[string]string{"MSG": "it's ok"},
+			want:    `echo 'it'\''s ok'`,
+		},
+		{
+			name:    "filters apply to default",
+			content: `{{MODE|default:"Fast"|upper}}`,
+			want:    "FAST",
+		},
+		{
+			name:        "escape keeps literal placeholder",
+			content:     `write \{{NAME}} or \{{NAME|default:"x"}} in templates; value {{NAME}}`,
+			want:        `write {{NAME}} or {{NAME|default:"x"}} in templates; value {{NAME}}`,
+			wantMissing: []string{"NAME"},
+		},
+		{
+			name:    "unknown filter is plain text",
+			content: "{{NAME|reverse}}",
+			want:    "{{NAME|reverse}}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, missing := Substitute(tt.content, tt.vars)
+			if got != tt.want {
+				t.Errorf("Substitute() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestCheckFiltersReportsUnknownFilters(t *testing.T) {
+	content := "{{NAME|lower}} {{NAME|reverse}} {{NAME | upper | trim}} \\{{NAME|reverse}} {{NAME|reverse}} {{lower|bad}}"
+	err := CheckFilters(content)
+	if err == nil {
+		t.Fatal("未知过滤器应报错")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "{{NAME|reverse}}, {{NAME | upper | trim}}（") {
+		t.Fatalf("应按出现顺序去重列出未知过滤器的占位符: %s", msg)
+	}
+	if strings.Contains(msg, "{{NAME|lower}}") || strings.Contains(msg, "bad") {
+		t.Fatalf("合法占位符、转义形式与非占位符不应报告: %s", msg)
+	}
+	if err := CheckFilters(`{{NAME|default:"a|b"|json}} \{{NAME|reverse}}`); err != nil {
+		t.Fatalf("合法占位符不应报错: %v", err)
+	}
+}
+
+func TestPathFilterExpandsHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	got, _, _ := Substitute("{{DATA_DIR|path}}", map[string]string{"DATA_DIR": "~/data/./cache"})
+	if want := filepath.Join(home, "data", "cache"); got != want {
+		t.Errorf("path filter = %q, want %q", got, want)
+	}
+}
+
+func TestExtractPlaceholdersSkipsEscapedAndReportsDefaults(t *testing.T) {
+	content := `\{{DOC_ONLY}} {{REGION|default:"us"}} {{TOKEN}} {{MODE|default:"a"}} {{MODE}}`
+	if got := ExtractPlaceholders(content); !reflect.DeepEqual(got, []string{"REGION", "TOKEN", "MODE"}) {
+		t.Errorf("ExtractPlaceholders() = %v", got)
+	}
+	defaults := ExtractPlaceholderDefaults(content)
+	if !reflect.DeepEqual(defaults, map[string]string{"REGION": "us"}) {
+		t.Errorf("只有每处都带 default 的变量才算有默认值, got %v", defaults)
+	}
+}
+
+func TestSubstituteFileRewritesDefaultsAndEscapesWithoutVars(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "rule.mdc")
+	if err := os.WriteFile(filePath, []byte(`{{REGION|default:"us"}} \{{REGION}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	used, missing, err := SubstituteFile(filePath, nil)
+	if err != nil || used != nil || missing != nil {
+		t.Fatalf("SubstituteFile() used=%v missing=%v err=%v", used, missing, err)
+	}
+	data, _ := os.ReadFile(filePath)
+	if string(data) != "us {{REGION}}" {
+		t.Errorf("file content = %q", data)
+	}
+}
+
+func TestResolveLayersReportsSource(t *testing.T) {
+	layers := Layers{
+		Project: &types.VarsConfig{
+			Vars:   map[string]string{"A": "project"},
+			Assets: &types.AssetVars{Rules: map[string]types.AssetVarEntry{"style": {Vars: map[string]string{"B": "asset"}}}},
+		},
+		Global:       &types.VarsConfig{Vars: map[string]string{"A": "global", "C": "global"}},
+		VaultProject: map[string]string{"C": "vault-project", "D": "vault-project"},
+		VaultBundle:  map[string]string{"D": "vault-bundle", "E": "vault-bundle"},
+		Builtins:     map[string]string{"E": "builtin", "F": "builtin"},
+	}
+	got := ResolveLayers(layers, "rule", "style", []string{"A", "B", "C", "D", "E", "F", "G"})
+	want := map[string]Resolved{
+		"A": {Value: "project", Source: SourceProject},
+		"B": {Value: "asset", Source: SourceAsset},
+		"C": {Value: "global", Source: SourceGlobal},
+		"D": {Value: "vault-project", Source: SourceVaultProject},
+		"E": {Value: "vault-bundle", Source: SourceVaultBundle},
+		"F": {Value: "builtin", Source: SourceBuiltin},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveLayers() = %#v, want %#v", got, want)
+	}
+}
+
+func TestResolveLayersProfileBetweenAssetAndProject(t *testing.T) {
+	layers := Layers{
+		Project: &types.VarsConfig{
+			Vars:   map[string]string{"A": "project", "B": "project"},
+			Assets: &types.AssetVars{Rules: map[string]types.AssetVarEntry{"style": {Vars: map[string]string{"A": "asset"}}}},
+		},
+		Profile: map[string]string{"A": "profile", "B": "profile", "C": "profile"},
+		Global:  &types.VarsConfig{Vars: map[string]string{"C": "global"}},
+	}
+	got := ResolveLayers(layers, "rule", "style", []string{"A", "B", "C"})
+	want := map[string]Resolved{
+		"A": {Value: "asset", Source: SourceAsset},
+		"B": {Value: "profile", Source: SourceProfile},
+		"C": {Value: "profile", Source: SourceProfile},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveLayers() = %#v, want %#v", got, want)
+	}
+}